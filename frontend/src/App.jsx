@@ -11,6 +11,7 @@ import TaskDetail from './pages/TaskDetail';
 import ResultList from './pages/ResultList';
 import ResultDetail from './pages/ResultDetail';
 import UserManagement from './pages/UserManagement';
+import Sessions from './pages/Sessions';
 
 
 import { useUserStore } from './store/user';         // 用户状态管理
@@ -40,6 +41,7 @@ export default function App() {
                     <Route path="task/:id" element={<TaskDetail />} />
                     <Route path="results" element={<ResultList />} />
                     <Route path="result/:id" element={<ResultDetail />} />
+                    <Route path="sessions" element={<Sessions />} />
                     <Route path="admin/users" element={<UserManagement />} />
                 </Route>
 
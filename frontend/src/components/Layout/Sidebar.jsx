@@ -5,6 +5,7 @@ import {
     IconList,
     IconFile,
     IconUser,
+    IconLock,
 } from '@arco-design/web-react/icon';
 import { useNavigate, useLocation } from 'react-router-dom';
 import { useUserStore } from "../../store/user";
@@ -13,16 +14,17 @@ import { useUserStore } from "../../store/user";
 export default function Sidebar() {
     const navigate = useNavigate();
     const { pathname } = useLocation();
-    const { role } = useUserStore(); // 获取当前角色
+    const { can } = useUserStore(); // 按权限控制菜单
 
     const menus = [
         { key: '/dashboard', icon: <IconHome />, label: '仪表盘' },
         { key: '/tasks', icon: <IconList />, label: '扫描任务' },
         { key: '/results', icon: <IconFile />, label: '漏洞结果' },
+        { key: '/sessions', icon: <IconLock />, label: '账户安全' },
     ];
 
-    // 只有 admin 显示用户管理
-    if (role === 'admin') {
+    // 具备查看用户权限时显示用户管理
+    if (can('user.read')) {
         menus.push({ key: '/admin/users', icon: <IconUser />, label: '用户管理' });
     }
 
@@ -0,0 +1,166 @@
+import React, { useEffect, useState } from 'react';
+import { Button, Card, Input, Message, Modal, Space, Tag, Typography } from '@arco-design/web-react';
+import {
+    getMFAStatus,
+    setupMFA,
+    enableMFA,
+    disableMFA,
+    regenerateRecoveryCodes,
+} from '../services/mfa';
+
+// 恢复码只展示一次
+const showRecoveryCodes = (codes) => {
+    Modal.info({
+        title: '请保存恢复码',
+        content: (
+            <div>
+                <Typography.Paragraph>
+                    丢失验证器时可使用以下恢复码登录，每个恢复码只能使用一次：
+                </Typography.Paragraph>
+                <Typography.Paragraph code copyable style={{ whiteSpace: 'pre-line' }}>
+                    {codes.join('\n')}
+                </Typography.Paragraph>
+            </div>
+        ),
+    });
+};
+
+// 二次验证（TOTP）设置：绑定验证器、重新生成恢复码、关闭
+export default function MFASettings() {
+    const [status, setStatus] = useState(null);
+    const [enrollment, setEnrollment] = useState(null); // { secret, uri }
+    const [code, setCode] = useState('');
+    const [password, setPassword] = useState('');
+    const [action, setAction] = useState(''); // regenerate / disable
+
+    const fetchStatus = async () => {
+        try {
+            setStatus(await getMFAStatus());
+        } catch (err) {
+            console.error('❌ 获取二次验证状态失败:', err);
+            Message.error(err?.message || '获取二次验证状态失败');
+        }
+    };
+
+    useEffect(() => {
+        fetchStatus();
+    }, []);
+
+    const handleSetup = async () => {
+        try {
+            setEnrollment(await setupMFA());
+            setCode('');
+        } catch (err) {
+            console.error('❌ 生成密钥失败:', err);
+            Message.error(err?.message || '生成密钥失败');
+        }
+    };
+
+    const handleEnable = async () => {
+        try {
+            const res = await enableMFA(code);
+            Message.success('二次验证已启用');
+            setEnrollment(null);
+            showRecoveryCodes(res.recovery_codes || []);
+            await fetchStatus();
+        } catch (err) {
+            console.error('❌ 启用二次验证失败:', err);
+            Message.error(err?.message || '验证码错误');
+        }
+    };
+
+    const handleConfirmAction = async () => {
+        try {
+            if (action === 'regenerate') {
+                const res = await regenerateRecoveryCodes(code);
+                showRecoveryCodes(res.recovery_codes || []);
+            } else {
+                await disableMFA({ password, code });
+                Message.success('二次验证已关闭');
+            }
+            setAction('');
+            await fetchStatus();
+        } catch (err) {
+            console.error('❌ 操作失败:', err);
+            Message.error(err?.message || '操作失败');
+        }
+    };
+
+    const openAction = (name) => {
+        setAction(name);
+        setCode('');
+        setPassword('');
+    };
+
+    if (!status) {
+        return null;
+    }
+
+    return (
+        <Card title="二次验证" style={{ marginBottom: 16 }}>
+            <Space direction="vertical" style={{ width: '100%' }}>
+                <div>
+                    状态：
+                    {status.enabled ? <Tag color="green">已启用</Tag> : <Tag>未启用</Tag>}
+                    {status.required && <Tag color="orange" style={{ marginLeft: 8 }}>当前角色强制启用</Tag>}
+                    {status.enabled && (
+                        <Typography.Text type="secondary" style={{ marginLeft: 8 }}>
+                            剩余恢复码 {status.recovery_codes_remaining} 个
+                        </Typography.Text>
+                    )}
+                </div>
+
+                {!status.enabled && !enrollment && (
+                    <Button type="primary" onClick={handleSetup}>绑定验证器</Button>
+                )}
+
+                {enrollment && (
+                    <div>
+                        <Typography.Paragraph>
+                            在验证器应用（如 Google Authenticator）中添加以下密钥，或使用绑定地址生成二维码，然后输入验证器显示的 6 位验证码：
+                        </Typography.Paragraph>
+                        <Typography.Paragraph code copyable>{enrollment.secret}</Typography.Paragraph>
+                        <Typography.Paragraph copyable={{ text: enrollment.uri }} type="secondary">
+                            复制绑定地址
+                        </Typography.Paragraph>
+                        <Space>
+                            <Input placeholder="6 位验证码" value={code} onChange={setCode} style={{ width: 160 }} />
+                            <Button type="primary" onClick={handleEnable}>启用</Button>
+                            <Button onClick={() => setEnrollment(null)}>取消</Button>
+                        </Space>
+                    </div>
+                )}
+
+                {status.enabled && (
+                    <Space>
+                        <Button onClick={() => openAction('regenerate')}>重新生成恢复码</Button>
+                        {!status.required && (
+                            <Button status="danger" onClick={() => openAction('disable')}>关闭二次验证</Button>
+                        )}
+                    </Space>
+                )}
+            </Space>
+
+            <Modal
+                title={action === 'regenerate' ? '重新生成恢复码' : '关闭二次验证'}
+                visible={!!action}
+                onOk={handleConfirmAction}
+                onCancel={() => setAction('')}
+            >
+                {action === 'disable' && (
+                    <Input.Password
+                        placeholder="当前密码"
+                        value={password}
+                        onChange={setPassword}
+                        style={{ marginBottom: 10 }}
+                    />
+                )}
+                <Input
+                    placeholder={action === 'disable' ? '6 位验证码或恢复码' : '6 位验证码'}
+                    value={code}
+                    onChange={setCode}
+                />
+            </Modal>
+        </Card>
+    );
+}
@@ -0,0 +1,45 @@
+import React from 'react';
+import { Table, Button, Tag, Modal } from '@arco-design/web-react';
+
+const formatTime = (val) => (val ? new Date(val).toLocaleString() : '-');
+
+// 登录会话列表，onRevoke 为空时不显示操作列
+export default function SessionTable({ sessions, loading, onRevoke }) {
+    const confirmRevoke = (record) => {
+        Modal.confirm({
+            title: '确认吊销会话',
+            content: `吊销后「${record.Device}」需重新登录，是否继续？`,
+            onOk: () => onRevoke(record),
+        });
+    };
+
+    const columns = [
+        {
+            title: '设备',
+            dataIndex: 'Device',
+            render: (val, record) => (
+                <span>
+                    {val}
+                    {record.current && <Tag color="green" size="small" style={{ marginLeft: 8 }}>当前会话</Tag>}
+                </span>
+            ),
+        },
+        { title: 'IP', dataIndex: 'IP', width: 140 },
+        { title: 'User-Agent', dataIndex: 'UserAgent', ellipsis: true },
+        { title: '登录时间', dataIndex: 'CreatedAt', width: 180, render: formatTime },
+        { title: '最近使用', dataIndex: 'LastUsedAt', width: 180, render: formatTime },
+    ];
+    if (onRevoke) {
+        columns.push({
+            title: '操作',
+            width: 90,
+            render: (_, record) => (
+                <Button size="mini" status="danger" onClick={() => confirmRevoke(record)}>
+                    吊销
+                </Button>
+            ),
+        });
+    }
+
+    return <Table rowKey="ID" columns={columns} data={sessions} loading={loading} pagination={false} />;
+}
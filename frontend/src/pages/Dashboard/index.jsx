@@ -13,16 +13,11 @@ import {
     IconUser,
 } from '@arco-design/web-react/icon';
 import { useUserStore } from '../../store/user';
-import { getTasks } from '../../services/task';
-import { getResultsByTaskId, getAllResults } from '../../services/result';
+import { getStats } from '../../services/stats';
 import ReactECharts from 'echarts-for-react';
-import dayjs from 'dayjs';
 
 const Row = Grid.Row;
 const Col = Grid.Col;
-const normalizeSeverity = (s) => (s || '').trim().toLowerCase();
-
-
 
 export default function Dashboard() {
     const { username, role } = useUserStore();
@@ -34,68 +29,22 @@ export default function Dashboard() {
         high: 0,
         critical: 0,
     });
-    const [taskTrend, setTaskTrend] = useState({}); // key: date, value: count
+    const [daily, setDaily] = useState([]);
+    const [mttr, setMttr] = useState(0);
 
     const loadData = async () => {
         try {
-            const tasks = await getTasks();
-            setTaskCount(tasks.length);
-
-            const severityCounter = {
-                low: 0,
-                medium: 0,
-                high: 0,
-                critical: 0,
-            };
-
-            const trendCounter = {};
-            const today = dayjs();
-
-            // 初始化近 7 天日期
-            for (let i = 6; i >= 0; i--) {
-                const date = today.subtract(i, 'day').format('YYYY-MM-DD');
-                trendCounter[date] = 0;
-            }
-
-            let totalResults = 0;
-
-            if (role === 'admin') {
-                const allResults = await getAllResults();
-                totalResults = allResults.length;
-
-                for (const r of allResults) {
-                    const level = normalizeSeverity(r.severity);
-                    const date = dayjs(r.created_at).format('YYYY-MM-DD');
-
-                    if (severityCounter[level] !== undefined) {
-                        severityCounter[level]++;
-                    }
-                    if (date in trendCounter) {
-                        trendCounter[date]++;
-                    }
-                }
-            } else {
-                for (const task of tasks) {
-                    const date = dayjs(task.createdAt).format('YYYY-MM-DD');
-                    if (date in trendCounter) {
-                        trendCounter[date]++;
-                    }
-
-                    const results = await getResultsByTaskId(task.ID);
-                    totalResults += results.length;
-
-                    for (const r of results) {
-                        const level = normalizeSeverity(r.severity);
-                        if (severityCounter[level] !== undefined) {
-                            severityCounter[level]++;
-                        }
-                    }
-                }
-            }
-
-            setResultCount(totalResults);
-            setSeverityStats(severityCounter);
-            setTaskTrend(trendCounter);
+            const stats = await getStats({ days: 7 });
+            setTaskCount(stats.total_tasks);
+            setResultCount(stats.open_findings);
+            setSeverityStats({
+                low: stats.severity.low || 0,
+                medium: stats.severity.medium || 0,
+                high: stats.severity.high || 0,
+                critical: stats.severity.critical || 0,
+            });
+            setDaily(stats.daily);
+            setMttr(Math.round(stats.mttr_hours * 10) / 10);
         } catch (err) {
             console.error('加载数据失败:', err);
             Message.error('加载图表数据失败');
@@ -126,15 +75,24 @@ export default function Dashboard() {
     };
 
     const lineOption = {
-        title: { text: '近7天任务数量趋势', left: 'center' },
+        title: { text: '近7天漏洞新增与关闭趋势', left: 'center' },
+        tooltip: { trigger: 'axis' },
+        legend: { bottom: '0%', left: 'center' },
         xAxis: {
             type: 'category',
-            data: Object.keys(taskTrend),
+            data: daily.map((d) => d.date),
         },
         yAxis: { type: 'value' },
         series: [
             {
-                data: Object.values(taskTrend),
+                name: '新增',
+                data: daily.map((d) => d.opened),
+                type: 'line',
+                smooth: true,
+            },
+            {
+                name: '关闭',
+                data: daily.map((d) => d.closed),
                 type: 'line',
                 smooth: true,
             },
@@ -155,12 +113,12 @@ export default function Dashboard() {
                 </Col>
                 <Col span={8}>
                     <Card bordered>
-                        <Statistic title="漏洞结果总数" value={resultCount} icon={<IconBug />} />
+                        <Statistic title="未处置漏洞数" value={resultCount} icon={<IconBug />} />
                     </Card>
                 </Col>
                 <Col span={8}>
                     <Card bordered>
-                        <Statistic title="当前用户" value={username} icon={<IconUser />} />
+                        <Statistic title="平均修复时长（小时）" value={mttr} icon={<IconUser />} />
                     </Card>
                 </Col>
             </Row>
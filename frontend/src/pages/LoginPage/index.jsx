@@ -1,43 +1,120 @@
 // src/pages/LoginPage/index.jsx
-import React, { useState } from 'react';
+import React, { useEffect, useState } from 'react';
 import { useNavigate } from 'react-router-dom';
 import {
     Card,
     Form,
     Input,
     Button,
+    Divider,
     Message,
+    Space,
+    Modal,
     Typography,
 } from '@arco-design/web-react';
-import { IconUser, IconLock } from '@arco-design/web-react/icon';
+import { IconUser, IconLock, IconSafe } from '@arco-design/web-react/icon';
 import ReactCanvasNest from 'react-canvas-nest';
 
 import { login } from '../../services/auth';
+import { loginMFA, setupLoginMFA } from '../../services/mfa';
+import { exchangeSSOTicket, getOIDCProviders, oidcLoginURL } from '../../services/oidc';
 import { getUserInfo } from '../../services/user';
 import { useUserStore } from '../../store/user';
 
 export default function LoginPage() {
     const [loading, setLoading] = useState(false);
+    const [mfaToken, setMfaToken] = useState('');
+    const [enrollment, setEnrollment] = useState(null); // 登录时绑定验证器：{ secret, uri }
+    const [providers, setProviders] = useState([]); // 单点登录方式
     const navigate = useNavigate();
     const setUser = useUserStore((state) => state.setUser);
 
+    const finishLogin = async (token) => {
+        localStorage.setItem('token', token);
+        const userInfo = await getUserInfo(token);
+        setUser({ token, ...userInfo });
+
+        Message.success('登录成功');
+        navigate('/dashboard');
+    };
+
+    useEffect(() => {
+        getOIDCProviders()
+            .then((list) => setProviders(list || []))
+            .catch((err) => console.error('❌ 获取单点登录方式失败:', err));
+
+        // 单点登录回调跳转：#sso_ticket=... 或 #sso_error=...
+        const params = new URLSearchParams(window.location.hash.slice(1));
+        const ticket = params.get('sso_ticket');
+        const ssoError = params.get('sso_error');
+        if (!ticket && !ssoError) {
+            return;
+        }
+        window.history.replaceState(null, '', window.location.pathname);
+        if (ssoError) {
+            Message.error(ssoError);
+            return;
+        }
+        setLoading(true);
+        exchangeSSOTicket(ticket)
+            .then((res) => finishLogin(res.token))
+            .catch((err) => Message.error(err?.message || err?.error || '单点登录失败'))
+            .finally(() => setLoading(false));
+    }, []);
+
     const handleSubmit = async (values) => {
         setLoading(true);
         try {
-            const { token } = await login(values);
-            if (!token) {
+            const res = await login(values);
+            if (res?.mfa_required) {
+                setMfaToken(res.mfa_token);
+                if (res.enrollment_required) {
+                    setEnrollment(await setupLoginMFA(res.mfa_token));
+                }
+                return;
+            }
+            if (!res?.token) {
                 Message.error('登录失败：未返回 token');
                 return;
             }
+            await finishLogin(res.token);
+        } catch (err) {
+            Message.error(err?.message || err?.error || '登录失败');
+        } finally {
+            setLoading(false);
+        }
+    };
 
-            localStorage.setItem('token', token);
-            const userInfo = await getUserInfo(token);
-            setUser({ token, ...userInfo });
-
-            Message.success('登录成功');
-            navigate('/dashboard');
+    const handleVerify = async ({ code }) => {
+        setLoading(true);
+        try {
+            const res = await loginMFA({ mfa_token: mfaToken, code });
+            if (res.recovery_codes) {
+                // 恢复码只展示一次，确认保存后再进入系统
+                Modal.info({
+                    title: '请保存恢复码',
+                    content: (
+                        <div>
+                            <Typography.Paragraph>
+                                丢失验证器时可使用以下恢复码登录，每个恢复码只能使用一次：
+                            </Typography.Paragraph>
+                            <Typography.Paragraph code copyable style={{ whiteSpace: 'pre-line' }}>
+                                {res.recovery_codes.join('\n')}
+                            </Typography.Paragraph>
+                        </div>
+                    ),
+                    onOk: () => finishLogin(res.token),
+                });
+                return;
+            }
+            await finishLogin(res.token);
         } catch (err) {
-            Message.error(err?.message || '登录失败');
+            Message.error(err?.message || err?.error || '验证失败');
+            // 挑战令牌过期或错误次数过多时回到密码登录
+            if (err?.error && err.error.includes('重新登录')) {
+                setMfaToken('');
+                setEnrollment(null);
+            }
         } finally {
             setLoading(false);
         }
@@ -87,21 +164,81 @@ export default function LoginPage() {
                 <Card style={{ width: 380 }}>
                     <Typography.Title heading={5}>VulnFusion 登录</Typography.Title>
 
-                    <Form layout="vertical" onSubmit={handleSubmit}>
-                        <Form.Item label="用户名" field="username" rules={[{ required: true }]}>
-                            <Input prefix={<IconUser />} placeholder="请输入用户名" />
-                        </Form.Item>
+                    {!mfaToken ? (
+                        <Form layout="vertical" onSubmit={handleSubmit}>
+                            <Form.Item label="用户名" field="username" rules={[{ required: true }]}>
+                                <Input prefix={<IconUser />} placeholder="请输入用户名" />
+                            </Form.Item>
+
+                            <Form.Item label="密码" field="password" rules={[{ required: true }]}>
+                                <Input.Password prefix={<IconLock />} placeholder="请输入密码" />
+                            </Form.Item>
+
+                            <Form.Item>
+                                <Button type="primary" htmlType="submit" loading={loading} long>
+                                    登录
+                                </Button>
+                            </Form.Item>
+
+                            {providers.length > 0 && (
+                                <>
+                                    <Divider>或</Divider>
+                                    <Space direction="vertical" style={{ width: '100%' }}>
+                                        {providers.map((p) => (
+                                            <Button
+                                                key={p.name}
+                                                long
+                                                disabled={loading}
+                                                onClick={() => {
+                                                    window.location.href = oidcLoginURL(p.name);
+                                                }}
+                                            >
+                                                使用{p.display_name}登录
+                                            </Button>
+                                        ))}
+                                    </Space>
+                                </>
+                            )}
+                        </Form>
+                    ) : (
+                        <Form layout="vertical" onSubmit={handleVerify}>
+                            {enrollment ? (
+                                <div style={{ marginBottom: 12 }}>
+                                    <Typography.Paragraph>
+                                        当前账号必须启用二次验证。请在验证器应用（如 Google Authenticator）中添加以下密钥，或使用绑定地址生成二维码：
+                                    </Typography.Paragraph>
+                                    <Typography.Paragraph code copyable>{enrollment.secret}</Typography.Paragraph>
+                                    <Typography.Paragraph copyable={{ text: enrollment.uri }} type="secondary">
+                                        复制绑定地址
+                                    </Typography.Paragraph>
+                                </div>
+                            ) : (
+                                <Typography.Paragraph type="secondary">
+                                    请输入验证器中的 6 位验证码，或使用恢复码
+                                </Typography.Paragraph>
+                            )}
 
-                        <Form.Item label="密码" field="password" rules={[{ required: true }]}>
-                            <Input.Password prefix={<IconLock />} placeholder="请输入密码" />
-                        </Form.Item>
+                            <Form.Item label="验证码" field="code" rules={[{ required: true }]}>
+                                <Input prefix={<IconSafe />} placeholder="6 位验证码或恢复码" autoComplete="one-time-code" />
+                            </Form.Item>
 
-                        <Form.Item>
-                            <Button type="primary" htmlType="submit" loading={loading} long>
-                                登录
+                            <Form.Item>
+                                <Button type="primary" htmlType="submit" loading={loading} long>
+                                    验证
+                                </Button>
+                            </Form.Item>
+                            <Button
+                                type="text"
+                                long
+                                onClick={() => {
+                                    setMfaToken('');
+                                    setEnrollment(null);
+                                }}
+                            >
+                                返回
                             </Button>
-                        </Form.Item>
-                    </Form>
+                        </Form>
+                    )}
                 </Card>
             </div>
         </div>
@@ -62,12 +62,59 @@ export default function ResultDetail() {
                 </Descriptions.Item>
                 <Descriptions.Item label="所属任务 ID">{result.taskID}</Descriptions.Item>
                 <Descriptions.Item label="时间戳">{result.timestamp}</Descriptions.Item>
+                {result.TicketKey && (
+                    <Descriptions.Item label="关联工单">
+                        <a href={result.TicketURL} target="_blank" rel="noreferrer">
+                            {result.TicketKey}
+                        </a>
+                        {result.TicketStatus && <Tag style={{ marginLeft: 8 }}>{result.TicketStatus}</Tag>}
+                    </Descriptions.Item>
+                )}
                 <Descriptions.Item label="详细信息">
                     <Typography.Paragraph copyable style={{ whiteSpace: 'pre-wrap' }}>
                         {result.detail}
                     </Typography.Paragraph>
                 </Descriptions.Item>
             </Descriptions>
+
+            {(result.enrichment?.cves || []).map((cve) => (
+                <Descriptions
+                    key={cve.id}
+                    title={cve.id}
+                    column={1}
+                    layout="horizontal"
+                    style={{ maxWidth: 800, marginTop: 24 }}
+                >
+                    <Descriptions.Item label="描述">{cve.description}</Descriptions.Item>
+                    <Descriptions.Item label="CVSS v3">
+                        {cve.cvss3_score} {cve.cvss3_vector}
+                    </Descriptions.Item>
+                    <Descriptions.Item label="CWE">{cve.cwes.join(', ')}</Descriptions.Item>
+                    <Descriptions.Item label="发布时间">{cve.published_at}</Descriptions.Item>
+                    <Descriptions.Item label="受影响 CPE">
+                        <Typography.Paragraph style={{ whiteSpace: 'pre-wrap' }}>
+                            {cve.cpes.join('\n')}
+                        </Typography.Paragraph>
+                    </Descriptions.Item>
+                    <Descriptions.Item label="参考链接">
+                        <Typography.Paragraph copyable style={{ whiteSpace: 'pre-wrap' }}>
+                            {cve.references.join('\n')}
+                        </Typography.Paragraph>
+                    </Descriptions.Item>
+                </Descriptions>
+            ))}
+
+            {(result.enrichment?.cwes || []).map((cwe) => (
+                <Descriptions
+                    key={cwe.id}
+                    title={`${cwe.id} ${cwe.name}`}
+                    column={1}
+                    layout="horizontal"
+                    style={{ maxWidth: 800, marginTop: 24 }}
+                >
+                    <Descriptions.Item label="描述">{cwe.description}</Descriptions.Item>
+                </Descriptions>
+            ))}
         </div>
     );
 }
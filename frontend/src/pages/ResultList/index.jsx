@@ -5,7 +5,7 @@ import { useUserStore } from '../../store/user';
 import { useNavigate } from 'react-router-dom';
 
 export default function ResultList() {
-    const { role } = useUserStore();
+    const { can, permissions } = useUserStore();
     const [results, setResults] = useState([]);
     const [loading, setLoading] = useState(false);
     const navigate = useNavigate();
@@ -17,6 +17,8 @@ export default function ResultList() {
             taskId: item.TaskID,
             vulnerability: item.Vulnerability,
             severity: item.Severity,
+            riskScore: item.RiskScore,
+            cve: item.CVEID,
             target: item.Target,
             timestamp: item.Timestamp,
         }));
@@ -25,7 +27,7 @@ export default function ResultList() {
         setLoading(true);
         try {
             const raw =
-                role === 'admin'
+                can('result.read.any')
                     ? await getAllResults()
                     : await getResultsByTaskId(localStorage.getItem('currentTaskId'));
 
@@ -41,7 +43,7 @@ export default function ResultList() {
 
     useEffect(() => {
         fetchData();
-    }, [role]);
+    }, [permissions]);
 
     const columns = [
         { title: '结果 ID', dataIndex: 'id', width: 80 },
@@ -60,6 +62,14 @@ export default function ResultList() {
                 return <Tag color={colorMap[level] || 'gray'}>{level}</Tag>;
             },
         },
+        {
+            title: '风险分',
+            dataIndex: 'riskScore',
+            width: 100,
+            sorter: (a, b) => a.riskScore - b.riskScore,
+            defaultSortOrder: 'descend',
+        },
+        { title: 'CVE', dataIndex: 'cve', width: 160 },
         { title: '时间戳', dataIndex: 'timestamp', width: 200 },
         {
             title: '操作',
@@ -0,0 +1,84 @@
+import React, { useEffect, useState } from 'react';
+import { Button, Message, Modal, Typography } from '@arco-design/web-react';
+import { useNavigate } from 'react-router-dom';
+import SessionTable from '../../components/SessionTable';
+import MFASettings from '../../components/MFASettings';
+import { getMySessions, revokeSession, logoutAll } from '../../services/session';
+import { useUserStore } from '../../store/user';
+
+export default function Sessions() {
+    const navigate = useNavigate();
+    const { clearUser } = useUserStore();
+    const [loading, setLoading] = useState(false);
+    const [sessions, setSessions] = useState([]);
+
+    const fetchSessions = async () => {
+        setLoading(true);
+        try {
+            const res = await getMySessions();
+            setSessions(res || []);
+        } catch (err) {
+            console.error('❌ 获取会话失败:', err);
+            Message.error(err?.message || '获取会话失败');
+        } finally {
+            setLoading(false);
+        }
+    };
+
+    useEffect(() => {
+        fetchSessions();
+    }, []);
+
+    const handleRevoke = async (record) => {
+        try {
+            await revokeSession(record.ID);
+            Message.success('会话已吊销');
+            if (record.current) {
+                clearUser();
+                navigate('/login');
+                return;
+            }
+            await fetchSessions();
+        } catch (err) {
+            console.error('❌ 吊销会话失败:', err);
+            Message.error(err?.message || '吊销会话失败');
+        }
+    };
+
+    const handleLogoutAll = () => {
+        Modal.confirm({
+            title: '退出所有设备',
+            content: '所有设备（包括当前设备）都需要重新登录，是否继续？',
+            onOk: async () => {
+                try {
+                    await logoutAll();
+                    Message.success('已退出所有设备');
+                    clearUser();
+                    navigate('/login');
+                } catch (err) {
+                    console.error('❌ 退出所有设备失败:', err);
+                    Message.error(err?.message || '操作失败');
+                }
+            },
+        });
+    };
+
+    return (
+        <div>
+            <MFASettings />
+
+            <Typography.Title heading={5}>登录会话</Typography.Title>
+            <Typography.Text type="secondary">
+                如发现不认识的设备，请吊销对应会话并修改密码
+            </Typography.Text>
+
+            <div style={{ margin: '12px 0' }}>
+                <Button status="danger" onClick={handleLogoutAll}>
+                    退出所有设备
+                </Button>
+            </div>
+
+            <SessionTable sessions={sessions} loading={loading} onRevoke={handleRevoke} />
+        </div>
+    );
+}
@@ -67,7 +67,24 @@ export default function TaskDetail() {
                         >
                             <Descriptions.Item label="任务 ID">{task.ID}</Descriptions.Item>
                             <Descriptions.Item label="目标地址">{task.Target}</Descriptions.Item>
-                            <Descriptions.Item label="模板名称">{task.Template}</Descriptions.Item>
+                            <Descriptions.Item label="模板名称">{task.Template || '-'}</Descriptions.Item>
+                            <Descriptions.Item label="扫描模式">
+                                {task.Mode === 'auto' ? '自动（按技术栈选择模板）' : '标准'}
+                            </Descriptions.Item>
+                            {task.Mode === 'auto' && (
+                                <>
+                                    <Descriptions.Item label="识别技术栈">
+                                        {task.DetectedTech
+                                            ? task.DetectedTech.split(',').map((t) => <Tag key={t} style={{ marginRight: 4 }}>{t}</Tag>)
+                                            : '-'}
+                                    </Descriptions.Item>
+                                    <Descriptions.Item label="选用模板标签">
+                                        {task.SelectedTags
+                                            ? task.SelectedTags.split(',').map((t) => <Tag key={t} color="arcoblue" style={{ marginRight: 4 }}>{t}</Tag>)
+                                            : '-'}
+                                    </Descriptions.Item>
+                                </>
+                            )}
                             <Descriptions.Item label="状态">
                                 <Tag color={statusColors[task.Status] || 'gray'}>
                                     {task.Status}
@@ -1,5 +1,5 @@
 import React, { useState } from 'react';
-import { Button, Drawer, Form, Input, Message } from '@arco-design/web-react';
+import { Button, Drawer, Form, Input, Message, Select } from '@arco-design/web-react';
 import { createTask } from '../../services/task';
 
 export default function CreateTaskForm({ onSuccess }) {
@@ -50,12 +50,25 @@ export default function CreateTaskForm({ onSuccess }) {
                         <Input placeholder="https://example.com" />
                     </Form.Item>
 
-                    <Form.Item
-                        label="Nuclei 模板路径"
-                        field="template"
-                        rules={[{ required: true, message: '请输入模板路径' }]}
-                    >
-                        <Input placeholder="cves/2021/*.yaml" />
+                    <Form.Item label="扫描模式" field="mode" initialValue="standard">
+                        <Select>
+                            <Select.Option value="standard">标准（指定模板）</Select.Option>
+                            <Select.Option value="auto">自动（按技术栈选择模板）</Select.Option>
+                        </Select>
+                    </Form.Item>
+
+                    <Form.Item shouldUpdate noStyle>
+                        {(values) =>
+                            values.mode !== 'auto' && (
+                                <Form.Item
+                                    label="Nuclei 模板路径"
+                                    field="template"
+                                    rules={[{ required: true, message: '请输入模板路径' }]}
+                                >
+                                    <Input placeholder="cves/2021/*.yaml" />
+                                </Form.Item>
+                            )
+                        }
                     </Form.Item>
                 </Form>
             </Drawer>
@@ -8,13 +8,13 @@ import CreateTaskForm from './CreateTaskForm'; // ✅ 引入创建任务抽屉
 export default function TaskList() {
     const [data, setData] = useState([]);
     const [loading, setLoading] = useState(false);
-    const { role } = useUserStore();
+    const { can, permissions } = useUserStore();
     const navigate = useNavigate();
 
     const fetchTasks = async () => {
         setLoading(true);
         try {
-            const res = role === 'admin' ? await getAllTasks() : await getTasks();
+            const res = can('task.read.any') ? await getAllTasks() : await getTasks();
             const normalized = (res || []).map(item => ({
                 id: item.ID,
                 target: item.Target,
@@ -33,7 +33,7 @@ export default function TaskList() {
 
     useEffect(() => {
         fetchTasks();
-    }, [role]);
+    }, [permissions]);
 
     const columns = [
         { title: '任务 ID', dataIndex: 'id', width: 80 },
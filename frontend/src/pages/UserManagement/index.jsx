@@ -14,14 +14,34 @@ import {
     deleteUserById,
     updateUserById,
     resetUserPassword,
+    getRoles,
+    createUser,
+    approveUser,
+    createInvite,
+    unlockUser,
 } from '../../services/user';
 import { useUserStore } from '../../store/user';
-import { register } from '../../services/auth';
+import { getUserSessions, revokeSession, revokeUserTokens } from '../../services/session';
+import { resetUserMFA } from '../../services/mfa';
+import SessionTable from '../../components/SessionTable';
 
 const Option = Select.Option;
 
+// 内置角色的显示名称，配置中新增的角色直接显示角色名
+const roleLabels = {
+    admin: '管理员',
+    user: '普通用户',
+    viewer: '只读用户',
+    auditor: '审计员',
+    'scanner-operator': '扫描操作员',
+};
+
+const roleLabel = (role) => roleLabels[role] || role;
+
 export default function UserManagement() {
-    const { role: currentUserRole } = useUserStore(); // 当前登录用户的角色
+    const { can } = useUserStore(); // 当前登录用户的权限
+    const canManage = can('user.manage');
+    const [roles, setRoles] = useState([]);
     const [loading, setLoading] = useState(false);
     const [users, setUsers] = useState([]);
     const [editUser, setEditUser] = useState(null);
@@ -29,6 +49,9 @@ export default function UserManagement() {
     const [newPassword, setNewPassword] = useState('');
     const [addModalVisible, setAddModalVisible] = useState(false);
     const [newUser, setNewUser] = useState({ username: '', password: '', role: 'user' });
+    const [sessionTarget, setSessionTarget] = useState(null);
+    const [sessions, setSessions] = useState([]);
+    const [sessionsLoading, setSessionsLoading] = useState(false);
 
     const fetchUsers = async () => {
         setLoading(true);
@@ -38,6 +61,9 @@ export default function UserManagement() {
                 id: u.ID,
                 username: u.Username,
                 role: u.Role,
+                mfaEnabled: u.TOTPEnabled,
+                pending: u.Status === 'pending',
+                locked: !!u.LockedUntil && new Date(u.LockedUntil) > new Date(),
             }));
             setUsers(normalized);
         } catch (err) {
@@ -48,10 +74,25 @@ export default function UserManagement() {
         }
     };
 
+    const fetchRoles = async () => {
+        try {
+            const res = await getRoles();
+            setRoles((res?.roles || []).map(r => r.name));
+        } catch (err) {
+            console.error('❌ 获取角色失败:', err);
+            setRoles(Object.keys(roleLabels));
+        }
+    };
+
     useEffect(() => {
         fetchUsers();
+        fetchRoles();
     }, []);
 
+    const roleOptions = roles.map(r => (
+        <Option key={r} value={r}>{roleLabel(r)}</Option>
+    ));
+
     const handleDelete = (id) => {
         Modal.confirm({
             title: '确认删除',
@@ -107,6 +148,118 @@ export default function UserManagement() {
         }
     };
 
+    const fetchUserSessions = async (user) => {
+        setSessionsLoading(true);
+        try {
+            const res = await getUserSessions(user.id);
+            setSessions(res || []);
+        } catch (err) {
+            console.error('❌ 获取会话失败:', err);
+            Message.error(err?.message || '获取会话失败');
+        } finally {
+            setSessionsLoading(false);
+        }
+    };
+
+    const handleShowSessions = (record) => {
+        setSessionTarget(record);
+        setSessions([]);
+        fetchUserSessions(record);
+    };
+
+    const handleRevokeSession = async (session) => {
+        try {
+            await revokeSession(session.ID);
+            Message.success('会话已吊销');
+            await fetchUserSessions(sessionTarget);
+        } catch (err) {
+            console.error('❌ 吊销会话失败:', err);
+            Message.error(err?.message || '吊销会话失败');
+        }
+    };
+
+    const handleRevokeAll = () => {
+        Modal.confirm({
+            title: '强制下线',
+            content: `将吊销用户「${sessionTarget?.username}」的全部登录会话，是否继续？`,
+            onOk: async () => {
+                try {
+                    await revokeUserTokens(sessionTarget.id);
+                    Message.success('已强制下线');
+                    await fetchUserSessions(sessionTarget);
+                } catch (err) {
+                    console.error('❌ 强制下线失败:', err);
+                    Message.error(err?.message || '强制下线失败');
+                }
+            },
+        });
+    };
+
+    const handleApprove = (record) => {
+        Modal.confirm({
+            title: '审批注册',
+            content: `审批通过后用户「${record.username}」即可登录（角色 ${roleLabel(record.role)}，可稍后编辑），是否继续？`,
+            onOk: async () => {
+                try {
+                    await approveUser(record.id);
+                    Message.success('已审批通过');
+                    await fetchUsers();
+                } catch (err) {
+                    console.error('❌ 审批失败:', err);
+                    Message.error(err?.message || '审批失败');
+                }
+            },
+        });
+    };
+
+    const handleUnlock = async (record) => {
+        try {
+            await unlockUser(record.id);
+            Message.success('已解除锁定');
+            await fetchUsers();
+        } catch (err) {
+            console.error('❌ 解除锁定失败:', err);
+            Message.error(err?.message || '解除锁定失败');
+        }
+    };
+
+    const handleCreateInvite = async () => {
+        try {
+            const res = await createInvite({});
+            Modal.info({
+                title: '邀请码已生成',
+                content: (
+                    <div>
+                        <Typography.Paragraph copyable>{res.code}</Typography.Paragraph>
+                        <Typography.Text type="secondary">
+                            邀请码只显示一次，可使用一次，过期时间：{new Date(res.info.ExpiresAt).toLocaleString()}
+                        </Typography.Text>
+                    </div>
+                ),
+            });
+        } catch (err) {
+            console.error('❌ 生成邀请码失败:', err);
+            Message.error(err?.message || '生成邀请码失败');
+        }
+    };
+
+    const handleResetMFA = (record) => {
+        Modal.confirm({
+            title: '重置二次验证',
+            content: `将清除用户「${record.username}」的验证器与恢复码并强制下线，是否继续？`,
+            onOk: async () => {
+                try {
+                    await resetUserMFA(record.id);
+                    Message.success('二次验证已重置');
+                    await fetchUsers();
+                } catch (err) {
+                    console.error('❌ 重置二次验证失败:', err);
+                    Message.error(err?.message || '重置二次验证失败');
+                }
+            },
+        });
+    };
+
     const handleAddUser = async () => {
         const { username, password, role } = newUser;
 
@@ -116,7 +269,7 @@ export default function UserManagement() {
         }
 
         try {
-            await register({ username, password, role });
+            await createUser({ username, password, role });
             Message.success('用户添加成功');
             setAddModalVisible(false);
             setNewUser({ username: '', password: '', role: 'user' });
@@ -140,33 +293,62 @@ export default function UserManagement() {
         {
             title: '角色',
             dataIndex: 'role',
-            render: (val) => (val === 'admin' ? '管理员' : '普通用户'),
+            render: roleLabel,
+        },
+        {
+            title: '状态',
+            dataIndex: 'pending',
+            render: (val, record) => {
+                if (val) return '待审批';
+                return record.locked ? '已锁定' : '正常';
+            },
+        },
+        {
+            title: '二次验证',
+            dataIndex: 'mfaEnabled',
+            render: (val) => (val ? '已启用' : '未启用'),
         },
         {
             title: '操作',
             render: (_, record) => (
                 <Space>
-                    <Button size="mini" onClick={() => handleEdit(record)}>编辑</Button>
-                    <Button
-                        size="mini"
-                        status="warning"
-                        onClick={() => handleResetPassword(record)}
-                    >
-                        重置密码
-                    </Button>
-                    <Button
-                        size="mini"
-                        status="danger"
-                        onClick={() => handleDelete(record.id)}
-                    >
-                        删除
-                    </Button>
+                    {canManage && record.pending && (
+                        <Button size="mini" type="primary" onClick={() => handleApprove(record)}>审批</Button>
+                    )}
+                    {canManage && record.locked && (
+                        <Button size="mini" type="primary" onClick={() => handleUnlock(record)}>解锁</Button>
+                    )}
+                    {canManage && <Button size="mini" onClick={() => handleEdit(record)}>编辑</Button>}
+                    <Button size="mini" onClick={() => handleShowSessions(record)}>会话</Button>
+                    {canManage && (
+                        <Button
+                            size="mini"
+                            status="warning"
+                            onClick={() => handleResetPassword(record)}
+                        >
+                            重置密码
+                        </Button>
+                    )}
+                    {canManage && record.mfaEnabled && (
+                        <Button size="mini" status="warning" onClick={() => handleResetMFA(record)}>
+                            重置二次验证
+                        </Button>
+                    )}
+                    {canManage && (
+                        <Button
+                            size="mini"
+                            status="danger"
+                            onClick={() => handleDelete(record.id)}
+                        >
+                            删除
+                        </Button>
+                    )}
                 </Space>
             ),
         },
     ];
 
-    if (currentUserRole !== 'admin') {
+    if (!can('user.read')) {
         return <Typography.Text>无权访问该页面</Typography.Text>;
     }
 
@@ -177,11 +359,16 @@ export default function UserManagement() {
                 当前用户数量：{users.length}
             </Typography.Text>
 
-            <div style={{ margin: '12px 0' }}>
-                <Button type="primary" onClick={() => setAddModalVisible(true)}>
-                    添加新用户
-                </Button>
-            </div>
+            {canManage && (
+                <div style={{ margin: '12px 0' }}>
+                    <Space>
+                        <Button type="primary" onClick={() => setAddModalVisible(true)}>
+                            添加新用户
+                        </Button>
+                        <Button onClick={handleCreateInvite}>生成邀请码</Button>
+                    </Space>
+                </div>
+            )}
 
             <Table
                 rowKey="id"
@@ -205,7 +392,7 @@ export default function UserManagement() {
                     style={{ marginBottom: 10 }}
                 />
                 <Input.Password
-                    placeholder="密码"
+                    placeholder="密码（至少 8 位，包含字母和数字）"
                     value={newUser.password}
                     onChange={(val) => setNewUser({ ...newUser, password: val })}
                     style={{ marginBottom: 10 }}
@@ -215,8 +402,7 @@ export default function UserManagement() {
                     onChange={(val) => setNewUser({ ...newUser, role: val })}
                     style={{ width: '100%' }}
                 >
-                    <Option value="admin">管理员</Option>
-                    <Option value="user">普通用户</Option>
+                    {roleOptions}
                 </Select>
             </Modal>
 
@@ -232,11 +418,26 @@ export default function UserManagement() {
                     onChange={(val) => setEditUser({ ...editUser, role: val })}
                     style={{ width: '100%' }}
                 >
-                    <Option value="admin">管理员</Option>
-                    <Option value="user">普通用户</Option>
+                    {roleOptions}
                 </Select>
             </Modal>
 
+            {/* 登录会话 */}
+            <Modal
+                title={`登录会话（${sessionTarget?.username}）`}
+                visible={!!sessionTarget}
+                footer={null}
+                style={{ width: 960 }}
+                onCancel={() => setSessionTarget(null)}
+            >
+                {canManage && (
+                    <div style={{ marginBottom: 12 }}>
+                        <Button status="danger" onClick={handleRevokeAll}>强制下线</Button>
+                    </div>
+                )}
+                <SessionTable sessions={sessions} loading={sessionsLoading} onRevoke={handleRevokeSession} />
+            </Modal>
+
             {/* 重置密码 */}
             <Modal
                 title={`重置用户密码（${resetTarget?.username}）`}
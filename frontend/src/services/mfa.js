@@ -0,0 +1,59 @@
+import request from '../utils/request';
+
+/**
+ * 二次验证登录（登录第二步）
+ * @param {Object} data - { mfa_token, code }
+ */
+export function loginMFA(data) {
+    return request.post('/auth/login/mfa', data);
+}
+
+/**
+ * 登录时绑定验证器（角色强制启用二次验证且尚未绑定）
+ * @param {string} mfaToken - 登录第一步返回的挑战令牌
+ */
+export function setupLoginMFA(mfaToken) {
+    return request.post('/auth/login/mfa/setup', { mfa_token: mfaToken });
+}
+
+// 获取二次验证状态
+export function getMFAStatus() {
+    return request.get('/auth/mfa');
+}
+
+// 生成待确认的 TOTP 密钥
+export function setupMFA() {
+    return request.post('/auth/mfa/setup');
+}
+
+/**
+ * 确认绑定并启用二次验证
+ * @param {string} code - 6 位验证码
+ */
+export function enableMFA(code) {
+    return request.post('/auth/mfa/enable', { code });
+}
+
+/**
+ * 关闭二次验证
+ * @param {Object} data - { password, code }
+ */
+export function disableMFA(data) {
+    return request.post('/auth/mfa/disable', data);
+}
+
+/**
+ * 重新生成恢复码
+ * @param {string} code - 6 位验证码
+ */
+export function regenerateRecoveryCodes(code) {
+    return request.post('/auth/mfa/recovery-codes', { code });
+}
+
+/**
+ * 重置用户的二次验证（管理员权限）
+ * @param {number} userId - 用户ID
+ */
+export function resetUserMFA(userId) {
+    return request.post(`/admin/users/${userId}/mfa/reset`);
+}
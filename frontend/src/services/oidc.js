@@ -0,0 +1,22 @@
+import request from '../utils/request';
+
+// 获取已配置的单点登录方式
+export function getOIDCProviders() {
+    return request.get('/auth/oidc/providers');
+}
+
+/**
+ * 发起单点登录的地址（整页跳转到身份提供方）
+ * @param {string} provider - 身份提供方名称
+ */
+export function oidcLoginURL(provider) {
+    return `/api/v1/auth/oidc/${encodeURIComponent(provider)}/login`;
+}
+
+/**
+ * 使用回调跳转中的一次性票据换取令牌
+ * @param {string} ticket - sso_ticket
+ */
+export function exchangeSSOTicket(ticket) {
+    return request.post('/auth/oidc/exchange', { ticket });
+}
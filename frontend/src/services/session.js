@@ -0,0 +1,35 @@
+import request from '../utils/request';
+
+// 获取当前用户的登录会话
+export function getMySessions() {
+    return request.get('/sessions');
+}
+
+/**
+ * 吊销登录会话（本人或管理员）
+ * @param {number} id - 会话ID
+ */
+export function revokeSession(id) {
+    return request.delete(`/sessions/${id}`);
+}
+
+// 退出所有设备
+export function logoutAll() {
+    return request.post('/auth/logout-all');
+}
+
+/**
+ * 获取指定用户的登录会话（管理员权限）
+ * @param {number} userId - 用户ID
+ */
+export function getUserSessions(userId) {
+    return request.get(`/admin/users/${userId}/sessions`);
+}
+
+/**
+ * 强制用户下线（管理员权限）
+ * @param {number} userId - 用户ID
+ */
+export function revokeUserTokens(userId) {
+    return request.post(`/admin/users/${userId}/revoke-tokens`);
+}
@@ -0,0 +1,6 @@
+import request from '../utils/request';
+
+// 获取仪表盘统计数据
+export function getStats(params) {
+    return request.get('/stats', { params });
+}
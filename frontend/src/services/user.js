@@ -41,3 +41,43 @@ export function updateUserById(id, data) {
 export function resetUserPassword(id, newPassword) {
     return request.put(`/admin/users/${id}/password`, { password: newPassword });
 }
+
+/**
+ * 获取全部角色及权限点说明
+ */
+export function getRoles() {
+    return request.get('/admin/roles');
+}
+
+/**
+ * 管理员创建用户
+ * @param {Object} data - { username, password, role }
+ */
+export function createUser(data) {
+    return request.post('/admin/users', data);
+}
+
+/**
+ * 审批通过自助注册的用户
+ * @param {number} id - 用户ID
+ * @param {string} role - 授予的角色，默认 viewer
+ */
+export function approveUser(id, role) {
+    return request.post(`/admin/users/${id}/approve`, role ? { role } : {});
+}
+
+/**
+ * 生成注册邀请码，明文只返回一次
+ * @param {Object} data - { note, expires_in_hours }
+ */
+export function createInvite(data) {
+    return request.post('/admin/invites', data);
+}
+
+/**
+ * 解除账号的登录失败锁定
+ * @param {number} id - 用户ID
+ */
+export function unlockUser(id) {
+    return request.post(`/admin/users/${id}/unlock`);
+}
@@ -1,12 +1,17 @@
 // src/store/user.js
 import { create } from 'zustand';
 
-export const useUserStore = create((set) => ({
+export const useUserStore = create((set, get) => ({
     token: '',
     username: '',
     role: '',
+    permissions: [], // 当前角色展开后的权限点，来自 /user/info
 
-    setUser: ({ token, username, role }) => set({ token, username, role }),
+    setUser: ({ token, username, role, permissions }) =>
+        set({ token, username, role, permissions: permissions || [] }),
 
-    clearUser: () => set({ token: '', username: '', role: '' }),
+    clearUser: () => set({ token: '', username: '', role: '', permissions: [] }),
+
+    // 判断当前用户是否具备权限
+    can: (permission) => get().permissions.includes(permission),
 }));
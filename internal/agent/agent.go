@@ -0,0 +1,372 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"VulnFusion/internal/config"
+	"VulnFusion/internal/log"
+	"VulnFusion/internal/models"
+	"VulnFusion/internal/provenance"
+	"VulnFusion/internal/scanner"
+)
+
+// resultBatchSize 每累计多少条结果回传一次
+const resultBatchSize = 20
+
+// Options 远程节点运行参数
+type Options struct {
+	ServerURL         string
+	RegistrationToken string
+	Name              string
+	Labels            []string
+	Capacity          int
+	StateFile         string
+	PollInterval      time.Duration
+	HeartbeatInterval time.Duration
+}
+
+// TaskUpdate 节点上报的任务状态
+type TaskUpdate struct {
+	Status       string `json:"status"`
+	DetectedTech string `json:"detected_tech,omitempty"`
+	SelectedTags string `json:"selected_tags,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+// state 节点注册后持久化到本地的凭据与签名密钥，避免重启后重复注册
+type state struct {
+	ServerURL  string `json:"server_url"`
+	AgentID    uint   `json:"agent_id"`
+	AgentToken string `json:"agent_token"`
+	PublicKey  string `json:"public_key"`
+	PrivateKey string `json:"private_key"`
+}
+
+// Agent 远程扫描节点
+type Agent struct {
+	opts    Options
+	client  *Client
+	state   state
+	running int32
+}
+
+// Run 解析命令行参数并以远程节点模式运行（vulnfusion agent ...）
+func Run(args []string) error {
+	if len(args) > 0 && args[0] == "sign" {
+		return runSign(args[1:])
+	}
+
+	fs := flag.NewFlagSet("agent", flag.ExitOnError)
+	configPath := fs.String("config", "config.yaml", "配置文件路径（用于 nuclei 模板目录等）")
+	server := fs.String("server", os.Getenv("VULNFUSION_SERVER"), "服务端地址，如 http://10.0.0.1:8080")
+	token := fs.String("token", os.Getenv("VULNFUSION_AGENT_TOKEN"), "服务端配置的节点注册令牌")
+	hostname, _ := os.Hostname()
+	name := fs.String("name", hostname, "节点名称")
+	labels := fs.String("labels", "", "节点标签，逗号分隔，如 dmz,internal-net")
+	capacity := fs.Int("capacity", 1, "最大并发任务数")
+	stateFile := fs.String("state", "./data/agent.json", "节点凭据保存路径")
+	poll := fs.Duration("poll", 10*time.Second, "领取任务的轮询间隔")
+	heartbeat := fs.Duration("heartbeat", 30*time.Second, "心跳间隔")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	log.InitLogger("agent", "info")
+	if _, err := os.Stat(*configPath); err == nil {
+		if err := config.LoadConfig(*configPath); err != nil {
+			return err
+		}
+	}
+	if err := scanner.InitNuclei(); err != nil {
+		return err
+	}
+
+	a, err := New(Options{
+		ServerURL:         *server,
+		RegistrationToken: *token,
+		Name:              *name,
+		Labels:            splitLabels(*labels),
+		Capacity:          *capacity,
+		StateFile:         *stateFile,
+		PollInterval:      *poll,
+		HeartbeatInterval: *heartbeat,
+	})
+	if err != nil {
+		return err
+	}
+
+	stop := make(chan struct{})
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sig
+		close(stop)
+	}()
+
+	a.Loop(stop)
+	return nil
+}
+
+// New 创建节点并完成注册（已有本地凭据时直接复用）
+func New(opts Options) (*Agent, error) {
+	if opts.ServerURL == "" {
+		return nil, errors.New("未指定服务端地址")
+	}
+	if opts.Capacity <= 0 {
+		opts.Capacity = 1
+	}
+
+	a := &Agent{opts: opts, client: NewClient(opts.ServerURL, "")}
+
+	if st, err := loadState(opts.StateFile); err == nil && st.ServerURL == a.client.ServerURL && st.AgentToken != "" && st.PrivateKey != "" {
+		a.state = *st
+		a.client.Token = st.AgentToken
+		log.Info("使用已保存的节点凭据，节点 ID %d", st.AgentID)
+		return a, nil
+	}
+
+	if opts.RegistrationToken == "" {
+		return nil, errors.New("首次运行需要提供注册令牌")
+	}
+	pub, priv, err := provenance.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	id, token, err := a.client.Register(opts.RegistrationToken, opts.Name, opts.Labels, opts.Capacity, pub)
+	if err != nil {
+		return nil, err
+	}
+	a.client.Token = token
+	a.state = state{ServerURL: a.client.ServerURL, AgentID: id, AgentToken: token, PublicKey: pub, PrivateKey: priv}
+	if err := saveState(opts.StateFile, a.state); err != nil {
+		log.Warn("保存节点凭据失败: %v", err)
+	}
+	log.Info("节点注册成功，节点 ID %d", id)
+	return a, nil
+}
+
+// Loop 持续心跳并领取任务，直到 stop 被关闭；返回前等待运行中的任务结束
+func (a *Agent) Loop(stop <-chan struct{}) {
+	var wg sync.WaitGroup
+	heartbeat := time.NewTicker(a.opts.HeartbeatInterval)
+	poll := time.NewTicker(a.opts.PollInterval)
+	defer heartbeat.Stop()
+	defer poll.Stop()
+
+	a.sendHeartbeat()
+	a.pullTasks(&wg)
+	for {
+		select {
+		case <-stop:
+			log.Info("节点停止领取新任务，等待运行中的任务结束")
+			wg.Wait()
+			return
+		case <-heartbeat.C:
+			a.sendHeartbeat()
+		case <-poll.C:
+			a.pullTasks(&wg)
+		}
+	}
+}
+
+// pullTasks 在仍有空闲容量时持续领取任务
+func (a *Agent) pullTasks(wg *sync.WaitGroup) {
+	for int(atomic.LoadInt32(&a.running)) < a.opts.Capacity {
+		task, err := a.client.PullTask()
+		if err != nil {
+			log.Warn("领取任务失败: %v", err)
+			return
+		}
+		if task == nil {
+			return
+		}
+
+		atomic.AddInt32(&a.running, 1)
+		wg.Add(1)
+		go func(t *models.Task) {
+			defer wg.Done()
+			defer atomic.AddInt32(&a.running, -1)
+			a.runTask(t)
+		}(task)
+	}
+}
+
+// runTask 在本地执行任务，边扫描边分批回传结果
+func (a *Agent) runTask(task *models.Task) {
+	log.Info("开始执行任务 %d，目标 %s", task.ID, task.Target)
+
+	options, err := scanner.PrepareTaskOptions(task)
+	update := TaskUpdate{DetectedTech: task.DetectedTech, SelectedTags: task.SelectedTags}
+	if errors.Is(err, scanner.ErrNoTemplateTags) {
+		update.Status = models.StatusDone
+		a.reportTask(task.ID, update)
+		return
+	}
+	if err != nil {
+		update.Status = models.StatusFailed
+		update.Error = err.Error()
+		a.reportTask(task.ID, update)
+		return
+	}
+
+	source := scanner.LocalProvenance()
+	sequence := 0
+	var batch []string
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		sequence++
+		signed, err := provenance.Sign(provenance.Batch{
+			AgentID:       a.state.AgentID,
+			TaskID:        task.ID,
+			Sequence:      sequence,
+			NucleiVersion: source.NucleiVersion,
+			TemplateHash:  source.TemplateHash,
+			Lines:         batch,
+		}, a.state.PrivateKey)
+		if err != nil {
+			log.Error("任务 %d 结果批次签名失败: %v", task.ID, err)
+		} else if err := a.client.UploadResults(task.ID, signed); err != nil {
+			log.Error("任务 %d 回传结果失败: %v", task.ID, err)
+		}
+		batch = nil
+	}
+
+	err = scanner.StreamScanTask(options, func(line []byte) {
+		batch = append(batch, string(line))
+		if len(batch) >= resultBatchSize {
+			flush()
+		}
+	})
+	flush()
+
+	if err != nil {
+		update.Status = models.StatusFailed
+		update.Error = err.Error()
+	} else {
+		update.Status = models.StatusDone
+	}
+	a.reportTask(task.ID, update)
+	log.Info("任务 %d 执行结束，状态 %s", task.ID, update.Status)
+}
+
+func (a *Agent) reportTask(taskID uint, update TaskUpdate) {
+	if err := a.client.UpdateTask(taskID, update); err != nil {
+		log.Error("上报任务 %d 状态失败: %v", taskID, err)
+	}
+}
+
+func (a *Agent) sendHeartbeat() {
+	running := int(atomic.LoadInt32(&a.running))
+	if err := a.client.Heartbeat(running, a.opts.Capacity, a.opts.Labels); err != nil {
+		log.Warn("心跳失败: %v", err)
+	}
+}
+
+// runSign 离线对 nuclei JSONL 结果文件签名，生成可通过 /tasks/{id}/import 导入的批次
+// 用法：vulnfusion agent sign -task 12 -in results.jsonl -out batch.json
+func runSign(args []string) error {
+	fs := flag.NewFlagSet("agent sign", flag.ExitOnError)
+	configPath := fs.String("config", "config.yaml", "配置文件路径（用于计算模板库摘要）")
+	stateFile := fs.String("state", "./data/agent.json", "节点凭据保存路径")
+	taskID := fs.Uint("task", 0, "结果所属任务 ID")
+	sequence := fs.Int("seq", 1, "批次序号，同一任务内不可重复")
+	input := fs.String("in", "", "nuclei JSONL 结果文件")
+	output := fs.String("out", "", "签名批次输出文件，默认输出到标准输出")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	log.InitLogger("agent", "info")
+	if _, err := os.Stat(*configPath); err == nil {
+		if err := config.LoadConfig(*configPath); err != nil {
+			return err
+		}
+	}
+	if *taskID == 0 || *input == "" {
+		return errors.New("需要指定 -task 与 -in")
+	}
+
+	st, err := loadState(*stateFile)
+	if err != nil || st.PrivateKey == "" {
+		return errors.New("未找到节点签名密钥，请先以节点模式完成注册")
+	}
+	raw, err := os.ReadFile(*input)
+	if err != nil {
+		return err
+	}
+
+	var lines []string
+	for _, l := range strings.Split(string(raw), "\n") {
+		if strings.TrimSpace(l) != "" {
+			lines = append(lines, l)
+		}
+	}
+
+	hash, _ := scanner.HashTemplateDir(config.GetNucleiTemplatePath())
+	signed, err := provenance.Sign(provenance.Batch{
+		AgentID:       st.AgentID,
+		TaskID:        *taskID,
+		Sequence:      *sequence,
+		NucleiVersion: scanner.DetectNucleiVersion(),
+		TemplateHash:  hash,
+		Lines:         lines,
+	}, st.PrivateKey)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(signed, "", "  ")
+	if err != nil {
+		return err
+	}
+	if *output == "" {
+		_, err = os.Stdout.Write(append(data, '\n'))
+		return err
+	}
+	return os.WriteFile(*output, data, 0644)
+}
+
+func splitLabels(raw string) []string {
+	var labels []string
+	for _, l := range strings.Split(raw, ",") {
+		if l = strings.TrimSpace(l); l != "" {
+			labels = append(labels, l)
+		}
+	}
+	return labels
+}
+
+func loadState(path string) (*state, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var st state
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
+
+func saveState(path string, st state) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
@@ -0,0 +1,124 @@
+package agent
+
+import (
+	"VulnFusion/internal/models"
+	"VulnFusion/internal/provenance"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Client 封装远程节点与服务端之间的 HTTP 通信
+type Client struct {
+	ServerURL string
+	Token     string
+	http      *http.Client
+}
+
+// NewClient 创建节点客户端
+func NewClient(serverURL, token string) *Client {
+	return &Client{
+		ServerURL: strings.TrimRight(serverURL, "/"),
+		Token:     token,
+		http:      &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Register 使用注册令牌向服务端注册节点，返回节点 ID 与节点令牌
+func (c *Client) Register(registrationToken, name string, labels []string, capacity int, publicKey string) (uint, string, error) {
+	var resp struct {
+		AgentID    uint   `json:"agent_id"`
+		AgentToken string `json:"agent_token"`
+	}
+	_, err := c.post("/api/v1/agent/register", map[string]interface{}{
+		"registration_token": registrationToken,
+		"name":               name,
+		"labels":             labels,
+		"capacity":           capacity,
+		"public_key":         publicKey,
+	}, &resp)
+	if err != nil {
+		return 0, "", err
+	}
+	return resp.AgentID, resp.AgentToken, nil
+}
+
+// Heartbeat 上报当前运行中的任务数与容量
+func (c *Client) Heartbeat(running, capacity int, labels []string) error {
+	_, err := c.post("/api/v1/agent/heartbeat", map[string]interface{}{
+		"running":  running,
+		"capacity": capacity,
+		"labels":   labels,
+	}, nil)
+	return err
+}
+
+// PullTask 领取一个待执行任务，无任务时返回 nil
+func (c *Client) PullTask() (*models.Task, error) {
+	var task models.Task
+	status, err := c.post("/api/v1/agent/tasks/pull", nil, &task)
+	if err != nil {
+		return nil, err
+	}
+	if status == http.StatusNoContent {
+		return nil, nil
+	}
+	return &task, nil
+}
+
+// UploadResults 回传一个签名结果批次
+func (c *Client) UploadResults(taskID uint, batch *provenance.SignedBatch) error {
+	_, err := c.post(fmt.Sprintf("/api/v1/agent/tasks/%d/results", taskID), batch, nil)
+	return err
+}
+
+// UpdateTask 上报任务状态
+func (c *Client) UpdateTask(taskID uint, update TaskUpdate) error {
+	_, err := c.post(fmt.Sprintf("/api/v1/agent/tasks/%d/status", taskID), update, nil)
+	return err
+}
+
+// post 发送 JSON 请求并解析响应，非 2xx 状态码视为错误
+func (c *Client) post(path string, body interface{}, out interface{}) (int, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.ServerURL+path, reader)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.Token != "" {
+		req.Header.Set("X-Agent-Token", c.Token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("服务端返回 %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if out != nil && resp.StatusCode != http.StatusNoContent && len(data) > 0 {
+		if err := json.Unmarshal(data, out); err != nil {
+			return resp.StatusCode, err
+		}
+	}
+	return resp.StatusCode, nil
+}
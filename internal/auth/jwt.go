@@ -1,115 +1,225 @@
 package auth
 
 import (
-	"VulnFusion/internal/config"
+	"VulnFusion/internal/log"
 	"crypto/rand"
 	"encoding/base64"
 	"errors"
+	"fmt"
 	"strconv"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
-	"sync"
 )
 
+// 令牌类型，写入 typ 声明
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+	TokenTypeMFA     = "mfa" // 密码验证通过后、二次验证前的挑战令牌
+	TokenTypeSSO     = "sso" // 单点登录回调后换取令牌的一次性票据
+)
+
+// 令牌受众，访问令牌与刷新令牌互不通用
+const (
+	AudienceAPI     = "vulnfusion-api"
+	AudienceRefresh = "vulnfusion-refresh"
+	AudienceMFA     = "vulnfusion-mfa"
+	AudienceSSO     = "vulnfusion-sso"
+)
+
+func init() {
+	// iat 等时间声明保留到微秒，按用户吊销时才能区分同一秒内吊销前后签发的令牌
+	jwt.TimePrecision = time.Microsecond
+}
+
 type CustomClaims struct {
 	UserID   uint   `json:"user_id"` // ✅ 改为 uint
-	Username string `json:"username"`
-	Role     string `json:"role"`
+	Username string `json:"username,omitempty"`
+	Role     string `json:"role,omitempty"`
+	Type     string `json:"typ"`           // 令牌类型：access / refresh
+	Family   string `json:"fam,omitempty"` // 令牌族：同一次登录签发及刷新得到的令牌共享
 	jwt.RegisteredClaims
-}
 
-var jwtSecret []byte
-var blacklist = make(map[string]int64)
-var blacklistMu sync.RWMutex
-var once sync.Once
+	// API 令牌鉴权时由中间件填充，不写入 JWT
+	TokenID uint     `json:"-"` // API 令牌 ID
+	Scopes  []string `json:"-"` // API 令牌的权限范围
+}
 
+// LoadOrGenerateJWTSecret 返回当前 HS256 签名密钥；未配置 jwt.secret 时使用密钥环中持久化的密钥，
+// 当前密钥为非对称算法时返回错误
 func LoadOrGenerateJWTSecret() ([]byte, error) {
-	var err error
-	once.Do(func() {
-		cfgSecret := config.GetJWTSecret()
-		if cfgSecret != "" {
-			jwtSecret = []byte(cfgSecret)
-		} else {
-			secret := make([]byte, 32)
-			_, e := rand.Read(secret)
-			if e != nil {
-				err = e
-				return
-			}
-			jwtSecret = secret
-		}
-	})
+	key, err := signingKey()
 	if err != nil {
 		return nil, err
 	}
-	return jwtSecret, nil
+	if key.Algorithm != AlgHS256 {
+		return nil, fmt.Errorf("当前签名算法为 %s，没有共享密钥", key.Algorithm)
+	}
+	return key.Material, nil
 }
 
 func GenerateToken(userID uint, username string, role string, duration time.Duration) (string, error) {
-	secret, err := LoadOrGenerateJWTSecret()
-	if err != nil {
-		return "", err
-	}
-	claims := CustomClaims{
+	return GenerateFamilyToken(userID, username, role, "", duration)
+}
+
+// GenerateFamilyToken 签发属于指定令牌族的访问令牌
+func GenerateFamilyToken(userID uint, username string, role string, family string, duration time.Duration) (string, error) {
+	token, _, err := signToken(CustomClaims{
 		UserID:   userID,
 		Username: username,
 		Role:     role,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(duration)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			ID:        generateJTI(),
-		},
-	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(secret)
+		Type:     TokenTypeAccess,
+		Family:   family,
+	}, AudienceAPI, duration)
+	return token, err
 }
 
 func GenerateRefreshToken(userID uint, duration time.Duration) (string, error) {
-	secret, err := LoadOrGenerateJWTSecret()
+	token, _, err := GenerateFamilyRefreshToken(userID, "", duration)
+	return token, err
+}
+
+// GenerateFamilyRefreshToken 签发属于指定令牌族的刷新令牌，同时返回其声明以便服务端保存
+func GenerateFamilyRefreshToken(userID uint, family string, duration time.Duration) (string, *CustomClaims, error) {
+	return signToken(CustomClaims{
+		UserID: userID,
+		Type:   TokenTypeRefresh,
+		Family: family,
+	}, AudienceRefresh, duration)
+}
+
+// GenerateMFAToken 签发二次验证挑战令牌，仅可用于完成登录，同时返回其声明以便使用后吊销
+func GenerateMFAToken(userID uint, duration time.Duration) (string, *CustomClaims, error) {
+	return signToken(CustomClaims{
+		UserID: userID,
+		Type:   TokenTypeMFA,
+	}, AudienceMFA, duration)
+}
+
+// GenerateSSOTicket 签发单点登录票据，前端凭票据换取访问令牌，避免令牌出现在跳转地址中
+func GenerateSSOTicket(userID uint, duration time.Duration) (string, *CustomClaims, error) {
+	return signToken(CustomClaims{
+		UserID: userID,
+		Type:   TokenTypeSSO,
+	}, AudienceSSO, duration)
+}
+
+// signToken 补全标准声明（sub、aud、exp、iat、jti）并签名
+func signToken(claims CustomClaims, audience string, duration time.Duration) (string, *CustomClaims, error) {
+	key, err := signingKey()
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
-	claims := jwt.RegisteredClaims{
-		Subject:   base64.StdEncoding.EncodeToString([]byte(strconv.Itoa(int(userID)))),
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(duration)),
-		IssuedAt:  jwt.NewNumericDate(time.Now()),
+	now := time.Now()
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		Subject:   strconv.FormatUint(uint64(claims.UserID), 10),
+		Audience:  jwt.ClaimStrings{audience},
+		ExpiresAt: jwt.NewNumericDate(now.Add(duration)),
+		IssuedAt:  jwt.NewNumericDate(now),
 		ID:        generateJTI(),
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(secret)
+	unsigned := jwt.NewWithClaims(key.method, claims)
+	unsigned.Header["kid"] = key.ID
+	token, err := unsigned.SignedString(key.signKey)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, &claims, nil
 }
 
+// ParseToken 解析访问令牌，拒绝刷新令牌及已吊销的令牌
 func ParseToken(tokenString string) (*CustomClaims, error) {
-	secret, err := LoadOrGenerateJWTSecret()
+	return parseToken(tokenString, TokenTypeAccess, AudienceAPI)
+}
+
+// ParseRefreshToken 解析刷新令牌，拒绝访问令牌及已吊销的令牌
+func ParseRefreshToken(tokenString string) (*CustomClaims, error) {
+	return parseToken(tokenString, TokenTypeRefresh, AudienceRefresh)
+}
+
+// ParseMFAToken 解析二次验证挑战令牌
+func ParseMFAToken(tokenString string) (*CustomClaims, error) {
+	return parseToken(tokenString, TokenTypeMFA, AudienceMFA)
+}
+
+// ParseSSOTicket 解析单点登录票据
+func ParseSSOTicket(tokenString string) (*CustomClaims, error) {
+	return parseToken(tokenString, TokenTypeSSO, AudienceSSO)
+}
+
+// StateClaims 短期状态令牌，携带只需本系统自行验证的数据（如 SSO 登录的 state、nonce 与 PKCE verifier）
+type StateClaims struct {
+	Data map[string]string `json:"data"`
+	jwt.RegisteredClaims
+}
+
+// SignState 签发状态令牌
+func SignState(audience string, data map[string]string, duration time.Duration) (string, error) {
+	key, err := signingKey()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	unsigned := jwt.NewWithClaims(key.method, StateClaims{
+		Data: data,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{audience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(duration)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	})
+	unsigned.Header["kid"] = key.ID
+	return unsigned.SignedString(key.signKey)
+}
+
+// ParseState 校验并解析状态令牌
+func ParseState(tokenString, audience string) (map[string]string, error) {
+	claims := &StateClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, keyFunc,
+		jwt.WithValidMethods([]string{AlgHS256, AlgEdDSA, AlgRS256}), jwt.WithAudience(audience))
+	if err != nil || !token.Valid {
+		return nil, errors.New("invalid or expired state")
+	}
+	return claims.Data, nil
+}
+
+// keyFunc 按 kid 选择验证密钥
+func keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, err := verificationKey(kid)
 	if err != nil {
 		return nil, err
 	}
+	// 算法必须与密钥一致，防止用公钥作为 HMAC 密钥伪造令牌
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, errors.New("签名算法与密钥不匹配")
+	}
+	return key.verifyKey, nil
+}
+
+func parseToken(tokenString, typ, audience string) (*CustomClaims, error) {
 	claims := &CustomClaims{}
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return secret, nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, claims, keyFunc,
+		jwt.WithValidMethods([]string{AlgHS256, AlgEdDSA, AlgRS256}), jwt.WithAudience(audience))
 	if err != nil || !token.Valid {
 		return nil, errors.New("invalid or expired token")
 	}
-	if IsTokenBlacklisted(claims.ID) {
+	if claims.Type != typ || claims.UserID == 0 {
+		return nil, errors.New("unexpected token type")
+	}
+	if revoked, err := IsRevoked(claims); revoked {
+		if err != nil {
+			log.Error("查询令牌吊销状态失败: %v", err)
+		}
 		return nil, errors.New("token is blacklisted")
 	}
 	return claims, nil
 }
 
-func IsTokenBlacklisted(jti string) bool {
-	blacklistMu.RLock()
-	expiry, exists := blacklist[jti]
-	blacklistMu.RUnlock()
-	return exists && time.Now().Unix() < expiry
-}
-
-func AddTokenToBlacklist(jti string, expiration time.Duration) error {
-	blacklistMu.Lock()
-	blacklist[jti] = time.Now().Add(expiration).Unix()
-	blacklistMu.Unlock()
-	return nil
+// NewFamilyID 生成新的令牌族 ID
+func NewFamilyID() string {
+	return generateJTI()
 }
 
 func generateJTI() string {
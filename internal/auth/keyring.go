@@ -0,0 +1,464 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"VulnFusion/internal/config"
+	"VulnFusion/internal/log"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// 支持的签名算法
+const (
+	AlgHS256 = "HS256" // HMAC-SHA256，密钥仅服务端持有
+	AlgEdDSA = "EdDSA" // Ed25519，公钥通过 JWKS 发布
+	AlgRS256 = "RS256" // RSA-SHA256（2048 位），公钥通过 JWKS 发布
+)
+
+// staticKeyID 配置了 jwt.secret 时固定密钥的 kid
+const staticKeyID = "static"
+
+// keyReloadInterval 重新加载密钥环的间隔；多实例部署时其他实例轮换的密钥最多延迟该时间用于签名
+const keyReloadInterval = time.Minute
+
+// unknownKidReloadInterval 遇到未知 kid 时重新加载密钥环的最小间隔，避免伪造 kid 反复查库
+const unknownKidReloadInterval = 5 * time.Second
+
+// SigningKey 一把签名密钥；RetiredAt 之后不再用于签名，宽限期内仍可验证其签发的令牌
+type SigningKey struct {
+	ID        string     // kid，写入 JWT 头
+	Algorithm string     // HS256 / EdDSA / RS256
+	Material  []byte     // HS256 为密钥本身，EdDSA / RS256 为 PKCS#8 DER 编码的私钥
+	CreatedAt time.Time  // 创建时间
+	RetiredAt *time.Time // 轮换下线时间，为空表示仍在使用
+}
+
+// SigningKeyInfo 密钥概要，不含密钥材料
+type SigningKeyInfo struct {
+	ID          string     `json:"kid"`
+	Algorithm   string     `json:"alg"`
+	CreatedAt   time.Time  `json:"created_at"`
+	RetiredAt   *time.Time `json:"retired_at,omitempty"`
+	VerifyUntil *time.Time `json:"verify_until,omitempty"` // 宽限期结束时间，之后其签发的令牌不再被接受
+	Active      bool       `json:"active"`                 // 当前用于签名
+}
+
+// KeyStore 签名密钥存储；默认存于进程内存，系统初始化后替换为数据库存储
+type KeyStore interface {
+	ListSigningKeys() ([]SigningKey, error)
+	SaveSigningKey(key SigningKey) error
+	RetireSigningKey(id string, at time.Time) error
+	// DeleteRetiredSigningKeys 删除在 before 之前下线的密钥
+	DeleteRetiredSigningKeys(before time.Time) (int64, error)
+}
+
+// memoryKeyStore 进程内密钥存储，未接入数据库时使用，重启后密钥丢失
+type memoryKeyStore struct {
+	mu   sync.RWMutex
+	keys []SigningKey
+}
+
+func (s *memoryKeyStore) ListSigningKeys() ([]SigningKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]SigningKey(nil), s.keys...), nil
+}
+
+func (s *memoryKeyStore) SaveSigningKey(key SigningKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = append(s.keys, key)
+	return nil
+}
+
+func (s *memoryKeyStore) RetireSigningKey(id string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.keys {
+		if s.keys[i].ID == id && s.keys[i].RetiredAt == nil {
+			s.keys[i].RetiredAt = &at
+		}
+	}
+	return nil
+}
+
+func (s *memoryKeyStore) DeleteRetiredSigningKeys(before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := s.keys[:0]
+	var n int64
+	for _, k := range s.keys {
+		if k.RetiredAt != nil && k.RetiredAt.Before(before) {
+			n++
+			continue
+		}
+		kept = append(kept, k)
+	}
+	s.keys = kept
+	return n, nil
+}
+
+// loadedKey 解析后的密钥
+type loadedKey struct {
+	SigningKey
+	method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+var (
+	keyMu        sync.RWMutex
+	keyStore     KeyStore = &memoryKeyStore{}
+	keyring      map[string]*loadedKey
+	activeKey    *loadedKey
+	keysLoadedAt time.Time
+)
+
+// SetKeyStore 替换密钥存储并清空已加载的密钥环
+func SetKeyStore(store KeyStore) {
+	keyMu.Lock()
+	defer keyMu.Unlock()
+	keyStore = store
+	keyring, activeKey, keysLoadedAt = nil, nil, time.Time{}
+}
+
+// InitSigningKeys 加载密钥环，存储中没有可用密钥时生成一把；系统启动时调用以尽早暴露错误
+func InitSigningKeys() error {
+	keyMu.Lock()
+	defer keyMu.Unlock()
+	return loadKeysLocked()
+}
+
+// HasStaticSigningKey 是否使用 jwt.secret 配置的固定密钥
+func HasStaticSigningKey() bool {
+	return config.GetJWTSecret() != ""
+}
+
+// keyGracePeriod 下线密钥的验证宽限期，默认为令牌最长有效期，保证轮换前签发的令牌可用到过期
+func keyGracePeriod() time.Duration {
+	if grace := config.JWTKeyGracePeriod(); grace > 0 {
+		return grace
+	}
+	return maxTokenLifetime()
+}
+
+// loadKeysLocked 从存储加载密钥环；调用方需持有写锁
+func loadKeysLocked() error {
+	if secret := config.GetJWTSecret(); secret != "" {
+		key, err := parseSigningKey(SigningKey{ID: staticKeyID, Algorithm: AlgHS256, Material: []byte(secret)})
+		if err != nil {
+			return err
+		}
+		keyring, activeKey, keysLoadedAt = map[string]*loadedKey{staticKeyID: key}, key, time.Now()
+		return nil
+	}
+
+	stored, err := keyStore.ListSigningKeys()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	grace := keyGracePeriod()
+	loaded := map[string]*loadedKey{}
+	var active *loadedKey
+	for _, k := range stored {
+		if k.RetiredAt != nil && !now.Before(k.RetiredAt.Add(grace)) {
+			continue
+		}
+		key, err := parseSigningKey(k)
+		if err != nil {
+			log.Warn("签名密钥 %s 解析失败，已忽略: %v", k.ID, err)
+			continue
+		}
+		loaded[k.ID] = key
+		if k.RetiredAt == nil && (active == nil || k.CreatedAt.After(active.CreatedAt)) {
+			active = key
+		}
+	}
+
+	if active == nil {
+		k, err := generateSigningKey(config.JWTAlgorithm())
+		if err != nil {
+			return err
+		}
+		if err := keyStore.SaveSigningKey(k); err != nil {
+			return err
+		}
+		if active, err = parseSigningKey(k); err != nil {
+			return err
+		}
+		loaded[k.ID] = active
+		log.Info("已生成 JWT 签名密钥 %s（%s）", k.ID, k.Algorithm)
+	}
+	keyring, activeKey, keysLoadedAt = loaded, active, now
+	return nil
+}
+
+// signingKey 返回当前签名密钥，密钥环过旧时重新加载
+func signingKey() (*loadedKey, error) {
+	keyMu.RLock()
+	key, loadedAt := activeKey, keysLoadedAt
+	keyMu.RUnlock()
+	if key != nil && time.Since(loadedAt) < keyReloadInterval {
+		return key, nil
+	}
+
+	keyMu.Lock()
+	defer keyMu.Unlock()
+	if activeKey != nil && time.Since(keysLoadedAt) < keyReloadInterval {
+		return activeKey, nil
+	}
+	if err := loadKeysLocked(); err != nil {
+		if activeKey == nil {
+			return nil, err
+		}
+		log.Warn("重新加载 JWT 签名密钥失败，继续使用已加载的密钥: %v", err)
+		keysLoadedAt = time.Now()
+	}
+	return activeKey, nil
+}
+
+// verificationKey 按 kid 查找验证密钥；未配置 jwt.secret 时不接受缺少 kid 的令牌
+func verificationKey(kid string) (*loadedKey, error) {
+	if _, err := signingKey(); err != nil {
+		return nil, err
+	}
+	if kid == "" && config.GetJWTSecret() != "" {
+		kid = staticKeyID
+	}
+
+	keyMu.RLock()
+	key, ok := keyring[kid]
+	loadedAt := keysLoadedAt
+	keyMu.RUnlock()
+	if ok {
+		return key, nil
+	}
+	if kid == "" || time.Since(loadedAt) < unknownKidReloadInterval {
+		return nil, errors.New("未知的签名密钥")
+	}
+
+	// 可能是其他实例刚轮换出的密钥
+	keyMu.Lock()
+	defer keyMu.Unlock()
+	if err := loadKeysLocked(); err != nil {
+		return nil, err
+	}
+	if key, ok := keyring[kid]; ok {
+		return key, nil
+	}
+	return nil, errors.New("未知的签名密钥")
+}
+
+// RotateSigningKey 生成新的签名密钥并下线当前密钥，下线密钥在宽限期内仍可验证；
+// algorithm 为空时使用 jwt.algorithm 配置
+func RotateSigningKey(algorithm string) (*SigningKeyInfo, error) {
+	if HasStaticSigningKey() {
+		return nil, errors.New("已配置 jwt.secret，固定密钥不支持轮换")
+	}
+	if algorithm == "" {
+		algorithm = config.JWTAlgorithm()
+	}
+	k, err := generateSigningKey(algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	keyMu.Lock()
+	defer keyMu.Unlock()
+	// 以存储为准下线全部在用密钥，避免多实例各自持有不同的当前密钥
+	stored, err := keyStore.ListSigningKeys()
+	if err != nil {
+		return nil, err
+	}
+	if err := keyStore.SaveSigningKey(k); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for _, old := range stored {
+		if old.RetiredAt == nil {
+			if err := keyStore.RetireSigningKey(old.ID, now); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := loadKeysLocked(); err != nil {
+		return nil, err
+	}
+	log.Info("JWT 签名密钥已轮换，新密钥 %s（%s）", k.ID, k.Algorithm)
+	return &SigningKeyInfo{ID: k.ID, Algorithm: k.Algorithm, CreatedAt: k.CreatedAt, Active: true}, nil
+}
+
+// ListSigningKeys 列出仍可用于验证的密钥，当前密钥在前
+func ListSigningKeys() ([]SigningKeyInfo, error) {
+	if _, err := signingKey(); err != nil {
+		return nil, err
+	}
+	keyMu.RLock()
+	defer keyMu.RUnlock()
+	grace := keyGracePeriod()
+	infos := make([]SigningKeyInfo, 0, len(keyring))
+	for _, k := range keyring {
+		info := SigningKeyInfo{ID: k.ID, Algorithm: k.Algorithm, CreatedAt: k.CreatedAt, RetiredAt: k.RetiredAt, Active: k == activeKey}
+		if k.RetiredAt != nil {
+			until := k.RetiredAt.Add(grace)
+			info.VerifyUntil = &until
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Active != infos[j].Active {
+			return infos[i].Active
+		}
+		return infos[i].CreatedAt.After(infos[j].CreatedAt)
+	})
+	return infos, nil
+}
+
+// PruneSigningKeys 删除宽限期已结束的下线密钥
+func PruneSigningKeys() (int64, error) {
+	if HasStaticSigningKey() {
+		return 0, nil
+	}
+	keyMu.RLock()
+	store := keyStore
+	keyMu.RUnlock()
+	return store.DeleteRetiredSigningKeys(time.Now().Add(-keyGracePeriod()))
+}
+
+// JWK 单个公钥（RFC 7517）
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	Crv string `json:"crv,omitempty"` // OKP
+	X   string `json:"x,omitempty"`   // OKP 公钥
+	N   string `json:"n,omitempty"`   // RSA 模数
+	E   string `json:"e,omitempty"`   // RSA 指数
+}
+
+// JWKS 公钥集合
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// PublicJWKS 返回可验证的非对称密钥的公钥集合；HS256 密钥不会发布
+func PublicJWKS() (JWKS, error) {
+	infos, err := ListSigningKeys()
+	if err != nil {
+		return JWKS{}, err
+	}
+	keyMu.RLock()
+	defer keyMu.RUnlock()
+	set := JWKS{Keys: []JWK{}}
+	for _, info := range infos {
+		k, ok := keyring[info.ID]
+		if !ok {
+			continue
+		}
+		switch pub := k.verifyKey.(type) {
+		case ed25519.PublicKey:
+			set.Keys = append(set.Keys, JWK{Kty: "OKP", Use: "sig", Alg: AlgEdDSA, Kid: k.ID, Crv: "Ed25519",
+				X: base64.RawURLEncoding.EncodeToString(pub)})
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, JWK{Kty: "RSA", Use: "sig", Alg: AlgRS256, Kid: k.ID,
+				N: base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())})
+		}
+	}
+	return set, nil
+}
+
+// NormalizeAlgorithm 规范化算法名，不支持时返回空字符串
+func NormalizeAlgorithm(algorithm string) string {
+	switch strings.ToUpper(algorithm) {
+	case "HS256":
+		return AlgHS256
+	case "EDDSA", "ED25519":
+		return AlgEdDSA
+	case "RS256":
+		return AlgRS256
+	}
+	return ""
+}
+
+// generateSigningKey 生成指定算法的新密钥
+func generateSigningKey(algorithm string) (SigningKey, error) {
+	alg := NormalizeAlgorithm(algorithm)
+	key := SigningKey{ID: generateKeyID(), Algorithm: alg, CreatedAt: time.Now()}
+	var err error
+	switch alg {
+	case AlgHS256:
+		key.Material = make([]byte, 32)
+		_, err = rand.Read(key.Material)
+	case AlgEdDSA:
+		var priv ed25519.PrivateKey
+		if _, priv, err = ed25519.GenerateKey(rand.Reader); err == nil {
+			key.Material, err = x509.MarshalPKCS8PrivateKey(priv)
+		}
+	case AlgRS256:
+		var priv *rsa.PrivateKey
+		if priv, err = rsa.GenerateKey(rand.Reader, 2048); err == nil {
+			key.Material, err = x509.MarshalPKCS8PrivateKey(priv)
+		}
+	default:
+		return SigningKey{}, fmt.Errorf("不支持的签名算法: %s", algorithm)
+	}
+	return key, err
+}
+
+// parseSigningKey 解析密钥材料
+func parseSigningKey(k SigningKey) (*loadedKey, error) {
+	key := &loadedKey{SigningKey: k}
+	switch k.Algorithm {
+	case AlgHS256:
+		if len(k.Material) == 0 {
+			return nil, errors.New("HS256 密钥为空")
+		}
+		key.method, key.signKey, key.verifyKey = jwt.SigningMethodHS256, k.Material, k.Material
+		return key, nil
+	case AlgEdDSA, AlgRS256:
+	default:
+		return nil, fmt.Errorf("不支持的签名算法: %s", k.Algorithm)
+	}
+
+	priv, err := x509.ParsePKCS8PrivateKey(k.Material)
+	if err != nil {
+		return nil, err
+	}
+	switch p := priv.(type) {
+	case ed25519.PrivateKey:
+		if k.Algorithm != AlgEdDSA {
+			break
+		}
+		key.method, key.signKey, key.verifyKey = jwt.SigningMethodEdDSA, p, p.Public()
+		return key, nil
+	case *rsa.PrivateKey:
+		if k.Algorithm != AlgRS256 {
+			break
+		}
+		key.method, key.signKey, key.verifyKey = jwt.SigningMethodRS256, p, &p.PublicKey
+		return key, nil
+	}
+	return nil, fmt.Errorf("密钥类型与算法 %s 不匹配", k.Algorithm)
+}
+
+// generateKeyID 生成随机 kid
+func generateKeyID() string {
+	raw := make([]byte, 9)
+	_, _ = rand.Read(raw)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
@@ -0,0 +1,234 @@
+package auth
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"VulnFusion/internal/config"
+)
+
+// LoginGuard 记录密码登录失败，按账号渐进延迟并临时锁定，按来源 IP 限制失败次数。
+// 账号按用户名记录，不区分用户是否存在，避免通过响应差异枚举用户
+type LoginGuard struct {
+	mu       sync.Mutex
+	accounts map[string]*loginRecord
+	ips      map[string]*loginRecord
+}
+
+type loginRecord struct {
+	failures    int
+	inflight    int       // 已放行、尚未得出结果的尝试数
+	first       time.Time // 统计窗口内首次失败时间
+	last        time.Time // 最近一次失败时间
+	lockedUntil time.Time // 账号锁定截止时间
+}
+
+// NewLoginGuard 创建登录失败记录器，策略取自 auth.login_protection 配置
+func NewLoginGuard() *LoginGuard {
+	return &LoginGuard{accounts: map[string]*loginRecord{}, ips: map[string]*loginRecord{}}
+}
+
+// Check 判断本次登录尝试是否放行：wait 大于 0 时须等待后重试，locked 表示账号已被锁定
+func (g *LoginGuard) Check(ip, username string, now time.Time) (wait time.Duration, locked bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.check(ip, username, now)
+}
+
+// Acquire 与 Check 相同，放行时在同一把锁内占用一次尝试名额，得出结果后须调用 Release 归还；
+// 并发的尝试因此按已放行次数计入失败上限，不会在任何失败被记录前全部通过检查
+func (g *LoginGuard) Acquire(ip, username string, now time.Time) (wait time.Duration, locked bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if wait, locked = g.check(ip, username, now); wait > 0 {
+		return wait, locked
+	}
+
+	key := accountKey(username)
+	rec := g.account(username, now)
+	if rec == nil {
+		rec = &loginRecord{first: now}
+		g.accounts[key] = rec
+	}
+	rec.inflight++
+	ipRec := g.ip(ip, now)
+	if ipRec == nil {
+		ipRec = &loginRecord{first: now}
+		g.ips[ip] = ipRec
+	}
+	ipRec.inflight++
+	return 0, false
+}
+
+// Release 归还 Acquire 占用的尝试名额，应在 Fail 或 Succeed 之后调用
+func (g *LoginGuard) Release(ip, username string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	key := accountKey(username)
+	if rec := g.accounts[key]; rec != nil && rec.inflight > 0 {
+		rec.inflight--
+		if rec.inflight == 0 && rec.failures == 0 && rec.lockedUntil.IsZero() {
+			delete(g.accounts, key)
+		}
+	}
+	if rec := g.ips[ip]; rec != nil && rec.inflight > 0 {
+		rec.inflight--
+	}
+}
+
+// check 计算须等待的时间；进行中的尝试按可能失败计入，账号已有失败时同一时间只放行一次尝试
+func (g *LoginGuard) check(ip, username string, now time.Time) (wait time.Duration, locked bool) {
+	if rec := g.account(username, now); rec != nil {
+		if now.Before(rec.lockedUntil) {
+			return rec.lockedUntil.Sub(now), true
+		}
+		pending := rec.failures + rec.inflight
+		switch {
+		case rec.failures > 0 && rec.inflight > 0, pending >= config.LoginMaxFailures():
+			wait = failureDelay(pending)
+		case rec.failures > 0:
+			wait = rec.last.Add(failureDelay(rec.failures)).Sub(now)
+		}
+	}
+	if rec := g.ip(ip, now); rec != nil {
+		switch max := config.LoginIPMaxFailures(); {
+		case rec.failures >= max:
+			if w := rec.first.Add(config.LoginIPWindow()).Sub(now); w > wait {
+				wait = w
+			}
+		case rec.failures+rec.inflight >= max:
+			if w := config.LoginBaseDelay(); w > wait {
+				wait = w
+			}
+		}
+	}
+	if wait < 0 {
+		wait = 0
+	}
+	return wait, false
+}
+
+// Fail 记录一次失败，返回账号连续失败次数；达到上限时锁定账号并返回锁定截止时间
+func (g *LoginGuard) Fail(ip, username string, now time.Time) (failures int, lockedUntil time.Time) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.prune(now)
+
+	rec := g.account(username, now)
+	if rec == nil {
+		rec = &loginRecord{first: now}
+		g.accounts[accountKey(username)] = rec
+	}
+	rec.failures++
+	rec.last = now
+	failures = rec.failures
+	if failures >= config.LoginMaxFailures() {
+		// 锁定后重新计数，解锁后再次连续失败才会重新锁定
+		rec.lockedUntil = now.Add(config.LoginLockoutDuration())
+		rec.failures = 0
+		lockedUntil = rec.lockedUntil
+	}
+
+	ipRec := g.ip(ip, now)
+	if ipRec == nil {
+		ipRec = &loginRecord{first: now}
+		g.ips[ip] = ipRec
+	} else if ipRec.failures == 0 {
+		ipRec.first = now
+	}
+	ipRec.failures++
+	ipRec.last = now
+	return failures, lockedUntil
+}
+
+// Succeed 密码验证通过后清除账号的失败记录；来源 IP 的失败次数保留到窗口结束
+func (g *LoginGuard) Succeed(username string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	key := accountKey(username)
+	if rec := g.accounts[key]; rec != nil && rec.inflight > 0 {
+		// 仍有进行中的尝试，保留其占用的名额
+		rec.failures, rec.lockedUntil = 0, time.Time{}
+		return
+	}
+	delete(g.accounts, key)
+}
+
+// Lock 将账号锁定到指定时间，用于恢复数据库中记录的锁定状态
+func (g *LoginGuard) Lock(username string, until time.Time) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	key := accountKey(username)
+	rec := g.accounts[key]
+	if rec == nil {
+		rec = &loginRecord{}
+		g.accounts[key] = rec
+	}
+	if until.After(rec.lockedUntil) {
+		rec.lockedUntil = until
+	}
+}
+
+// Unlock 解除账号锁定并清除失败记录
+func (g *LoginGuard) Unlock(username string) {
+	g.Succeed(username)
+}
+
+// account 返回账号的失败记录，长时间无失败的记录视为过期
+func (g *LoginGuard) account(username string, now time.Time) *loginRecord {
+	rec := g.accounts[accountKey(username)]
+	if rec == nil || accountExpired(rec, now) {
+		return nil
+	}
+	return rec
+}
+
+// ip 返回来源 IP 在当前统计窗口内的失败记录
+func (g *LoginGuard) ip(ip string, now time.Time) *loginRecord {
+	rec := g.ips[ip]
+	if rec == nil || now.Sub(rec.first) < config.LoginIPWindow() {
+		return rec
+	}
+	if rec.inflight == 0 {
+		return nil
+	}
+	// 窗口已结束但仍有进行中的尝试，保留占用名额并重新计数
+	rec.failures, rec.first = 0, now
+	return rec
+}
+
+// prune 清理过期记录，避免大量不同用户名或 IP 的尝试占用内存
+func (g *LoginGuard) prune(now time.Time) {
+	for key, rec := range g.accounts {
+		if accountExpired(rec, now) {
+			delete(g.accounts, key)
+		}
+	}
+	for key, rec := range g.ips {
+		if rec.inflight == 0 && now.Sub(rec.first) >= config.LoginIPWindow() {
+			delete(g.ips, key)
+		}
+	}
+}
+
+// accountExpired 锁定已结束且超过一个锁定时长没有新的失败时，连续失败次数清零
+func accountExpired(rec *loginRecord, now time.Time) bool {
+	return rec.inflight == 0 && !now.Before(rec.lockedUntil) && now.Sub(rec.last) >= config.LoginLockoutDuration()
+}
+
+// failureDelay 连续失败 n 次后须等待的时间：base_delay × 2^(n-1)，不超过 max_delay
+func failureDelay(n int) time.Duration {
+	delay, max := config.LoginBaseDelay(), config.LoginMaxDelay()
+	for i := 1; i < n && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	return delay
+}
+
+func accountKey(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
@@ -0,0 +1,233 @@
+package auth
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"VulnFusion/internal/config"
+	"VulnFusion/internal/log"
+)
+
+// 吊销记录类型
+const (
+	RevokeJTI    = "jti"    // 单个令牌
+	RevokeUser   = "user"   // 用户在吊销时间之前签发的全部令牌
+	RevokeFamily = "family" // 同一次登录派生的全部令牌
+)
+
+// revocationCacheTTL 未吊销结果的缓存时间；多实例共享数据库时，其他实例的吊销最多延迟该时间生效
+const revocationCacheTTL = 5 * time.Second
+
+// Revocation 一条吊销记录；ExpiresAt 之后被吊销的令牌已自然过期，记录可清理
+type Revocation struct {
+	Kind      string
+	Value     string
+	RevokedAt time.Time
+	ExpiresAt time.Time
+}
+
+// RevocationStore 吊销记录存储；默认存于进程内存，系统初始化后替换为数据库存储
+type RevocationStore interface {
+	SaveRevocation(r Revocation) error
+	// FindRevocation 查询吊销记录，不存在时返回 nil, nil
+	FindRevocation(kind, value string) (*Revocation, error)
+	DeleteExpiredRevocations(now time.Time) (int64, error)
+}
+
+// memoryRevocationStore 进程内吊销存储，未接入数据库时使用
+type memoryRevocationStore struct {
+	mu      sync.RWMutex
+	records map[string]Revocation
+}
+
+func (s *memoryRevocationStore) SaveRevocation(r Revocation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[r.Kind+":"+r.Value] = r
+	return nil
+}
+
+func (s *memoryRevocationStore) FindRevocation(kind, value string) (*Revocation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if r, ok := s.records[kind+":"+value]; ok {
+		return &r, nil
+	}
+	return nil, nil
+}
+
+func (s *memoryRevocationStore) DeleteExpiredRevocations(now time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int64
+	for key, r := range s.records {
+		if !now.Before(r.ExpiresAt) {
+			delete(s.records, key)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// revocationCacheEntry 缓存的查询结果，rev 为 nil 表示未吊销
+type revocationCacheEntry struct {
+	rev       *Revocation
+	checkedAt time.Time
+}
+
+var (
+	revocationMu    sync.RWMutex
+	revocationStore RevocationStore = &memoryRevocationStore{records: map[string]Revocation{}}
+	revocationCache                 = map[string]revocationCacheEntry{}
+)
+
+// SetRevocationStore 替换吊销存储并清空缓存
+func SetRevocationStore(store RevocationStore) {
+	revocationMu.Lock()
+	defer revocationMu.Unlock()
+	revocationStore = store
+	revocationCache = map[string]revocationCacheEntry{}
+}
+
+// revoke 写入吊销记录并同步缓存
+func revoke(r Revocation) error {
+	revocationMu.Lock()
+	defer revocationMu.Unlock()
+	if err := revocationStore.SaveRevocation(r); err != nil {
+		return err
+	}
+	revocationCache[r.Kind+":"+r.Value] = revocationCacheEntry{rev: &r, checkedAt: time.Now()}
+	return nil
+}
+
+// lookupRevocation 先查缓存再查存储；JTI 与令牌族的吊销不可变，缓存到记录过期，
+// 按用户吊销的时间可能被刷新，与未吊销的结果一样只缓存 revocationCacheTTL
+func lookupRevocation(kind, value string) (*Revocation, error) {
+	key := kind + ":" + value
+	now := time.Now()
+
+	revocationMu.RLock()
+	entry, ok := revocationCache[key]
+	store := revocationStore
+	revocationMu.RUnlock()
+	if ok {
+		if entry.rev != nil && kind != RevokeUser && now.Before(entry.rev.ExpiresAt) {
+			return entry.rev, nil
+		}
+		if now.Sub(entry.checkedAt) < revocationCacheTTL {
+			return entry.rev, nil
+		}
+	}
+
+	rev, err := store.FindRevocation(kind, value)
+	if err != nil {
+		return nil, err
+	}
+	revocationMu.Lock()
+	revocationCache[key] = revocationCacheEntry{rev: rev, checkedAt: now}
+	revocationMu.Unlock()
+	return rev, nil
+}
+
+// maxTokenLifetime 签发令牌的最长有效期，按用户吊销的记录保留该时长
+func maxTokenLifetime() time.Duration {
+	if ttl := config.DefaultRefreshTokenTTL(); ttl > config.DefaultTokenTTL() {
+		return ttl
+	}
+	return config.DefaultTokenTTL()
+}
+
+// AddTokenToBlacklist 吊销单个令牌，expiration 为令牌剩余有效期
+func AddTokenToBlacklist(jti string, expiration time.Duration) error {
+	now := time.Now()
+	return revoke(Revocation{Kind: RevokeJTI, Value: jti, RevokedAt: now, ExpiresAt: now.Add(expiration)})
+}
+
+// IsTokenBlacklisted 判断单个令牌是否已吊销；查询失败时按已吊销处理
+func IsTokenBlacklisted(jti string) bool {
+	rev, err := lookupRevocation(RevokeJTI, jti)
+	if err != nil {
+		log.Error("查询令牌吊销状态失败: %v", err)
+		return true
+	}
+	return rev != nil
+}
+
+// RevokeUserTokens 吊销用户当前已签发的全部令牌（退出所有设备），之后签发的令牌不受影响
+func RevokeUserTokens(userID uint) error {
+	now := time.Now()
+	return revoke(Revocation{Kind: RevokeUser, Value: strconv.FormatUint(uint64(userID), 10), RevokedAt: now, ExpiresAt: now.Add(maxTokenLifetime())})
+}
+
+// RevokeTokenFamily 吊销同一次登录派生的全部令牌
+func RevokeTokenFamily(family string) error {
+	if family == "" {
+		return errors.New("令牌族为空")
+	}
+	now := time.Now()
+	return revoke(Revocation{Kind: RevokeFamily, Value: family, RevokedAt: now, ExpiresAt: now.Add(maxTokenLifetime())})
+}
+
+// IsRevoked 判断令牌是否已按 JTI、令牌族或用户被吊销
+func IsRevoked(claims *CustomClaims) (bool, error) {
+	if claims.ID != "" {
+		if rev, err := lookupRevocation(RevokeJTI, claims.ID); err != nil || rev != nil {
+			return true, err
+		}
+	}
+	if claims.Family != "" {
+		if rev, err := lookupRevocation(RevokeFamily, claims.Family); err != nil || rev != nil {
+			return true, err
+		}
+	}
+	if claims.UserID != 0 {
+		rev, err := lookupRevocation(RevokeUser, strconv.FormatUint(uint64(claims.UserID), 10))
+		if err != nil {
+			return true, err
+		}
+		// iat 精度为微秒，不晚于吊销时间签发的令牌视为已吊销
+		if rev != nil && (claims.IssuedAt == nil || !claims.IssuedAt.After(rev.RevokedAt)) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// SweepRevocations 清理已过期的吊销记录与缓存
+func SweepRevocations() (int64, error) {
+	now := time.Now()
+	revocationMu.Lock()
+	defer revocationMu.Unlock()
+	for key, entry := range revocationCache {
+		if (entry.rev != nil && !now.Before(entry.rev.ExpiresAt)) || (entry.rev == nil && now.Sub(entry.checkedAt) >= revocationCacheTTL) {
+			delete(revocationCache, key)
+		}
+	}
+	return revocationStore.DeleteExpiredRevocations(now)
+}
+
+// StartRevocationSweeper 启动后台任务，定期清理过期的吊销记录与宽限期已结束的签名密钥
+func StartRevocationSweeper(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if n, err := PruneSigningKeys(); err != nil {
+				log.Warn("清理下线签名密钥失败: %v", err)
+			} else if n > 0 {
+				log.Info("已清理 %d 把下线的签名密钥", n)
+			}
+
+			n, err := SweepRevocations()
+			if err != nil {
+				log.Warn("清理过期吊销记录失败: %v", err)
+				continue
+			}
+			if n > 0 {
+				log.Info("已清理 %d 条过期令牌记录", n)
+			}
+		}
+	}()
+}
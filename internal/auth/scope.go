@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"net/http"
+	"strings"
+)
+
+// APITokenPrefix API 令牌前缀，用于与 JWT 区分
+const APITokenPrefix = "vf_"
+
+// Scopes 可授予 API 令牌的权限范围；write 包含同一资源的 read
+var Scopes = []string{
+	"tasks:read", "tasks:write",
+	"ci:read", "ci:write", // CI 门禁扫描：/ci/scan 发起与查询
+	"results:read", "results:write",
+	"assets:read", "assets:write",
+	"projects:read", "projects:write",
+	"scope:read", "scope:write",
+	"stats:read",
+	"webhooks:read", "webhooks:write",
+	"trackers:read", "trackers:write",
+	"user:read", "user:write",
+	"admin:read", "admin:write",
+}
+
+// readOnlyRoutes 使用非 GET 方法但只读的接口
+var readOnlyRoutes = map[string]bool{
+	"/api/v1/scope/check": true,
+}
+
+// IsValidScope 判断权限范围是否可授予
+func IsValidScope(scope string) bool {
+	for _, s := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// ScopeForRoute 按路由推导所需权限范围：资源取 /api/v1 后的第一段路径，GET 为 read，其余为 write
+func ScopeForRoute(method, fullPath string) string {
+	resource := strings.TrimPrefix(fullPath, "/api/v1/")
+	if i := strings.Index(resource, "/"); i >= 0 {
+		resource = resource[:i]
+	}
+	if method == http.MethodGet || method == http.MethodHead || readOnlyRoutes[fullPath] {
+		return resource + ":read"
+	}
+	return resource + ":write"
+}
+
+// IsAPIToken 判断凭据是否为 API 令牌
+func (c *CustomClaims) IsAPIToken() bool {
+	return c.TokenID != 0
+}
+
+// HasScope 判断凭据是否具备权限范围；登录会话（JWT）不受限制
+func (c *CustomClaims) HasScope(scope string) bool {
+	if !c.IsAPIToken() {
+		return true
+	}
+	resource, action, _ := strings.Cut(scope, ":")
+	for _, s := range c.Scopes {
+		if s == scope || (action == "read" && s == resource+":write") {
+			return true
+		}
+	}
+	return false
+}
@@ -1,11 +1,15 @@
 package bootstrap
 
 import (
+	"VulnFusion/internal/auth"
 	"VulnFusion/internal/config"
 	"VulnFusion/internal/db"
 	"VulnFusion/internal/log"
 	"VulnFusion/internal/models"
+	"VulnFusion/internal/notify"
+	"VulnFusion/internal/rbac"
 	"VulnFusion/internal/scanner"
+	"VulnFusion/internal/tracker"
 	"VulnFusion/internal/utils"
 )
 
@@ -22,6 +26,23 @@ func InitializeSystem() error {
 		return err
 	}
 
+	// 令牌吊销记录持久化到数据库，并定期清理过期记录
+	auth.SetRevocationStore(models.RevocationStore{})
+	auth.StartRevocationSweeper(config.RevocationSweepInterval())
+
+	// JWT 签名密钥持久化到数据库，重启后已签发的令牌仍然有效
+	auth.SetKeyStore(models.SigningKeyStore{})
+	if err := auth.InitSigningKeys(); err != nil {
+		log.Error("加载 JWT 签名密钥失败: %v", err)
+		return err
+	}
+
+	// 校验自定义角色配置
+	if err := rbac.Validate(); err != nil {
+		log.Error("角色配置无效: %v", err)
+		return err
+	}
+
 	// 初始化管理员账号
 	if err := InitializeAdmin(); err != nil {
 		log.Error("初始化管理员失败: %v", err)
@@ -34,15 +55,41 @@ func InitializeSystem() error {
 		return err
 	}
 
+	// 节点心跳超时后回收其运行中的任务
+	scanner.StartAgentWatchdog(config.AgentHeartbeatTimeout())
+
+	// 启动 Webhook 失败重试调度
+	notify.StartRetryScheduler(config.WebhookRetryInterval())
+
+	// 启动高危漏洞摘要邮件调度
+	notify.StartDigestScheduler()
+
+	// 启动工单状态同步
+	tracker.StartSyncScheduler(config.TrackerSyncInterval())
+
 	log.Info("系统初始化完成")
 	return nil
 }
 
+// InitializeDatabase 仅初始化日志与数据库，供命令行工具使用
+func InitializeDatabase() error {
+	log.InitLogger("dev", "info")
+
+	if _, err := db.InitDatabase(config.GetDBPath()); err != nil {
+		log.Error("数据库初始化失败: %v", err)
+		return err
+	}
+	return nil
+}
+
 // InitializeAdmin 检查是否存在管理员账号，不存在则创建默认账号
 func InitializeAdmin() error {
 	admin, err := models.GetUserByUsername("admin")
 	if err == nil && admin != nil {
 		log.Info("已存在管理员账号，无需初始化")
+		if utils.CheckPassword("admin123", admin.Password) {
+			log.Warn("管理员账号 admin 仍在使用默认密码，请立即修改")
+		}
 		return nil
 	}
 
@@ -0,0 +1,191 @@
+package ci
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"VulnFusion/internal/config"
+	"VulnFusion/internal/models"
+	"VulnFusion/internal/notify"
+)
+
+// 门禁结论
+const (
+	VerdictPass    = "pass"    // 没有违反策略的漏洞
+	VerdictFail    = "fail"    // 存在违反策略的漏洞
+	VerdictError   = "error"   // 扫描失败，无法判定
+	VerdictPending = "pending" // 扫描尚未完成
+)
+
+// 漏洞被豁免的原因
+const (
+	ReasonAllowed  = "allow_list"      // 模板 ID 在豁免名单中
+	ReasonAccepted = "accepted_risk"   // 已接受风险
+	ReasonBelow    = "below_threshold" // 低于失败等级
+)
+
+// Policy CI 门禁策略
+type Policy struct {
+	FailOn         string   `json:"fail_on"`         // 达到该等级（含）即失败
+	AllowTemplates []string `json:"allow_templates"` // 豁免的模板 ID
+	IgnoreAccepted bool     `json:"ignore_accepted"` // 忽略已接受风险的漏洞
+}
+
+// DefaultPolicy 返回配置文件中的默认策略
+func DefaultPolicy() Policy {
+	return Policy{
+		FailOn:         config.CIFailOn(),
+		AllowTemplates: config.Global.CI.AllowTemplates,
+		IgnoreAccepted: config.CIIgnoreAccepted(),
+	}
+}
+
+// Validate 校验策略
+func (p Policy) Validate() error {
+	if notify.SeverityRank(p.FailOn) < 0 {
+		return fmt.Errorf("不支持的失败等级: %s", p.FailOn)
+	}
+	return nil
+}
+
+// Encode 序列化策略，用于随任务保存
+func (p Policy) Encode() string {
+	data, _ := json.Marshal(p)
+	return string(data)
+}
+
+// DecodePolicy 解析随任务保存的策略
+func DecodePolicy(raw string) (Policy, error) {
+	var p Policy
+	err := json.Unmarshal([]byte(raw), &p)
+	return p, err
+}
+
+// Finding 参与判定的一条漏洞
+type Finding struct {
+	ResultID   uint    `json:"result_id"`
+	TemplateID string  `json:"template_id"`
+	Name       string  `json:"name"`
+	Severity   string  `json:"severity"`
+	Target     string  `json:"target"`
+	CVSSScore  float64 `json:"cvss_score,omitempty"`
+	Reason     string  `json:"reason,omitempty"` // 豁免原因，违反策略的漏洞为空
+}
+
+// Verdict CI 门禁判定结果
+type Verdict struct {
+	TaskID     uint           `json:"task_id"`
+	Target     string         `json:"target"`
+	TaskStatus string         `json:"task_status"`
+	Verdict    string         `json:"verdict"` // pass / fail / error / pending
+	Passed     bool           `json:"passed"`
+	Policy     Policy         `json:"policy"`
+	Total      int            `json:"total"`
+	Severity   map[string]int `json:"severity"`   // 各等级漏洞数
+	Violations []Finding      `json:"violations"` // 违反策略的漏洞
+	Ignored    []Finding      `json:"ignored"`    // 被豁免或低于阈值的漏洞
+}
+
+// Evaluate 加载任务结果并按策略判定；任务未结束时返回 pending
+func Evaluate(task *models.Task, p Policy) (*Verdict, error) {
+	if task.Status != models.StatusDone {
+		return Decide(task, nil, nil, p), nil
+	}
+	results, err := models.ListResultsByTaskID(task.ID)
+	if err != nil {
+		return nil, err
+	}
+	var accepted []models.Result
+	if p.IgnoreAccepted {
+		accepted, err = models.ListAcceptedResultsByAssetIDs(assetIDs(results))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return Decide(task, results, accepted, p), nil
+}
+
+// Decide 按策略判定扫描结果；accepted 为同一资产上已接受风险的历史结果，
+// 资产与模板 ID 均相同的漏洞视为已接受风险
+func Decide(task *models.Task, results, accepted []models.Result, p Policy) *Verdict {
+	v := &Verdict{
+		TaskID:     task.ID,
+		Target:     task.Target,
+		TaskStatus: task.Status,
+		Policy:     p,
+		Severity:   map[string]int{},
+		Violations: []Finding{},
+		Ignored:    []Finding{},
+	}
+	switch task.Status {
+	case models.StatusDone:
+	case models.StatusFailed:
+		v.Verdict = VerdictError
+		return v
+	default:
+		v.Verdict = VerdictPending
+		return v
+	}
+
+	allowed := map[string]bool{}
+	for _, id := range p.AllowTemplates {
+		allowed[strings.TrimSpace(id)] = true
+	}
+	acceptedKeys := map[string]bool{}
+	for _, r := range accepted {
+		acceptedKeys[acceptKey(r)] = true
+	}
+
+	threshold := notify.SeverityRank(p.FailOn)
+	for _, r := range results {
+		v.Total++
+		v.Severity[strings.ToLower(r.Severity)]++
+		f := Finding{
+			ResultID:   r.ID,
+			TemplateID: r.TemplateID,
+			Name:       r.Vulnerability,
+			Severity:   strings.ToLower(r.Severity),
+			Target:     r.Target,
+			CVSSScore:  r.CVSSScore,
+		}
+		switch {
+		case r.TemplateID != "" && allowed[r.TemplateID]:
+			f.Reason = ReasonAllowed
+		case p.IgnoreAccepted && (r.Status == models.ResultStatusAccepted || (r.AssetID != 0 && acceptedKeys[acceptKey(r)])):
+			f.Reason = ReasonAccepted
+		case notify.SeverityRank(r.Severity) < threshold:
+			f.Reason = ReasonBelow
+		}
+		if f.Reason == "" {
+			v.Violations = append(v.Violations, f)
+		} else {
+			v.Ignored = append(v.Ignored, f)
+		}
+	}
+
+	v.Passed = len(v.Violations) == 0
+	v.Verdict = VerdictPass
+	if !v.Passed {
+		v.Verdict = VerdictFail
+	}
+	return v
+}
+
+// acceptKey 已接受风险的匹配键：资产 + 模板 ID
+func acceptKey(r models.Result) string {
+	return fmt.Sprintf("%d|%s", r.AssetID, r.TemplateID)
+}
+
+// assetIDs 提取结果关联的资产 ID（去重）
+func assetIDs(results []models.Result) []uint {
+	seen := map[uint]bool{}
+	var ids []uint
+	for _, r := range results {
+		if r.AssetID != 0 && !seen[r.AssetID] {
+			seen[r.AssetID] = true
+			ids = append(ids, r.AssetID)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,261 @@
+package ci
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// junitTestSuites JUnit XML 根节点
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Body    string `xml:",chardata"`
+}
+
+// JUnit 将判定结果渲染为 JUnit XML：违反策略的漏洞为 failure，被豁免的漏洞为 skipped，
+// 没有漏洞时输出一条通过的用例，扫描失败时输出一条 error
+func JUnit(v *Verdict) ([]byte, error) {
+	suite := junitTestSuite{Name: fmt.Sprintf("VulnFusion CI gate #%d", v.TaskID)}
+	className := "vulnfusion." + v.Target
+
+	switch {
+	case v.Verdict == VerdictError || v.Verdict == VerdictPending:
+		suite.Errors = 1
+		suite.Cases = append(suite.Cases, junitTestCase{
+			Name:      "scan",
+			ClassName: className,
+			Error:     &junitMessage{Message: "扫描未成功完成，任务状态: " + v.TaskStatus, Type: v.Verdict},
+		})
+	case v.Total == 0:
+		suite.Cases = append(suite.Cases, junitTestCase{Name: "no findings", ClassName: className})
+	}
+
+	for _, f := range v.Violations {
+		suite.Failures++
+		suite.Cases = append(suite.Cases, junitTestCase{
+			Name:      caseName(f),
+			ClassName: className,
+			Failure: &junitMessage{
+				Message: fmt.Sprintf("%s 漏洞达到失败等级 %s", f.Severity, v.Policy.FailOn),
+				Type:    f.Severity,
+				Body:    f.Target,
+			},
+		})
+	}
+	for _, f := range v.Ignored {
+		tc := junitTestCase{Name: caseName(f), ClassName: className}
+		// 低于阈值的漏洞视为通过，豁免的漏洞标记为跳过
+		if f.Reason != ReasonBelow {
+			suite.Skipped++
+			tc.Skipped = &junitMessage{Message: f.Reason, Body: f.Target}
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+	suite.Tests = len(suite.Cases)
+
+	root := junitTestSuites{
+		Name:     "VulnFusion",
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Errors:   suite.Errors,
+		Skipped:  suite.Skipped,
+		Suites:   []junitTestSuite{suite},
+	}
+	data, err := xml.MarshalIndent(root, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+// caseName 用例名称：[等级] 模板 ID - 漏洞名称
+func caseName(f Finding) string {
+	id := f.TemplateID
+	if id == "" {
+		id = "unknown"
+	}
+	return fmt.Sprintf("[%s] %s - %s", strings.ToUpper(f.Severity), id, f.Name)
+}
+
+// sarifSchema SARIF 2.1.0 schema 地址
+const sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool       sarifTool              `json:"tool"`
+	Results    []sarifResult          `json:"results"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri,omitempty"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string                 `json:"id"`
+	Name             string                 `json:"name,omitempty"`
+	ShortDescription sarifText              `json:"shortDescription"`
+	Properties       map[string]interface{} `json:"properties,omitempty"`
+}
+
+type sarifText struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID       string                 `json:"ruleId"`
+	Level        string                 `json:"level"`
+	Message      sarifText              `json:"message"`
+	Locations    []sarifLocation        `json:"locations,omitempty"`
+	Suppressions []sarifSuppression     `json:"suppressions,omitempty"`
+	Properties   map[string]interface{} `json:"properties,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifSuppression struct {
+	Kind          string `json:"kind"`
+	Justification string `json:"justification,omitempty"`
+}
+
+// securitySeverity 未提供 CVSS 时各等级对应的 security-severity 分值
+var securitySeverity = map[string]float64{
+	"critical": 9.5,
+	"high":     8.0,
+	"medium":   5.5,
+	"low":      3.0,
+	"info":     0.0,
+}
+
+// SARIF 将判定结果渲染为 SARIF 2.1.0：违反策略的漏洞级别为 error，
+// 低于阈值的为 warning/note，被豁免的漏洞附带 suppressions
+func SARIF(v *Verdict) ([]byte, error) {
+	rules := map[string]sarifRule{}
+	var results []sarifResult
+
+	add := func(f Finding, violation bool) {
+		ruleID := f.TemplateID
+		if ruleID == "" {
+			ruleID = "unknown"
+		}
+		score := f.CVSSScore
+		if score == 0 {
+			score = securitySeverity[f.Severity]
+		}
+		if _, ok := rules[ruleID]; !ok {
+			rules[ruleID] = sarifRule{
+				ID:               ruleID,
+				Name:             f.Name,
+				ShortDescription: sarifText{Text: f.Name},
+				Properties: map[string]interface{}{
+					"security-severity": fmt.Sprintf("%.1f", score),
+					"tags":              []string{"security", f.Severity},
+				},
+			}
+		}
+
+		level := "note"
+		switch {
+		case violation:
+			level = "error"
+		case f.Severity == "high" || f.Severity == "critical" || f.Severity == "medium":
+			level = "warning"
+		}
+		r := sarifResult{
+			RuleID:     ruleID,
+			Level:      level,
+			Message:    sarifText{Text: fmt.Sprintf("[%s] %s: %s", strings.ToUpper(f.Severity), f.Name, f.Target)},
+			Properties: map[string]interface{}{"result_id": f.ResultID, "severity": f.Severity},
+		}
+		if f.Target != "" {
+			r.Locations = []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: f.Target}}}}
+		}
+		if f.Reason == ReasonAllowed || f.Reason == ReasonAccepted {
+			r.Suppressions = []sarifSuppression{{Kind: "external", Justification: f.Reason}}
+		}
+		results = append(results, r)
+	}
+	for _, f := range v.Violations {
+		add(f, true)
+	}
+	for _, f := range v.Ignored {
+		add(f, false)
+	}
+
+	ids := make([]string, 0, len(rules))
+	for id := range rules {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	driver := sarifDriver{Name: "VulnFusion", Rules: make([]sarifRule, 0, len(ids))}
+	for _, id := range ids {
+		driver.Rules = append(driver.Rules, rules[id])
+	}
+	if results == nil {
+		results = []sarifResult{}
+	}
+
+	return json.MarshalIndent(sarifLog{
+		Schema:  sarifSchema,
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool:    sarifTool{Driver: driver},
+			Results: results,
+			Properties: map[string]interface{}{
+				"task_id": v.TaskID,
+				"verdict": v.Verdict,
+			},
+		}},
+	}, "", "  ")
+}
@@ -4,6 +4,7 @@ import (
 	"gopkg.in/yaml.v3"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -12,12 +13,60 @@ type Config struct {
 	Port    int    `yaml:"port"`
 	Env     string `yaml:"env"`
 
+	TrustedProxies []string `yaml:"trusted_proxies"` // 可信反向代理的 IP 或 CIDR，只采信其转发的 X-Forwarded-For / X-Real-IP；为空时客户端地址始终取连接来源地址
+
 	JWT struct {
 		Secret          string        `yaml:"secret"`
 		AccessTokenTTL  time.Duration `yaml:"access_token_ttl"`
 		RefreshTokenTTL time.Duration `yaml:"refresh_token_ttl"`
+		SweepInterval   time.Duration `yaml:"sweep_interval"`   // 过期吊销记录清理间隔，默认 1 小时
+		Algorithm       string        `yaml:"algorithm"`        // 新生成密钥的签名算法：HS256（默认）/ EdDSA / RS256
+		KeyGracePeriod  time.Duration `yaml:"key_grace_period"` // 轮换下线的密钥继续用于验证的时长，默认与刷新令牌有效期一致
 	} `yaml:"jwt"`
 
+	// 二次验证（TOTP）配置
+	MFA struct {
+		Issuer       string        `yaml:"issuer"`        // 验证器应用中显示的发行方，默认 app_name
+		EnforceRoles []string      `yaml:"enforce_roles"` // 必须启用二次验证的角色，未启用的用户登录时须先完成绑定
+		ChallengeTTL time.Duration `yaml:"challenge_ttl"` // 密码验证通过后完成二次验证的时限，默认 5 分钟
+	} `yaml:"mfa"`
+
+	// OIDC 单点登录，本地密码登录仍然可用
+	OIDC struct {
+		RedirectBaseURL string         `yaml:"redirect_base_url"` // 对外访问地址，用于拼接回调地址，如 https://vulnfusion.example.com；为空时按请求推断
+		FrontendURL     string         `yaml:"frontend_url"`      // 登录完成后跳转的前端登录页，默认 /login
+		Providers       []OIDCProvider `yaml:"providers"`
+	} `yaml:"oidc"`
+
+	// 角色与权限，内置角色 admin / user / viewer / auditor / scanner-operator
+	RBAC struct {
+		Roles map[string][]string `yaml:"roles"` // 新增角色或覆盖内置角色的权限（admin 始终拥有全部权限）
+	} `yaml:"rbac"`
+
+	// 密码登录后端
+	Auth struct {
+		Backend string `yaml:"backend"` // local（默认）/ ldap
+
+		// 本地自助注册，注册用户始终获得最低权限角色（viewer）
+		Registration struct {
+			Mode      string        `yaml:"mode"`       // disabled（默认）/ invite（凭邀请码注册）/ approval（开放注册，管理员审批后才能登录）
+			InviteTTL time.Duration `yaml:"invite_ttl"` // 邀请码默认有效期，默认 7 天
+		} `yaml:"registration"`
+
+		// 登录防暴力破解：按账号渐进延迟并临时锁定，按来源 IP 限制失败次数
+		LoginProtection struct {
+			MaxFailures     int           `yaml:"max_failures"`     // 账号连续失败次数达到后临时锁定，默认 5
+			LockoutDuration time.Duration `yaml:"lockout_duration"` // 账号锁定时长，默认 15 分钟
+			IPMaxFailures   int           `yaml:"ip_max_failures"`  // 单个来源 IP 在统计窗口内允许的失败次数，默认 20
+			IPWindow        time.Duration `yaml:"ip_window"`        // 来源 IP 失败次数统计窗口，默认 15 分钟
+			BaseDelay       time.Duration `yaml:"base_delay"`       // 账号首次失败后须等待的时间，此后每次失败翻倍，默认 1 秒
+			MaxDelay        time.Duration `yaml:"max_delay"`        // 单次等待时间上限，默认 30 秒
+		} `yaml:"login_protection"`
+	} `yaml:"auth"`
+
+	// LDAP / Active Directory 认证，auth.backend 为 ldap 时启用
+	LDAP LDAPConfig `yaml:"ldap"`
+
 	Admin struct {
 		Username string `yaml:"username"`
 		Password string `yaml:"password"`
@@ -29,8 +78,114 @@ type Config struct {
 
 	// ✅ 新增 nuclei 配置
 	Nuclei struct {
-		TemplatePath string `yaml:"template_path"`
+		TemplatePath   string   `yaml:"template_path"`
+		TechDetectTags []string `yaml:"tech_detect_tags"` // 自动扫描时用于技术栈识别的模板标签
 	} `yaml:"nuclei"`
+
+	// 远程扫描节点配置
+	Agent struct {
+		RegistrationToken string        `yaml:"registration_token"` // 节点注册令牌，为空则禁止注册
+		HeartbeatTimeout  time.Duration `yaml:"heartbeat_timeout"`  // 超过该时长未心跳视为离线
+	} `yaml:"agent"`
+
+	// 扫描范围配置
+	Scope struct {
+		Enforcement  string `yaml:"enforcement"`   // reject：整体拒绝；strip：剔除越界目标后继续
+		RequireScope bool   `yaml:"require_scope"` // 为 true 时未配置扫描范围的用户或项目不能创建任务
+	} `yaml:"scope"`
+
+	// 风险评分配置
+	Risk struct {
+		SeverityScores     map[string]float64 `yaml:"severity_scores"`     // 各严重等级的基础分（0~10）
+		CVSSWeight         *float64           `yaml:"cvss_weight"`         // 同时存在 CVSS 时 CVSS 所占权重（0~1）
+		ExploitWeight      *float64           `yaml:"exploit_weight"`      // 利用可能性放大系数
+		CriticalityFactors map[string]float64 `yaml:"criticality_factors"` // 资产重要性系数
+	} `yaml:"risk"`
+
+	// Webhook 通知配置
+	Webhook struct {
+		MaxAttempts   int           `yaml:"max_attempts"`   // 单次投递最多尝试次数
+		Timeout       time.Duration `yaml:"timeout"`        // 单次请求超时
+		Workers       int           `yaml:"workers"`        // 并发投递的工作协程数
+		RetryInterval time.Duration `yaml:"retry_interval"` // 扫描待重试投递的间隔
+	} `yaml:"webhook"`
+
+	// 出站请求配置（Webhook、缺陷跟踪系统等用户配置的地址）
+	Outbound struct {
+		AllowedCIDRs []string `yaml:"allowed_cidrs"` // 允许访问的内网网段，默认禁止访问回环、内网与链路本地地址
+	} `yaml:"outbound"`
+
+	// SMTP 邮件配置，host 为空时不发送邮件
+	SMTP struct {
+		Host               string        `yaml:"host"`
+		Port               int           `yaml:"port"`
+		Username           string        `yaml:"username"`
+		Password           string        `yaml:"password"`
+		From               string        `yaml:"from"`                 // 发件人地址
+		Encryption         string        `yaml:"encryption"`           // none / starttls / tls
+		InsecureSkipVerify bool          `yaml:"insecure_skip_verify"` // 跳过证书校验（仅测试环境）
+		Timeout            time.Duration `yaml:"timeout"`
+	} `yaml:"smtp"`
+
+	// 漏洞摘要邮件配置
+	Digest struct {
+		Hour    *int   `yaml:"hour"`    // 每天发送的整点（本地时间），默认 8
+		Weekday string `yaml:"weekday"` // 周报发送日，默认 monday
+	} `yaml:"digest"`
+
+	// 缺陷跟踪系统配置
+	Tracker struct {
+		SyncInterval time.Duration `yaml:"sync_interval"` // 工单状态同步间隔，默认 30 分钟
+	} `yaml:"tracker"`
+
+	// CI 门禁默认策略，可在请求中覆盖
+	CI struct {
+		FailOn         string        `yaml:"fail_on"`         // 达到该等级即判定失败，默认 high
+		AllowTemplates []string      `yaml:"allow_templates"` // 豁免的模板 ID
+		IgnoreAccepted *bool         `yaml:"ignore_accepted"` // 忽略已接受风险的漏洞，默认 true
+		MaxWait        time.Duration `yaml:"max_wait"`        // 同步等待扫描完成的最长时间，默认 30 分钟
+	} `yaml:"ci"`
+}
+
+// OIDCProvider 单个 OIDC 身份提供方
+type OIDCProvider struct {
+	Name          string            `yaml:"name"`           // 标识，用于登录与回调地址
+	DisplayName   string            `yaml:"display_name"`   // 登录页按钮文字，默认同 name
+	Issuer        string            `yaml:"issuer"`         // 发行方地址，据此读取 /.well-known/openid-configuration
+	ClientID      string            `yaml:"client_id"`      // 客户端 ID
+	ClientSecret  string            `yaml:"client_secret"`  // 客户端密钥，公开客户端可留空（仅依赖 PKCE）
+	Scopes        []string          `yaml:"scopes"`         // 默认 openid profile email
+	UsernameClaim string            `yaml:"username_claim"` // 用户名声明，默认 preferred_username，缺失时依次取 email、sub
+	RoleClaim     string            `yaml:"role_claim"`     // 角色来源声明（如 groups），为空时不同步角色
+	RoleMapping   map[string]string `yaml:"role_mapping"`   // 声明值到本系统角色的映射
+	DefaultRole   string            `yaml:"default_role"`   // 未匹配映射时的角色，默认 user
+	AllowSignup   *bool             `yaml:"allow_signup"`   // 首次登录时自动创建用户，默认 true
+	LinkByEmail   bool              `yaml:"link_by_email"`  // 首次登录时按已验证邮箱关联已有本地用户
+	TrustMFA      bool              `yaml:"trust_mfa"`      // 身份提供方已强制多因素认证，单点登录时不再要求本系统的二次验证
+}
+
+// LDAPConfig LDAP / Active Directory 连接与映射配置
+type LDAPConfig struct {
+	URL                string            `yaml:"url"`                  // 目录地址，ldap://host:389 或 ldaps://host:636
+	StartTLS           bool              `yaml:"start_tls"`            // 在 ldap:// 连接上使用 StartTLS
+	InsecureSkipVerify bool              `yaml:"insecure_skip_verify"` // 跳过证书校验，仅用于测试环境
+	Timeout            time.Duration     `yaml:"timeout"`              // 连接与单次操作超时，默认 10 秒
+	BindDN             string            `yaml:"bind_dn"`              // 查询用户的服务账号，为空时按 user_dn_template 直接绑定
+	BindPassword       string            `yaml:"bind_password"`        // 服务账号密码
+	BaseDN             string            `yaml:"base_dn"`              // 用户搜索起点
+	UserFilter         string            `yaml:"user_filter"`          // 用户搜索过滤器，%s 为转义后的用户名，默认 (uid=%s)
+	UserDNTemplate     string            `yaml:"user_dn_template"`     // 未配置服务账号时的用户 DN 模板，如 uid=%s,ou=people,dc=example,dc=com
+	UsernameAttribute  string            `yaml:"username_attribute"`   // 用户名属性，默认 uid（AD 通常为 sAMAccountName）
+	EmailAttribute     string            `yaml:"email_attribute"`      // 邮箱属性，默认 mail
+	IDAttribute        string            `yaml:"id_attribute"`         // 不变标识属性（如 entryUUID、objectGUID），为空时使用 DN
+	GroupAttribute     string            `yaml:"group_attribute"`      // 用户记录上的组属性，默认 memberOf
+	GroupBaseDN        string            `yaml:"group_base_dn"`        // 组搜索起点，为空时只使用 group_attribute
+	GroupFilter        string            `yaml:"group_filter"`         // 组搜索过滤器，%s 为转义后的用户 DN，默认 (member=%s)
+	RoleMapping        map[string]string `yaml:"role_mapping"`         // 组 DN 或组 CN 到本系统角色的映射（不区分大小写）
+	DefaultRole        string            `yaml:"default_role"`         // 未匹配映射时的角色，默认 user
+	DenyUnmapped       bool              `yaml:"deny_unmapped"`        // 不属于任何映射组的用户禁止登录
+	LinkLocalUsers     bool              `yaml:"link_local_users"`     // 首次登录时关联同名本地用户，否则拒绝登录
+	LocalUsers         []string          `yaml:"local_users"`          // 仍使用本地密码登录的账号（如应急管理员）
 }
 
 var Global Config
@@ -60,6 +215,171 @@ func DefaultRefreshTokenTTL() time.Duration {
 	return 7 * 24 * time.Hour
 }
 
+// RevocationSweepInterval 返回过期吊销记录的清理间隔，默认 1 小时
+func RevocationSweepInterval() time.Duration {
+	if Global.JWT.SweepInterval > 0 {
+		return Global.JWT.SweepInterval
+	}
+	return time.Hour
+}
+
+// JWTAlgorithm 返回新生成签名密钥的算法，默认 HS256
+func JWTAlgorithm() string {
+	if Global.JWT.Algorithm != "" {
+		return Global.JWT.Algorithm
+	}
+	return "HS256"
+}
+
+// JWTKeyGracePeriod 返回下线签名密钥的验证宽限期，未配置时返回 0 由调用方取默认值
+func JWTKeyGracePeriod() time.Duration {
+	return Global.JWT.KeyGracePeriod
+}
+
+// MFAIssuer 返回 TOTP 发行方名称
+func MFAIssuer() string {
+	if Global.MFA.Issuer != "" {
+		return Global.MFA.Issuer
+	}
+	if Global.AppName != "" {
+		return Global.AppName
+	}
+	return "VulnFusion"
+}
+
+// MFARequired 判断该角色是否必须启用二次验证
+func MFARequired(role string) bool {
+	for _, r := range Global.MFA.EnforceRoles {
+		if strings.EqualFold(r, role) {
+			return true
+		}
+	}
+	return false
+}
+
+// MFAChallengeTTL 返回二次验证挑战令牌有效期，默认 5 分钟
+func MFAChallengeTTL() time.Duration {
+	if Global.MFA.ChallengeTTL > 0 {
+		return Global.MFA.ChallengeTTL
+	}
+	return 5 * time.Minute
+}
+
+// OIDCProviders 返回已配置的 OIDC 身份提供方
+func OIDCProviders() []OIDCProvider {
+	return Global.OIDC.Providers
+}
+
+// FindOIDCProvider 按名称查找 OIDC 身份提供方
+func FindOIDCProvider(name string) (OIDCProvider, bool) {
+	for _, p := range Global.OIDC.Providers {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return OIDCProvider{}, false
+}
+
+// OIDCFrontendURL 返回单点登录完成后跳转的前端地址，默认 /login
+func OIDCFrontendURL() string {
+	if Global.OIDC.FrontendURL != "" {
+		return Global.OIDC.FrontendURL
+	}
+	return "/login"
+}
+
+// RBACRoles 返回配置中自定义的角色权限
+func RBACRoles() map[string][]string {
+	return Global.RBAC.Roles
+}
+
+// LDAPEnabled 判断密码登录是否使用 LDAP 认证
+func LDAPEnabled() bool {
+	return strings.EqualFold(Global.Auth.Backend, "ldap")
+}
+
+// LDAPLocalUser 判断启用 LDAP 后该账号是否仍使用本地密码登录
+func LDAPLocalUser(username string) bool {
+	for _, u := range Global.LDAP.LocalUsers {
+		if u == username {
+			return true
+		}
+	}
+	return false
+}
+
+// 自助注册模式
+const (
+	RegistrationDisabled = "disabled"
+	RegistrationInvite   = "invite"
+	RegistrationApproval = "approval"
+)
+
+// RegistrationMode 返回自助注册模式，未配置或无法识别时关闭注册
+func RegistrationMode() string {
+	switch mode := strings.ToLower(Global.Auth.Registration.Mode); mode {
+	case RegistrationInvite, RegistrationApproval:
+		return mode
+	}
+	return RegistrationDisabled
+}
+
+// RegistrationInviteTTL 返回邀请码默认有效期，默认 7 天
+func RegistrationInviteTTL() time.Duration {
+	if Global.Auth.Registration.InviteTTL > 0 {
+		return Global.Auth.Registration.InviteTTL
+	}
+	return 7 * 24 * time.Hour
+}
+
+// LoginMaxFailures 返回账号锁定前允许的连续失败次数，默认 5
+func LoginMaxFailures() int {
+	if Global.Auth.LoginProtection.MaxFailures > 0 {
+		return Global.Auth.LoginProtection.MaxFailures
+	}
+	return 5
+}
+
+// LoginLockoutDuration 返回账号锁定时长，默认 15 分钟
+func LoginLockoutDuration() time.Duration {
+	if Global.Auth.LoginProtection.LockoutDuration > 0 {
+		return Global.Auth.LoginProtection.LockoutDuration
+	}
+	return 15 * time.Minute
+}
+
+// LoginIPMaxFailures 返回单个来源 IP 在统计窗口内允许的失败次数，默认 20
+func LoginIPMaxFailures() int {
+	if Global.Auth.LoginProtection.IPMaxFailures > 0 {
+		return Global.Auth.LoginProtection.IPMaxFailures
+	}
+	return 20
+}
+
+// LoginIPWindow 返回来源 IP 失败次数统计窗口，默认 15 分钟
+func LoginIPWindow() time.Duration {
+	if Global.Auth.LoginProtection.IPWindow > 0 {
+		return Global.Auth.LoginProtection.IPWindow
+	}
+	return 15 * time.Minute
+}
+
+// LoginBaseDelay 返回账号首次失败后的等待时间，默认 1 秒
+func LoginBaseDelay() time.Duration {
+	if Global.Auth.LoginProtection.BaseDelay > 0 {
+		return Global.Auth.LoginProtection.BaseDelay
+	}
+	return time.Second
+}
+
+// LoginMaxDelay 返回失败后单次等待时间上限，默认 30 秒
+func LoginMaxDelay() time.Duration {
+	if Global.Auth.LoginProtection.MaxDelay > 0 {
+		return Global.Auth.LoginProtection.MaxDelay
+	}
+	return 30 * time.Second
+}
+
 // GetJWTSecret 返回 JWT 密钥字符串
 func GetJWTSecret() string {
 	return Global.JWT.Secret
@@ -70,6 +390,11 @@ func GetDBPath() string {
 	return Global.Database.Path
 }
 
+// TrustedProxies 返回可信反向代理列表，默认不信任任何代理
+func TrustedProxies() []string {
+	return Global.TrustedProxies
+}
+
 func GetListenAddr() string {
 	return ":" + strconv.Itoa(Global.Port)
 }
@@ -77,3 +402,180 @@ func GetListenAddr() string {
 func GetNucleiTemplatePath() string {
 	return Global.Nuclei.TemplatePath
 }
+
+// GetTechDetectTags 返回技术栈识别所用的模板标签，默认使用 nuclei 官方的 tech 标签
+func GetTechDetectTags() []string {
+	if len(Global.Nuclei.TechDetectTags) > 0 {
+		return Global.Nuclei.TechDetectTags
+	}
+	return []string{"tech"}
+}
+
+// GetAgentRegistrationToken 返回远程节点注册令牌
+func GetAgentRegistrationToken() string {
+	return Global.Agent.RegistrationToken
+}
+
+// AgentHeartbeatTimeout 返回节点心跳超时时间
+func AgentHeartbeatTimeout() time.Duration {
+	if Global.Agent.HeartbeatTimeout > 0 {
+		return Global.Agent.HeartbeatTimeout
+	}
+	return 90 * time.Second
+}
+
+// GetScopeEnforcement 返回越界目标的处理方式，默认整体拒绝
+func GetScopeEnforcement() string {
+	if Global.Scope.Enforcement == "strip" {
+		return "strip"
+	}
+	return "reject"
+}
+
+// ScopeRequired 返回未配置扫描范围时是否拒绝创建任务，默认不限制
+func ScopeRequired() bool {
+	return Global.Scope.RequireScope
+}
+
+// GetRiskSeverityScores 返回各严重等级的基础分，未配置的等级使用默认值
+func GetRiskSeverityScores() map[string]float64 {
+	scores := map[string]float64{"critical": 9.5, "high": 7.5, "medium": 5, "low": 2.5, "info": 0, "unknown": 0}
+	for k, v := range Global.Risk.SeverityScores {
+		scores[k] = v
+	}
+	return scores
+}
+
+// RiskCVSSWeight 返回 CVSS 在基础分中的权重，默认 0.7
+func RiskCVSSWeight() float64 {
+	if w := Global.Risk.CVSSWeight; w != nil && *w >= 0 && *w <= 1 {
+		return *w
+	}
+	return 0.7
+}
+
+// RiskExploitWeight 返回利用可能性放大系数，默认 1（利用概率为 1 时风险分翻倍）
+func RiskExploitWeight() float64 {
+	if w := Global.Risk.ExploitWeight; w != nil && *w >= 0 {
+		return *w
+	}
+	return 1
+}
+
+// GetRiskCriticalityFactors 返回资产重要性系数，未配置的等级使用默认值
+func GetRiskCriticalityFactors() map[string]float64 {
+	factors := map[string]float64{"low": 0.8, "medium": 1, "high": 1.2, "critical": 1.5}
+	for k, v := range Global.Risk.CriticalityFactors {
+		factors[k] = v
+	}
+	return factors
+}
+
+// WebhookMaxAttempts 返回 Webhook 单次投递的最多尝试次数，默认 5
+func WebhookMaxAttempts() int {
+	if Global.Webhook.MaxAttempts > 0 {
+		return Global.Webhook.MaxAttempts
+	}
+	return 5
+}
+
+// WebhookTimeout 返回 Webhook 请求超时，默认 10 秒
+func WebhookTimeout() time.Duration {
+	if Global.Webhook.Timeout > 0 {
+		return Global.Webhook.Timeout
+	}
+	return 10 * time.Second
+}
+
+// WebhookWorkers 返回并发投递的工作协程数，默认 4
+func WebhookWorkers() int {
+	if Global.Webhook.Workers > 0 {
+		return Global.Webhook.Workers
+	}
+	return 4
+}
+
+// WebhookRetryInterval 返回扫描待重试投递的间隔，默认 10 秒
+func WebhookRetryInterval() time.Duration {
+	if Global.Webhook.RetryInterval > 0 {
+		return Global.Webhook.RetryInterval
+	}
+	return 10 * time.Second
+}
+
+// OutboundAllowedCIDRs 返回出站请求允许访问的内网网段
+func OutboundAllowedCIDRs() []string {
+	return Global.Outbound.AllowedCIDRs
+}
+
+// SMTPPort 返回 SMTP 端口，未配置时按加密方式取默认值
+func SMTPPort() int {
+	if Global.SMTP.Port > 0 {
+		return Global.SMTP.Port
+	}
+	switch Global.SMTP.Encryption {
+	case "tls":
+		return 465
+	case "starttls":
+		return 587
+	}
+	return 25
+}
+
+// SMTPTimeout 返回 SMTP 连接超时，默认 30 秒
+func SMTPTimeout() time.Duration {
+	if Global.SMTP.Timeout > 0 {
+		return Global.SMTP.Timeout
+	}
+	return 30 * time.Second
+}
+
+// DigestHour 返回摘要邮件发送的整点，默认 8 点
+func DigestHour() int {
+	if h := Global.Digest.Hour; h != nil && *h >= 0 && *h < 24 {
+		return *h
+	}
+	return 8
+}
+
+// DigestWeekday 返回周报发送日，默认周一
+func DigestWeekday() time.Weekday {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(d.String(), Global.Digest.Weekday) {
+			return d
+		}
+	}
+	return time.Monday
+}
+
+// TrackerSyncInterval 返回工单状态同步间隔，默认 30 分钟
+func TrackerSyncInterval() time.Duration {
+	if Global.Tracker.SyncInterval > 0 {
+		return Global.Tracker.SyncInterval
+	}
+	return 30 * time.Minute
+}
+
+// CIFailOn 返回 CI 门禁的失败等级，默认 high
+func CIFailOn() string {
+	if Global.CI.FailOn != "" {
+		return Global.CI.FailOn
+	}
+	return "high"
+}
+
+// CIIgnoreAccepted 返回 CI 门禁是否忽略已接受风险的漏洞，默认 true
+func CIIgnoreAccepted() bool {
+	if Global.CI.IgnoreAccepted != nil {
+		return *Global.CI.IgnoreAccepted
+	}
+	return true
+}
+
+// CIMaxWait 返回 CI 扫描同步等待的最长时间，默认 30 分钟
+func CIMaxWait() time.Duration {
+	if Global.CI.MaxWait > 0 {
+		return Global.CI.MaxWait
+	}
+	return 30 * time.Minute
+}
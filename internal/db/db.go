@@ -60,6 +60,27 @@ func AutoRebuildModels(db *gorm.DB) error {
 		&User{},
 		&Task{},
 		&Result{},
+		&Agent{},
+		&ResultBatch{},
+		&Scope{},
+		&ScopeRule{},
+		&AuditLog{},
+		&Project{},
+		&ProjectMember{},
+		&Asset{},
+		&ExploitLikelihood{},
+		&CVEEntry{},
+		&CWEEntry{},
+		&Webhook{},
+		&WebhookDelivery{},
+		&TrackerConnector{},
+		&APIToken{},
+		&TokenRevocation{},
+		&RefreshToken{},
+		&Session{},
+		&SigningKey{},
+		&RecoveryCode{},
+		&Invite{},
 	}
 
 	for _, model := range modelsToCheck {
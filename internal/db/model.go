@@ -5,8 +5,25 @@ import "time"
 type User struct {
 	ID       uint   `gorm:"primaryKey"`
 	Username string `gorm:"unique;not null"`
-	Password string `gorm:"not null"`
-	Role     string `gorm:"default:user"` // user / admin
+	Password string `gorm:"not null" json:"-"` // 密码哈希，不随接口返回
+	Role     string `gorm:"default:user"`      // 角色：admin / user / viewer / auditor / scanner-operator 或 rbac.roles 中的自定义角色
+
+	Email           string     // 通知邮箱
+	NotifyTaskEmail bool       // 任务结束时发送邮件
+	Digest          string     // 高危漏洞摘要频率：空（关闭）/ daily / weekly
+	DigestSentAt    *time.Time // 最近一次发送摘要的时间
+
+	TOTPSecret      string `json:"-"` // TOTP 密钥，未启用时为待确认的密钥
+	TOTPEnabled     bool   // 是否已启用二次验证
+	TOTPLastCounter int64  `json:"-"` // 最近一次使用的验证码时间步，防止验证码重放
+
+	AuthProvider string `gorm:"index"` // 认证来源：空为本地账号，否则为 OIDC 身份提供方名称
+	ExternalID   string `gorm:"index"` // 身份提供方中的用户标识（sub）
+
+	Status string `gorm:"index"` // 账号状态：空为正常，pending 为自助注册后等待管理员审批
+
+	FailedLogins int        // 连续登录失败次数，登录成功或管理员解锁后清零
+	LockedUntil  *time.Time // 连续失败过多后的锁定截止时间
 }
 
 type Task struct {
@@ -16,6 +33,17 @@ type Task struct {
 	Template  string    `gorm:"not null"`        // nuclei 模板名称
 	CreatedAt time.Time `gorm:"autoCreateTime"`  // 创建时间
 	Status    string    `gorm:"default:pending"` // 状态：pending、running、done、failed
+
+	Mode         string `gorm:"default:standard"` // 扫描模式：standard、auto
+	DetectedTech string `gorm:"type:text"`        // 自动模式下识别出的技术栈
+	SelectedTags string `gorm:"type:text"`        // 自动模式下选中的模板标签
+
+	AgentLabel string // 指定由带有该标签的远程节点执行，为空则由服务端执行
+	AgentID    uint   // 实际领取任务的远程节点 ID
+
+	ProjectID uint `gorm:"index"` // 所属项目 ID，为 0 表示个人任务
+
+	CIPolicy string `gorm:"type:text"` // CI 门禁策略（JSON），非 CI 扫描任务为空
 }
 
 type Result struct {
@@ -25,5 +53,278 @@ type Result struct {
 	Vulnerability string    `gorm:"not null"`       // 漏洞名称或标识
 	Severity      string    `gorm:"default:medium"` // 风险等级：low / medium / high / critical
 	Detail        string    `gorm:"type:text"`      // 详细信息（原始输出或解析后的内容）
-	Timestamp     time.Time `gorm:"autoCreateTime"` // 记录时间
+	Timestamp     time.Time `gorm:"autoCreateTime"` // 记录时间（首次发现时间）
+	LastSeen      time.Time // 最近一次被检出的时间
+
+	// 结果来源信息
+	AgentID       uint   // 回传结果的远程节点 ID
+	BatchID       uint   // 签名批次 ID
+	NucleiVersion string // 执行扫描的 nuclei 版本
+	TemplateHash  string // 模板库摘要
+
+	// 资产与处置信息
+	Host     string     // nuclei 输出中的主机
+	IP       string     // nuclei 输出中的 IP
+	AssetID  uint       `gorm:"index"`        // 关联资产 ID
+	Status   string     `gorm:"default:open"` // 处置状态
+	ClosedAt *time.Time // 关闭时间
+
+	// 风险评分信息
+	TemplateID   string  // nuclei 模板 ID
+	CVSSScore    float64 // CVSS 基础分
+	CVSSVector   string  // CVSS 向量
+	CVEID        string  // 关联 CVE
+	CWEID        string  // 关联 CWE
+	ExploitScore float64 // 利用概率
+	RiskScore    float64 `gorm:"index"` // 综合风险分
+
+	// 工单信息
+	TicketConnectorID uint   // 创建工单的连接器 ID
+	TicketKey         string // 工单编号
+	TicketURL         string // 工单链接
+	TicketStatus      string // 工单状态
+}
+
+type Asset struct {
+	ID           uint      `gorm:"primaryKey"`
+	UserID       uint      `gorm:"uniqueIndex:idx_asset_host"`          // 个人资产所属用户
+	ProjectID    uint      `gorm:"uniqueIndex:idx_asset_host"`          // 所属项目
+	Host         string    `gorm:"not null;uniqueIndex:idx_asset_host"` // 主机名或 IP
+	IP           string    // 最近一次解析到的 IP
+	Ports        string    // 已发现端口
+	Endpoints    string    `gorm:"type:text"` // 已发现的服务地址
+	Technologies string    `gorm:"type:text"` // 技术栈
+	Owner        string    // 资产负责人
+	Tags         string    // 资产标签
+	Criticality  string    `gorm:"default:medium"` // 重要性
+	FirstSeen    time.Time // 首次发现时间
+	LastSeen     time.Time // 最近出现时间
+}
+
+type Agent struct {
+	ID            uint      `gorm:"primaryKey"`
+	Name          string    `gorm:"not null"`             // 节点名称
+	TokenHash     string    `gorm:"uniqueIndex;not null"` // 节点访问令牌摘要
+	Labels        string    `gorm:"type:text"`            // 节点标签（逗号分隔）
+	Capacity      int       `gorm:"default:1"`            // 最大并发任务数
+	Running       int       `gorm:"default:0"`            // 当前运行中的任务数
+	LastHeartbeat time.Time // 最近一次心跳时间
+	PublicKey     string    // 节点结果签名公钥（ed25519，base64）
+	CreatedAt     time.Time `gorm:"autoCreateTime"`
+}
+
+type ResultBatch struct {
+	ID            uint      `gorm:"primaryKey"`
+	TaskID        uint      `gorm:"not null;uniqueIndex:idx_batch_seq"` // 所属任务 ID
+	AgentID       uint      `gorm:"not null;uniqueIndex:idx_batch_seq"` // 签名节点 ID
+	Sequence      int       `gorm:"uniqueIndex:idx_batch_seq"`          // 批次序号
+	Source        string    // 来源：agent / import
+	NucleiVersion string    // nuclei 版本
+	TemplateHash  string    // 模板库摘要
+	PayloadHash   string    `gorm:"uniqueIndex"` // payload 摘要
+	Payload       string    `gorm:"type:text"`   // 原始签名 payload
+	Signature     string    `gorm:"type:text"`   // ed25519 签名
+	PublicKey     string    // 签名时节点登记的公钥
+	ReceivedAt    time.Time `gorm:"autoCreateTime"`
+}
+
+type Scope struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"index"` // 所属用户
+	ProjectID uint      `gorm:"index"` // 所属项目
+	Name      string    // 范围名称
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+type ScopeRule struct {
+	ID      uint   `gorm:"primaryKey"`
+	ScopeID uint   `gorm:"index;not null"` // 所属范围
+	Action  string `gorm:"not null"`       // include / exclude
+	Type    string `gorm:"not null"`       // domain / cidr / url / port
+	Value   string `gorm:"not null"`       // 规则值
+}
+
+type AuditLog struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"index"` // 操作用户 ID
+	Username  string    // 操作用户名
+	Action    string    `gorm:"index"` // 事件类型
+	Resource  string    // 相关资源
+	Detail    string    `gorm:"type:text"` // 事件详情
+	IP        string    // 来源 IP
+	CreatedAt time.Time `gorm:"autoCreateTime;index"`
+}
+
+type Project struct {
+	ID          uint      `gorm:"primaryKey"`
+	Name        string    `gorm:"not null"`  // 项目名称
+	Description string    `gorm:"type:text"` // 项目描述
+	CreatedBy   uint      // 创建人
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+
+	Digest       string     // 项目高危漏洞摘要频率：空（关闭）/ daily / weekly
+	DigestEmails string     // 项目摘要收件人（逗号分隔）
+	DigestSentAt *time.Time // 最近一次发送摘要的时间
+}
+
+type ProjectMember struct {
+	ID        uint      `gorm:"primaryKey"`
+	ProjectID uint      `gorm:"not null;uniqueIndex:idx_project_member"` // 所属项目
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_project_member"` // 成员用户 ID
+	Role      string    `gorm:"not null"`                                // owner / editor / viewer
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+type ExploitLikelihood struct {
+	CVEID      string    `gorm:"primaryKey"` // CVE 编号
+	Score      float64   // 利用概率
+	Percentile float64   // 百分位
+	UpdatedAt  time.Time `gorm:"autoUpdateTime"`
+}
+
+type CVEEntry struct {
+	ID          string    `gorm:"primaryKey"`          // CVE 编号
+	Description string    `gorm:"type:text"`           // 描述
+	CVSS3Score  float64   `gorm:"column:cvss3_score"`  // CVSS v3 基础分
+	CVSS3Vector string    `gorm:"column:cvss3_vector"` // CVSS v3 向量
+	CVSS2Score  float64   `gorm:"column:cvss2_score"`  // CVSS v2 基础分
+	CVSS2Vector string    `gorm:"column:cvss2_vector"` // CVSS v2 向量
+	CWEIDs      string    // 关联 CWE
+	CPEs        string    `gorm:"column:cpes;type:text"` // 受影响 CPE
+	References  string    `gorm:"type:text"`             // 参考链接
+	PublishedAt time.Time // 发布时间
+	ModifiedAt  time.Time // 最近修改时间
+}
+
+type CWEEntry struct {
+	ID          string `gorm:"primaryKey"` // CWE 编号
+	Name        string // 名称
+	Abstraction string // 抽象层级
+	Description string `gorm:"type:text"` // 描述
+}
+
+type Webhook struct {
+	ID          uint      `gorm:"primaryKey"`
+	UserID      uint      `gorm:"index"`    // 创建人
+	Name        string    `gorm:"not null"` // 名称
+	URL         string    `gorm:"not null"` // 投递地址
+	Secret      string    // HMAC 签名密钥
+	Events      string    // 订阅的事件
+	MinSeverity string    // 最低严重等级
+	Global      bool      // 接收全部任务的事件
+	Format      string    // 消息格式
+	QuietStart  string    // 免打扰开始时间
+	QuietEnd    string    // 免打扰结束时间
+	Enabled     bool      `gorm:"default:true"`
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+}
+
+type WebhookDelivery struct {
+	ID            uint       `gorm:"primaryKey"`
+	WebhookID     uint       `gorm:"index"` // 所属 Webhook
+	EventID       string     // 事件 ID
+	Event         string     // 事件类型
+	Payload       string     `gorm:"type:text"` // 投递内容
+	StatusCode    int        // 最近一次响应状态码
+	Attempts      int        // 已尝试次数
+	Success       bool       // 是否投递成功
+	Error         string     `gorm:"type:text"` // 最近一次失败原因
+	CreatedAt     time.Time  `gorm:"autoCreateTime"`
+	DeliveredAt   *time.Time // 投递成功时间
+	MaxAttempts   int        // 最多尝试次数
+	NextAttemptAt *time.Time `gorm:"index"` // 下次重试时间，为空表示已成功或不再重试
+}
+
+type TrackerConnector struct {
+	ID           uint      `gorm:"primaryKey"`
+	UserID       uint      `gorm:"index"` // 创建人
+	ProjectID    uint      `gorm:"index"` // 所属项目
+	Name         string    `gorm:"not null"`
+	Kind         string    `gorm:"not null"` // jira / gitlab / github
+	BaseURL      string    // 服务地址
+	Project      string    // 目标项目
+	Username     string    // Jira 账号
+	Token        string    // 访问令牌
+	IssueType    string    // Jira 问题类型
+	FieldMapping string    `gorm:"type:text"` // 字段映射（JSON）
+	Enabled      bool      `gorm:"default:true"`
+	CreatedAt    time.Time `gorm:"autoCreateTime"`
+}
+
+type APIToken struct {
+	ID         uint       `gorm:"primaryKey"`
+	UserID     uint       `gorm:"index"`    // 所属用户
+	Name       string     `gorm:"not null"` // 名称
+	Prefix     string     // 令牌前缀
+	TokenHash  string     `gorm:"uniqueIndex;not null"` // 令牌 SHA-256 摘要
+	Scopes     string     // 授权范围（空格分隔）
+	ExpiresAt  *time.Time // 过期时间
+	LastUsedAt *time.Time // 最近使用时间
+	LastUsedIP string     // 最近使用来源 IP
+	RevokedAt  *time.Time // 吊销时间
+	CreatedAt  time.Time  `gorm:"autoCreateTime"`
+}
+
+type TokenRevocation struct {
+	ID        uint      `gorm:"primaryKey"`
+	Kind      string    `gorm:"uniqueIndex:idx_revocation_key;not null"` // 吊销类型：jti / user / family
+	Value     string    `gorm:"uniqueIndex:idx_revocation_key;not null"` // JTI、用户 ID 或令牌族 ID
+	RevokedAt time.Time // 吊销时间
+	ExpiresAt time.Time `gorm:"index"` // 记录过期时间
+}
+
+type RefreshToken struct {
+	ID        uint       `gorm:"primaryKey"`
+	UserID    uint       `gorm:"index"`                // 所属用户
+	Family    string     `gorm:"index;not null"`       // 令牌族
+	JTI       string     `gorm:"uniqueIndex;not null"` // 令牌 ID
+	ParentJTI string     // 轮换前的令牌 ID
+	ExpiresAt time.Time  `gorm:"index"`
+	RotatedAt *time.Time // 已轮换时间
+	RevokedAt *time.Time // 吊销时间
+	CreatedAt time.Time  `gorm:"autoCreateTime"`
+}
+
+type Session struct {
+	ID         uint       `gorm:"primaryKey"`
+	UserID     uint       `gorm:"index"`                // 所属用户
+	Family     string     `gorm:"uniqueIndex;not null"` // 令牌族
+	Device     string     // 设备描述
+	UserAgent  string     // 登录时的 User-Agent
+	IP         string     // 最近使用来源 IP
+	CreatedAt  time.Time  `gorm:"autoCreateTime"`
+	LastUsedAt time.Time  // 最近登录或刷新时间
+	ExpiresAt  time.Time  // 最新刷新令牌的过期时间
+	RevokedAt  *time.Time // 吊销时间
+}
+
+type SigningKey struct {
+	ID         uint       `gorm:"primaryKey"`
+	KeyID      string     `gorm:"uniqueIndex;not null"` // 密钥 ID（kid），写入 JWT 头
+	Algorithm  string     `gorm:"not null"`             // HS256 / EdDSA / RS256
+	PrivateKey string     `gorm:"not null"`             // base64 编码的密钥材料
+	CreatedAt  time.Time  // 创建时间
+	RetiredAt  *time.Time `gorm:"index"` // 轮换下线时间
+}
+
+type RecoveryCode struct {
+	ID        uint       `gorm:"primaryKey"`
+	UserID    uint       `gorm:"index;not null"` // 所属用户
+	CodeHash  string     `gorm:"not null"`       // 恢复码的 SHA-256 哈希
+	UsedAt    *time.Time // 使用时间，为空表示未使用
+	CreatedAt time.Time  `gorm:"autoCreateTime"`
+}
+
+type Invite struct {
+	ID        uint       `gorm:"primaryKey"`
+	CodeHash  string     `gorm:"uniqueIndex;not null"` // 邀请码的 SHA-256 哈希
+	Prefix    string     // 邀请码前缀，用于在列表中辨认
+	Note      string     // 备注，如受邀人
+	CreatedBy uint       `gorm:"index"` // 生成邀请码的管理员
+	ExpiresAt time.Time  // 过期时间
+	UsedAt    *time.Time // 使用时间，为空表示未使用
+	UsedBy    uint       // 使用邀请码注册的用户
+	RevokedAt *time.Time // 作废时间
+	CreatedAt time.Time  `gorm:"autoCreateTime"`
 }
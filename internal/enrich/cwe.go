@@ -0,0 +1,59 @@
+package enrich
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+
+	"VulnFusion/internal/models"
+)
+
+// cweWeakness CWE XML 目录中的 Weakness 元素
+type cweWeakness struct {
+	ID          string `xml:"ID,attr"`
+	Name        string `xml:"Name,attr"`
+	Abstraction string `xml:"Abstraction,attr"`
+	Description string `xml:"Description"`
+}
+
+// ParseCWE 流式解析 MITRE 发布的 CWE XML 目录（支持 gzip 压缩），逐条回调
+func ParseCWE(r io.Reader, fn func(models.CWEEntry) error) (int, error) {
+	dec := xml.NewDecoder(maybeGzip(r))
+	count := 0
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return count, err
+		}
+
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "Weakness" {
+			continue
+		}
+		var w cweWeakness
+		if err := dec.DecodeElement(&w, &start); err != nil {
+			return count, err
+		}
+		if w.ID == "" {
+			continue
+		}
+		count++
+		if err := fn(models.CWEEntry{
+			ID:          "CWE-" + strings.TrimSpace(w.ID),
+			Name:        w.Name,
+			Abstraction: w.Abstraction,
+			Description: strings.Join(strings.Fields(w.Description), " "),
+		}); err != nil {
+			return count, err
+		}
+	}
+
+	if count == 0 {
+		return 0, errors.New("未解析出任何 CWE 条目")
+	}
+	return count, nil
+}
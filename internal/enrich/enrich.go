@@ -0,0 +1,170 @@
+package enrich
+
+import (
+	"io"
+	"strings"
+	"time"
+
+	"VulnFusion/internal/log"
+	"VulnFusion/internal/models"
+)
+
+// importBatchSize 导入时每批写库的条数
+const importBatchSize = 500
+
+// CVEDetail 结果详情中展示的 CVE 信息
+type CVEDetail struct {
+	ID          string    `json:"id"`
+	Description string    `json:"description"`
+	CVSS3Score  float64   `json:"cvss3_score"`
+	CVSS3Vector string    `json:"cvss3_vector"`
+	CVSS2Score  float64   `json:"cvss2_score"`
+	CVSS2Vector string    `json:"cvss2_vector"`
+	CWEs        []string  `json:"cwes"`
+	CPEs        []string  `json:"cpes"`
+	References  []string  `json:"references"`
+	PublishedAt time.Time `json:"published_at"`
+	ModifiedAt  time.Time `json:"modified_at"`
+}
+
+// CWEDetail 结果详情中展示的 CWE 信息
+type CWEDetail struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Abstraction string `json:"abstraction"`
+	Description string `json:"description"`
+}
+
+// Enrichment 单条结果的本地漏洞库补充信息
+type Enrichment struct {
+	CVEs []CVEDetail `json:"cves"`
+	CWEs []CWEDetail `json:"cwes"`
+}
+
+// ImportNVD 导入 NVD JSON 数据到本地漏洞库，返回导入条数
+func ImportNVD(r io.Reader) (int, error) {
+	batch := make([]models.CVEEntry, 0, importBatchSize)
+	count, err := ParseNVD(r, func(entry models.CVEEntry) error {
+		if entry.ID == "" {
+			return nil
+		}
+		batch = append(batch, entry)
+		if len(batch) < importBatchSize {
+			return nil
+		}
+		err := models.SaveCVEEntries(batch)
+		batch = batch[:0]
+		return err
+	})
+	if err != nil {
+		return count, err
+	}
+	if err := models.SaveCVEEntries(batch); err != nil {
+		return count, err
+	}
+	log.Info("导入 NVD 数据 %d 条", count)
+	return count, nil
+}
+
+// ImportCWE 导入 CWE XML 目录到本地漏洞库，返回导入条数
+func ImportCWE(r io.Reader) (int, error) {
+	batch := make([]models.CWEEntry, 0, importBatchSize)
+	count, err := ParseCWE(r, func(entry models.CWEEntry) error {
+		batch = append(batch, entry)
+		if len(batch) < importBatchSize {
+			return nil
+		}
+		err := models.SaveCWEEntries(batch)
+		batch = batch[:0]
+		return err
+	})
+	if err != nil {
+		return count, err
+	}
+	if err := models.SaveCWEEntries(batch); err != nil {
+		return count, err
+	}
+	log.Info("导入 CWE 数据 %d 条", count)
+	return count, nil
+}
+
+// Lookup 按结果携带的 CVE / CWE 编号查询本地漏洞库；CVE 关联的 CWE 一并返回
+func Lookup(result *models.Result) (*Enrichment, error) {
+	out := &Enrichment{CVEs: []CVEDetail{}, CWEs: []CWEDetail{}}
+
+	cves, err := models.ListCVEEntries(splitIDs(result.CVEID, "CVE-"))
+	if err != nil {
+		return nil, err
+	}
+	cweIDs := splitIDs(result.CWEID, "CWE-")
+	for _, c := range cves {
+		detail := CVEDetail{
+			ID:          c.ID,
+			Description: c.Description,
+			CVSS3Score:  c.CVSS3Score,
+			CVSS3Vector: c.CVSS3Vector,
+			CVSS2Score:  c.CVSS2Score,
+			CVSS2Vector: c.CVSS2Vector,
+			CWEs:        splitIDs(c.CWEIDs, "CWE-"),
+			CPEs:        splitLines(c.CPEs),
+			References:  splitLines(c.References),
+			PublishedAt: c.PublishedAt,
+			ModifiedAt:  c.ModifiedAt,
+		}
+		cweIDs = append(cweIDs, detail.CWEs...)
+		out.CVEs = append(out.CVEs, detail)
+	}
+
+	cwes, err := models.ListCWEEntries(cweIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range cwes {
+		out.CWEs = append(out.CWEs, CWEDetail{ID: c.ID, Name: c.Name, Abstraction: c.Abstraction, Description: c.Description})
+	}
+	return out, nil
+}
+
+// FillCVSS 模板未提供 CVSS 时，使用本地漏洞库中关联 CVE 的 CVSS v3 评分补全
+func FillCVSS(result *models.Result) {
+	if result.CVSSScore > 0 || result.CVSSVector != "" {
+		return
+	}
+	cves, err := models.ListCVEEntries(splitIDs(result.CVEID, "CVE-"))
+	if err != nil {
+		log.Warn("查询本地漏洞库失败: %v", err)
+		return
+	}
+	for _, c := range cves {
+		if c.CVSS3Score > result.CVSSScore {
+			result.CVSSScore = c.CVSS3Score
+			result.CVSSVector = c.CVSS3Vector
+		}
+	}
+}
+
+// splitIDs 拆分逗号分隔的编号并规范为带前缀的大写形式，如 79 → CWE-79
+func splitIDs(s, prefix string) []string {
+	var ids []string
+	for _, v := range strings.Split(s, ",") {
+		v = strings.ToUpper(strings.TrimSpace(v))
+		if v == "" {
+			continue
+		}
+		if !strings.HasPrefix(v, prefix) {
+			v = prefix + v
+		}
+		ids = append(ids, v)
+	}
+	return ids
+}
+
+func splitLines(s string) []string {
+	lines := []string{}
+	for _, v := range strings.Split(s, "\n") {
+		if v = strings.TrimSpace(v); v != "" {
+			lines = append(lines, v)
+		}
+	}
+	return lines
+}
@@ -0,0 +1,339 @@
+package enrich
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"VulnFusion/internal/models"
+)
+
+// ErrUnknownFeed 无法识别的 NVD 数据格式
+var ErrUnknownFeed = errors.New("无法识别的 NVD 数据格式，需为 1.1 数据源（CVE_Items）或 2.0 API 格式（vulnerabilities）")
+
+type langValue struct {
+	Lang  string `json:"lang"`
+	Value string `json:"value"`
+}
+
+type cvssData struct {
+	Vector string  `json:"vectorString"`
+	Score  float64 `json:"baseScore"`
+}
+
+// nvd11Item NVD JSON 1.1 数据源中的单个 CVE
+type nvd11Item struct {
+	CVE struct {
+		Meta struct {
+			ID string `json:"ID"`
+		} `json:"CVE_data_meta"`
+		ProblemType struct {
+			Data []struct {
+				Description []langValue `json:"description"`
+			} `json:"problemtype_data"`
+		} `json:"problemtype"`
+		References struct {
+			Data []struct {
+				URL string `json:"url"`
+			} `json:"reference_data"`
+		} `json:"references"`
+		Description struct {
+			Data []langValue `json:"description_data"`
+		} `json:"description"`
+	} `json:"cve"`
+	Configurations struct {
+		Nodes []nvd11Node `json:"nodes"`
+	} `json:"configurations"`
+	Impact struct {
+		V3 struct {
+			CVSS cvssData `json:"cvssV3"`
+		} `json:"baseMetricV3"`
+		V2 struct {
+			CVSS cvssData `json:"cvssV2"`
+		} `json:"baseMetricV2"`
+	} `json:"impact"`
+	Published string `json:"publishedDate"`
+	Modified  string `json:"lastModifiedDate"`
+}
+
+type nvd11Node struct {
+	CPEMatch []struct {
+		Vulnerable bool   `json:"vulnerable"`
+		URI        string `json:"cpe23Uri"`
+	} `json:"cpe_match"`
+	Children []nvd11Node `json:"children"`
+}
+
+// nvd20Item NVD 2.0 API 响应中的单个 CVE
+type nvd20Item struct {
+	CVE struct {
+		ID           string      `json:"id"`
+		Published    string      `json:"published"`
+		LastModified string      `json:"lastModified"`
+		Descriptions []langValue `json:"descriptions"`
+		Metrics      struct {
+			V31 []nvd20Metric `json:"cvssMetricV31"`
+			V30 []nvd20Metric `json:"cvssMetricV30"`
+			V2  []nvd20Metric `json:"cvssMetricV2"`
+		} `json:"metrics"`
+		Weaknesses []struct {
+			Description []langValue `json:"description"`
+		} `json:"weaknesses"`
+		Configurations []struct {
+			Nodes []struct {
+				CPEMatch []struct {
+					Vulnerable bool   `json:"vulnerable"`
+					Criteria   string `json:"criteria"`
+				} `json:"cpeMatch"`
+			} `json:"nodes"`
+		} `json:"configurations"`
+		References []struct {
+			URL string `json:"url"`
+		} `json:"references"`
+	} `json:"cve"`
+}
+
+type nvd20Metric struct {
+	Type string   `json:"type"` // Primary / Secondary
+	Data cvssData `json:"cvssData"`
+}
+
+// ParseNVD 流式解析 NVD JSON 数据（支持 1.1 数据源与 2.0 API 格式，支持 gzip 压缩），逐条回调
+func ParseNVD(r io.Reader, fn func(models.CVEEntry) error) (int, error) {
+	dec := json.NewDecoder(maybeGzip(r))
+	if err := expectDelim(dec, '{'); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	found := false
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return count, err
+		}
+		key, _ := tok.(string)
+
+		switch key {
+		case "CVE_Items":
+			found = true
+			err = decodeArray(dec, func() error {
+				var item nvd11Item
+				if err := dec.Decode(&item); err != nil {
+					return err
+				}
+				count++
+				return fn(item.toEntry())
+			})
+		case "vulnerabilities":
+			found = true
+			err = decodeArray(dec, func() error {
+				var item nvd20Item
+				if err := dec.Decode(&item); err != nil {
+					return err
+				}
+				count++
+				return fn(item.toEntry())
+			})
+		default:
+			var skip json.RawMessage
+			err = dec.Decode(&skip)
+		}
+		if err != nil {
+			return count, fmt.Errorf("解析第 %d 条 CVE 失败: %w", count+1, err)
+		}
+	}
+
+	if !found {
+		return 0, ErrUnknownFeed
+	}
+	return count, nil
+}
+
+func (item nvd11Item) toEntry() models.CVEEntry {
+	entry := models.CVEEntry{
+		ID:          strings.ToUpper(item.CVE.Meta.ID),
+		Description: englishValue(item.CVE.Description.Data),
+		CVSS3Score:  item.Impact.V3.CVSS.Score,
+		CVSS3Vector: item.Impact.V3.CVSS.Vector,
+		CVSS2Score:  item.Impact.V2.CVSS.Score,
+		CVSS2Vector: item.Impact.V2.CVSS.Vector,
+		PublishedAt: parseNVDTime(item.Published),
+		ModifiedAt:  parseNVDTime(item.Modified),
+	}
+
+	var cwes []string
+	for _, pt := range item.CVE.ProblemType.Data {
+		for _, d := range pt.Description {
+			cwes = append(cwes, d.Value)
+		}
+	}
+	entry.CWEIDs = joinUnique(cweOnly(cwes), ",")
+
+	var cpes []string
+	var walk func(nodes []nvd11Node)
+	walk = func(nodes []nvd11Node) {
+		for _, n := range nodes {
+			for _, m := range n.CPEMatch {
+				if m.Vulnerable {
+					cpes = append(cpes, m.URI)
+				}
+			}
+			walk(n.Children)
+		}
+	}
+	walk(item.Configurations.Nodes)
+	entry.CPEs = joinUnique(cpes, "\n")
+
+	var refs []string
+	for _, ref := range item.CVE.References.Data {
+		refs = append(refs, ref.URL)
+	}
+	entry.References = joinUnique(refs, "\n")
+	return entry
+}
+
+func (item nvd20Item) toEntry() models.CVEEntry {
+	c := item.CVE
+	entry := models.CVEEntry{
+		ID:          strings.ToUpper(c.ID),
+		Description: englishValue(c.Descriptions),
+		PublishedAt: parseNVDTime(c.Published),
+		ModifiedAt:  parseNVDTime(c.LastModified),
+	}
+
+	v3 := c.Metrics.V31
+	if len(v3) == 0 {
+		v3 = c.Metrics.V30
+	}
+	if m, ok := primaryMetric(v3); ok {
+		entry.CVSS3Score, entry.CVSS3Vector = m.Score, m.Vector
+	}
+	if m, ok := primaryMetric(c.Metrics.V2); ok {
+		entry.CVSS2Score, entry.CVSS2Vector = m.Score, m.Vector
+	}
+
+	var cwes []string
+	for _, w := range c.Weaknesses {
+		for _, d := range w.Description {
+			cwes = append(cwes, d.Value)
+		}
+	}
+	entry.CWEIDs = joinUnique(cweOnly(cwes), ",")
+
+	var cpes []string
+	for _, conf := range c.Configurations {
+		for _, n := range conf.Nodes {
+			for _, m := range n.CPEMatch {
+				if m.Vulnerable {
+					cpes = append(cpes, m.Criteria)
+				}
+			}
+		}
+	}
+	entry.CPEs = joinUnique(cpes, "\n")
+
+	var refs []string
+	for _, ref := range c.References {
+		refs = append(refs, ref.URL)
+	}
+	entry.References = joinUnique(refs, "\n")
+	return entry
+}
+
+// primaryMetric 优先取 NVD 自身（Primary）的评分
+func primaryMetric(metrics []nvd20Metric) (cvssData, bool) {
+	for _, m := range metrics {
+		if m.Type == "Primary" {
+			return m.Data, true
+		}
+	}
+	if len(metrics) > 0 {
+		return metrics[0].Data, true
+	}
+	return cvssData{}, false
+}
+
+func englishValue(values []langValue) string {
+	for _, v := range values {
+		if v.Lang == "en" {
+			return v.Value
+		}
+	}
+	if len(values) > 0 {
+		return values[0].Value
+	}
+	return ""
+}
+
+// cweOnly 过滤掉 NVD-CWE-Other、NVD-CWE-noinfo 等非 CWE 编号
+func cweOnly(ids []string) []string {
+	var out []string
+	for _, id := range ids {
+		if id = strings.ToUpper(strings.TrimSpace(id)); strings.HasPrefix(id, "CWE-") {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+func joinUnique(items []string, sep string) string {
+	seen := map[string]bool{}
+	var out []string
+	for _, v := range items {
+		if v = strings.TrimSpace(v); v != "" && !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return strings.Join(out, sep)
+}
+
+// parseNVDTime 解析 NVD 1.1（2021-12-10T10:15Z）与 2.0（2021-12-10T10:15:09.143）的时间格式
+func parseNVDTime(s string) time.Time {
+	for _, layout := range []string{"2006-01-02T15:04Z", "2006-01-02T15:04:05.000", "2006-01-02T15:04:05", time.RFC3339} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// maybeGzip 根据文件头自动识别 gzip 压缩
+func maybeGzip(r io.Reader) io.Reader {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		if gz, err := gzip.NewReader(br); err == nil {
+			return gz
+		}
+	}
+	return br
+}
+
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := tok.(json.Delim); !ok || d != want {
+		return ErrUnknownFeed
+	}
+	return nil
+}
+
+func decodeArray(dec *json.Decoder, each func() error) error {
+	if err := expectDelim(dec, '['); err != nil {
+		return err
+	}
+	for dec.More() {
+		if err := each(); err != nil {
+			return err
+		}
+	}
+	return expectDelim(dec, ']')
+}
@@ -0,0 +1,235 @@
+package ldap
+
+import (
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"VulnFusion/internal/config"
+)
+
+// ErrInvalidCredentials 用户不存在或密码错误，两者不作区分
+var ErrInvalidCredentials = errors.New("用户名或密码错误")
+
+// Identity 通过目录认证的用户
+type Identity struct {
+	DN       string   // 用户 DN
+	ID       string   // 不变标识，按 id_attribute 取得，默认为 DN
+	Username string   // 按 username_attribute 取得的用户名
+	Email    string   // 邮箱
+	Groups   []string // 所属组的 DN
+}
+
+// Authenticate 以用户身份绑定目录验证密码，并读取用户属性与所属组
+func Authenticate(cfg config.LDAPConfig, username, password string) (*Identity, error) {
+	if username == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+	conn, err := connect(cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	usernameAttr := valueOr(cfg.UsernameAttribute, "uid")
+	emailAttr := valueOr(cfg.EmailAttribute, "mail")
+	groupAttr := valueOr(cfg.GroupAttribute, "memberOf")
+	attrs := []string{usernameAttr, emailAttr, groupAttr}
+	if cfg.IDAttribute != "" {
+		attrs = append(attrs, cfg.IDAttribute)
+	}
+
+	var entry *Entry
+	if cfg.BindDN != "" {
+		// 先用服务账号查出用户 DN，再以用户身份绑定
+		if err := conn.Bind(cfg.BindDN, cfg.BindPassword); err != nil {
+			return nil, fmt.Errorf("服务账号绑定失败：%w", err)
+		}
+		if entry, err = findUser(conn, cfg, username, attrs); err != nil {
+			return nil, err
+		}
+		if err := conn.Bind(entry.DN, password); err != nil {
+			return nil, bindError(err)
+		}
+	} else {
+		if cfg.UserDNTemplate == "" {
+			return nil, errors.New("未配置 bind_dn 或 user_dn_template")
+		}
+		dn := strings.ReplaceAll(cfg.UserDNTemplate, "%s", EscapeDN(username))
+		if err := conn.Bind(dn, password); err != nil {
+			return nil, bindError(err)
+		}
+		// 绑定后以用户自身权限读取属性
+		entries, err := conn.Search(SearchRequest{BaseDN: dn, Scope: ScopeBaseObject, Filter: "(objectClass=*)", Attributes: attrs, SizeLimit: 1})
+		if err != nil {
+			return nil, err
+		}
+		if len(entries) != 1 {
+			return nil, ErrInvalidCredentials
+		}
+		entry = entries[0]
+	}
+
+	id := &Identity{
+		DN:       entry.DN,
+		ID:       entry.DN,
+		Username: entry.Get(usernameAttr),
+		Email:    entry.Get(emailAttr),
+		Groups:   entry.GetAll(groupAttr),
+	}
+	if id.Username == "" {
+		id.Username = username
+	}
+	if cfg.IDAttribute != "" {
+		if v := entry.Get(cfg.IDAttribute); v != "" {
+			// objectGUID 等二进制属性转为十六进制
+			if !utf8.ValidString(v) {
+				v = hex.EncodeToString([]byte(v))
+			}
+			id.ID = v
+		}
+	}
+
+	if cfg.GroupBaseDN != "" {
+		filter := strings.ReplaceAll(valueOr(cfg.GroupFilter, "(member=%s)"), "%s", EscapeFilter(entry.DN))
+		groups, err := conn.Search(SearchRequest{BaseDN: cfg.GroupBaseDN, Scope: ScopeWholeSubtree, Filter: filter, Attributes: []string{"cn"}})
+		if err != nil {
+			return nil, fmt.Errorf("查询用户组失败：%w", err)
+		}
+		for _, g := range groups {
+			id.Groups = appendUnique(id.Groups, g.DN)
+		}
+	}
+	return id, nil
+}
+
+// ResolveRole 按组映射得出本系统角色：命中 admin 优先，其次取第一个命中的映射；
+// 映射键可以是组 DN 或组 CN，均不区分大小写。mapped 为 false 表示未命中任何映射
+func ResolveRole(cfg config.LDAPConfig, groups []string) (role string, mapped bool) {
+	mapping := make(map[string]string, len(cfg.RoleMapping))
+	for k, v := range cfg.RoleMapping {
+		mapping[normalizeDN(k)] = v
+	}
+	for _, g := range groups {
+		r, ok := mapping[normalizeDN(g)]
+		if !ok {
+			r, ok = mapping[strings.ToLower(groupCN(g))]
+		}
+		if !ok {
+			continue
+		}
+		if r == "admin" {
+			return r, true
+		}
+		if role == "" {
+			role = r
+		}
+	}
+	if role != "" {
+		return role, true
+	}
+	return valueOr(cfg.DefaultRole, "user"), false
+}
+
+// EscapeDN 转义 DN 属性值中的特殊字符（RFC 4514 2.4）
+func EscapeDN(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case strings.IndexByte(`,+"\<>;=`, c) >= 0,
+			c == '#' && i == 0,
+			c == ' ' && (i == 0 || i == len(s)-1):
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c == 0:
+			b.WriteString(`\00`)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func connect(cfg config.LDAPConfig) (*Conn, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("未配置 LDAP 地址")
+	}
+	tlsConfig := &tls.Config{InsecureSkipVerify: cfg.InsecureSkipVerify}
+	conn, err := Dial(cfg.URL, tlsConfig, cfg.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("连接目录服务失败：%w", err)
+	}
+	if cfg.StartTLS && strings.HasPrefix(cfg.URL, "ldap://") {
+		u, _ := url.Parse(cfg.URL)
+		if err := conn.StartTLS(tlsConfig, u.Hostname()); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("StartTLS 失败：%w", err)
+		}
+	}
+	return conn, nil
+}
+
+func findUser(conn *Conn, cfg config.LDAPConfig, username string, attrs []string) (*Entry, error) {
+	filter := strings.ReplaceAll(valueOr(cfg.UserFilter, "(uid=%s)"), "%s", EscapeFilter(username))
+	entries, err := conn.Search(SearchRequest{BaseDN: cfg.BaseDN, Scope: ScopeWholeSubtree, Filter: filter, Attributes: attrs, SizeLimit: 2})
+	if err != nil {
+		var e *Error
+		if errors.As(err, &e) && e.Code == ResultSizeLimitExceeded {
+			return nil, fmt.Errorf("用户名 %q 匹配到多个目录用户", username)
+		}
+		return nil, fmt.Errorf("查询用户失败：%w", err)
+	}
+	switch len(entries) {
+	case 0:
+		return nil, ErrInvalidCredentials
+	case 1:
+		return entries[0], nil
+	}
+	return nil, fmt.Errorf("用户名 %q 匹配到多个目录用户", username)
+}
+
+func bindError(err error) error {
+	if IsInvalidCredentials(err) {
+		return ErrInvalidCredentials
+	}
+	return err
+}
+
+// groupCN 取组 DN 第一个 RDN 的值，如 cn=admins,ou=groups → admins
+func groupCN(dn string) string {
+	first := strings.SplitN(dn, ",", 2)[0]
+	if eq := strings.IndexByte(first, '='); eq >= 0 {
+		return strings.TrimSpace(first[eq+1:])
+	}
+	return strings.TrimSpace(first)
+}
+
+// normalizeDN 去除 RDN 之间的空白并转为小写，用于比较
+func normalizeDN(dn string) string {
+	parts := strings.Split(dn, ",")
+	for i, p := range parts {
+		parts[i] = strings.TrimSpace(p)
+	}
+	return strings.ToLower(strings.Join(parts, ","))
+}
+
+func appendUnique(list []string, v string) []string {
+	for _, existing := range list {
+		if strings.EqualFold(normalizeDN(existing), normalizeDN(v)) {
+			return list
+		}
+	}
+	return append(list, v)
+}
+
+func valueOr(v, def string) string {
+	if v != "" {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,244 @@
+package ldap
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// maxPacketSize 单个 LDAP 消息的最大长度，防止异常长度导致内存耗尽
+const maxPacketSize = 16 << 20
+
+// BER 标识字节中的类别与构造位
+const (
+	ClassUniversal   byte = 0x00
+	ClassApplication byte = 0x40
+	ClassContext     byte = 0x80
+	constructed      byte = 0x20
+)
+
+// 用到的通用类型标签
+const (
+	TagBoolean     byte = 0x01
+	TagInteger     byte = 0x02
+	TagOctetString byte = 0x04
+	TagEnumerated  byte = 0x0a
+	TagSequence    byte = 0x30 // SEQUENCE / SEQUENCE OF（已含构造位）
+	TagSet         byte = 0x31 // SET / SET OF（已含构造位）
+)
+
+// Packet BER 编码的单个元素；构造类型使用 Children，基本类型使用 Value。
+// LDAP 用到的标签号都小于 31，因此标识只占一个字节
+type Packet struct {
+	Tag      byte
+	Value    []byte
+	Children []*Packet
+}
+
+// IsConstructed 是否为构造类型
+func (p *Packet) IsConstructed() bool {
+	return p.Tag&constructed != 0
+}
+
+// NewSequence 创建 SEQUENCE
+func NewSequence(children ...*Packet) *Packet {
+	return &Packet{Tag: TagSequence, Children: children}
+}
+
+// NewConstructed 创建指定标签的构造类型
+func NewConstructed(class, number byte, children ...*Packet) *Packet {
+	return &Packet{Tag: class | constructed | number, Children: children}
+}
+
+// NewPrimitive 创建指定标签的基本类型
+func NewPrimitive(class, number byte, value []byte) *Packet {
+	return &Packet{Tag: class | number, Value: value}
+}
+
+// NewString 创建 OCTET STRING
+func NewString(s string) *Packet {
+	return &Packet{Tag: TagOctetString, Value: []byte(s)}
+}
+
+// NewInteger 创建 INTEGER
+func NewInteger(n int64) *Packet {
+	return &Packet{Tag: TagInteger, Value: encodeInt(n)}
+}
+
+// NewEnumerated 创建 ENUMERATED
+func NewEnumerated(n int64) *Packet {
+	return &Packet{Tag: TagEnumerated, Value: encodeInt(n)}
+}
+
+// NewBoolean 创建 BOOLEAN
+func NewBoolean(b bool) *Packet {
+	if b {
+		return &Packet{Tag: TagBoolean, Value: []byte{0xff}}
+	}
+	return &Packet{Tag: TagBoolean, Value: []byte{0x00}}
+}
+
+// Int 将基本类型内容解析为整数
+func (p *Packet) Int() int64 {
+	if len(p.Value) == 0 {
+		return 0
+	}
+	n := int64(int8(p.Value[0])) // 符号扩展
+	for _, b := range p.Value[1:] {
+		n = n<<8 | int64(b)
+	}
+	return n
+}
+
+// String 返回基本类型内容
+func (p *Packet) String() string {
+	return string(p.Value)
+}
+
+// Child 返回第 i 个子元素，不存在时返回空元素，便于按位置读取可选字段
+func (p *Packet) Child(i int) *Packet {
+	if i < len(p.Children) {
+		return p.Children[i]
+	}
+	return &Packet{}
+}
+
+// Bytes 编码为 BER（定长形式）
+func (p *Packet) Bytes() []byte {
+	content := p.Value
+	if p.IsConstructed() {
+		content = nil
+		for _, c := range p.Children {
+			content = append(content, c.Bytes()...)
+		}
+	}
+	out := append([]byte{p.Tag}, encodeLength(len(content))...)
+	return append(out, content...)
+}
+
+// ReadPacket 从流中读取一个完整的 BER 元素
+func ReadPacket(r *bufio.Reader) (*Packet, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if tag&0x1f == 0x1f {
+		return nil, errors.New("不支持多字节标签")
+	}
+	length, err := readLength(r)
+	if err != nil {
+		return nil, err
+	}
+	content := make([]byte, length)
+	if _, err := io.ReadFull(r, content); err != nil {
+		return nil, err
+	}
+	return parsePacket(tag, content)
+}
+
+// DecodePacket 解析一段完整的 BER 数据
+func DecodePacket(data []byte) (*Packet, error) {
+	p, rest, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("BER 数据末尾存在多余字节")
+	}
+	return p, nil
+}
+
+func parsePacket(tag byte, content []byte) (*Packet, error) {
+	p := &Packet{Tag: tag}
+	if !p.IsConstructed() {
+		p.Value = content
+		return p, nil
+	}
+	for len(content) > 0 {
+		child, rest, err := decode(content)
+		if err != nil {
+			return nil, err
+		}
+		p.Children = append(p.Children, child)
+		content = rest
+	}
+	return p, nil
+}
+
+func decode(data []byte) (*Packet, []byte, error) {
+	if len(data) < 2 {
+		return nil, nil, io.ErrUnexpectedEOF
+	}
+	tag := data[0]
+	if tag&0x1f == 0x1f {
+		return nil, nil, errors.New("不支持多字节标签")
+	}
+	r := bytes.NewReader(data[1:])
+	length, err := readLength(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	header := len(data) - r.Len()
+	if header+length > len(data) {
+		return nil, nil, io.ErrUnexpectedEOF
+	}
+	p, err := parsePacket(tag, data[header:header+length])
+	if err != nil {
+		return nil, nil, err
+	}
+	return p, data[header+length:], nil
+}
+
+func readLength(r io.ByteReader) (int, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	if b&0x80 == 0 {
+		return int(b), nil
+	}
+	n := int(b & 0x7f)
+	if n == 0 {
+		return 0, errors.New("不支持不定长编码")
+	}
+	if n > 4 {
+		return 0, fmt.Errorf("BER 长度字段过长：%d 字节", n)
+	}
+	length := 0
+	for i := 0; i < n; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		length = length<<8 | int(b)
+	}
+	if length > maxPacketSize {
+		return 0, fmt.Errorf("LDAP 消息过大：%d 字节", length)
+	}
+	return length, nil
+}
+
+func encodeLength(n int) []byte {
+	if n < 0x80 {
+		return []byte{byte(n)}
+	}
+	var buf []byte
+	for v := n; v > 0; v >>= 8 {
+		buf = append([]byte{byte(v)}, buf...)
+	}
+	return append([]byte{0x80 | byte(len(buf))}, buf...)
+}
+
+// encodeInt 最短的二进制补码编码
+func encodeInt(n int64) []byte {
+	buf := []byte{byte(n)}
+	for v := n >> 8; ; v >>= 8 {
+		top := buf[0]
+		if (v == 0 && top&0x80 == 0) || (v == -1 && top&0x80 != 0) {
+			return buf
+		}
+		buf = append([]byte{byte(v)}, buf...)
+	}
+}
@@ -0,0 +1,299 @@
+package ldap
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// LDAP 操作的应用标签（RFC 4511 4.2 - 4.12）
+const (
+	AppBindRequest       byte = 0
+	AppBindResponse      byte = 1
+	AppUnbindRequest     byte = 2
+	AppSearchRequest     byte = 3
+	AppSearchEntry       byte = 4
+	AppSearchDone        byte = 5
+	AppSearchReference   byte = 19
+	AppExtendedRequest   byte = 23
+	AppExtendedResponse  byte = 24
+	startTLSOID               = "1.3.6.1.4.1.1466.20037"
+	defaultConnTimeout        = 10 * time.Second
+	defaultSearchSizeMax      = 1000
+)
+
+// 搜索范围
+const (
+	ScopeBaseObject   = 0
+	ScopeSingleLevel  = 1
+	ScopeWholeSubtree = 2
+)
+
+// 常用结果码
+const (
+	ResultSuccess            = 0
+	ResultSizeLimitExceeded  = 4
+	ResultInvalidCredentials = 49
+)
+
+// Error 服务端返回的非成功结果
+type Error struct {
+	Code    int64
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("LDAP 错误 %d：%s", e.Code, e.Message)
+	}
+	return fmt.Sprintf("LDAP 错误 %d", e.Code)
+}
+
+// IsInvalidCredentials 判断是否为账号或密码错误
+func IsInvalidCredentials(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && e.Code == ResultInvalidCredentials
+}
+
+// Entry 搜索结果中的一条记录，属性名统一小写
+type Entry struct {
+	DN         string
+	Attributes map[string][]string
+}
+
+// Get 返回属性的第一个值
+func (e *Entry) Get(attr string) string {
+	if vals := e.Attributes[strings.ToLower(attr)]; len(vals) > 0 {
+		return vals[0]
+	}
+	return ""
+}
+
+// GetAll 返回属性的全部值
+func (e *Entry) GetAll(attr string) []string {
+	return e.Attributes[strings.ToLower(attr)]
+}
+
+// SearchRequest 搜索参数
+type SearchRequest struct {
+	BaseDN     string
+	Scope      int
+	Filter     string
+	Attributes []string
+	SizeLimit  int
+}
+
+// Conn 单个 LDAP 连接，操作按顺序同步执行，不可并发使用
+type Conn struct {
+	conn    net.Conn
+	r       *bufio.Reader
+	msgID   int64
+	timeout time.Duration
+}
+
+// Dial 连接目录服务，支持 ldap:// 与 ldaps://
+func Dial(rawURL string, tlsConfig *tls.Config, timeout time.Duration) (*Conn, error) {
+	if timeout <= 0 {
+		timeout = defaultConnTimeout
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("无效的 LDAP 地址：%w", err)
+	}
+	host := u.Host
+	dialer := &net.Dialer{Timeout: timeout}
+	var conn net.Conn
+	switch u.Scheme {
+	case "ldap":
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "389")
+		}
+		conn, err = dialer.Dial("tcp", host)
+	case "ldaps":
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "636")
+		}
+		conn, err = tls.DialWithDialer(dialer, "tcp", host, withServerName(tlsConfig, u.Hostname()))
+	default:
+		return nil, fmt.Errorf("不支持的 LDAP 协议：%q", u.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Conn{conn: conn, r: bufio.NewReader(conn), timeout: timeout}, nil
+}
+
+// StartTLS 在明文连接上升级为 TLS（RFC 4511 4.14）
+func (c *Conn) StartTLS(tlsConfig *tls.Config, serverName string) error {
+	req := NewConstructed(ClassApplication, AppExtendedRequest,
+		NewPrimitive(ClassContext, 0, []byte(startTLSOID)))
+	resp, err := c.roundTrip(req, AppExtendedResponse)
+	if err != nil {
+		return err
+	}
+	if err := resultError(resp); err != nil {
+		return err
+	}
+	tlsConn := tls.Client(c.conn, withServerName(tlsConfig, serverName))
+	_ = tlsConn.SetDeadline(time.Now().Add(c.timeout))
+	if err := tlsConn.Handshake(); err != nil {
+		return err
+	}
+	c.conn, c.r = tlsConn, bufio.NewReader(tlsConn)
+	return nil
+}
+
+// Bind 简单绑定。空密码会被服务端视为匿名绑定（RFC 4513 5.1.2），因此直接拒绝
+func (c *Conn) Bind(dn, password string) error {
+	if password == "" {
+		return &Error{Code: ResultInvalidCredentials, Message: "密码不能为空"}
+	}
+	req := NewConstructed(ClassApplication, AppBindRequest,
+		NewInteger(3),
+		NewString(dn),
+		NewPrimitive(ClassContext, 0, []byte(password)))
+	resp, err := c.roundTrip(req, AppBindResponse)
+	if err != nil {
+		return err
+	}
+	return resultError(resp)
+}
+
+// Search 执行搜索，返回全部结果记录
+func (c *Conn) Search(req SearchRequest) ([]*Entry, error) {
+	filter, err := CompileFilter(req.Filter)
+	if err != nil {
+		return nil, err
+	}
+	sizeLimit := req.SizeLimit
+	if sizeLimit <= 0 {
+		sizeLimit = defaultSearchSizeMax
+	}
+	attrs := NewSequence()
+	for _, a := range req.Attributes {
+		attrs.Children = append(attrs.Children, NewString(a))
+	}
+	op := NewConstructed(ClassApplication, AppSearchRequest,
+		NewString(req.BaseDN),
+		NewEnumerated(int64(req.Scope)),
+		NewEnumerated(0), // derefAliases: neverDerefAliases
+		NewInteger(int64(sizeLimit)),
+		NewInteger(int64(c.timeout/time.Second)),
+		NewBoolean(false),
+		filter,
+		attrs)
+
+	id, err := c.send(op)
+	if err != nil {
+		return nil, err
+	}
+	var entries []*Entry
+	for {
+		resp, err := c.receive(id)
+		if err != nil {
+			return nil, err
+		}
+		switch resp.Tag {
+		case ClassApplication | constructed | AppSearchEntry:
+			entries = append(entries, parseEntry(resp))
+		case ClassApplication | constructed | AppSearchReference:
+			// 不跟随引用
+		case ClassApplication | constructed | AppSearchDone:
+			if err := resultError(resp); err != nil {
+				return nil, err
+			}
+			return entries, nil
+		default:
+			return nil, fmt.Errorf("意外的 LDAP 响应标签 0x%02x", resp.Tag)
+		}
+	}
+}
+
+// Close 发送 Unbind 并关闭连接
+func (c *Conn) Close() error {
+	_, _ = c.send(NewPrimitive(ClassApplication, AppUnbindRequest, nil))
+	return c.conn.Close()
+}
+
+func (c *Conn) roundTrip(op *Packet, want byte) (*Packet, error) {
+	id, err := c.send(op)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.receive(id)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Tag != ClassApplication|constructed|want {
+		return nil, fmt.Errorf("意外的 LDAP 响应标签 0x%02x", resp.Tag)
+	}
+	return resp, nil
+}
+
+func (c *Conn) send(op *Packet) (int64, error) {
+	c.msgID++
+	msg := NewSequence(NewInteger(c.msgID), op)
+	_ = c.conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	_, err := c.conn.Write(msg.Bytes())
+	return c.msgID, err
+}
+
+// receive 读取指定消息 ID 的下一个响应，返回其中的协议操作
+func (c *Conn) receive(id int64) (*Packet, error) {
+	for {
+		_ = c.conn.SetReadDeadline(time.Now().Add(c.timeout))
+		msg, err := ReadPacket(c.r)
+		if err != nil {
+			return nil, err
+		}
+		if msg.Tag != TagSequence || len(msg.Children) < 2 {
+			return nil, errors.New("无效的 LDAP 消息")
+		}
+		switch msg.Children[0].Int() {
+		case id:
+			return msg.Children[1], nil
+		case 0:
+			// 未经请求的通知（如服务端即将断开连接）
+			return nil, resultError(msg.Children[1])
+		}
+	}
+}
+
+// resultError 解析 LDAPResult，成功时返回 nil
+func resultError(op *Packet) error {
+	code := op.Child(0).Int()
+	if code == ResultSuccess {
+		return nil
+	}
+	return &Error{Code: code, Message: op.Child(2).String()}
+}
+
+func parseEntry(op *Packet) *Entry {
+	e := &Entry{DN: op.Child(0).String(), Attributes: map[string][]string{}}
+	for _, attr := range op.Child(1).Children {
+		name := strings.ToLower(attr.Child(0).String())
+		for _, v := range attr.Child(1).Children {
+			e.Attributes[name] = append(e.Attributes[name], v.String())
+		}
+	}
+	return e
+}
+
+func withServerName(cfg *tls.Config, name string) *tls.Config {
+	if cfg == nil {
+		cfg = &tls.Config{}
+	}
+	cfg = cfg.Clone()
+	if cfg.ServerName == "" {
+		cfg.ServerName = name
+	}
+	if cfg.MinVersion == 0 {
+		cfg.MinVersion = tls.VersionTLS12
+	}
+	return cfg
+}
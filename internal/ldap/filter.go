@@ -0,0 +1,183 @@
+package ldap
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// 过滤器的上下文标签（RFC 4511 4.5.1）
+const (
+	FilterAnd            byte = 0
+	FilterOr             byte = 1
+	FilterNot            byte = 2
+	FilterEqualityMatch  byte = 3
+	FilterSubstrings     byte = 4
+	FilterGreaterOrEqual byte = 5
+	FilterLessOrEqual    byte = 6
+	FilterPresent        byte = 7
+	FilterApproxMatch    byte = 8
+)
+
+// 子串过滤器各部分的标签
+const (
+	SubstringInitial byte = 0
+	SubstringAny     byte = 1
+	SubstringFinal   byte = 2
+)
+
+// EscapeFilter 转义过滤器中的值（RFC 4515 3），用户输入拼入过滤器前必须转义
+func EscapeFilter(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '*' || c == '(' || c == ')' || c == '\\' || c == 0 || c >= 0x80:
+			fmt.Fprintf(&b, "\\%02x", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// CompileFilter 将字符串形式的过滤器编码为 BER，如 (&(objectClass=person)(uid=alice))
+func CompileFilter(filter string) (*Packet, error) {
+	filter = strings.TrimSpace(filter)
+	if !strings.HasPrefix(filter, "(") {
+		filter = "(" + filter + ")"
+	}
+	p, rest, err := parseFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	if rest != "" {
+		return nil, fmt.Errorf("过滤器末尾存在多余内容：%q", rest)
+	}
+	return p, nil
+}
+
+// parseFilter 解析以 ( 开头的一个过滤器，返回剩余部分
+func parseFilter(s string) (*Packet, string, error) {
+	if !strings.HasPrefix(s, "(") {
+		return nil, "", fmt.Errorf("过滤器应以 ( 开头：%q", s)
+	}
+	s = s[1:]
+	if s == "" {
+		return nil, "", fmt.Errorf("过滤器不完整")
+	}
+
+	switch s[0] {
+	case '&', '|':
+		tag := FilterAnd
+		if s[0] == '|' {
+			tag = FilterOr
+		}
+		set := NewConstructed(ClassContext, tag)
+		s = s[1:]
+		for strings.HasPrefix(s, "(") {
+			child, rest, err := parseFilter(s)
+			if err != nil {
+				return nil, "", err
+			}
+			set.Children = append(set.Children, child)
+			s = rest
+		}
+		if !strings.HasPrefix(s, ")") {
+			return nil, "", fmt.Errorf("过滤器缺少 )")
+		}
+		return set, s[1:], nil
+	case '!':
+		child, rest, err := parseFilter(s[1:])
+		if err != nil {
+			return nil, "", err
+		}
+		if !strings.HasPrefix(rest, ")") {
+			return nil, "", fmt.Errorf("过滤器缺少 )")
+		}
+		return NewConstructed(ClassContext, FilterNot, child), rest[1:], nil
+	}
+
+	end := strings.IndexByte(s, ')')
+	if end < 0 {
+		return nil, "", fmt.Errorf("过滤器缺少 )")
+	}
+	p, err := parseItem(s[:end])
+	if err != nil {
+		return nil, "", err
+	}
+	return p, s[end+1:], nil
+}
+
+// parseItem 解析单个比较项，如 uid=alice、cn=adm*、mail=*
+func parseItem(item string) (*Packet, error) {
+	eq := strings.IndexByte(item, '=')
+	if eq <= 0 {
+		return nil, fmt.Errorf("无效的过滤条件：%q", item)
+	}
+	attr, raw := item[:eq], item[eq+1:]
+	tag := FilterEqualityMatch
+	switch attr[len(attr)-1] {
+	case '>':
+		tag, attr = FilterGreaterOrEqual, attr[:len(attr)-1]
+	case '<':
+		tag, attr = FilterLessOrEqual, attr[:len(attr)-1]
+	case '~':
+		tag, attr = FilterApproxMatch, attr[:len(attr)-1]
+	}
+	if attr == "" {
+		return nil, fmt.Errorf("无效的过滤条件：%q", item)
+	}
+
+	if tag == FilterEqualityMatch && strings.Contains(raw, "*") {
+		if raw == "*" {
+			return NewPrimitive(ClassContext, FilterPresent, []byte(attr)), nil
+		}
+		parts := strings.Split(raw, "*")
+		subs := NewSequence()
+		for i, part := range parts {
+			if part == "" {
+				continue
+			}
+			value, err := unescapeFilter(part)
+			if err != nil {
+				return nil, err
+			}
+			kind := SubstringAny
+			if i == 0 {
+				kind = SubstringInitial
+			} else if i == len(parts)-1 {
+				kind = SubstringFinal
+			}
+			subs.Children = append(subs.Children, NewPrimitive(ClassContext, kind, value))
+		}
+		return NewConstructed(ClassContext, FilterSubstrings, NewString(attr), subs), nil
+	}
+
+	value, err := unescapeFilter(raw)
+	if err != nil {
+		return nil, err
+	}
+	return NewConstructed(ClassContext, tag, NewString(attr), &Packet{Tag: TagOctetString, Value: value}), nil
+}
+
+// unescapeFilter 还原 \XX 形式的转义
+func unescapeFilter(s string) ([]byte, error) {
+	out := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			out = append(out, s[i])
+			continue
+		}
+		if i+3 > len(s) {
+			return nil, fmt.Errorf("无效的转义：%q", s)
+		}
+		b, err := hex.DecodeString(s[i+1 : i+3])
+		if err != nil {
+			return nil, fmt.Errorf("无效的转义：%q", s)
+		}
+		out = append(out, b[0])
+		i += 2
+	}
+	return out, nil
+}
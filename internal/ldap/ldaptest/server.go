@@ -0,0 +1,312 @@
+// Package ldaptest 提供进程内的最小 LDAP 目录服务，用于测试目录认证，用法类似 net/http/httptest
+package ldaptest
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"sync"
+
+	"VulnFusion/internal/ldap"
+)
+
+// 返回给客户端的结果码
+const (
+	resultSuccess            = 0
+	resultProtocolError      = 2
+	resultSizeLimitExceeded  = 4
+	resultNoSuchObject       = 32
+	resultInvalidCredentials = 49
+	resultInsufficientAccess = 50
+)
+
+// Server 内存中的目录：支持简单绑定、搜索与解绑，匿名连接不能搜索
+type Server struct {
+	URL string
+
+	listener net.Listener
+	mu       sync.Mutex
+	entries  []*entry
+	binds    []string
+	wg       sync.WaitGroup
+}
+
+type entry struct {
+	dn       string
+	password string
+	attrs    map[string][]string // 属性名小写
+}
+
+// NewServer 在本机随机端口启动目录服务
+func NewServer() *Server {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic("ldaptest: 监听失败: " + err.Error())
+	}
+	s := &Server{URL: "ldap://" + l.Addr().String(), listener: l}
+	s.wg.Add(1)
+	go s.serve()
+	return s
+}
+
+// AddEntry 添加一条记录，password 为空表示不能绑定
+func (s *Server) AddEntry(dn, password string, attrs map[string][]string) {
+	lower := map[string][]string{}
+	for k, v := range attrs {
+		lower[strings.ToLower(k)] = v
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, &entry{dn: dn, password: password, attrs: lower})
+}
+
+// SetPassword 修改记录的密码
+func (s *Server) SetPassword(dn, password string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e := s.find(dn); e != nil {
+		e.password = password
+	}
+}
+
+// Binds 返回成功绑定过的 DN，按时间顺序
+func (s *Server) Binds() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.binds...)
+}
+
+// Close 停止服务
+func (s *Server) Close() {
+	_ = s.listener.Close()
+	s.wg.Wait()
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	bound := ""
+	for {
+		msg, err := ldap.ReadPacket(r)
+		if err != nil || len(msg.Children) < 2 {
+			return
+		}
+		id := msg.Children[0].Int()
+		op := msg.Children[1]
+		reply := func(ops ...*ldap.Packet) {
+			for _, p := range ops {
+				_, _ = conn.Write(ldap.NewSequence(ldap.NewInteger(id), p).Bytes())
+			}
+		}
+
+		switch op.Tag &^ ldap.ClassApplication &^ 0x20 {
+		case ldap.AppBindRequest:
+			dn, password := op.Child(1).String(), op.Child(2).String()
+			code := int64(resultInvalidCredentials)
+			s.mu.Lock()
+			if e := s.find(dn); e != nil && e.password != "" && e.password == password {
+				code, bound = resultSuccess, e.dn
+				s.binds = append(s.binds, e.dn)
+			} else if dn == "" && password == "" {
+				code, bound = resultSuccess, ""
+			}
+			s.mu.Unlock()
+			reply(result(ldap.AppBindResponse, code, ""))
+		case ldap.AppUnbindRequest:
+			return
+		case ldap.AppSearchRequest:
+			if bound == "" {
+				reply(result(ldap.AppSearchDone, resultInsufficientAccess, "需要先绑定"))
+				continue
+			}
+			reply(s.search(op)...)
+		case ldap.AppExtendedRequest:
+			reply(result(ldap.AppExtendedResponse, resultProtocolError, "不支持的扩展操作"))
+		default:
+			reply(result(ldap.AppExtendedResponse, resultProtocolError, "不支持的操作"))
+		}
+	}
+}
+
+func (s *Server) search(op *ldap.Packet) []*ldap.Packet {
+	base := op.Child(0).String()
+	scope := op.Child(1).Int()
+	sizeLimit := op.Child(3).Int()
+	filter := op.Child(6)
+	var want []string
+	for _, a := range op.Child(7).Children {
+		want = append(want, strings.ToLower(a.String()))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if base != "" && s.find(base) == nil && !s.hasDescendant(base) {
+		return []*ldap.Packet{result(ldap.AppSearchDone, resultNoSuchObject, "")}
+	}
+	var out []*ldap.Packet
+	for _, e := range s.entries {
+		if !inScope(e.dn, base, scope) || !match(e, filter) {
+			continue
+		}
+		if sizeLimit > 0 && int64(len(out)) >= sizeLimit {
+			return append(out, result(ldap.AppSearchDone, resultSizeLimitExceeded, ""))
+		}
+		out = append(out, encodeEntry(e, want))
+	}
+	return append(out, result(ldap.AppSearchDone, resultSuccess, ""))
+}
+
+func (s *Server) find(dn string) *entry {
+	for _, e := range s.entries {
+		if normalize(e.dn) == normalize(dn) {
+			return e
+		}
+	}
+	return nil
+}
+
+func (s *Server) hasDescendant(base string) bool {
+	for _, e := range s.entries {
+		if inScope(e.dn, base, ldap.ScopeWholeSubtree) {
+			return true
+		}
+	}
+	return false
+}
+
+func inScope(dn, base string, scope int64) bool {
+	dn, base = normalize(dn), normalize(base)
+	switch scope {
+	case ldap.ScopeBaseObject:
+		return dn == base
+	case ldap.ScopeSingleLevel:
+		parts := strings.SplitN(dn, ",", 2)
+		return len(parts) == 2 && parts[1] == base
+	}
+	return base == "" || dn == base || strings.HasSuffix(dn, ","+base)
+}
+
+// match 按 RFC 4511 过滤器匹配记录，比较不区分大小写
+func match(e *entry, f *ldap.Packet) bool {
+	number := f.Tag & 0x1f
+	switch number {
+	case ldap.FilterAnd:
+		for _, c := range f.Children {
+			if !match(e, c) {
+				return false
+			}
+		}
+		return true
+	case ldap.FilterOr:
+		for _, c := range f.Children {
+			if match(e, c) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterNot:
+		return !match(e, f.Child(0))
+	case ldap.FilterPresent:
+		attr := strings.ToLower(f.String())
+		return attr == "objectclass" || len(e.attrs[attr]) > 0
+	case ldap.FilterSubstrings:
+		for _, v := range e.attrs[strings.ToLower(f.Child(0).String())] {
+			if matchSubstrings(strings.ToLower(v), f.Child(1).Children) {
+				return true
+			}
+		}
+		return false
+	}
+
+	attr, value := strings.ToLower(f.Child(0).String()), strings.ToLower(f.Child(1).String())
+	for _, v := range e.attrs[attr] {
+		v = strings.ToLower(v)
+		switch number {
+		case ldap.FilterEqualityMatch, ldap.FilterApproxMatch:
+			if v == value || (attr == "member" && normalize(v) == normalize(value)) {
+				return true
+			}
+		case ldap.FilterGreaterOrEqual:
+			if v >= value {
+				return true
+			}
+		case ldap.FilterLessOrEqual:
+			if v <= value {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func matchSubstrings(v string, parts []*ldap.Packet) bool {
+	for _, p := range parts {
+		s := strings.ToLower(p.String())
+		switch p.Tag & 0x1f {
+		case ldap.SubstringInitial:
+			if !strings.HasPrefix(v, s) {
+				return false
+			}
+			v = v[len(s):]
+		case ldap.SubstringAny:
+			i := strings.Index(v, s)
+			if i < 0 {
+				return false
+			}
+			v = v[i+len(s):]
+		case ldap.SubstringFinal:
+			if !strings.HasSuffix(v, s) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func encodeEntry(e *entry, want []string) *ldap.Packet {
+	attrs := ldap.NewSequence()
+	for name, vals := range e.attrs {
+		if len(want) > 0 && !contains(want, name) && !contains(want, "*") {
+			continue
+		}
+		set := &ldap.Packet{Tag: ldap.TagSet}
+		for _, v := range vals {
+			set.Children = append(set.Children, ldap.NewString(v))
+		}
+		attrs.Children = append(attrs.Children, ldap.NewSequence(ldap.NewString(name), set))
+	}
+	return ldap.NewConstructed(ldap.ClassApplication, ldap.AppSearchEntry, ldap.NewString(e.dn), attrs)
+}
+
+func result(app byte, code int64, message string) *ldap.Packet {
+	return ldap.NewConstructed(ldap.ClassApplication, app,
+		ldap.NewEnumerated(code), ldap.NewString(""), ldap.NewString(message))
+}
+
+func normalize(dn string) string {
+	parts := strings.Split(dn, ",")
+	for i, p := range parts {
+		parts[i] = strings.TrimSpace(p)
+	}
+	return strings.ToLower(strings.Join(parts, ","))
+}
+
+func contains(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"VulnFusion/internal/db"
+	"strings"
+	"time"
+)
+
+// 远程扫描节点状态常量定义
+const (
+	AgentOnline  = "online"
+	AgentOffline = "offline"
+)
+
+type Agent struct {
+	ID            uint      `gorm:"primaryKey"`
+	Name          string    `gorm:"not null"`             // 节点名称
+	TokenHash     string    `gorm:"uniqueIndex;not null"` // 节点访问令牌的 SHA-256 摘要
+	Labels        string    `gorm:"type:text"`            // 节点标签（逗号分隔），如 dmz,internal-net
+	Capacity      int       `gorm:"default:1"`            // 最大并发任务数
+	Running       int       `gorm:"default:0"`            // 当前运行中的任务数
+	LastHeartbeat time.Time // 最近一次心跳时间
+	PublicKey     string    // 节点结果签名公钥（ed25519，base64）
+	CreatedAt     time.Time `gorm:"autoCreateTime"`
+}
+
+// LabelList 返回节点标签数组
+func (a *Agent) LabelList() []string {
+	var labels []string
+	for _, l := range strings.Split(a.Labels, ",") {
+		if l = strings.TrimSpace(l); l != "" {
+			labels = append(labels, l)
+		}
+	}
+	return labels
+}
+
+// StatusAt 根据心跳超时时间判断节点是否在线
+func (a *Agent) StatusAt(now time.Time, timeout time.Duration) string {
+	if now.Sub(a.LastHeartbeat) <= timeout {
+		return AgentOnline
+	}
+	return AgentOffline
+}
+
+// CreateAgent 注册新的远程扫描节点
+func CreateAgent(agent *Agent) error {
+	return db.GetDB().Create(agent).Error
+}
+
+// GetAgentByID 根据节点 ID 查询节点信息
+func GetAgentByID(id uint) (*Agent, error) {
+	var agent Agent
+	if err := db.GetDB().First(&agent, id).Error; err != nil {
+		return nil, err
+	}
+	return &agent, nil
+}
+
+// GetAgentByTokenHash 根据令牌摘要查询节点，用于节点请求鉴权
+func GetAgentByTokenHash(hash string) (*Agent, error) {
+	var agent Agent
+	if err := db.GetDB().Where("token_hash = ?", hash).First(&agent).Error; err != nil {
+		return nil, err
+	}
+	return &agent, nil
+}
+
+// ListAgents 列出所有已注册节点
+func ListAgents() ([]Agent, error) {
+	var agents []Agent
+	err := db.GetDB().Order("id asc").Find(&agents).Error
+	return agents, err
+}
+
+// UpdateAgentByID 根据节点 ID 更新部分字段
+func UpdateAgentByID(id uint, updates map[string]interface{}) error {
+	return db.GetDB().Model(&Agent{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// DeleteAgentByID 删除指定节点
+func DeleteAgentByID(id uint) error {
+	return db.GetDB().Delete(&Agent{}, id).Error
+}
+
+// AgentLabelExists 判断是否有已注册节点具备指定标签
+func AgentLabelExists(label string) (bool, error) {
+	agents, err := ListAgents()
+	if err != nil {
+		return false, err
+	}
+	for i := range agents {
+		for _, l := range agents[i].LabelList() {
+			if l == label {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
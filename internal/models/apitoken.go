@@ -0,0 +1,86 @@
+package models
+
+import (
+	"VulnFusion/internal/db"
+	"strings"
+	"time"
+)
+
+type APIToken struct {
+	ID         uint       `gorm:"primaryKey"`
+	UserID     uint       `gorm:"index"`    // 所属用户
+	Name       string     `gorm:"not null"` // 名称，如 "GitLab CI"
+	Prefix     string     // 令牌前缀，用于在列表中辨认令牌
+	TokenHash  string     `gorm:"uniqueIndex;not null" json:"-"` // 令牌 SHA-256 摘要
+	Scopes     string     // 授权范围（空格分隔），如 "tasks:write results:read"
+	ExpiresAt  *time.Time // 过期时间，为空表示永不过期
+	LastUsedAt *time.Time // 最近使用时间
+	LastUsedIP string     // 最近使用来源 IP
+	RevokedAt  *time.Time // 吊销时间
+	CreatedAt  time.Time  `gorm:"autoCreateTime"`
+}
+
+// ScopeList 返回授权范围列表
+func (t *APIToken) ScopeList() []string {
+	return strings.Fields(t.Scopes)
+}
+
+// Active 判断令牌是否未吊销且未过期
+func (t *APIToken) Active(now time.Time) bool {
+	return t.RevokedAt == nil && (t.ExpiresAt == nil || now.Before(*t.ExpiresAt))
+}
+
+// CreateAPIToken 创建 API 令牌
+func CreateAPIToken(token *APIToken) error {
+	return db.GetDB().Create(token).Error
+}
+
+// GetAPITokenByHash 根据令牌摘要查询
+func GetAPITokenByHash(hash string) (*APIToken, error) {
+	var token APIToken
+	if err := db.GetDB().Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// GetAPITokenByID 根据 ID 查询
+func GetAPITokenByID(id uint) (*APIToken, error) {
+	var token APIToken
+	if err := db.GetDB().First(&token, id).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// ListAPITokensByUserID 列出用户的 API 令牌
+func ListAPITokensByUserID(userID uint) ([]APIToken, error) {
+	var tokens []APIToken
+	err := db.GetDB().Where("user_id = ?", userID).Order("id desc").Find(&tokens).Error
+	return tokens, err
+}
+
+// ListAllAPITokens 列出全部 API 令牌（管理员）
+func ListAllAPITokens() ([]APIToken, error) {
+	var tokens []APIToken
+	err := db.GetDB().Order("id desc").Find(&tokens).Error
+	return tokens, err
+}
+
+// RevokeAPIToken 吊销令牌
+func RevokeAPIToken(id uint) error {
+	return db.GetDB().Model(&APIToken{}).Where("id = ? AND revoked_at IS NULL", id).Update("revoked_at", time.Now()).Error
+}
+
+// RevokeAPITokensByUserID 吊销用户的全部令牌
+func RevokeAPITokensByUserID(userID uint) error {
+	return db.GetDB().Model(&APIToken{}).Where("user_id = ? AND revoked_at IS NULL", userID).Update("revoked_at", time.Now()).Error
+}
+
+// TouchAPIToken 记录令牌最近使用时间与来源 IP
+func TouchAPIToken(id uint, ip string) error {
+	return db.GetDB().Model(&APIToken{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"last_used_at": time.Now(),
+		"last_used_ip": ip,
+	}).Error
+}
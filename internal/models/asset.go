@@ -0,0 +1,219 @@
+package models
+
+import (
+	"VulnFusion/internal/db"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 资产重要性等级
+const (
+	CriticalityLow      = "low"
+	CriticalityMedium   = "medium"
+	CriticalityHigh     = "high"
+	CriticalityCritical = "critical"
+)
+
+type Asset struct {
+	ID           uint      `gorm:"primaryKey"`
+	UserID       uint      `gorm:"uniqueIndex:idx_asset_host"`          // 个人资产所属用户，项目资产为 0
+	ProjectID    uint      `gorm:"uniqueIndex:idx_asset_host"`          // 所属项目，个人资产为 0
+	Host         string    `gorm:"not null;uniqueIndex:idx_asset_host"` // 主机名或 IP
+	IP           string    // 最近一次解析到的 IP
+	Ports        string    // 已发现端口（逗号分隔）
+	Endpoints    string    `gorm:"type:text"` // 已发现的服务地址（逗号分隔），如 https://app.example.com:8443
+	Technologies string    `gorm:"type:text"` // 识别出的技术栈（逗号分隔）
+	Owner        string    // 资产负责人
+	Tags         string    // 资产标签（逗号分隔）
+	Criticality  string    `gorm:"default:medium"` // 重要性：low / medium / high / critical
+	FirstSeen    time.Time // 首次发现时间
+	LastSeen     time.Time // 最近一次出现在扫描中的时间
+}
+
+// AssetObservation 一次扫描中对某个主机的观测
+type AssetObservation struct {
+	UserID       uint
+	ProjectID    uint
+	Host         string
+	IP           string
+	Port         int
+	Endpoint     string // 带协议或端口的服务地址，仅有主机名时为空
+	Technologies []string
+}
+
+// AssetOwnerOf 返回任务产生的资产归属：项目任务归项目，个人任务归创建人
+func AssetOwnerOf(task *Task) (userID uint, projectID uint) {
+	if task.ProjectID != 0 {
+		return 0, task.ProjectID
+	}
+	return task.UserID, 0
+}
+
+// UpsertAsset 按归属与主机名记录资产观测：不存在时创建，存在时合并端口与技术栈并刷新最近出现时间
+func UpsertAsset(obs AssetObservation) (*Asset, error) {
+	host := strings.ToLower(strings.TrimSpace(obs.Host))
+	if host == "" {
+		return nil, errors.New("资产主机为空")
+	}
+
+	var asset Asset
+	err := db.GetDB().Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		err := tx.Where("user_id = ? AND project_id = ? AND host = ?", obs.UserID, obs.ProjectID, host).First(&asset).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			asset = Asset{
+				UserID:       obs.UserID,
+				ProjectID:    obs.ProjectID,
+				Host:         host,
+				IP:           obs.IP,
+				Ports:        mergePorts("", obs.Port),
+				Endpoints:    mergeList("", obs.Endpoint),
+				Technologies: mergeList("", obs.Technologies...),
+				Criticality:  CriticalityMedium,
+				FirstSeen:    now,
+				LastSeen:     now,
+			}
+			return tx.Create(&asset).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{
+			"ports":        mergePorts(asset.Ports, obs.Port),
+			"endpoints":    mergeList(asset.Endpoints, obs.Endpoint),
+			"technologies": mergeList(asset.Technologies, obs.Technologies...),
+			"last_seen":    now,
+		}
+		if obs.IP != "" {
+			updates["ip"] = obs.IP
+		}
+		return tx.Model(&asset).Updates(updates).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &asset, nil
+}
+
+// ScanTargets 返回再次扫描该资产时使用的目标：优先使用已发现的服务地址（保留协议与端口），否则使用主机名
+func (a *Asset) ScanTargets() []string {
+	if endpoints := strings.Split(a.Endpoints, ","); a.Endpoints != "" {
+		return endpoints
+	}
+	return []string{a.Host}
+}
+
+// ListAssetIDsByHosts 按归属与主机名查询资产 ID
+func ListAssetIDsByHosts(userID, projectID uint, hosts []string) ([]uint, error) {
+	var ids []uint
+	if len(hosts) == 0 {
+		return ids, nil
+	}
+	// 与 UpsertAsset 一致按小写主机名匹配
+	normalized := make([]string, 0, len(hosts))
+	for _, h := range hosts {
+		normalized = append(normalized, strings.ToLower(strings.TrimSpace(h)))
+	}
+	err := db.GetDB().Model(&Asset{}).
+		Where("user_id = ? AND project_id = ? AND host IN ?", userID, projectID, normalized).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// GetAssetByID 根据资产 ID 查询资产
+func GetAssetByID(id uint) (*Asset, error) {
+	var asset Asset
+	if err := db.GetDB().First(&asset, id).Error; err != nil {
+		return nil, err
+	}
+	return &asset, nil
+}
+
+// ListAssetsByIDs 根据资产 ID 列表批量查询资产
+func ListAssetsByIDs(ids []uint) ([]Asset, error) {
+	var assets []Asset
+	err := db.GetDB().Where("id IN ?", ids).Find(&assets).Error
+	return assets, err
+}
+
+// ListVisibleAssets 列出用户可见的资产：本人的个人资产及其所在项目的资产，projectID 非 0 时仅返回该项目
+func ListVisibleAssets(userID uint, projectID uint) ([]Asset, error) {
+	var assets []Asset
+	memberProjects := db.GetDB().Model(&ProjectMember{}).Select("project_id").Where("user_id = ?", userID)
+	query := db.GetDB().Where("(project_id = 0 AND user_id = ?) OR project_id IN (?)", userID, memberProjects)
+	if projectID != 0 {
+		query = query.Where("project_id = ?", projectID)
+	}
+	err := query.Order("last_seen desc").Find(&assets).Error
+	return assets, err
+}
+
+// ListAllAssets 列出系统中全部资产（管理员）
+func ListAllAssets(projectID uint) ([]Asset, error) {
+	var assets []Asset
+	query := db.GetDB()
+	if projectID != 0 {
+		query = query.Where("project_id = ?", projectID)
+	}
+	err := query.Order("last_seen desc").Find(&assets).Error
+	return assets, err
+}
+
+// UpdateAssetByID 更新资产的人工维护字段
+func UpdateAssetByID(id uint, updates map[string]interface{}) error {
+	return db.GetDB().Model(&Asset{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// IsValidCriticality 判断资产重要性等级是否合法
+func IsValidCriticality(c string) bool {
+	switch c {
+	case CriticalityLow, CriticalityMedium, CriticalityHigh, CriticalityCritical:
+		return true
+	}
+	return false
+}
+
+// mergeList 合并逗号分隔的列表并去重排序
+func mergeList(existing string, add ...string) string {
+	set := map[string]bool{}
+	for _, v := range append(strings.Split(existing, ","), add...) {
+		if v = strings.TrimSpace(v); v != "" {
+			set[v] = true
+		}
+	}
+	items := make([]string, 0, len(set))
+	for v := range set {
+		items = append(items, v)
+	}
+	sort.Strings(items)
+	return strings.Join(items, ",")
+}
+
+// mergePorts 将端口合并到逗号分隔的端口列表中，按数值排序
+func mergePorts(existing string, port int) string {
+	set := map[int]bool{}
+	for _, v := range strings.Split(existing, ",") {
+		if p, err := strconv.Atoi(strings.TrimSpace(v)); err == nil && p > 0 {
+			set[p] = true
+		}
+	}
+	if port > 0 {
+		set[port] = true
+	}
+	ports := make([]int, 0, len(set))
+	for p := range set {
+		ports = append(ports, p)
+	}
+	sort.Ints(ports)
+	items := make([]string, 0, len(ports))
+	for _, p := range ports {
+		items = append(items, strconv.Itoa(p))
+	}
+	return strings.Join(items, ",")
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"VulnFusion/internal/db"
+	"time"
+)
+
+// 审计事件类型常量定义
+const (
+	AuditScopeReject = "scope.reject" // 任务目标越界被拒绝
+	AuditScopeStrip  = "scope.strip"  // 越界目标被剔除
+	AuditScopeUpdate = "scope.update" // 扫描范围被修改
+
+	AuditProjectMember = "project.member" // 项目成员变更
+
+	AuditTokenCreate = "token.create" // 创建 API 令牌
+	AuditTokenRevoke = "token.revoke" // 吊销 API 令牌
+
+	AuditTokensRevokeAll  = "auth.revoke_all"    // 吊销用户全部登录令牌
+	AuditRefreshReuse     = "auth.refresh_reuse" // 已轮换的刷新令牌被再次使用
+	AuditSessionRevoke    = "session.revoke"     // 吊销单个登录会话
+	AuditSigningKeyRotate = "auth.key_rotate"    // 轮换 JWT 签名密钥
+
+	AuditMFAEnable   = "mfa.enable"           // 启用二次验证
+	AuditMFADisable  = "mfa.disable"          // 关闭二次验证
+	AuditMFAReset    = "mfa.reset"            // 管理员重置用户的二次验证
+	AuditMFARecovery = "mfa.recovery"         // 使用恢复码登录
+	AuditMFARegen    = "mfa.regenerate_codes" // 重新生成恢复码
+
+	AuditSSOProvision = "sso.provision" // 单点登录首次登录创建用户
+	AuditSSOLink      = "sso.link"      // 单点登录关联已有本地用户
+	AuditSSORole      = "sso.role"      // 单点登录同步角色变更
+
+	AuditLDAPProvision = "ldap.provision" // 目录用户首次登录创建用户
+	AuditLDAPLink      = "ldap.link"      // 目录用户关联同名本地用户
+	AuditLDAPRole      = "ldap.role"      // 目录组变化导致角色变更
+	AuditLDAPDenied    = "ldap.denied"    // 目录用户不在任何映射组中被拒绝登录
+
+	AuditUserRegister = "user.register" // 自助注册
+	AuditUserCreate   = "user.create"   // 管理员创建用户
+	AuditUserApprove  = "user.approve"  // 审批通过自助注册的用户
+	AuditUserRole     = "user.role"     // 管理员修改用户角色
+	AuditInviteCreate = "invite.create" // 生成邀请码
+	AuditInviteRevoke = "invite.revoke" // 作废邀请码
+
+	AuditLoginFailed = "auth.login_failed" // 密码登录失败
+	AuditLoginLocked = "auth.login_locked" // 连续登录失败导致账号被临时锁定
+	AuditLoginUnlock = "auth.login_unlock" // 管理员解除账号锁定
+)
+
+type AuditLog struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"index"` // 操作用户 ID，未登录操作为 0
+	Username  string    // 操作用户名
+	Action    string    `gorm:"index"` // 事件类型
+	Resource  string    // 相关资源，如 task:12
+	Detail    string    `gorm:"type:text"` // 事件详情
+	IP        string    // 来源 IP
+	CreatedAt time.Time `gorm:"autoCreateTime;index"`
+}
+
+// RecordAudit 写入一条审计记录
+func RecordAudit(entry *AuditLog) error {
+	return db.GetDB().Create(entry).Error
+}
+
+// ListAuditLogs 按时间倒序查询审计记录，action 为空时不过滤
+func ListAuditLogs(action string, limit int) ([]AuditLog, error) {
+	var logs []AuditLog
+	query := db.GetDB().Order("created_at desc")
+	if action != "" {
+		query = query.Where("action = ?", action)
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	err := query.Find(&logs).Error
+	return logs, err
+}
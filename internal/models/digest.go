@@ -0,0 +1,60 @@
+package models
+
+import (
+	"VulnFusion/internal/db"
+	"strings"
+	"time"
+)
+
+// 摘要频率
+const (
+	DigestOff    = ""
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+// DigestSeverities 摘要邮件收录的严重等级
+var DigestSeverities = []string{"high", "critical"}
+
+// IsValidDigest 判断摘要频率是否合法
+func IsValidDigest(freq string) bool {
+	return freq == DigestOff || freq == DigestDaily || freq == DigestWeekly
+}
+
+// ListDigestUsers 列出开启了摘要且填写了邮箱的用户
+func ListDigestUsers() ([]User, error) {
+	var users []User
+	err := db.GetDB().Where("digest <> '' AND email <> ''").Find(&users).Error
+	return users, err
+}
+
+// ListDigestProjects 列出开启了摘要且配置了收件人的项目
+func ListDigestProjects() ([]Project, error) {
+	var projects []Project
+	err := db.GetDB().Where("digest <> '' AND digest_emails <> ''").Find(&projects).Error
+	return projects, err
+}
+
+// MarkUserDigestSent 记录用户摘要发送时间
+func MarkUserDigestSent(userID uint, at time.Time) error {
+	return db.GetDB().Model(&User{}).Where("id = ?", userID).Update("digest_sent_at", at).Error
+}
+
+// MarkProjectDigestSent 记录项目摘要发送时间
+func MarkProjectDigestSent(projectID uint, at time.Time) error {
+	return db.GetDB().Model(&Project{}).Where("id = ?", projectID).Update("digest_sent_at", at).Error
+}
+
+// ListNewFindings 按可见范围列出 since 之后发现、仍未处置且属于指定严重等级的漏洞，按风险分降序
+func ListNewFindings(f StatsFilter, since time.Time, severities []string) ([]Result, error) {
+	lower := make([]string, len(severities))
+	for i, s := range severities {
+		lower[i] = strings.ToLower(s)
+	}
+	var results []Result
+	err := openResults(f).
+		Where("timestamp >= ? AND LOWER(severity) IN ?", since, lower).
+		Order("risk_score desc, id asc").
+		Find(&results).Error
+	return results, err
+}
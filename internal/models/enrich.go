@@ -0,0 +1,74 @@
+package models
+
+import (
+	"VulnFusion/internal/db"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+type CVEEntry struct {
+	ID          string    `gorm:"primaryKey"`          // CVE 编号，如 CVE-2021-44228
+	Description string    `gorm:"type:text"`           // 英文描述
+	CVSS3Score  float64   `gorm:"column:cvss3_score"`  // CVSS v3 基础分
+	CVSS3Vector string    `gorm:"column:cvss3_vector"` // CVSS v3 向量
+	CVSS2Score  float64   `gorm:"column:cvss2_score"`  // CVSS v2 基础分
+	CVSS2Vector string    `gorm:"column:cvss2_vector"` // CVSS v2 向量
+	CWEIDs      string    // 关联 CWE（逗号分隔）
+	CPEs        string    `gorm:"column:cpes;type:text"` // 受影响 CPE（换行分隔）
+	References  string    `gorm:"type:text"`             // 参考链接（换行分隔）
+	PublishedAt time.Time // 发布时间
+	ModifiedAt  time.Time // 最近修改时间
+}
+
+type CWEEntry struct {
+	ID          string `gorm:"primaryKey"` // CWE 编号，如 CWE-79
+	Name        string // 名称
+	Abstraction string // 抽象层级：Pillar / Class / Base / Variant
+	Description string `gorm:"type:text"` // 描述
+}
+
+// SaveCVEEntries 批量写入 CVE 数据，已存在的条目整体覆盖
+func SaveCVEEntries(entries []CVEEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	return db.GetDB().Clauses(clause.OnConflict{UpdateAll: true}).CreateInBatches(entries, 200).Error
+}
+
+// SaveCWEEntries 批量写入 CWE 数据，已存在的条目整体覆盖
+func SaveCWEEntries(entries []CWEEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	return db.GetDB().Clauses(clause.OnConflict{UpdateAll: true}).CreateInBatches(entries, 200).Error
+}
+
+// ListCVEEntries 按编号批量查询 CVE 数据
+func ListCVEEntries(ids []string) ([]CVEEntry, error) {
+	var entries []CVEEntry
+	if len(ids) == 0 {
+		return entries, nil
+	}
+	err := db.GetDB().Where("id IN ?", ids).Find(&entries).Error
+	return entries, err
+}
+
+// ListCWEEntries 按编号批量查询 CWE 数据
+func ListCWEEntries(ids []string) ([]CWEEntry, error) {
+	var entries []CWEEntry
+	if len(ids) == 0 {
+		return entries, nil
+	}
+	err := db.GetDB().Where("id IN ?", ids).Find(&entries).Error
+	return entries, err
+}
+
+// CountEnrichmentEntries 统计本地 CVE 与 CWE 条目数
+func CountEnrichmentEntries() (cves int64, cwes int64, err error) {
+	if err = db.GetDB().Model(&CVEEntry{}).Count(&cves).Error; err != nil {
+		return
+	}
+	err = db.GetDB().Model(&CWEEntry{}).Count(&cwes).Error
+	return
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"VulnFusion/internal/db"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidInvite 邀请码不存在、已使用、已作废或已过期
+var ErrInvalidInvite = errors.New("邀请码无效或已过期")
+
+type Invite struct {
+	ID        uint       `gorm:"primaryKey"`
+	CodeHash  string     `gorm:"uniqueIndex;not null" json:"-"` // 邀请码的 SHA-256 哈希，明文只在生成时展示一次
+	Prefix    string     // 邀请码前缀，用于在列表中辨认
+	Note      string     // 备注，如受邀人
+	CreatedBy uint       `gorm:"index"` // 生成邀请码的管理员
+	ExpiresAt time.Time  // 过期时间
+	UsedAt    *time.Time // 使用时间，为空表示未使用
+	UsedBy    uint       // 使用邀请码注册的用户
+	RevokedAt *time.Time // 作废时间
+	CreatedAt time.Time  `gorm:"autoCreateTime"`
+}
+
+// CreateInvite 保存邀请码
+func CreateInvite(invite *Invite) error {
+	return db.GetDB().Create(invite).Error
+}
+
+// ListInvites 按创建时间倒序列出邀请码
+func ListInvites() ([]Invite, error) {
+	var invites []Invite
+	err := db.GetDB().Order("id desc").Find(&invites).Error
+	return invites, err
+}
+
+// RevokeInvite 作废尚未使用的邀请码；不存在、已使用或已作废时返回 false
+func RevokeInvite(id uint) (bool, error) {
+	result := db.GetDB().Model(&Invite{}).Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now())
+	return result.RowsAffected == 1, result.Error
+}
+
+// CreateUserWithInvite 使用邀请码创建用户，邀请码只能使用一次；
+// 邀请码无效时返回 ErrInvalidInvite，创建用户失败时邀请码不会被消耗
+func CreateUserWithInvite(user *User, codeHash string) error {
+	return db.GetDB().Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&Invite{}).
+			Where("code_hash = ? AND used_at IS NULL AND revoked_at IS NULL AND expires_at > ?", codeHash, now).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidInvite
+		}
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		return tx.Model(&Invite{}).Where("code_hash = ?", codeHash).Update("used_by", user.ID).Error
+	})
+}
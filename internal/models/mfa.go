@@ -0,0 +1,92 @@
+package models
+
+import (
+	"time"
+
+	"VulnFusion/internal/db"
+
+	"gorm.io/gorm"
+)
+
+type RecoveryCode struct {
+	ID        uint       `gorm:"primaryKey"`
+	UserID    uint       `gorm:"index;not null"` // 所属用户
+	CodeHash  string     `gorm:"not null"`       // 恢复码的 SHA-256 哈希，明文只在生成时展示一次
+	UsedAt    *time.Time // 使用时间，为空表示未使用
+	CreatedAt time.Time  `gorm:"autoCreateTime"`
+}
+
+// SetPendingTOTPSecret 保存待确认的 TOTP 密钥，验证通过后才启用
+func SetPendingTOTPSecret(userID uint, secret string) error {
+	return db.GetDB().Model(&User{}).Where("id = ? AND totp_enabled = ?", userID, false).
+		Updates(map[string]interface{}{"totp_secret": secret, "totp_last_counter": 0}).Error
+}
+
+// EnableTOTP 启用二次验证并替换恢复码；counter 为确认时使用的验证码时间步
+func EnableTOTP(userID uint, counter int64, codeHashes []string) error {
+	return db.GetDB().Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&User{}).Where("id = ? AND totp_enabled = ? AND totp_secret <> ''", userID, false).
+			Updates(map[string]interface{}{"totp_enabled": true, "totp_last_counter": counter})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return replaceRecoveryCodes(tx, userID, codeHashes)
+	})
+}
+
+// DisableTOTP 关闭二次验证，清除密钥与恢复码
+func DisableTOTP(userID uint) error {
+	return db.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&User{}).Where("id = ?", userID).
+			Updates(map[string]interface{}{"totp_enabled": false, "totp_secret": "", "totp_last_counter": 0}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&RecoveryCode{}).Error
+	})
+}
+
+// UseTOTPCounter 记录已使用的验证码时间步；counter 不大于上次使用的值时返回 false（重放）
+func UseTOTPCounter(userID uint, counter int64) (bool, error) {
+	result := db.GetDB().Model(&User{}).Where("id = ? AND totp_last_counter < ?", userID, counter).
+		Update("totp_last_counter", counter)
+	return result.RowsAffected == 1, result.Error
+}
+
+// ReplaceRecoveryCodes 作废旧恢复码并保存新生成的恢复码哈希
+func ReplaceRecoveryCodes(userID uint, codeHashes []string) error {
+	return db.GetDB().Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, userID, codeHashes)
+	})
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userID uint, codeHashes []string) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&RecoveryCode{}).Error; err != nil {
+		return err
+	}
+	codes := make([]RecoveryCode, 0, len(codeHashes))
+	for _, h := range codeHashes {
+		codes = append(codes, RecoveryCode{UserID: userID, CodeHash: h})
+	}
+	if len(codes) == 0 {
+		return nil
+	}
+	return tx.Create(&codes).Error
+}
+
+// UseRecoveryCode 使用恢复码，每个恢复码只能使用一次；不存在或已使用时返回 false
+func UseRecoveryCode(userID uint, codeHash string) (bool, error) {
+	result := db.GetDB().Model(&RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	return result.RowsAffected == 1, result.Error
+}
+
+// CountUnusedRecoveryCodes 统计剩余可用的恢复码数量
+func CountUnusedRecoveryCodes(userID uint) (int64, error) {
+	var n int64
+	err := db.GetDB().Model(&RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).Count(&n).Error
+	return n, err
+}
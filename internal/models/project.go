@@ -0,0 +1,158 @@
+package models
+
+import (
+	"VulnFusion/internal/db"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 项目成员角色常量定义，权限依次递增
+const (
+	ProjectRoleViewer = "viewer" // 只读查看任务与结果
+	ProjectRoleEditor = "editor" // 可创建、删除任务与结果
+	ProjectRoleOwner  = "owner"  // 可管理成员、范围与项目本身
+)
+
+type Project struct {
+	ID          uint      `gorm:"primaryKey"`
+	Name        string    `gorm:"not null"`  // 项目名称
+	Description string    `gorm:"type:text"` // 项目描述
+	CreatedBy   uint      // 创建人
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+
+	Digest       string     // 项目高危漏洞摘要频率：空（关闭）/ daily / weekly
+	DigestEmails string     // 项目摘要收件人（逗号分隔）
+	DigestSentAt *time.Time // 最近一次发送摘要的时间
+}
+
+type ProjectMember struct {
+	ID        uint      `gorm:"primaryKey"`
+	ProjectID uint      `gorm:"not null;uniqueIndex:idx_project_member"` // 所属项目
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_project_member"` // 成员用户 ID
+	Role      string    `gorm:"not null"`                                // owner / editor / viewer
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// ProjectRoleRank 返回项目角色的权限等级，未知角色为 0
+func ProjectRoleRank(role string) int {
+	switch role {
+	case ProjectRoleViewer:
+		return 1
+	case ProjectRoleEditor:
+		return 2
+	case ProjectRoleOwner:
+		return 3
+	}
+	return 0
+}
+
+// CreateProject 创建项目，并将创建人加入为 owner
+func CreateProject(project *Project) error {
+	return db.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(project).Error; err != nil {
+			return err
+		}
+		return tx.Create(&ProjectMember{
+			ProjectID: project.ID,
+			UserID:    project.CreatedBy,
+			Role:      ProjectRoleOwner,
+		}).Error
+	})
+}
+
+// GetProjectByID 根据项目 ID 查询项目
+func GetProjectByID(id uint) (*Project, error) {
+	var project Project
+	if err := db.GetDB().First(&project, id).Error; err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// ListAllProjects 列出所有项目（管理员）
+func ListAllProjects() ([]Project, error) {
+	var projects []Project
+	err := db.GetDB().Order("created_at desc").Find(&projects).Error
+	return projects, err
+}
+
+// ListProjectsByUserID 列出用户参与的所有项目
+func ListProjectsByUserID(userID uint) ([]Project, error) {
+	var projects []Project
+	err := db.GetDB().
+		Where("id IN (?)", db.GetDB().Model(&ProjectMember{}).Select("project_id").Where("user_id = ?", userID)).
+		Order("created_at desc").
+		Find(&projects).Error
+	return projects, err
+}
+
+// UpdateProjectByID 更新项目基本信息
+func UpdateProjectByID(id uint, updates map[string]interface{}) error {
+	return db.GetDB().Model(&Project{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// DeleteProjectByID 删除项目及其成员与范围，项目下的任务转为创建人个人任务
+func DeleteProjectByID(id uint) error {
+	return db.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&Task{}).Where("project_id = ?", id).Update("project_id", 0).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("project_id = ?", id).Delete(&ProjectMember{}).Error; err != nil {
+			return err
+		}
+		var scopeIDs []uint
+		if err := tx.Model(&Scope{}).Where("project_id = ?", id).Pluck("id", &scopeIDs).Error; err != nil {
+			return err
+		}
+		if len(scopeIDs) > 0 {
+			if err := tx.Where("scope_id IN ?", scopeIDs).Delete(&ScopeRule{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("id IN ?", scopeIDs).Delete(&Scope{}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&Project{}, id).Error
+	})
+}
+
+// GetProjectMember 查询用户在项目中的成员记录
+func GetProjectMember(projectID, userID uint) (*ProjectMember, error) {
+	var member ProjectMember
+	err := db.GetDB().Where("project_id = ? AND user_id = ?", projectID, userID).First(&member).Error
+	if err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+// ListProjectMembers 列出项目的所有成员
+func ListProjectMembers(projectID uint) ([]ProjectMember, error) {
+	var members []ProjectMember
+	err := db.GetDB().Where("project_id = ?", projectID).Order("id asc").Find(&members).Error
+	return members, err
+}
+
+// SaveProjectMember 添加成员或更新已有成员的角色
+func SaveProjectMember(projectID, userID uint, role string) error {
+	member, err := GetProjectMember(projectID, userID)
+	if err != nil {
+		return db.GetDB().Create(&ProjectMember{ProjectID: projectID, UserID: userID, Role: role}).Error
+	}
+	return db.GetDB().Model(member).Update("role", role).Error
+}
+
+// RemoveProjectMember 移除项目成员
+func RemoveProjectMember(projectID, userID uint) error {
+	return db.GetDB().Where("project_id = ? AND user_id = ?", projectID, userID).Delete(&ProjectMember{}).Error
+}
+
+// CountProjectOwners 统计项目 owner 数量，用于防止移除最后一个 owner
+func CountProjectOwners(projectID uint) (int64, error) {
+	var count int64
+	err := db.GetDB().Model(&ProjectMember{}).
+		Where("project_id = ? AND role = ?", projectID, ProjectRoleOwner).
+		Count(&count).Error
+	return count, err
+}
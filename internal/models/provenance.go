@@ -0,0 +1,36 @@
+package models
+
+import (
+	"VulnFusion/internal/db"
+	"time"
+)
+
+// ResultBatch 记录一次经过签名校验的结果回传批次，保存原始 payload 以便事后复核
+type ResultBatch struct {
+	ID            uint      `gorm:"primaryKey"`
+	TaskID        uint      `gorm:"not null;uniqueIndex:idx_batch_seq"` // 所属任务 ID
+	AgentID       uint      `gorm:"not null;uniqueIndex:idx_batch_seq"` // 签名节点 ID
+	Sequence      int       `gorm:"uniqueIndex:idx_batch_seq"`          // 批次序号，防止重放
+	Source        string    // 来源：agent（节点回传）/ import（人工导入）
+	NucleiVersion string    // nuclei 版本
+	TemplateHash  string    // 模板库摘要
+	PayloadHash   string    `gorm:"uniqueIndex"` // payload 的 SHA-256 摘要
+	Payload       string    `gorm:"type:text"`   // 原始签名 payload（base64）
+	Signature     string    `gorm:"type:text"`   // ed25519 签名（base64）
+	PublicKey     string    // 签名时节点登记的公钥，节点轮换密钥后仍可复核
+	ReceivedAt    time.Time `gorm:"autoCreateTime"`
+}
+
+// CreateResultBatch 保存结果批次记录
+func CreateResultBatch(batch *ResultBatch) error {
+	return db.GetDB().Create(batch).Error
+}
+
+// GetResultBatchByID 根据批次 ID 查询批次记录
+func GetResultBatchByID(id uint) (*ResultBatch, error) {
+	var batch ResultBatch
+	if err := db.GetDB().First(&batch, id).Error; err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
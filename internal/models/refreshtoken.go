@@ -0,0 +1,57 @@
+package models
+
+import (
+	"VulnFusion/internal/db"
+	"time"
+)
+
+type RefreshToken struct {
+	ID        uint       `gorm:"primaryKey"`
+	UserID    uint       `gorm:"index"`                // 所属用户
+	Family    string     `gorm:"index;not null"`       // 令牌族，同一次登录轮换出的刷新令牌共享
+	JTI       string     `gorm:"uniqueIndex;not null"` // 令牌 ID
+	ParentJTI string     // 轮换前的令牌 ID，登录签发的为空
+	ExpiresAt time.Time  `gorm:"index"`
+	RotatedAt *time.Time // 已被使用并轮换的时间，再次出现即为重放
+	RevokedAt *time.Time // 吊销时间
+	CreatedAt time.Time  `gorm:"autoCreateTime"`
+}
+
+// CreateRefreshToken 保存新签发的刷新令牌
+func CreateRefreshToken(token *RefreshToken) error {
+	return db.GetDB().Create(token).Error
+}
+
+// GetRefreshTokenByJTI 根据令牌 ID 查询
+func GetRefreshTokenByJTI(jti string) (*RefreshToken, error) {
+	var token RefreshToken
+	if err := db.GetDB().Where("jti = ?", jti).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// MarkRefreshTokenRotated 将刷新令牌标记为已轮换；仅当令牌未被使用且未吊销时成功，
+// 返回 false 表示令牌已被使用过（重放）或已吊销
+func MarkRefreshTokenRotated(id uint) (bool, error) {
+	result := db.GetDB().Model(&RefreshToken{}).
+		Where("id = ? AND rotated_at IS NULL AND revoked_at IS NULL", id).
+		Update("rotated_at", time.Now())
+	return result.RowsAffected == 1, result.Error
+}
+
+// RevokeRefreshTokensByFamily 吊销令牌族内全部刷新令牌
+func RevokeRefreshTokensByFamily(family string) error {
+	return db.GetDB().Model(&RefreshToken{}).Where("family = ? AND revoked_at IS NULL", family).Update("revoked_at", time.Now()).Error
+}
+
+// RevokeRefreshTokensByUserID 吊销用户的全部刷新令牌
+func RevokeRefreshTokensByUserID(userID uint) error {
+	return db.GetDB().Model(&RefreshToken{}).Where("user_id = ? AND revoked_at IS NULL", userID).Update("revoked_at", time.Now()).Error
+}
+
+// DeleteExpiredRefreshTokens 删除已过期的刷新令牌记录
+func DeleteExpiredRefreshTokens(now time.Time) (int64, error) {
+	result := db.GetDB().Where("expires_at <= ?", now).Delete(&RefreshToken{})
+	return result.RowsAffected, result.Error
+}
@@ -2,7 +2,18 @@ package models
 
 import (
 	"VulnFusion/internal/db"
+	"errors"
 	"time"
+
+	"gorm.io/gorm"
+)
+
+// 漏洞处置状态
+const (
+	ResultStatusOpen          = "open"           // 未处置
+	ResultStatusFixed         = "fixed"          // 已修复
+	ResultStatusAccepted      = "accepted"       // 已接受风险
+	ResultStatusFalsePositive = "false_positive" // 误报
 )
 
 type Result struct {
@@ -12,7 +23,45 @@ type Result struct {
 	Vulnerability string    `gorm:"not null"`       // 漏洞名称或标识
 	Severity      string    `gorm:"default:medium"` // 风险等级：low / medium / high / critical
 	Detail        string    `gorm:"type:text"`      // 详细信息（原始输出或解析后的内容）
-	Timestamp     time.Time `gorm:"autoCreateTime"` // 记录时间
+	Timestamp     time.Time `gorm:"autoCreateTime"` // 记录时间（首次发现时间）
+	LastSeen      time.Time // 最近一次被检出的时间
+
+	// 结果来源信息
+	AgentID       uint   // 回传结果的远程节点 ID，服务端本地扫描为 0
+	BatchID       uint   // 签名批次 ID，服务端本地扫描为 0
+	NucleiVersion string // 执行扫描的 nuclei 版本
+	TemplateHash  string // 模板库摘要
+
+	// 资产与处置信息
+	Host     string     // nuclei 输出中的主机
+	IP       string     // nuclei 输出中的 IP
+	AssetID  uint       `gorm:"index"`        // 关联资产 ID
+	Status   string     `gorm:"default:open"` // 处置状态：open / fixed / accepted / false_positive
+	ClosedAt *time.Time // 关闭时间，open 状态为空
+
+	// 风险评分信息
+	TemplateID   string  // nuclei 模板 ID
+	CVSSScore    float64 // CVSS 基础分
+	CVSSVector   string  // CVSS 向量
+	CVEID        string  // 关联 CVE（逗号分隔）
+	CWEID        string  // 关联 CWE（逗号分隔）
+	ExploitScore float64 // 利用概率（0~1）
+	RiskScore    float64 `gorm:"index"` // 综合风险分（0~100）
+
+	// 工单信息
+	TicketConnectorID uint   // 创建工单的连接器 ID
+	TicketKey         string // 工单编号（Jira Key / GitLab iid / GitHub number）
+	TicketURL         string // 工单链接
+	TicketStatus      string // 工单最近一次同步的状态
+}
+
+// IsValidResultStatus 判断处置状态是否合法
+func IsValidResultStatus(status string) bool {
+	switch status {
+	case ResultStatusOpen, ResultStatusFixed, ResultStatusAccepted, ResultStatusFalsePositive:
+		return true
+	}
+	return false
 }
 
 // SaveScanResult 保存单条扫描结果
@@ -20,17 +69,100 @@ func SaveScanResult(result *Result) error {
 	return db.GetDB().Create(result).Error
 }
 
-// ListResultsByTaskID 根据任务 ID 获取所有扫描结果
+// UpsertFinding 按资产、模板与命中位置合并扫描结果：首次发现时新建；再次发现时归入本次任务并刷新内容，
+// 已修复的结果重新打开，其余处置状态与工单信息保持不变。返回 true 表示新增或重新打开
+func UpsertFinding(result *Result) (bool, error) {
+	result.LastSeen = time.Now()
+	if result.AssetID == 0 || result.TemplateID == "" {
+		return true, db.GetDB().Create(result).Error
+	}
+
+	fresh := false
+	err := db.GetDB().Transaction(func(tx *gorm.DB) error {
+		var existing Result
+		err := tx.Where("asset_id = ? AND template_id = ? AND target = ?", result.AssetID, result.TemplateID, result.Target).
+			Order("id desc").First(&existing).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			fresh = true
+			return tx.Create(result).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{
+			"task_id":        result.TaskID,
+			"vulnerability":  result.Vulnerability,
+			"severity":       result.Severity,
+			"detail":         result.Detail,
+			"agent_id":       result.AgentID,
+			"batch_id":       result.BatchID,
+			"nuclei_version": result.NucleiVersion,
+			"template_hash":  result.TemplateHash,
+			"host":           result.Host,
+			"ip":             result.IP,
+			"cvss_score":     result.CVSSScore,
+			"cvss_vector":    result.CVSSVector,
+			"cve_id":         result.CVEID,
+			"cwe_id":         result.CWEID,
+			"exploit_score":  result.ExploitScore,
+			"risk_score":     result.RiskScore,
+			"last_seen":      result.LastSeen,
+		}
+		if existing.Status == ResultStatusFixed {
+			fresh = true
+			updates["status"] = ResultStatusOpen
+			updates["closed_at"] = nil
+		}
+		if err := tx.Model(&existing).Updates(updates).Error; err != nil {
+			return err
+		}
+		return tx.First(result, existing.ID).Error
+	})
+	return fresh, err
+}
+
+// CloseMissingFindings 任务完成后，将所扫描资产上由相同扫描配置检出、但本次未再检出的未处置结果标记为已修复，返回关闭条数
+func CloseMissingFindings(task *Task, assetIDs []uint) (int64, error) {
+	if len(assetIDs) == 0 {
+		return 0, nil
+	}
+	sameScan := db.GetDB().Model(&Task{}).Select("id").
+		Where("template = ? AND mode = ? AND selected_tags = ?", task.Template, task.Mode, task.SelectedTags)
+	res := db.GetDB().Model(&Result{}).
+		Where("status = ? AND asset_id IN ? AND task_id <> ? AND task_id IN (?)", ResultStatusOpen, assetIDs, task.ID, sameScan).
+		Updates(map[string]interface{}{"status": ResultStatusFixed, "closed_at": time.Now()})
+	return res.RowsAffected, res.Error
+}
+
+// ListResultsByTaskID 根据任务 ID 获取所有扫描结果，按风险分降序
 func ListResultsByTaskID(taskID uint) ([]Result, error) {
 	var results []Result
-	err := db.GetDB().Where("task_id = ?", taskID).Find(&results).Error
+	err := db.GetDB().Where("task_id = ?", taskID).Order("risk_score desc, id asc").Find(&results).Error
+	return results, err
+}
+
+// CountResultsByTaskID 统计任务的扫描结果条数
+func CountResultsByTaskID(taskID uint) (int64, error) {
+	var count int64
+	err := db.GetDB().Model(&Result{}).Where("task_id = ?", taskID).Count(&count).Error
+	return count, err
+}
+
+// ListAcceptedResultsByAssetIDs 列出指定资产上已接受风险的结果，用于沿用历史处置
+func ListAcceptedResultsByAssetIDs(assetIDs []uint) ([]Result, error) {
+	var results []Result
+	if len(assetIDs) == 0 {
+		return results, nil
+	}
+	err := db.GetDB().Where("asset_id IN ? AND status = ?", assetIDs, ResultStatusAccepted).Find(&results).Error
 	return results, err
 }
 
-// ListAllResults 获取系统所有扫描结果（管理员）
+// ListAllResults 获取系统所有扫描结果（管理员），按风险分降序
 func ListAllResults() ([]Result, error) {
 	var results []Result
-	err := db.GetDB().Find(&results).Error
+	err := db.GetDB().Order("risk_score desc, id asc").Find(&results).Error
 	return results, err
 }
 
@@ -48,3 +180,20 @@ func GetResultByID(resultID uint) (*Result, error) {
 func DeleteResultsByTaskID(taskID uint) error {
 	return db.GetDB().Where("task_id = ?", taskID).Delete(&Result{}).Error
 }
+
+// ListOpenResultsByAssetID 获取资产下所有未处置的扫描结果，按风险分降序
+func ListOpenResultsByAssetID(assetID uint) ([]Result, error) {
+	var results []Result
+	err := db.GetDB().Where("asset_id = ? AND status = ?", assetID, ResultStatusOpen).
+		Order("risk_score desc, timestamp desc").Find(&results).Error
+	return results, err
+}
+
+// UpdateResultStatus 更新结果处置状态，非 open 状态记录关闭时间
+func UpdateResultStatus(resultID uint, status string) error {
+	updates := map[string]interface{}{"status": status, "closed_at": nil}
+	if status != ResultStatusOpen {
+		updates["closed_at"] = time.Now()
+	}
+	return db.GetDB().Model(&Result{}).Where("id = ?", resultID).Updates(updates).Error
+}
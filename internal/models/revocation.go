@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+
+	"VulnFusion/internal/auth"
+	"VulnFusion/internal/db"
+
+	"gorm.io/gorm/clause"
+)
+
+type TokenRevocation struct {
+	ID        uint      `gorm:"primaryKey"`
+	Kind      string    `gorm:"uniqueIndex:idx_revocation_key;not null"` // 吊销类型：jti / user / family
+	Value     string    `gorm:"uniqueIndex:idx_revocation_key;not null"` // JTI、用户 ID 或令牌族 ID
+	RevokedAt time.Time // 吊销时间
+	ExpiresAt time.Time `gorm:"index"` // 记录过期时间，之后相关令牌均已自然过期
+}
+
+// RevocationStore 基于数据库的令牌吊销存储，实现 auth.RevocationStore
+type RevocationStore struct{}
+
+// SaveRevocation 写入吊销记录，已存在时刷新吊销时间与过期时间
+func (RevocationStore) SaveRevocation(r auth.Revocation) error {
+	return db.GetDB().Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "kind"}, {Name: "value"}},
+		DoUpdates: clause.AssignmentColumns([]string{"revoked_at", "expires_at"}),
+	}).Create(&TokenRevocation{Kind: r.Kind, Value: r.Value, RevokedAt: r.RevokedAt, ExpiresAt: r.ExpiresAt}).Error
+}
+
+// FindRevocation 查询吊销记录，不存在时返回 nil, nil
+func (RevocationStore) FindRevocation(kind, value string) (*auth.Revocation, error) {
+	// 绝大多数令牌未被吊销，用 Find 避免记录不存在时的错误日志
+	var recs []TokenRevocation
+	if err := db.GetDB().Where("kind = ? AND value = ?", kind, value).Limit(1).Find(&recs).Error; err != nil {
+		return nil, err
+	}
+	if len(recs) == 0 {
+		return nil, nil
+	}
+	rec := recs[0]
+	return &auth.Revocation{Kind: rec.Kind, Value: rec.Value, RevokedAt: rec.RevokedAt, ExpiresAt: rec.ExpiresAt}, nil
+}
+
+// DeleteExpiredRevocations 删除已过期的吊销记录，并顺带清理已过期的刷新令牌与会话记录
+func (RevocationStore) DeleteExpiredRevocations(now time.Time) (int64, error) {
+	result := db.GetDB().Where("expires_at <= ?", now).Delete(&TokenRevocation{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	refreshed, err := DeleteExpiredRefreshTokens(now)
+	if err != nil {
+		return result.RowsAffected, err
+	}
+	sessions, err := DeleteExpiredSessions(now)
+	return result.RowsAffected + refreshed + sessions, err
+}
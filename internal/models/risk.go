@@ -0,0 +1,70 @@
+package models
+
+import (
+	"VulnFusion/internal/db"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ExploitLikelihood struct {
+	CVEID      string    `gorm:"primaryKey"` // CVE 编号（大写）
+	Score      float64   // 利用概率（0~1）
+	Percentile float64   // 百分位（0~1）
+	UpdatedAt  time.Time `gorm:"autoUpdateTime"`
+}
+
+// ImportExploitLikelihood 批量写入利用可能性数据，已存在的 CVE 覆盖更新
+func ImportExploitLikelihood(entries []ExploitLikelihood) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	return db.GetDB().Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "cve_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"score", "percentile", "updated_at"}),
+	}).CreateInBatches(entries, 500).Error
+}
+
+// GetMaxExploitScore 返回一组 CVE 中最高的利用概率，无数据时为 0
+func GetMaxExploitScore(cveIDs []string) (float64, error) {
+	if len(cveIDs) == 0 {
+		return 0, nil
+	}
+	var score *float64
+	err := db.GetDB().Model(&ExploitLikelihood{}).
+		Where("cve_id IN ?", cveIDs).
+		Select("MAX(score)").Scan(&score).Error
+	if err != nil || score == nil {
+		return 0, err
+	}
+	return *score, nil
+}
+
+// CountExploitLikelihood 统计已导入的利用可能性条数
+func CountExploitLikelihood() (int64, error) {
+	var count int64
+	err := db.GetDB().Model(&ExploitLikelihood{}).Count(&count).Error
+	return count, err
+}
+
+// EachResultBatch 分批遍历全部扫描结果
+func EachResultBatch(size int, fn func(results []Result) error) error {
+	var results []Result
+	return db.GetDB().FindInBatches(&results, size, func(tx *gorm.DB, batch int) error {
+		return fn(results)
+	}).Error
+}
+
+// ListResultsByAssetID 获取资产下的全部扫描结果
+func ListResultsByAssetID(assetID uint) ([]Result, error) {
+	var results []Result
+	err := db.GetDB().Where("asset_id = ?", assetID).Find(&results).Error
+	return results, err
+}
+
+// UpdateResultRisk 更新结果的利用概率与风险分
+func UpdateResultRisk(resultID uint, exploit, score float64) error {
+	return db.GetDB().Model(&Result{}).Where("id = ?", resultID).
+		Updates(map[string]interface{}{"exploit_score": exploit, "risk_score": score}).Error
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"VulnFusion/internal/db"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Scope struct {
+	ID        uint        `gorm:"primaryKey"`
+	UserID    uint        `gorm:"index"` // 所属用户（个人范围）
+	ProjectID uint        `gorm:"index"` // 所属项目（项目范围），个人范围为 0
+	Name      string      // 范围名称，如某次渗透测试的合同编号
+	CreatedAt time.Time   `gorm:"autoCreateTime"`
+	UpdatedAt time.Time   `gorm:"autoUpdateTime"`
+	Rules     []ScopeRule `gorm:"foreignKey:ScopeID"`
+}
+
+type ScopeRule struct {
+	ID      uint   `gorm:"primaryKey"`
+	ScopeID uint   `gorm:"index;not null"` // 所属范围
+	Action  string `gorm:"not null"`       // include / exclude
+	Type    string `gorm:"not null"`       // domain / cidr / url / port
+	Value   string `gorm:"not null"`       // 规则值
+}
+
+// GetScopeByUserID 查询用户的扫描范围及其规则，不存在时返回 gorm.ErrRecordNotFound
+func GetScopeByUserID(userID uint) (*Scope, error) {
+	var scope Scope
+	if err := db.GetDB().Preload("Rules").Where("user_id = ? AND project_id = 0", userID).First(&scope).Error; err != nil {
+		return nil, err
+	}
+	return &scope, nil
+}
+
+// GetScopeByProjectID 查询项目的扫描范围及其规则，不存在时返回 gorm.ErrRecordNotFound
+func GetScopeByProjectID(projectID uint) (*Scope, error) {
+	var scope Scope
+	if err := db.GetDB().Preload("Rules").Where("project_id = ?", projectID).First(&scope).Error; err != nil {
+		return nil, err
+	}
+	return &scope, nil
+}
+
+// SaveScopeWithRules 保存范围并整体替换其规则
+func SaveScopeWithRules(scope *Scope, rules []ScopeRule) error {
+	return db.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Rules").Save(scope).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("scope_id = ?", scope.ID).Delete(&ScopeRule{}).Error; err != nil {
+			return err
+		}
+		for i := range rules {
+			rules[i].ID = 0
+			rules[i].ScopeID = scope.ID
+		}
+		if len(rules) > 0 {
+			if err := tx.Create(&rules).Error; err != nil {
+				return err
+			}
+		}
+		scope.Rules = rules
+		return nil
+	})
+}
+
+// DeleteScopeByID 删除范围及其规则
+func DeleteScopeByID(id uint) error {
+	return db.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("scope_id = ?", id).Delete(&ScopeRule{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&Scope{}, id).Error
+	})
+}
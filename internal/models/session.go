@@ -0,0 +1,71 @@
+package models
+
+import (
+	"VulnFusion/internal/db"
+	"time"
+)
+
+type Session struct {
+	ID         uint       `gorm:"primaryKey"`
+	UserID     uint       `gorm:"index"`                         // 所属用户
+	Family     string     `gorm:"uniqueIndex;not null" json:"-"` // 令牌族，一次登录对应一个会话
+	Device     string     // 设备描述，如 "Chrome / Windows"
+	UserAgent  string     // 登录时的 User-Agent
+	IP         string     // 最近使用来源 IP
+	CreatedAt  time.Time  `gorm:"autoCreateTime"`
+	LastUsedAt time.Time  // 最近登录或刷新时间
+	ExpiresAt  time.Time  // 最新刷新令牌的过期时间
+	RevokedAt  *time.Time // 吊销时间
+}
+
+// Active 判断会话是否未吊销且未过期
+func (s *Session) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// CreateSession 登录时创建会话
+func CreateSession(session *Session) error {
+	return db.GetDB().Create(session).Error
+}
+
+// GetSessionByID 根据 ID 查询会话
+func GetSessionByID(id uint) (*Session, error) {
+	var session Session
+	if err := db.GetDB().First(&session, id).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// ListActiveSessionsByUserID 列出用户未吊销且未过期的会话，最近使用的在前
+func ListActiveSessionsByUserID(userID uint) ([]Session, error) {
+	var sessions []Session
+	err := db.GetDB().Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_used_at desc").Find(&sessions).Error
+	return sessions, err
+}
+
+// TouchSession 刷新令牌时更新会话的最近使用时间、来源 IP 与过期时间
+func TouchSession(family, ip string, expiresAt time.Time) error {
+	return db.GetDB().Model(&Session{}).Where("family = ?", family).Updates(map[string]interface{}{
+		"last_used_at": time.Now(),
+		"ip":           ip,
+		"expires_at":   expiresAt,
+	}).Error
+}
+
+// RevokeSessionByFamily 吊销令牌族对应的会话
+func RevokeSessionByFamily(family string) error {
+	return db.GetDB().Model(&Session{}).Where("family = ? AND revoked_at IS NULL", family).Update("revoked_at", time.Now()).Error
+}
+
+// RevokeSessionsByUserID 吊销用户的全部会话
+func RevokeSessionsByUserID(userID uint) error {
+	return db.GetDB().Model(&Session{}).Where("user_id = ? AND revoked_at IS NULL", userID).Update("revoked_at", time.Now()).Error
+}
+
+// DeleteExpiredSessions 删除已过期的会话记录
+func DeleteExpiredSessions(now time.Time) (int64, error) {
+	result := db.GetDB().Where("expires_at <= ?", now).Delete(&Session{})
+	return result.RowsAffected, result.Error
+}
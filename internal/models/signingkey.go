@@ -0,0 +1,60 @@
+package models
+
+import (
+	"encoding/base64"
+	"time"
+
+	"VulnFusion/internal/auth"
+	"VulnFusion/internal/db"
+)
+
+type SigningKey struct {
+	ID         uint       `gorm:"primaryKey"`
+	KeyID      string     `gorm:"uniqueIndex;not null"` // 密钥 ID（kid），写入 JWT 头
+	Algorithm  string     `gorm:"not null"`             // HS256 / EdDSA / RS256
+	PrivateKey string     `gorm:"not null"`             // base64 编码的密钥材料（HS256 密钥或 PKCS#8 私钥）
+	CreatedAt  time.Time  // 创建时间
+	RetiredAt  *time.Time `gorm:"index"` // 轮换下线时间，为空表示当前密钥
+}
+
+// SigningKeyStore 基于数据库的签名密钥存储，实现 auth.KeyStore
+type SigningKeyStore struct{}
+
+// ListSigningKeys 返回全部签名密钥
+func (SigningKeyStore) ListSigningKeys() ([]auth.SigningKey, error) {
+	var recs []SigningKey
+	if err := db.GetDB().Order("created_at DESC").Find(&recs).Error; err != nil {
+		return nil, err
+	}
+	keys := make([]auth.SigningKey, 0, len(recs))
+	for _, rec := range recs {
+		material, err := base64.StdEncoding.DecodeString(rec.PrivateKey)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, auth.SigningKey{ID: rec.KeyID, Algorithm: rec.Algorithm, Material: material, CreatedAt: rec.CreatedAt, RetiredAt: rec.RetiredAt})
+	}
+	return keys, nil
+}
+
+// SaveSigningKey 保存新生成的签名密钥
+func (SigningKeyStore) SaveSigningKey(key auth.SigningKey) error {
+	return db.GetDB().Create(&SigningKey{
+		KeyID:      key.ID,
+		Algorithm:  key.Algorithm,
+		PrivateKey: base64.StdEncoding.EncodeToString(key.Material),
+		CreatedAt:  key.CreatedAt,
+		RetiredAt:  key.RetiredAt,
+	}).Error
+}
+
+// RetireSigningKey 将密钥标记为下线，已下线的保持原下线时间
+func (SigningKeyStore) RetireSigningKey(kid string, at time.Time) error {
+	return db.GetDB().Model(&SigningKey{}).Where("key_id = ? AND retired_at IS NULL", kid).Update("retired_at", at).Error
+}
+
+// DeleteRetiredSigningKeys 删除在 before 之前下线的密钥
+func (SigningKeyStore) DeleteRetiredSigningKeys(before time.Time) (int64, error) {
+	result := db.GetDB().Where("retired_at IS NOT NULL AND retired_at < ?", before).Delete(&SigningKey{})
+	return result.RowsAffected, result.Error
+}
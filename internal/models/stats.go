@@ -0,0 +1,185 @@
+package models
+
+import (
+	"VulnFusion/internal/db"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// StatsFilter 统计范围：非管理员仅统计可见任务，ProjectID 非 0 时仅统计该项目
+type StatsFilter struct {
+	UserID    uint
+	ProjectID uint
+	All       bool      // 管理员统计全部任务
+	Since     time.Time // 时间序列起始日期
+	Limit     int       // Top N 条数
+}
+
+// NamedCount 名称与计数
+type NamedCount struct {
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+// DailyCount 单日新增与关闭的漏洞数
+type DailyCount struct {
+	Date   string `json:"date"`
+	Opened int64  `json:"opened"`
+	Closed int64  `json:"closed"`
+}
+
+// Stats 仪表盘统计数据
+type Stats struct {
+	TotalTasks    int64            `json:"total_tasks"`
+	TotalFindings int64            `json:"total_findings"`
+	OpenFindings  int64            `json:"open_findings"`
+	Severity      map[string]int64 `json:"severity"`       // 未处置漏洞按严重等级计数
+	FindingStatus map[string]int64 `json:"finding_status"` // 漏洞按处置状态计数
+	TaskStatus    map[string]int64 `json:"task_status"`    // 任务按状态计数
+	TopTemplates  []NamedCount     `json:"top_templates"`  // 未处置漏洞最多的模板
+	TopHosts      []NamedCount     `json:"top_hosts"`      // 未处置漏洞最多的主机
+	Daily         []DailyCount     `json:"daily"`          // 每日新增与关闭数
+	MTTRHours     float64          `json:"mttr_hours"`     // 平均修复时长（小时），仅统计已修复漏洞
+}
+
+// GetStats 按可见范围聚合任务与漏洞统计
+func GetStats(f StatsFilter) (*Stats, error) {
+	if f.Limit <= 0 {
+		f.Limit = 10
+	}
+	stats := &Stats{
+		Severity:      map[string]int64{},
+		FindingStatus: map[string]int64{},
+		TaskStatus:    map[string]int64{},
+		TopTemplates:  []NamedCount{},
+		TopHosts:      []NamedCount{},
+		Daily:         []DailyCount{},
+	}
+
+	var taskStatus []NamedCount
+	if err := visibleTasks(f).Select("status AS name, COUNT(*) AS count").Group("status").Scan(&taskStatus).Error; err != nil {
+		return nil, err
+	}
+	for _, c := range taskStatus {
+		stats.TaskStatus[c.Name] = c.Count
+		stats.TotalTasks += c.Count
+	}
+
+	var findingStatus []NamedCount
+	if err := visibleResults(f).Select("status AS name, COUNT(*) AS count").Group("status").Scan(&findingStatus).Error; err != nil {
+		return nil, err
+	}
+	for _, c := range findingStatus {
+		stats.FindingStatus[c.Name] = c.Count
+		stats.TotalFindings += c.Count
+	}
+	stats.OpenFindings = stats.FindingStatus[ResultStatusOpen]
+
+	var severity []NamedCount
+	if err := openResults(f).Select("LOWER(severity) AS name, COUNT(*) AS count").Group("LOWER(severity)").Scan(&severity).Error; err != nil {
+		return nil, err
+	}
+	for _, c := range severity {
+		stats.Severity[c.Name] = c.Count
+	}
+
+	if err := openResults(f).
+		Select("COALESCE(NULLIF(template_id, ''), vulnerability) AS name, COUNT(*) AS count").
+		Group("name").Order("count DESC, name").Limit(f.Limit).
+		Scan(&stats.TopTemplates).Error; err != nil {
+		return nil, err
+	}
+	if err := openResults(f).
+		Select("COALESCE(NULLIF(host, ''), target) AS name, COUNT(*) AS count").
+		Group("name").Order("count DESC, name").Limit(f.Limit).
+		Scan(&stats.TopHosts).Error; err != nil {
+		return nil, err
+	}
+
+	daily, err := dailyCounts(f)
+	if err != nil {
+		return nil, err
+	}
+	stats.Daily = daily
+
+	var mttr *float64
+	if err := visibleResults(f).
+		Where("status = ? AND closed_at IS NOT NULL", ResultStatusFixed).
+		Select("AVG((julianday(closed_at) - julianday(timestamp)) * 24)").
+		Scan(&mttr).Error; err != nil {
+		return nil, err
+	}
+	if mttr != nil {
+		stats.MTTRHours = *mttr
+	}
+	return stats, nil
+}
+
+// dailyCounts 生成自 Since 起每天的新增与关闭数（按服务器本地日期），无数据的日期补 0
+func dailyCounts(f StatsFilter) ([]DailyCount, error) {
+	if f.Since.IsZero() {
+		return []DailyCount{}, nil
+	}
+	start := time.Date(f.Since.Year(), f.Since.Month(), f.Since.Day(), 0, 0, 0, 0, time.Local)
+
+	var opened, closed []NamedCount
+	if err := visibleResults(f).
+		Where("timestamp >= ?", start).
+		Select("date(timestamp, 'localtime') AS name, COUNT(*) AS count").
+		Group("name").Scan(&opened).Error; err != nil {
+		return nil, err
+	}
+	if err := visibleResults(f).
+		Where("closed_at IS NOT NULL AND closed_at >= ? AND status <> ?", start, ResultStatusOpen).
+		Select("date(closed_at, 'localtime') AS name, COUNT(*) AS count").
+		Group("name").Scan(&closed).Error; err != nil {
+		return nil, err
+	}
+
+	byDay := map[string]*DailyCount{}
+	var days []DailyCount
+	for d := start; !d.After(time.Now()); d = d.AddDate(0, 0, 1) {
+		days = append(days, DailyCount{Date: d.Format("2006-01-02")})
+	}
+	for i := range days {
+		byDay[days[i].Date] = &days[i]
+	}
+	for _, c := range opened {
+		if d, ok := byDay[c.Name]; ok {
+			d.Opened = c.Count
+		}
+	}
+	for _, c := range closed {
+		if d, ok := byDay[c.Name]; ok {
+			d.Closed = c.Count
+		}
+	}
+	return days, nil
+}
+
+// visibleTasks 返回统计范围内的任务查询
+func visibleTasks(f StatsFilter) *gorm.DB {
+	query := db.GetDB().Model(&Task{})
+	if !f.All {
+		memberProjects := db.GetDB().Model(&ProjectMember{}).Select("project_id").Where("user_id = ?", f.UserID)
+		query = query.Where("(project_id = 0 AND user_id = ?) OR project_id IN (?)", f.UserID, memberProjects)
+	}
+	if f.ProjectID != 0 {
+		query = query.Where("project_id = ?", f.ProjectID)
+	}
+	return query
+}
+
+// visibleResults 返回统计范围内的扫描结果查询
+func visibleResults(f StatsFilter) *gorm.DB {
+	query := db.GetDB().Model(&Result{})
+	if !f.All || f.ProjectID != 0 {
+		query = query.Where("task_id IN (?)", visibleTasks(f).Select("id"))
+	}
+	return query
+}
+
+func openResults(f StatsFilter) *gorm.DB {
+	return visibleResults(f).Where("status = ?", ResultStatusOpen)
+}
@@ -3,6 +3,8 @@ package models
 import (
 	"VulnFusion/internal/db"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // 任务状态常量定义
@@ -13,6 +15,12 @@ const (
 	StatusFailed  = "failed"
 )
 
+// 任务扫描模式常量定义
+const (
+	ModeStandard = "standard" // 按指定模板扫描
+	ModeAuto     = "auto"     // 先识别技术栈，再按标签挑选模板
+)
+
 type Task struct {
 	ID        uint      `gorm:"primaryKey"`
 	UserID    uint      `gorm:"not null"`        // 所属用户
@@ -20,6 +28,17 @@ type Task struct {
 	Template  string    `gorm:"not null"`        // nuclei 模板名称
 	CreatedAt time.Time `gorm:"autoCreateTime"`  // 创建时间
 	Status    string    `gorm:"default:pending"` // 状态：pending、running、done、failed
+
+	Mode         string `gorm:"default:standard"` // 扫描模式：standard、auto
+	DetectedTech string `gorm:"type:text"`        // 自动模式下识别出的技术栈（逗号分隔）
+	SelectedTags string `gorm:"type:text"`        // 自动模式下选中的模板标签（逗号分隔）
+
+	AgentLabel string // 指定由带有该标签的远程节点执行，为空则由服务端执行
+	AgentID    uint   // 实际领取任务的远程节点 ID
+
+	ProjectID uint `gorm:"index"` // 所属项目 ID，为 0 表示个人任务
+
+	CIPolicy string `gorm:"type:text"` // CI 门禁策略（JSON），非 CI 扫描任务为空
 }
 
 // CreateTask 创建新任务记录
@@ -43,6 +62,26 @@ func ListTasksByUserID(userID uint) ([]Task, error) {
 	return tasks, err
 }
 
+// ListVisibleTasks 列出用户可见的任务：本人创建的个人任务与其参与项目下的任务
+// projectID 不为 0 时仅返回该项目下的任务
+func ListVisibleTasks(userID uint, projectID uint) ([]Task, error) {
+	var tasks []Task
+	memberProjects := db.GetDB().Model(&ProjectMember{}).Select("project_id").Where("user_id = ?", userID)
+	query := db.GetDB().Where("(project_id = 0 AND user_id = ?) OR project_id IN (?)", userID, memberProjects)
+	if projectID != 0 {
+		query = query.Where("project_id = ?", projectID)
+	}
+	err := query.Order("created_at desc").Find(&tasks).Error
+	return tasks, err
+}
+
+// ListTasksByIDs 根据任务 ID 列表批量查询任务
+func ListTasksByIDs(ids []uint) ([]Task, error) {
+	var tasks []Task
+	err := db.GetDB().Where("id IN ?", ids).Find(&tasks).Error
+	return tasks, err
+}
+
 // ListAllTasks 列出系统中全部任务记录
 func ListAllTasks() ([]Task, error) {
 	var tasks []Task
@@ -68,3 +107,85 @@ func BatchDeleteTasks(ids []uint, userID *uint) error {
 func UpdateTaskStatus(taskID uint, status string) error {
 	return db.GetDB().Model(&Task{}).Where("id = ?", taskID).Update("status", status).Error
 }
+
+// UpdateTaskByID 根据任务 ID 更新任务的部分字段
+func UpdateTaskByID(taskID uint, updates map[string]interface{}) error {
+	return db.GetDB().Model(&Task{}).Where("id = ?", taskID).Updates(updates).Error
+}
+
+// ClaimPendingTask 为远程节点领取一个匹配其标签的待执行任务；无任务或节点运行中的任务已达最大并发数时返回 nil
+func ClaimPendingTask(agentID uint, labels []string) (*Task, error) {
+	if len(labels) == 0 {
+		return nil, nil
+	}
+
+	var claimed *Task
+	err := db.GetDB().Transaction(func(tx *gorm.DB) error {
+		// 在同一事务内统计运行中的任务，避免并发领取超出节点的最大并发数
+		var agent Agent
+		if err := tx.Select("capacity").First(&agent, agentID).Error; err != nil {
+			return err
+		}
+		capacity := agent.Capacity
+		if capacity <= 0 {
+			capacity = 1
+		}
+		var running int64
+		if err := tx.Model(&Task{}).Where("agent_id = ? AND status = ?", agentID, StatusRunning).Count(&running).Error; err != nil {
+			return err
+		}
+		if running >= int64(capacity) {
+			return nil
+		}
+
+		var candidates []Task
+		err := tx.Where("status = ? AND agent_label IN ?", StatusPending, labels).
+			Order("created_at asc").
+			Limit(10).
+			Find(&candidates).Error
+		if err != nil {
+			return err
+		}
+
+		for i := range candidates {
+			// 通过带状态条件的更新保证同一任务只会被一个节点领取
+			res := tx.Model(&Task{}).
+				Where("id = ? AND status = ?", candidates[i].ID, StatusPending).
+				Updates(map[string]interface{}{"status": StatusRunning, "agent_id": agentID})
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 1 {
+				candidates[i].Status = StatusRunning
+				candidates[i].AgentID = agentID
+				claimed = &candidates[i]
+				return nil
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return claimed, nil
+}
+
+// UpdateUnfinishedTask 仅在任务尚未结束（done / failed）时更新字段，返回是否更新成功
+func UpdateUnfinishedTask(taskID uint, updates map[string]interface{}) (bool, error) {
+	res := db.GetDB().Model(&Task{}).
+		Where("id = ? AND status NOT IN ?", taskID, []string{StatusDone, StatusFailed}).
+		Updates(updates)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+// RequeueStaleAgentTasks 将心跳早于 cutoff 或已被删除的节点所领取的运行中任务放回队列，返回放回的任务数
+func RequeueStaleAgentTasks(cutoff time.Time) (int64, error) {
+	alive := db.GetDB().Model(&Agent{}).Select("id").Where("last_heartbeat >= ?", cutoff)
+	res := db.GetDB().Model(&Task{}).
+		Where("status = ? AND agent_id <> 0 AND agent_id NOT IN (?)", StatusRunning, alive).
+		Updates(map[string]interface{}{"status": StatusPending, "agent_id": 0})
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,153 @@
+package models
+
+import (
+	"VulnFusion/internal/db"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 缺陷跟踪系统类型
+const (
+	TrackerJira   = "jira"
+	TrackerGitLab = "gitlab"
+	TrackerGitHub = "github"
+)
+
+type TrackerConnector struct {
+	ID           uint      `gorm:"primaryKey"`
+	UserID       uint      `gorm:"index"` // 创建人
+	ProjectID    uint      `gorm:"index"` // 所属项目，0 表示个人连接器
+	Name         string    `gorm:"not null"`
+	Kind         string    `gorm:"not null"` // jira / gitlab / github
+	BaseURL      string    // 服务地址，如 https://jira.example.com、https://gitlab.example.com、https://api.github.com
+	Project      string    // Jira 项目 Key / GitLab 项目 ID 或路径 / GitHub owner/repo
+	Username     string    // Jira 账号（与 Token 组成 Basic 认证），为空时使用 Bearer
+	Token        string    `json:"-"` // 访问令牌
+	IssueType    string    // Jira 问题类型，默认 Bug
+	FieldMapping string    `gorm:"type:text"` // 字段映射（JSON），见 TrackerFieldMapping
+	Enabled      bool      `gorm:"default:true"`
+	CreatedAt    time.Time `gorm:"autoCreateTime"`
+}
+
+// TrackerFieldMapping 连接器字段映射
+type TrackerFieldMapping struct {
+	Priority map[string]string      `json:"priority,omitempty"` // 严重等级 → 优先级（Jira priority.name；GitLab / GitHub 作为标签）
+	Labels   []string               `json:"labels,omitempty"`   // 附加标签
+	Extra    map[string]interface{} `json:"extra,omitempty"`    // 原样合并到创建请求中的字段（Jira 为 fields 下的字段）
+	Status   map[string]string      `json:"status,omitempty"`   // 工单状态 → 处置状态，覆盖默认映射
+}
+
+// Mapping 解析字段映射，格式错误时返回空映射
+func (c *TrackerConnector) Mapping() TrackerFieldMapping {
+	var m TrackerFieldMapping
+	if c.FieldMapping != "" {
+		_ = json.Unmarshal([]byte(c.FieldMapping), &m)
+	}
+	return m
+}
+
+// IsValidTrackerKind 判断缺陷跟踪系统类型是否支持
+func IsValidTrackerKind(kind string) bool {
+	return kind == TrackerJira || kind == TrackerGitLab || kind == TrackerGitHub
+}
+
+// CreateTrackerConnector 创建连接器
+func CreateTrackerConnector(conn *TrackerConnector) error {
+	return db.GetDB().Create(conn).Error
+}
+
+// GetTrackerConnectorByID 根据 ID 查询连接器
+func GetTrackerConnectorByID(id uint) (*TrackerConnector, error) {
+	var conn TrackerConnector
+	if err := db.GetDB().First(&conn, id).Error; err != nil {
+		return nil, err
+	}
+	return &conn, nil
+}
+
+// ListVisibleTrackerConnectors 列出用户的个人连接器与所在项目的连接器
+func ListVisibleTrackerConnectors(userID uint) ([]TrackerConnector, error) {
+	var conns []TrackerConnector
+	memberProjects := db.GetDB().Model(&ProjectMember{}).Select("project_id").Where("user_id = ?", userID)
+	err := db.GetDB().
+		Where("(project_id = 0 AND user_id = ?) OR project_id IN (?)", userID, memberProjects).
+		Order("id asc").Find(&conns).Error
+	return conns, err
+}
+
+// ListAllTrackerConnectors 列出全部连接器（管理员）
+func ListAllTrackerConnectors() ([]TrackerConnector, error) {
+	var conns []TrackerConnector
+	err := db.GetDB().Order("id asc").Find(&conns).Error
+	return conns, err
+}
+
+// UpdateTrackerConnectorByID 更新连接器
+func UpdateTrackerConnectorByID(id uint, updates map[string]interface{}) error {
+	return db.GetDB().Model(&TrackerConnector{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// DeleteTrackerConnectorByID 删除连接器，已关联的工单信息保留在结果中
+func DeleteTrackerConnectorByID(id uint) error {
+	return db.GetDB().Delete(&TrackerConnector{}, id).Error
+}
+
+// SetResultTicket 记录结果关联的工单；仅当结果尚未关联工单时写入，返回是否写入成功
+func SetResultTicket(resultID, connectorID uint, key, url, status string) (bool, error) {
+	tx := db.GetDB().Model(&Result{}).
+		Where("id = ? AND (ticket_key IS NULL OR ticket_key = '')", resultID).
+		Updates(map[string]interface{}{
+			"ticket_connector_id": connectorID,
+			"ticket_key":          key,
+			"ticket_url":          url,
+			"ticket_status":       status,
+		})
+	return tx.RowsAffected > 0, tx.Error
+}
+
+// FindingKey 返回结果对应漏洞的唯一标识（资产 + 模板 + 命中位置），无法识别资产或模板时按结果 ID 区分
+func (r *Result) FindingKey() string {
+	if r.AssetID == 0 || r.TemplateID == "" {
+		return fmt.Sprintf("result:%d", r.ID)
+	}
+	return fmt.Sprintf("asset:%d|%s|%s", r.AssetID, r.TemplateID, r.Target)
+}
+
+// FindFindingTicket 查找同一漏洞的其他结果在指定连接器中已创建的工单，不存在时返回 nil
+func FindFindingTicket(r *Result, connectorID uint) (*Result, error) {
+	if r.AssetID == 0 || r.TemplateID == "" {
+		return nil, nil
+	}
+	var other Result
+	err := db.GetDB().
+		Where("asset_id = ? AND template_id = ? AND target = ? AND id <> ?", r.AssetID, r.TemplateID, r.Target, r.ID).
+		Where("ticket_connector_id = ? AND ticket_key <> ''", connectorID).
+		Order("id asc").First(&other).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &other, nil
+}
+
+// UpdateResultTicketStatus 更新结果关联工单的最新状态
+func UpdateResultTicketStatus(resultID uint, status string) error {
+	return db.GetDB().Model(&Result{}).Where("id = ?", resultID).Update("ticket_status", status).Error
+}
+
+// ListTicketedResults 列出关联了指定连接器工单的结果，connectorID 为 0 时列出全部
+func ListTicketedResults(connectorID uint) ([]Result, error) {
+	var results []Result
+	query := db.GetDB().Where("ticket_key <> ''")
+	if connectorID != 0 {
+		query = query.Where("ticket_connector_id = ?", connectorID)
+	}
+	err := query.Find(&results).Error
+	return results, err
+}
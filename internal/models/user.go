@@ -3,14 +3,37 @@ package models
 
 import (
 	"VulnFusion/internal/db"
+	"time"
+
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
+// UserStatusPending 自助注册后等待管理员审批的账号状态
+const UserStatusPending = "pending"
+
 type User struct {
 	ID       uint   `gorm:"primaryKey"`
 	Username string `gorm:"unique;not null"`
-	Password string `gorm:"not null"`
-	Role     string `gorm:"default:user"` // user / admin
+	Password string `gorm:"not null" json:"-"` // 密码哈希，不随接口返回
+	Role     string `gorm:"default:user"`      // 角色：admin / user / viewer / auditor / scanner-operator 或 rbac.roles 中的自定义角色
+
+	Email           string     // 通知邮箱
+	NotifyTaskEmail bool       // 任务结束时发送邮件
+	Digest          string     // 高危漏洞摘要频率：空（关闭）/ daily / weekly
+	DigestSentAt    *time.Time // 最近一次发送摘要的时间
+
+	TOTPSecret      string `json:"-"` // TOTP 密钥，未启用时为待确认的密钥
+	TOTPEnabled     bool   // 是否已启用二次验证
+	TOTPLastCounter int64  `json:"-"` // 最近一次使用的验证码时间步，防止验证码重放
+
+	AuthProvider string `gorm:"index"` // 认证来源：空为本地账号，否则为 OIDC 身份提供方名称
+	ExternalID   string `gorm:"index"` // 身份提供方中的用户标识（sub）
+
+	Status string `gorm:"index"` // 账号状态：空为正常，pending 为自助注册后等待管理员审批
+
+	FailedLogins int        // 连续登录失败次数，登录成功或管理员解锁后清零
+	LockedUntil  *time.Time // 连续失败过多后的锁定截止时间
 }
 
 // CreateUser 创建新用户记录，写入用户名、密码哈希、角色等字段
@@ -59,3 +82,58 @@ func UpdateUserByID(id uint, updates map[string]interface{}) error {
 func DeleteUserByID(id uint) error {
 	return db.GetDB().Delete(&User{}, id).Error
 }
+
+// GetUserByExternalID 根据身份提供方与其用户标识查询单点登录用户
+func GetUserByExternalID(provider, externalID string) (*User, error) {
+	var user User
+	if err := db.GetDB().Where("auth_provider = ? AND external_id = ?", provider, externalID).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetLocalUserByEmail 根据邮箱查询尚未关联身份提供方的本地用户
+func GetLocalUserByEmail(email string) (*User, error) {
+	var user User
+	if err := db.GetDB().Where("email = ? AND auth_provider = ''", email).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// LinkExternalIdentity 将本地用户关联到身份提供方
+func LinkExternalIdentity(userID uint, provider, externalID string) error {
+	return db.GetDB().Model(&User{}).Where("id = ?", userID).
+		Updates(map[string]interface{}{"auth_provider": provider, "external_id": externalID}).Error
+}
+
+// ListUsersByStatus 按账号状态查询用户，如待审批用户
+func ListUsersByStatus(status string) ([]User, error) {
+	var users []User
+	if err := db.GetDB().Where("status = ?", status).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// ApproveUser 审批通过待审批用户并设置角色；用户不存在或不处于待审批状态时返回 false
+func ApproveUser(id uint, role string) (bool, error) {
+	result := db.GetDB().Model(&User{}).Where("id = ? AND status = ?", id, UserStatusPending).
+		Updates(map[string]interface{}{"status": "", "role": role})
+	return result.RowsAffected == 1, result.Error
+}
+
+// RecordLoginFailure 累计一次登录失败；lockedUntil 非空时同时锁定账号
+func RecordLoginFailure(id uint, lockedUntil *time.Time) error {
+	updates := map[string]interface{}{"failed_logins": gorm.Expr("failed_logins + 1")}
+	if lockedUntil != nil {
+		updates["locked_until"] = *lockedUntil
+	}
+	return db.GetDB().Model(&User{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// ResetLoginFailures 清除登录失败次数并解除锁定
+func ResetLoginFailures(id uint) error {
+	return db.GetDB().Model(&User{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"failed_logins": 0, "locked_until": nil}).Error
+}
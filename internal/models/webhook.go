@@ -0,0 +1,170 @@
+package models
+
+import (
+	"VulnFusion/internal/db"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Webhook struct {
+	ID          uint      `gorm:"primaryKey"`
+	UserID      uint      `gorm:"index"`    // 创建人
+	Name        string    `gorm:"not null"` // 名称
+	URL         string    `gorm:"not null"` // 投递地址
+	Secret      string    `json:"-"`        // 签名密钥：generic 用于请求体 HMAC，dingtalk / feishu 为机器人加签密钥
+	Events      string    // 订阅的事件（逗号分隔），为空表示全部事件
+	MinSeverity string    // finding.created 事件的最低严重等级，为空表示不过滤
+	Global      bool      // 接收全部任务的事件（仅管理员可设置），否则仅接收创建人可见任务的事件
+	Format      string    // 消息格式：generic / slack / teams / dingtalk / feishu
+	QuietStart  string    // 免打扰开始时间（HH:MM，本地时间）
+	QuietEnd    string    // 免打扰结束时间（HH:MM），跨零点时表示次日
+	Enabled     bool      `gorm:"default:true"`
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+}
+
+type WebhookDelivery struct {
+	ID            uint       `gorm:"primaryKey"`
+	WebhookID     uint       `gorm:"index"` // 所属 Webhook
+	EventID       string     // 事件 ID
+	Event         string     // 事件类型
+	Payload       string     `gorm:"type:text"` // 投递内容
+	StatusCode    int        // 最近一次响应状态码
+	Attempts      int        // 已尝试次数
+	Success       bool       // 是否投递成功
+	Error         string     `gorm:"type:text"` // 最近一次失败原因
+	CreatedAt     time.Time  `gorm:"autoCreateTime"`
+	DeliveredAt   *time.Time // 投递成功时间
+	MaxAttempts   int        // 最多尝试次数
+	NextAttemptAt *time.Time `gorm:"index"` // 下次重试时间，为空表示已成功或不再重试
+}
+
+// EventList 返回订阅的事件列表
+func (w *Webhook) EventList() []string {
+	var events []string
+	for _, e := range strings.Split(w.Events, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// Subscribes 判断 Webhook 是否订阅了指定事件
+func (w *Webhook) Subscribes(event string) bool {
+	events := w.EventList()
+	if len(events) == 0 {
+		return true
+	}
+	for _, e := range events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateWebhook 创建 Webhook
+func CreateWebhook(webhook *Webhook) error {
+	return db.GetDB().Create(webhook).Error
+}
+
+// GetWebhookByID 根据 ID 查询 Webhook
+func GetWebhookByID(id uint) (*Webhook, error) {
+	var webhook Webhook
+	if err := db.GetDB().First(&webhook, id).Error; err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// ListWebhooksByUserID 列出用户创建的 Webhook
+func ListWebhooksByUserID(userID uint) ([]Webhook, error) {
+	var webhooks []Webhook
+	err := db.GetDB().Where("user_id = ?", userID).Order("id asc").Find(&webhooks).Error
+	return webhooks, err
+}
+
+// ListAllWebhooks 列出全部 Webhook（管理员）
+func ListAllWebhooks() ([]Webhook, error) {
+	var webhooks []Webhook
+	err := db.GetDB().Order("id asc").Find(&webhooks).Error
+	return webhooks, err
+}
+
+// ListEnabledWebhooks 列出所有启用的 Webhook
+func ListEnabledWebhooks() ([]Webhook, error) {
+	var webhooks []Webhook
+	err := db.GetDB().Where("enabled = ?", true).Find(&webhooks).Error
+	return webhooks, err
+}
+
+// UpdateWebhookByID 更新 Webhook
+func UpdateWebhookByID(id uint, updates map[string]interface{}) error {
+	return db.GetDB().Model(&Webhook{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// DeleteWebhookByID 删除 Webhook 及其投递记录
+func DeleteWebhookByID(id uint) error {
+	return db.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("webhook_id = ?", id).Delete(&WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&Webhook{}, id).Error
+	})
+}
+
+// CreateWebhookDelivery 创建投递记录
+func CreateWebhookDelivery(delivery *WebhookDelivery) error {
+	return db.GetDB().Create(delivery).Error
+}
+
+// SaveWebhookDelivery 保存投递记录的最新状态
+func SaveWebhookDelivery(delivery *WebhookDelivery) error {
+	return db.GetDB().Save(delivery).Error
+}
+
+// ListDueWebhookDeliveries 列出重试时间已到的投递记录
+func ListDueWebhookDeliveries(now time.Time, limit int) ([]WebhookDelivery, error) {
+	var deliveries []WebhookDelivery
+	err := db.GetDB().Where("next_attempt_at IS NOT NULL AND next_attempt_at <= ?", now).
+		Order("next_attempt_at asc").Limit(limit).Find(&deliveries).Error
+	return deliveries, err
+}
+
+// ClaimWebhookDelivery 领取到期的投递记录，将下次重试时间推迟到 until；已被其他调度领取时返回 false
+func ClaimWebhookDelivery(id uint, now, until time.Time) (bool, error) {
+	result := db.GetDB().Model(&WebhookDelivery{}).
+		Where("id = ? AND next_attempt_at IS NOT NULL AND next_attempt_at <= ?", id, now).
+		Update("next_attempt_at", until)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// RescheduleWebhookDelivery 设置投递记录的下次重试时间，零值表示不再重试
+func RescheduleWebhookDelivery(id uint, next time.Time) error {
+	var value interface{}
+	if !next.IsZero() {
+		value = next
+	}
+	return db.GetDB().Model(&WebhookDelivery{}).Where("id = ?", id).Update("next_attempt_at", value).Error
+}
+
+// ListWebhookDeliveries 按时间倒序列出 Webhook 的投递记录
+func ListWebhookDeliveries(webhookID uint, limit int) ([]WebhookDelivery, error) {
+	var deliveries []WebhookDelivery
+	err := db.GetDB().Where("webhook_id = ?", webhookID).Order("id desc").Limit(limit).Find(&deliveries).Error
+	return deliveries, err
+}
+
+// IsTaskVisibleTo 判断任务对用户是否可见：本人的个人任务或所在项目的任务
+func IsTaskVisibleTo(userID uint, task *Task) bool {
+	if task.ProjectID == 0 {
+		return task.UserID == userID
+	}
+	_, err := GetProjectMember(task.ProjectID, userID)
+	return err == nil
+}
@@ -0,0 +1,137 @@
+package netguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+
+	"VulnFusion/internal/config"
+	"VulnFusion/internal/log"
+)
+
+// ErrBlockedAddress 目标解析到回环、内网、链路本地等保留地址
+var ErrBlockedAddress = errors.New("目标地址为内网或保留地址，禁止访问")
+
+// maxRedirects 出站请求最多跟随的重定向次数
+const maxRedirects = 5
+
+// reservedNets net.IP 方法未覆盖的保留网段
+var reservedNets = mustParseCIDRs("0.0.0.0/8", "100.64.0.0/10", "192.0.0.0/24", "198.18.0.0/15", "240.0.0.0/4")
+
+// IsBlockedIP 判断 IP 是否禁止出站访问：回环、私有、链路本地、组播、未指定及其他保留地址，
+// 命中 outbound.allowed_cidrs 的地址除外
+func IsBlockedIP(ip net.IP) bool {
+	for _, n := range allowedNets() {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsMulticast() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return true
+	}
+	for _, n := range reservedNets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewClient 创建用于访问用户配置地址的 HTTP 客户端：连接时校验实际解析出的 IP（防止 DNS 重绑定），
+// 每次重定向都经过同样的校验，且不使用环境变量中的代理
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: controlDial}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy:                 nil,
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   timeout,
+			ResponseHeaderTimeout: timeout,
+			MaxIdleConns:          20,
+			IdleConnTimeout:       90 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return errors.New("重定向次数过多")
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("不支持重定向到 %s 协议", req.URL.Scheme)
+			}
+			return nil
+		},
+	}
+}
+
+// ValidateURL 保存地址前的预检：须为 http / https，且主机不能是（或解析到）禁止访问的地址。
+// 域名暂时无法解析时放行，实际请求时仍会在连接阶段校验
+func ValidateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return errors.New("地址必须为 http 或 https URL")
+	}
+	if ip := net.ParseIP(u.Hostname()); ip != nil {
+		if IsBlockedIP(ip) {
+			return ErrBlockedAddress
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil {
+		return nil
+	}
+	for _, a := range addrs {
+		if IsBlockedIP(a.IP) {
+			return ErrBlockedAddress
+		}
+	}
+	return nil
+}
+
+// controlDial 在建立连接前检查实际要连接的 IP
+func controlDial(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || IsBlockedIP(ip) {
+		return ErrBlockedAddress
+	}
+	return nil
+}
+
+// allowedNets 解析 outbound.allowed_cidrs，忽略格式错误的条目
+func allowedNets() []*net.IPNet {
+	var nets []*net.IPNet
+	for _, c := range config.OutboundAllowedCIDRs() {
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			log.Warn("忽略无效的出站白名单网段 %s: %v", c, err)
+			continue
+		}
+		nets = append(nets, n)
+	}
+	return nets
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, c := range cidrs {
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			panic(err)
+		}
+		nets = append(nets, n)
+	}
+	return nets
+}
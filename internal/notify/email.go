@@ -0,0 +1,208 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"VulnFusion/internal/config"
+	"VulnFusion/internal/log"
+	"VulnFusion/internal/mail"
+	"VulnFusion/internal/models"
+	"VulnFusion/internal/rbac"
+	"VulnFusion/internal/report"
+)
+
+// mailTaskEvent 任务结束时向开启了邮件通知的任务创建人发送邮件
+func mailTaskEvent(e Event) {
+	if e.Task == nil || !mail.Enabled() {
+		return
+	}
+	user, err := models.GetUserByID(e.Task.UserID)
+	if err != nil || !user.NotifyTaskEmail || user.Email == "" {
+		return
+	}
+	if err := SendTaskEmail(user.Email, e); err != nil {
+		log.Warn("发送任务 %d 通知邮件失败: %v", e.Task.ID, err)
+	}
+}
+
+// SendTaskEmail 发送任务完成/失败邮件，正文与附件为任务的 HTML 报告
+func SendTaskEmail(to string, e Event) error {
+	results, err := models.ListResultsByTaskID(e.Task.ID)
+	if err != nil {
+		return err
+	}
+	m := buildMessage(e)
+	r := &report.Report{
+		Title:       m.Title,
+		Summary:     fmt.Sprintf("目标：%s", e.Task.Target),
+		GeneratedAt: e.Time,
+		Sections:    []report.Section{{Name: fmt.Sprintf("任务 #%d 扫描结果", e.Task.ID), Findings: results}},
+	}
+	html, err := report.RenderHTML(r)
+	if err != nil {
+		return err
+	}
+	return mail.Send(&mail.Message{
+		To:          []string{to},
+		Subject:     m.Title,
+		HTML:        string(html),
+		Attachments: []mail.Attachment{{Name: fmt.Sprintf("task-%d-report.html", e.Task.ID), ContentType: "text/html; charset=UTF-8", Data: html}},
+	})
+}
+
+// StartDigestScheduler 启动摘要邮件调度，每小时检查一次到期的用户与项目摘要
+func StartDigestScheduler() {
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			RunDigests(now)
+		}
+	}()
+}
+
+// RunDigests 发送所有到期的用户与项目摘要
+func RunDigests(now time.Time) {
+	if !mail.Enabled() {
+		return
+	}
+
+	users, err := models.ListDigestUsers()
+	if err != nil {
+		log.Error("查询摘要用户失败: %v", err)
+	}
+	for _, u := range users {
+		if !DigestDue(u.Digest, u.DigestSentAt, now) {
+			continue
+		}
+		filter := models.StatsFilter{UserID: u.ID, All: rbac.Can(u.Role, rbac.StatsReadAny)}
+		if err := sendDigest(u.Digest, filter, []string{u.Email}, u.DigestSentAt, now); err != nil {
+			log.Warn("发送用户 %d 摘要邮件失败: %v", u.ID, err)
+			continue
+		}
+		_ = models.MarkUserDigestSent(u.ID, now)
+	}
+
+	projects, err := models.ListDigestProjects()
+	if err != nil {
+		log.Error("查询摘要项目失败: %v", err)
+	}
+	for _, p := range projects {
+		if !DigestDue(p.Digest, p.DigestSentAt, now) {
+			continue
+		}
+		filter := models.StatsFilter{ProjectID: p.ID, All: true}
+		if err := sendDigest(p.Digest, filter, splitAddresses(p.DigestEmails), p.DigestSentAt, now); err != nil {
+			log.Warn("发送项目 %d 摘要邮件失败: %v", p.ID, err)
+			continue
+		}
+		_ = models.MarkProjectDigestSent(p.ID, now)
+	}
+}
+
+// DigestDue 判断摘要是否到期：最近一个计划发送时刻（每日配置整点，周报还需为配置的星期）之后尚未发送
+func DigestDue(freq string, last *time.Time, now time.Time) bool {
+	if freq != models.DigestDaily && freq != models.DigestWeekly {
+		return false
+	}
+	slot := time.Date(now.Year(), now.Month(), now.Day(), config.DigestHour(), 0, 0, 0, now.Location())
+	if slot.After(now) {
+		slot = slot.AddDate(0, 0, -1)
+	}
+	if freq == models.DigestWeekly {
+		for slot.Weekday() != config.DigestWeekday() {
+			slot = slot.AddDate(0, 0, -1)
+		}
+	}
+	return last == nil || last.Before(slot)
+}
+
+// sendDigest 汇总 last 之后新增的高危漏洞并按项目分组发送，没有新增时不发送
+func sendDigest(freq string, filter models.StatsFilter, to []string, last *time.Time, now time.Time) error {
+	period, label := 24*time.Hour, "每日"
+	if freq == models.DigestWeekly {
+		period, label = 7*24*time.Hour, "每周"
+	}
+	since := now.Add(-period)
+	if last != nil && last.After(since) {
+		since = *last
+	}
+
+	findings, err := models.ListNewFindings(filter, since, models.DigestSeverities)
+	if err != nil {
+		return err
+	}
+	if len(findings) == 0 {
+		return nil
+	}
+
+	sections, err := groupByProject(findings)
+	if err != nil {
+		return err
+	}
+	title := fmt.Sprintf("[VulnFusion] %s高危漏洞摘要：新增 %d 个", label, len(findings))
+	html, err := report.RenderHTML(&report.Report{
+		Title:       title,
+		Summary:     fmt.Sprintf("统计区间：%s 至 %s，仅包含 high / critical 且未处置的漏洞。", since.Format("2006-01-02 15:04"), now.Format("2006-01-02 15:04")),
+		GeneratedAt: now,
+		Sections:    sections,
+	})
+	if err != nil {
+		return err
+	}
+	return mail.Send(&mail.Message{
+		To:          to,
+		Subject:     title,
+		HTML:        string(html),
+		Attachments: []mail.Attachment{{Name: "digest-" + now.Format("20060102") + ".html", ContentType: "text/html; charset=UTF-8", Data: html}},
+	})
+}
+
+// groupByProject 按任务所属项目分组，个人任务归入“个人任务”
+func groupByProject(findings []models.Result) ([]report.Section, error) {
+	taskIDs := make([]uint, 0, len(findings))
+	for _, f := range findings {
+		taskIDs = append(taskIDs, f.TaskID)
+	}
+	tasks, err := models.ListTasksByIDs(taskIDs)
+	if err != nil {
+		return nil, err
+	}
+	projectOf := map[uint]uint{}
+	for _, t := range tasks {
+		projectOf[t.ID] = t.ProjectID
+	}
+
+	var sections []report.Section
+	index := map[uint]int{}
+	for _, f := range findings {
+		pid := projectOf[f.TaskID]
+		i, ok := index[pid]
+		if !ok {
+			name := "个人任务"
+			if pid != 0 {
+				name = fmt.Sprintf("项目 #%d", pid)
+				if p, err := models.GetProjectByID(pid); err == nil {
+					name = "项目：" + p.Name
+				}
+			}
+			i = len(sections)
+			index[pid] = i
+			sections = append(sections, report.Section{Name: name})
+		}
+		sections[i].Findings = append(sections[i].Findings, f)
+	}
+	return sections, nil
+}
+
+func splitAddresses(s string) []string {
+	var addrs []string
+	for _, a := range strings.Split(s, ",") {
+		if a = strings.TrimSpace(a); a != "" {
+			addrs = append(addrs, a)
+		}
+	}
+	return addrs
+}
@@ -0,0 +1,149 @@
+package notify
+
+import (
+	"strings"
+	"time"
+
+	"VulnFusion/internal/models"
+	"VulnFusion/internal/utils"
+)
+
+// 事件类型
+const (
+	EventTaskDone       = "task.done"
+	EventTaskFailed     = "task.failed"
+	EventFindingCreated = "finding.created"
+	EventTest           = "test"
+)
+
+// IsValidEvent 判断是否为可订阅的事件类型
+func IsValidEvent(eventType string) bool {
+	switch eventType {
+	case EventTaskDone, EventTaskFailed, EventFindingCreated:
+		return true
+	}
+	return false
+}
+
+// TaskInfo 事件中的任务信息
+type TaskInfo struct {
+	ID        uint   `json:"id"`
+	UserID    uint   `json:"user_id"`
+	ProjectID uint   `json:"project_id"`
+	Target    string `json:"target"`
+	Template  string `json:"template"`
+	Mode      string `json:"mode"`
+	Status    string `json:"status"`
+	Findings  int    `json:"findings"` // 本次保存的漏洞条数
+}
+
+// FindingInfo 事件中的漏洞信息
+type FindingInfo struct {
+	ID            uint    `json:"id"`
+	TaskID        uint    `json:"task_id"`
+	Vulnerability string  `json:"vulnerability"`
+	Severity      string  `json:"severity"`
+	Target        string  `json:"target"`
+	Host          string  `json:"host"`
+	TemplateID    string  `json:"template_id"`
+	CVEID         string  `json:"cve_id"`
+	RiskScore     float64 `json:"risk_score"`
+}
+
+// Event 通知事件，序列化后作为 Webhook 请求体
+type Event struct {
+	ID      string       `json:"id"`
+	Type    string       `json:"type"`
+	Time    time.Time    `json:"time"`
+	Task    *TaskInfo    `json:"task,omitempty"`
+	Finding *FindingInfo `json:"finding,omitempty"`
+
+	task *models.Task // 用于判断接收方是否有权查看
+}
+
+// NewTaskEvent 构造任务事件
+func NewTaskEvent(eventType string, task *models.Task, findings int) Event {
+	return Event{
+		ID:   newEventID(),
+		Type: eventType,
+		Time: time.Now(),
+		Task: &TaskInfo{
+			ID:        task.ID,
+			UserID:    task.UserID,
+			ProjectID: task.ProjectID,
+			Target:    task.Target,
+			Template:  task.Template,
+			Mode:      task.Mode,
+			Status:    task.Status,
+			Findings:  findings,
+		},
+		task: task,
+	}
+}
+
+// NewFindingEvent 构造漏洞事件
+func NewFindingEvent(task *models.Task, result *models.Result) Event {
+	return Event{
+		ID:   newEventID(),
+		Type: EventFindingCreated,
+		Time: time.Now(),
+		Task: &TaskInfo{ID: task.ID, UserID: task.UserID, ProjectID: task.ProjectID, Target: task.Target, Status: task.Status},
+		Finding: &FindingInfo{
+			ID:            result.ID,
+			TaskID:        result.TaskID,
+			Vulnerability: result.Vulnerability,
+			Severity:      result.Severity,
+			Target:        result.Target,
+			Host:          result.Host,
+			TemplateID:    result.TemplateID,
+			CVEID:         result.CVEID,
+			RiskScore:     result.RiskScore,
+		},
+		task: task,
+	}
+}
+
+// NewTestEvent 构造测试事件
+func NewTestEvent() Event {
+	return Event{ID: newEventID(), Type: EventTest, Time: time.Now()}
+}
+
+// SeverityRank 返回严重等级的排序值，未知等级为 -1
+func SeverityRank(severity string) int {
+	switch strings.ToLower(strings.TrimSpace(severity)) {
+	case "info":
+		return 0
+	case "low":
+		return 1
+	case "medium":
+		return 2
+	case "high":
+		return 3
+	case "critical":
+		return 4
+	}
+	return -1
+}
+
+// Matches 判断 Webhook 是否应接收该事件：订阅了事件类型、有权查看对应任务、且漏洞达到最低严重等级。
+// 免打扰时段不影响是否接收，只推迟投递时间
+func Matches(w *models.Webhook, e Event) bool {
+	if !w.Enabled || !w.Subscribes(e.Type) {
+		return false
+	}
+	if e.task != nil && !w.Global && !models.IsTaskVisibleTo(w.UserID, e.task) {
+		return false
+	}
+	if e.Finding != nil && w.MinSeverity != "" && SeverityRank(e.Finding.Severity) < SeverityRank(w.MinSeverity) {
+		return false
+	}
+	return true
+}
+
+func newEventID() string {
+	token, err := utils.GenerateSecureToken(12)
+	if err != nil {
+		return time.Now().Format("20060102150405.000000000")
+	}
+	return token
+}
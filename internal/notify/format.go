@@ -0,0 +1,257 @@
+package notify
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"VulnFusion/internal/models"
+)
+
+// 消息格式
+const (
+	FormatGeneric  = "generic"  // 原始事件 JSON，附 HMAC 签名头
+	FormatSlack    = "slack"    // Slack incoming webhook
+	FormatTeams    = "teams"    // Microsoft Teams connector（MessageCard）
+	FormatDingTalk = "dingtalk" // 钉钉自定义机器人（markdown）
+	FormatFeishu   = "feishu"   // 飞书 / Lark 自定义机器人（消息卡片）
+)
+
+// IsValidFormat 判断是否为支持的消息格式，空值等同 generic
+func IsValidFormat(format string) bool {
+	switch format {
+	case "", FormatGeneric, FormatSlack, FormatTeams, FormatDingTalk, FormatFeishu:
+		return true
+	}
+	return false
+}
+
+// message 与平台无关的消息内容
+type message struct {
+	Title string
+	Lines []string // markdown 行
+	Level string   // 用于配色：critical / high / medium / low / info / ok / failed
+}
+
+// Render 按 Webhook 的消息格式生成请求体
+func Render(w *models.Webhook, e Event, now time.Time) ([]byte, error) {
+	if w.Format == "" || w.Format == FormatGeneric {
+		return json.Marshal(e)
+	}
+
+	m := buildMessage(e)
+	text := strings.Join(m.Lines, "\n\n")
+	switch w.Format {
+	case FormatSlack:
+		return json.Marshal(map[string]interface{}{
+			"text": m.Title,
+			"blocks": []interface{}{
+				map[string]interface{}{"type": "header", "text": map[string]string{"type": "plain_text", "text": m.Title}},
+				map[string]interface{}{"type": "section", "text": map[string]string{"type": "mrkdwn", "text": slackMarkdown(strings.Join(m.Lines, "\n"))}},
+			},
+		})
+	case FormatTeams:
+		return json.Marshal(map[string]interface{}{
+			"@type":      "MessageCard",
+			"@context":   "https://schema.org/extensions",
+			"summary":    m.Title,
+			"themeColor": levelColor(m.Level),
+			"title":      m.Title,
+			"text":       text,
+		})
+	case FormatDingTalk:
+		return json.Marshal(map[string]interface{}{
+			"msgtype":  "markdown",
+			"markdown": map[string]string{"title": m.Title, "text": "### " + m.Title + "\n\n" + text},
+		})
+	case FormatFeishu:
+		body := map[string]interface{}{
+			"msg_type": "interactive",
+			"card": map[string]interface{}{
+				"header": map[string]interface{}{
+					"title":    map[string]string{"tag": "plain_text", "content": m.Title},
+					"template": levelTemplate(m.Level),
+				},
+				"elements": []interface{}{
+					map[string]string{"tag": "markdown", "content": strings.Join(m.Lines, "\n")},
+				},
+			},
+		}
+		if w.Secret != "" {
+			ts := strconv.FormatInt(now.Unix(), 10)
+			body["timestamp"] = ts
+			body["sign"] = FeishuSign(w.Secret, ts)
+		}
+		return json.Marshal(body)
+	}
+	return nil, fmt.Errorf("不支持的消息格式: %s", w.Format)
+}
+
+// SignedURL 返回实际投递地址；钉钉加签需将 timestamp 与 sign 附加到查询参数
+func SignedURL(w *models.Webhook, now time.Time) string {
+	if w.Format != FormatDingTalk || w.Secret == "" {
+		return w.URL
+	}
+	u, err := url.Parse(w.URL)
+	if err != nil {
+		return w.URL
+	}
+	ts := strconv.FormatInt(now.UnixMilli(), 10)
+	q := u.Query()
+	q.Set("timestamp", ts)
+	q.Set("sign", DingTalkSign(w.Secret, ts))
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// DingTalkSign 钉钉加签：HMAC-SHA256(key=secret, "timestamp\nsecret") 的 base64
+func DingTalkSign(secret, timestamp string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "\n" + secret))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// FeishuSign 飞书加签：以 "timestamp\nsecret" 为密钥对空串做 HMAC-SHA256 后 base64
+func FeishuSign(secret, timestamp string) string {
+	mac := hmac.New(sha256.New, []byte(timestamp+"\n"+secret))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// checkChatResponse 钉钉与飞书在 HTTP 200 中以 errcode / code 表示业务错误
+func checkChatResponse(format string, body []byte) error {
+	if format != FormatDingTalk && format != FormatFeishu {
+		return nil
+	}
+	var resp struct {
+		ErrCode *int   `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+		Code    *int   `json:"code"`
+		Msg     string `json:"msg"`
+	}
+	if json.Unmarshal(body, &resp) != nil {
+		return nil
+	}
+	if resp.ErrCode != nil && *resp.ErrCode != 0 {
+		return fmt.Errorf("接收方返回错误 %d: %s", *resp.ErrCode, resp.ErrMsg)
+	}
+	if resp.Code != nil && *resp.Code != 0 {
+		return fmt.Errorf("接收方返回错误 %d: %s", *resp.Code, resp.Msg)
+	}
+	return nil
+}
+
+// buildMessage 将事件渲染为标题与 markdown 行
+func buildMessage(e Event) message {
+	switch {
+	case e.Finding != nil:
+		f := e.Finding
+		severity := strings.ToUpper(f.Severity)
+		lines := []string{
+			fmt.Sprintf("**漏洞**：%s", f.Vulnerability),
+			fmt.Sprintf("**等级**：%s", severity),
+			fmt.Sprintf("**目标**：%s", f.Target),
+		}
+		if f.TemplateID != "" {
+			lines = append(lines, fmt.Sprintf("**模板**：%s", f.TemplateID))
+		}
+		if f.CVEID != "" {
+			lines = append(lines, fmt.Sprintf("**CVE**：%s", f.CVEID))
+		}
+		lines = append(lines,
+			fmt.Sprintf("**风险分**：%.1f", f.RiskScore),
+			fmt.Sprintf("**任务**：#%d", f.TaskID),
+		)
+		return message{Title: fmt.Sprintf("[VulnFusion] 发现 %s 漏洞：%s", severity, f.Vulnerability), Lines: lines, Level: strings.ToLower(f.Severity)}
+
+	case e.Task != nil:
+		t := e.Task
+		title, level := fmt.Sprintf("[VulnFusion] 任务 #%d 扫描完成", t.ID), "ok"
+		if e.Type == EventTaskFailed {
+			title, level = fmt.Sprintf("[VulnFusion] 任务 #%d 扫描失败", t.ID), "failed"
+		}
+		lines := []string{fmt.Sprintf("**目标**：%s", t.Target)}
+		if t.Template != "" {
+			lines = append(lines, fmt.Sprintf("**模板**：%s", t.Template))
+		}
+		lines = append(lines, fmt.Sprintf("**新增漏洞**：%d", t.Findings))
+		return message{Title: title, Lines: lines, Level: level}
+	}
+	return message{Title: "[VulnFusion] 测试消息", Lines: []string{"Webhook 配置成功，可以正常接收通知。"}, Level: "info"}
+}
+
+// slackMarkdown Slack mrkdwn 的加粗为单星号
+func slackMarkdown(text string) string {
+	return strings.ReplaceAll(text, "**", "*")
+}
+
+// levelColor Teams 卡片主题色
+func levelColor(level string) string {
+	switch level {
+	case "critical", "failed":
+		return "D32F2F"
+	case "high":
+		return "F57C00"
+	case "medium":
+		return "FBC02D"
+	case "ok":
+		return "388E3C"
+	}
+	return "1976D2"
+}
+
+// levelTemplate 飞书卡片标题颜色
+func levelTemplate(level string) string {
+	switch level {
+	case "critical", "failed":
+		return "red"
+	case "high":
+		return "orange"
+	case "medium":
+		return "yellow"
+	case "ok":
+		return "green"
+	}
+	return "blue"
+}
+
+// InQuietHours 判断时间是否落在 Webhook 的免打扰时段内，支持跨零点（如 22:00-08:00）
+func InQuietHours(w *models.Webhook, t time.Time) bool {
+	start, ok1 := ParseClock(w.QuietStart)
+	end, ok2 := ParseClock(w.QuietEnd)
+	if !ok1 || !ok2 || start == end {
+		return false
+	}
+	now := t.Hour()*60 + t.Minute()
+	if start < end {
+		return now >= start && now < end
+	}
+	return now >= start || now < end
+}
+
+// QuietHoursEnd 返回 t 所在免打扰时段的结束时间，t 不在免打扰时段内时返回 t
+func QuietHoursEnd(w *models.Webhook, t time.Time) time.Time {
+	if !InQuietHours(w, t) {
+		return t
+	}
+	end, _ := ParseClock(w.QuietEnd)
+	next := time.Date(t.Year(), t.Month(), t.Day(), end/60, end%60, 0, 0, t.Location())
+	if !next.After(t) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// ParseClock 解析 HH:MM，返回当天的分钟数
+func ParseClock(s string) (int, bool) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, false
+	}
+	return t.Hour()*60 + t.Minute(), true
+}
@@ -0,0 +1,281 @@
+package notify
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"VulnFusion/internal/config"
+	"VulnFusion/internal/log"
+	"VulnFusion/internal/models"
+	"VulnFusion/internal/netguard"
+	"VulnFusion/internal/utils"
+)
+
+// 投递请求头
+const (
+	HeaderEvent     = "X-VulnFusion-Event"
+	HeaderDelivery  = "X-VulnFusion-Delivery"
+	HeaderSignature = "X-VulnFusion-Signature" // sha256=<HMAC-SHA256(secret, body) 十六进制>
+)
+
+// maxBackoff 重试间隔上限
+const maxBackoff = 5 * time.Minute
+
+// deliveryLease 投递交给工作协程后，记录在该时间内不会被重试调度再次领取；进程中途退出时到期后自动重试
+const deliveryLease = 5 * time.Minute
+
+// retryBatchSize 每轮最多领取的待重试投递数
+const retryBatchSize = 100
+
+// Dispatcher 负责将事件投递到 Webhook。投递记录持久化到数据库，失败后按指数退避写入下次重试时间，
+// 由重试调度统一领取，进程重启后未完成的投递会继续重试
+type Dispatcher struct {
+	Client      *http.Client
+	MaxAttempts int
+	Backoff     func(attempt int) time.Duration // 第 attempt 次失败后的等待时间
+
+	jobs chan func() // 工作队列，为空时在调用方协程中直接发送
+}
+
+var (
+	defaultDispatcher *Dispatcher
+	dispatcherOnce    sync.Once
+)
+
+// NewDispatcher 按配置创建投递器，禁止投递到内网与保留地址
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{
+		Client:      netguard.NewClient(config.WebhookTimeout()),
+		MaxAttempts: config.WebhookMaxAttempts(),
+		Backoff:     ExponentialBackoff,
+	}
+}
+
+// dispatcher 返回全局投递器，首次调用时启动固定数量的工作协程
+func dispatcher() *Dispatcher {
+	dispatcherOnce.Do(func() {
+		defaultDispatcher = NewDispatcher()
+		workers := config.WebhookWorkers()
+		defaultDispatcher.jobs = make(chan func(), workers*100)
+		for i := 0; i < workers; i++ {
+			go func() {
+				for job := range defaultDispatcher.jobs {
+					job()
+				}
+			}()
+		}
+	})
+	return defaultDispatcher
+}
+
+// ExponentialBackoff 2s、4s、8s……，最长 5 分钟
+func ExponentialBackoff(attempt int) time.Duration {
+	d := 2 * time.Second << uint(attempt-1)
+	if d <= 0 || d > maxBackoff {
+		return maxBackoff
+	}
+	return d
+}
+
+// Publish 为所有匹配的 Webhook 生成投递记录并交给工作协程发送，任务结束事件同时发送邮件通知
+func Publish(e Event) {
+	dispatcher().Publish(e)
+	if e.Type == EventTaskDone || e.Type == EventTaskFailed {
+		go mailTaskEvent(e)
+	}
+}
+
+// StartRetryScheduler 定期领取到期的投递记录重新发送
+func StartRetryScheduler(interval time.Duration) {
+	d := dispatcher()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			d.RetryDue(time.Now())
+		}
+	}()
+}
+
+// Publish 为所有匹配的 Webhook 生成投递记录并排队发送；队列已满时留给重试调度处理
+func (d *Dispatcher) Publish(e Event) {
+	webhooks, err := models.ListEnabledWebhooks()
+	if err != nil {
+		log.Error("查询 Webhook 失败: %v", err)
+		return
+	}
+	for i := range webhooks {
+		w := &webhooks[i]
+		if !Matches(w, e) {
+			continue
+		}
+		// 免打扰时段内的事件推迟到时段结束后由重试调度投递
+		now := time.Now()
+		if InQuietHours(w, e.Time) {
+			end := QuietHoursEnd(w, e.Time)
+			d.newDelivery(w, e, d.MaxAttempts, &end)
+			continue
+		}
+		lease := now.Add(deliveryLease)
+		delivery := d.newDelivery(w, e, d.MaxAttempts, &lease)
+		if delivery == nil {
+			continue
+		}
+		if !d.submit(func() { d.attempt(w, delivery) }) {
+			log.Warn("Webhook 投递队列已满，事件 %s 将由重试调度发送", e.ID)
+			if err := models.RescheduleWebhookDelivery(delivery.ID, now); err != nil {
+				log.Error("保存 Webhook 投递记录失败: %v", err)
+			}
+		}
+	}
+}
+
+// Deliver 生成投递记录并立即发送一次，失败且未达到 attempts 次时写入下次重试时间，返回投递记录
+func (d *Dispatcher) Deliver(w *models.Webhook, e Event, attempts int) *models.WebhookDelivery {
+	delivery := d.newDelivery(w, e, attempts, nil)
+	if delivery == nil {
+		return nil
+	}
+	d.attempt(w, delivery)
+	return delivery
+}
+
+// RetryDue 领取到期的投递记录重新发送；Webhook 已删除或停用时不再重试
+func (d *Dispatcher) RetryDue(now time.Time) {
+	due, err := models.ListDueWebhookDeliveries(now, retryBatchSize)
+	if err != nil {
+		log.Error("查询待重试的 Webhook 投递失败: %v", err)
+		return
+	}
+	for i := range due {
+		delivery := &due[i]
+		// 领取成功后其他调度轮次不会重复发送
+		claimed, err := models.ClaimWebhookDelivery(delivery.ID, now, now.Add(deliveryLease))
+		if err != nil || !claimed {
+			continue
+		}
+		w, err := models.GetWebhookByID(delivery.WebhookID)
+		if err != nil || !w.Enabled {
+			_ = models.RescheduleWebhookDelivery(delivery.ID, time.Time{})
+			continue
+		}
+		if !d.submit(func() { d.attempt(w, delivery) }) {
+			_ = models.RescheduleWebhookDelivery(delivery.ID, now)
+			return
+		}
+	}
+}
+
+// submit 将任务放入工作队列，未配置队列时直接执行；队列已满时返回 false
+func (d *Dispatcher) submit(job func()) bool {
+	if d.jobs == nil {
+		job()
+		return true
+	}
+	select {
+	case d.jobs <- job:
+		return true
+	default:
+		return false
+	}
+}
+
+// newDelivery 渲染消息并创建投递记录
+func (d *Dispatcher) newDelivery(w *models.Webhook, e Event, attempts int, next *time.Time) *models.WebhookDelivery {
+	body, err := Render(w, e, time.Now())
+	if err != nil {
+		log.Error("生成 Webhook %d 的事件 %s 消息失败: %v", w.ID, e.ID, err)
+		return nil
+	}
+
+	delivery := &models.WebhookDelivery{
+		WebhookID:     w.ID,
+		EventID:       e.ID,
+		Event:         e.Type,
+		Payload:       string(body),
+		MaxAttempts:   attempts,
+		NextAttemptAt: next,
+	}
+	if err := models.CreateWebhookDelivery(delivery); err != nil {
+		log.Error("创建 Webhook 投递记录失败: %v", err)
+		return nil
+	}
+	return delivery
+}
+
+// attempt 发送一次投递记录中的消息并保存结果，失败且仍有剩余次数时按退避时间安排下次重试
+func (d *Dispatcher) attempt(w *models.Webhook, delivery *models.WebhookDelivery) {
+	delivery.Attempts++
+	delivery.NextAttemptAt = nil
+
+	var err error
+	delivery.StatusCode, err = d.send(w, delivery.Event, delivery.ID, []byte(delivery.Payload))
+	if err == nil {
+		now := time.Now()
+		delivery.Success = true
+		delivery.Error = ""
+		delivery.DeliveredAt = &now
+	} else {
+		log.Warn("Webhook %d 投递事件 %s 第 %d 次失败: %v", w.ID, delivery.Event, delivery.Attempts, err)
+		delivery.Error = deliveryError(err)
+		if delivery.Attempts < delivery.MaxAttempts {
+			next := time.Now()
+			if d.Backoff != nil {
+				next = next.Add(d.Backoff(delivery.Attempts))
+			}
+			delivery.NextAttemptAt = &next
+		}
+	}
+	if err := models.SaveWebhookDelivery(delivery); err != nil {
+		log.Error("保存 Webhook 投递记录失败: %v", err)
+	}
+}
+
+// send 发送一次请求，2xx 视为成功
+func (d *Dispatcher) send(w *models.Webhook, event string, deliveryID uint, body []byte) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, SignedURL(w, time.Now()), bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "VulnFusion-Webhook")
+	req.Header.Set(HeaderEvent, event)
+	req.Header.Set(HeaderDelivery, strconv.FormatUint(uint64(deliveryID), 10))
+	if w.Secret != "" && (w.Format == "" || w.Format == FormatGeneric) {
+		req.Header.Set(HeaderSignature, "sha256="+utils.HMACSHA256Hex(w.Secret, body))
+	}
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("接收方返回 %d", resp.StatusCode)
+	}
+	return resp.StatusCode, checkChatResponse(w.Format, respBody)
+}
+
+// deliveryError 返回写入投递记录的错误信息；连接类错误只记录类别，避免暴露网络探测细节
+func deliveryError(err error) string {
+	if errors.Is(err, netguard.ErrBlockedAddress) {
+		return netguard.ErrBlockedAddress.Error()
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return "连接接收方超时"
+		}
+		return "连接接收方失败"
+	}
+	return err.Error()
+}
@@ -0,0 +1,348 @@
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"VulnFusion/internal/config"
+	"VulnFusion/internal/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// discoveryTTL 发现文档与公钥集合的缓存时间
+const discoveryTTL = time.Hour
+
+// jwksRefreshInterval 遇到未知 kid 时刷新公钥集合的最小间隔
+const jwksRefreshInterval = time.Minute
+
+// ErrUnknownProvider 未配置的身份提供方
+var ErrUnknownProvider = errors.New("未配置的身份提供方")
+
+// Discovery OpenID Provider 元数据中用到的字段
+type Discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Identity 已验证的 id_token 中的用户信息
+type Identity struct {
+	Subject       string   // sub，在同一发行方内唯一且不变
+	Username      string   // 按 username_claim 取得的用户名
+	Email         string   // 邮箱
+	EmailVerified bool     // 邮箱是否已由身份提供方验证
+	Groups        []string // role_claim 声明的值
+}
+
+// Client 单个身份提供方的客户端，缓存发现文档与公钥
+type Client struct {
+	cfg  config.OIDCProvider
+	http *http.Client
+
+	mu           sync.Mutex
+	discovery    *Discovery
+	discoveredAt time.Time
+	keys         map[string]interface{}
+	keysAt       time.Time
+}
+
+var (
+	clientsMu sync.Mutex
+	clients   = map[string]*Client{}
+)
+
+// ClientFor 返回已配置身份提供方的客户端；配置变化时重新创建
+func ClientFor(name string) (*Client, error) {
+	cfg, ok := config.FindOIDCProvider(name)
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	clientsMu.Lock()
+	defer clientsMu.Unlock()
+	if c, ok := clients[name]; ok && c.cfg.Issuer == cfg.Issuer && c.cfg.ClientID == cfg.ClientID {
+		c.cfg = cfg
+		return c, nil
+	}
+	c := New(cfg, nil)
+	clients[name] = c
+	return c, nil
+}
+
+// New 创建身份提供方客户端，client 为空时使用默认超时的 HTTP 客户端
+func New(cfg config.OIDCProvider, client *http.Client) *Client {
+	if client == nil {
+		client = &http.Client{Timeout: 15 * time.Second}
+	}
+	return &Client{cfg: cfg, http: client}
+}
+
+// Config 返回身份提供方配置
+func (c *Client) Config() config.OIDCProvider {
+	return c.cfg
+}
+
+// NewPKCE 生成 PKCE 的 code_verifier 与 S256 code_challenge
+func NewPKCE() (verifier, challenge string, err error) {
+	verifier, err = utils.GenerateSecureToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	return verifier, base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// AuthCodeURL 生成授权地址（授权码模式 + PKCE）
+func (c *Client) AuthCodeURL(ctx context.Context, redirectURI, state, nonce, challenge string) (string, error) {
+	d, err := c.getDiscovery(ctx)
+	if err != nil {
+		return "", err
+	}
+	scopes := c.cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "profile", "email"}
+	}
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", c.cfg.ClientID)
+	q.Set("redirect_uri", redirectURI)
+	q.Set("scope", strings.Join(scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", challenge)
+	q.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(d.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return d.AuthorizationEndpoint + sep + q.Encode(), nil
+}
+
+// Exchange 用授权码换取 id_token 并验证签名、发行方、受众、有效期与 nonce
+func (c *Client) Exchange(ctx context.Context, code, redirectURI, verifier, nonce string) (*Identity, error) {
+	d, err := c.getDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", redirectURI)
+	form.Set("code_verifier", verifier)
+	if c.cfg.ClientSecret == "" {
+		// 公开客户端：不使用客户端密钥，仅依赖 PKCE
+		form.Set("client_id", c.cfg.ClientID)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if c.cfg.ClientSecret != "" {
+		// client_secret_basic：凭据需先做表单编码（RFC 6749 2.3.1）
+		req.SetBasicAuth(url.QueryEscape(c.cfg.ClientID), url.QueryEscape(c.cfg.ClientSecret))
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("令牌端点返回 %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	var tokens struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.Unmarshal(body, &tokens); err != nil {
+		return nil, err
+	}
+	if tokens.IDToken == "" {
+		return nil, errors.New("令牌端点未返回 id_token")
+	}
+	return c.VerifyIDToken(ctx, tokens.IDToken, nonce)
+}
+
+// VerifyIDToken 验证 id_token 并提取用户信息
+func (c *Client) VerifyIDToken(ctx context.Context, raw, nonce string) (*Identity, error) {
+	d, err := c.getDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(raw, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return c.publicKey(ctx, d, kid, t.Method.Alg())
+	},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "EdDSA"}),
+		jwt.WithIssuer(d.Issuer),
+		jwt.WithAudience(c.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("id_token 验证失败: %w", err)
+	}
+	if got, _ := claims["nonce"].(string); nonce == "" || got != nonce {
+		return nil, errors.New("id_token 的 nonce 不匹配")
+	}
+
+	id := &Identity{}
+	id.Subject, _ = claims["sub"].(string)
+	if id.Subject == "" {
+		return nil, errors.New("id_token 缺少 sub")
+	}
+	id.Email, _ = claims["email"].(string)
+	id.EmailVerified = claimBool(claims["email_verified"])
+
+	usernameClaim := c.cfg.UsernameClaim
+	if usernameClaim == "" {
+		usernameClaim = "preferred_username"
+	}
+	for _, name := range []string{usernameClaim, "email", "sub"} {
+		if v, _ := claims[name].(string); v != "" {
+			id.Username = v
+			break
+		}
+	}
+	if c.cfg.RoleClaim != "" {
+		id.Groups = claimStrings(claims[c.cfg.RoleClaim])
+	}
+	return id, nil
+}
+
+// ResolveRole 按角色映射得出本系统角色：命中 admin 优先，其次取第一个命中的映射，均未命中时使用默认角色
+func ResolveRole(cfg config.OIDCProvider, groups []string) string {
+	role := ""
+	for _, g := range groups {
+		mapped, ok := cfg.RoleMapping[g]
+		if !ok {
+			continue
+		}
+		if mapped == "admin" {
+			return mapped
+		}
+		if role == "" {
+			role = mapped
+		}
+	}
+	if role != "" {
+		return role
+	}
+	if cfg.DefaultRole != "" {
+		return cfg.DefaultRole
+	}
+	return "user"
+}
+
+// getDiscovery 读取并缓存发现文档
+func (c *Client) getDiscovery(ctx context.Context) (*Discovery, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.discovery != nil && time.Since(c.discoveredAt) < discoveryTTL {
+		return c.discovery, nil
+	}
+
+	var d Discovery
+	wellKnown := strings.TrimRight(c.cfg.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := c.getJSON(ctx, wellKnown, &d); err != nil {
+		return nil, fmt.Errorf("读取发现文档失败: %w", err)
+	}
+	// 发现文档中的 issuer 必须与配置一致（OpenID Connect Discovery 4.3）
+	if strings.TrimRight(d.Issuer, "/") != strings.TrimRight(c.cfg.Issuer, "/") {
+		return nil, fmt.Errorf("发现文档的 issuer %q 与配置不一致", d.Issuer)
+	}
+	if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JWKSURI == "" {
+		return nil, errors.New("发现文档缺少必要端点")
+	}
+	c.discovery, c.discoveredAt = &d, time.Now()
+	return c.discovery, nil
+}
+
+// publicKey 按 kid 查找公钥，未找到时刷新公钥集合（身份提供方可能已轮换密钥）
+func (c *Client) publicKey(ctx context.Context, d *Discovery, kid, alg string) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stale := time.Since(c.keysAt) > discoveryTTL
+	if key, ok := c.keys[kid]; ok && !stale {
+		return key, nil
+	}
+	if !stale && time.Since(c.keysAt) < jwksRefreshInterval {
+		return nil, fmt.Errorf("未知的签名密钥 %q", kid)
+	}
+
+	var set jwkSet
+	if err := c.getJSON(ctx, d.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("读取 JWKS 失败: %w", err)
+	}
+	c.keys, c.keysAt = set.publicKeys(), time.Now()
+	if key, ok := c.keys[kid]; ok {
+		return key, nil
+	}
+	// 身份提供方只有一把密钥时可能省略 kid
+	if kid == "" && len(c.keys) == 1 {
+		for _, key := range c.keys {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("未知的签名密钥 %q（%s）", kid, alg)
+}
+
+func (c *Client) getJSON(ctx context.Context, u string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s 返回 %d", u, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(out)
+}
+
+// claimBool 兼容布尔值与字符串形式的 email_verified
+func claimBool(v interface{}) bool {
+	switch b := v.(type) {
+	case bool:
+		return b
+	case string:
+		return b == "true"
+	}
+	return false
+}
+
+// claimStrings 将字符串或字符串数组声明统一为切片
+func claimStrings(v interface{}) []string {
+	switch val := v.(type) {
+	case string:
+		return []string{val}
+	case []interface{}:
+		out := make([]string, 0, len(val))
+		for _, item := range val {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package provenance
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Batch 一批待回传的扫描结果及其来源信息，签名覆盖其完整 JSON 编码
+type Batch struct {
+	AgentID       uint     `json:"agent_id"`
+	TaskID        uint     `json:"task_id"`
+	Sequence      int      `json:"sequence"`       // 同一任务内递增的批次序号，用于防重放
+	NucleiVersion string   `json:"nuclei_version"` // 执行扫描的 nuclei 版本
+	TemplateHash  string   `json:"template_hash"`  // 模板库摘要
+	CreatedAt     int64    `json:"created_at"`     // 批次生成时间（Unix 秒）
+	Lines         []string `json:"lines"`          // nuclei JSONL 原始输出行
+}
+
+// SignedBatch 签名后的批次：payload 为 Batch 的 JSON 编码（base64），signature 为 ed25519 签名（base64）
+type SignedBatch struct {
+	Payload   string `json:"payload"`
+	Signature string `json:"signature"`
+}
+
+var (
+	ErrInvalidEncoding  = errors.New("批次编码无效")
+	ErrInvalidSignature = errors.New("批次签名校验失败")
+	ErrInvalidPublicKey = errors.New("节点公钥无效")
+)
+
+// GenerateKey 生成节点签名密钥对，返回 base64 编码的公钥与私钥
+func GenerateKey() (publicKey string, privateKey string, err error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	return base64.StdEncoding.EncodeToString(pub), base64.StdEncoding.EncodeToString(priv), nil
+}
+
+// Sign 使用 base64 编码的私钥对批次签名
+func Sign(batch Batch, privateKey string) (*SignedBatch, error) {
+	key, err := base64.StdEncoding.DecodeString(privateKey)
+	if err != nil || len(key) != ed25519.PrivateKeySize {
+		return nil, errors.New("节点私钥无效")
+	}
+	if batch.CreatedAt == 0 {
+		batch.CreatedAt = time.Now().Unix()
+	}
+
+	payload, err := json.Marshal(batch)
+	if err != nil {
+		return nil, err
+	}
+	sig := ed25519.Sign(ed25519.PrivateKey(key), payload)
+	return &SignedBatch{
+		Payload:   base64.StdEncoding.EncodeToString(payload),
+		Signature: base64.StdEncoding.EncodeToString(sig),
+	}, nil
+}
+
+// Verify 使用 base64 编码的公钥校验签名，通过后返回解码的批次与原始 payload
+func Verify(signed SignedBatch, publicKey string) (*Batch, []byte, error) {
+	pub, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return nil, nil, ErrInvalidPublicKey
+	}
+	payload, err := base64.StdEncoding.DecodeString(signed.Payload)
+	if err != nil {
+		return nil, nil, ErrInvalidEncoding
+	}
+	sig, err := base64.StdEncoding.DecodeString(signed.Signature)
+	if err != nil {
+		return nil, nil, ErrInvalidEncoding
+	}
+	if !ed25519.Verify(ed25519.PublicKey(pub), payload, sig) {
+		return nil, nil, ErrInvalidSignature
+	}
+
+	var batch Batch
+	if err := json.Unmarshal(payload, &batch); err != nil {
+		return nil, nil, ErrInvalidEncoding
+	}
+	return &batch, payload, nil
+}
+
+// ValidatePublicKey 校验 base64 编码的 ed25519 公钥
+func ValidatePublicKey(publicKey string) error {
+	pub, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return ErrInvalidPublicKey
+	}
+	return nil
+}
+
+// PayloadHash 返回 payload 的 SHA-256 十六进制摘要
+func PayloadHash(payload []byte) string {
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// Decode 解码批次 payload 但不校验签名，仅用于在校验前确定签名节点
+func Decode(signed SignedBatch) (*Batch, error) {
+	payload, err := base64.StdEncoding.DecodeString(signed.Payload)
+	if err != nil {
+		return nil, ErrInvalidEncoding
+	}
+	var batch Batch
+	if err := json.Unmarshal(payload, &batch); err != nil {
+		return nil, ErrInvalidEncoding
+	}
+	return &batch, nil
+}
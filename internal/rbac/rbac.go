@@ -0,0 +1,342 @@
+package rbac
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"VulnFusion/internal/config"
+)
+
+// 权限点。不带 .any 的权限作用于本人或所在项目的资源（项目内还需满足项目成员角色），
+// 带 .any 的权限可访问全系统资源
+const (
+	TaskCreate    = "task.create"     // 创建扫描任务（含资产扫描与 CI 门禁）
+	TaskRead      = "task.read"       // 查看任务
+	TaskWrite     = "task.write"      // 修改或删除任务
+	TaskReadAny   = "task.read.any"   // 查看全部任务
+	TaskManageAny = "task.manage.any" // 修改或删除任意任务及其结果
+
+	ResultRead    = "result.read"     // 查看与导出扫描结果
+	ResultTriage  = "result.triage"   // 修改处置状态、创建与同步工单、导入离线结果
+	ResultReadAny = "result.read.any" // 查看全部扫描结果
+
+	ProjectCreate    = "project.create"     // 创建项目
+	ProjectManage    = "project.manage"     // 修改、删除所在项目及管理成员（还需为项目 owner）
+	ProjectReadAny   = "project.read.any"   // 查看全部项目
+	ProjectManageAny = "project.manage.any" // 管理任意项目
+
+	AssetRead      = "asset.read"       // 查看资产
+	AssetWrite     = "asset.write"      // 修改资产
+	AssetReadAny   = "asset.read.any"   // 查看全部资产
+	AssetManageAny = "asset.manage.any" // 修改任意资产
+
+	ScopeRead   = "scope.read"   // 查看扫描范围与检查目标
+	ScopeManage = "scope.manage" // 设置个人、其他用户与项目的扫描范围
+
+	StatsRead    = "stats.read"     // 查看统计
+	StatsReadAny = "stats.read.any" // 查看全系统统计
+
+	WebhookManage    = "webhook.manage"     // 管理个人 Webhook
+	WebhookManageAny = "webhook.manage.any" // 管理任意 Webhook，并可接收全部任务的事件
+
+	TrackerManage    = "tracker.manage"     // 管理个人或项目的缺陷跟踪连接器
+	TrackerManageAny = "tracker.manage.any" // 管理任意连接器
+
+	TokenManage    = "token.manage"     // 管理个人 API 令牌
+	TokenReadAny   = "token.read.any"   // 查看全部 API 令牌
+	TokenManageAny = "token.manage.any" // 吊销任意 API 令牌
+
+	SessionManageAny = "session.manage.any" // 吊销任意用户的会话
+
+	UserRead   = "user.read"   // 查看用户列表、会话与角色定义
+	UserManage = "user.manage" // 创建、审批、修改、删除、解锁用户，管理注册邀请码，重置密码与二次验证，吊销令牌
+
+	AuditRead   = "audit.read"   // 查看审计日志
+	AgentRead   = "agent.read"   // 查看远程扫描节点
+	AgentManage = "agent.manage" // 管理远程扫描节点
+
+	KeyManage    = "system.keys"   // 查看与轮换 JWT 签名密钥
+	RiskManage   = "risk.manage"   // 导入可能性数据、重算风险
+	EnrichRead   = "enrich.read"   // 查看漏洞库导入状态
+	EnrichManage = "enrich.manage" // 导入 NVD / CWE 数据
+)
+
+// 内置角色
+const (
+	RoleAdmin           = "admin"
+	RoleUser            = "user"
+	RoleViewer          = "viewer"
+	RoleAuditor         = "auditor"
+	RoleScannerOperator = "scanner-operator"
+)
+
+// Permissions 全部权限点及说明，用于校验配置与前端展示
+var Permissions = map[string]string{
+	TaskCreate:       "创建扫描任务",
+	TaskRead:         "查看任务",
+	TaskWrite:        "修改或删除任务",
+	TaskReadAny:      "查看全部任务",
+	TaskManageAny:    "管理任意任务",
+	ResultRead:       "查看扫描结果",
+	ResultTriage:     "处置扫描结果",
+	ResultReadAny:    "查看全部扫描结果",
+	ProjectCreate:    "创建项目",
+	ProjectManage:    "管理项目",
+	ProjectReadAny:   "查看全部项目",
+	ProjectManageAny: "管理任意项目",
+	AssetRead:        "查看资产",
+	AssetWrite:       "修改资产",
+	AssetReadAny:     "查看全部资产",
+	AssetManageAny:   "管理任意资产",
+	ScopeRead:        "查看扫描范围",
+	ScopeManage:      "管理扫描范围",
+	StatsRead:        "查看统计",
+	StatsReadAny:     "查看全系统统计",
+	WebhookManage:    "管理 Webhook",
+	WebhookManageAny: "管理任意 Webhook",
+	TrackerManage:    "管理缺陷跟踪连接器",
+	TrackerManageAny: "管理任意连接器",
+	TokenManage:      "管理 API 令牌",
+	TokenReadAny:     "查看全部 API 令牌",
+	TokenManageAny:   "吊销任意 API 令牌",
+	SessionManageAny: "吊销任意会话",
+	UserRead:         "查看用户",
+	UserManage:       "管理用户",
+	AuditRead:        "查看审计日志",
+	AgentRead:        "查看扫描节点",
+	AgentManage:      "管理扫描节点",
+	KeyManage:        "管理签名密钥",
+	RiskManage:       "管理风险评分",
+	EnrichRead:       "查看漏洞库状态",
+	EnrichManage:     "导入漏洞库",
+}
+
+// AdminPermissions 管理接口（/api/v1/admin）使用的权限，具备其中任一权限即可授予 API 令牌 admin 权限范围
+var AdminPermissions = []string{
+	TaskReadAny, ResultReadAny, UserRead, UserManage, TokenReadAny, AuditRead,
+	AgentRead, AgentManage, KeyManage, RiskManage, EnrichRead, EnrichManage, ScopeManage,
+}
+
+// 只读角色共用的权限
+var readOwn = []string{TaskRead, ResultRead, AssetRead, ScopeRead, StatsRead}
+
+// builtinRoles 内置角色的权限，可通过 rbac.roles 配置覆盖（admin 除外）或新增角色
+var builtinRoles = map[string][]string{
+	RoleAdmin: {"*"},
+	RoleUser: append([]string{
+		TaskCreate, TaskWrite, ResultTriage, ProjectCreate, ProjectManage, AssetWrite,
+		WebhookManage, TrackerManage, TokenManage,
+	}, readOwn...),
+	RoleViewer: readOwn,
+	RoleAuditor: append([]string{
+		TaskReadAny, ResultReadAny, ProjectReadAny, AssetReadAny, StatsReadAny,
+		TokenReadAny, UserRead, AuditRead, AgentRead, EnrichRead, TokenManage,
+	}, readOwn...),
+	RoleScannerOperator: append([]string{
+		TaskCreate, TaskWrite, TokenManage, AgentRead,
+	}, readOwn...),
+}
+
+// RoleInfo 角色及其权限
+type RoleInfo struct {
+	Name        string   `json:"name"`
+	Builtin     bool     `json:"builtin"`
+	Permissions []string `json:"permissions"`
+}
+
+var (
+	mu     sync.RWMutex
+	cached map[string]map[string]bool
+	source map[string][]string // 生成缓存时的配置，配置变化后重新计算
+)
+
+// Can 判断角色是否具备权限；支持 * 与 task.* 形式的通配
+func Can(role, permission string) bool {
+	perms, ok := roles()[role]
+	if !ok {
+		return false
+	}
+	if perms["*"] || perms[permission] {
+		return true
+	}
+	for prefix := permission; ; {
+		i := strings.LastIndexByte(prefix, '.')
+		if i < 0 {
+			return false
+		}
+		prefix = prefix[:i]
+		if perms[prefix+".*"] {
+			return true
+		}
+	}
+}
+
+// CanAny 判断角色是否具备任一权限
+func CanAny(role string, permissions ...string) bool {
+	for _, p := range permissions {
+		if Can(role, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasAdminAccess 判断角色能否访问任一管理接口
+func HasAdminAccess(role string) bool {
+	return CanAny(role, AdminPermissions...)
+}
+
+// RoleExists 判断角色是否已定义
+func RoleExists(role string) bool {
+	_, ok := roles()[role]
+	return ok
+}
+
+// CanGrant 判断 granter 角色能否授予 role 角色：role 的每项权限 granter 都须具备，避免借分配角色提升权限
+func CanGrant(granter, role string) bool {
+	if !RoleExists(role) {
+		return false
+	}
+	for _, p := range PermissionsOf(role) {
+		if !Can(granter, p) {
+			return false
+		}
+	}
+	return true
+}
+
+// PermissionsOf 返回角色展开后的权限列表（通配符展开为具体权限点）
+func PermissionsOf(role string) []string {
+	out := []string{}
+	for p := range Permissions {
+		if Can(role, p) {
+			out = append(out, p)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// ListRoles 返回全部角色，按名称排序
+func ListRoles() []RoleInfo {
+	var list []RoleInfo
+	for name := range roles() {
+		_, builtin := builtinRoles[name]
+		list = append(list, RoleInfo{Name: name, Builtin: builtin, Permissions: PermissionsOf(name)})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// ValidPermission 判断配置中的权限是否有效（具体权限点、* 或 前缀.*）
+func ValidPermission(p string) bool {
+	if p == "*" {
+		return true
+	}
+	if _, ok := Permissions[p]; ok {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(p, ".*"); ok {
+		for known := range Permissions {
+			if strings.HasPrefix(known, prefix+".") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Validate 校验 rbac.roles 配置以及单点登录、目录认证的角色映射，存在未知权限或角色时返回错误
+func Validate() error {
+	for name, perms := range config.RBACRoles() {
+		if name == "" {
+			return fmt.Errorf("rbac.roles 中存在空角色名")
+		}
+		for _, p := range perms {
+			if !ValidPermission(p) {
+				return fmt.Errorf("角色 %s 配置了未知权限 %q", name, p)
+			}
+		}
+	}
+	// 单点登录与目录认证映射出的角色必须已定义
+	mapped := map[string]string{}
+	for _, p := range config.OIDCProviders() {
+		for _, r := range p.RoleMapping {
+			mapped[r] = "oidc." + p.Name
+		}
+		if p.DefaultRole != "" {
+			mapped[p.DefaultRole] = "oidc." + p.Name
+		}
+	}
+	for _, r := range config.Global.LDAP.RoleMapping {
+		mapped[r] = "ldap"
+	}
+	if config.Global.LDAP.DefaultRole != "" {
+		mapped[config.Global.LDAP.DefaultRole] = "ldap"
+	}
+	for role, source := range mapped {
+		if !RoleExists(role) {
+			return fmt.Errorf("%s 的角色映射使用了未定义的角色 %q", source, role)
+		}
+	}
+	return nil
+}
+
+// roles 合并内置角色与配置中的角色
+func roles() map[string]map[string]bool {
+	configured := config.RBACRoles()
+	mu.RLock()
+	if cached != nil && sameConfig(source, configured) {
+		defer mu.RUnlock()
+		return cached
+	}
+	mu.RUnlock()
+
+	merged := map[string]map[string]bool{}
+	for name, perms := range builtinRoles {
+		merged[name] = toSet(perms)
+	}
+	for name, perms := range configured {
+		if name == RoleAdmin {
+			continue // 管理员始终拥有全部权限，避免误配置导致无人可管理
+		}
+		merged[name] = toSet(perms)
+	}
+
+	snapshot := make(map[string][]string, len(configured))
+	for name, perms := range configured {
+		snapshot[name] = append([]string(nil), perms...)
+	}
+	mu.Lock()
+	cached, source = merged, snapshot
+	mu.Unlock()
+	return merged
+}
+
+func toSet(perms []string) map[string]bool {
+	set := make(map[string]bool, len(perms))
+	for _, p := range perms {
+		set[p] = true
+	}
+	return set
+}
+
+func sameConfig(a, b map[string][]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, va := range a {
+		vb, ok := b[k]
+		if !ok || len(va) != len(vb) {
+			return false
+		}
+		for i := range va {
+			if va[i] != vb[i] {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,107 @@
+package report
+
+import (
+	"bytes"
+	"html/template"
+	"strings"
+	"time"
+
+	"VulnFusion/internal/models"
+)
+
+// Section 报告中的一组漏洞（如某个任务或项目）
+type Section struct {
+	Name     string
+	Findings []models.Result
+}
+
+// Report HTML 漏洞报告
+type Report struct {
+	Title       string
+	Summary     string
+	GeneratedAt time.Time
+	Sections    []Section
+}
+
+// Total 返回报告中的漏洞总数
+func (r *Report) Total() int {
+	total := 0
+	for _, s := range r.Sections {
+		total += len(s.Findings)
+	}
+	return total
+}
+
+var funcs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"color": severityColor,
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(funcs).Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="UTF-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", "PingFang SC", "Microsoft YaHei", sans-serif; color: #1d2129; margin: 24px; }
+h1 { font-size: 20px; margin-bottom: 4px; }
+h2 { font-size: 16px; margin-top: 28px; border-bottom: 1px solid #e5e6eb; padding-bottom: 6px; }
+.meta { color: #86909c; font-size: 12px; }
+table { border-collapse: collapse; width: 100%; font-size: 13px; }
+th, td { border: 1px solid #e5e6eb; padding: 6px 8px; text-align: left; vertical-align: top; }
+th { background: #f7f8fa; }
+.sev { color: #fff; border-radius: 3px; padding: 1px 6px; font-size: 12px; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<div class="meta">生成时间：{{.GeneratedAt.Format "2006-01-02 15:04:05"}}　漏洞总数：{{.Total}}</div>
+{{if .Summary}}<p>{{.Summary}}</p>{{end}}
+{{range .Sections}}
+<h2>{{.Name}}（{{len .Findings}}）</h2>
+{{if .Findings}}
+<table>
+<tr><th>等级</th><th>漏洞</th><th>目标</th><th>模板</th><th>CVE</th><th>风险分</th><th>发现时间</th></tr>
+{{range .Findings}}
+<tr>
+<td><span class="sev" style="background:{{color .Severity}}">{{upper .Severity}}</span></td>
+<td>{{.Vulnerability}}</td>
+<td>{{.Target}}</td>
+<td>{{.TemplateID}}</td>
+<td>{{.CVEID}}</td>
+<td>{{printf "%.1f" .RiskScore}}</td>
+<td>{{.Timestamp.Format "2006-01-02 15:04"}}</td>
+</tr>
+{{end}}
+</table>
+{{else}}
+<p class="meta">无</p>
+{{end}}
+{{end}}
+</body>
+</html>
+`))
+
+// RenderHTML 渲染 HTML 报告
+func RenderHTML(r *Report) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := reportTemplate.Execute(&buf, r); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// severityColor 严重等级对应的标签颜色
+func severityColor(severity string) template.CSS {
+	switch strings.ToLower(severity) {
+	case "critical":
+		return "#d32f2f"
+	case "high":
+		return "#f57c00"
+	case "medium":
+		return "#fbc02d"
+	case "low":
+		return "#1976d2"
+	}
+	return "#86909c"
+}
@@ -0,0 +1,113 @@
+package risk
+
+import (
+	"io"
+	"strings"
+
+	"VulnFusion/internal/log"
+	"VulnFusion/internal/models"
+)
+
+// Apply 根据结果自身的分类信息、利用可能性表与关联资产的重要性计算风险分，写回 result（不落库）
+func Apply(result *models.Result) {
+	criticality := ""
+	if result.AssetID != 0 {
+		if asset, err := models.GetAssetByID(result.AssetID); err == nil {
+			criticality = asset.Criticality
+		}
+	}
+	applyWithCriticality(result, criticality)
+}
+
+func applyWithCriticality(result *models.Result, criticality string) {
+	exploit, err := models.GetMaxExploitScore(cveList(result.CVEID))
+	if err != nil {
+		log.Warn("查询利用可能性失败: %v", err)
+	}
+	result.ExploitScore = exploit
+	result.RiskScore = Score(Input{
+		Severity:    result.Severity,
+		CVSSScore:   result.CVSSScore,
+		CVSSVector:  result.CVSSVector,
+		Exploit:     exploit,
+		Criticality: criticality,
+	})
+}
+
+// RecalculateAsset 资产重要性变化后重新计算其下所有结果的风险分
+func RecalculateAsset(assetID uint) error {
+	asset, err := models.GetAssetByID(assetID)
+	if err != nil {
+		return err
+	}
+	results, err := models.ListResultsByAssetID(assetID)
+	if err != nil {
+		return err
+	}
+	for i := range results {
+		applyWithCriticality(&results[i], asset.Criticality)
+		if err := models.UpdateResultRisk(results[i].ID, results[i].ExploitScore, results[i].RiskScore); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RecalculateAll 重新计算全部结果的风险分（如导入利用可能性数据或调整评分配置后），返回处理条数
+func RecalculateAll() (int, error) {
+	criticality := map[uint]string{}
+	total := 0
+	err := models.EachResultBatch(500, func(results []models.Result) error {
+		for i := range results {
+			r := &results[i]
+			c, ok := criticality[r.AssetID]
+			if !ok && r.AssetID != 0 {
+				if asset, err := models.GetAssetByID(r.AssetID); err == nil {
+					c = asset.Criticality
+				}
+				criticality[r.AssetID] = c
+			}
+			applyWithCriticality(r, c)
+			if err := models.UpdateResultRisk(r.ID, r.ExploitScore, r.RiskScore); err != nil {
+				return err
+			}
+			total++
+		}
+		return nil
+	})
+	return total, err
+}
+
+// ImportLikelihood 导入 EPSS 风格的利用可能性 CSV 并重新计算全部风险分，返回导入条数
+func ImportLikelihood(r io.Reader) (int, error) {
+	entries, err := ParseLikelihoodCSV(r)
+	if err != nil {
+		return 0, err
+	}
+
+	rows := make([]models.ExploitLikelihood, 0, len(entries))
+	for _, e := range entries {
+		rows = append(rows, models.ExploitLikelihood{CVEID: e.CVEID, Score: e.Score, Percentile: e.Percentile})
+	}
+	if err := models.ImportExploitLikelihood(rows); err != nil {
+		return 0, err
+	}
+
+	updated, err := RecalculateAll()
+	if err != nil {
+		return len(rows), err
+	}
+	log.Info("导入利用可能性数据 %d 条，重新计算 %d 条结果的风险分", len(rows), updated)
+	return len(rows), nil
+}
+
+// cveList 拆分逗号分隔的 CVE 编号
+func cveList(s string) []string {
+	var ids []string
+	for _, v := range strings.Split(s, ",") {
+		if id := NormalizeCVE(v); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,96 @@
+package risk
+
+import (
+	"errors"
+	"math"
+	"strings"
+)
+
+// ErrInvalidVector CVSS 向量格式无效
+var ErrInvalidVector = errors.New("无效的 CVSS v3 向量")
+
+// cvss3Weights CVSS v3.x 各基础指标取值对应的权重
+var cvss3Weights = map[string]map[string]float64{
+	"AV": {"N": 0.85, "A": 0.62, "L": 0.55, "P": 0.2},
+	"AC": {"L": 0.77, "H": 0.44},
+	"UI": {"N": 0.85, "R": 0.62},
+	"C":  {"H": 0.56, "L": 0.22, "N": 0},
+	"I":  {"H": 0.56, "L": 0.22, "N": 0},
+	"A":  {"H": 0.56, "L": 0.22, "N": 0},
+}
+
+// CVSS3BaseScore 根据 CVSS v3.0 / v3.1 向量计算基础分，如 CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H
+func CVSS3BaseScore(vector string) (float64, error) {
+	parts := strings.Split(strings.TrimSpace(vector), "/")
+	if len(parts) < 9 || !strings.HasPrefix(parts[0], "CVSS:3") {
+		return 0, ErrInvalidVector
+	}
+
+	metrics := map[string]string{}
+	for _, p := range parts[1:] {
+		kv := strings.SplitN(p, ":", 2)
+		if len(kv) != 2 {
+			return 0, ErrInvalidVector
+		}
+		metrics[kv[0]] = kv[1]
+	}
+
+	scope, ok := metrics["S"]
+	if !ok || (scope != "U" && scope != "C") {
+		return 0, ErrInvalidVector
+	}
+	changed := scope == "C"
+
+	values := map[string]float64{}
+	for name, weights := range cvss3Weights {
+		w, ok := weights[metrics[name]]
+		if !ok {
+			return 0, ErrInvalidVector
+		}
+		values[name] = w
+	}
+
+	var pr float64
+	switch metrics["PR"] {
+	case "N":
+		pr = 0.85
+	case "L":
+		pr = 0.62
+		if changed {
+			pr = 0.68
+		}
+	case "H":
+		pr = 0.27
+		if changed {
+			pr = 0.5
+		}
+	default:
+		return 0, ErrInvalidVector
+	}
+
+	iss := 1 - (1-values["C"])*(1-values["I"])*(1-values["A"])
+	var impact float64
+	if changed {
+		impact = 7.52*(iss-0.029) - 3.25*math.Pow(iss-0.02, 15)
+	} else {
+		impact = 6.42 * iss
+	}
+	if impact <= 0 {
+		return 0, nil
+	}
+
+	exploitability := 8.22 * values["AV"] * values["AC"] * pr * values["UI"]
+	if changed {
+		return roundUp(math.Min(1.08*(impact+exploitability), 10)), nil
+	}
+	return roundUp(math.Min(impact+exploitability, 10)), nil
+}
+
+// roundUp 按 CVSS v3.1 规范向上取整到一位小数
+func roundUp(v float64) float64 {
+	i := int(math.Round(v * 100000))
+	if i%10000 == 0 {
+		return float64(i) / 100000
+	}
+	return float64(i/10000+1) / 10
+}
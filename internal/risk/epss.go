@@ -0,0 +1,104 @@
+package risk
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Likelihood 单个 CVE 的利用可能性
+type Likelihood struct {
+	CVEID      string
+	Score      float64 // 利用概率（0~1）
+	Percentile float64 // 百分位（0~1）
+}
+
+// ParseLikelihoodCSV 解析 EPSS 风格的 CSV：表头需包含 cve 与 epss 列，percentile 列可选；
+// 以 # 开头的注释行（如 #model_version）会被忽略
+func ParseLikelihoodCSV(r io.Reader) ([]Likelihood, error) {
+	reader := csv.NewReader(&commentSkipper{r: bufio.NewReader(r)})
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("读取表头失败: %w", err)
+	}
+	cveCol, scoreCol, pctCol := -1, -1, -1
+	for i, h := range header {
+		switch strings.ToLower(strings.TrimSpace(h)) {
+		case "cve", "cve_id", "cve-id":
+			cveCol = i
+		case "epss", "score", "probability":
+			scoreCol = i
+		case "percentile":
+			pctCol = i
+		}
+	}
+	if cveCol < 0 || scoreCol < 0 {
+		return nil, errors.New("CSV 缺少 cve 或 epss 列")
+	}
+
+	var entries []Likelihood
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 行解析失败: %w", line, err)
+		}
+		if len(record) <= cveCol || len(record) <= scoreCol {
+			continue
+		}
+
+		cve := NormalizeCVE(record[cveCol])
+		score, err := strconv.ParseFloat(strings.TrimSpace(record[scoreCol]), 64)
+		if cve == "" || err != nil || score < 0 || score > 1 {
+			return nil, fmt.Errorf("第 %d 行数据无效", line)
+		}
+		entry := Likelihood{CVEID: cve, Score: score}
+		if pctCol >= 0 && len(record) > pctCol {
+			entry.Percentile, _ = strconv.ParseFloat(strings.TrimSpace(record[pctCol]), 64)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// NormalizeCVE 规范化 CVE 编号为大写形式，非 CVE 编号返回空字符串
+func NormalizeCVE(id string) string {
+	id = strings.ToUpper(strings.TrimSpace(id))
+	if !strings.HasPrefix(id, "CVE-") {
+		return ""
+	}
+	return id
+}
+
+// commentSkipper 跳过以 # 开头的行
+type commentSkipper struct {
+	r   *bufio.Reader
+	buf []byte
+}
+
+func (c *commentSkipper) Read(p []byte) (int, error) {
+	for len(c.buf) == 0 {
+		line, err := c.r.ReadBytes('\n')
+		if len(line) > 0 && !strings.HasPrefix(strings.TrimSpace(string(line)), "#") {
+			c.buf = line
+		}
+		if err != nil {
+			if len(c.buf) == 0 {
+				return 0, err
+			}
+			break
+		}
+	}
+	n := copy(p, c.buf)
+	c.buf = c.buf[n:]
+	return n, nil
+}
@@ -0,0 +1,51 @@
+package risk
+
+import (
+	"math"
+	"strings"
+
+	"VulnFusion/internal/config"
+)
+
+// Input 计算风险分所需的输入
+type Input struct {
+	Severity    string  // 模板严重等级
+	CVSSScore   float64 // CVSS 基础分，为 0 时尝试从向量计算
+	CVSSVector  string  // CVSS v3 向量
+	Exploit     float64 // 利用概率（0~1）
+	Criticality string  // 资产重要性
+}
+
+// MaxScore 风险分上限
+const MaxScore = 100
+
+// Score 计算 0~100 的风险分：
+// 基础分由严重等级分与 CVSS 按权重混合，再乘以利用可能性与资产重要性系数
+func Score(in Input) float64 {
+	severityScores := config.GetRiskSeverityScores()
+	severity, ok := severityScores[strings.ToLower(strings.TrimSpace(in.Severity))]
+	if !ok {
+		severity = severityScores["medium"]
+	}
+
+	cvss := in.CVSSScore
+	if cvss <= 0 && in.CVSSVector != "" {
+		cvss, _ = CVSS3BaseScore(in.CVSSVector)
+	}
+
+	base := severity
+	if cvss > 0 {
+		w := config.RiskCVSSWeight()
+		base = w*cvss + (1-w)*severity
+	}
+
+	factor, ok := config.GetRiskCriticalityFactors()[in.Criticality]
+	if !ok {
+		factor = 1
+	}
+
+	exploit := math.Max(0, math.Min(in.Exploit, 1))
+	score := base * 10 * (1 + config.RiskExploitWeight()*exploit) * factor
+	score = math.Max(0, math.Min(score, MaxScore))
+	return math.Round(score*10) / 10
+}
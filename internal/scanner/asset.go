@@ -0,0 +1,144 @@
+package scanner
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"VulnFusion/internal/log"
+	"VulnFusion/internal/models"
+	"VulnFusion/internal/scope"
+)
+
+// RecordTargetAssets 将任务目标登记为资产；目标为服务端列表文件时跳过
+func RecordTargetAssets(task *models.Task) {
+	if info, err := os.Stat(task.Target); err == nil && !info.IsDir() {
+		return
+	}
+
+	userID, projectID := models.AssetOwnerOf(task)
+	techs := splitList(task.DetectedTech)
+	for _, raw := range scope.SplitTargets(task.Target) {
+		t, err := scope.ParseTarget(raw)
+		if err != nil {
+			continue
+		}
+		obs := models.AssetObservation{
+			UserID:       userID,
+			ProjectID:    projectID,
+			Host:         t.Host,
+			Port:         t.Port,
+			Endpoint:     targetEndpoint(t),
+			Technologies: techs,
+		}
+		if t.IP != nil {
+			obs.IP = t.IP.String()
+		}
+		if _, err := models.UpsertAsset(obs); err != nil {
+			log.Warn("任务 %d 登记资产 %s 失败: %v", task.ID, t.Host, err)
+		}
+	}
+}
+
+// recordFindingAsset 根据 nuclei 输出中的 host / ip 字段登记资产，返回资产 ID；无法识别主机时返回 0
+func recordFindingAsset(task *models.Task, r Result) uint {
+	obs, ok := findingObservation(r)
+	if !ok {
+		return 0
+	}
+	obs.UserID, obs.ProjectID = models.AssetOwnerOf(task)
+
+	asset, err := models.UpsertAsset(obs)
+	if err != nil {
+		log.Warn("任务 %d 登记资产 %s 失败: %v", task.ID, obs.Host, err)
+		return 0
+	}
+	return asset.ID
+}
+
+// findingObservation 从单条结果中提取主机、IP 与端口；host 字段缺失时退回 matched-at
+func findingObservation(r Result) (models.AssetObservation, bool) {
+	var obs models.AssetObservation
+	for _, raw := range []string{r.Host, r.Matched} {
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
+		t, err := scope.ParseTarget(raw)
+		if err != nil || t.Host == "" {
+			continue
+		}
+		if obs.Host == "" {
+			obs.Host = t.Host
+		}
+		if obs.Port == 0 && t.Host == obs.Host {
+			obs.Port = t.Port
+		}
+		if obs.Endpoint == "" && t.Host == obs.Host {
+			obs.Endpoint = targetEndpoint(t)
+		}
+	}
+	if obs.Host == "" {
+		return obs, false
+	}
+
+	obs.IP = strings.TrimSpace(r.IP)
+	if obs.IP == "" {
+		if t, err := scope.ParseTarget(obs.Host); err == nil && t.IP != nil {
+			obs.IP = t.IP.String()
+		}
+	}
+	return obs, true
+}
+
+// CloseMissingFindings 任务完成后将其目标资产上本次未再检出的历史漏洞标记为已修复；目标为列表文件时跳过
+func CloseMissingFindings(task *models.Task) {
+	if info, err := os.Stat(task.Target); err == nil && !info.IsDir() {
+		return
+	}
+
+	var hosts []string
+	for _, raw := range scope.SplitTargets(task.Target) {
+		if t, err := scope.ParseTarget(raw); err == nil && t.Host != "" {
+			hosts = append(hosts, t.Host)
+		}
+	}
+	userID, projectID := models.AssetOwnerOf(task)
+	assetIDs, err := models.ListAssetIDsByHosts(userID, projectID, hosts)
+	if err != nil {
+		log.Warn("任务 %d 查询目标资产失败: %v", task.ID, err)
+		return
+	}
+	closed, err := models.CloseMissingFindings(task, assetIDs)
+	if err != nil {
+		log.Warn("任务 %d 关闭未再检出的漏洞失败: %v", task.ID, err)
+		return
+	}
+	if closed > 0 {
+		log.Info("任务 %d 未再检出 %d 个历史漏洞，已标记为已修复", task.ID, closed)
+	}
+}
+
+// targetEndpoint 返回目标的服务地址：有协议时为 协议://主机:端口，仅有端口时为 主机:端口，否则为空
+func targetEndpoint(t scope.Target) string {
+	switch {
+	case t.Scheme != "" && t.Port > 0:
+		return t.Scheme + "://" + net.JoinHostPort(t.Host, strconv.Itoa(t.Port))
+	case t.Scheme != "":
+		return t.Scheme + "://" + t.Host
+	case t.Port > 0:
+		return net.JoinHostPort(t.Host, strconv.Itoa(t.Port))
+	}
+	return ""
+}
+
+// splitList 拆分逗号分隔的列表
+func splitList(s string) []string {
+	var items []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			items = append(items, v)
+		}
+	}
+	return items
+}
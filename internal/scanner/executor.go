@@ -2,7 +2,9 @@ package scanner
 
 import (
 	"VulnFusion/internal/config"
+	"bufio"
 	"errors"
+	"os"
 	"os/exec"
 	"strings"
 
@@ -11,8 +13,9 @@ import (
 
 // ScanOptions 描述 nuclei 扫描参数
 type ScanOptions struct {
-	Target     string   // 单个目标地址（-u）或文件路径（-l）
+	Target     string   // 目标地址，多个以逗号分隔（-u）或目标列表文件路径（-l）
 	Template   string   // 模板路径或目录（-t）
+	Tags       []string // 按标签筛选模板（-tags）
 	Silent     bool     // 静默模式（-silent）
 	JsonOutput bool     // 是否启用 JSONL 输出（-jsonl）
 	CustomArgs []string // 用户自定义附加参数
@@ -41,6 +44,45 @@ func RunScanTask(options ScanOptions) ([]byte, error) {
 	return output, nil
 }
 
+// StreamScanTask 执行 nuclei 扫描任务，并在 stdout 每输出一行时回调 onLine
+func StreamScanTask(options ScanOptions, onLine func(line []byte)) error {
+	if err := ValidateScanOptions(options); err != nil {
+		log.Error("参数校验失败: %v", err)
+		return err
+	}
+
+	cmd, err := BuildNucleiCommand(options)
+	if err != nil {
+		log.Error("命令构建失败: %v", err)
+		return err
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		log.Error("nuclei 启动失败: %v", err)
+		return err
+	}
+
+	reader := bufio.NewScanner(stdout)
+	reader.Buffer(make([]byte, 64*1024), maxLineSize)
+	for reader.Scan() {
+		line := reader.Bytes()
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
+		}
+		onLine(append([]byte(nil), line...))
+	}
+
+	if err := cmd.Wait(); err != nil {
+		log.Error("nuclei 执行失败: %v", err)
+		return err
+	}
+	return reader.Err()
+}
+
 // BuildNucleiCommand 根据参数构造 nuclei 命令
 func BuildNucleiCommand(options ScanOptions) (*exec.Cmd, error) {
 	args := BuildCommandArgs(options)
@@ -53,10 +95,11 @@ func BuildNucleiCommand(options ScanOptions) (*exec.Cmd, error) {
 func BuildCommandArgs(options ScanOptions) []string {
 	var args []string
 
-	if strings.HasPrefix(options.Target, "http") {
-		args = append(args, "-u", options.Target)
-	} else {
+	// 服务端存在的目标列表文件使用 -l，其余（URL、主机、IP，可逗号分隔多个）使用 -u
+	if info, err := os.Stat(options.Target); err == nil && !info.IsDir() {
 		args = append(args, "-l", options.Target)
+	} else {
+		args = append(args, "-u", options.Target)
 	}
 
 	if options.Template != "" {
@@ -71,6 +114,10 @@ func BuildCommandArgs(options ScanOptions) []string {
 		}
 	}
 
+	if len(options.Tags) > 0 {
+		args = append(args, "-tags", strings.Join(options.Tags, ","))
+	}
+
 	if options.JsonOutput {
 		args = append(args, "-jsonl")
 	}
@@ -93,8 +140,8 @@ func ValidateScanOptions(opt ScanOptions) error {
 		log.Error(err.Error())
 		return err
 	}
-	if opt.Template == "" {
-		err := errors.New("Template 不能为空")
+	if opt.Template == "" && len(opt.Tags) == 0 {
+		err := errors.New("Template 与 Tags 不能同时为空")
 		log.Error(err.Error())
 		return err
 	}
@@ -0,0 +1,178 @@
+package scanner
+
+import (
+	"VulnFusion/internal/config"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"VulnFusion/internal/log"
+	"gopkg.in/yaml.v3"
+)
+
+// techAliases 将 nuclei 技术识别结果映射为常见的模板标签
+var techAliases = map[string][]string{
+	"apache-http-server": {"apache"},
+	"apache-httpd":       {"apache"},
+	"apache-tomcat":      {"tomcat", "apache"},
+	"microsoft-iis":      {"iis", "microsoft"},
+	"wp":                 {"wordpress"},
+	"wordpress-plugins":  {"wordpress", "wp-plugin"},
+	"spring-boot":        {"springboot", "spring"},
+	"jboss-as":           {"jboss"},
+}
+
+// techSuffixes 识别模板 ID 中常见的后缀，去除后即为技术名称
+var techSuffixes = []string{"-detect", "-detection", "-version", "-panel", "-login"}
+
+// DetectTechnologies 使用 nuclei 技术识别模板对目标进行指纹识别，返回去重后的技术名称
+func DetectTechnologies(target string) ([]string, error) {
+	output, err := RunScanTask(ScanOptions{
+		Target:     target,
+		Tags:       config.GetTechDetectTags(),
+		JsonOutput: true,
+		Silent:     true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := ParseNucleiResult(output)
+	if err != nil {
+		// 未识别出任何技术栈不视为错误
+		return nil, nil
+	}
+
+	seen := make(map[string]struct{})
+	var techs []string
+	for _, r := range parsed {
+		name := techNameFromResult(r)
+		if name == "" {
+			continue
+		}
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
+		techs = append(techs, name)
+	}
+	sort.Strings(techs)
+
+	log.Info("目标 %s 识别出技术栈: %v", target, techs)
+	return techs, nil
+}
+
+// techNameFromResult 从单条识别结果中提取技术名称，优先使用 matcher-name
+func techNameFromResult(r Result) string {
+	name := r.MatcherName
+	if name == "" {
+		name = r.TemplateID
+		for _, suffix := range techSuffixes {
+			name = strings.TrimSuffix(name, suffix)
+		}
+	}
+	return normalizeTag(name)
+}
+
+// normalizeTag 将技术名称统一为小写、以短横线分隔的标签格式
+func normalizeTag(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	return strings.Join(strings.Fields(strings.ReplaceAll(name, "_", " ")), "-")
+}
+
+// CollectTemplateTags 遍历模板目录，收集所有模板声明的标签
+func CollectTemplateTags(dir string) (map[string]struct{}, error) {
+	tags := make(map[string]struct{})
+	if dir == "" {
+		return tags, nil
+	}
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if info.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Warn("读取模板失败: %s: %v", path, err)
+			return nil
+		}
+
+		var tpl struct {
+			Info struct {
+				Tags interface{} `yaml:"tags"`
+			} `yaml:"info"`
+		}
+		if err := yaml.Unmarshal(data, &tpl); err != nil {
+			log.Warn("解析模板失败: %s: %v", path, err)
+			return nil
+		}
+
+		for _, tag := range splitTemplateTags(tpl.Info.Tags) {
+			tags[tag] = struct{}{}
+		}
+		return nil
+	})
+	return tags, err
+}
+
+// splitTemplateTags 兼容模板中以逗号分隔字符串或数组形式声明的标签
+func splitTemplateTags(raw interface{}) []string {
+	var parts []string
+	switch v := raw.(type) {
+	case string:
+		parts = strings.Split(v, ",")
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				parts = append(parts, s)
+			}
+		}
+	}
+
+	var tags []string
+	for _, p := range parts {
+		if tag := normalizeTag(p); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// SelectTemplateTags 根据识别出的技术栈挑选模板标签
+// available 为模板库中存在的标签集合，为空时不做过滤
+func SelectTemplateTags(techs []string, available map[string]struct{}) []string {
+	excluded := make(map[string]struct{})
+	for _, t := range config.GetTechDetectTags() {
+		excluded[normalizeTag(t)] = struct{}{}
+	}
+
+	selected := make(map[string]struct{})
+	for _, tech := range techs {
+		tech = normalizeTag(tech)
+		candidates := append([]string{tech}, techAliases[tech]...)
+		for _, c := range candidates {
+			if _, skip := excluded[c]; skip || c == "" {
+				continue
+			}
+			if len(available) > 0 {
+				if _, ok := available[c]; !ok {
+					continue
+				}
+			}
+			selected[c] = struct{}{}
+		}
+	}
+
+	tags := make([]string, 0, len(selected))
+	for tag := range selected {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
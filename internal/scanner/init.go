@@ -12,6 +12,9 @@ import (
 
 var nucleiPath = "./data/bin/nuclei"
 
+// nucleiVersion 初始化时检测到的 nuclei 版本
+var nucleiVersion string
+
 // InitNuclei 初始化 nuclei 环境（自动安装 + 版本检测）
 func InitNuclei() error {
 	absPath, _ := filepath.Abs(nucleiPath)
@@ -46,10 +49,35 @@ func InitNuclei() error {
 		log.Error("获取 nuclei 版本失败: %v", err)
 		return err
 	}
+	nucleiVersion = parseNucleiVersion(string(output))
 	log.Info("nuclei 版本：%s", strings.TrimSpace(string(output)))
 	return nil
 }
 
+// parseNucleiVersion 从 nuclei -version 输出中提取版本号，如 v3.4.5
+func parseNucleiVersion(output string) string {
+	for _, line := range strings.Split(output, "\n") {
+		if idx := strings.Index(line, "Version:"); idx >= 0 {
+			return strings.TrimSpace(line[idx+len("Version:"):])
+		}
+	}
+	return strings.TrimSpace(output)
+}
+
+// DetectNucleiVersion 直接执行 nuclei -version 获取版本，未安装时返回空字符串
+func DetectNucleiVersion() string {
+	output, err := exec.Command(GetNucleiPath(), "-version").CombinedOutput()
+	if err != nil {
+		return ""
+	}
+	return parseNucleiVersion(string(output))
+}
+
+// GetNucleiVersion 返回初始化时检测到的 nuclei 版本
+func GetNucleiVersion() string {
+	return nucleiVersion
+}
+
 // GetNucleiPath 返回 nuclei 执行路径
 func GetNucleiPath() string {
 	return nucleiPath
@@ -16,16 +16,54 @@ type Result struct {
 		Name     string   `json:"name"`
 		Severity string   `json:"severity"`
 		Tags     []string `json:"tags"`
+
+		Classification struct {
+			CVSSScore   float64    `json:"cvss-score"`
+			CVSSMetrics string     `json:"cvss-metrics"`
+			CVEID       stringList `json:"cve-id"`
+			CWEID       stringList `json:"cwe-id"`
+		} `json:"classification"`
 	} `json:"info"`
-	Matched   string `json:"matched-at"`
-	Timestamp string `json:"timestamp"`
+	MatcherName string `json:"matcher-name"`
+	Matched     string `json:"matched-at"`
+	Host        string `json:"host"`
+	IP          string `json:"ip"`
+	Timestamp   string `json:"timestamp"`
+
+	Raw string `json:"-"` // 原始 JSONL 行
 }
 
-// ParseNucleiResult 解析 nuclei JSONL 输出，返回结构化结果数组
+// stringList 兼容 nuclei 输出中单个字符串或字符串数组两种形式
+type stringList []string
+
+func (s *stringList) UnmarshalJSON(data []byte) error {
+	var list []string
+	if err := json.Unmarshal(data, &list); err == nil {
+		*s = list
+		return nil
+	}
+	var single string
+	if err := json.Unmarshal(data, &single); err != nil {
+		return err
+	}
+	if single != "" {
+		*s = stringList{single}
+	}
+	return nil
+}
+
+// maxLineSize nuclei 单行输出可能包含完整请求与响应，放宽扫描缓冲区上限
+const maxLineSize = 10 * 1024 * 1024
+
+// ErrNoResults 输出中没有任何有效结果，通常表示本次扫描未检出漏洞
+var ErrNoResults = errors.New("未解析出任何有效结果")
+
+// ParseNucleiResult 解析 nuclei JSONL 输出，返回结构化结果数组；没有有效结果时返回 ErrNoResults
 func ParseNucleiResult(raw []byte) ([]Result, error) {
 	var results []Result
 
 	scanner := bufio.NewScanner(strings.NewReader(string(raw)))
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
 	for scanner.Scan() {
 		line := scanner.Text()
 		if strings.TrimSpace(line) == "" {
@@ -37,6 +75,7 @@ func ParseNucleiResult(raw []byte) ([]Result, error) {
 			log.Error("解析 JSONL 行失败: %v\n内容: %s", err, line)
 			continue
 		}
+		r.Raw = line
 		results = append(results, r)
 	}
 
@@ -45,9 +84,8 @@ func ParseNucleiResult(raw []byte) ([]Result, error) {
 		return nil, err
 	}
 	if len(results) == 0 {
-		err := errors.New("未解析出任何有效结果")
-		log.Warn(err.Error())
-		return nil, err
+		log.Warn(ErrNoResults.Error())
+		return nil, ErrNoResults
 	}
 
 	log.Info("成功解析 %d 条漏洞结果", len(results))
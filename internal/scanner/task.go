@@ -0,0 +1,189 @@
+package scanner
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"VulnFusion/internal/config"
+	"VulnFusion/internal/enrich"
+	"VulnFusion/internal/log"
+	"VulnFusion/internal/models"
+	"VulnFusion/internal/notify"
+	"VulnFusion/internal/risk"
+)
+
+// ErrNoTemplateTags 自动模式下未匹配到任何模板标签
+var ErrNoTemplateTags = errors.New("未匹配到任何模板标签")
+
+// ExecuteTask 执行一个扫描任务：更新状态、调用 nuclei 并保存解析结果
+func ExecuteTask(task *models.Task) {
+	_ = models.UpdateTaskStatus(task.ID, models.StatusRunning)
+
+	options, err := PrepareTaskOptions(task)
+	if task.Mode == models.ModeAuto {
+		saveTechProfile(task)
+	}
+	if errors.Is(err, ErrNoTemplateTags) {
+		log.Warn("任务 %d 未匹配到任何模板标签，跳过漏洞扫描", task.ID)
+		finishTask(task, models.StatusDone, 0)
+		return
+	}
+	if err != nil {
+		log.Error("任务 %d 技术栈识别失败: %v", task.ID, err)
+		finishTask(task, models.StatusFailed, 0)
+		return
+	}
+
+	output, err := RunScanTask(options)
+	if err != nil {
+		log.Error("任务 %d 扫描失败: %v", task.ID, err)
+		finishTask(task, models.StatusFailed, 0)
+		return
+	}
+
+	// 未检出任何漏洞时同样需要关闭目标资产上的历史漏洞，仅在读取输出失败时跳过
+	parsed, err := ParseNucleiResult(output)
+	if err != nil && !errors.Is(err, ErrNoResults) {
+		log.Warn("任务 %d 扫描结果解析失败: %v", task.ID, err)
+		finishTask(task, models.StatusDone, 0)
+		return
+	}
+
+	saved := SaveFindings(task, parsed, LocalProvenance())
+	CloseMissingFindings(task)
+	finishTask(task, models.StatusDone, saved)
+}
+
+// finishTask 更新任务的最终状态并发布任务完成/失败事件
+func finishTask(task *models.Task, status string, findings int) {
+	_ = models.UpdateTaskStatus(task.ID, status)
+	task.Status = status
+	eventType := notify.EventTaskDone
+	if status == models.StatusFailed {
+		eventType = notify.EventTaskFailed
+	}
+	notify.Publish(notify.NewTaskEvent(eventType, task, findings))
+}
+
+// Provenance 描述一批扫描结果的来源
+type Provenance struct {
+	AgentID       uint
+	BatchID       uint
+	NucleiVersion string
+	TemplateHash  string
+}
+
+// LocalProvenance 返回服务端本地扫描的来源信息
+func LocalProvenance() Provenance {
+	hash, err := HashTemplateDir(config.GetNucleiTemplatePath())
+	if err != nil {
+		log.Warn("计算模板库摘要失败: %v", err)
+	}
+	return Provenance{NucleiVersion: GetNucleiVersion(), TemplateHash: hash}
+}
+
+// SaveFindings 将解析后的 nuclei 结果合并到任务的扫描结果，返回成功写入的条数；
+// 同一资产上已存在的漏洞归入本次任务，仅新发现或重新出现的漏洞发布通知
+func SaveFindings(task *models.Task, parsed []Result, source Provenance) int {
+	saved := 0
+	for _, p := range parsed {
+		res := &models.Result{
+			TaskID:        task.ID,
+			Target:        p.Matched,
+			Vulnerability: p.Info.Name,
+			Severity:      strings.ToLower(p.Info.Severity),
+			Detail:        p.Raw,
+			AgentID:       source.AgentID,
+			BatchID:       source.BatchID,
+			NucleiVersion: source.NucleiVersion,
+			TemplateHash:  source.TemplateHash,
+			Host:          p.Host,
+			IP:            p.IP,
+			AssetID:       recordFindingAsset(task, p),
+			TemplateID:    p.TemplateID,
+			CVSSScore:     p.Info.Classification.CVSSScore,
+			CVSSVector:    p.Info.Classification.CVSSMetrics,
+			CVEID:         strings.Join(p.Info.Classification.CVEID, ","),
+			CWEID:         strings.Join(p.Info.Classification.CWEID, ","),
+		}
+		enrich.FillCVSS(res)
+		risk.Apply(res)
+		fresh, err := models.UpsertFinding(res)
+		if err != nil {
+			log.Error("保存任务 %d 扫描结果失败: %v", task.ID, err)
+			continue
+		}
+		if fresh {
+			notify.Publish(notify.NewFindingEvent(task, res))
+		}
+		saved++
+	}
+	return saved
+}
+
+// PrepareTaskOptions 根据任务构造扫描参数；自动模式下会先做技术栈识别，
+// 并将识别结果写回 task.DetectedTech 与 task.SelectedTags（不落库）
+func PrepareTaskOptions(task *models.Task) (ScanOptions, error) {
+	options := ScanOptions{
+		Target:     task.Target,
+		Template:   task.Template,
+		JsonOutput: true,
+		Silent:     true,
+	}
+	if task.Mode != models.ModeAuto {
+		return options, nil
+	}
+
+	techs, err := DetectTechnologies(task.Target)
+	if err != nil {
+		return options, err
+	}
+
+	available, err := CollectTemplateTags(config.GetNucleiTemplatePath())
+	if err != nil {
+		log.Warn("收集模板标签失败，将不按模板库过滤: %v", err)
+		available = nil
+	}
+
+	tags := SelectTemplateTags(techs, available)
+	task.DetectedTech = strings.Join(techs, ",")
+	task.SelectedTags = strings.Join(tags, ",")
+	log.Info("任务 %d 自动选择模板标签: %v", task.ID, tags)
+
+	if len(tags) == 0 {
+		return options, ErrNoTemplateTags
+	}
+	options.Template = ""
+	options.Tags = tags
+	return options, nil
+}
+
+// saveTechProfile 保存任务的技术栈识别结果，并同步到目标资产
+func saveTechProfile(task *models.Task) {
+	RecordTargetAssets(task)
+	if err := models.UpdateTaskByID(task.ID, map[string]interface{}{
+		"detected_tech": task.DetectedTech,
+		"selected_tags": task.SelectedTags,
+	}); err != nil {
+		log.Error("保存任务 %d 技术栈信息失败: %v", task.ID, err)
+	}
+}
+
+// StartAgentWatchdog 定期将心跳超时节点所领取的运行中任务放回队列，由其他节点重新领取
+func StartAgentWatchdog(timeout time.Duration) {
+	go func() {
+		ticker := time.NewTicker(timeout)
+		defer ticker.Stop()
+		for range ticker.C {
+			requeued, err := models.RequeueStaleAgentTasks(time.Now().Add(-timeout))
+			if err != nil {
+				log.Error("回收超时节点任务失败: %v", err)
+				continue
+			}
+			if requeued > 0 {
+				log.Warn("节点心跳超时，已将 %d 个运行中任务放回队列", requeued)
+			}
+		}
+	}()
+}
@@ -0,0 +1,275 @@
+package scope
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// 规则动作
+const (
+	ActionInclude = "include"
+	ActionExclude = "exclude"
+)
+
+// 规则类型
+const (
+	TypeDomain = "domain" // 域名，支持 *.example.com 通配子域名
+	TypeCIDR   = "cidr"   // IP 段或单个 IP
+	TypeURL    = "url"    // URL 前缀
+	TypePort   = "port"   // 端口或端口范围，如 80,443,8000-9000
+)
+
+// Rule 描述一条范围规则
+type Rule struct {
+	Action string `json:"action"`
+	Type   string `json:"type"`
+	Value  string `json:"value"`
+}
+
+// Target 描述解析后的扫描目标
+type Target struct {
+	Raw    string
+	Scheme string
+	Host   string
+	Port   int // 未显式指定且无法从协议推断时为 0
+	Path   string
+	IP     net.IP
+}
+
+// ValidateRule 校验规则格式
+func ValidateRule(r Rule) error {
+	if r.Action != ActionInclude && r.Action != ActionExclude {
+		return fmt.Errorf("不支持的规则动作: %s", r.Action)
+	}
+	value := strings.TrimSpace(r.Value)
+	if value == "" {
+		return errors.New("规则值不能为空")
+	}
+
+	switch r.Type {
+	case TypeDomain:
+		if strings.Contains(strings.TrimPrefix(value, "*."), "*") {
+			return fmt.Errorf("域名通配符仅支持 *. 前缀: %s", value)
+		}
+	case TypeCIDR:
+		if _, _, err := parseCIDR(value); err != nil {
+			return fmt.Errorf("无效的 IP 段: %s", value)
+		}
+	case TypeURL:
+		u, err := url.Parse(value)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("无效的 URL 前缀: %s", value)
+		}
+	case TypePort:
+		if _, err := parsePorts(value); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("不支持的规则类型: %s", r.Type)
+	}
+	return nil
+}
+
+// SplitTargets 将以换行或逗号分隔的目标字符串拆分为目标数组
+func SplitTargets(raw string) []string {
+	var targets []string
+	for _, t := range strings.FieldsFunc(raw, func(r rune) bool {
+		return r == '\n' || r == '\r' || r == ','
+	}) {
+		if t = strings.TrimSpace(t); t != "" {
+			targets = append(targets, t)
+		}
+	}
+	return targets
+}
+
+// ParseTarget 解析扫描目标，支持 URL、host、host:port 与 IP
+func ParseTarget(raw string) (Target, error) {
+	t := Target{Raw: raw}
+	s := strings.TrimSpace(raw)
+	if s == "" {
+		return t, errors.New("目标为空")
+	}
+
+	if strings.Contains(s, "://") {
+		u, err := url.Parse(s)
+		if err != nil || u.Hostname() == "" {
+			return t, fmt.Errorf("无效的目标: %s", raw)
+		}
+		t.Scheme = strings.ToLower(u.Scheme)
+		t.Host = strings.ToLower(u.Hostname())
+		t.Path = u.EscapedPath()
+		if p := u.Port(); p != "" {
+			t.Port, _ = strconv.Atoi(p)
+		} else {
+			t.Port = defaultPort(t.Scheme)
+		}
+	} else if host, port, err := net.SplitHostPort(s); err == nil {
+		t.Host = strings.ToLower(host)
+		t.Port, _ = strconv.Atoi(port)
+	} else {
+		t.Host = strings.ToLower(strings.Trim(s, "[]"))
+	}
+
+	t.Host = strings.TrimSuffix(t.Host, ".")
+	t.IP = net.ParseIP(t.Host)
+	return t, nil
+}
+
+// Check 判断目标是否在范围内；不在范围内时返回原因
+// 规则语义：命中任一 exclude 规则即越界；存在主机类 include 规则（domain/cidr/url）时必须命中其一；
+// 存在端口 include 规则时端口必须落在其中
+func Check(raw string, rules []Rule) (bool, string) {
+	if len(rules) == 0 {
+		return true, ""
+	}
+
+	t, err := ParseTarget(raw)
+	if err != nil {
+		return false, err.Error()
+	}
+
+	hostIncludes, portIncludes := 0, 0
+	hostMatched, portMatched := false, false
+	for _, r := range rules {
+		matched := matchRule(t, r)
+		if r.Action == ActionExclude {
+			if matched {
+				return false, fmt.Sprintf("命中排除规则 %s:%s", r.Type, r.Value)
+			}
+			continue
+		}
+
+		if r.Type == TypePort {
+			portIncludes++
+			portMatched = portMatched || matched
+		} else {
+			hostIncludes++
+			hostMatched = hostMatched || matched
+		}
+	}
+
+	if hostIncludes > 0 && !hostMatched {
+		return false, "未命中任何包含规则"
+	}
+	if portIncludes > 0 && !portMatched {
+		if t.Port == 0 {
+			return false, "目标未指定端口，无法确认是否在允许的端口范围内"
+		}
+		return false, fmt.Sprintf("端口 %d 不在允许范围内", t.Port)
+	}
+	return true, ""
+}
+
+// matchRule 判断目标是否命中单条规则
+func matchRule(t Target, r Rule) bool {
+	value := strings.ToLower(strings.TrimSpace(r.Value))
+	switch r.Type {
+	case TypeDomain:
+		if t.IP != nil {
+			return false
+		}
+		value = strings.TrimSuffix(value, ".")
+		if strings.HasPrefix(value, "*.") {
+			return strings.HasSuffix(t.Host, value[1:])
+		}
+		return t.Host == value
+	case TypeCIDR:
+		if t.IP == nil {
+			return false
+		}
+		_, network, err := parseCIDR(value)
+		return err == nil && network.Contains(t.IP)
+	case TypeURL:
+		if t.Scheme == "" {
+			return false
+		}
+		u, err := url.Parse(value)
+		if err != nil {
+			return false
+		}
+		port := defaultPort(strings.ToLower(u.Scheme))
+		if p := u.Port(); p != "" {
+			port, _ = strconv.Atoi(p)
+		}
+		if strings.ToLower(u.Scheme) != t.Scheme || strings.ToLower(u.Hostname()) != t.Host || port != t.Port {
+			return false
+		}
+		prefix := u.EscapedPath()
+		if prefix == "" || prefix == "/" {
+			return true
+		}
+		path := t.Path
+		return path == prefix || strings.HasPrefix(path, strings.TrimSuffix(prefix, "/")+"/")
+	case TypePort:
+		if t.Port == 0 {
+			return false
+		}
+		ranges, err := parsePorts(value)
+		if err != nil {
+			return false
+		}
+		for _, pr := range ranges {
+			if t.Port >= pr[0] && t.Port <= pr[1] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseCIDR 解析 IP 段，单个 IP 视为 /32 或 /128
+func parseCIDR(value string) (net.IP, *net.IPNet, error) {
+	if !strings.Contains(value, "/") {
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return nil, nil, errors.New("无效的 IP")
+		}
+		bits := 128
+		if ip.To4() != nil {
+			ip = ip.To4()
+			bits = 32
+		}
+		return ip, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+	return net.ParseCIDR(value)
+}
+
+// parsePorts 解析端口列表，如 "80,443,8000-9000"
+func parsePorts(value string) ([][2]int, error) {
+	var ranges [][2]int
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		lo, hi := part, part
+		if i := strings.Index(part, "-"); i >= 0 {
+			lo, hi = part[:i], part[i+1:]
+		}
+		a, err1 := strconv.Atoi(strings.TrimSpace(lo))
+		b, err2 := strconv.Atoi(strings.TrimSpace(hi))
+		if err1 != nil || err2 != nil || a < 1 || b > 65535 || a > b {
+			return nil, fmt.Errorf("无效的端口范围: %s", part)
+		}
+		ranges = append(ranges, [2]int{a, b})
+	}
+	if len(ranges) == 0 {
+		return nil, errors.New("端口规则不能为空")
+	}
+	return ranges, nil
+}
+
+func defaultPort(scheme string) int {
+	switch scheme {
+	case "http":
+		return 80
+	case "https":
+		return 443
+	}
+	return 0
+}
@@ -0,0 +1,161 @@
+package tracker
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// jira Jira REST API v2
+type jira struct {
+	restClient
+	project   string
+	issueType string
+}
+
+func (j *jira) CreateIssue(ctx context.Context, issue Issue) (*Ticket, error) {
+	fields := map[string]interface{}{
+		"project":     map[string]string{"key": j.project},
+		"summary":     issue.Title,
+		"description": issue.Body,
+		"issuetype":   map[string]string{"name": j.issueType},
+	}
+	if len(issue.Labels) > 0 {
+		fields["labels"] = issue.Labels
+	}
+	if issue.Priority != "" {
+		fields["priority"] = map[string]string{"name": issue.Priority}
+	}
+	for k, v := range issue.Extra {
+		fields[k] = v
+	}
+
+	var resp struct {
+		Key string `json:"key"`
+	}
+	if err := j.do(ctx, http.MethodPost, "/rest/api/2/issue", map[string]interface{}{"fields": fields}, &resp); err != nil {
+		return nil, err
+	}
+	return j.GetIssue(ctx, resp.Key)
+}
+
+func (j *jira) GetIssue(ctx context.Context, key string) (*Ticket, error) {
+	var resp struct {
+		Key    string `json:"key"`
+		Fields struct {
+			Status struct {
+				Name string `json:"name"`
+			} `json:"status"`
+		} `json:"fields"`
+	}
+	if err := j.do(ctx, http.MethodGet, "/rest/api/2/issue/"+url.PathEscape(key)+"?fields=status", nil, &resp); err != nil {
+		return nil, err
+	}
+	return &Ticket{Key: resp.Key, URL: j.baseURL + "/browse/" + resp.Key, Status: resp.Fields.Status.Name}, nil
+}
+
+// gitlab GitLab REST API v4，优先级作为标签
+type gitlab struct {
+	restClient
+	project string
+}
+
+type gitlabIssue struct {
+	IID    int    `json:"iid"`
+	WebURL string `json:"web_url"`
+	State  string `json:"state"`
+}
+
+func (g *gitlab) path() string {
+	return "/api/v4/projects/" + url.PathEscape(g.project) + "/issues"
+}
+
+func (g *gitlab) CreateIssue(ctx context.Context, issue Issue) (*Ticket, error) {
+	body := map[string]interface{}{
+		"title":       issue.Title,
+		"description": issue.Body,
+	}
+	if labels := withPriority(issue); len(labels) > 0 {
+		body["labels"] = strings.Join(labels, ",")
+	}
+	for k, v := range issue.Extra {
+		body[k] = v
+	}
+
+	var resp gitlabIssue
+	if err := g.do(ctx, http.MethodPost, g.path(), body, &resp); err != nil {
+		return nil, err
+	}
+	return resp.ticket(), nil
+}
+
+func (g *gitlab) GetIssue(ctx context.Context, key string) (*Ticket, error) {
+	var resp gitlabIssue
+	if err := g.do(ctx, http.MethodGet, g.path()+"/"+url.PathEscape(key), nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.ticket(), nil
+}
+
+func (i gitlabIssue) ticket() *Ticket {
+	return &Ticket{Key: strconv.Itoa(i.IID), URL: i.WebURL, Status: i.State}
+}
+
+// github GitHub REST API，优先级作为标签；关闭原因拼接在状态后，如 closed:not_planned
+type github struct {
+	restClient
+	repo string
+}
+
+type githubIssue struct {
+	Number      int    `json:"number"`
+	HTMLURL     string `json:"html_url"`
+	State       string `json:"state"`
+	StateReason string `json:"state_reason"`
+}
+
+func (g *github) CreateIssue(ctx context.Context, issue Issue) (*Ticket, error) {
+	body := map[string]interface{}{
+		"title": issue.Title,
+		"body":  issue.Body,
+	}
+	if labels := withPriority(issue); len(labels) > 0 {
+		body["labels"] = labels
+	}
+	for k, v := range issue.Extra {
+		body[k] = v
+	}
+
+	var resp githubIssue
+	if err := g.do(ctx, http.MethodPost, "/repos/"+g.repo+"/issues", body, &resp); err != nil {
+		return nil, err
+	}
+	return resp.ticket(), nil
+}
+
+func (g *github) GetIssue(ctx context.Context, key string) (*Ticket, error) {
+	var resp githubIssue
+	if err := g.do(ctx, http.MethodGet, "/repos/"+g.repo+"/issues/"+url.PathEscape(key), nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.ticket(), nil
+}
+
+func (i githubIssue) ticket() *Ticket {
+	status := i.State
+	if i.State == "closed" && i.StateReason != "" {
+		status += ":" + i.StateReason
+	}
+	return &Ticket{Key: strconv.Itoa(i.Number), URL: i.HTMLURL, Status: status}
+}
+
+// withPriority 将优先级追加为标签
+func withPriority(issue Issue) []string {
+	labels := append([]string(nil), issue.Labels...)
+	if issue.Priority != "" {
+		labels = append(labels, issue.Priority)
+	}
+	return labels
+}
@@ -0,0 +1,263 @@
+package tracker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"VulnFusion/internal/log"
+	"VulnFusion/internal/models"
+)
+
+// maxDetailLength 工单正文中原始输出的最大长度
+const maxDetailLength = 4000
+
+// defaultStatusMap 各平台工单状态到处置状态的默认映射（键为小写）
+var defaultStatusMap = map[string]map[string]string{
+	models.TrackerJira: {
+		"to do": models.ResultStatusOpen, "open": models.ResultStatusOpen, "reopened": models.ResultStatusOpen, "in progress": models.ResultStatusOpen,
+		"done": models.ResultStatusFixed, "resolved": models.ResultStatusFixed, "closed": models.ResultStatusFixed,
+		"won't do": models.ResultStatusAccepted, "won't fix": models.ResultStatusAccepted,
+	},
+	models.TrackerGitLab: {
+		"opened": models.ResultStatusOpen, "reopened": models.ResultStatusOpen,
+		"closed": models.ResultStatusFixed,
+	},
+	models.TrackerGitHub: {
+		"open": models.ResultStatusOpen, "reopened": models.ResultStatusOpen,
+		"closed": models.ResultStatusFixed, "closed:completed": models.ResultStatusFixed,
+		"closed:not_planned": models.ResultStatusAccepted, "closed:duplicate": models.ResultStatusAccepted,
+	},
+}
+
+// ticketLocks 按漏洞串行化工单创建，保证同一漏洞只对应一个工单，不同漏洞的创建互不阻塞
+var ticketLocks = keyedMutex{locks: map[string]*refMutex{}}
+
+// keyedMutex 按键加锁，锁在无人持有时释放
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*refMutex
+}
+
+type refMutex struct {
+	sync.Mutex
+	refs int
+}
+
+// Lock 锁定 key，返回解锁函数
+func (k *keyedMutex) Lock(key string) func() {
+	k.mu.Lock()
+	m := k.locks[key]
+	if m == nil {
+		m = &refMutex{}
+		k.locks[key] = m
+	}
+	m.refs++
+	k.mu.Unlock()
+
+	m.Lock()
+	return func() {
+		m.Unlock()
+		k.mu.Lock()
+		if m.refs--; m.refs == 0 {
+			delete(k.locks, key)
+		}
+		k.mu.Unlock()
+	}
+}
+
+// BuildIssue 根据结果与连接器字段映射生成工单内容
+func BuildIssue(conn *models.TrackerConnector, r *models.Result) Issue {
+	mapping := conn.Mapping()
+	severity := strings.ToLower(r.Severity)
+
+	where := r.Host
+	if where == "" {
+		where = r.Target
+	}
+	lines := []string{
+		fmt.Sprintf("**漏洞**：%s", r.Vulnerability),
+		fmt.Sprintf("**等级**：%s", strings.ToUpper(severity)),
+		fmt.Sprintf("**目标**：%s", r.Target),
+	}
+	optional := [][2]string{{"模板", r.TemplateID}, {"CVE", r.CVEID}, {"CWE", r.CWEID}, {"CVSS 向量", r.CVSSVector}}
+	for _, kv := range optional {
+		if kv[1] != "" {
+			lines = append(lines, fmt.Sprintf("**%s**：%s", kv[0], kv[1]))
+		}
+	}
+	if r.CVSSScore > 0 {
+		lines = append(lines, fmt.Sprintf("**CVSS**：%.1f", r.CVSSScore))
+	}
+	lines = append(lines,
+		fmt.Sprintf("**风险分**：%.1f", r.RiskScore),
+		fmt.Sprintf("**VulnFusion**：任务 #%d / 结果 #%d", r.TaskID, r.ID),
+	)
+	if detail := strings.TrimSpace(r.Detail); detail != "" {
+		if len(detail) > maxDetailLength {
+			detail = detail[:maxDetailLength] + "\n..."
+		}
+		lines = append(lines, "```\n"+detail+"\n```")
+	}
+
+	return Issue{
+		Title:    fmt.Sprintf("[VulnFusion][%s] %s - %s", strings.ToUpper(severity), r.Vulnerability, where),
+		Body:     strings.Join(lines, "\n\n"),
+		Labels:   append([]string{"vulnfusion"}, mapping.Labels...),
+		Priority: mapping.Priority[severity],
+		Extra:    mapping.Extra,
+	}
+}
+
+// CreateTicket 为结果创建工单并回写关联信息；结果已有工单，或同一漏洞（资产 + 模板 + 命中位置）
+// 的其他结果已在该连接器中创建过工单时，直接关联已有工单，created 为 false
+func CreateTicket(conn *models.TrackerConnector, resultID uint, client *http.Client) (result *models.Result, created bool, err error) {
+	result, err = models.GetResultByID(resultID)
+	if err != nil {
+		return nil, false, err
+	}
+	unlock := ticketLocks.Lock(result.FindingKey())
+	defer unlock()
+
+	// 加锁期间其他请求可能已创建工单，重新读取
+	result, err = models.GetResultByID(resultID)
+	if err != nil {
+		return nil, false, err
+	}
+	if result.TicketKey != "" {
+		return result, false, nil
+	}
+
+	existing, err := models.FindFindingTicket(result, conn.ID)
+	if err != nil {
+		return nil, false, err
+	}
+	if existing != nil {
+		if _, err := models.SetResultTicket(result.ID, conn.ID, existing.TicketKey, existing.TicketURL, existing.TicketStatus); err != nil {
+			return nil, false, err
+		}
+		result, err = models.GetResultByID(resultID)
+		return result, false, err
+	}
+
+	c, err := New(conn, client)
+	if err != nil {
+		return nil, false, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	ticket, err := c.CreateIssue(ctx, BuildIssue(conn, result))
+	if err != nil {
+		return nil, false, err
+	}
+
+	set, err := models.SetResultTicket(result.ID, conn.ID, ticket.Key, ticket.URL, ticket.Status)
+	if err != nil {
+		return nil, false, err
+	}
+	if !set {
+		// 其他实例抢先关联了工单，以已关联的为准
+		log.Warn("结果 %d 已关联其他工单，新建的工单 %s 未被关联", result.ID, ticket.Key)
+		result, err = models.GetResultByID(resultID)
+		return result, false, err
+	}
+	result.TicketConnectorID = conn.ID
+	result.TicketKey = ticket.Key
+	result.TicketURL = ticket.URL
+	result.TicketStatus = ticket.Status
+	return result, true, nil
+}
+
+// MapStatus 将工单状态映射为处置状态，优先使用连接器字段映射，无匹配时返回空串
+func MapStatus(conn *models.TrackerConnector, ticketStatus string) string {
+	key := strings.ToLower(strings.TrimSpace(ticketStatus))
+	for k, v := range conn.Mapping().Status {
+		if strings.ToLower(k) == key && models.IsValidResultStatus(v) {
+			return v
+		}
+	}
+	return defaultStatusMap[conn.Kind][key]
+}
+
+// SyncResult 拉取结果关联工单的状态；仅在工单状态发生变化时按映射更新处置状态，
+// 避免覆盖在 VulnFusion 中手工做出的处置。返回处置状态是否被修改
+func SyncResult(conn *models.TrackerConnector, result *models.Result, client *http.Client) (bool, error) {
+	c, err := New(conn, client)
+	if err != nil {
+		return false, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	ticket, err := c.GetIssue(ctx, result.TicketKey)
+	if err != nil {
+		return false, err
+	}
+	if ticket.Status == result.TicketStatus {
+		return false, nil
+	}
+	if err := models.UpdateResultTicketStatus(result.ID, ticket.Status); err != nil {
+		return false, err
+	}
+	result.TicketStatus = ticket.Status
+
+	status := MapStatus(conn, ticket.Status)
+	if status == "" || status == result.Status {
+		return false, nil
+	}
+	if err := models.UpdateResultStatus(result.ID, status); err != nil {
+		return false, err
+	}
+	result.Status = status
+	return true, nil
+}
+
+// SyncConnector 同步连接器下所有工单，返回同步的工单数与处置状态被修改的结果数
+func SyncConnector(conn *models.TrackerConnector, client *http.Client) (synced, changed int, err error) {
+	results, err := models.ListTicketedResults(conn.ID)
+	if err != nil {
+		return 0, 0, err
+	}
+	for i := range results {
+		ok, err := SyncResult(conn, &results[i], client)
+		if err != nil {
+			log.Warn("同步结果 %d 的工单 %s 失败: %v", results[i].ID, results[i].TicketKey, err)
+			continue
+		}
+		synced++
+		if ok {
+			changed++
+		}
+	}
+	return synced, changed, nil
+}
+
+// StartSyncScheduler 按间隔同步所有启用的连接器
+func StartSyncScheduler(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			conns, err := models.ListAllTrackerConnectors()
+			if err != nil {
+				log.Error("查询工单连接器失败: %v", err)
+				continue
+			}
+			for i := range conns {
+				if !conns[i].Enabled {
+					continue
+				}
+				synced, changed, err := SyncConnector(&conns[i], nil)
+				if err != nil {
+					log.Warn("同步连接器 %d 失败: %v", conns[i].ID, err)
+					continue
+				}
+				if changed > 0 {
+					log.Info("连接器 %d 同步 %d 个工单，更新 %d 个处置状态", conns[i].ID, synced, changed)
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,130 @@
+package tracker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"VulnFusion/internal/log"
+	"VulnFusion/internal/models"
+	"VulnFusion/internal/netguard"
+)
+
+// ErrUnknownKind 不支持的缺陷跟踪系统类型
+var ErrUnknownKind = errors.New("不支持的缺陷跟踪系统类型")
+
+// Issue 待创建的工单内容
+type Issue struct {
+	Title    string
+	Body     string // markdown
+	Labels   []string
+	Priority string                 // 映射后的优先级
+	Extra    map[string]interface{} // 连接器字段映射中的附加字段
+}
+
+// Ticket 缺陷跟踪系统中的工单
+type Ticket struct {
+	Key    string
+	URL    string
+	Status string // 平台原始状态，如 Jira 的 Done、GitLab 的 closed、GitHub 的 closed:not_planned
+}
+
+// Connector 缺陷跟踪系统连接器
+type Connector interface {
+	CreateIssue(ctx context.Context, issue Issue) (*Ticket, error)
+	GetIssue(ctx context.Context, key string) (*Ticket, error)
+}
+
+// New 根据连接器配置创建对应平台的客户端；client 为空时使用禁止访问内网与保留地址的默认客户端
+func New(conn *models.TrackerConnector, client *http.Client) (Connector, error) {
+	if client == nil {
+		client = netguard.NewClient(15 * time.Second)
+	}
+	base := restClient{client: client, baseURL: strings.TrimRight(conn.BaseURL, "/")}
+	switch conn.Kind {
+	case models.TrackerJira:
+		if conn.Username != "" {
+			base.auth = func(r *http.Request) { r.SetBasicAuth(conn.Username, conn.Token) }
+		} else {
+			base.auth = bearer(conn.Token)
+		}
+		issueType := conn.IssueType
+		if issueType == "" {
+			issueType = "Bug"
+		}
+		return &jira{restClient: base, project: conn.Project, issueType: issueType}, nil
+	case models.TrackerGitLab:
+		base.auth = func(r *http.Request) { r.Header.Set("PRIVATE-TOKEN", conn.Token) }
+		return &gitlab{restClient: base, project: conn.Project}, nil
+	case models.TrackerGitHub:
+		if base.baseURL == "" {
+			base.baseURL = "https://api.github.com"
+		}
+		base.auth = bearer(conn.Token)
+		return &github{restClient: base, repo: conn.Project}, nil
+	}
+	return nil, ErrUnknownKind
+}
+
+// restClient JSON REST 请求的公共实现
+type restClient struct {
+	client  *http.Client
+	baseURL string
+	auth    func(*http.Request)
+}
+
+func bearer(token string) func(*http.Request) {
+	return func(r *http.Request) {
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+}
+
+// do 发送 JSON 请求并解析响应；非 2xx 时响应摘要只写入服务端日志，返回的错误仅包含状态码
+func (c *restClient) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.auth != nil {
+		c.auth(req)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		snippet := strings.TrimSpace(string(data))
+		if len(snippet) > 200 {
+			snippet = snippet[:200]
+		}
+		log.Warn("缺陷跟踪系统 %s %s 返回 %d: %s", method, path, resp.StatusCode, snippet)
+		return fmt.Errorf("%s %s 返回 %d", method, path, resp.StatusCode)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
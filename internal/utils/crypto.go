@@ -2,6 +2,12 @@
 package utils
 
 import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -19,3 +25,25 @@ func CheckPassword(plain, hashed string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(hashed), []byte(plain))
 	return err == nil
 }
+
+// GenerateSecureToken 使用 crypto/rand 生成指定字节数的随机令牌（URL 安全的 base64 编码）
+func GenerateSecureToken(size int) (string, error) {
+	raw := make([]byte, size)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// SHA256Hex 返回字符串的 SHA-256 十六进制摘要，用于存储令牌等不可逆凭据
+func SHA256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// HMACSHA256Hex 使用密钥计算数据的 HMAC-SHA256 十六进制摘要
+func HMACSHA256Hex(secret string, data []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -1,12 +1,21 @@
 package main
 
 import (
+	"VulnFusion/internal/agent"
+	"VulnFusion/internal/auth"
 	"VulnFusion/internal/bootstrap"
 	"VulnFusion/internal/config"
+	"VulnFusion/internal/enrich"
+	"VulnFusion/internal/models"
+	"VulnFusion/internal/risk"
 	"VulnFusion/web/router"
+	"flag"
+	"fmt"
 	"github.com/gin-gonic/gin"
+	"io"
 	"log"
 	"net/http"
+	"os"
 
 	_ "VulnFusion/docs" // ✅ 加载 swag 生成的文档文件
 	swaggerFiles "github.com/swaggo/files"
@@ -19,17 +28,36 @@ import (
 // @host localhost:8080
 // @BasePath /api/v1
 func main() {
+	// 远程扫描节点模式：vulnfusion agent -server ... -token ...
+	if len(os.Args) > 1 && os.Args[1] == "agent" {
+		if err := agent.Run(os.Args[2:]); err != nil {
+			log.Fatalf("节点运行失败: %v", err)
+		}
+		return
+	}
+
 	// 加载配置
 	if err := config.LoadConfig("config.yaml"); err != nil {
 		log.Fatalf("配置加载失败: %v", err)
 	}
 
+	// 离线数据导入等命令行工具：vulnfusion import-epss|import-nvd|import-cwe <文件>...、vulnfusion rotate-jwt-key
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1], os.Args[2:]); err != nil {
+			log.Fatalf("命令执行失败: %v", err)
+		}
+		return
+	}
+
 	if err := bootstrap.InitializeSystem(); err != nil {
 		panic(err)
 	}
 
 	// 启动 Gin 引擎
 	r := gin.Default()
+	if err := router.SetTrustedProxies(r); err != nil {
+		log.Fatalf("trusted_proxies 配置无效: %v", err)
+	}
 
 	// 注册业务路由
 	router.RegisterRoutes(r)
@@ -51,3 +79,64 @@ func main() {
 		log.Fatalf("启动服务失败: %v", err)
 	}
 }
+
+// runCommand 执行命令行子命令
+func runCommand(name string, args []string) error {
+	if name == "rotate-jwt-key" {
+		return rotateJWTKey(args)
+	}
+
+	var importFn func(io.Reader) (int, error)
+	switch name {
+	case "import-epss":
+		importFn = risk.ImportLikelihood
+	case "import-nvd":
+		importFn = enrich.ImportNVD
+	case "import-cwe":
+		importFn = enrich.ImportCWE
+	default:
+		return fmt.Errorf("未知命令: %s", name)
+	}
+
+	if len(args) == 0 {
+		return fmt.Errorf("用法: vulnfusion %s <文件>...", name)
+	}
+	if err := bootstrap.InitializeDatabase(); err != nil {
+		return err
+	}
+	for _, path := range args {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		count, err := importFn(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		fmt.Printf("%s: 已导入 %d 条\n", path, count)
+	}
+	return nil
+}
+
+// rotateJWTKey 轮换 JWT 签名密钥：vulnfusion rotate-jwt-key [-alg HS256|EdDSA|RS256]
+func rotateJWTKey(args []string) error {
+	fs := flag.NewFlagSet("rotate-jwt-key", flag.ContinueOnError)
+	alg := fs.String("alg", "", "新密钥的签名算法，默认使用 jwt.algorithm 配置")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *alg != "" && auth.NormalizeAlgorithm(*alg) == "" {
+		return fmt.Errorf("不支持的签名算法: %s", *alg)
+	}
+	if err := bootstrap.InitializeDatabase(); err != nil {
+		return err
+	}
+	auth.SetKeyStore(models.SigningKeyStore{})
+	key, err := auth.RotateSigningKey(*alg)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("已轮换 JWT 签名密钥：kid=%s alg=%s，旧密钥在宽限期内仍可验证\n", key.ID, key.Algorithm)
+	return nil
+}
@@ -0,0 +1,185 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"VulnFusion/internal/auth"
+	"VulnFusion/internal/db"
+	"VulnFusion/internal/log"
+	"VulnFusion/internal/models"
+	"VulnFusion/internal/utils"
+	"VulnFusion/web/api"
+	"VulnFusion/web/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMain(m *testing.M) {
+	log.InitLogger("dev", "debug")
+	code := m.Run()
+	_ = os.RemoveAll("./testdata")
+	os.Exit(code)
+}
+
+func TestScopeForRoute(t *testing.T) {
+	assert.Equal(t, "tasks:read", auth.ScopeForRoute(http.MethodGet, "/api/v1/tasks/:id"))
+	assert.Equal(t, "tasks:write", auth.ScopeForRoute(http.MethodPost, "/api/v1/tasks"))
+	assert.Equal(t, "admin:write", auth.ScopeForRoute(http.MethodDelete, "/api/v1/admin/users/:id"))
+	assert.Equal(t, "scope:read", auth.ScopeForRoute(http.MethodPost, "/api/v1/scope/check"))
+	assert.Equal(t, "tokens:write", auth.ScopeForRoute(http.MethodPost, "/api/v1/tokens"))
+	assert.False(t, auth.IsValidScope("tokens:write"))
+	assert.Equal(t, "ci:write", auth.ScopeForRoute(http.MethodPost, "/api/v1/ci/scan"))
+	assert.Equal(t, "ci:read", auth.ScopeForRoute(http.MethodGet, "/api/v1/ci/scan/:id"))
+	assert.True(t, auth.IsValidScope("ci:write"))
+
+	claims := &auth.CustomClaims{TokenID: 1, Scopes: []string{"tasks:write", "results:read"}}
+	assert.True(t, claims.HasScope("tasks:read"))
+	assert.True(t, claims.HasScope("results:read"))
+	assert.False(t, claims.HasScope("results:write"))
+	assert.True(t, (&auth.CustomClaims{UserID: 1}).HasScope("admin:write"))
+}
+
+func createAPIToken(t *testing.T, userID uint, scopes string, expires *time.Time) string {
+	secret, err := utils.GenerateSecureToken(32)
+	assert.NoError(t, err)
+	raw := auth.APITokenPrefix + secret
+	assert.NoError(t, models.CreateAPIToken(&models.APIToken{
+		UserID: userID, Name: "ci", Prefix: raw[:11], TokenHash: utils.SHA256Hex(raw), Scopes: scopes, ExpiresAt: expires,
+	}))
+	return raw
+}
+
+func TestAPITokenMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	_ = os.RemoveAll("./testdata")
+	_, err := db.InitDatabase("./testdata/test.db")
+	assert.NoError(t, err)
+
+	user := &models.User{Username: "ci-bot", Password: "x", Role: "user"}
+	assert.NoError(t, models.CreateUser(user))
+
+	r := gin.New()
+	group := r.Group("/api/v1", middleware.JWTAuthMiddleware())
+	group.GET("/results", func(ctx *gin.Context) {
+		claims := ctx.MustGet("claims").(*auth.CustomClaims)
+		assert.Equal(t, user.ID, claims.UserID)
+		ctx.String(http.StatusOK, "ok")
+	})
+	group.POST("/tasks", func(ctx *gin.Context) { ctx.String(http.StatusOK, "ok") })
+
+	call := func(method, path, token string) int {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	raw := createAPIToken(t, user.ID, "results:read", nil)
+	assert.Equal(t, http.StatusOK, call(http.MethodGet, "/api/v1/results", raw))
+	assert.Equal(t, http.StatusForbidden, call(http.MethodPost, "/api/v1/tasks", raw))
+
+	// 记录最近使用
+	saved, err := models.GetAPITokenByHash(utils.SHA256Hex(raw))
+	assert.NoError(t, err)
+	assert.NotNil(t, saved.LastUsedAt)
+	assert.NotEmpty(t, saved.LastUsedIP)
+
+	// 吊销后立即失效
+	assert.NoError(t, models.RevokeAPIToken(saved.ID))
+	assert.Equal(t, http.StatusUnauthorized, call(http.MethodGet, "/api/v1/results", raw))
+
+	// 过期令牌
+	past := time.Now().Add(-time.Hour)
+	expired := createAPIToken(t, user.ID, "results:read", &past)
+	assert.Equal(t, http.StatusUnauthorized, call(http.MethodGet, "/api/v1/results", expired))
+
+	// 未知令牌
+	assert.Equal(t, http.StatusUnauthorized, call(http.MethodGet, "/api/v1/results", auth.APITokenPrefix+"unknown"))
+}
+
+func TestAdminRevocationRevokesAPITokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	_ = os.RemoveAll("./testdata")
+	_, err := db.InitDatabase("./testdata/test.db")
+	assert.NoError(t, err)
+
+	user := &models.User{Username: "ci-owner", Password: "x", Role: "user"}
+	assert.NoError(t, models.CreateUser(user))
+
+	r := gin.New()
+	asAdmin := func(ctx *gin.Context) {
+		middleware.InjectClaimsToContext(ctx, &auth.CustomClaims{UserID: 99, Username: "root", Role: "admin"})
+	}
+	r.POST("/admin/users/:id/revoke-tokens", asAdmin, api.HandleRevokeUserTokens)
+	r.PUT("/admin/users/:id/password", asAdmin, api.HandleResetPasswordByID)
+	group := r.Group("/api/v1", middleware.JWTAuthMiddleware())
+	group.GET("/results", func(ctx *gin.Context) { ctx.String(http.StatusOK, "ok") })
+
+	call := func(method, path, token, body string) int {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	userPath := fmt.Sprintf("/admin/users/%d", user.ID)
+
+	// 管理员强制下线后 API 令牌同样失效
+	raw := createAPIToken(t, user.ID, "results:read", nil)
+	assert.Equal(t, http.StatusOK, call(http.MethodGet, "/api/v1/results", raw, ""))
+	assert.Equal(t, http.StatusOK, call(http.MethodPost, userPath+"/revoke-tokens", "", ""))
+	assert.Equal(t, http.StatusUnauthorized, call(http.MethodGet, "/api/v1/results", raw, ""))
+
+	// 管理员重置密码后 API 令牌同样失效
+	raw = createAPIToken(t, user.ID, "results:read", nil)
+	assert.Equal(t, http.StatusOK, call(http.MethodGet, "/api/v1/results", raw, ""))
+	assert.Equal(t, http.StatusOK, call(http.MethodPut, userPath+"/password", "", `{"password":"N3w-Passw0rd!"}`))
+	assert.Equal(t, http.StatusUnauthorized, call(http.MethodGet, "/api/v1/results", raw, ""))
+}
+
+func TestAPITokenCIScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	_ = os.RemoveAll("./testdata")
+	_, err := db.InitDatabase("./testdata/test.db")
+	assert.NoError(t, err)
+
+	user := &models.User{Username: "pipeline", Password: "x", Role: "user"}
+	assert.NoError(t, models.CreateUser(user))
+
+	r := gin.New()
+	group := r.Group("/api/v1", middleware.JWTAuthMiddleware())
+	ok := func(ctx *gin.Context) { ctx.String(http.StatusOK, "ok") }
+	group.POST("/ci/scan", ok)
+	group.GET("/ci/scan/:id", ok)
+
+	call := func(method, path, token string) int {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// ci:write 可发起并查询门禁扫描
+	ci := createAPIToken(t, user.ID, "ci:write", nil)
+	assert.Equal(t, http.StatusOK, call(http.MethodPost, "/api/v1/ci/scan", ci))
+	assert.Equal(t, http.StatusOK, call(http.MethodGet, "/api/v1/ci/scan/1", ci))
+
+	// 其他资源的权限范围不能访问门禁接口
+	other := createAPIToken(t, user.ID, "tasks:write", nil)
+	assert.Equal(t, http.StatusForbidden, call(http.MethodPost, "/api/v1/ci/scan", other))
+	readOnly := createAPIToken(t, user.ID, "ci:read", nil)
+	assert.Equal(t, http.StatusForbidden, call(http.MethodPost, "/api/v1/ci/scan", readOnly))
+	assert.Equal(t, http.StatusOK, call(http.MethodGet, "/api/v1/ci/scan/1", readOnly))
+}
@@ -2,7 +2,6 @@
 package auth
 
 import (
-	"encoding/base64"
 	"strconv"
 	"testing"
 	"time"
@@ -23,7 +22,8 @@ func TestGenerateAndParseToken(t *testing.T) {
 
 	claims, err := auth.ParseToken(tokenStr)
 	assert.Nil(t, err)
-	assert.Equal(t, 1, claims.UserID)
+	assert.Equal(t, uint(1), claims.UserID)
+	assert.Equal(t, auth.TokenTypeAccess, claims.Type)
 	assert.Equal(t, "admin", claims.Username)
 	assert.Equal(t, "super", claims.Role)
 }
@@ -41,13 +41,27 @@ func TestGenerateRefreshToken(t *testing.T) {
 
 	claims := parsedToken.Claims.(*jwt.RegisteredClaims)
 
-	// 解码 subject 为 userID
-	decodedSub, err := base64.StdEncoding.DecodeString(claims.Subject)
+	// subject 为十进制用户 ID，受众与访问令牌不同
+	userID, err := strconv.Atoi(claims.Subject)
 	assert.Nil(t, err)
+	assert.Equal(t, 1, userID)
+	assert.Equal(t, jwt.ClaimStrings{auth.AudienceRefresh}, claims.Audience)
 
-	userID, err := strconv.Atoi(string(decodedSub))
+	refreshClaims, err := auth.ParseRefreshToken(tokenStr)
 	assert.Nil(t, err)
-	assert.Equal(t, 1, userID)
+	assert.Equal(t, uint(1), refreshClaims.UserID)
+}
+
+func TestTokenTypesAreNotInterchangeable(t *testing.T) {
+	access, err := auth.GenerateToken(1, "admin", "super", time.Minute)
+	assert.Nil(t, err)
+	refresh, err := auth.GenerateRefreshToken(1, time.Hour)
+	assert.Nil(t, err)
+
+	_, err = auth.ParseToken(refresh)
+	assert.NotNil(t, err)
+	_, err = auth.ParseRefreshToken(access)
+	assert.NotNil(t, err)
 }
 
 func TestBlacklistToken(t *testing.T) {
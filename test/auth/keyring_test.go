@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"os"
+	"testing"
+	"time"
+
+	"VulnFusion/internal/auth"
+	"VulnFusion/internal/config"
+	"VulnFusion/internal/db"
+	"VulnFusion/internal/models"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// tokenKid 读取令牌头中的 kid
+func tokenKid(t *testing.T, token string) string {
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, &auth.CustomClaims{})
+	assert.NoError(t, err)
+	kid, _ := parsed.Header["kid"].(string)
+	return kid
+}
+
+func TestSigningKeyRotationAndGracePeriod(t *testing.T) {
+	_ = os.RemoveAll("./testdata")
+	_, err := db.InitDatabase("./testdata/test.db")
+	assert.NoError(t, err)
+	auth.SetKeyStore(models.SigningKeyStore{})
+	assert.NoError(t, auth.InitSigningKeys())
+
+	oldToken, err := auth.GenerateToken(1, "admin", "admin", time.Hour)
+	assert.NoError(t, err)
+	oldKid := tokenKid(t, oldToken)
+	assert.NotEmpty(t, oldKid)
+
+	key, err := auth.RotateSigningKey("EdDSA")
+	assert.NoError(t, err)
+	assert.Equal(t, auth.AlgEdDSA, key.Algorithm)
+
+	newToken, err := auth.GenerateToken(1, "admin", "admin", time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, key.ID, tokenKid(t, newToken))
+
+	// 模拟重启：密钥从数据库重新加载，轮换前后签发的令牌均有效
+	auth.SetKeyStore(models.SigningKeyStore{})
+	_, err = auth.ParseToken(oldToken)
+	assert.NoError(t, err)
+	_, err = auth.ParseToken(newToken)
+	assert.NoError(t, err)
+
+	keys, err := auth.ListSigningKeys()
+	assert.NoError(t, err)
+	assert.Len(t, keys, 2)
+	assert.True(t, keys[0].Active)
+	assert.Equal(t, key.ID, keys[0].ID)
+	assert.NotNil(t, keys[1].VerifyUntil)
+
+	// JWKS 只发布非对称公钥，其他服务可据此验证令牌
+	set, err := auth.PublicJWKS()
+	assert.NoError(t, err)
+	assert.Len(t, set.Keys, 1)
+	assert.Equal(t, key.ID, set.Keys[0].Kid)
+	x, err := base64.RawURLEncoding.DecodeString(set.Keys[0].X)
+	assert.NoError(t, err)
+	parsed, err := jwt.Parse(newToken, func(*jwt.Token) (interface{}, error) {
+		return ed25519.PublicKey(x), nil
+	}, jwt.WithValidMethods([]string{"EdDSA"}))
+	assert.NoError(t, err)
+	assert.True(t, parsed.Valid)
+
+	// 宽限期结束后旧密钥签发的令牌不再被接受
+	config.Global.JWT.KeyGracePeriod = time.Nanosecond
+	defer func() { config.Global.JWT.KeyGracePeriod = 0 }()
+	auth.SetKeyStore(models.SigningKeyStore{})
+	_, err = auth.ParseToken(oldToken)
+	assert.Error(t, err)
+	_, err = auth.ParseToken(newToken)
+	assert.NoError(t, err)
+	n, err := auth.PruneSigningKeys()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+}
+
+func TestSigningKeyRejectsAlgorithmConfusion(t *testing.T) {
+	_ = os.RemoveAll("./testdata")
+	_, err := db.InitDatabase("./testdata/test.db")
+	assert.NoError(t, err)
+	auth.SetKeyStore(models.SigningKeyStore{})
+	key, err := auth.RotateSigningKey("EdDSA")
+	assert.NoError(t, err)
+
+	set, err := auth.PublicJWKS()
+	assert.NoError(t, err)
+	pub, _ := base64.RawURLEncoding.DecodeString(set.Keys[0].X)
+
+	// 以公钥作为 HMAC 密钥伪造的令牌必须被拒绝
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, auth.CustomClaims{
+		UserID: 1,
+		Type:   auth.TokenTypeAccess,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{auth.AudienceAPI},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	})
+	forged.Header["kid"] = key.ID
+	signed, err := forged.SignedString(pub)
+	assert.NoError(t, err)
+	_, err = auth.ParseToken(signed)
+	assert.Error(t, err)
+
+	// 缺少 kid 的令牌同样被拒绝
+	delete(forged.Header, "kid")
+	signed, _ = forged.SignedString(pub)
+	_, err = auth.ParseToken(signed)
+	assert.Error(t, err)
+}
+
+func TestStaticSecretCannotRotate(t *testing.T) {
+	config.Global.JWT.Secret = "static-test-secret"
+	defer func() {
+		config.Global.JWT.Secret = ""
+		auth.SetKeyStore(models.SigningKeyStore{})
+	}()
+	auth.SetKeyStore(models.SigningKeyStore{})
+
+	token, err := auth.GenerateToken(1, "admin", "admin", time.Hour)
+	assert.NoError(t, err)
+	_, err = auth.ParseToken(token)
+	assert.NoError(t, err)
+
+	_, err = auth.RotateSigningKey("")
+	assert.Error(t, err)
+}
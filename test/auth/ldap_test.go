@@ -0,0 +1,125 @@
+package auth
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"VulnFusion/internal/config"
+	"VulnFusion/internal/ldap/ldaptest"
+	"VulnFusion/internal/models"
+	"VulnFusion/web/api"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+const ldapAdmins = "cn=vf-admins,ou=groups,dc=example,dc=com"
+
+func setupLDAPTest(t *testing.T) *ldaptest.Server {
+	setupMFATest(t, "breakglass", "admin")
+	srv := ldaptest.NewServer()
+	t.Cleanup(srv.Close)
+	srv.AddEntry("cn=svc,dc=example,dc=com", "svc-pass", nil)
+	srv.AddEntry("uid=carol,ou=people,dc=example,dc=com", "carol-pass", map[string][]string{
+		"uid":      {"carol"},
+		"mail":     {"carol@example.com"},
+		"memberOf": {ldapAdmins},
+	})
+	srv.AddEntry("uid=dave,ou=people,dc=example,dc=com", "dave-pass", map[string][]string{
+		"uid": {"dave"},
+	})
+
+	oldAuth, oldLDAP, oldMFA := config.Global.Auth, config.Global.LDAP, config.Global.MFA
+	t.Cleanup(func() {
+		config.Global.Auth, config.Global.LDAP, config.Global.MFA = oldAuth, oldLDAP, oldMFA
+	})
+	config.Global.MFA.EnforceRoles = nil
+	config.Global.Auth.Backend = "ldap"
+	// 各用例复用同一批目录账号，避免上一个用例的密码错误触发登录等待
+	config.Global.Auth.LoginProtection.BaseDelay = time.Nanosecond
+	config.Global.LDAP = config.LDAPConfig{
+		URL:          srv.URL,
+		BindDN:       "cn=svc,dc=example,dc=com",
+		BindPassword: "svc-pass",
+		BaseDN:       "ou=people,dc=example,dc=com",
+		RoleMapping:  map[string]string{"vf-admins": "admin"},
+		LocalUsers:   []string{"breakglass"},
+	}
+	return srv
+}
+
+func ldapRouter() *gin.Engine {
+	r := gin.New()
+	r.POST("/auth/login", api.HandleLogin)
+	r.POST("/auth/register", api.HandleRegister)
+	return r
+}
+
+func TestLDAPLoginProvisionsAndRemapsRoles(t *testing.T) {
+	setupLDAPTest(t)
+	r := ldapRouter()
+
+	code, resp := callJSON(r, "/auth/login", "", map[string]string{"username": "carol", "password": "carol-pass"})
+	assert.Equal(t, http.StatusOK, code)
+	assert.NotEmpty(t, resp["token"])
+	user, err := models.GetUserByUsername("carol")
+	assert.NoError(t, err)
+	assert.Equal(t, "admin", user.Role)
+	assert.Equal(t, "ldap", user.AuthProvider)
+	assert.Equal(t, "carol@example.com", user.Email)
+
+	// 组映射调整后下次登录角色随之变化，不会重复创建用户
+	config.Global.LDAP.RoleMapping = map[string]string{}
+	code, _ = callJSON(r, "/auth/login", "", map[string]string{"username": "carol", "password": "carol-pass"})
+	assert.Equal(t, http.StatusOK, code)
+	again, _ := models.GetUserByUsername("carol")
+	assert.Equal(t, user.ID, again.ID)
+	assert.Equal(t, "user", again.Role)
+
+	code, _ = callJSON(r, "/auth/login", "", map[string]string{"username": "carol", "password": "wrong"})
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	// 启用目录认证后关闭本地注册
+	code, _ = callJSON(r, "/auth/register", "", map[string]string{"username": "eve", "password": "x", "role": "user"})
+	assert.Equal(t, http.StatusForbidden, code)
+}
+
+func TestLDAPDenyUnmappedAndLocalAccounts(t *testing.T) {
+	srv := setupLDAPTest(t)
+	r := ldapRouter()
+	config.Global.LDAP.DenyUnmapped = true
+
+	code, _ := callJSON(r, "/auth/login", "", map[string]string{"username": "dave", "password": "dave-pass"})
+	assert.Equal(t, http.StatusForbidden, code)
+	_, err := models.GetUserByUsername("dave")
+	assert.Error(t, err)
+
+	// 应急账号仍使用本地密码
+	code, resp := callJSON(r, "/auth/login", "", map[string]string{"username": "breakglass", "password": "s3cret-pass"})
+	assert.Equal(t, http.StatusOK, code)
+	assert.NotEmpty(t, resp["token"])
+
+	// 同名本地账号默认不会被目录账号接管，开启 link_local_users 后才关联
+	srv.AddEntry("uid=breakglass,ou=people,dc=example,dc=com", "dir-pass", map[string][]string{"uid": {"breakglass"}})
+	config.Global.LDAP.LocalUsers = nil
+	config.Global.LDAP.DenyUnmapped = false
+	creds := map[string]string{"username": "breakglass", "password": "dir-pass"}
+	code, _ = callJSON(r, "/auth/login", "", creds)
+	assert.Equal(t, http.StatusForbidden, code)
+
+	config.Global.LDAP.LinkLocalUsers = true
+	code, _ = callJSON(r, "/auth/login", "", creds)
+	assert.Equal(t, http.StatusOK, code)
+	linked, _ := models.GetUserByUsername("breakglass")
+	assert.Equal(t, "ldap", linked.AuthProvider)
+	assert.Equal(t, "user", linked.Role)
+}
+
+func TestLDAPDirectoryUnavailable(t *testing.T) {
+	srv := setupLDAPTest(t)
+	srv.Close()
+	code, resp := callJSON(ldapRouter(), "/auth/login", "", map[string]string{"username": "carol", "password": "carol-pass"})
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.NotEmpty(t, resp["error"])
+}
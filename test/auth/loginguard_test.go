@@ -0,0 +1,291 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"VulnFusion/internal/auth"
+	"VulnFusion/internal/config"
+	"VulnFusion/internal/models"
+	"VulnFusion/web/api"
+	"VulnFusion/web/middleware"
+	"VulnFusion/web/router"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func withLoginProtection(t *testing.T, maxFailures, ipMaxFailures int, baseDelay time.Duration) {
+	old := config.Global.Auth
+	t.Cleanup(func() { config.Global.Auth = old })
+	config.Global.Auth.Backend = "local"
+	config.Global.Auth.LoginProtection.MaxFailures = maxFailures
+	config.Global.Auth.LoginProtection.IPMaxFailures = ipMaxFailures
+	config.Global.Auth.LoginProtection.LockoutDuration = 10 * time.Minute
+	config.Global.Auth.LoginProtection.IPWindow = 10 * time.Minute
+	config.Global.Auth.LoginProtection.BaseDelay = baseDelay
+	config.Global.Auth.LoginProtection.MaxDelay = 8 * time.Second
+}
+
+func TestLoginGuardProgressiveDelayAndLockout(t *testing.T) {
+	withLoginProtection(t, 4, 100, time.Second)
+	g := auth.NewLoginGuard()
+	now := time.Now()
+
+	wait, locked := g.Check("10.0.0.1", "alice", now)
+	assert.Zero(t, wait)
+	assert.False(t, locked)
+
+	// 每次失败后等待时间翻倍
+	for i, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second} {
+		failures, lockedUntil := g.Fail("10.0.0.1", "alice", now)
+		assert.Equal(t, i+1, failures)
+		assert.True(t, lockedUntil.IsZero())
+		wait, locked = g.Check("10.0.0.2", "Alice", now)
+		assert.Equal(t, want, wait)
+		assert.False(t, locked)
+		now = now.Add(want)
+	}
+	wait, _ = g.Check("10.0.0.2", "alice", now)
+	assert.Zero(t, wait)
+
+	_, lockedUntil := g.Fail("10.0.0.1", "alice", now)
+	assert.Equal(t, now.Add(10*time.Minute), lockedUntil)
+	wait, locked = g.Check("10.0.0.3", "alice", now.Add(time.Minute))
+	assert.True(t, locked)
+	assert.Equal(t, 9*time.Minute, wait)
+
+	// 其他账号不受影响，锁定到期后重新计数
+	wait, _ = g.Check("10.0.0.3", "bob", now)
+	assert.Zero(t, wait)
+	wait, locked = g.Check("10.0.0.3", "alice", lockedUntil)
+	assert.Zero(t, wait)
+	assert.False(t, locked)
+
+	g.Lock("bob", now.Add(time.Hour))
+	_, locked = g.Check("10.0.0.3", "bob", now)
+	assert.True(t, locked)
+	g.Unlock("bob")
+	_, locked = g.Check("10.0.0.3", "bob", now)
+	assert.False(t, locked)
+}
+
+func TestLoginGuardIPLimit(t *testing.T) {
+	withLoginProtection(t, 100, 3, time.Nanosecond)
+	g := auth.NewLoginGuard()
+	now := time.Now()
+
+	// 同一来源轮换用户名也会被限制
+	for i := 0; i < 3; i++ {
+		g.Fail("10.0.0.9", fmt.Sprintf("user%d", i), now)
+	}
+	wait, locked := g.Check("10.0.0.9", "someone", now.Add(time.Minute))
+	assert.False(t, locked)
+	assert.Equal(t, 9*time.Minute, wait)
+	wait, _ = g.Check("10.0.0.10", "someone", now)
+	assert.Zero(t, wait)
+
+	// 登录成功不清除来源 IP 的失败次数
+	g.Succeed("user0")
+	wait, _ = g.Check("10.0.0.9", "user0", now)
+	assert.Positive(t, wait)
+	wait, _ = g.Check("10.0.0.9", "someone", now.Add(10*time.Minute))
+	assert.Zero(t, wait)
+}
+
+// loginFrom 以指定来源 IP 登录
+func loginFrom(r http.Handler, ip, username, password string) *httptest.ResponseRecorder {
+	data, _ := json.Marshal(map[string]string{"username": username, "password": password})
+	req := httptest.NewRequest(http.MethodPost, "/auth/login", bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = ip + ":40000"
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestLoginGuardAcquireReservesAttempts(t *testing.T) {
+	withLoginProtection(t, 3, 5, time.Second)
+	g := auth.NewLoginGuard()
+	now := time.Now()
+
+	// 尚无失败时可并发放行，进行中的尝试计入失败上限
+	for i := 0; i < 3; i++ {
+		wait, _ := g.Acquire("10.0.1.1", "bob", now)
+		assert.Zero(t, wait)
+	}
+	wait, locked := g.Acquire("10.0.1.1", "bob", now)
+	assert.False(t, locked)
+	assert.Positive(t, wait)
+
+	// 已有失败时同一时间只放行一次尝试
+	g.Fail("10.0.1.1", "bob", now)
+	g.Release("10.0.1.1", "bob")
+	g.Release("10.0.1.1", "bob")
+	wait, _ = g.Acquire("10.0.1.1", "bob", now.Add(time.Second))
+	assert.Positive(t, wait)
+	g.Release("10.0.1.1", "bob")
+	wait, _ = g.Acquire("10.0.1.1", "bob", now.Add(time.Second))
+	assert.Zero(t, wait)
+
+	// 来源 IP 的进行中尝试同样计入上限
+	for i := 0; i < 3; i++ {
+		wait, _ = g.Acquire("10.0.1.1", fmt.Sprintf("user%d", i), now)
+		assert.Zero(t, wait)
+	}
+	wait, _ = g.Acquire("10.0.1.1", "user3", now)
+	assert.Positive(t, wait)
+	wait, _ = g.Acquire("10.0.1.2", "user3", now)
+	assert.Zero(t, wait)
+}
+
+func TestLoginLockoutAndAdminUnlock(t *testing.T) {
+	target := setupMFATest(t, "victim", "user")
+	admin := &models.User{Username: "root", Password: target.Password, Role: "admin"}
+	assert.NoError(t, models.CreateUser(admin))
+	withLoginProtection(t, 3, 100, time.Nanosecond)
+
+	r := gin.New()
+	r.POST("/auth/login", api.HandleLogin)
+	r.POST("/api/v1/admin/users/:id/unlock", middleware.JWTAuthMiddleware(), api.HandleUnlockUser)
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusUnauthorized, loginFrom(r, "198.51.100.1", "victim", "bad-pass").Code)
+	}
+	// 锁定期间正确密码也被拒绝，且不再校验密码
+	w := loginFrom(r, "198.51.100.2", "victim", "s3cret-pass")
+	assert.Equal(t, http.StatusLocked, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+	locked, _ := models.GetUserByID(target.ID)
+	assert.Equal(t, 3, locked.FailedLogins)
+	assert.NotNil(t, locked.LockedUntil)
+	logs, _ := models.ListAuditLogs(models.AuditLoginFailed, 0)
+	assert.Len(t, logs, 3)
+	logs, _ = models.ListAuditLogs(models.AuditLoginLocked, 0)
+	if assert.Len(t, logs, 1) {
+		assert.Equal(t, fmt.Sprintf("user:%d", target.ID), logs[0].Resource)
+	}
+
+	// 不存在的用户表现一致，无法据此枚举用户
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusUnauthorized, loginFrom(r, "198.51.100.3", "ghost", "bad-pass").Code)
+	}
+	assert.Equal(t, http.StatusLocked, loginFrom(r, "198.51.100.3", "ghost", "bad-pass").Code)
+
+	token, err := auth.GenerateToken(admin.ID, admin.Username, admin.Role, time.Minute)
+	assert.NoError(t, err)
+	code, _ := callJSON(r, fmt.Sprintf("/api/v1/admin/users/%d/unlock", target.ID), token, nil)
+	assert.Equal(t, http.StatusOK, code)
+
+	w = loginFrom(r, "198.51.100.2", "victim", "s3cret-pass")
+	assert.Equal(t, http.StatusOK, w.Code)
+	unlocked, _ := models.GetUserByID(target.ID)
+	assert.Zero(t, unlocked.FailedLogins)
+	assert.Nil(t, unlocked.LockedUntil)
+}
+
+func TestLoginLockoutSurvivesRestart(t *testing.T) {
+	user := setupMFATest(t, "persisted", "user")
+	withLoginProtection(t, 3, 100, time.Nanosecond)
+	until := time.Now().Add(5 * time.Minute)
+	assert.NoError(t, models.RecordLoginFailure(user.ID, &until))
+
+	r := gin.New()
+	r.POST("/auth/login", api.HandleLogin)
+	assert.Equal(t, http.StatusLocked, loginFrom(r, "198.51.100.4", "persisted", "s3cret-pass").Code)
+}
+
+func TestLoginThrottledAfterFailure(t *testing.T) {
+	setupMFATest(t, "slowpoke", "user")
+	withLoginProtection(t, 5, 100, 4*time.Second)
+
+	r := gin.New()
+	r.POST("/auth/login", api.HandleLogin)
+	assert.Equal(t, http.StatusUnauthorized, loginFrom(r, "198.51.100.5", "slowpoke", "bad-pass").Code)
+	w := loginFrom(r, "198.51.100.5", "slowpoke", "s3cret-pass")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "4", w.Header().Get("Retry-After"))
+}
+
+func TestConcurrentLoginsCannotExceedFailureLimit(t *testing.T) {
+	user := setupMFATest(t, "burst", "user")
+	withLoginProtection(t, 3, 100, time.Nanosecond)
+
+	r := gin.New()
+	r.POST("/auth/login", api.HandleLogin)
+
+	// 并发猜测密码时，进入密码校验的次数不超过失败上限，其余请求被限流或锁定
+	const n = 20
+	codes := make([]int, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			codes[i] = loginFrom(r, fmt.Sprintf("198.51.100.%d", 100+i), "burst", "bad-pass").Code
+		}(i)
+	}
+	wg.Wait()
+
+	checked := 0
+	for _, code := range codes {
+		if code == http.StatusUnauthorized {
+			checked++
+		} else {
+			assert.Contains(t, []int{http.StatusTooManyRequests, http.StatusLocked}, code)
+		}
+	}
+	assert.LessOrEqual(t, checked, 3)
+	assert.Positive(t, checked)
+	logs, _ := models.ListAuditLogs(models.AuditLoginFailed, 0)
+	assert.Len(t, logs, checked)
+	stored, _ := models.GetUserByID(user.ID)
+	assert.LessOrEqual(t, stored.FailedLogins, 3)
+}
+
+func TestLoginIPLimitIgnoresSpoofedForwardedFor(t *testing.T) {
+	setupMFATest(t, "proxied", "user")
+	withLoginProtection(t, 100, 3, time.Nanosecond)
+	old := config.Global.TrustedProxies
+	t.Cleanup(func() { config.Global.TrustedProxies = old })
+
+	newRouter := func() *gin.Engine {
+		r := gin.New()
+		assert.NoError(t, router.SetTrustedProxies(r))
+		r.POST("/auth/login", api.HandleLogin)
+		return r
+	}
+	login := func(r http.Handler, remote, forwarded string) int {
+		data, _ := json.Marshal(map[string]string{"username": "proxied", "password": "bad-pass"})
+		req := httptest.NewRequest(http.MethodPost, "/auth/login", bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Forwarded-For", forwarded)
+		req.RemoteAddr = remote + ":40000"
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// 未配置可信代理时，伪造 X-Forwarded-For 不能绕过来源 IP 的失败次数限制
+	r := newRouter()
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusUnauthorized, login(r, "198.51.100.50", fmt.Sprintf("203.0.113.%d", i)))
+	}
+	assert.Equal(t, http.StatusTooManyRequests, login(r, "198.51.100.50", "203.0.113.99"))
+
+	// 来自可信代理的请求按转发的客户端地址计数
+	config.Global.TrustedProxies = []string{"198.51.100.60"}
+	r = newRouter()
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusUnauthorized, login(r, "198.51.100.60", "203.0.113.10"))
+	}
+	assert.Equal(t, http.StatusTooManyRequests, login(r, "198.51.100.60", "203.0.113.10"))
+	assert.Equal(t, http.StatusUnauthorized, login(r, "198.51.100.60", "203.0.113.11"))
+}
@@ -0,0 +1,221 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/base32"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"VulnFusion/internal/auth"
+	"VulnFusion/internal/config"
+	"VulnFusion/internal/db"
+	"VulnFusion/internal/models"
+	"VulnFusion/internal/utils"
+	"VulnFusion/web/api"
+	"VulnFusion/web/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTOTPRFC6238Vectors(t *testing.T) {
+	// RFC 6238 附录 B 的 SHA1 测试向量（取后 6 位）
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	cases := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for ts, want := range cases {
+		code, err := auth.TOTPCode(secret, time.Unix(ts, 0))
+		assert.NoError(t, err)
+		assert.Equal(t, want, code)
+	}
+
+	counter, ok := auth.ValidateTOTP(secret, "287082", time.Unix(59+30, 0))
+	assert.True(t, ok)
+	assert.Equal(t, int64(1), counter)
+	_, ok = auth.ValidateTOTP(secret, "287082", time.Unix(59+90, 0))
+	assert.False(t, ok)
+
+	uri := auth.TOTPProvisioningURI("VulnFusion", "alice", "ABC")
+	assert.Equal(t, "otpauth://totp/VulnFusion:alice?algorithm=SHA1&digits=6&issuer=VulnFusion&period=30&secret=ABC", uri)
+}
+
+// mfaRouter 构建登录与二次验证相关路由
+func mfaRouter() *gin.Engine {
+	r := gin.New()
+	r.POST("/auth/login", api.HandleLogin)
+	r.POST("/auth/login/mfa", api.HandleLoginMFA)
+	r.POST("/auth/login/mfa/setup", api.HandleLoginMFASetup)
+	group := r.Group("/api/v1", middleware.JWTAuthMiddleware())
+	group.POST("/auth/mfa/setup", api.HandleSetupMFA)
+	group.POST("/auth/mfa/enable", api.HandleEnableMFA)
+	return r
+}
+
+// callJSON 发送 JSON 请求，token 非空时附带访问令牌
+func callJSON(r http.Handler, path, token string, body interface{}) (int, map[string]interface{}) {
+	data, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var resp map[string]interface{}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	return w.Code, resp
+}
+
+func setupMFATest(t *testing.T, username, role string) *models.User {
+	gin.SetMode(gin.TestMode)
+	_ = os.RemoveAll("./testdata")
+	_, err := db.InitDatabase("./testdata/test.db")
+	assert.NoError(t, err)
+	auth.SetRevocationStore(models.RevocationStore{})
+
+	hashed, _ := utils.HashPassword("s3cret-pass")
+	user := &models.User{Username: username, Password: hashed, Role: role}
+	assert.NoError(t, models.CreateUser(user))
+	return user
+}
+
+func TestMFAEnrolmentAndTwoStepLogin(t *testing.T) {
+	setupMFATest(t, "alice", "user")
+	withLoginProtection(t, 100, 100, time.Nanosecond)
+	r := mfaRouter()
+	creds := map[string]string{"username": "alice", "password": "s3cret-pass"}
+
+	// 未启用时直接登录
+	code, resp := callJSON(r, "/auth/login", "", creds)
+	assert.Equal(t, http.StatusOK, code)
+	access := resp["token"].(string)
+
+	code, resp = callJSON(r, "/api/v1/auth/mfa/setup", access, nil)
+	assert.Equal(t, http.StatusOK, code)
+	secret := resp["secret"].(string)
+	assert.Contains(t, resp["uri"], "otpauth://totp/")
+
+	code, _ = callJSON(r, "/api/v1/auth/mfa/enable", access, map[string]string{"code": "000000"})
+	assert.Equal(t, http.StatusBadRequest, code)
+	now, _ := auth.TOTPCode(secret, time.Now())
+	code, resp = callJSON(r, "/api/v1/auth/mfa/enable", access, map[string]string{"code": now})
+	assert.Equal(t, http.StatusOK, code)
+	recovery := resp["recovery_codes"].([]interface{})
+	assert.Len(t, recovery, 10)
+
+	// 启用后登录只返回挑战令牌，挑战令牌不能当作访问令牌使用
+	code, resp = callJSON(r, "/auth/login", "", creds)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, true, resp["mfa_required"])
+	assert.Nil(t, resp["token"])
+	challenge := resp["mfa_token"].(string)
+	code, _ = callJSON(r, "/api/v1/auth/mfa/setup", challenge, nil)
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	// 已使用过的验证码不能重放，下一时间步的验证码可用
+	code, _ = callJSON(r, "/auth/login/mfa", "", map[string]string{"mfa_token": challenge, "code": now})
+	assert.Equal(t, http.StatusUnauthorized, code)
+	next, _ := auth.TOTPCode(secret, time.Now().Add(30*time.Second))
+	code, resp = callJSON(r, "/auth/login/mfa", "", map[string]string{"mfa_token": challenge, "code": next})
+	assert.Equal(t, http.StatusOK, code)
+	assert.NotEmpty(t, resp["token"])
+	assert.NotEmpty(t, resp["refresh_token"])
+
+	// 挑战令牌只能使用一次
+	code, _ = callJSON(r, "/auth/login/mfa", "", map[string]string{"mfa_token": challenge, "code": next})
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	// 恢复码可登录一次
+	_, resp = callJSON(r, "/auth/login", "", creds)
+	challenge = resp["mfa_token"].(string)
+	code, _ = callJSON(r, "/auth/login/mfa", "", map[string]string{"mfa_token": challenge, "code": recovery[0].(string)})
+	assert.Equal(t, http.StatusOK, code)
+	_, resp = callJSON(r, "/auth/login", "", creds)
+	challenge = resp["mfa_token"].(string)
+	code, _ = callJSON(r, "/auth/login/mfa", "", map[string]string{"mfa_token": challenge, "code": recovery[0].(string)})
+	assert.Equal(t, http.StatusUnauthorized, code)
+}
+
+func TestMFAChallengeAttemptLimit(t *testing.T) {
+	user := setupMFATest(t, "bob", "user")
+	withLoginProtection(t, 100, 100, time.Nanosecond)
+	r := mfaRouter()
+	secret, _ := auth.GenerateTOTPSecret()
+	assert.NoError(t, models.SetPendingTOTPSecret(user.ID, secret))
+	assert.NoError(t, models.EnableTOTP(user.ID, 0, nil))
+
+	_, resp := callJSON(r, "/auth/login", "", map[string]string{"username": "bob", "password": "s3cret-pass"})
+	challenge := resp["mfa_token"].(string)
+	for i := 0; i < 5; i++ {
+		code, _ := callJSON(r, "/auth/login/mfa", "", map[string]string{"mfa_token": challenge, "code": "abcdef"})
+		assert.Equal(t, http.StatusUnauthorized, code)
+	}
+	valid, _ := auth.TOTPCode(secret, time.Now())
+	code, _ := callJSON(r, "/auth/login/mfa", "", map[string]string{"mfa_token": challenge, "code": valid})
+	assert.Equal(t, http.StatusUnauthorized, code)
+}
+
+func TestMFAFailuresCountTowardsLockout(t *testing.T) {
+	user := setupMFATest(t, "mallory", "user")
+	withLoginProtection(t, 3, 100, time.Nanosecond)
+	r := mfaRouter()
+	secret, _ := auth.GenerateTOTPSecret()
+	assert.NoError(t, models.SetPendingTOTPSecret(user.ID, secret))
+	assert.NoError(t, models.EnableTOTP(user.ID, 0, nil))
+	creds := map[string]string{"username": "mallory", "password": "s3cret-pass"}
+
+	// 密码正确但尚未通过二次验证时不清除此前的失败记录
+	assert.Equal(t, http.StatusUnauthorized, loginFrom(r, "198.51.100.10", "mallory", "bad-pass").Code)
+	_, resp := callJSON(r, "/auth/login", "", creds)
+	challenge := resp["mfa_token"].(string)
+
+	// 验证码错误同样计入失败次数，达到上限后账号被锁定
+	for i := 0; i < 2; i++ {
+		code, _ := callJSON(r, "/auth/login/mfa", "", map[string]string{"mfa_token": challenge, "code": "000000"})
+		assert.Equal(t, http.StatusUnauthorized, code)
+	}
+	valid, _ := auth.TOTPCode(secret, time.Now())
+	code, _ := callJSON(r, "/auth/login/mfa", "", map[string]string{"mfa_token": challenge, "code": valid})
+	assert.Equal(t, http.StatusLocked, code)
+	code, _ = callJSON(r, "/auth/login", "", creds)
+	assert.Equal(t, http.StatusLocked, code)
+
+	locked, _ := models.GetUserByID(user.ID)
+	assert.Equal(t, 3, locked.FailedLogins)
+	assert.NotNil(t, locked.LockedUntil)
+	logs, _ := models.ListAuditLogs(models.AuditLoginLocked, 0)
+	assert.Len(t, logs, 1)
+}
+
+func TestMFAEnforcedEnrolmentAtLogin(t *testing.T) {
+	setupMFATest(t, "root", "admin")
+	config.Global.MFA.EnforceRoles = []string{"admin"}
+	defer func() { config.Global.MFA.EnforceRoles = nil }()
+	r := mfaRouter()
+
+	code, resp := callJSON(r, "/auth/login", "", map[string]string{"username": "root", "password": "s3cret-pass"})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, true, resp["enrollment_required"])
+	challenge := resp["mfa_token"].(string)
+
+	code, resp = callJSON(r, "/auth/login/mfa/setup", "", map[string]string{"mfa_token": challenge})
+	assert.Equal(t, http.StatusOK, code)
+	secret := resp["secret"].(string)
+
+	totp, _ := auth.TOTPCode(secret, time.Now())
+	code, resp = callJSON(r, "/auth/login/mfa", "", map[string]string{"mfa_token": challenge, "code": totp})
+	assert.Equal(t, http.StatusOK, code)
+	assert.NotEmpty(t, resp["token"])
+	assert.Len(t, resp["recovery_codes"], 10)
+
+	user, _ := models.GetUserByUsername("root")
+	assert.True(t, user.TOTPEnabled)
+}
@@ -0,0 +1,279 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"VulnFusion/internal/auth"
+	"VulnFusion/internal/config"
+	"VulnFusion/internal/db"
+	"VulnFusion/internal/models"
+	"VulnFusion/web/api"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockIssuer 最小化的 OIDC 身份提供方：发现文档、JWKS 与校验 PKCE 的令牌端点
+type mockIssuer struct {
+	srv    *httptest.Server
+	key    *rsa.PrivateKey
+	mu     sync.Mutex
+	codes  map[string]mockGrant
+	claims jwt.MapClaims // 下一次签发的 id_token 附加声明
+}
+
+type mockGrant struct {
+	nonce     string
+	challenge string
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	m := &mockIssuer{key: key, codes: map[string]mockGrant{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 m.srv.URL,
+			"authorization_endpoint": m.srv.URL + "/authorize",
+			"token_endpoint":         m.srv.URL + "/token",
+			"jwks_uri":               m.srv.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "idp-1",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		m.mu.Lock()
+		grant, ok := m.codes[r.PostForm.Get("code")]
+		delete(m.codes, r.PostForm.Get("code"))
+		extra := m.claims
+		m.mu.Unlock()
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != grant.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		claims := jwt.MapClaims{
+			"iss":   m.srv.URL,
+			"aud":   "vulnfusion",
+			"exp":   time.Now().Add(time.Minute).Unix(),
+			"iat":   time.Now().Unix(),
+			"nonce": grant.nonce,
+		}
+		for k, v := range extra {
+			claims[k] = v
+		}
+		tok := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		tok.Header["kid"] = "idp-1"
+		signed, _ := tok.SignedString(key)
+		_ = json.NewEncoder(w).Encode(map[string]string{"id_token": signed, "token_type": "Bearer"})
+	})
+	m.srv = httptest.NewServer(mux)
+	t.Cleanup(m.srv.Close)
+	return m
+}
+
+// authorize 模拟用户在身份提供方登录成功，返回回调地址的查询参数
+func (m *mockIssuer) authorize(t *testing.T, authURL string) url.Values {
+	u, err := url.Parse(authURL)
+	assert.NoError(t, err)
+	q := u.Query()
+	assert.Equal(t, "S256", q.Get("code_challenge_method"))
+	m.mu.Lock()
+	m.codes["code-"+q.Get("state")] = mockGrant{nonce: q.Get("nonce"), challenge: q.Get("code_challenge")}
+	m.mu.Unlock()
+	return url.Values{"code": {"code-" + q.Get("state")}, "state": {q.Get("state")}}
+}
+
+func oidcRouter() *gin.Engine {
+	r := gin.New()
+	r.GET("/api/v1/auth/oidc/providers", api.HandleListOIDCProviders)
+	r.GET("/api/v1/auth/oidc/:provider/login", api.HandleOIDCLogin)
+	r.GET("/api/v1/auth/oidc/:provider/callback", api.HandleOIDCCallback)
+	r.POST("/api/v1/auth/oidc/exchange", api.HandleOIDCExchange)
+	return r
+}
+
+// ssoLogin 走完发起登录与回调，返回回调跳转地址的片段参数
+func ssoLogin(t *testing.T, r http.Handler, m *mockIssuer, tamper func(url.Values)) url.Values {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/auth/oidc/corp/login", nil))
+	assert.Equal(t, http.StatusFound, w.Code)
+	cookies := w.Result().Cookies()
+	assert.Len(t, cookies, 1)
+	assert.True(t, cookies[0].HttpOnly)
+
+	params := m.authorize(t, w.Header().Get("Location"))
+	if tamper != nil {
+		tamper(params)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/auth/oidc/corp/callback?"+params.Encode(), nil)
+	req.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusFound, w.Code)
+
+	loc := w.Header().Get("Location")
+	assert.True(t, strings.HasPrefix(loc, "/login#"), loc)
+	fragment, _ := url.ParseQuery(loc[strings.Index(loc, "#")+1:])
+	return fragment
+}
+
+func setupOIDCTest(t *testing.T, m *mockIssuer) {
+	setupMFATest(t, "local", "user")
+	old := config.Global.OIDC
+	t.Cleanup(func() { config.Global.OIDC = old })
+	config.Global.OIDC.RedirectBaseURL = "http://vulnfusion.test"
+	config.Global.OIDC.Providers = []config.OIDCProvider{{
+		Name:        "corp",
+		DisplayName: "企业账号",
+		Issuer:      m.srv.URL,
+		ClientID:    "vulnfusion",
+		RoleClaim:   "groups",
+		RoleMapping: map[string]string{"sec-admins": "admin"},
+		LinkByEmail: true,
+	}}
+}
+
+func TestOIDCLoginProvisionsUserAndSyncsRole(t *testing.T) {
+	m := newMockIssuer(t)
+	setupOIDCTest(t, m)
+	r := oidcRouter()
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/auth/oidc/providers", nil))
+	assert.JSONEq(t, `[{"name":"corp","display_name":"企业账号"}]`, w.Body.String())
+
+	m.claims = jwt.MapClaims{"sub": "u-1", "preferred_username": "bob", "groups": []string{"sec-admins"}}
+	fragment := ssoLogin(t, r, m, nil)
+	ticket := fragment.Get("sso_ticket")
+	assert.NotEmpty(t, ticket, fragment.Get("sso_error"))
+
+	code, resp := callJSON(r, "/api/v1/auth/oidc/exchange", "", map[string]string{"ticket": ticket})
+	assert.Equal(t, http.StatusOK, code)
+	assert.NotEmpty(t, resp["token"])
+	assert.NotEmpty(t, resp["refresh_token"])
+	// 票据只能使用一次
+	code, _ = callJSON(r, "/api/v1/auth/oidc/exchange", "", map[string]string{"ticket": ticket})
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	user, err := models.GetUserByExternalID("corp", "u-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "bob", user.Username)
+	assert.Equal(t, "admin", user.Role)
+
+	// 移出管理员组后再次登录，角色随之同步
+	m.claims = jwt.MapClaims{"sub": "u-1", "preferred_username": "bob", "groups": []string{"staff"}}
+	assert.NotEmpty(t, ssoLogin(t, r, m, nil).Get("sso_ticket"))
+	user, _ = models.GetUserByExternalID("corp", "u-1")
+	assert.Equal(t, "user", user.Role)
+}
+
+func TestOIDCLinksLocalUserByVerifiedEmail(t *testing.T) {
+	m := newMockIssuer(t)
+	setupOIDCTest(t, m)
+	local, _ := models.GetUserByUsername("local")
+	assert.NoError(t, models.UpdateUserByID(local.ID, map[string]interface{}{"email": "local@example.com"}))
+	r := oidcRouter()
+
+	// 邮箱未验证时不关联，且用户名与本地账号冲突时拒绝创建
+	m.claims = jwt.MapClaims{"sub": "u-2", "preferred_username": "local", "email": "local@example.com", "email_verified": false}
+	assert.NotEmpty(t, ssoLogin(t, r, m, nil).Get("sso_error"))
+
+	m.claims["email_verified"] = true
+	assert.NotEmpty(t, ssoLogin(t, r, m, nil).Get("sso_ticket"))
+	linked, err := models.GetUserByExternalID("corp", "u-2")
+	assert.NoError(t, err)
+	assert.Equal(t, local.ID, linked.ID)
+}
+
+func TestOIDCRejectsBadStateAndNonce(t *testing.T) {
+	m := newMockIssuer(t)
+	setupOIDCTest(t, m)
+	r := oidcRouter()
+	m.claims = jwt.MapClaims{"sub": "u-3", "preferred_username": "carol"}
+
+	fragment := ssoLogin(t, r, m, func(v url.Values) { v.Set("state", "forged") })
+	assert.Empty(t, fragment.Get("sso_ticket"))
+	assert.NotEmpty(t, fragment.Get("sso_error"))
+
+	// 身份提供方返回的 nonce 与登录时不一致
+	m.claims["nonce"] = "replayed"
+	fragment = ssoLogin(t, r, m, nil)
+	assert.Empty(t, fragment.Get("sso_ticket"))
+
+	_, err := models.GetUserByExternalID("corp", "u-3")
+	assert.Error(t, err)
+}
+
+func TestOIDCLoginRequiresLocalMFA(t *testing.T) {
+	m := newMockIssuer(t)
+	setupOIDCTest(t, m)
+	local, _ := models.GetUserByUsername("local")
+	secret, _ := auth.GenerateTOTPSecret()
+	assert.NoError(t, models.SetPendingTOTPSecret(local.ID, secret))
+	assert.NoError(t, models.EnableTOTP(local.ID, 0, nil))
+	assert.NoError(t, models.UpdateUserByID(local.ID, map[string]interface{}{"email": "local@example.com"}))
+	r := oidcRouter()
+	r.POST("/auth/login/mfa", api.HandleLoginMFA)
+
+	// 按邮箱关联到已启用二次验证的账号后，票据只能换取挑战令牌
+	m.claims = jwt.MapClaims{"sub": "u-4", "preferred_username": "local", "email": "local@example.com", "email_verified": true}
+	ticket := ssoLogin(t, r, m, nil).Get("sso_ticket")
+	code, resp := callJSON(r, "/api/v1/auth/oidc/exchange", "", map[string]string{"ticket": ticket})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, true, resp["mfa_required"])
+	assert.Nil(t, resp["token"])
+
+	valid, _ := auth.TOTPCode(secret, time.Now())
+	code, resp = callJSON(r, "/auth/login/mfa", "", map[string]string{"mfa_token": resp["mfa_token"].(string), "code": valid})
+	assert.Equal(t, http.StatusOK, code)
+	assert.NotEmpty(t, resp["token"])
+
+	// 身份提供方配置为已执行多因素认证时直接签发令牌
+	config.Global.OIDC.Providers[0].TrustMFA = true
+	ticket = ssoLogin(t, r, m, nil).Get("sso_ticket")
+	code, resp = callJSON(r, "/api/v1/auth/oidc/exchange", "", map[string]string{"ticket": ticket})
+	assert.Equal(t, http.StatusOK, code)
+	assert.NotEmpty(t, resp["token"])
+}
+
+func TestOIDCCallbackHidesInternalErrors(t *testing.T) {
+	m := newMockIssuer(t)
+	setupOIDCTest(t, m)
+	r := oidcRouter()
+
+	// 用户名冲突等可处理的原因原样提示
+	m.claims = jwt.MapClaims{"sub": "u-5", "preferred_username": "local"}
+	assert.Equal(t, "用户名已被本地账号占用，请联系管理员关联", ssoLogin(t, r, m, nil).Get("sso_error"))
+
+	// 数据库等内部错误只返回通用提示
+	sqlDB, err := db.GetDB().DB()
+	assert.NoError(t, err)
+	assert.NoError(t, sqlDB.Close())
+	m.claims = jwt.MapClaims{"sub": "u-6", "preferred_username": "dave"}
+	assert.Equal(t, "单点登录失败，请联系管理员", ssoLogin(t, r, m, nil).Get("sso_error"))
+}
@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"VulnFusion/internal/auth"
+	"VulnFusion/internal/db"
+	"VulnFusion/internal/models"
+	"VulnFusion/internal/utils"
+	"VulnFusion/web/api"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func postJSON(r http.Handler, path string, body interface{}) (int, map[string]string) {
+	data, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var resp map[string]string
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	return w.Code, resp
+}
+
+func TestRefreshRotationAndReuseDetection(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	_ = os.RemoveAll("./testdata")
+	_, err := db.InitDatabase("./testdata/test.db")
+	assert.NoError(t, err)
+	auth.SetRevocationStore(models.RevocationStore{})
+
+	hashed, _ := utils.HashPassword("s3cret-pass")
+	assert.NoError(t, models.CreateUser(&models.User{Username: "carol", Password: hashed, Role: "user"}))
+
+	r := gin.New()
+	r.POST("/auth/login", api.HandleLogin)
+	r.POST("/auth/refresh", api.HandleRefreshToken)
+
+	code, login := postJSON(r, "/auth/login", map[string]string{"username": "carol", "password": "s3cret-pass"})
+	assert.Equal(t, http.StatusOK, code)
+
+	// 访问令牌不能用于刷新
+	code, _ = postJSON(r, "/auth/refresh", map[string]string{"refresh_token": login["token"]})
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	// 每次刷新都轮换出新的刷新令牌，且属于同一令牌族
+	code, first := postJSON(r, "/auth/refresh", map[string]string{"refresh_token": login["refresh_token"]})
+	assert.Equal(t, http.StatusOK, code)
+	assert.NotEqual(t, login["refresh_token"], first["refresh_token"])
+	accessClaims, err := auth.ParseToken(first["token"])
+	assert.NoError(t, err)
+	assert.Equal(t, "carol", accessClaims.Username)
+	loginClaims, _ := auth.ParseToken(login["token"])
+	assert.Equal(t, loginClaims.Family, accessClaims.Family)
+
+	code, second := postJSON(r, "/auth/refresh", map[string]string{"refresh_token": first["refresh_token"]})
+	assert.Equal(t, http.StatusOK, code)
+
+	// 重放已轮换的刷新令牌：整个令牌族被吊销
+	code, _ = postJSON(r, "/auth/refresh", map[string]string{"refresh_token": login["refresh_token"]})
+	assert.Equal(t, http.StatusUnauthorized, code)
+	code, _ = postJSON(r, "/auth/refresh", map[string]string{"refresh_token": second["refresh_token"]})
+	assert.Equal(t, http.StatusUnauthorized, code)
+	_, err = auth.ParseToken(second["token"])
+	assert.Error(t, err)
+
+	logs, err := models.ListAuditLogs(models.AuditRefreshReuse, 10)
+	assert.NoError(t, err)
+	assert.Len(t, logs, 1)
+}
@@ -0,0 +1,211 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"VulnFusion/internal/auth"
+	"VulnFusion/internal/config"
+	"VulnFusion/internal/models"
+	"VulnFusion/internal/utils"
+	"VulnFusion/web/api"
+	"VulnFusion/web/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupRegisterTest(t *testing.T, mode string) (*gin.Engine, string) {
+	admin := setupMFATest(t, "root", "admin")
+	old := config.Global.Auth
+	t.Cleanup(func() { config.Global.Auth = old })
+	config.Global.Auth.Backend = "local"
+	config.Global.Auth.Registration.Mode = mode
+
+	r := gin.New()
+	r.POST("/auth/register", api.HandleRegister)
+	r.POST("/auth/login", api.HandleLogin)
+	group := r.Group("/api/v1/admin", middleware.JWTAuthMiddleware())
+	group.GET("/users", api.HandleListAllUsers)
+	group.POST("/users", api.HandleCreateUser)
+	group.POST("/users/:id/approve", api.HandleApproveUser)
+	group.POST("/users/:id", api.HandleUpdateUserByID)
+	group.POST("/users/:id/password", api.HandleResetPasswordByID)
+	group.POST("/invites", api.HandleCreateInvite)
+
+	token, err := auth.GenerateToken(admin.ID, admin.Username, admin.Role, time.Minute)
+	assert.NoError(t, err)
+	return r, token
+}
+
+func TestRegistrationDisabledByDefault(t *testing.T) {
+	r, _ := setupRegisterTest(t, "")
+	code, _ := callJSON(r, "/auth/register", "", map[string]string{"username": "eve", "password": "Passw0rd123"})
+	assert.Equal(t, http.StatusForbidden, code)
+	_, err := models.GetUserByUsername("eve")
+	assert.Error(t, err)
+}
+
+func TestRegistrationWithApproval(t *testing.T) {
+	r, adminToken := setupRegisterTest(t, "approval")
+
+	code, _ := callJSON(r, "/auth/register", "", map[string]string{"username": "eve", "password": "short"})
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// 请求中的角色被忽略，注册用户始终为最低权限角色
+	code, resp := callJSON(r, "/auth/register", "", map[string]string{"username": "eve", "password": "Passw0rd123", "role": "admin"})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, true, resp["pending"])
+	user, err := models.GetUserByUsername("eve")
+	assert.NoError(t, err)
+	assert.Equal(t, "viewer", user.Role)
+	assert.Equal(t, models.UserStatusPending, user.Status)
+
+	creds := map[string]string{"username": "eve", "password": "Passw0rd123"}
+	code, resp = callJSON(r, "/auth/login", "", creds)
+	assert.Equal(t, http.StatusForbidden, code)
+	assert.Empty(t, resp["token"])
+
+	path := fmt.Sprintf("/api/v1/admin/users/%d/approve", user.ID)
+	code, _ = callJSON(r, path, adminToken, map[string]string{"role": "user"})
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = callJSON(r, path, adminToken, nil)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, resp = callJSON(r, "/auth/login", "", creds)
+	assert.Equal(t, http.StatusOK, code)
+	assert.NotEmpty(t, resp["token"])
+	approved, _ := models.GetUserByUsername("eve")
+	assert.Equal(t, "user", approved.Role)
+}
+
+func TestRegistrationWithInvite(t *testing.T) {
+	r, adminToken := setupRegisterTest(t, "invite")
+
+	code, _ := callJSON(r, "/auth/register", "", map[string]string{"username": "eve", "password": "Passw0rd123"})
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, resp := callJSON(r, "/api/v1/admin/invites", adminToken, map[string]interface{}{"note": "eve", "expires_in_hours": 1})
+	assert.Equal(t, http.StatusOK, code)
+	invite := resp["code"].(string)
+
+	code, resp = callJSON(r, "/auth/register", "", map[string]string{"username": "eve", "password": "Passw0rd123", "invite_code": invite})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, false, resp["pending"])
+	user, err := models.GetUserByUsername("eve")
+	assert.NoError(t, err)
+	assert.Equal(t, "viewer", user.Role)
+	assert.Empty(t, user.Status)
+
+	// 邀请码只能使用一次
+	code, _ = callJSON(r, "/auth/register", "", map[string]string{"username": "mallory", "password": "Passw0rd123", "invite_code": invite})
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// 过期的邀请码不可用
+	expired := "expired-invite"
+	assert.NoError(t, models.CreateInvite(&models.Invite{CodeHash: utils.SHA256Hex(expired), ExpiresAt: time.Now().Add(-time.Minute)}))
+	code, _ = callJSON(r, "/auth/register", "", map[string]string{"username": "mallory", "password": "Passw0rd123", "invite_code": expired})
+	assert.Equal(t, http.StatusBadRequest, code)
+	_, err = models.GetUserByUsername("mallory")
+	assert.Error(t, err)
+
+	// 用户名冲突时邀请码不会被消耗
+	code, resp = callJSON(r, "/api/v1/admin/invites", adminToken, nil)
+	assert.Equal(t, http.StatusOK, code)
+	second := resp["code"].(string)
+	code, _ = callJSON(r, "/auth/register", "", map[string]string{"username": "eve", "password": "Passw0rd123", "invite_code": second})
+	assert.Equal(t, http.StatusInternalServerError, code)
+	code, _ = callJSON(r, "/auth/register", "", map[string]string{"username": "trent", "password": "Passw0rd123", "invite_code": second})
+	assert.Equal(t, http.StatusOK, code)
+}
+
+func TestAdminPasswordPolicy(t *testing.T) {
+	r, adminToken := setupRegisterTest(t, "")
+
+	code, _ := callJSON(r, "/api/v1/admin/users", adminToken, map[string]string{"username": "bob", "password": "12345678", "role": "user"})
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = callJSON(r, "/api/v1/admin/users", adminToken, map[string]string{"username": "bob", "password": "Passw0rd123", "role": "ghost"})
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, resp := callJSON(r, "/api/v1/admin/users", adminToken, map[string]string{"username": "bob", "password": "Passw0rd123", "role": "auditor"})
+	assert.Equal(t, http.StatusOK, code)
+	// 响应不包含密码哈希
+	assert.Equal(t, "bob", resp["Username"])
+	assert.NotContains(t, resp, "Password")
+	assert.NotContains(t, resp, "password")
+
+	path := fmt.Sprintf("/api/v1/admin/users/%v/password", resp["ID"])
+	code, _ = callJSON(r, path, adminToken, map[string]string{"password": "password"})
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = callJSON(r, path, adminToken, map[string]string{"password": "N3w-password"})
+	assert.Equal(t, http.StatusOK, code)
+}
+
+func TestRoleAssignmentLimitedToOwnPermissions(t *testing.T) {
+	r, adminToken := setupRegisterTest(t, "")
+	old := config.Global.RBAC.Roles
+	t.Cleanup(func() { config.Global.RBAC.Roles = old })
+	config.Global.RBAC.Roles = map[string][]string{"helpdesk": {"user.read", "user.manage"}}
+
+	helpdesk := &models.User{Username: "helpdesk", Password: "x", Role: "helpdesk"}
+	assert.NoError(t, models.CreateUser(helpdesk))
+	token, err := auth.GenerateToken(helpdesk.ID, helpdesk.Username, helpdesk.Role, time.Minute)
+	assert.NoError(t, err)
+
+	// 只能授予自身权限范围内的角色
+	code, _ := callJSON(r, "/api/v1/admin/users", token, map[string]string{"username": "eve", "password": "Passw0rd123", "role": "admin"})
+	assert.Equal(t, http.StatusForbidden, code)
+	code, resp := callJSON(r, "/api/v1/admin/users", token, map[string]string{"username": "eve", "password": "Passw0rd123", "role": "helpdesk"})
+	assert.Equal(t, http.StatusOK, code)
+	userPath := fmt.Sprintf("/api/v1/admin/users/%v", resp["ID"])
+
+	code, _ = callJSON(r, userPath, token, map[string]string{"role": "admin"})
+	assert.Equal(t, http.StatusForbidden, code)
+	pending := &models.User{Username: "frank", Password: "x", Role: "viewer", Status: models.UserStatusPending}
+	assert.NoError(t, models.CreateUser(pending))
+	code, _ = callJSON(r, fmt.Sprintf("/api/v1/admin/users/%d/approve", pending.ID), token, map[string]string{"role": "admin"})
+	assert.Equal(t, http.StatusForbidden, code)
+
+	eve, _ := models.GetUserByUsername("eve")
+	assert.Equal(t, "helpdesk", eve.Role)
+
+	// 不能重置、降级或删除权限超出自身的用户
+	admin, _ := models.GetUserByUsername("root")
+	adminPath := fmt.Sprintf("/api/v1/admin/users/%d", admin.ID)
+	code, _ = callJSON(r, adminPath+"/password", token, map[string]string{"password": "N3w-password"})
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _ = callJSON(r, adminPath, token, map[string]string{"role": "helpdesk"})
+	assert.Equal(t, http.StatusForbidden, code)
+	root, _ := models.GetUserByID(admin.ID)
+	assert.Equal(t, admin.Password, root.Password)
+	assert.Equal(t, "admin", root.Role)
+	code, _ = callJSON(r, userPath+"/password", token, map[string]string{"password": "N3w-password"})
+	assert.Equal(t, http.StatusOK, code)
+
+	// 角色变更写入审计日志
+	code, _ = callJSON(r, userPath, adminToken, map[string]string{"role": "auditor"})
+	assert.Equal(t, http.StatusOK, code)
+	logs, _ := models.ListAuditLogs(models.AuditUserRole, 0)
+	if assert.Len(t, logs, 1) {
+		assert.Equal(t, "eve 角色由 helpdesk 变更为 auditor", logs[0].Detail)
+	}
+}
+
+func TestListUsersHidesPasswordHash(t *testing.T) {
+	r, _ := setupRegisterTest(t, "")
+	auditor := &models.User{Username: "auditor", Password: "x", Role: "auditor"}
+	assert.NoError(t, models.CreateUser(auditor))
+	token, err := auth.GenerateToken(auditor.ID, auditor.Username, auditor.Role, time.Minute)
+	assert.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/users", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"Username":"root"`)
+	assert.NotContains(t, w.Body.String(), "Password")
+	assert.NotContains(t, w.Body.String(), "$2a$")
+}
@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"VulnFusion/internal/auth"
+	"VulnFusion/internal/db"
+	"VulnFusion/internal/models"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRevocationPersistsAcrossRestart(t *testing.T) {
+	_ = os.RemoveAll("./testdata")
+	_, err := db.InitDatabase("./testdata/test.db")
+	assert.NoError(t, err)
+	auth.SetRevocationStore(models.RevocationStore{})
+
+	token, err := auth.GenerateToken(1, "alice", "user", time.Minute)
+	assert.NoError(t, err)
+	claims, err := auth.ParseToken(token)
+	assert.NoError(t, err)
+	assert.NoError(t, auth.AddTokenToBlacklist(claims.ID, time.Minute))
+
+	// 重新设置存储会清空缓存，相当于进程重启
+	auth.SetRevocationStore(models.RevocationStore{})
+	assert.True(t, auth.IsTokenBlacklisted(claims.ID))
+	_, err = auth.ParseToken(token)
+	assert.Error(t, err)
+}
+
+func TestRevokeFamilyAndUser(t *testing.T) {
+	_ = os.RemoveAll("./testdata")
+	_, err := db.InitDatabase("./testdata/test.db")
+	assert.NoError(t, err)
+	auth.SetRevocationStore(models.RevocationStore{})
+
+	// 令牌族：访问令牌与刷新令牌一起失效，其他登录不受影响
+	family := auth.NewFamilyID()
+	access, _ := auth.GenerateFamilyToken(2, "bob", "user", family, time.Minute)
+	refresh, _, _ := auth.GenerateFamilyRefreshToken(2, family, time.Hour)
+	other, _ := auth.GenerateFamilyToken(2, "bob", "user", auth.NewFamilyID(), time.Minute)
+
+	_, err = auth.ParseRefreshToken(refresh)
+	assert.NoError(t, err)
+
+	assert.NoError(t, auth.RevokeTokenFamily(family))
+	_, err = auth.ParseToken(access)
+	assert.Error(t, err)
+	_, err = auth.ParseRefreshToken(refresh)
+	assert.Error(t, err)
+	_, err = auth.ParseToken(other)
+	assert.NoError(t, err)
+
+	// 按用户吊销：之前签发的令牌（含刷新令牌）失效，之后签发的不受影响
+	userRefresh, _ := auth.GenerateRefreshToken(3, time.Hour)
+	assert.NoError(t, auth.RevokeUserTokens(2))
+	assert.NoError(t, auth.RevokeUserTokens(3))
+	_, err = auth.ParseToken(other)
+	assert.Error(t, err)
+	_, err = auth.ParseRefreshToken(userRefresh)
+	assert.Error(t, err)
+
+	later := &auth.CustomClaims{UserID: 2, RegisteredClaims: jwt.RegisteredClaims{ID: "later", IssuedAt: jwt.NewNumericDate(time.Now().Add(2 * time.Second))}}
+	revoked, err := auth.IsRevoked(later)
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+}
+
+func TestReloginAfterRevokeUserInSameSecond(t *testing.T) {
+	_ = os.RemoveAll("./testdata")
+	_, err := db.InitDatabase("./testdata/test.db")
+	assert.NoError(t, err)
+	auth.SetRevocationStore(models.RevocationStore{})
+
+	before, _ := auth.GenerateToken(4, "carol", "user", time.Minute)
+	time.Sleep(time.Millisecond)
+	assert.NoError(t, auth.RevokeUserTokens(4))
+	time.Sleep(time.Millisecond)
+	// 退出所有设备后立即重新登录，新令牌与吊销记录通常位于同一秒内
+	after, _ := auth.GenerateToken(4, "carol", "user", time.Minute)
+
+	// 重新设置存储，吊销时间从数据库读取
+	auth.SetRevocationStore(models.RevocationStore{})
+	_, err = auth.ParseToken(before)
+	assert.Error(t, err)
+	_, err = auth.ParseToken(after)
+	assert.NoError(t, err)
+}
+
+func TestSweepExpiredRevocations(t *testing.T) {
+	_ = os.RemoveAll("./testdata")
+	_, err := db.InitDatabase("./testdata/test.db")
+	assert.NoError(t, err)
+	store := models.RevocationStore{}
+	auth.SetRevocationStore(store)
+
+	assert.NoError(t, auth.AddTokenToBlacklist("expired", -time.Second))
+	assert.NoError(t, auth.AddTokenToBlacklist("live", time.Hour))
+
+	n, err := auth.SweepRevocations()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	rec, err := store.FindRevocation(auth.RevokeJTI, "expired")
+	assert.NoError(t, err)
+	assert.Nil(t, rec)
+	assert.True(t, auth.IsTokenBlacklisted("live"))
+}
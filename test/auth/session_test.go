@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"VulnFusion/internal/auth"
+	"VulnFusion/internal/db"
+	"VulnFusion/internal/models"
+	"VulnFusion/internal/utils"
+	"VulnFusion/web/api"
+	"VulnFusion/web/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListAndRevokeSessions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	_ = os.RemoveAll("./testdata")
+	_, err := db.InitDatabase("./testdata/test.db")
+	assert.NoError(t, err)
+	auth.SetRevocationStore(models.RevocationStore{})
+
+	hashed, _ := utils.HashPassword("s3cret-pass")
+	user := &models.User{Username: "dave", Password: hashed, Role: "user"}
+	assert.NoError(t, models.CreateUser(user))
+
+	r := gin.New()
+	r.POST("/auth/login", api.HandleLogin)
+	r.POST("/auth/refresh", api.HandleRefreshToken)
+	group := r.Group("/api/v1", middleware.JWTAuthMiddleware())
+	group.GET("/sessions", api.HandleListSessions)
+	group.DELETE("/sessions/:id", api.HandleRevokeSession)
+
+	login := func(ua string) map[string]string {
+		data, _ := json.Marshal(map[string]string{"username": "dave", "password": "s3cret-pass"})
+		req := httptest.NewRequest(http.MethodPost, "/auth/login", bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", ua)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp map[string]string
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return resp
+	}
+	call := func(method, path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	laptop := login("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0 Safari/537.36")
+	phone := login("Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1")
+
+	w := call(http.MethodGet, "/api/v1/sessions", phone["token"])
+	assert.Equal(t, http.StatusOK, w.Code)
+	var sessions []api.SessionView
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &sessions))
+	assert.Len(t, sessions, 2)
+	devices := map[string]bool{}
+	var laptopID uint
+	for _, s := range sessions {
+		devices[s.Device] = s.Current
+		if s.Device == "Chrome / Windows" {
+			laptopID = s.ID
+		}
+	}
+	assert.Equal(t, map[string]bool{"Chrome / Windows": false, "Safari / iOS": true}, devices)
+
+	// 从手机上吊销被盗的笔记本会话：访问令牌与刷新令牌立即失效
+	w = call(http.MethodDelete, fmt.Sprintf("/api/v1/sessions/%d", laptopID), phone["token"])
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusUnauthorized, call(http.MethodGet, "/api/v1/sessions", laptop["token"]).Code)
+	code, _ := postJSON(r, "/auth/refresh", map[string]string{"refresh_token": laptop["refresh_token"]})
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	active, err := models.ListActiveSessionsByUserID(user.ID)
+	assert.NoError(t, err)
+	assert.Len(t, active, 1)
+
+	// 其他用户不能吊销他人的会话
+	eveToken, _ := auth.GenerateFamilyToken(user.ID+1, "eve", "user", auth.NewFamilyID(), time.Minute)
+	w = call(http.MethodDelete, fmt.Sprintf("/api/v1/sessions/%d", active[0].ID), eveToken)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
@@ -0,0 +1,147 @@
+package ci
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"os"
+	"testing"
+
+	"VulnFusion/internal/ci"
+	"VulnFusion/internal/db"
+	"VulnFusion/internal/log"
+	"VulnFusion/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMain(m *testing.M) {
+	log.InitLogger("dev", "debug")
+	code := m.Run()
+	_ = os.RemoveAll("./testdata")
+	os.Exit(code)
+}
+
+func TestDecidePolicy(t *testing.T) {
+	task := &models.Task{ID: 7, Target: "https://staging.example.com", Status: models.StatusDone}
+	results := []models.Result{
+		{ID: 1, TemplateID: "sqli-error", Vulnerability: "SQL 注入", Severity: "critical", AssetID: 3, Target: "https://staging.example.com/login"},
+		{ID: 2, TemplateID: "tech-detect", Vulnerability: "技术栈识别", Severity: "high", AssetID: 3},
+		{ID: 3, TemplateID: "xss-reflected", Vulnerability: "反射型 XSS", Severity: "high", AssetID: 3},
+		{ID: 4, TemplateID: "missing-headers", Vulnerability: "缺少安全头", Severity: "low", AssetID: 3},
+	}
+	// 同一资产上同一模板的历史结果已接受风险
+	accepted := []models.Result{{ID: 99, TemplateID: "xss-reflected", AssetID: 3, Status: models.ResultStatusAccepted}}
+	policy := ci.Policy{FailOn: "high", AllowTemplates: []string{"tech-detect"}, IgnoreAccepted: true}
+
+	v := ci.Decide(task, results, accepted, policy)
+	assert.Equal(t, ci.VerdictFail, v.Verdict)
+	assert.False(t, v.Passed)
+	assert.Equal(t, 4, v.Total)
+	assert.Len(t, v.Violations, 1)
+	assert.Equal(t, "sqli-error", v.Violations[0].TemplateID)
+	reasons := map[string]string{}
+	for _, f := range v.Ignored {
+		reasons[f.TemplateID] = f.Reason
+	}
+	assert.Equal(t, ci.ReasonAllowed, reasons["tech-detect"])
+	assert.Equal(t, ci.ReasonAccepted, reasons["xss-reflected"])
+	assert.Equal(t, ci.ReasonBelow, reasons["missing-headers"])
+
+	// 不忽略已接受风险时 XSS 也会失败
+	policy.IgnoreAccepted = false
+	assert.Len(t, ci.Decide(task, results, accepted, policy).Violations, 2)
+
+	// 只在 critical 失败且豁免 SQL 注入时通过
+	policy = ci.Policy{FailOn: "critical", AllowTemplates: []string{"sqli-error"}}
+	assert.Equal(t, ci.VerdictPass, ci.Decide(task, results, nil, policy).Verdict)
+
+	assert.Equal(t, ci.VerdictError, ci.Decide(&models.Task{Status: models.StatusFailed}, nil, nil, policy).Verdict)
+	assert.Equal(t, ci.VerdictPending, ci.Decide(&models.Task{Status: models.StatusRunning}, nil, nil, policy).Verdict)
+	assert.Error(t, ci.Policy{FailOn: "severe"}.Validate())
+}
+
+func TestEvaluateUsesAcceptedHistory(t *testing.T) {
+	_ = os.RemoveAll("./testdata")
+	_, err := db.InitDatabase("./testdata/test.db")
+	assert.NoError(t, err)
+
+	old := &models.Task{UserID: 1, Target: "https://a", Template: "x", Status: models.StatusDone}
+	assert.NoError(t, models.CreateTask(old))
+	assert.NoError(t, models.SaveScanResult(&models.Result{TaskID: old.ID, Target: "https://a", Vulnerability: "XSS", Severity: "high", TemplateID: "xss", AssetID: 5, Status: models.ResultStatusAccepted}))
+
+	task := &models.Task{UserID: 1, Target: "https://a", Template: "x", Status: models.StatusDone}
+	assert.NoError(t, models.CreateTask(task))
+	assert.NoError(t, models.SaveScanResult(&models.Result{TaskID: task.ID, Target: "https://a", Vulnerability: "XSS", Severity: "high", TemplateID: "xss", AssetID: 5}))
+
+	v, err := ci.Evaluate(task, ci.Policy{FailOn: "high", IgnoreAccepted: true})
+	assert.NoError(t, err)
+	assert.Equal(t, ci.VerdictPass, v.Verdict)
+	assert.Len(t, v.Ignored, 1)
+
+	v, err = ci.Evaluate(task, ci.Policy{FailOn: "high"})
+	assert.NoError(t, err)
+	assert.Equal(t, ci.VerdictFail, v.Verdict)
+}
+
+func TestReports(t *testing.T) {
+	task := &models.Task{ID: 9, Target: "https://a", Status: models.StatusDone}
+	results := []models.Result{
+		{ID: 1, TemplateID: "sqli-error", Vulnerability: "SQL 注入", Severity: "critical", Target: "https://a/login", CVSSScore: 9.8},
+		{ID: 2, TemplateID: "tech-detect", Vulnerability: "技术栈识别", Severity: "info", Target: "https://a"},
+		{ID: 3, TemplateID: "xss", Vulnerability: "XSS", Severity: "high", Target: "https://a/q"},
+	}
+	v := ci.Decide(task, results, nil, ci.Policy{FailOn: "high", AllowTemplates: []string{"xss"}})
+
+	data, err := ci.JUnit(v)
+	assert.NoError(t, err)
+	var suites struct {
+		Tests    int `xml:"tests,attr"`
+		Failures int `xml:"failures,attr"`
+		Skipped  int `xml:"skipped,attr"`
+		Suites   []struct {
+			Cases []struct {
+				Name    string    `xml:"name,attr"`
+				Failure *struct{} `xml:"failure"`
+			} `xml:"testcase"`
+		} `xml:"testsuite"`
+	}
+	assert.NoError(t, xml.Unmarshal(data, &suites))
+	assert.Equal(t, 3, suites.Tests)
+	assert.Equal(t, 1, suites.Failures)
+	assert.Equal(t, 1, suites.Skipped)
+	assert.Equal(t, "[CRITICAL] sqli-error - SQL 注入", suites.Suites[0].Cases[0].Name)
+	assert.NotNil(t, suites.Suites[0].Cases[0].Failure)
+
+	data, err = ci.SARIF(v)
+	assert.NoError(t, err)
+	var sarif struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Rules []struct {
+						ID         string                 `json:"id"`
+						Properties map[string]interface{} `json:"properties"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID       string        `json:"ruleId"`
+				Level        string        `json:"level"`
+				Suppressions []interface{} `json:"suppressions"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	assert.NoError(t, json.Unmarshal(data, &sarif))
+	assert.Equal(t, "2.1.0", sarif.Version)
+	assert.Len(t, sarif.Runs[0].Tool.Driver.Rules, 3)
+	assert.Equal(t, "9.8", sarif.Runs[0].Tool.Driver.Rules[0].Properties["security-severity"])
+	levels := map[string]string{}
+	for _, r := range sarif.Runs[0].Results {
+		levels[r.RuleID] = r.Level
+		if r.RuleID == "xss" {
+			assert.Len(t, r.Suppressions, 1)
+		}
+	}
+	assert.Equal(t, map[string]string{"sqli-error": "error", "tech-detect": "note", "xss": "warning"}, levels)
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"VulnFusion/internal/db"
+	"VulnFusion/internal/enrich"
 	"VulnFusion/internal/models"
 	"VulnFusion/internal/utils"
 
@@ -139,3 +140,297 @@ func TestResultModel(t *testing.T) {
 	err = models.DeleteResultsByTaskID(task.ID)
 	assert.NoError(t, err)
 }
+
+func TestAgentClaimPendingTask(t *testing.T) {
+	defer cleanupTestDB()
+	setupTestDB(t)
+
+	agent := &models.Agent{Name: "dmz-runner", TokenHash: utils.SHA256Hex("token"), Labels: "dmz", Capacity: 1}
+	err := models.CreateAgent(agent)
+	assert.NoError(t, err)
+
+	got, err := models.GetAgentByTokenHash(utils.SHA256Hex("token"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"dmz"}, got.LabelList())
+
+	internal := &models.Task{UserID: 1, Target: "http://10.0.0.1", Template: "x", AgentLabel: "internal-net"}
+	dmz := &models.Task{UserID: 1, Target: "http://dmz", Template: "x", AgentLabel: "dmz"}
+	assert.NoError(t, models.CreateTask(internal))
+	assert.NoError(t, models.CreateTask(dmz))
+
+	claimed, err := models.ClaimPendingTask(agent.ID, got.LabelList())
+	assert.NoError(t, err)
+	assert.NotNil(t, claimed)
+	assert.Equal(t, dmz.ID, claimed.ID)
+	assert.Equal(t, models.StatusRunning, claimed.Status)
+
+	// 同一任务不能被重复领取
+	claimed, err = models.ClaimPendingTask(agent.ID, got.LabelList())
+	assert.NoError(t, err)
+	assert.Nil(t, claimed)
+
+	// 运行中的任务已达最大并发数时不再领取新任务
+	next := &models.Task{UserID: 1, Target: "http://dmz2", Template: "x", AgentLabel: "dmz"}
+	assert.NoError(t, models.CreateTask(next))
+	claimed, err = models.ClaimPendingTask(agent.ID, got.LabelList())
+	assert.NoError(t, err)
+	assert.Nil(t, claimed)
+
+	assert.NoError(t, models.UpdateTaskStatus(dmz.ID, models.StatusDone))
+	claimed, err = models.ClaimPendingTask(agent.ID, got.LabelList())
+	assert.NoError(t, err)
+	if assert.NotNil(t, claimed) {
+		assert.Equal(t, next.ID, claimed.ID)
+	}
+}
+
+func TestAgentTaskLifecycle(t *testing.T) {
+	defer cleanupTestDB()
+	setupTestDB(t)
+
+	now := time.Now()
+	dead := &models.Agent{Name: "dead", TokenHash: utils.SHA256Hex("dead"), Labels: "dmz", LastHeartbeat: now.Add(-time.Hour)}
+	alive := &models.Agent{Name: "alive", TokenHash: utils.SHA256Hex("alive"), Labels: "dmz", LastHeartbeat: now}
+	assert.NoError(t, models.CreateAgent(dead))
+	assert.NoError(t, models.CreateAgent(alive))
+
+	exists, err := models.AgentLabelExists("dmz")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+	exists, err = models.AgentLabelExists("nowhere")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	lost := &models.Task{UserID: 1, Target: "http://a", Template: "x", AgentLabel: "dmz"}
+	kept := &models.Task{UserID: 1, Target: "http://b", Template: "x", AgentLabel: "dmz"}
+	assert.NoError(t, models.CreateTask(lost))
+	assert.NoError(t, models.CreateTask(kept))
+	claimed, err := models.ClaimPendingTask(dead.ID, dead.LabelList())
+	assert.NoError(t, err)
+	assert.Equal(t, lost.ID, claimed.ID)
+	claimed, err = models.ClaimPendingTask(alive.ID, alive.LabelList())
+	assert.NoError(t, err)
+	assert.Equal(t, kept.ID, claimed.ID)
+
+	// 心跳超时节点的任务放回队列，在线节点的任务不受影响
+	requeued, err := models.RequeueStaleAgentTasks(now.Add(-90 * time.Second))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), requeued)
+	got, _ := models.GetTaskByID(lost.ID)
+	assert.Equal(t, models.StatusPending, got.Status)
+	assert.Zero(t, got.AgentID)
+	got, _ = models.GetTaskByID(kept.ID)
+	assert.Equal(t, models.StatusRunning, got.Status)
+
+	// 已结束的任务不能再回到运行中
+	updated, err := models.UpdateUnfinishedTask(kept.ID, map[string]interface{}{"status": models.StatusDone})
+	assert.NoError(t, err)
+	assert.True(t, updated)
+	updated, err = models.UpdateUnfinishedTask(kept.ID, map[string]interface{}{"status": models.StatusRunning})
+	assert.NoError(t, err)
+	assert.False(t, updated)
+	got, _ = models.GetTaskByID(kept.ID)
+	assert.Equal(t, models.StatusDone, got.Status)
+}
+
+func TestProjectVisibility(t *testing.T) {
+	defer cleanupTestDB()
+	setupTestDB(t)
+
+	project := &models.Project{Name: "客户 A", CreatedBy: 1}
+	assert.NoError(t, models.CreateProject(project))
+	assert.NoError(t, models.SaveProjectMember(project.ID, 2, models.ProjectRoleViewer))
+
+	owner, err := models.GetProjectMember(project.ID, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, models.ProjectRoleOwner, owner.Role)
+
+	shared := &models.Task{UserID: 1, ProjectID: project.ID, Target: "http://a", Template: "x"}
+	private := &models.Task{UserID: 1, Target: "http://b", Template: "x"}
+	assert.NoError(t, models.CreateTask(shared))
+	assert.NoError(t, models.CreateTask(private))
+
+	// 项目成员可见项目任务，但看不到他人的个人任务
+	tasks, err := models.ListVisibleTasks(2, 0)
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 1)
+	assert.Equal(t, shared.ID, tasks[0].ID)
+
+	tasks, err = models.ListVisibleTasks(1, 0)
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 2)
+
+	// 删除项目后任务转为创建人个人任务
+	assert.NoError(t, models.DeleteProjectByID(project.ID))
+	tasks, err = models.ListVisibleTasks(2, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, tasks)
+	count, err := models.CountProjectOwners(project.ID)
+	assert.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+func TestAssetUpsertAndFindings(t *testing.T) {
+	defer cleanupTestDB()
+	setupTestDB(t)
+
+	first, err := models.UpsertAsset(models.AssetObservation{UserID: 1, Host: "App.Example.com", Port: 443, Technologies: []string{"nginx"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "app.example.com", first.Host)
+
+	second, err := models.UpsertAsset(models.AssetObservation{UserID: 1, Host: "app.example.com", IP: "10.0.0.5", Port: 80, Technologies: []string{"php", "nginx"}})
+	assert.NoError(t, err)
+	assert.Equal(t, first.ID, second.ID)
+
+	got, err := models.GetAssetByID(first.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "80,443", got.Ports)
+	assert.Equal(t, "nginx,php", got.Technologies)
+	assert.Equal(t, "10.0.0.5", got.IP)
+	assert.Equal(t, models.CriticalityMedium, got.Criticality)
+
+	// 同一主机在项目中是独立资产
+	other, err := models.UpsertAsset(models.AssetObservation{ProjectID: 3, Host: "app.example.com"})
+	assert.NoError(t, err)
+	assert.NotEqual(t, first.ID, other.ID)
+
+	open := &models.Result{TaskID: 1, Target: "https://app.example.com", Vulnerability: "a", AssetID: first.ID}
+	fixed := &models.Result{TaskID: 1, Target: "https://app.example.com", Vulnerability: "b", AssetID: first.ID}
+	assert.NoError(t, models.SaveScanResult(open))
+	assert.NoError(t, models.SaveScanResult(fixed))
+	assert.NoError(t, models.UpdateResultStatus(fixed.ID, models.ResultStatusFixed))
+
+	findings, err := models.ListOpenResultsByAssetID(first.ID)
+	assert.NoError(t, err)
+	assert.Len(t, findings, 1)
+	assert.Equal(t, open.ID, findings[0].ID)
+
+	closed, err := models.GetResultByID(fixed.ID)
+	assert.NoError(t, err)
+	assert.NotNil(t, closed.ClosedAt)
+}
+
+func TestFindingDedupeAndAutoClose(t *testing.T) {
+	defer cleanupTestDB()
+	setupTestDB(t)
+
+	asset, err := models.UpsertAsset(models.AssetObservation{UserID: 1, Host: "app.example.com", Port: 8443, Endpoint: "https://app.example.com:8443"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"https://app.example.com:8443"}, asset.ScanTargets())
+	ids, err := models.ListAssetIDsByHosts(1, 0, []string{"app.example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{asset.ID}, ids)
+	mixed, err := models.ListAssetIDsByHosts(1, 0, []string{" App.Example.COM"})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{asset.ID}, mixed)
+
+	first := &models.Task{UserID: 1, Target: "https://app.example.com:8443", Template: "cves"}
+	second := &models.Task{UserID: 1, Target: "https://app.example.com:8443", Template: "cves"}
+	assert.NoError(t, models.CreateTask(first))
+	assert.NoError(t, models.CreateTask(second))
+
+	finding := func(taskID uint, template string) *models.Result {
+		return &models.Result{TaskID: taskID, Target: "https://app.example.com:8443/login", Vulnerability: template, TemplateID: template, AssetID: asset.ID}
+	}
+	sqli, gone := finding(first.ID, "sqli"), finding(first.ID, "xss")
+	fresh, err := models.UpsertFinding(sqli)
+	assert.NoError(t, err)
+	assert.True(t, fresh)
+	_, err = models.UpsertFinding(gone)
+	assert.NoError(t, err)
+	assert.NoError(t, models.UpdateResultStatus(sqli.ID, models.ResultStatusAccepted))
+
+	// 再次检出时合并到已有结果并归入新任务，处置状态保留
+	again := finding(second.ID, "sqli")
+	fresh, err = models.UpsertFinding(again)
+	assert.NoError(t, err)
+	assert.False(t, fresh)
+	assert.Equal(t, sqli.ID, again.ID)
+	assert.Equal(t, second.ID, again.TaskID)
+	assert.Equal(t, models.ResultStatusAccepted, again.Status)
+
+	// 本次未再检出的漏洞标记为已修复
+	closed, err := models.CloseMissingFindings(second, ids)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), closed)
+	got, _ := models.GetResultByID(gone.ID)
+	assert.Equal(t, models.ResultStatusFixed, got.Status)
+	assert.NotNil(t, got.ClosedAt)
+
+	// 已修复的漏洞再次出现时重新打开
+	fresh, err = models.UpsertFinding(finding(second.ID, "xss"))
+	assert.NoError(t, err)
+	assert.True(t, fresh)
+	got, _ = models.GetResultByID(gone.ID)
+	assert.Equal(t, models.ResultStatusOpen, got.Status)
+	assert.Nil(t, got.ClosedAt)
+	count, _ := models.CountResultsByTaskID(second.ID)
+	assert.Equal(t, int64(2), count)
+}
+
+func TestEnrichmentLookup(t *testing.T) {
+	defer cleanupTestDB()
+	setupTestDB(t)
+
+	assert.NoError(t, models.SaveCVEEntries([]models.CVEEntry{{
+		ID:          "CVE-2021-44228",
+		CVSS3Score:  10,
+		CVSS3Vector: "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:C/C:H/I:H/A:H",
+		CWEIDs:      "CWE-502",
+		References:  "https://a\nhttps://b",
+	}}))
+	assert.NoError(t, models.SaveCWEEntries([]models.CWEEntry{{ID: "CWE-502", Name: "Deserialization of Untrusted Data"}}))
+
+	result := &models.Result{CVEID: "cve-2021-44228"}
+	info, err := enrich.Lookup(result)
+	assert.NoError(t, err)
+	assert.Len(t, info.CVEs, 1)
+	assert.Equal(t, []string{"https://a", "https://b"}, info.CVEs[0].References)
+	assert.Len(t, info.CWEs, 1)
+	assert.Equal(t, "CWE-502", info.CWEs[0].ID)
+
+	enrich.FillCVSS(result)
+	assert.Equal(t, 10.0, result.CVSSScore)
+}
+
+func TestStatsVisibility(t *testing.T) {
+	defer cleanupTestDB()
+	setupTestDB(t)
+
+	mine := &models.Task{UserID: 1, Target: "http://a", Template: "x", Status: models.StatusDone}
+	other := &models.Task{UserID: 2, Target: "http://b", Template: "x", Status: models.StatusRunning}
+	assert.NoError(t, models.CreateTask(mine))
+	assert.NoError(t, models.CreateTask(other))
+
+	results := []*models.Result{
+		{TaskID: mine.ID, Target: "http://a/x", Host: "a", Vulnerability: "xss", TemplateID: "xss-reflected", Severity: "high"},
+		{TaskID: mine.ID, Target: "http://a/y", Host: "a", Vulnerability: "xss", TemplateID: "xss-reflected", Severity: "High"},
+		{TaskID: mine.ID, Target: "http://a/z", Host: "a", Vulnerability: "sqli", TemplateID: "sqli-error", Severity: "critical"},
+		{TaskID: other.ID, Target: "http://b", Host: "b", Vulnerability: "info", Severity: "low"},
+	}
+	for _, r := range results {
+		assert.NoError(t, models.SaveScanResult(r))
+	}
+	assert.NoError(t, models.UpdateResultStatus(results[2].ID, models.ResultStatusFixed))
+
+	stats, err := models.GetStats(models.StatsFilter{UserID: 1, Since: time.Now().AddDate(0, 0, -6)})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), stats.TotalTasks)
+	assert.Equal(t, int64(3), stats.TotalFindings)
+	assert.Equal(t, int64(2), stats.OpenFindings)
+	assert.Equal(t, int64(2), stats.Severity["high"])
+	assert.Equal(t, "xss-reflected", stats.TopTemplates[0].Name)
+	assert.Equal(t, models.NamedCount{Name: "a", Count: 2}, stats.TopHosts[0])
+	assert.Len(t, stats.Daily, 7)
+
+	today := stats.Daily[len(stats.Daily)-1]
+	assert.Equal(t, time.Now().Format("2006-01-02"), today.Date)
+	assert.Equal(t, int64(3), today.Opened)
+	assert.Equal(t, int64(1), today.Closed)
+	assert.GreaterOrEqual(t, stats.MTTRHours, 0.0)
+
+	all, err := models.GetStats(models.StatsFilter{All: true})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), all.TotalTasks)
+	assert.Equal(t, int64(4), all.TotalFindings)
+}
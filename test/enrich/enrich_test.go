@@ -0,0 +1,125 @@
+package enrich
+
+import (
+	"bytes"
+	"compress/gzip"
+	"strings"
+	"testing"
+
+	"VulnFusion/internal/enrich"
+	"VulnFusion/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const nvd11Feed = `{
+  "CVE_data_type": "CVE",
+  "CVE_Items": [{
+    "cve": {
+      "CVE_data_meta": {"ID": "CVE-2021-44228"},
+      "problemtype": {"problemtype_data": [{"description": [{"lang": "en", "value": "CWE-502"}, {"lang": "en", "value": "NVD-CWE-Other"}]}]},
+      "references": {"reference_data": [{"url": "https://logging.apache.org/log4j/2.x/security.html"}]},
+      "description": {"description_data": [{"lang": "en", "value": "Apache Log4j2 JNDI features do not protect against attacker controlled LDAP."}]}
+    },
+    "configurations": {"nodes": [{"cpe_match": [], "children": [{"cpe_match": [{"vulnerable": true, "cpe23Uri": "cpe:2.3:a:apache:log4j:*:*:*:*:*:*:*:*"}]}]}]},
+    "impact": {
+      "baseMetricV3": {"cvssV3": {"vectorString": "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:C/C:H/I:H/A:H", "baseScore": 10.0}},
+      "baseMetricV2": {"cvssV2": {"vectorString": "AV:N/AC:M/Au:N/C:C/I:C/A:C", "baseScore": 9.3}}
+    },
+    "publishedDate": "2021-12-10T10:15Z",
+    "lastModifiedDate": "2023-04-03T20:15Z"
+  }]
+}`
+
+const nvd20Feed = `{
+  "resultsPerPage": 1,
+  "vulnerabilities": [{
+    "cve": {
+      "id": "CVE-2023-0001",
+      "published": "2023-01-05T10:15:09.143",
+      "lastModified": "2023-02-01T00:00:00.000",
+      "descriptions": [{"lang": "es", "value": "desc"}, {"lang": "en", "value": "An issue was found."}],
+      "metrics": {
+        "cvssMetricV31": [
+          {"type": "Secondary", "cvssData": {"vectorString": "CVSS:3.1/AV:L/AC:L/PR:L/UI:N/S:U/C:H/I:N/A:N", "baseScore": 5.5}},
+          {"type": "Primary", "cvssData": {"vectorString": "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H", "baseScore": 9.8}}
+        ]
+      },
+      "weaknesses": [{"description": [{"lang": "en", "value": "CWE-79"}]}],
+      "configurations": [{"nodes": [{"cpeMatch": [{"vulnerable": true, "criteria": "cpe:2.3:a:vendor:product:1.0:*:*:*:*:*:*:*"}]}]}],
+      "references": [{"url": "https://example.com/advisory"}]
+    }
+  }]
+}`
+
+const cweCatalog = `<?xml version="1.0" encoding="UTF-8"?>
+<Weakness_Catalog xmlns="http://cwe.mitre.org/cwe-7" Name="CWE" Version="4.13">
+  <Weaknesses>
+    <Weakness ID="79" Name="Improper Neutralization of Input During Web Page Generation" Abstraction="Base" Status="Stable">
+      <Description>The product does not neutralize
+        user-controllable input.</Description>
+    </Weakness>
+    <Weakness ID="502" Name="Deserialization of Untrusted Data" Abstraction="Base" Status="Draft">
+      <Description>The product deserializes untrusted data.</Description>
+    </Weakness>
+  </Weaknesses>
+</Weakness_Catalog>`
+
+func collectNVD(t *testing.T, data []byte) []models.CVEEntry {
+	var entries []models.CVEEntry
+	count, err := enrich.ParseNVD(bytes.NewReader(data), func(e models.CVEEntry) error {
+		entries = append(entries, e)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, len(entries), count)
+	return entries
+}
+
+func TestParseNVD11(t *testing.T) {
+	entries := collectNVD(t, []byte(nvd11Feed))
+	assert.Len(t, entries, 1)
+
+	e := entries[0]
+	assert.Equal(t, "CVE-2021-44228", e.ID)
+	assert.Equal(t, 10.0, e.CVSS3Score)
+	assert.Equal(t, "AV:N/AC:M/Au:N/C:C/I:C/A:C", e.CVSS2Vector)
+	assert.Equal(t, "CWE-502", e.CWEIDs)
+	assert.Equal(t, "cpe:2.3:a:apache:log4j:*:*:*:*:*:*:*:*", e.CPEs)
+	assert.Equal(t, 2021, e.PublishedAt.Year())
+}
+
+func TestParseNVD20Gzip(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, _ = gz.Write([]byte(nvd20Feed))
+	_ = gz.Close()
+
+	entries := collectNVD(t, buf.Bytes())
+	assert.Len(t, entries, 1)
+
+	e := entries[0]
+	assert.Equal(t, "CVE-2023-0001", e.ID)
+	assert.Equal(t, "An issue was found.", e.Description)
+	assert.Equal(t, 9.8, e.CVSS3Score)
+	assert.Equal(t, "CWE-79", e.CWEIDs)
+	assert.Equal(t, "https://example.com/advisory", e.References)
+}
+
+func TestParseNVDUnknownFormat(t *testing.T) {
+	_, err := enrich.ParseNVD(strings.NewReader(`{"foo": []}`), func(models.CVEEntry) error { return nil })
+	assert.ErrorIs(t, err, enrich.ErrUnknownFeed)
+}
+
+func TestParseCWE(t *testing.T) {
+	var entries []models.CWEEntry
+	count, err := enrich.ParseCWE(strings.NewReader(cweCatalog), func(e models.CWEEntry) error {
+		entries = append(entries, e)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.Equal(t, "CWE-79", entries[0].ID)
+	assert.Equal(t, "Base", entries[0].Abstraction)
+	assert.Equal(t, "The product does not neutralize user-controllable input.", entries[0].Description)
+}
@@ -0,0 +1,130 @@
+package ldap
+
+import (
+	"testing"
+
+	"VulnFusion/internal/config"
+	"VulnFusion/internal/ldap"
+	"VulnFusion/internal/ldap/ldaptest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBERRoundTrip(t *testing.T) {
+	long := make([]byte, 300)
+	p := ldap.NewSequence(ldap.NewInteger(-129), ldap.NewInteger(65535), ldap.NewBoolean(true),
+		&ldap.Packet{Tag: ldap.TagOctetString, Value: long})
+	decoded, err := ldap.DecodePacket(p.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-129), decoded.Child(0).Int())
+	assert.Equal(t, int64(65535), decoded.Child(1).Int())
+	assert.Equal(t, []byte{0xff}, decoded.Child(2).Value)
+	assert.Len(t, decoded.Child(3).Value, 300)
+
+	_, err = ldap.DecodePacket([]byte{0x30, 0x05, 0x02})
+	assert.Error(t, err)
+}
+
+func TestCompileFilter(t *testing.T) {
+	f, err := ldap.CompileFilter("(&(objectClass=person)(|(uid=al*ce)(!(mail=*))))")
+	assert.NoError(t, err)
+	assert.Len(t, f.Children, 2)
+
+	for _, bad := range []string{"(uid=alice", "(&(uid=a)", "(=x)", `(uid=\zz)`, "(uid=a))"} {
+		_, err := ldap.CompileFilter(bad)
+		assert.Error(t, err, bad)
+	}
+
+	// 用户输入中的特殊字符被转义，不能改变过滤器结构
+	assert.Equal(t, `a\2a\29\28uid=\2a`, ldap.EscapeFilter("a*)(uid=*"))
+	assert.Equal(t, `x\,cn\=admin\+`, ldap.EscapeDN("x,cn=admin+"))
+}
+
+func newDirectory(t *testing.T) *ldaptest.Server {
+	srv := ldaptest.NewServer()
+	t.Cleanup(srv.Close)
+	srv.AddEntry("cn=svc,dc=example,dc=com", "svc-pass", map[string][]string{"cn": {"svc"}})
+	srv.AddEntry("uid=alice,ou=people,dc=example,dc=com", "alice-pass", map[string][]string{
+		"uid":      {"alice"},
+		"mail":     {"alice@example.com"},
+		"memberOf": {"cn=VulnFusion-Admins,ou=groups,dc=example,dc=com"},
+	})
+	srv.AddEntry("uid=bob,ou=people,dc=example,dc=com", "bob-pass", map[string][]string{
+		"uid": {"bob"},
+	})
+	srv.AddEntry("cn=scanners,ou=groups,dc=example,dc=com", "", map[string][]string{
+		"cn":     {"scanners"},
+		"member": {"uid=bob,ou=people,dc=example,dc=com"},
+	})
+	return srv
+}
+
+func TestAuthenticateWithServiceAccount(t *testing.T) {
+	srv := newDirectory(t)
+	cfg := config.LDAPConfig{
+		URL:          srv.URL,
+		BindDN:       "cn=svc,dc=example,dc=com",
+		BindPassword: "svc-pass",
+		BaseDN:       "ou=people,dc=example,dc=com",
+		GroupBaseDN:  "ou=groups,dc=example,dc=com",
+		RoleMapping: map[string]string{
+			"CN=VulnFusion-Admins, OU=Groups, DC=example, DC=com": "admin",
+			"scanners": "user",
+		},
+	}
+
+	id, err := ldap.Authenticate(cfg, "alice", "alice-pass")
+	assert.NoError(t, err)
+	assert.Equal(t, "uid=alice,ou=people,dc=example,dc=com", id.DN)
+	assert.Equal(t, "alice", id.Username)
+	assert.Equal(t, "alice@example.com", id.Email)
+	role, mapped := ldap.ResolveRole(cfg, id.Groups)
+	assert.True(t, mapped)
+	assert.Equal(t, "admin", role)
+
+	// 组成员关系来自组搜索，按 CN 映射
+	id, err = ldap.Authenticate(cfg, "bob", "bob-pass")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"cn=scanners,ou=groups,dc=example,dc=com"}, id.Groups)
+	role, mapped = ldap.ResolveRole(cfg, id.Groups)
+	assert.True(t, mapped)
+	assert.Equal(t, "user", role)
+
+	_, err = ldap.Authenticate(cfg, "alice", "wrong")
+	assert.ErrorIs(t, err, ldap.ErrInvalidCredentials)
+	_, err = ldap.Authenticate(cfg, "nobody", "x")
+	assert.ErrorIs(t, err, ldap.ErrInvalidCredentials)
+	// 空密码会变成匿名绑定，必须在客户端拒绝
+	_, err = ldap.Authenticate(cfg, "alice", "")
+	assert.ErrorIs(t, err, ldap.ErrInvalidCredentials)
+	// 通配符不能匹配到其他用户
+	_, err = ldap.Authenticate(cfg, "*", "alice-pass")
+	assert.ErrorIs(t, err, ldap.ErrInvalidCredentials)
+
+	cfg.BindPassword = "wrong"
+	_, err = ldap.Authenticate(cfg, "alice", "alice-pass")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ldap.ErrInvalidCredentials)
+}
+
+func TestAuthenticateWithDNTemplate(t *testing.T) {
+	srv := newDirectory(t)
+	cfg := config.LDAPConfig{
+		URL:            srv.URL,
+		UserDNTemplate: "uid=%s,ou=people,dc=example,dc=com",
+		DefaultRole:    "viewer",
+	}
+	id, err := ldap.Authenticate(cfg, "bob", "bob-pass")
+	assert.NoError(t, err)
+	assert.Equal(t, "bob", id.Username)
+	role, mapped := ldap.ResolveRole(cfg, id.Groups)
+	assert.False(t, mapped)
+	assert.Equal(t, "viewer", role)
+	assert.Equal(t, []string{"uid=bob,ou=people,dc=example,dc=com"}, srv.Binds())
+
+	_, err = ldap.Authenticate(cfg, "bob", "nope")
+	assert.ErrorIs(t, err, ldap.ErrInvalidCredentials)
+
+	_, err = ldap.Authenticate(config.LDAPConfig{URL: "ldap://127.0.0.1:1", UserDNTemplate: "uid=%s"}, "bob", "bob-pass")
+	assert.Error(t, err)
+}
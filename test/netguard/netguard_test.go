@@ -0,0 +1,82 @@
+package netguard
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"VulnFusion/internal/config"
+	"VulnFusion/internal/log"
+	"VulnFusion/internal/netguard"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMain(m *testing.M) {
+	log.InitLogger("dev", "debug")
+	os.Exit(m.Run())
+}
+
+func withAllowed(t *testing.T, cidrs ...string) {
+	old := config.Global.Outbound
+	t.Cleanup(func() { config.Global.Outbound = old })
+	config.Global.Outbound.AllowedCIDRs = cidrs
+}
+
+func TestIsBlockedIP(t *testing.T) {
+	withAllowed(t)
+	for _, ip := range []string{"127.0.0.1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254", "100.64.0.1", "0.0.0.0", "::1", "fe80::1", "fd00::1", "::ffff:127.0.0.1"} {
+		assert.True(t, netguard.IsBlockedIP(net.ParseIP(ip)), ip)
+	}
+	for _, ip := range []string{"8.8.8.8", "1.1.1.1", "2001:4860:4860::8888"} {
+		assert.False(t, netguard.IsBlockedIP(net.ParseIP(ip)), ip)
+	}
+
+	// 白名单网段可以访问
+	withAllowed(t, "10.20.0.0/16", "bad-cidr")
+	assert.False(t, netguard.IsBlockedIP(net.ParseIP("10.20.1.1")))
+	assert.True(t, netguard.IsBlockedIP(net.ParseIP("10.21.1.1")))
+}
+
+func TestValidateURL(t *testing.T) {
+	withAllowed(t)
+	assert.Error(t, netguard.ValidateURL("ftp://example.com"))
+	assert.Error(t, netguard.ValidateURL("http://"))
+	assert.ErrorIs(t, netguard.ValidateURL("http://127.0.0.1:8080/hook"), netguard.ErrBlockedAddress)
+	assert.ErrorIs(t, netguard.ValidateURL("http://[::1]/hook"), netguard.ErrBlockedAddress)
+	assert.ErrorIs(t, netguard.ValidateURL("http://169.254.169.254/latest/meta-data"), netguard.ErrBlockedAddress)
+	assert.NoError(t, netguard.ValidateURL("https://8.8.8.8/hook"))
+}
+
+func TestClientBlocksLoopbackAndRedirects(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer target.Close()
+
+	// 连接阶段拒绝回环地址
+	withAllowed(t)
+	client := netguard.NewClient(time.Second)
+	_, err := client.Get(target.URL)
+	assert.True(t, errors.Is(err, netguard.ErrBlockedAddress), err)
+
+	// 白名单内的地址可以访问
+	withAllowed(t, "127.0.0.1/32")
+	resp, err := client.Get(target.URL)
+	if assert.NoError(t, err) {
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	}
+
+	// 重定向到非白名单地址时同样被拒绝
+	redirect := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://[::1]:1/", http.StatusFound)
+	}))
+	defer redirect.Close()
+	_, err = client.Get(redirect.URL)
+	assert.True(t, errors.Is(err, netguard.ErrBlockedAddress), err)
+}
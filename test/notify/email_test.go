@@ -0,0 +1,209 @@
+package notify
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	netmail "net/mail"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"VulnFusion/internal/config"
+	"VulnFusion/internal/models"
+	"VulnFusion/internal/notify"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// smtpSink 本地 SMTP 收件服务，记录收到的邮件
+type smtpSink struct {
+	ln       net.Listener
+	mu       sync.Mutex
+	messages []sinkMessage
+}
+
+type sinkMessage struct {
+	Rcpt []string
+	Data []byte
+}
+
+func startSMTPSink(t *testing.T) *smtpSink {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	s := &smtpSink{ln: ln}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+
+	addr := ln.Addr().(*net.TCPAddr)
+	config.Global.SMTP.Host = "127.0.0.1"
+	config.Global.SMTP.Port = addr.Port
+	config.Global.SMTP.From = "VulnFusion <noreply@example.com>"
+	config.Global.SMTP.Encryption = "none"
+	t.Cleanup(func() {
+		ln.Close()
+		config.Global.SMTP.Host = ""
+	})
+	return s
+}
+
+func (s *smtpSink) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { _, _ = conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 sink ready")
+	var msg sinkMessage
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 sink")
+		case strings.HasPrefix(cmd, "MAIL FROM"):
+			msg = sinkMessage{}
+			reply("250 ok")
+		case strings.HasPrefix(cmd, "RCPT TO"):
+			msg.Rcpt = append(msg.Rcpt, strings.Trim(strings.TrimSpace(line)[8:], "<>"))
+			reply("250 ok")
+		case cmd == "DATA":
+			reply("354 go ahead")
+			var data bytes.Buffer
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(l, "."))
+			}
+			msg.Data = data.Bytes()
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			reply("250 queued")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func (s *smtpSink) received() []sinkMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]sinkMessage(nil), s.messages...)
+}
+
+// parseMail 解析邮件主题、HTML 正文与附件名
+func parseMail(t *testing.T, data []byte) (subject, html string, attachments []string) {
+	msg, err := netmail.ReadMessage(bytes.NewReader(data))
+	assert.NoError(t, err)
+	subject, _ = new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+
+	_, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	assert.NoError(t, err)
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		raw, _ := io.ReadAll(part)
+		decoded, _ := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(raw), "\r\n", ""))
+		if name := part.FileName(); name != "" {
+			attachments = append(attachments, name)
+		} else {
+			html = string(decoded)
+		}
+	}
+	return subject, html, attachments
+}
+
+func TestTaskCompletionEmail(t *testing.T) {
+	setupTestDB(t)
+	sink := startSMTPSink(t)
+
+	task := &models.Task{UserID: 1, Target: "http://a", Template: "x", Status: models.StatusDone}
+	assert.NoError(t, models.CreateTask(task))
+	assert.NoError(t, models.SaveScanResult(&models.Result{TaskID: task.ID, Target: "http://a/x", Vulnerability: "SQL 注入", Severity: "critical"}))
+
+	err := notify.SendTaskEmail("alice@example.com", notify.NewTaskEvent(notify.EventTaskDone, task, 1))
+	assert.NoError(t, err)
+
+	msgs := sink.received()
+	assert.Len(t, msgs, 1)
+	assert.Equal(t, []string{"alice@example.com"}, msgs[0].Rcpt)
+	subject, html, attachments := parseMail(t, msgs[0].Data)
+	assert.Contains(t, subject, "扫描完成")
+	assert.Contains(t, html, "SQL 注入")
+	assert.Equal(t, []string{"task-1-report.html"}, attachments)
+}
+
+func TestDigest(t *testing.T) {
+	setupTestDB(t)
+	sink := startSMTPSink(t)
+
+	hour := 8
+	config.Global.Digest.Hour = &hour
+	config.Global.Digest.Weekday = "monday"
+	defer func() { config.Global.Digest.Hour = nil }()
+
+	// 2024-05-06 为周一
+	monday9 := time.Date(2024, 5, 6, 9, 0, 0, 0, time.Local)
+	assert.True(t, notify.DigestDue(models.DigestDaily, nil, monday9))
+	assert.False(t, notify.DigestDue(models.DigestDaily, &monday9, monday9.Add(time.Hour)))
+	assert.True(t, notify.DigestDue(models.DigestDaily, &monday9, monday9.Add(24*time.Hour)))
+	assert.False(t, notify.DigestDue(models.DigestWeekly, &monday9, monday9.Add(3*24*time.Hour)))
+	assert.True(t, notify.DigestDue(models.DigestWeekly, &monday9, monday9.Add(7*24*time.Hour)))
+	assert.False(t, notify.DigestDue(models.DigestOff, nil, monday9))
+
+	user := &models.User{Username: "alice", Password: "x", Email: "alice@example.com", Digest: models.DigestDaily}
+	assert.NoError(t, models.CreateUser(user))
+	task := &models.Task{UserID: user.ID, Target: "http://a", Template: "x", Status: models.StatusDone}
+	assert.NoError(t, models.CreateTask(task))
+	for _, r := range []*models.Result{
+		{TaskID: task.ID, Target: "http://a/1", Vulnerability: "RCE", Severity: "critical"},
+		{TaskID: task.ID, Target: "http://a/2", Vulnerability: "目录列表", Severity: "low"},
+	} {
+		assert.NoError(t, models.SaveScanResult(r))
+	}
+
+	now := time.Now()
+	notify.RunDigests(now)
+	msgs := sink.received()
+	assert.Len(t, msgs, 1)
+	subject, html, attachments := parseMail(t, msgs[0].Data)
+	assert.Contains(t, subject, "新增 1 个")
+	assert.Contains(t, html, "RCE")
+	assert.NotContains(t, html, "目录列表")
+	assert.Len(t, attachments, 1)
+
+	saved, err := models.GetUserByID(user.ID)
+	assert.NoError(t, err)
+	assert.NotNil(t, saved.DigestSentAt)
+
+	// 同一周期内不再重复发送
+	notify.RunDigests(now.Add(time.Minute))
+	assert.Len(t, sink.received(), 1)
+}
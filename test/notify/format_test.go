@@ -0,0 +1,149 @@
+package notify
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"VulnFusion/internal/models"
+	"VulnFusion/internal/notify"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func criticalEvent() notify.Event {
+	task := &models.Task{ID: 3, UserID: 1, Target: "http://a"}
+	return notify.NewFindingEvent(task, &models.Result{
+		ID: 9, TaskID: 3, Vulnerability: "Log4Shell", Severity: "critical",
+		Target: "http://a/api", TemplateID: "CVE-2021-44228", CVEID: "CVE-2021-44228", RiskScore: 98.5,
+	})
+}
+
+func TestChatFormatters(t *testing.T) {
+	e := criticalEvent()
+	now := time.Unix(1700000000, 0)
+
+	cases := map[string]func(map[string]interface{}){
+		notify.FormatSlack: func(body map[string]interface{}) {
+			assert.Contains(t, body["text"], "Log4Shell")
+			assert.Len(t, body["blocks"], 2)
+		},
+		notify.FormatTeams: func(body map[string]interface{}) {
+			assert.Equal(t, "MessageCard", body["@type"])
+			assert.Equal(t, "D32F2F", body["themeColor"])
+		},
+		notify.FormatDingTalk: func(body map[string]interface{}) {
+			assert.Equal(t, "markdown", body["msgtype"])
+			md := body["markdown"].(map[string]interface{})
+			assert.Contains(t, md["text"], "CVE-2021-44228")
+		},
+		notify.FormatFeishu: func(body map[string]interface{}) {
+			assert.Equal(t, "interactive", body["msg_type"])
+			assert.Equal(t, "1700000000", body["timestamp"])
+			assert.Equal(t, notify.FeishuSign("fs", "1700000000"), body["sign"])
+			header := body["card"].(map[string]interface{})["header"].(map[string]interface{})
+			assert.Equal(t, "red", header["template"])
+		},
+	}
+	for format, check := range cases {
+		raw, err := notify.Render(&models.Webhook{Format: format, Secret: "fs"}, e, now)
+		assert.NoError(t, err, format)
+		var body map[string]interface{}
+		assert.NoError(t, json.Unmarshal(raw, &body), format)
+		check(body)
+	}
+
+	_, err := notify.Render(&models.Webhook{Format: "irc"}, e, now)
+	assert.Error(t, err)
+}
+
+func TestDingTalkDelivery(t *testing.T) {
+	setupTestDB(t)
+
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ts := r.URL.Query().Get("timestamp")
+		assert.Equal(t, "abc", r.URL.Query().Get("access_token"))
+		assert.Equal(t, notify.DingTalkSign("SECxyz", ts), r.URL.Query().Get("sign"))
+		assert.Empty(t, r.Header.Get(notify.HeaderSignature))
+		body, _ = io.ReadAll(r.Body)
+		// 钉钉以 HTTP 200 + errcode 表示业务结果
+		if strings.Contains(string(body), "测试消息") {
+			_, _ = w.Write([]byte(`{"errcode":310000,"errmsg":"sign not match"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+	}))
+	defer server.Close()
+
+	hook := &models.Webhook{UserID: 1, Name: "钉钉", URL: server.URL + "/robot/send?access_token=abc", Secret: "SECxyz", Format: notify.FormatDingTalk, Enabled: true}
+	assert.NoError(t, models.CreateWebhook(hook))
+	d := &notify.Dispatcher{Client: server.Client(), MaxAttempts: 1}
+
+	ok := d.Deliver(hook, criticalEvent(), 1)
+	assert.True(t, ok.Success)
+	assert.Contains(t, string(body), "Log4Shell")
+
+	failed := d.Deliver(hook, notify.NewTestEvent(), 1)
+	assert.False(t, failed.Success)
+	assert.Contains(t, failed.Error, "310000")
+}
+
+func TestQuietHours(t *testing.T) {
+	hook := &models.Webhook{QuietStart: "22:00", QuietEnd: "08:00"}
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.Local)
+
+	assert.True(t, notify.InQuietHours(hook, day.Add(23*time.Hour)))
+	assert.True(t, notify.InQuietHours(hook, day.Add(7*time.Hour+59*time.Minute)))
+	assert.False(t, notify.InQuietHours(hook, day.Add(8*time.Hour)))
+	assert.False(t, notify.InQuietHours(hook, day.Add(12*time.Hour)))
+
+	hook = &models.Webhook{QuietStart: "12:00", QuietEnd: "13:30"}
+	assert.True(t, notify.InQuietHours(hook, day.Add(13*time.Hour)))
+	assert.False(t, notify.InQuietHours(hook, day.Add(14*time.Hour)))
+
+	assert.False(t, notify.InQuietHours(&models.Webhook{}, day))
+
+	// 免打扰结束时间：跨零点时为次日
+	hook = &models.Webhook{QuietStart: "22:00", QuietEnd: "08:00"}
+	assert.Equal(t, day.Add(32*time.Hour), notify.QuietHoursEnd(hook, day.Add(23*time.Hour)))
+	assert.Equal(t, day.Add(8*time.Hour), notify.QuietHoursEnd(hook, day.Add(time.Hour)))
+	assert.Equal(t, day.Add(12*time.Hour), notify.QuietHoursEnd(hook, day.Add(12*time.Hour)))
+}
+
+func TestQuietHoursDeferDelivery(t *testing.T) {
+	setupTestDB(t)
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	hook := &models.Webhook{UserID: 1, Name: "夜间", URL: server.URL, Enabled: true,
+		QuietStart: now.Add(-time.Hour).Format("15:04"), QuietEnd: now.Add(time.Hour).Format("15:04")}
+	assert.NoError(t, models.CreateWebhook(hook))
+	d := &notify.Dispatcher{Client: server.Client(), MaxAttempts: 3}
+
+	// 免打扰时段内不立即投递，事件保留到时段结束
+	e := notify.NewTestEvent()
+	d.Publish(e)
+	assert.Zero(t, atomic.LoadInt32(&calls))
+	logs, _ := models.ListWebhookDeliveries(hook.ID, 10)
+	if assert.Len(t, logs, 1) && assert.NotNil(t, logs[0].NextAttemptAt) {
+		assert.Equal(t, notify.QuietHoursEnd(hook, e.Time).Unix(), logs[0].NextAttemptAt.Unix())
+	}
+
+	d.RetryDue(now)
+	assert.Zero(t, atomic.LoadInt32(&calls))
+	d.RetryDue(now.Add(2 * time.Hour))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	logs, _ = models.ListWebhookDeliveries(hook.ID, 10)
+	assert.True(t, logs[0].Success)
+}
@@ -0,0 +1,139 @@
+package notify
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"VulnFusion/internal/db"
+	"VulnFusion/internal/log"
+	"VulnFusion/internal/models"
+	"VulnFusion/internal/notify"
+	"VulnFusion/internal/utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testDBPath = "./testdata/test.db"
+
+func TestMain(m *testing.M) {
+	log.InitLogger("dev", "debug")
+	code := m.Run()
+	_ = os.RemoveAll("./testdata")
+	os.Exit(code)
+}
+
+func setupTestDB(t *testing.T) {
+	_ = os.RemoveAll("./testdata")
+	_, err := db.InitDatabase(testDBPath)
+	assert.NoError(t, err)
+}
+
+func TestWebhookSignatureAndRetry(t *testing.T) {
+	setupTestDB(t)
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, "sha256="+utils.HMACSHA256Hex("s3cret", body), r.Header.Get(notify.HeaderSignature))
+		assert.Equal(t, notify.EventTaskDone, r.Header.Get(notify.HeaderEvent))
+
+		var e notify.Event
+		assert.NoError(t, json.Unmarshal(body, &e))
+		assert.Equal(t, 3, e.Task.Findings)
+
+		// 前两次返回 500，第三次成功
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	hook := &models.Webhook{UserID: 1, Name: "ops", URL: server.URL, Secret: "s3cret", Enabled: true}
+	assert.NoError(t, models.CreateWebhook(hook))
+
+	task := &models.Task{ID: 7, UserID: 1, Target: "http://a", Status: models.StatusDone}
+	d := &notify.Dispatcher{Client: server.Client(), MaxAttempts: 5, Backoff: func(int) time.Duration { return time.Minute }}
+	delivery := d.Deliver(hook, notify.NewTaskEvent(notify.EventTaskDone, task, 3), d.MaxAttempts)
+
+	// 首次失败后写入下次重试时间，未到期时不会重试
+	assert.NotNil(t, delivery)
+	assert.False(t, delivery.Success)
+	assert.Equal(t, 1, delivery.Attempts)
+	if assert.NotNil(t, delivery.NextAttemptAt) {
+		assert.WithinDuration(t, time.Now().Add(time.Minute), *delivery.NextAttemptAt, 5*time.Second)
+	}
+	d.RetryDue(time.Now())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// 重试由持久化的投递记录驱动，新的投递器（如进程重启后）也能继续重试
+	retry := &notify.Dispatcher{Client: server.Client(), MaxAttempts: 5, Backoff: func(int) time.Duration { return 0 }}
+	retry.RetryDue(time.Now().Add(time.Minute))
+	retry.RetryDue(time.Now().Add(time.Minute))
+
+	logs, err := models.ListWebhookDeliveries(hook.ID, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, logs, 1) {
+		assert.True(t, logs[0].Success)
+		assert.Equal(t, 3, logs[0].Attempts)
+		assert.Equal(t, http.StatusNoContent, logs[0].StatusCode)
+		assert.NotNil(t, logs[0].DeliveredAt)
+		assert.Nil(t, logs[0].NextAttemptAt)
+	}
+	retry.RetryDue(time.Now().Add(time.Hour))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestWebhookRetryStopsAfterMaxAttempts(t *testing.T) {
+	setupTestDB(t)
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	hook := &models.Webhook{UserID: 1, Name: "ops", URL: server.URL, Enabled: true}
+	assert.NoError(t, models.CreateWebhook(hook))
+	d := &notify.Dispatcher{Client: server.Client(), MaxAttempts: 2}
+	delivery := d.Deliver(hook, notify.NewTestEvent(), d.MaxAttempts)
+	assert.NotNil(t, delivery.NextAttemptAt)
+
+	for i := 0; i < 3; i++ {
+		d.RetryDue(time.Now())
+	}
+	logs, _ := models.ListWebhookDeliveries(hook.ID, 10)
+	assert.Equal(t, 2, logs[0].Attempts)
+	assert.False(t, logs[0].Success)
+	assert.Nil(t, logs[0].NextAttemptAt)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestWebhookMatches(t *testing.T) {
+	setupTestDB(t)
+
+	mine := &models.Task{ID: 1, UserID: 1}
+	others := &models.Task{ID: 2, UserID: 2}
+	high := &models.Result{TaskID: 1, Severity: "high"}
+	low := &models.Result{TaskID: 1, Severity: "low"}
+
+	hook := &models.Webhook{UserID: 1, Events: "finding.created", MinSeverity: "high", Enabled: true}
+	assert.True(t, notify.Matches(hook, notify.NewFindingEvent(mine, high)))
+	assert.False(t, notify.Matches(hook, notify.NewFindingEvent(mine, low)))
+	assert.False(t, notify.Matches(hook, notify.NewFindingEvent(others, high)))
+	assert.False(t, notify.Matches(hook, notify.NewTaskEvent(notify.EventTaskDone, mine, 0)))
+
+	hook.Global = true
+	assert.True(t, notify.Matches(hook, notify.NewFindingEvent(others, high)))
+
+	hook.Enabled = false
+	assert.False(t, notify.Matches(hook, notify.NewFindingEvent(mine, high)))
+}
@@ -0,0 +1,52 @@
+package provenance
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"VulnFusion/internal/provenance"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignAndVerifyBatch(t *testing.T) {
+	pub, priv, err := provenance.GenerateKey()
+	assert.NoError(t, err)
+
+	batch := provenance.Batch{
+		AgentID:       3,
+		TaskID:        7,
+		Sequence:      1,
+		NucleiVersion: "v3.4.5",
+		TemplateHash:  "abc",
+		Lines:         []string{`{"templateID":"x","info":{"name":"X","severity":"high"}}`},
+	}
+	signed, err := provenance.Sign(batch, priv)
+	assert.NoError(t, err)
+
+	got, payload, err := provenance.Verify(*signed, pub)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, payload)
+	assert.Equal(t, uint(7), got.TaskID)
+	assert.Equal(t, batch.Lines, got.Lines)
+}
+
+func TestVerifyRejectsTamperedBatch(t *testing.T) {
+	pub, priv, _ := provenance.GenerateKey()
+	otherPub, _, _ := provenance.GenerateKey()
+
+	signed, err := provenance.Sign(provenance.Batch{AgentID: 1, TaskID: 1, Lines: []string{"a"}}, priv)
+	assert.NoError(t, err)
+
+	// 其他节点的公钥无法通过校验
+	_, _, err = provenance.Verify(*signed, otherPub)
+	assert.ErrorIs(t, err, provenance.ErrInvalidSignature)
+
+	// 篡改 payload 后签名失效
+	tampered := *signed
+	tampered.Payload = base64.StdEncoding.EncodeToString([]byte(`{"agent_id":1,"task_id":2,"lines":["a"]}`))
+	_, _, err = provenance.Verify(tampered, pub)
+	assert.ErrorIs(t, err, provenance.ErrInvalidSignature)
+
+	_, _, err = provenance.Verify(*signed, "not-a-key")
+	assert.ErrorIs(t, err, provenance.ErrInvalidPublicKey)
+}
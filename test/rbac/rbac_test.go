@@ -0,0 +1,205 @@
+package rbac
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"VulnFusion/internal/auth"
+	"VulnFusion/internal/config"
+	"VulnFusion/internal/db"
+	"VulnFusion/internal/log"
+	"VulnFusion/internal/models"
+	"VulnFusion/internal/rbac"
+	"VulnFusion/web/middleware"
+	"VulnFusion/web/router"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMain(m *testing.M) {
+	log.InitLogger("dev", "debug")
+	code := m.Run()
+	_ = os.RemoveAll("./testdata")
+	os.Exit(code)
+}
+
+func withRoles(t *testing.T, roles map[string][]string) {
+	old := config.Global.RBAC.Roles
+	t.Cleanup(func() { config.Global.RBAC.Roles = old })
+	config.Global.RBAC.Roles = roles
+}
+
+func TestBuiltinRoles(t *testing.T) {
+	withRoles(t, nil)
+
+	assert.True(t, rbac.Can(rbac.RoleAdmin, rbac.UserManage))
+	assert.True(t, rbac.Can(rbac.RoleUser, rbac.ResultTriage))
+	assert.False(t, rbac.Can(rbac.RoleUser, rbac.TaskReadAny))
+
+	// 审计员全局只读，不能修改任何数据
+	assert.True(t, rbac.Can(rbac.RoleAuditor, rbac.TaskReadAny))
+	assert.True(t, rbac.Can(rbac.RoleAuditor, rbac.AuditRead))
+	assert.False(t, rbac.Can(rbac.RoleAuditor, rbac.UserManage))
+	assert.False(t, rbac.Can(rbac.RoleAuditor, rbac.ResultTriage))
+	assert.False(t, rbac.Can(rbac.RoleAuditor, rbac.TaskCreate))
+
+	assert.True(t, rbac.Can(rbac.RoleScannerOperator, rbac.TaskCreate))
+	assert.False(t, rbac.Can(rbac.RoleScannerOperator, rbac.ResultTriage))
+	assert.False(t, rbac.Can(rbac.RoleViewer, rbac.TaskCreate))
+	assert.True(t, rbac.Can(rbac.RoleViewer, rbac.ResultRead))
+
+	// 扫描范围只能由管理员设置
+	assert.True(t, rbac.Can(rbac.RoleAdmin, rbac.ScopeManage))
+	assert.False(t, rbac.Can(rbac.RoleUser, rbac.ScopeManage))
+	assert.False(t, rbac.Can(rbac.RoleScannerOperator, rbac.ScopeManage))
+
+	assert.True(t, rbac.Can(rbac.RoleUser, rbac.ProjectManage))
+	assert.False(t, rbac.Can(rbac.RoleViewer, rbac.ProjectManage))
+
+	assert.False(t, rbac.Can("ghost", rbac.TaskRead))
+	assert.True(t, rbac.HasAdminAccess(rbac.RoleAuditor))
+	assert.False(t, rbac.HasAdminAccess(rbac.RoleUser))
+}
+
+func TestConfiguredRoles(t *testing.T) {
+	withRoles(t, map[string][]string{
+		"triager":        {rbac.ResultRead, "result.*"},
+		rbac.RoleViewer:  {rbac.TaskRead},
+		rbac.RoleAdmin:   {rbac.TaskRead}, // 管理员不可被降权
+		"release-bot-ci": {"task.*"},
+	})
+
+	assert.True(t, rbac.RoleExists("triager"))
+	assert.True(t, rbac.Can("triager", rbac.ResultTriage))
+	assert.True(t, rbac.Can("triager", rbac.ResultReadAny))
+	assert.False(t, rbac.Can("triager", rbac.TaskRead))
+	assert.False(t, rbac.Can(rbac.RoleViewer, rbac.ResultRead))
+	assert.True(t, rbac.Can(rbac.RoleAdmin, rbac.UserManage))
+	assert.True(t, rbac.Can("release-bot-ci", rbac.TaskManageAny))
+	assert.Contains(t, rbac.PermissionsOf("triager"), rbac.ResultTriage)
+	assert.NoError(t, rbac.Validate())
+
+	// 原地修改配置后重新生效
+	config.Global.RBAC.Roles["triager"] = []string{rbac.TaskRead}
+	assert.False(t, rbac.Can("triager", rbac.ResultTriage))
+
+	config.Global.RBAC.Roles["broken"] = []string{"task.fly"}
+	assert.Error(t, rbac.Validate())
+}
+
+func TestValidateRoleMappings(t *testing.T) {
+	withRoles(t, nil)
+	old := config.Global.LDAP
+	t.Cleanup(func() { config.Global.LDAP = old })
+
+	config.Global.LDAP.RoleMapping = map[string]string{"sec": rbac.RoleAuditor}
+	assert.NoError(t, rbac.Validate())
+	config.Global.LDAP.RoleMapping = map[string]string{"sec": "superuser"}
+	assert.Error(t, rbac.Validate())
+}
+
+func TestRequirePermission(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	withRoles(t, nil)
+
+	r := gin.New()
+	group := r.Group("/api/v1/admin", middleware.JWTAuthMiddleware())
+	ok := func(ctx *gin.Context) { ctx.String(http.StatusOK, "ok") }
+	group.GET("/audit", middleware.RequirePermission(rbac.AuditRead), ok)
+	group.DELETE("/users/:id", middleware.RequirePermission(rbac.UserManage), ok)
+
+	call := func(role, method, path string) int {
+		token, err := auth.GenerateToken(1, "tester", role, time.Minute)
+		assert.NoError(t, err)
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, call(rbac.RoleAuditor, http.MethodGet, "/api/v1/admin/audit"))
+	assert.Equal(t, http.StatusForbidden, call(rbac.RoleAuditor, http.MethodDelete, "/api/v1/admin/users/2"))
+	assert.Equal(t, http.StatusForbidden, call(rbac.RoleUser, http.MethodGet, "/api/v1/admin/audit"))
+	assert.Equal(t, http.StatusOK, call(rbac.RoleAdmin, http.MethodDelete, "/api/v1/admin/users/2"))
+}
+
+func TestProjectAndResultRoutePermissions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	withRoles(t, map[string][]string{"result-reader": {rbac.ResultRead, rbac.ResultReadAny}})
+	_ = os.RemoveAll("./testdata")
+	_, err := db.InitDatabase("./testdata/test.db")
+	assert.NoError(t, err)
+
+	// 只读角色即使是项目 owner 也不能修改、删除项目或管理成员
+	project := &models.Project{Name: "demo", CreatedBy: 1}
+	assert.NoError(t, models.CreateProject(project))
+	task := &models.Task{UserID: 1, Target: "https://example.com"}
+	assert.NoError(t, models.CreateTask(task))
+
+	r := gin.New()
+	router.RegisterRoutes(r)
+	call := func(userID uint, role, method, path string) int {
+		token, err := auth.GenerateToken(userID, "tester", role, time.Minute)
+		assert.NoError(t, err)
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	projectPath := fmt.Sprintf("/api/v1/projects/%d", project.ID)
+	assert.Equal(t, http.StatusForbidden, call(1, rbac.RoleViewer, http.MethodPut, projectPath))
+	assert.Equal(t, http.StatusForbidden, call(1, rbac.RoleViewer, http.MethodDelete, projectPath))
+	assert.Equal(t, http.StatusForbidden, call(1, rbac.RoleViewer, http.MethodPost, projectPath+"/members"))
+	assert.Equal(t, http.StatusForbidden, call(1, rbac.RoleViewer, http.MethodDelete, projectPath+"/members/2"))
+	assert.Equal(t, http.StatusOK, call(1, rbac.RoleUser, http.MethodDelete, projectPath))
+
+	// 具备 result.read.any 时可查看任意任务的结果
+	resultsPath := fmt.Sprintf("/api/v1/results/task/%d", task.ID)
+	assert.Equal(t, http.StatusForbidden, call(2, rbac.RoleUser, http.MethodGet, resultsPath))
+	assert.Equal(t, http.StatusOK, call(2, "result-reader", http.MethodGet, resultsPath))
+}
+
+func TestUpdateTaskStatusValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	_ = os.RemoveAll("./testdata")
+	_, err := db.InitDatabase("./testdata/test.db")
+	assert.NoError(t, err)
+
+	task := &models.Task{UserID: 1, Target: "https://example.com"}
+	assert.NoError(t, models.CreateTask(task))
+
+	r := gin.New()
+	router.RegisterRoutes(r)
+	token, err := auth.GenerateToken(1, "tester", rbac.RoleUser, time.Minute)
+	assert.NoError(t, err)
+	update := func(status string) int {
+		body := fmt.Sprintf(`{"id":%d,"status":%q}`, task.ID, status)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks/status", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// 只接受预定义的任务状态
+	assert.Equal(t, http.StatusBadRequest, update("hacked"))
+	assert.Equal(t, http.StatusBadRequest, update(""))
+	assert.Equal(t, http.StatusOK, update(models.StatusRunning))
+	assert.Equal(t, http.StatusOK, update(models.StatusDone))
+
+	// 已结束的任务不能再变更状态
+	assert.Equal(t, http.StatusConflict, update(models.StatusPending))
+	stored, err := models.GetTaskByID(task.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.StatusDone, stored.Status)
+}
@@ -0,0 +1,65 @@
+package risk
+
+import (
+	"strings"
+	"testing"
+
+	"VulnFusion/internal/risk"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCVSS3BaseScore(t *testing.T) {
+	cases := map[string]float64{
+		"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H": 9.8,
+		"CVSS:3.1/AV:N/AC:L/PR:N/UI:R/S:C/C:L/I:L/A:N": 6.1,
+		"CVSS:3.0/AV:L/AC:L/PR:L/UI:N/S:U/C:H/I:N/A:N": 5.5,
+		"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:N/I:N/A:N": 0,
+	}
+	for vector, want := range cases {
+		got, err := risk.CVSS3BaseScore(vector)
+		assert.NoError(t, err, vector)
+		assert.Equal(t, want, got, vector)
+	}
+
+	_, err := risk.CVSS3BaseScore("AV:N/AC:L")
+	assert.ErrorIs(t, err, risk.ErrInvalidVector)
+}
+
+func TestScore(t *testing.T) {
+	// 仅严重等级：medium 5 × 10
+	assert.Equal(t, 50.0, risk.Score(risk.Input{Severity: "medium"}))
+
+	// CVSS 与严重等级按 0.7 / 0.3 混合：(0.7×9.8 + 0.3×9.5) × 10
+	withCVSS := risk.Score(risk.Input{Severity: "critical", CVSSScore: 9.8})
+	assert.Equal(t, 97.1, withCVSS)
+
+	// 向量可替代分数
+	assert.Equal(t, withCVSS, risk.Score(risk.Input{
+		Severity:   "critical",
+		CVSSVector: "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H",
+	}))
+
+	// 利用可能性与资产重要性放大，结果不超过上限
+	low := risk.Score(risk.Input{Severity: "high", Criticality: "low"})
+	high := risk.Score(risk.Input{Severity: "high", Criticality: "critical", Exploit: 0.5})
+	assert.Less(t, low, high)
+	assert.Equal(t, float64(risk.MaxScore), risk.Score(risk.Input{Severity: "critical", Exploit: 1, Criticality: "critical"}))
+}
+
+func TestParseLikelihoodCSV(t *testing.T) {
+	data := `#model_version:v2023.03.01,score_date:2024-01-01T00:00:00+0000
+cve,epss,percentile
+CVE-2021-44228,0.97565,0.99996
+cve-2023-0001,0.00043,0.0812
+`
+	entries, err := risk.ParseLikelihoodCSV(strings.NewReader(data))
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, "CVE-2021-44228", entries[0].CVEID)
+	assert.Equal(t, 0.97565, entries[0].Score)
+	assert.Equal(t, "CVE-2023-0001", entries[1].CVEID)
+
+	_, err = risk.ParseLikelihoodCSV(strings.NewReader("id,value\nx,1\n"))
+	assert.Error(t, err)
+}
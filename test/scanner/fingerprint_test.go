@@ -0,0 +1,40 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"VulnFusion/internal/scanner"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollectTemplateTags(t *testing.T) {
+	dir := t.TempDir()
+	_ = os.WriteFile(filepath.Join(dir, "a.yaml"), []byte("id: a\ninfo:\n  tags: wordpress,cve\n"), 0644)
+	_ = os.WriteFile(filepath.Join(dir, "b.yml"), []byte("id: b\ninfo:\n  tags:\n    - jenkins\n    - Apache\n"), 0644)
+	_ = os.WriteFile(filepath.Join(dir, "c.txt"), []byte("tags: ignored"), 0644)
+
+	tags, err := scanner.CollectTemplateTags(dir)
+	assert.NoError(t, err)
+	assert.Contains(t, tags, "wordpress")
+	assert.Contains(t, tags, "jenkins")
+	assert.Contains(t, tags, "apache")
+	assert.NotContains(t, tags, "ignored")
+}
+
+func TestSelectTemplateTags(t *testing.T) {
+	available := map[string]struct{}{
+		"wordpress": {},
+		"apache":    {},
+		"tomcat":    {},
+		"tech":      {},
+	}
+
+	tags := scanner.SelectTemplateTags([]string{"WordPress", "apache-tomcat", "tech", "nginx"}, available)
+	assert.Equal(t, []string{"apache", "tomcat", "wordpress"}, tags)
+
+	// 模板库为空时不做过滤
+	tags = scanner.SelectTemplateTags([]string{"nginx"}, nil)
+	assert.Equal(t, []string{"nginx"}, tags)
+}
@@ -0,0 +1,26 @@
+package scanner
+
+import (
+	"os"
+	"testing"
+
+	"VulnFusion/internal/log"
+	"VulnFusion/internal/scanner"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMain(m *testing.M) {
+	log.InitLogger("dev", "debug")
+	os.Exit(m.Run())
+}
+
+func TestParseNucleiResultNoFindings(t *testing.T) {
+	// 未检出漏洞时返回 ErrNoResults，调用方据此按空结果处理
+	parsed, err := scanner.ParseNucleiResult([]byte("\n\n"))
+	assert.ErrorIs(t, err, scanner.ErrNoResults)
+	assert.Empty(t, parsed)
+
+	parsed, err = scanner.ParseNucleiResult([]byte(`{"template-id":"sqli","host":"app.example.com","matched-at":"https://app.example.com/login","info":{"name":"SQLi","severity":"high"}}`))
+	assert.NoError(t, err)
+	assert.Len(t, parsed, 1)
+}
@@ -0,0 +1,139 @@
+package scope
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"VulnFusion/internal/auth"
+	"VulnFusion/internal/config"
+	"VulnFusion/internal/db"
+	"VulnFusion/internal/log"
+	"VulnFusion/internal/models"
+	"VulnFusion/internal/rbac"
+	"VulnFusion/internal/scope"
+	"VulnFusion/web/api"
+	"VulnFusion/web/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMain(m *testing.M) {
+	log.InitLogger("dev", "debug")
+	gin.SetMode(gin.TestMode)
+	code := m.Run()
+	_ = os.RemoveAll("./testdata")
+	os.Exit(code)
+}
+
+func TestCheckDomainAndCIDR(t *testing.T) {
+	rules := []scope.Rule{
+		{Action: scope.ActionInclude, Type: scope.TypeDomain, Value: "*.example.com"},
+		{Action: scope.ActionInclude, Type: scope.TypeCIDR, Value: "10.0.0.0/24"},
+		{Action: scope.ActionExclude, Type: scope.TypeDomain, Value: "admin.example.com"},
+	}
+
+	cases := map[string]bool{
+		"https://app.example.com/login": true,
+		"app.example.com:8443":          true,
+		"example.com":                   false, // *. 仅匹配子域名
+		"https://admin.example.com":     false,
+		"10.0.0.15":                     true,
+		"http://10.0.1.1":               false,
+		"https://evil-example.com":      false,
+	}
+	for target, want := range cases {
+		got, reason := scope.Check(target, rules)
+		assert.Equal(t, want, got, "%s: %s", target, reason)
+	}
+}
+
+func TestCheckURLPrefixAndPorts(t *testing.T) {
+	rules := []scope.Rule{
+		{Action: scope.ActionInclude, Type: scope.TypeURL, Value: "https://shop.example.com/api"},
+		{Action: scope.ActionInclude, Type: scope.TypePort, Value: "443,8000-8100"},
+		{Action: scope.ActionExclude, Type: scope.TypePort, Value: "8080"},
+	}
+
+	ok, _ := scope.Check("https://shop.example.com/api/v1/orders", rules)
+	assert.True(t, ok)
+
+	ok, _ = scope.Check("https://shop.example.com/apix", rules)
+	assert.False(t, ok)
+
+	ok, _ = scope.Check("http://shop.example.com/api", rules)
+	assert.False(t, ok)
+
+	ok, _ = scope.Check("shop.example.com:8080", rules)
+	assert.False(t, ok)
+}
+
+func TestCheckWithoutRulesAllowsAll(t *testing.T) {
+	ok, _ := scope.Check("https://anything.test", nil)
+	assert.True(t, ok)
+}
+
+func TestValidateRule(t *testing.T) {
+	assert.NoError(t, scope.ValidateRule(scope.Rule{Action: "include", Type: "cidr", Value: "192.168.1.1"}))
+	assert.Error(t, scope.ValidateRule(scope.Rule{Action: "include", Type: "cidr", Value: "300.1.1.1/8"}))
+	assert.Error(t, scope.ValidateRule(scope.Rule{Action: "include", Type: "port", Value: "70000"}))
+	assert.Error(t, scope.ValidateRule(scope.Rule{Action: "include", Type: "domain", Value: "a.*.com"}))
+	assert.Error(t, scope.ValidateRule(scope.Rule{Action: "allow", Type: "domain", Value: "a.com"}))
+}
+
+func TestSplitTargets(t *testing.T) {
+	assert.Equal(t, []string{"a.com", "b.com", "10.0.0.1"}, scope.SplitTargets("a.com, b.com\n10.0.0.1\n"))
+}
+
+func TestScopeManagedByAdminAndRequired(t *testing.T) {
+	_ = os.RemoveAll("./testdata")
+	_, err := db.InitDatabase("./testdata/test.db")
+	assert.NoError(t, err)
+	old := config.Global.Scope
+	t.Cleanup(func() { config.Global.Scope = old })
+	config.Global.Scope.RequireScope = true
+
+	user := &models.User{Username: "alice", Password: "x", Role: rbac.RoleUser}
+	assert.NoError(t, models.CreateUser(user))
+
+	r := gin.New()
+	group := r.Group("/api/v1", middleware.JWTAuthMiddleware())
+	group.PUT("/scope", middleware.RequirePermission(rbac.ScopeManage), api.HandleUpdateMyScope)
+	group.POST("/scope/check", middleware.RequirePermission(rbac.ScopeRead), api.HandleCheckScope)
+	group.PUT("/admin/users/:id/scope", middleware.RequirePermission(rbac.ScopeManage), api.HandleUpdateUserScope)
+
+	call := func(role, method, path string, body interface{}) (int, []byte) {
+		token, err := auth.GenerateToken(user.ID, user.Username, role, time.Minute)
+		assert.NoError(t, err)
+		data, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code, w.Body.Bytes()
+	}
+	rules := api.ScopeRequest{Rules: []scope.Rule{{Action: scope.ActionInclude, Type: scope.TypeDomain, Value: "*.example.com"}}}
+	check := map[string]string{"targets": "https://app.example.com"}
+
+	// 未配置范围时拒绝所有目标
+	code, body := call(rbac.RoleUser, http.MethodPost, "/api/v1/scope/check", check)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, string(body), `"in_scope":false`)
+
+	// 普通用户不能自行设置范围
+	code, _ = call(rbac.RoleUser, http.MethodPut, "/api/v1/scope", rules)
+	assert.Equal(t, http.StatusForbidden, code)
+
+	code, _ = call(rbac.RoleAdmin, http.MethodPut, fmt.Sprintf("/api/v1/admin/users/%d/scope", user.ID), rules)
+	assert.Equal(t, http.StatusOK, code)
+	code, body = call(rbac.RoleUser, http.MethodPost, "/api/v1/scope/check", check)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, string(body), `"in_scope":true`)
+}
@@ -0,0 +1,224 @@
+package tracker
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"VulnFusion/internal/db"
+	"VulnFusion/internal/log"
+	"VulnFusion/internal/models"
+	"VulnFusion/internal/netguard"
+	"VulnFusion/internal/tracker"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testDBPath = "./testdata/test.db"
+
+func TestMain(m *testing.M) {
+	log.InitLogger("dev", "debug")
+	code := m.Run()
+	_ = os.RemoveAll("./testdata")
+	os.Exit(code)
+}
+
+func setupTestDB(t *testing.T) {
+	_ = os.RemoveAll("./testdata")
+	_, err := db.InitDatabase(testDBPath)
+	assert.NoError(t, err)
+}
+
+func newResult(t *testing.T, severity string) *models.Result {
+	task := &models.Task{UserID: 1, Target: "http://a", Template: "x", Status: models.StatusDone}
+	assert.NoError(t, models.CreateTask(task))
+	r := &models.Result{TaskID: task.ID, Target: "http://a/login", Host: "a", Vulnerability: "SQL 注入", Severity: severity, TemplateID: "sqli-error"}
+	assert.NoError(t, models.SaveScanResult(r))
+	return r
+}
+
+func TestJiraTicketDedupAndSync(t *testing.T) {
+	setupTestDB(t)
+
+	var creates int32
+	status := "To Do"
+	var fields map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, _ := r.BasicAuth()
+		assert.Equal(t, "bot@example.com", user)
+		assert.Equal(t, "tok", pass)
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/rest/api/2/issue":
+			atomic.AddInt32(&creates, 1)
+			var body struct {
+				Fields map[string]interface{} `json:"fields"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			fields = body.Fields
+			_, _ = w.Write([]byte(`{"id":"10001","key":"SEC-1"}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/rest/api/2/issue/SEC-1":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"key":    "SEC-1",
+				"fields": map[string]interface{}{"status": map[string]string{"name": status}},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	conn := &models.TrackerConnector{
+		UserID: 1, Name: "jira", Kind: models.TrackerJira, BaseURL: server.URL, Project: "SEC",
+		Username: "bot@example.com", Token: "tok", Enabled: true,
+		FieldMapping: `{"priority":{"critical":"Highest"},"labels":["appsec"],"extra":{"components":[{"name":"web"}]}}`,
+	}
+	assert.NoError(t, models.CreateTrackerConnector(conn))
+	r := newResult(t, "critical")
+
+	updated, created, err := tracker.CreateTicket(conn, r.ID, server.Client())
+	assert.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, "SEC-1", updated.TicketKey)
+	assert.Equal(t, server.URL+"/browse/SEC-1", updated.TicketURL)
+	assert.Equal(t, "To Do", updated.TicketStatus)
+
+	// 字段映射
+	assert.Equal(t, map[string]interface{}{"key": "SEC"}, fields["project"])
+	assert.Equal(t, map[string]interface{}{"name": "Highest"}, fields["priority"])
+	assert.Equal(t, []interface{}{"vulnfusion", "appsec"}, fields["labels"])
+	assert.NotNil(t, fields["components"])
+	assert.Contains(t, fields["summary"], "[CRITICAL] SQL 注入 - a")
+
+	// 一个结果只对应一个工单
+	_, created, err = tracker.CreateTicket(conn, r.ID, server.Client())
+	assert.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&creates))
+
+	// 工单状态未变化时不覆盖手工处置
+	assert.NoError(t, models.UpdateResultStatus(r.ID, models.ResultStatusFalsePositive))
+	synced, changed, err := tracker.SyncConnector(conn, server.Client())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, synced)
+	assert.Equal(t, 0, changed)
+
+	// 工单关闭后回写为已修复
+	status = "Done"
+	_, changed, err = tracker.SyncConnector(conn, server.Client())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, changed)
+	saved, _ := models.GetResultByID(r.ID)
+	assert.Equal(t, models.ResultStatusFixed, saved.Status)
+	assert.Equal(t, "Done", saved.TicketStatus)
+	assert.NotNil(t, saved.ClosedAt)
+}
+
+func TestGitHubTicketStatusMapping(t *testing.T) {
+	setupTestDB(t)
+
+	state := map[string]interface{}{"number": 42, "html_url": "https://github.example/acme/web/issues/42", "state": "open"}
+	var labels []interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer ghp", r.Header.Get("Authorization"))
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/repos/acme/web/issues":
+			var body map[string]interface{}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			labels, _ = body["labels"].([]interface{})
+			_ = json.NewEncoder(w).Encode(state)
+		case r.Method == http.MethodGet && r.URL.Path == "/repos/acme/web/issues/42":
+			_ = json.NewEncoder(w).Encode(state)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	conn := &models.TrackerConnector{
+		UserID: 1, Name: "gh", Kind: models.TrackerGitHub, BaseURL: server.URL, Project: "acme/web", Token: "ghp", Enabled: true,
+		FieldMapping: `{"priority":{"high":"P1"}}`,
+	}
+	assert.NoError(t, models.CreateTrackerConnector(conn))
+	r := newResult(t, "high")
+
+	updated, _, err := tracker.CreateTicket(conn, r.ID, server.Client())
+	assert.NoError(t, err)
+	assert.Equal(t, "42", updated.TicketKey)
+	assert.Equal(t, []interface{}{"vulnfusion", "P1"}, labels)
+
+	state["state"], state["state_reason"] = "closed", "not_planned"
+	changed, err := tracker.SyncResult(conn, updated, server.Client())
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, models.ResultStatusAccepted, updated.Status)
+
+	// 自定义映射优先于默认映射
+	conn.FieldMapping = `{"status":{"closed:not_planned":"false_positive"}}`
+	assert.Equal(t, models.ResultStatusFalsePositive, tracker.MapStatus(conn, "closed:not_planned"))
+	assert.Equal(t, models.ResultStatusFixed, tracker.MapStatus(conn, "closed"))
+}
+
+func TestTrackerBlocksInternalAddressesAndHidesResponse(t *testing.T) {
+	setupTestDB(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"errorMessages":["internal-secret-detail"]}`))
+	}))
+	defer server.Close()
+
+	conn := &models.TrackerConnector{UserID: 1, Name: "gitlab", Kind: models.TrackerGitLab, BaseURL: server.URL, Project: "1", Token: "tok", Enabled: true}
+	assert.NoError(t, models.CreateTrackerConnector(conn))
+	r := newResult(t, "high")
+
+	// 默认客户端拒绝连接回环地址
+	_, _, err := tracker.CreateTicket(conn, r.ID, nil)
+	assert.ErrorIs(t, err, netguard.ErrBlockedAddress)
+
+	// 错误信息不包含响应内容
+	_, _, err = tracker.CreateTicket(conn, r.ID, server.Client())
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "403")
+		assert.NotContains(t, err.Error(), "internal-secret-detail")
+	}
+}
+
+func TestTicketDedupPerFinding(t *testing.T) {
+	setupTestDB(t)
+
+	var creates int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&creates, 1)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"iid": n, "web_url": "https://git/issues/1", "state": "opened"})
+	}))
+	defer server.Close()
+
+	conn := &models.TrackerConnector{UserID: 1, Name: "gitlab", Kind: models.TrackerGitLab, BaseURL: server.URL, Project: "1", Token: "tok", Enabled: true}
+	assert.NoError(t, models.CreateTrackerConnector(conn))
+	first, second := newResult(t, "high"), newResult(t, "high")
+	assert.NoError(t, db.GetDB().Model(&models.Result{}).Where("id IN ?", []uint{first.ID, second.ID}).Update("asset_id", 9).Error)
+
+	// 同一结果并发创建只产生一个工单
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, err := tracker.CreateTicket(conn, first.ID, server.Client())
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&creates))
+
+	// 同一漏洞的其他结果关联已有工单
+	linked, created, err := tracker.CreateTicket(conn, second.ID, server.Client())
+	assert.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, "1", linked.TicketKey)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&creates))
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"VulnFusion/internal/auth"
+	"VulnFusion/internal/models"
+	"VulnFusion/internal/rbac"
+)
+
+// projectRole 返回用户在项目中的角色，非成员返回空字符串
+func projectRole(projectID, userID uint) string {
+	member, err := models.GetProjectMember(projectID, userID)
+	if err != nil {
+		return ""
+	}
+	return member.Role
+}
+
+// isProjectMember 判断用户在项目中的角色是否不低于 need
+func isProjectMember(claims *auth.CustomClaims, projectID uint, need string) bool {
+	return models.ProjectRoleRank(projectRole(projectID, claims.UserID)) >= models.ProjectRoleRank(need)
+}
+
+// canAny 按所需项目角色选择全局权限：只读访问接受 readAny 或 manageAny，其余只接受 manageAny
+func canAny(claims *auth.CustomClaims, need, readAny, manageAny string) bool {
+	if need == models.ProjectRoleViewer && rbac.Can(claims.Role, readAny) {
+		return true
+	}
+	return rbac.Can(claims.Role, manageAny)
+}
+
+// canAccessProject 判断用户在项目中的角色是否不低于 need，具备 project.read.any / project.manage.any 时始终允许
+func canAccessProject(claims *auth.CustomClaims, projectID uint, need string) bool {
+	if canAny(claims, need, rbac.ProjectReadAny, rbac.ProjectManageAny) {
+		return true
+	}
+	return isProjectMember(claims, projectID, need)
+}
+
+// canAccessTask 判断用户能否以 need 角色访问任务：
+// 个人任务仅创建人可访问，项目任务按项目成员角色判断，具备 task.read.any / task.manage.any 时始终允许
+func canAccessTask(claims *auth.CustomClaims, task *models.Task, need string) bool {
+	if canAny(claims, need, rbac.TaskReadAny, rbac.TaskManageAny) {
+		return true
+	}
+	if task.ProjectID == 0 {
+		return task.UserID == claims.UserID
+	}
+	return isProjectMember(claims, task.ProjectID, need)
+}
+
+// canReadResults 判断用户能否查看任务的扫描结果：可查看任务，或具备 result.read.any
+func canReadResults(claims *auth.CustomClaims, task *models.Task) bool {
+	return rbac.Can(claims.Role, rbac.ResultReadAny) || canAccessTask(claims, task, models.ProjectRoleViewer)
+}
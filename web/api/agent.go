@@ -0,0 +1,472 @@
+package api
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"VulnFusion/internal/auth"
+	"VulnFusion/internal/config"
+	"VulnFusion/internal/log"
+	"VulnFusion/internal/models"
+	"VulnFusion/internal/notify"
+	"VulnFusion/internal/provenance"
+	"VulnFusion/internal/scanner"
+	"VulnFusion/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleAgentRegister 远程扫描节点注册
+// @Summary 远程节点注册
+// @Description 节点使用注册令牌注册并登记结果签名公钥，返回节点 ID 与后续请求使用的节点令牌
+// @Tags Agent
+// @Accept json
+// @Produce json
+// @Param data body api.AgentRegisterRequest true "注册参数"
+// @Success 200 {object} map[string]interface{} "包含 agent_id 与 agent_token"
+// @Failure 400 {object} map[string]string "参数错误"
+// @Failure 401 {object} map[string]string "注册令牌错误"
+// @Failure 500 {object} map[string]string "服务器错误"
+// @Router /api/v1/agent/register [post]
+func HandleAgentRegister(ctx *gin.Context) {
+	var req AgentRegisterRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil || req.Name == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+	if err := provenance.ValidatePublicKey(req.PublicKey); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "节点签名公钥无效"})
+		return
+	}
+
+	expected := config.GetAgentRegistrationToken()
+	if expected == "" || subtle.ConstantTimeCompare([]byte(expected), []byte(req.RegistrationToken)) != 1 {
+		log.Warn("节点 %s 注册令牌校验失败，来源 %s", req.Name, ctx.ClientIP())
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "注册令牌错误"})
+		return
+	}
+
+	token, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		log.Error("生成节点令牌失败: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+		return
+	}
+
+	if req.Capacity <= 0 {
+		req.Capacity = 1
+	}
+	agent := &models.Agent{
+		Name:          req.Name,
+		TokenHash:     utils.SHA256Hex(token),
+		Labels:        strings.Join(req.Labels, ","),
+		Capacity:      req.Capacity,
+		LastHeartbeat: time.Now(),
+		PublicKey:     req.PublicKey,
+	}
+	if err := models.CreateAgent(agent); err != nil {
+		log.Error("节点注册失败: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "节点注册失败"})
+		return
+	}
+
+	log.Info("远程节点 %s（ID %d）注册成功，标签: %s", agent.Name, agent.ID, agent.Labels)
+	ctx.JSON(http.StatusOK, gin.H{"agent_id": agent.ID, "agent_token": token})
+}
+
+// HandleAgentHeartbeat 远程节点心跳
+// @Summary 远程节点心跳
+// @Description 节点定期上报运行中任务数与容量
+// @Tags Agent
+// @Accept json
+// @Produce json
+// @Param data body api.AgentHeartbeatRequest true "心跳参数"
+// @Success 200 {object} map[string]string "心跳成功"
+// @Failure 400 {object} map[string]string "参数错误"
+// @Router /api/v1/agent/heartbeat [post]
+func HandleAgentHeartbeat(ctx *gin.Context) {
+	agent := ctx.MustGet("agent").(*models.Agent)
+	var req AgentHeartbeatRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+
+	updates := map[string]interface{}{
+		"last_heartbeat": time.Now(),
+		"running":        req.Running,
+	}
+	if req.Capacity > 0 {
+		updates["capacity"] = req.Capacity
+	}
+	if req.Labels != nil {
+		updates["labels"] = strings.Join(req.Labels, ",")
+	}
+	if err := models.UpdateAgentByID(agent.ID, updates); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "心跳更新失败"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "ok"})
+}
+
+// HandleAgentPullTask 远程节点领取任务
+// @Summary 远程节点领取任务
+// @Description 领取一个与节点标签匹配的待执行任务，无任务时返回 204
+// @Tags Agent
+// @Produce json
+// @Success 200 {object} models.Task "领取到的任务"
+// @Success 204 "暂无任务"
+// @Failure 500 {object} map[string]string "服务器错误"
+// @Router /api/v1/agent/tasks/pull [post]
+func HandleAgentPullTask(ctx *gin.Context) {
+	agent := ctx.MustGet("agent").(*models.Agent)
+
+	task, err := models.ClaimPendingTask(agent.ID, agent.LabelList())
+	if err != nil {
+		log.Error("节点 %d 领取任务失败: %v", agent.ID, err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "领取任务失败"})
+		return
+	}
+	if task == nil {
+		ctx.Status(http.StatusNoContent)
+		return
+	}
+
+	_ = models.UpdateAgentByID(agent.ID, map[string]interface{}{"last_heartbeat": time.Now()})
+	log.Info("任务 %d 已由节点 %s（ID %d）领取", task.ID, agent.Name, agent.ID)
+	ctx.JSON(http.StatusOK, task)
+}
+
+// HandleAgentUploadResults 远程节点回传扫描结果
+// @Summary 远程节点回传结果
+// @Description 节点以签名批次的形式分批回传 nuclei JSONL 结果，服务端校验签名后入库
+// @Tags Agent
+// @Accept json
+// @Produce json
+// @Param id path int true "任务 ID"
+// @Param data body provenance.SignedBatch true "签名结果批次"
+// @Success 200 {object} map[string]interface{} "写入条数"
+// @Failure 400 {object} map[string]string "参数错误"
+// @Failure 403 {object} map[string]string "任务不属于该节点或签名无效"
+// @Failure 409 {object} map[string]string "批次重复"
+// @Router /api/v1/agent/tasks/{id}/results [post]
+func HandleAgentUploadResults(ctx *gin.Context) {
+	agent := ctx.MustGet("agent").(*models.Agent)
+	task, ok := loadAgentTask(ctx, agent)
+	if !ok {
+		return
+	}
+
+	var signed provenance.SignedBatch
+	if err := ctx.ShouldBindJSON(&signed); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+
+	saved, status, err := ingestSignedBatch(task, agent, signed, "agent")
+	if err != nil {
+		ctx.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"saved": saved})
+}
+
+// HandleImportSignedResults 导入离线签名的扫描结果
+// @Summary 导入签名结果批次
+// @Description 导入由已注册节点离线签名的结果批次，签名校验通过后写入指定任务
+// @Tags Result
+// @Accept json
+// @Produce json
+// @Param id path int true "任务 ID"
+// @Param data body provenance.SignedBatch true "签名结果批次"
+// @Success 200 {object} map[string]interface{} "写入条数"
+// @Failure 400 {object} map[string]string "参数错误"
+// @Failure 403 {object} map[string]string "无权限或签名无效"
+// @Failure 409 {object} map[string]string "批次重复"
+// @Security ApiKeyAuth
+// @Router /api/v1/tasks/{id}/import [post]
+func HandleImportSignedResults(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
+
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "ID 格式错误"})
+		return
+	}
+	task, err := models.GetTaskByID(uint(id))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+		return
+	}
+	if !canAccessTask(claims, task, models.ProjectRoleEditor) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "无权限访问此任务"})
+		return
+	}
+
+	var signed provenance.SignedBatch
+	if err := ctx.ShouldBindJSON(&signed); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+
+	batch, err := provenance.Decode(signed)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	agent, err := models.GetAgentByID(batch.AgentID)
+	if err != nil {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "签名节点未注册"})
+		return
+	}
+
+	saved, status, err := ingestSignedBatch(task, agent, signed, "import")
+	if err != nil {
+		ctx.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	log.Info("用户 %d 向任务 %d 导入节点 %d 签名的 %d 条结果", claims.UserID, task.ID, agent.ID, saved)
+	ctx.JSON(http.StatusOK, gin.H{"saved": saved})
+}
+
+// ingestSignedBatch 校验批次签名与归属，记录批次来源后写入扫描结果
+func ingestSignedBatch(task *models.Task, agent *models.Agent, signed provenance.SignedBatch, source string) (int, int, error) {
+	if agent.PublicKey == "" {
+		return 0, http.StatusForbidden, errors.New("节点未登记签名公钥")
+	}
+
+	batch, payload, err := provenance.Verify(signed, agent.PublicKey)
+	if err != nil {
+		log.Warn("节点 %d 回传的任务 %d 结果批次签名校验失败: %v", agent.ID, task.ID, err)
+		return 0, http.StatusForbidden, err
+	}
+	if batch.AgentID != agent.ID || batch.TaskID != task.ID {
+		return 0, http.StatusForbidden, errors.New("批次归属与节点或任务不符")
+	}
+
+	record := &models.ResultBatch{
+		TaskID:        task.ID,
+		AgentID:       agent.ID,
+		Sequence:      batch.Sequence,
+		Source:        source,
+		NucleiVersion: batch.NucleiVersion,
+		TemplateHash:  batch.TemplateHash,
+		PayloadHash:   provenance.PayloadHash(payload),
+		Payload:       signed.Payload,
+		Signature:     signed.Signature,
+		PublicKey:     agent.PublicKey,
+	}
+	if err := models.CreateResultBatch(record); err != nil {
+		log.Warn("任务 %d 结果批次 %d 重复提交: %v", task.ID, batch.Sequence, err)
+		return 0, http.StatusConflict, errors.New("结果批次重复")
+	}
+
+	if len(batch.Lines) == 0 {
+		return 0, http.StatusOK, nil
+	}
+	parsed, err := scanner.ParseNucleiResult([]byte(strings.Join(batch.Lines, "\n")))
+	if err != nil {
+		return 0, http.StatusBadRequest, errors.New("结果解析失败")
+	}
+
+	saved := scanner.SaveFindings(task, parsed, scanner.Provenance{
+		AgentID:       agent.ID,
+		BatchID:       record.ID,
+		NucleiVersion: batch.NucleiVersion,
+		TemplateHash:  batch.TemplateHash,
+	})
+	return saved, http.StatusOK, nil
+}
+
+// HandleAgentUpdateTask 远程节点上报任务状态
+// @Summary 远程节点上报任务状态
+// @Description 节点在任务结束时上报 done / failed，以及自动模式下的技术栈识别结果
+// @Tags Agent
+// @Accept json
+// @Produce json
+// @Param id path int true "任务 ID"
+// @Param data body api.AgentTaskUpdateRequest true "状态参数"
+// @Success 200 {object} map[string]string "更新成功"
+// @Failure 400 {object} map[string]string "参数错误"
+// @Failure 403 {object} map[string]string "任务不属于该节点"
+// @Failure 409 {object} map[string]string "任务已结束"
+// @Router /api/v1/agent/tasks/{id}/status [post]
+func HandleAgentUpdateTask(ctx *gin.Context) {
+	agent := ctx.MustGet("agent").(*models.Agent)
+	task, ok := loadAgentTask(ctx, agent)
+	if !ok {
+		return
+	}
+
+	var req AgentTaskUpdateRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+	if req.Status != models.StatusDone && req.Status != models.StatusFailed && req.Status != models.StatusRunning {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "不支持的任务状态"})
+		return
+	}
+
+	updates := map[string]interface{}{"status": req.Status}
+	if req.DetectedTech != "" {
+		updates["detected_tech"] = req.DetectedTech
+	}
+	if req.SelectedTags != "" {
+		updates["selected_tags"] = req.SelectedTags
+		task.SelectedTags = req.SelectedTags
+	}
+	// 已结束的任务不允许再变更状态
+	updated, err := models.UpdateUnfinishedTask(task.ID, updates)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "更新失败"})
+		return
+	}
+	if !updated {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "任务已结束，不能再变更状态"})
+		return
+	}
+
+	if req.Error != "" {
+		log.Warn("节点 %d 报告任务 %d 失败: %s", agent.ID, task.ID, req.Error)
+	}
+	if req.Status == models.StatusDone {
+		scanner.CloseMissingFindings(task)
+	}
+	if req.Status == models.StatusDone || req.Status == models.StatusFailed {
+		publishTaskFinished(task, req.Status)
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "任务状态已更新"})
+}
+
+// publishTaskFinished 发布远程任务完成/失败事件
+func publishTaskFinished(task *models.Task, status string) {
+	findings, err := models.CountResultsByTaskID(task.ID)
+	if err != nil {
+		log.Warn("统计任务 %d 结果失败: %v", task.ID, err)
+	}
+	task.Status = status
+	eventType := notify.EventTaskDone
+	if status == models.StatusFailed {
+		eventType = notify.EventTaskFailed
+	}
+	notify.Publish(notify.NewTaskEvent(eventType, task, int(findings)))
+}
+
+// HandleListAgents 管理员查看远程节点
+// @Summary 获取远程节点列表（管理员）
+// @Description 返回所有节点的标签、容量、运行任务数与在线状态
+// @Tags Admin
+// @Produce json
+// @Success 200 {array} map[string]interface{} "节点列表"
+// @Failure 500 {object} map[string]string "获取失败"
+// @Security ApiKeyAuth
+// @Router /api/v1/admin/agents [get]
+func HandleListAgents(ctx *gin.Context) {
+	agents, err := models.ListAgents()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "获取节点失败"})
+		return
+	}
+
+	now := time.Now()
+	timeout := config.AgentHeartbeatTimeout()
+	list := make([]gin.H, 0, len(agents))
+	for i := range agents {
+		a := &agents[i]
+		list = append(list, gin.H{
+			"id":             a.ID,
+			"name":           a.Name,
+			"labels":         a.LabelList(),
+			"capacity":       a.Capacity,
+			"running":        a.Running,
+			"last_heartbeat": a.LastHeartbeat,
+			"status":         a.StatusAt(now, timeout),
+			"created_at":     a.CreatedAt,
+		})
+	}
+	ctx.JSON(http.StatusOK, list)
+}
+
+// HandleDeleteAgent 管理员删除远程节点
+// @Summary 删除远程节点（管理员）
+// @Description 删除节点后其令牌立即失效
+// @Tags Admin
+// @Produce json
+// @Param id path int true "节点 ID"
+// @Success 200 {object} map[string]string "删除成功"
+// @Failure 400 {object} map[string]string "参数错误"
+// @Failure 500 {object} map[string]string "删除失败"
+// @Security ApiKeyAuth
+// @Router /api/v1/admin/agents/{id} [delete]
+func HandleDeleteAgent(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || id <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "无效的节点 ID"})
+		return
+	}
+	if err := models.DeleteAgentByID(uint(id)); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "删除失败"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "删除成功"})
+}
+
+// HandleUpdateAgentKey 管理员登记或轮换节点签名公钥
+// @Summary 更新节点签名公钥（管理员）
+// @Description 登记或轮换节点用于结果签名的 ed25519 公钥
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param id path int true "节点 ID"
+// @Param body body object{public_key=string} true "base64 编码的 ed25519 公钥"
+// @Success 200 {object} map[string]string "更新成功"
+// @Failure 400 {object} map[string]string "参数错误"
+// @Failure 500 {object} map[string]string "更新失败"
+// @Security ApiKeyAuth
+// @Router /api/v1/admin/agents/{id}/key [put]
+func HandleUpdateAgentKey(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || id <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "无效的节点 ID"})
+		return
+	}
+
+	var body struct {
+		PublicKey string `json:"public_key"`
+	}
+	if err := ctx.ShouldBindJSON(&body); err != nil || provenance.ValidatePublicKey(body.PublicKey) != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "节点签名公钥无效"})
+		return
+	}
+
+	if err := models.UpdateAgentByID(uint(id), map[string]interface{}{"public_key": body.PublicKey}); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "更新失败"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "更新成功"})
+}
+
+// loadAgentTask 读取路径中的任务并校验其由当前节点领取
+func loadAgentTask(ctx *gin.Context, agent *models.Agent) (*models.Task, bool) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "ID 格式错误"})
+		return nil, false
+	}
+
+	task, err := models.GetTaskByID(uint(id))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+		return nil, false
+	}
+	if task.AgentID != agent.ID {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "任务不属于该节点"})
+		return nil, false
+	}
+	return task, true
+}
@@ -0,0 +1,162 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"VulnFusion/internal/auth"
+	"VulnFusion/internal/log"
+	"VulnFusion/internal/models"
+	"VulnFusion/internal/rbac"
+	"VulnFusion/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxAPITokenDays API 令牌最长有效天数
+const maxAPITokenDays = 3650
+
+// HandleCreateAPIToken 创建个人 API 令牌
+// @Summary 创建 API 令牌
+// @Description 创建带权限范围的长期令牌，供 CI/CD 等自动化场景以 Authorization: Bearer vf_... 调用接口；令牌明文仅在创建时返回一次。令牌管理接口本身不接受 API 令牌
+// @Tags Token
+// @Accept json
+// @Produce json
+// @Param data body api.CreateAPITokenRequest true "令牌信息"
+// @Success 200 {object} map[string]interface{} "令牌明文与令牌信息"
+// @Failure 400 {object} map[string]string "参数错误"
+// @Failure 500 {object} map[string]string "创建失败"
+// @Security ApiKeyAuth
+// @Router /api/v1/tokens [post]
+func HandleCreateAPIToken(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
+
+	var req CreateAPITokenRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Name) == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "令牌名称不能为空"})
+		return
+	}
+	if len(req.Scopes) == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "请至少选择一个权限范围"})
+		return
+	}
+	for _, scope := range req.Scopes {
+		if !auth.IsValidScope(scope) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "不支持的权限范围: " + scope})
+			return
+		}
+		if strings.HasPrefix(scope, "admin:") && !rbac.HasAdminAccess(claims.Role) {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "当前角色无管理权限，不能授予 admin 权限范围"})
+			return
+		}
+	}
+	if req.ExpiresInDays < 0 || req.ExpiresInDays > maxAPITokenDays {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("有效天数应在 0~%d 之间", maxAPITokenDays)})
+		return
+	}
+
+	secret, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "生成令牌失败"})
+		return
+	}
+	raw := auth.APITokenPrefix + secret
+
+	token := &models.APIToken{
+		UserID:    claims.UserID,
+		Name:      strings.TrimSpace(req.Name),
+		Prefix:    raw[:len(auth.APITokenPrefix)+8],
+		TokenHash: utils.SHA256Hex(raw),
+		Scopes:    strings.Join(req.Scopes, " "),
+	}
+	if req.ExpiresInDays > 0 {
+		expires := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		token.ExpiresAt = &expires
+	}
+	if err := models.CreateAPIToken(token); err != nil {
+		log.Error("创建 API 令牌失败: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "创建令牌失败"})
+		return
+	}
+
+	recordAudit(ctx, models.AuditTokenCreate, fmt.Sprintf("token:%d", token.ID), token.Name+" ["+token.Scopes+"]")
+	ctx.JSON(http.StatusOK, gin.H{"token": raw, "info": token})
+}
+
+// HandleListAPITokens 获取个人 API 令牌
+// @Summary 获取 API 令牌列表
+// @Description 返回当前用户的 API 令牌（不含明文），包括权限范围、过期时间与最近使用记录
+// @Tags Token
+// @Produce json
+// @Success 200 {array} models.APIToken "令牌列表"
+// @Failure 500 {object} map[string]string "获取失败"
+// @Security ApiKeyAuth
+// @Router /api/v1/tokens [get]
+func HandleListAPITokens(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
+
+	tokens, err := models.ListAPITokensByUserID(claims.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "获取令牌失败"})
+		return
+	}
+	ctx.JSON(http.StatusOK, tokens)
+}
+
+// HandleRevokeAPIToken 吊销 API 令牌
+// @Summary 吊销 API 令牌
+// @Description 立即吊销令牌，仅令牌所属用户或管理员可操作
+// @Tags Token
+// @Produce json
+// @Param id path int true "令牌 ID"
+// @Success 200 {object} map[string]string "吊销成功"
+// @Failure 403 {object} map[string]string "无权限"
+// @Failure 404 {object} map[string]string "令牌不存在"
+// @Security ApiKeyAuth
+// @Router /api/v1/tokens/{id} [delete]
+func HandleRevokeAPIToken(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
+
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "令牌 ID 格式错误"})
+		return
+	}
+	token, err := models.GetAPITokenByID(uint(id))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "令牌不存在"})
+		return
+	}
+	if token.UserID != claims.UserID && !rbac.Can(claims.Role, rbac.TokenManageAny) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "无权限吊销此令牌"})
+		return
+	}
+
+	if err := models.RevokeAPIToken(token.ID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "吊销令牌失败"})
+		return
+	}
+	recordAudit(ctx, models.AuditTokenRevoke, fmt.Sprintf("token:%d", token.ID), token.Name)
+	ctx.JSON(http.StatusOK, gin.H{"message": "令牌已吊销"})
+}
+
+// HandleListAllAPITokens 管理员查看全部 API 令牌
+// @Summary 获取全部 API 令牌（管理员）
+// @Description 返回所有用户的 API 令牌（不含明文），用于排查与清理
+// @Tags Admin
+// @Produce json
+// @Success 200 {array} models.APIToken "令牌列表"
+// @Failure 500 {object} map[string]string "获取失败"
+// @Security ApiKeyAuth
+// @Router /api/v1/admin/tokens [get]
+func HandleListAllAPITokens(ctx *gin.Context) {
+	tokens, err := models.ListAllAPITokens()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "获取令牌失败"})
+		return
+	}
+	ctx.JSON(http.StatusOK, tokens)
+}
@@ -0,0 +1,239 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"VulnFusion/internal/auth"
+	"VulnFusion/internal/log"
+	"VulnFusion/internal/models"
+	"VulnFusion/internal/rbac"
+	"VulnFusion/internal/risk"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleListAssets 获取资产列表
+// @Summary 获取资产列表
+// @Description 返回当前用户的个人资产及其所在项目的资产（管理员返回全部），可按 project_id 过滤
+// @Tags Asset
+// @Produce json
+// @Param project_id query int false "项目 ID"
+// @Success 200 {array} models.Asset "资产列表"
+// @Failure 400 {object} map[string]string "参数错误"
+// @Failure 500 {object} map[string]string "获取失败"
+// @Security ApiKeyAuth
+// @Router /api/v1/assets [get]
+func HandleListAssets(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
+	projectID, err := strconv.ParseUint(ctx.DefaultQuery("project_id", "0"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "项目 ID 格式错误"})
+		return
+	}
+
+	var assets []models.Asset
+	if rbac.CanAny(claims.Role, rbac.AssetReadAny, rbac.AssetManageAny) {
+		assets, err = models.ListAllAssets(uint(projectID))
+	} else {
+		assets, err = models.ListVisibleAssets(claims.UserID, uint(projectID))
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "获取资产失败"})
+		return
+	}
+	ctx.JSON(http.StatusOK, assets)
+}
+
+// HandleGetAsset 获取资产详情
+// @Summary 获取资产详情
+// @Description 根据资产 ID 返回资产信息
+// @Tags Asset
+// @Produce json
+// @Param id path int true "资产 ID"
+// @Success 200 {object} models.Asset "资产信息"
+// @Failure 403 {object} map[string]string "无权限访问"
+// @Failure 404 {object} map[string]string "资产不存在"
+// @Security ApiKeyAuth
+// @Router /api/v1/assets/{id} [get]
+func HandleGetAsset(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
+	asset, ok := loadAsset(ctx, claims, models.ProjectRoleViewer)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, asset)
+}
+
+// HandleUpdateAsset 更新资产负责人、标签与重要性
+// @Summary 更新资产
+// @Description 维护资产的负责人、标签与重要性等级，需 editor 及以上权限；重要性变化后重新计算该资产下结果的风险分
+// @Tags Asset
+// @Accept json
+// @Produce json
+// @Param id path int true "资产 ID"
+// @Param data body api.UpdateAssetRequest true "资产信息"
+// @Success 200 {object} map[string]string "更新成功"
+// @Failure 400 {object} map[string]string "参数错误"
+// @Failure 403 {object} map[string]string "无权限"
+// @Failure 404 {object} map[string]string "资产不存在"
+// @Security ApiKeyAuth
+// @Router /api/v1/assets/{id} [put]
+func HandleUpdateAsset(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
+	asset, ok := loadAsset(ctx, claims, models.ProjectRoleEditor)
+	if !ok {
+		return
+	}
+
+	var req UpdateAssetRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+	if req.Criticality == "" {
+		req.Criticality = asset.Criticality
+	}
+	if !models.IsValidCriticality(req.Criticality) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "不支持的重要性等级"})
+		return
+	}
+
+	if err := models.UpdateAssetByID(asset.ID, map[string]interface{}{
+		"owner":       strings.TrimSpace(req.Owner),
+		"tags":        strings.Join(splitCSV(req.Tags), ","),
+		"criticality": req.Criticality,
+	}); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "更新资产失败"})
+		return
+	}
+	if req.Criticality != asset.Criticality {
+		if err := risk.RecalculateAsset(asset.ID); err != nil {
+			log.Error("重新计算资产 %d 风险分失败: %v", asset.ID, err)
+		}
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "资产已更新"})
+}
+
+// HandleListAssetFindings 获取资产的未处置漏洞
+// @Summary 获取资产漏洞
+// @Description 返回资产下所有处置状态为 open 的扫描结果
+// @Tags Asset
+// @Produce json
+// @Param id path int true "资产 ID"
+// @Success 200 {array} models.Result "未处置的扫描结果"
+// @Failure 403 {object} map[string]string "无权限访问"
+// @Failure 404 {object} map[string]string "资产不存在"
+// @Security ApiKeyAuth
+// @Router /api/v1/assets/{id}/findings [get]
+func HandleListAssetFindings(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
+	asset, ok := loadAsset(ctx, claims, models.ProjectRoleViewer)
+	if !ok {
+		return
+	}
+
+	results, err := models.ListOpenResultsByAssetID(asset.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "获取资产漏洞失败"})
+		return
+	}
+	ctx.JSON(http.StatusOK, results)
+}
+
+// HandleScanAssets 对选中资产发起扫描任务
+// @Summary 扫描选中资产
+// @Description 以选中资产已发现的服务地址（保留协议与端口，无记录时为主机名）为目标创建扫描任务；资产须属于同一项目（或均为个人资产），目标仍需通过范围检查
+// @Tags Asset
+// @Accept json
+// @Produce json
+// @Param data body api.ScanAssetsRequest true "资产与扫描参数"
+// @Success 200 {object} map[string]interface{} "任务创建成功，返回任务 ID"
+// @Failure 400 {object} map[string]string "参数错误"
+// @Failure 403 {object} map[string]string "无权限"
+// @Security ApiKeyAuth
+// @Router /api/v1/assets/scan [post]
+func HandleScanAssets(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
+
+	var req ScanAssetsRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil || len(req.AssetIDs) == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "请选择要扫描的资产"})
+		return
+	}
+
+	assets, err := models.ListAssetsByIDs(req.AssetIDs)
+	if err != nil || len(assets) == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "资产不存在"})
+		return
+	}
+
+	projectID := assets[0].ProjectID
+	targets := make([]string, 0, len(assets))
+	for i := range assets {
+		if assets[i].ProjectID != projectID {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "所选资产不属于同一项目"})
+			return
+		}
+		if !canAccessAsset(claims, &assets[i], models.ProjectRoleEditor) {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "无权限扫描所选资产"})
+			return
+		}
+		targets = append(targets, assets[i].ScanTargets()...)
+	}
+
+	task, ok := createTask(ctx, claims, CreateTaskRequest{
+		Target:     strings.Join(targets, ","),
+		Template:   req.Template,
+		Mode:       req.Mode,
+		AgentLabel: req.AgentLabel,
+		ProjectID:  projectID,
+	})
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "任务创建成功", "task_id": task.ID})
+}
+
+// canAccessAsset 判断用户能否以 need 角色访问资产，规则与任务一致
+func canAccessAsset(claims *auth.CustomClaims, asset *models.Asset, need string) bool {
+	if canAny(claims, need, rbac.AssetReadAny, rbac.AssetManageAny) {
+		return true
+	}
+	if asset.ProjectID == 0 {
+		return asset.UserID == claims.UserID
+	}
+	return isProjectMember(claims, asset.ProjectID, need)
+}
+
+// loadAsset 读取路径中的资产并校验权限；ok 为 false 时已写入错误响应
+func loadAsset(ctx *gin.Context, claims *auth.CustomClaims, need string) (*models.Asset, bool) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "资产 ID 格式错误"})
+		return nil, false
+	}
+
+	asset, err := models.GetAssetByID(uint(id))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "资产不存在"})
+		return nil, false
+	}
+	if !canAccessAsset(claims, asset, need) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "无权限访问此资产"})
+		return nil, false
+	}
+	return asset, true
+}
+
+// splitCSV 拆分逗号分隔的字符串并去除空白项
+func splitCSV(s string) []string {
+	var items []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			items = append(items, v)
+		}
+	}
+	return items
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"VulnFusion/internal/auth"
+	"VulnFusion/internal/log"
+	"VulnFusion/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// recordAudit 记录一条审计事件，操作人取自当前请求的 claims（如存在）
+func recordAudit(ctx *gin.Context, action, resource, detail string) {
+	entry := &models.AuditLog{
+		Action:   action,
+		Resource: resource,
+		Detail:   detail,
+		IP:       ctx.ClientIP(),
+	}
+	if v, ok := ctx.Get("claims"); ok {
+		if claims, ok := v.(*auth.CustomClaims); ok {
+			entry.UserID = claims.UserID
+			entry.Username = claims.Username
+		}
+	}
+	if err := models.RecordAudit(entry); err != nil {
+		log.Error("写入审计日志失败: %v", err)
+	}
+}
+
+// HandleListAuditLogs 管理员查看审计日志
+// @Summary 获取审计日志（管理员）
+// @Description 按时间倒序返回审计事件，可按事件类型过滤
+// @Tags Admin
+// @Produce json
+// @Param action query string false "事件类型，如 scope.reject"
+// @Param limit query int false "返回条数，默认 200"
+// @Success 200 {array} models.AuditLog "审计日志"
+// @Failure 500 {object} map[string]string "获取失败"
+// @Security ApiKeyAuth
+// @Router /api/v1/admin/audit [get]
+func HandleListAuditLogs(ctx *gin.Context) {
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "200"))
+	if err != nil || limit <= 0 {
+		limit = 200
+	}
+
+	logs, err := models.ListAuditLogs(ctx.Query("action"), limit)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "获取审计日志失败"})
+		return
+	}
+	ctx.JSON(http.StatusOK, logs)
+}
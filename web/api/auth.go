@@ -1,41 +1,67 @@
 package api
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
-	"strconv"
+	"strings"
 	"time"
 
 	"VulnFusion/internal/auth"
+	"VulnFusion/internal/config"
 	"VulnFusion/internal/log"
 	"VulnFusion/internal/models"
+	"VulnFusion/internal/rbac"
 	"VulnFusion/internal/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
+// weakPasswordMessage 密码不符合复杂度要求时的提示
+const weakPasswordMessage = "密码长度至少 8 位，且需同时包含字母和数字"
+
 // HandleRegister 用户注册
 // @Summary 用户注册
-// @Description 使用用户名、密码和角色注册新用户
+// @Description 按 auth.registration.mode 自助注册：disabled 关闭注册；invite 需提供有效邀请码；approval 注册后需管理员审批才能登录。注册用户始终为 viewer 角色
 // @Tags Auth
 // @Accept json
 // @Produce json
 // @Param data body api.RegisterRequest true "注册参数"
-// @Success 200 {object} map[string]string "注册成功"
-// @Failure 400 {object} map[string]string "参数错误"
+// @Success 200 {object} map[string]interface{} "注册成功，pending 为 true 时需等待审批"
+// @Failure 400 {object} map[string]string "参数错误、密码强度不足或邀请码无效"
+// @Failure 403 {object} map[string]string "未开放注册或已启用目录认证"
 // @Failure 500 {object} map[string]string "服务器错误或用户名已存在"
 // @Router /api/v1/auth/register [post]
 func HandleRegister(ctx *gin.Context) {
-	var req struct {
-		Username string `json:"username"`
-		Password string `json:"password"`
-		Role     string `json:"role"`
+	if config.LDAPEnabled() {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "已启用目录认证，请使用企业账号登录"})
+		return
+	}
+	mode := config.RegistrationMode()
+	if mode == config.RegistrationDisabled {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "系统未开放注册，请联系管理员创建账号"})
+		return
 	}
 
+	var req RegisterRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		log.Warn("注册参数绑定失败: %v", err)
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
 		return
 	}
+	req.Username = strings.TrimSpace(req.Username)
+	if req.Username == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "用户名不能为空"})
+		return
+	}
+	if !utils.IsValidPassword(req.Password) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": weakPasswordMessage})
+		return
+	}
+	if mode == config.RegistrationInvite && strings.TrimSpace(req.InviteCode) == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "请填写邀请码"})
+		return
+	}
 
 	hashed, err := utils.HashPassword(req.Password)
 	if err != nil {
@@ -44,25 +70,41 @@ func HandleRegister(ctx *gin.Context) {
 		return
 	}
 
+	// 自助注册只能获得最低权限角色，忽略请求中的任何角色信息
 	user := &models.User{
 		Username: req.Username,
 		Password: hashed,
-		Role:     req.Role,
+		Role:     rbac.RoleViewer,
 	}
 
-	err = models.CreateUser(user)
+	if mode == config.RegistrationInvite {
+		err = models.CreateUserWithInvite(user, utils.SHA256Hex(strings.TrimSpace(req.InviteCode)))
+	} else {
+		user.Status = models.UserStatusPending
+		err = models.CreateUser(user)
+	}
+	if errors.Is(err, models.ErrInvalidInvite) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		log.Error("创建用户失败: %v", err)
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "用户名已存在或创建失败"})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"message": "注册成功"})
+	pending := user.Status == models.UserStatusPending
+	recordAudit(ctx, models.AuditUserRegister, fmt.Sprintf("user:%d", user.ID), user.Username+" 通过 "+mode+" 模式注册")
+	if pending {
+		ctx.JSON(http.StatusOK, gin.H{"message": "注册成功，请等待管理员审批", "pending": true})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "注册成功", "pending": false})
 }
 
 // HandleLogin 用户登录
 // @Summary 用户登录
-// @Description 使用用户名和密码进行登录，返回访问令牌与刷新令牌
+// @Description 使用用户名和密码进行登录（auth.backend 为 ldap 时由目录验证密码并同步角色），返回访问令牌与刷新令牌；已启用或所属角色强制启用二次验证时返回 mfa_required 与 mfa_token，需调用 /auth/login/mfa 完成登录
 // @Tags Auth
 // @Accept json
 // @Produce json
@@ -70,7 +112,11 @@ func HandleRegister(ctx *gin.Context) {
 // @Success 200 {object} map[string]string "包含 access_token 和 refresh_token"
 // @Failure 400 {object} map[string]string "参数错误"
 // @Failure 401 {object} map[string]string "用户名或密码错误"
+// @Failure 403 {object} map[string]string "目录账号未被授权访问或账号待审批"
+// @Failure 423 {object} map[string]string "连续失败次数过多，账号已被临时锁定"
+// @Failure 429 {object} map[string]string "登录尝试过于频繁，Retry-After 为需等待的秒数"
 // @Failure 500 {object} map[string]string "服务器错误"
+// @Failure 503 {object} map[string]string "目录服务不可用"
 // @Router /api/v1/auth/login [post]
 func HandleLogin(ctx *gin.Context) {
 	var req struct {
@@ -84,84 +130,184 @@ func HandleLogin(ctx *gin.Context) {
 		return
 	}
 
-	user, err := models.GetUserByUsername(req.Username)
+	// 同名本地用户用于恢复锁定状态与记录失败次数，查询失败时按用户不存在处理
+	local, err := models.GetUserByUsername(req.Username)
 	if err != nil {
-		log.Warn("用户不存在: %v", err)
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "用户名或密码错误"})
+		local = nil
+	}
+	if !checkLoginAllowed(ctx, req.Username, local) {
 		return
 	}
+	defer releaseLoginAttempt(ctx, req.Username)
+
+	var user *models.User
+	if config.LDAPEnabled() && !config.LDAPLocalUser(req.Username) {
+		// 启用目录认证后，除应急账号外均由目录验证密码
+		var ok bool
+		if user, ok = loginWithLDAP(ctx, req.Username, req.Password, local); !ok {
+			return
+		}
+	} else {
+		if local == nil {
+			// 用户不存在时同样比较一次密码哈希，避免通过响应时间判断用户是否存在
+			utils.CheckPassword(req.Password, dummyPasswordHash())
+			recordLoginFailure(ctx, req.Username, nil)
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "用户名或密码错误"})
+			return
+		}
+		if !utils.CheckPassword(req.Password, local.Password) {
+			recordLoginFailure(ctx, req.Username, local)
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "用户名或密码错误"})
+			return
+		}
+		user = local
+	}
 
-	if !utils.CheckPassword(req.Password, user.Password) {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "用户名或密码错误"})
+	if !userActive(ctx, user) {
 		return
 	}
 
-	token, err := auth.GenerateToken(uint(int(user.ID)), user.Username, user.Role, 15*time.Minute)
-	if err != nil {
-		log.Error("生成 Token 失败: %v", err)
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "生成 token 失败"})
+	// 已启用或被强制启用二次验证时，先返回挑战令牌，验证码通过后再签发令牌并清除失败记录
+	if user.TOTPEnabled || config.MFARequired(user.Role) {
+		respondMFAChallenge(ctx, user)
 		return
 	}
+	recordLoginSuccess(req.Username, user)
 
-	refreshToken, err := auth.GenerateRefreshToken(uint(user.ID), 7*24*time.Hour)
+	if resp, ok := completeLogin(ctx, user); ok {
+		ctx.JSON(http.StatusOK, resp)
+	}
+}
+
+// userActive 判断账号能否登录，待审批账号写入 403 响应并返回 false
+func userActive(ctx *gin.Context, user *models.User) bool {
+	if user.Status == models.UserStatusPending {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "账号正在等待管理员审批"})
+		return false
+	}
+	return true
+}
+
+// completeLogin 身份验证全部通过后签发令牌并记录会话；ok 为 false 时已写入错误响应
+func completeLogin(ctx *gin.Context, user *models.User) (gin.H, bool) {
+	// 单点登录可能关联到待审批的本地账号，签发令牌前统一检查
+	if !userActive(ctx, user) {
+		return nil, false
+	}
+	// 同一次登录签发的访问令牌与刷新令牌属于同一令牌族，可整体吊销
+	family := auth.NewFamilyID()
+	token, refreshToken, err := issueTokenPair(user, family, "")
 	if err != nil {
-		log.Error("生成刷新 Token 失败: %v", err)
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "生成 refresh token 失败"})
-		return
+		log.Error("生成 Token 失败: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "生成 token 失败"})
+		return nil, false
 	}
+	startSession(ctx, user.ID, family)
 
-	ctx.JSON(http.StatusOK, gin.H{
+	return gin.H{
 		"token":         token,
 		"refresh_token": refreshToken,
-	})
+	}, true
+}
+
+// issueTokenPair 签发同一令牌族的访问令牌与刷新令牌，并在服务端保存刷新令牌；parentJTI 为被轮换的刷新令牌
+func issueTokenPair(user *models.User, family, parentJTI string) (string, string, error) {
+	token, err := auth.GenerateFamilyToken(user.ID, user.Username, user.Role, family, config.DefaultTokenTTL())
+	if err != nil {
+		return "", "", err
+	}
+	refreshToken, claims, err := auth.GenerateFamilyRefreshToken(user.ID, family, config.DefaultRefreshTokenTTL())
+	if err != nil {
+		return "", "", err
+	}
+	if err := models.CreateRefreshToken(&models.RefreshToken{
+		UserID:    user.ID,
+		Family:    family,
+		JTI:       claims.ID,
+		ParentJTI: parentJTI,
+		ExpiresAt: claims.ExpiresAt.Time,
+	}); err != nil {
+		return "", "", err
+	}
+	return token, refreshToken, nil
 }
 
 // HandleRefreshToken 刷新 JWT Token
 // @Summary 刷新 JWT Token
-// @Description 使用 Refresh Token 获取新的访问令牌
+// @Description 使用 Refresh Token 换取新的访问令牌与刷新令牌，旧刷新令牌随即作废；已作废的刷新令牌再次使用视为泄露，同一次登录的全部令牌将被吊销
 // @Tags Auth
 // @Accept json
 // @Produce json
 // @Param data body api.RefreshRequest true "刷新令牌参数"
-// @Success 200 {object} map[string]string "新的访问令牌"
+// @Success 200 {object} map[string]string "新的访问令牌与刷新令牌"
 // @Failure 400 {object} map[string]string "参数错误"
 // @Failure 401 {object} map[string]string "无效或过期的刷新令牌"
 // @Failure 500 {object} map[string]string "服务器错误"
 // @Router /api/v1/auth/refresh [post]
 func HandleRefreshToken(ctx *gin.Context) {
-	var req struct {
-		RefreshToken string `json:"refresh_token"`
-	}
+	var req RefreshRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
 		return
 	}
 
-	claims, err := auth.ParseToken(req.RefreshToken)
+	claims, err := auth.ParseRefreshToken(req.RefreshToken)
 	if err != nil {
 		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "无效或已过期的刷新令牌"})
 		return
 	}
+	stored, err := models.GetRefreshTokenByJTI(claims.ID)
+	if err != nil || stored.UserID != claims.UserID || stored.RevokedAt != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "无效或已过期的刷新令牌"})
+		return
+	}
 
-	userID, _ := strconv.Atoi(claims.Subject)
-	user, err := models.GetUserByID(uint(userID))
+	rotated, err := models.MarkRefreshTokenRotated(stored.ID)
+	if err != nil {
+		log.Error("轮换刷新令牌失败: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "刷新失败"})
+		return
+	}
+	if !rotated {
+		revokeFamilyOnReuse(ctx, stored)
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "刷新令牌已被使用，请重新登录"})
+		return
+	}
+
+	user, err := models.GetUserByID(claims.UserID)
 	if err != nil {
 		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "用户不存在"})
 		return
 	}
 
-	token, err := auth.GenerateToken(uint(user.ID), user.Username, user.Role, 15*time.Minute)
+	token, refreshToken, err := issueTokenPair(user, stored.Family, stored.JTI)
 	if err != nil {
+		log.Error("生成 Token 失败: %v", err)
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "生成 token 失败"})
 		return
 	}
+	if err := models.TouchSession(stored.Family, ctx.ClientIP(), time.Now().Add(config.DefaultRefreshTokenTTL())); err != nil {
+		log.Warn("更新会话失败: %v", err)
+	}
 
-	ctx.JSON(http.StatusOK, gin.H{"token": token})
+	ctx.JSON(http.StatusOK, gin.H{
+		"token":         token,
+		"refresh_token": refreshToken,
+	})
+}
+
+// revokeFamilyOnReuse 已轮换的刷新令牌被再次使用，说明令牌可能已泄露，吊销整个令牌族
+func revokeFamilyOnReuse(ctx *gin.Context, stored *models.RefreshToken) {
+	log.Warn("用户 %d 的刷新令牌 %s 被重复使用，吊销令牌族 %s", stored.UserID, stored.JTI, stored.Family)
+	if err := revokeSessionFamily(stored.Family); err != nil {
+		log.Error("吊销令牌族失败: %v", err)
+	}
+	recordAudit(ctx, models.AuditRefreshReuse, fmt.Sprintf("user:%d", stored.UserID), "已轮换的刷新令牌被再次使用，已吊销该次登录的全部令牌")
 }
 
 // HandleLogout 用户注销登录
 // @Summary 用户注销登录
-// @Description 将当前访问令牌加入黑名单，防止后续使用
+// @Description 吊销当前访问令牌及同一次登录签发的刷新令牌
 // @Tags Auth
 // @Produce json
 // @Success 200 {object} map[string]string "注销成功"
@@ -182,14 +328,46 @@ func HandleLogout(ctx *gin.Context) {
 	}
 
 	exp := time.Until(customClaims.ExpiresAt.Time)
-	auth.AddTokenToBlacklist(customClaims.ID, exp)
+	if err := auth.AddTokenToBlacklist(customClaims.ID, exp); err != nil {
+		log.Error("吊销令牌失败: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "注销失败"})
+		return
+	}
+	if customClaims.Family != "" {
+		if err := revokeSessionFamily(customClaims.Family); err != nil {
+			log.Error("吊销令牌族失败: %v", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "注销失败"})
+			return
+		}
+	}
 
 	ctx.JSON(http.StatusOK, gin.H{"message": "注销成功"})
 }
 
+// HandleLogoutAll 退出所有设备
+// @Summary 退出所有设备
+// @Description 吊销当前用户已签发的全部访问令牌与刷新令牌（包括当前令牌），API 令牌不受影响
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} map[string]string "已退出所有设备"
+// @Failure 500 {object} map[string]string "系统错误"
+// @Security ApiKeyAuth
+// @Router /api/v1/auth/logout-all [post]
+func HandleLogoutAll(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
+
+	if err := revokeAllSessions(claims.UserID); err != nil {
+		log.Error("吊销用户 %d 的令牌失败: %v", claims.UserID, err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "操作失败"})
+		return
+	}
+	recordAudit(ctx, models.AuditTokensRevokeAll, fmt.Sprintf("user:%d", claims.UserID), "退出所有设备")
+	ctx.JSON(http.StatusOK, gin.H{"message": "已退出所有设备"})
+}
+
 // HandleGetCurrentUser 获取当前用户信息
 // @Summary 获取当前登录用户信息
-// @Description 从 JWT 中解析并返回用户 ID、用户名与角色
+// @Description 从 JWT 中解析并返回用户 ID、用户名、角色与通知邮箱
 // @Tags User
 // @Produce json
 // @Success 200 {object} map[string]interface{} "包含用户 id、用户名、角色"
@@ -217,8 +395,11 @@ func HandleGetCurrentUser(ctx *gin.Context) {
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{
-		"id":       user.ID,
-		"username": user.Username,
-		"role":     user.Role,
+		"id":          user.ID,
+		"username":    user.Username,
+		"role":        user.Role,
+		"email":       user.Email,
+		"mfa_enabled": user.TOTPEnabled,
+		"permissions": rbac.PermissionsOf(user.Role),
 	})
 }
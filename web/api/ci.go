@@ -0,0 +1,225 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"VulnFusion/internal/auth"
+	"VulnFusion/internal/ci"
+	"VulnFusion/internal/config"
+	"VulnFusion/internal/log"
+	"VulnFusion/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ciPollInterval 同步等待时查询任务状态的间隔
+const ciPollInterval = 2 * time.Second
+
+// HandleCIScan 发起 CI 扫描并按策略返回门禁结论
+// @Summary 发起 CI 门禁扫描
+// @Description 创建扫描任务并同步等待完成（最长 wait 秒，受 ci.max_wait 限制），按策略判定：达到 fail_on 等级的漏洞判定失败，allow_templates 中的模板与已接受风险的漏洞不计入。完成时返回 200，结论见 verdict 字段与 X-VulnFusion-Verdict 响应头；未完成时返回 202 与轮询地址。format 可选 json / junit / sarif
+// @Tags CI
+// @Accept json
+// @Produce json,xml
+// @Param data body api.CIScanRequest true "扫描参数"
+// @Param format query string false "输出格式：json（默认）/ junit / sarif"
+// @Success 200 {object} ci.Verdict "门禁结论"
+// @Success 202 {object} map[string]interface{} "扫描未完成"
+// @Failure 400 {object} map[string]string "参数错误"
+// @Failure 403 {object} map[string]string "无项目权限或目标不在范围内"
+// @Security ApiKeyAuth
+// @Router /api/v1/ci/scan [post]
+func HandleCIScan(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
+
+	var req CIScanRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+	format, ok := ciFormat(ctx)
+	if !ok {
+		return
+	}
+	policy := ci.DefaultPolicy()
+	if p := req.Policy; p != nil {
+		if p.FailOn != "" {
+			policy.FailOn = strings.ToLower(p.FailOn)
+		}
+		if p.AllowTemplates != nil {
+			policy.AllowTemplates = p.AllowTemplates
+		}
+		if p.IgnoreAccepted != nil {
+			policy.IgnoreAccepted = *p.IgnoreAccepted
+		}
+	}
+	if err := policy.Validate(); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	taskReq := CreateTaskRequest{
+		Target:     req.Target,
+		Template:   req.Profile,
+		Mode:       models.ModeStandard,
+		AgentLabel: req.AgentLabel,
+		ProjectID:  req.ProjectID,
+	}
+	if strings.EqualFold(req.Profile, models.ModeAuto) {
+		taskReq.Template, taskReq.Mode = "", models.ModeAuto
+	}
+	task, ok := buildTask(ctx, claims, taskReq)
+	if !ok {
+		return
+	}
+	task.CIPolicy = policy.Encode()
+	if !startTask(ctx, task) {
+		return
+	}
+	log.Info("用户 %d 发起 CI 扫描任务 %d，目标 %s", claims.UserID, task.ID, task.Target)
+
+	wait := config.CIMaxWait()
+	if req.Wait != nil {
+		wait = ciWait(*req.Wait)
+	}
+	respondCIVerdict(ctx, task.ID, policy, format, wait)
+}
+
+// HandleGetCIScan 查询 CI 扫描的门禁结论
+// @Summary 查询 CI 门禁结论
+// @Description 查询 CI 扫描任务的结论，可通过 wait 参数长轮询等待（秒）；完成返回 200，未完成返回 202
+// @Tags CI
+// @Produce json,xml
+// @Param id path int true "任务 ID"
+// @Param wait query int false "等待秒数"
+// @Param format query string false "输出格式：json（默认）/ junit / sarif"
+// @Success 200 {object} ci.Verdict "门禁结论"
+// @Success 202 {object} map[string]interface{} "扫描未完成"
+// @Failure 403 {object} map[string]string "无权限访问"
+// @Failure 404 {object} map[string]string "CI 扫描不存在"
+// @Security ApiKeyAuth
+// @Router /api/v1/ci/scan/{id} [get]
+func HandleGetCIScan(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
+
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "ID 格式错误"})
+		return
+	}
+	format, ok := ciFormat(ctx)
+	if !ok {
+		return
+	}
+	task, err := models.GetTaskByID(uint(id))
+	if err != nil || task.CIPolicy == "" {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "CI 扫描不存在"})
+		return
+	}
+	if !canAccessTask(claims, task, models.ProjectRoleViewer) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "无权限访问此任务"})
+		return
+	}
+	policy, err := ci.DecodePolicy(task.CIPolicy)
+	if err != nil {
+		log.Error("解析任务 %d 的 CI 策略失败: %v", task.ID, err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "CI 策略损坏"})
+		return
+	}
+
+	seconds, _ := strconv.Atoi(ctx.Query("wait"))
+	respondCIVerdict(ctx, task.ID, policy, format, ciWait(seconds))
+}
+
+// ciFormat 读取并校验输出格式；ok 为 false 时已写入错误响应
+func ciFormat(ctx *gin.Context) (string, bool) {
+	format := strings.ToLower(ctx.DefaultQuery("format", "json"))
+	switch format {
+	case "json", "junit", "sarif":
+		return format, true
+	}
+	ctx.JSON(http.StatusBadRequest, gin.H{"error": "不支持的输出格式，可选 json / junit / sarif"})
+	return "", false
+}
+
+// ciWait 将等待秒数限制在 0 ~ ci.max_wait 之间
+func ciWait(seconds int) time.Duration {
+	wait := time.Duration(seconds) * time.Second
+	if wait < 0 {
+		return 0
+	}
+	if limit := config.CIMaxWait(); wait > limit {
+		return limit
+	}
+	return wait
+}
+
+// waitForTask 轮询任务状态直到结束、超时或客户端断开
+func waitForTask(ctx *gin.Context, id uint, wait time.Duration) (*models.Task, error) {
+	deadline := time.Now().Add(wait)
+	ticker := time.NewTicker(ciPollInterval)
+	defer ticker.Stop()
+	for {
+		task, err := models.GetTaskByID(id)
+		if err != nil {
+			return nil, err
+		}
+		if task.Status == models.StatusDone || task.Status == models.StatusFailed || !time.Now().Before(deadline) {
+			return task, nil
+		}
+		select {
+		case <-ctx.Request.Context().Done():
+			return task, nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// respondCIVerdict 等待任务结束并按格式输出门禁结论，未结束时返回 202
+func respondCIVerdict(ctx *gin.Context, taskID uint, policy ci.Policy, format string, wait time.Duration) {
+	task, err := waitForTask(ctx, taskID, wait)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+		return
+	}
+	verdict, err := ci.Evaluate(task, policy)
+	if err != nil {
+		log.Error("任务 %d 门禁判定失败: %v", task.ID, err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "门禁判定失败"})
+		return
+	}
+
+	ctx.Header("X-VulnFusion-Verdict", verdict.Verdict)
+	if verdict.Verdict == ci.VerdictPending {
+		ctx.JSON(http.StatusAccepted, gin.H{
+			"task_id":  task.ID,
+			"status":   task.Status,
+			"verdict":  verdict.Verdict,
+			"poll_url": fmt.Sprintf("/api/v1/ci/scan/%d", task.ID),
+		})
+		return
+	}
+
+	switch format {
+	case "junit":
+		data, err := ci.JUnit(verdict)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "生成 JUnit 报告失败"})
+			return
+		}
+		ctx.Data(http.StatusOK, "application/xml; charset=utf-8", data)
+	case "sarif":
+		data, err := ci.SARIF(verdict)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "生成 SARIF 报告失败"})
+			return
+		}
+		ctx.Data(http.StatusOK, "application/sarif+json", data)
+	default:
+		ctx.JSON(http.StatusOK, verdict)
+	}
+}
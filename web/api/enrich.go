@@ -0,0 +1,83 @@
+package api
+
+import (
+	"io"
+	"net/http"
+
+	"VulnFusion/internal/enrich"
+	"VulnFusion/internal/log"
+	"VulnFusion/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleImportNVD 导入 NVD 漏洞数据（管理员）
+// @Summary 导入 NVD 数据
+// @Description 上传 NVD JSON 数据（1.1 数据源或 2.0 API 格式，可为 .gz），写入本地漏洞库
+// @Tags Admin
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "NVD JSON 文件"
+// @Success 200 {object} map[string]interface{} "导入条数"
+// @Failure 400 {object} map[string]string "文件格式错误"
+// @Security ApiKeyAuth
+// @Router /api/v1/admin/enrich/nvd [post]
+func HandleImportNVD(ctx *gin.Context) {
+	handleEnrichImport(ctx, "NVD", enrich.ImportNVD)
+}
+
+// HandleImportCWE 导入 CWE 目录（管理员）
+// @Summary 导入 CWE 数据
+// @Description 上传 MITRE 发布的 CWE XML 目录（可为 .gz），写入本地漏洞库
+// @Tags Admin
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "CWE XML 文件"
+// @Success 200 {object} map[string]interface{} "导入条数"
+// @Failure 400 {object} map[string]string "文件格式错误"
+// @Security ApiKeyAuth
+// @Router /api/v1/admin/enrich/cwe [post]
+func HandleImportCWE(ctx *gin.Context) {
+	handleEnrichImport(ctx, "CWE", enrich.ImportCWE)
+}
+
+// HandleEnrichStatus 查看本地漏洞库条目数（管理员）
+// @Summary 本地漏洞库状态
+// @Description 返回本地漏洞库中的 CVE 与 CWE 条目数
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} map[string]interface{} "条目数"
+// @Failure 500 {object} map[string]string "查询失败"
+// @Security ApiKeyAuth
+// @Router /api/v1/admin/enrich/status [get]
+func HandleEnrichStatus(ctx *gin.Context) {
+	cves, cwes, err := models.CountEnrichmentEntries()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "查询漏洞库失败"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"cves": cves, "cwes": cwes})
+}
+
+// handleEnrichImport 读取上传文件并调用对应的导入函数
+func handleEnrichImport(ctx *gin.Context, kind string, importFn func(io.Reader) (int, error)) {
+	header, err := ctx.FormFile("file")
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "请上传数据文件"})
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "读取文件失败"})
+		return
+	}
+	defer file.Close()
+
+	count, err := importFn(file)
+	if err != nil {
+		log.Warn("导入 %s 数据失败（已导入 %d 条）: %v", kind, count, err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "imported": count})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "导入成功", "imported": count})
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"VulnFusion/internal/auth"
+	"VulnFusion/internal/config"
+	"VulnFusion/internal/log"
+	"VulnFusion/internal/models"
+	"VulnFusion/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 邀请码最长有效期（小时）
+const maxInviteHours = 30 * 24
+
+// HandleCreateInvite 生成邀请码
+// @Summary 生成注册邀请码（管理员）
+// @Description 生成一次性注册邀请码，明文只在本次响应中返回；auth.registration.mode 为 invite 时凭邀请码注册，注册用户为 viewer 角色
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param data body api.CreateInviteRequest false "邀请码参数"
+// @Success 200 {object} map[string]interface{} "code 为邀请码明文，info 为邀请码信息"
+// @Failure 400 {object} map[string]string "参数错误"
+// @Failure 500 {object} map[string]string "生成失败"
+// @Security ApiKeyAuth
+// @Router /api/v1/admin/invites [post]
+func HandleCreateInvite(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
+
+	var req CreateInviteRequest
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+			return
+		}
+	}
+	if req.ExpiresInHours < 0 || req.ExpiresInHours > maxInviteHours {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("有效小时数应在 0~%d 之间", maxInviteHours)})
+		return
+	}
+	ttl := config.RegistrationInviteTTL()
+	if req.ExpiresInHours > 0 {
+		ttl = time.Duration(req.ExpiresInHours) * time.Hour
+	}
+
+	code, err := utils.GenerateSecureToken(16)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "生成邀请码失败"})
+		return
+	}
+	invite := &models.Invite{
+		CodeHash:  utils.SHA256Hex(code),
+		Prefix:    code[:6],
+		Note:      strings.TrimSpace(req.Note),
+		CreatedBy: claims.UserID,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := models.CreateInvite(invite); err != nil {
+		log.Error("保存邀请码失败: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "生成邀请码失败"})
+		return
+	}
+
+	recordAudit(ctx, models.AuditInviteCreate, fmt.Sprintf("invite:%d", invite.ID), invite.Note)
+	ctx.JSON(http.StatusOK, gin.H{"code": code, "info": invite})
+}
+
+// HandleListInvites 查看邀请码
+// @Summary 获取注册邀请码列表（管理员）
+// @Description 返回全部邀请码（不含明文），包括过期时间、使用与作废情况
+// @Tags Admin
+// @Produce json
+// @Success 200 {array} models.Invite "邀请码列表"
+// @Failure 500 {object} map[string]string "获取失败"
+// @Security ApiKeyAuth
+// @Router /api/v1/admin/invites [get]
+func HandleListInvites(ctx *gin.Context) {
+	invites, err := models.ListInvites()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "获取邀请码失败"})
+		return
+	}
+	ctx.JSON(http.StatusOK, invites)
+}
+
+// HandleRevokeInvite 作废邀请码
+// @Summary 作废注册邀请码（管理员）
+// @Description 作废尚未使用的邀请码
+// @Tags Admin
+// @Produce json
+// @Param id path int true "邀请码 ID"
+// @Success 200 {object} map[string]string "已作废"
+// @Failure 400 {object} map[string]string "无效的 ID"
+// @Failure 404 {object} map[string]string "邀请码不存在或已使用"
+// @Failure 500 {object} map[string]string "作废失败"
+// @Security ApiKeyAuth
+// @Router /api/v1/admin/invites/{id} [delete]
+func HandleRevokeInvite(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || id <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "无效的邀请码 ID"})
+		return
+	}
+
+	revoked, err := models.RevokeInvite(uint(id))
+	if err != nil {
+		log.Error("作废邀请码 %d 失败: %v", id, err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "作废失败"})
+		return
+	}
+	if !revoked {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "邀请码不存在或已使用"})
+		return
+	}
+
+	recordAudit(ctx, models.AuditInviteRevoke, fmt.Sprintf("invite:%d", id), "")
+	ctx.JSON(http.StatusOK, gin.H{"message": "已作废"})
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"VulnFusion/internal/config"
+	"VulnFusion/internal/ldap"
+	"VulnFusion/internal/log"
+	"VulnFusion/internal/models"
+	"VulnFusion/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ldapProvider 目录用户在 users.auth_provider 中的取值
+const ldapProvider = "ldap"
+
+// 目录认证通过但不允许登录本系统的原因，会直接返回给用户
+var (
+	errLDAPDenied   = errors.New("该账号未被授权访问本系统，请联系管理员")
+	errLDAPConflict = errors.New("用户名已被其他账号占用，请联系管理员")
+)
+
+// loginWithLDAP 通过目录验证密码，并按目录信息创建或更新本地用户；ok 为 false 时已写入错误响应。
+// local 为同名本地用户（可能为空），用于记录登录失败次数
+func loginWithLDAP(ctx *gin.Context, username, password string, local *models.User) (*models.User, bool) {
+	cfg := config.Global.LDAP
+	identity, err := ldap.Authenticate(cfg, username, password)
+	if errors.Is(err, ldap.ErrInvalidCredentials) {
+		recordLoginFailure(ctx, username, local)
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "用户名或密码错误"})
+		return nil, false
+	}
+	if err != nil {
+		log.Error("目录认证失败: %v", err)
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "目录服务不可用，请稍后重试"})
+		return nil, false
+	}
+
+	user, err := provisionLDAPUser(ctx, cfg, identity)
+	if errors.Is(err, errLDAPDenied) || errors.Is(err, errLDAPConflict) {
+		log.Warn("目录用户 %s 登录被拒绝: %v", identity.DN, err)
+		ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if err != nil {
+		log.Error("同步目录用户 %s 失败: %v", identity.DN, err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+		return nil, false
+	}
+	return user, true
+}
+
+// provisionLDAPUser 首次登录时创建用户（或关联同名本地用户），之后每次登录按目录组重新映射角色
+func provisionLDAPUser(ctx *gin.Context, cfg config.LDAPConfig, id *ldap.Identity) (*models.User, error) {
+	role, mapped := ldap.ResolveRole(cfg, id.Groups)
+	if !mapped && cfg.DenyUnmapped {
+		recordAudit(ctx, models.AuditLDAPDenied, id.DN, id.Username+" 不属于任何映射组")
+		return nil, errLDAPDenied
+	}
+
+	user, err := models.GetUserByExternalID(ldapProvider, id.ID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if user == nil {
+		existing, err := models.GetUserByUsername(id.Username)
+		switch {
+		case err == nil && cfg.LinkLocalUsers && existing.AuthProvider == "":
+			if err := models.LinkExternalIdentity(existing.ID, ldapProvider, id.ID); err != nil {
+				return nil, err
+			}
+			existing.AuthProvider, existing.ExternalID = ldapProvider, id.ID
+			user = existing
+			recordAudit(ctx, models.AuditLDAPLink, fmt.Sprintf("user:%d", user.ID),
+				fmt.Sprintf("%s 关联目录账号 %s", user.Username, id.DN))
+		case err == nil:
+			return nil, errLDAPConflict
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return nil, err
+		default:
+			// 目录用户不使用本地密码
+			random, err := utils.GenerateSecureToken(32)
+			if err != nil {
+				return nil, err
+			}
+			hashed, err := utils.HashPassword(random)
+			if err != nil {
+				return nil, err
+			}
+			user = &models.User{
+				Username:     id.Username,
+				Password:     hashed,
+				Role:         role,
+				Email:        id.Email,
+				AuthProvider: ldapProvider,
+				ExternalID:   id.ID,
+			}
+			if err := models.CreateUser(user); err != nil {
+				return nil, err
+			}
+			recordAudit(ctx, models.AuditLDAPProvision, fmt.Sprintf("user:%d", user.ID),
+				fmt.Sprintf("%s（%s）首次登录，角色 %s", user.Username, id.DN, role))
+			return user, nil
+		}
+	}
+
+	updates := map[string]interface{}{}
+	if role != user.Role {
+		updates["role"] = role
+		recordAudit(ctx, models.AuditLDAPRole, fmt.Sprintf("user:%d", user.ID),
+			fmt.Sprintf("%s 角色由 %s 同步为 %s", user.Username, user.Role, role))
+		user.Role = role
+	}
+	if id.Email != "" && id.Email != user.Email {
+		updates["email"] = id.Email
+		user.Email = id.Email
+	}
+	if len(updates) > 0 {
+		if err := models.UpdateUserByID(user.ID, updates); err != nil {
+			return nil, err
+		}
+	}
+	return user, nil
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"VulnFusion/internal/auth"
+	"VulnFusion/internal/log"
+	"VulnFusion/internal/models"
+	"VulnFusion/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// loginGuard 密码登录失败记录，按账号渐进延迟与锁定，按来源 IP 限制失败次数
+var loginGuard = auth.NewLoginGuard()
+
+// dummyPasswordHash 用户不存在时用于比较的哈希，使响应时间与密码错误时一致
+var dummyPasswordHash = sync.OnceValue(func() string {
+	secret, _ := utils.GenerateSecureToken(16)
+	hash, err := utils.HashPassword(secret)
+	if err != nil {
+		log.Error("生成占位密码哈希失败: %v", err)
+	}
+	return hash
+})
+
+// checkLoginAllowed 校验密码前检查账号与来源 IP 的失败记录；返回 false 时已写入 429 或 423 响应。
+// 返回 true 时已占用一次尝试名额，调用方须在得出结果后调用 releaseLoginAttempt 归还。
+// local 为同名本地用户（可能为空），其锁定状态保存在数据库中，进程重启后依然有效
+func checkLoginAllowed(ctx *gin.Context, username string, local *models.User) bool {
+	now := time.Now()
+	if local != nil && local.LockedUntil != nil && now.Before(*local.LockedUntil) {
+		loginGuard.Lock(username, *local.LockedUntil)
+	}
+	wait, locked := loginGuard.Acquire(ctx.ClientIP(), username, now)
+	if wait <= 0 {
+		return true
+	}
+
+	seconds := int((wait + time.Second - 1) / time.Second)
+	ctx.Header("Retry-After", strconv.Itoa(seconds))
+	if locked {
+		ctx.JSON(http.StatusLocked, gin.H{"error": fmt.Sprintf("登录失败次数过多，账号已被临时锁定，请 %d 分钟后重试或联系管理员解锁", (seconds+59)/60)})
+		return false
+	}
+	ctx.JSON(http.StatusTooManyRequests, gin.H{"error": fmt.Sprintf("登录尝试过于频繁，请 %d 秒后重试", seconds)})
+	return false
+}
+
+// releaseLoginAttempt 归还 checkLoginAllowed 占用的尝试名额
+func releaseLoginAttempt(ctx *gin.Context, username string) {
+	loginGuard.Release(ctx.ClientIP(), username)
+}
+
+// recordLoginFailure 记录一次密码错误并写入审计日志，连续失败达到上限时锁定账号
+func recordLoginFailure(ctx *gin.Context, username string, local *models.User) {
+	recordAuthFailure(ctx, username, local, "用户名或密码错误")
+}
+
+// recordMFALoginFailure 记录一次登录验证码错误，与密码错误共用账号的失败次数与锁定
+func recordMFALoginFailure(ctx *gin.Context, user *models.User) {
+	recordAuthFailure(ctx, user.Username, user, "二次验证码错误")
+}
+
+// recordAuthFailure 累计账号与来源 IP 的失败次数并写入审计日志，reason 为失败原因
+func recordAuthFailure(ctx *gin.Context, username string, local *models.User, reason string) {
+	failures, lockedUntil := loginGuard.Fail(ctx.ClientIP(), username, time.Now())
+
+	name := truncateRunes(username, 64)
+	resource := "username:" + name
+	if local != nil {
+		resource = fmt.Sprintf("user:%d", local.ID)
+		var lock *time.Time
+		if !lockedUntil.IsZero() {
+			lock = &lockedUntil
+		}
+		if err := models.RecordLoginFailure(local.ID, lock); err != nil {
+			log.Error("记录用户 %d 登录失败次数失败: %v", local.ID, err)
+		}
+	}
+
+	recordAudit(ctx, models.AuditLoginFailed, resource, fmt.Sprintf("%s %s（连续第 %d 次）", name, reason, failures))
+	if !lockedUntil.IsZero() {
+		log.Warn("账号 %s 连续登录失败 %d 次，锁定至 %s（来源 %s）", name, failures, lockedUntil.Format(time.RFC3339), ctx.ClientIP())
+		recordAudit(ctx, models.AuditLoginLocked, resource, fmt.Sprintf("%s 连续登录失败 %d 次，锁定至 %s", name, failures, lockedUntil.Format(time.RFC3339)))
+	}
+}
+
+// recordLoginSuccess 身份验证全部通过（含二次验证）后清除账号的失败记录
+func recordLoginSuccess(username string, user *models.User) {
+	loginGuard.Succeed(username)
+	if user.FailedLogins == 0 && user.LockedUntil == nil {
+		return
+	}
+	if err := models.ResetLoginFailures(user.ID); err != nil {
+		log.Error("清除用户 %d 登录失败次数失败: %v", user.ID, err)
+	}
+}
+
+// truncateRunes 截断过长的用户输入，避免写入审计日志的内容过大
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "…"
+}
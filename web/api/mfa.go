@@ -0,0 +1,484 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"VulnFusion/internal/auth"
+	"VulnFusion/internal/config"
+	"VulnFusion/internal/log"
+	"VulnFusion/internal/models"
+	"VulnFusion/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// recoveryCodeCount 每次生成的恢复码数量
+const recoveryCodeCount = 10
+
+// maxMFAAttempts 单个挑战令牌允许的验证码错误次数，超过后须重新输入密码
+const maxMFAAttempts = 5
+
+// mfaAttempts 按挑战令牌 JTI 记录验证码错误次数
+var mfaAttempts = struct {
+	sync.Mutex
+	failures map[string]mfaAttempt
+}{failures: map[string]mfaAttempt{}}
+
+type mfaAttempt struct {
+	count     int
+	expiresAt time.Time
+}
+
+// recordMFAFailure 记录一次验证失败，返回是否已达到上限
+func recordMFAFailure(claims *auth.CustomClaims) bool {
+	mfaAttempts.Lock()
+	defer mfaAttempts.Unlock()
+	now := time.Now()
+	for jti, a := range mfaAttempts.failures {
+		if now.After(a.expiresAt) {
+			delete(mfaAttempts.failures, jti)
+		}
+	}
+	a := mfaAttempts.failures[claims.ID]
+	a.count++
+	a.expiresAt = claims.ExpiresAt.Time
+	mfaAttempts.failures[claims.ID] = a
+	return a.count >= maxMFAAttempts
+}
+
+// consumeMFAChallenge 作废挑战令牌，每个挑战令牌只能完成一次登录
+func consumeMFAChallenge(claims *auth.CustomClaims) {
+	mfaAttempts.Lock()
+	delete(mfaAttempts.failures, claims.ID)
+	mfaAttempts.Unlock()
+	if err := auth.AddTokenToBlacklist(claims.ID, time.Until(claims.ExpiresAt.Time)); err != nil {
+		log.Warn("作废二次验证挑战令牌失败: %v", err)
+	}
+}
+
+// respondMFAChallenge 密码验证通过但需要二次验证，返回挑战令牌；
+// enrollment_required 为 true 表示该角色强制二次验证而用户尚未绑定验证器
+func respondMFAChallenge(ctx *gin.Context, user *models.User) {
+	token, _, err := auth.GenerateMFAToken(user.ID, config.MFAChallengeTTL())
+	if err != nil {
+		log.Error("生成二次验证挑战令牌失败: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "生成 token 失败"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"mfa_required":        true,
+		"mfa_token":           token,
+		"enrollment_required": !user.TOTPEnabled,
+	})
+}
+
+// verifySecondFactor 校验 6 位验证码或恢复码；usedRecovery 表示使用了恢复码
+func verifySecondFactor(user *models.User, code string) (ok bool, usedRecovery bool, err error) {
+	if counter, valid := auth.ValidateTOTP(user.TOTPSecret, code, time.Now()); valid {
+		ok, err = models.UseTOTPCounter(user.ID, counter)
+		return ok, false, err
+	}
+	normalized := auth.NormalizeRecoveryCode(code)
+	if len(normalized) != 10 {
+		return false, false, nil
+	}
+	ok, err = models.UseRecoveryCode(user.ID, utils.SHA256Hex(normalized))
+	return ok, ok, err
+}
+
+// beginTOTPEnrollment 生成待确认的 TOTP 密钥并返回绑定信息
+func beginTOTPEnrollment(ctx *gin.Context, user *models.User) {
+	if user.TOTPEnabled {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "已启用二次验证"})
+		return
+	}
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		log.Error("生成 TOTP 密钥失败: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "生成密钥失败"})
+		return
+	}
+	if err := models.SetPendingTOTPSecret(user.ID, secret); err != nil {
+		log.Error("保存用户 %d 的 TOTP 密钥失败: %v", user.ID, err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "生成密钥失败"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"secret": secret,
+		"uri":    auth.TOTPProvisioningURI(config.MFAIssuer(), user.Username, secret),
+	})
+}
+
+// confirmTOTPEnrollment 校验待确认密钥的验证码并启用二次验证，返回新生成的恢复码明文
+func confirmTOTPEnrollment(user *models.User, code string) ([]string, bool, error) {
+	if user.TOTPSecret == "" {
+		return nil, false, nil
+	}
+	counter, valid := auth.ValidateTOTP(user.TOTPSecret, code, time.Now())
+	if !valid {
+		return nil, false, nil
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, false, err
+	}
+	if err := models.EnableTOTP(user.ID, counter, hashes); err != nil {
+		return nil, false, err
+	}
+	return codes, true, nil
+}
+
+// newRecoveryCodes 生成恢复码明文及其哈希
+func newRecoveryCodes() ([]string, []string, error) {
+	codes, err := auth.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, nil, err
+	}
+	hashes := make([]string, 0, len(codes))
+	for _, c := range codes {
+		hashes = append(hashes, utils.SHA256Hex(auth.NormalizeRecoveryCode(c)))
+	}
+	return codes, hashes, nil
+}
+
+// mfaChallengeUser 解析挑战令牌并加载用户；ok 为 false 时已写入错误响应
+func mfaChallengeUser(ctx *gin.Context, token string) (*auth.CustomClaims, *models.User, bool) {
+	claims, err := auth.ParseMFAToken(token)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "验证已过期，请重新登录"})
+		return nil, nil, false
+	}
+	user, err := models.GetUserByID(claims.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "用户不存在"})
+		return nil, nil, false
+	}
+	return claims, user, true
+}
+
+// HandleLoginMFA 完成二次验证登录
+// @Summary 二次验证登录
+// @Description 使用登录第一步返回的 mfa_token 与验证器中的 6 位验证码（或一次性恢复码）换取访问令牌与刷新令牌。尚未绑定验证器且被强制启用时，先调用 /auth/login/mfa/setup 获取密钥，再以首个验证码完成绑定，响应中附带恢复码。同一挑战令牌错误 5 次后失效；验证码错误与密码错误一同计入账号的连续失败次数，达到上限后锁定账号
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param data body api.MFALoginRequest true "挑战令牌与验证码"
+// @Success 200 {object} map[string]interface{} "包含 token 和 refresh_token，完成绑定时另含 recovery_codes"
+// @Failure 400 {object} map[string]string "参数错误"
+// @Failure 401 {object} map[string]string "验证码错误或挑战令牌无效"
+// @Failure 423 {object} map[string]string "连续失败次数过多，账号已被临时锁定"
+// @Failure 429 {object} map[string]string "验证尝试过于频繁，Retry-After 为需等待的秒数"
+// @Router /api/v1/auth/login/mfa [post]
+func HandleLoginMFA(ctx *gin.Context) {
+	var req MFALoginRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+	claims, user, ok := mfaChallengeUser(ctx, req.MFAToken)
+	if !ok {
+		return
+	}
+	// 验证码错误与密码错误共用账号的渐进延迟与锁定
+	if !checkLoginAllowed(ctx, user.Username, user) {
+		return
+	}
+	defer releaseLoginAttempt(ctx, user.Username)
+
+	var (
+		recoveryCodes []string
+		usedRecovery  bool
+		err           error
+	)
+	if user.TOTPEnabled {
+		ok, usedRecovery, err = verifySecondFactor(user, req.Code)
+	} else {
+		recoveryCodes, ok, err = confirmTOTPEnrollment(user, req.Code)
+	}
+	if err != nil {
+		log.Error("用户 %d 二次验证失败: %v", user.ID, err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "验证失败"})
+		return
+	}
+	if !ok {
+		recordMFALoginFailure(ctx, user)
+		if recordMFAFailure(claims) {
+			consumeMFAChallenge(claims)
+			log.Warn("用户 %d 验证码错误次数过多，挑战令牌已作废", user.ID)
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "验证码错误次数过多，请重新登录"})
+			return
+		}
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "验证码错误"})
+		return
+	}
+	consumeMFAChallenge(claims)
+	recordLoginSuccess(user.Username, user)
+
+	resource := fmt.Sprintf("user:%d", user.ID)
+	if recoveryCodes != nil {
+		recordAudit(ctx, models.AuditMFAEnable, resource, user.Username+" 登录时绑定验证器")
+	}
+	if usedRecovery {
+		remaining, _ := models.CountUnusedRecoveryCodes(user.ID)
+		recordAudit(ctx, models.AuditMFARecovery, resource, fmt.Sprintf("%s 使用恢复码登录，剩余 %d 个", user.Username, remaining))
+	}
+
+	resp, ok := completeLogin(ctx, user)
+	if !ok {
+		return
+	}
+	if recoveryCodes != nil {
+		resp["recovery_codes"] = recoveryCodes
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// HandleLoginMFASetup 登录过程中绑定验证器
+// @Summary 登录时绑定验证器
+// @Description 角色被强制启用二次验证而尚未绑定时，使用 mfa_token 生成 TOTP 密钥与 otpauth:// 地址（可渲染为二维码），随后调用 /auth/login/mfa 提交首个验证码完成绑定与登录
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param data body api.MFALoginRequest true "挑战令牌（code 可留空）"
+// @Success 200 {object} map[string]string "包含 secret 与 uri"
+// @Failure 401 {object} map[string]string "挑战令牌无效"
+// @Failure 409 {object} map[string]string "已启用二次验证"
+// @Router /api/v1/auth/login/mfa/setup [post]
+func HandleLoginMFASetup(ctx *gin.Context) {
+	var req MFALoginRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+	_, user, ok := mfaChallengeUser(ctx, req.MFAToken)
+	if !ok {
+		return
+	}
+	beginTOTPEnrollment(ctx, user)
+}
+
+// HandleGetMFAStatus 获取二次验证状态
+// @Summary 获取二次验证状态
+// @Description 返回当前用户是否已启用二次验证、所属角色是否强制启用以及剩余恢复码数量
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} map[string]interface{} "二次验证状态"
+// @Security ApiKeyAuth
+// @Router /api/v1/auth/mfa [get]
+func HandleGetMFAStatus(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
+	user, err := models.GetUserByID(claims.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+	remaining, err := models.CountUnusedRecoveryCodes(user.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "获取状态失败"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"enabled":                  user.TOTPEnabled,
+		"required":                 config.MFARequired(user.Role),
+		"recovery_codes_remaining": remaining,
+	})
+}
+
+// HandleSetupMFA 开始绑定验证器
+// @Summary 绑定验证器
+// @Description 生成新的 TOTP 密钥与 otpauth:// 地址（可渲染为二维码），在验证器应用中添加后调用 /auth/mfa/enable 提交验证码完成启用
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} map[string]string "包含 secret 与 uri"
+// @Failure 409 {object} map[string]string "已启用二次验证"
+// @Security ApiKeyAuth
+// @Router /api/v1/auth/mfa/setup [post]
+func HandleSetupMFA(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
+	user, err := models.GetUserByID(claims.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+	beginTOTPEnrollment(ctx, user)
+}
+
+// HandleEnableMFA 确认并启用二次验证
+// @Summary 启用二次验证
+// @Description 提交验证器中的验证码确认绑定，成功后返回 10 个一次性恢复码，恢复码只展示这一次
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param data body api.MFACodeRequest true "验证码"
+// @Success 200 {object} map[string]interface{} "包含 recovery_codes"
+// @Failure 400 {object} map[string]string "验证码错误或未开始绑定"
+// @Failure 409 {object} map[string]string "已启用二次验证"
+// @Security ApiKeyAuth
+// @Router /api/v1/auth/mfa/enable [post]
+func HandleEnableMFA(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
+	var req MFACodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+	user, err := models.GetUserByID(claims.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+	if user.TOTPEnabled {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "已启用二次验证"})
+		return
+	}
+
+	codes, ok, err := confirmTOTPEnrollment(user, req.Code)
+	if err != nil {
+		log.Error("启用用户 %d 的二次验证失败: %v", user.ID, err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "启用失败"})
+		return
+	}
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "验证码错误，请确认验证器时间准确"})
+		return
+	}
+	recordAudit(ctx, models.AuditMFAEnable, fmt.Sprintf("user:%d", user.ID), "启用二次验证")
+	ctx.JSON(http.StatusOK, gin.H{"message": "二次验证已启用", "recovery_codes": codes})
+}
+
+// HandleDisableMFA 关闭二次验证
+// @Summary 关闭二次验证
+// @Description 需同时提供当前密码与验证码（或恢复码）；所属角色被强制启用时不可关闭
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param data body api.MFADisableRequest true "密码与验证码"
+// @Success 200 {object} map[string]string "已关闭"
+// @Failure 401 {object} map[string]string "密码或验证码错误"
+// @Failure 403 {object} map[string]string "角色强制启用"
+// @Security ApiKeyAuth
+// @Router /api/v1/auth/mfa/disable [post]
+func HandleDisableMFA(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
+	var req MFADisableRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+	user, err := models.GetUserByID(claims.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+	if !user.TOTPEnabled {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "未启用二次验证"})
+		return
+	}
+	if config.MFARequired(user.Role) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "当前角色必须启用二次验证"})
+		return
+	}
+	if !utils.CheckPassword(req.Password, user.Password) {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "密码或验证码错误"})
+		return
+	}
+	ok, _, err := verifySecondFactor(user, req.Code)
+	if err != nil || !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "密码或验证码错误"})
+		return
+	}
+
+	if err := models.DisableTOTP(user.ID); err != nil {
+		log.Error("关闭用户 %d 的二次验证失败: %v", user.ID, err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "操作失败"})
+		return
+	}
+	recordAudit(ctx, models.AuditMFADisable, fmt.Sprintf("user:%d", user.ID), "关闭二次验证")
+	ctx.JSON(http.StatusOK, gin.H{"message": "二次验证已关闭"})
+}
+
+// HandleRegenerateRecoveryCodes 重新生成恢复码
+// @Summary 重新生成恢复码
+// @Description 提交验证器中的验证码后生成新的 10 个恢复码，旧恢复码全部作废
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param data body api.MFACodeRequest true "验证码"
+// @Success 200 {object} map[string]interface{} "包含 recovery_codes"
+// @Failure 401 {object} map[string]string "验证码错误"
+// @Security ApiKeyAuth
+// @Router /api/v1/auth/mfa/recovery-codes [post]
+func HandleRegenerateRecoveryCodes(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
+	var req MFACodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+	user, err := models.GetUserByID(claims.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+	if !user.TOTPEnabled {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "未启用二次验证"})
+		return
+	}
+	// 只接受验证器中的验证码，恢复码不能用来换取新的恢复码
+	counter, valid := auth.ValidateTOTP(user.TOTPSecret, req.Code, time.Now())
+	if valid {
+		valid, err = models.UseTOTPCounter(user.ID, counter)
+	}
+	if err != nil || !valid {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "验证码错误"})
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err == nil {
+		err = models.ReplaceRecoveryCodes(user.ID, hashes)
+	}
+	if err != nil {
+		log.Error("重新生成用户 %d 的恢复码失败: %v", user.ID, err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "生成恢复码失败"})
+		return
+	}
+	recordAudit(ctx, models.AuditMFARegen, fmt.Sprintf("user:%d", user.ID), "重新生成恢复码")
+	ctx.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// HandleResetUserMFA 管理员重置用户的二次验证
+// @Summary 重置用户二次验证（管理员）
+// @Description 用户丢失验证器且恢复码用尽时，由管理员清除其二次验证并强制下线；若角色强制启用，下次登录时需重新绑定
+// @Tags Admin
+// @Produce json
+// @Param id path int true "用户 ID"
+// @Success 200 {object} map[string]string "已重置"
+// @Failure 400 {object} map[string]string "ID 错误"
+// @Failure 403 {object} map[string]string "不能管理权限超出自身的用户"
+// @Failure 404 {object} map[string]string "用户不存在"
+// @Security ApiKeyAuth
+// @Router /api/v1/admin/users/{id}/mfa/reset [post]
+func HandleResetUserMFA(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户 ID"})
+		return
+	}
+	user, ok := loadManagedUser(ctx, uint(id))
+	if !ok {
+		return
+	}
+	if err := models.DisableTOTP(user.ID); err != nil {
+		log.Error("重置用户 %d 的二次验证失败: %v", user.ID, err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "操作失败"})
+		return
+	}
+	revokeUserSessions(user.ID)
+	recordAudit(ctx, models.AuditMFAReset, fmt.Sprintf("user:%d", user.ID), "管理员重置 "+user.Username+" 的二次验证")
+	ctx.JSON(http.StatusOK, gin.H{"message": "二次验证已重置"})
+}
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"VulnFusion/internal/auth"
+	"VulnFusion/internal/log"
+	"VulnFusion/internal/mail"
+	"VulnFusion/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleGetNotificationSettings 获取个人通知设置
+// @Summary 获取个人通知设置
+// @Description 返回当前用户的通知邮箱、任务结束邮件开关与高危漏洞摘要频率
+// @Tags User
+// @Produce json
+// @Success 200 {object} map[string]interface{} "通知设置"
+// @Failure 500 {object} map[string]string "用户不存在"
+// @Security ApiKeyAuth
+// @Router /api/v1/user/notifications [get]
+func HandleGetNotificationSettings(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
+
+	user, err := models.GetUserByID(claims.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "用户不存在"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"email":             user.Email,
+		"notify_task_email": user.NotifyTaskEmail,
+		"digest":            user.Digest,
+		"digest_sent_at":    user.DigestSentAt,
+		"smtp_enabled":      mail.Enabled(),
+	})
+}
+
+// HandleUpdateNotificationSettings 更新个人通知设置
+// @Summary 更新个人通知设置
+// @Description 设置通知邮箱、任务结束邮件开关与高危漏洞摘要频率（daily / weekly，留空关闭）
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param data body api.NotificationSettingsRequest true "通知设置"
+// @Success 200 {object} map[string]string "更新成功"
+// @Failure 400 {object} map[string]string "参数错误"
+// @Failure 500 {object} map[string]string "更新失败"
+// @Security ApiKeyAuth
+// @Router /api/v1/user/notifications [put]
+func HandleUpdateNotificationSettings(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
+
+	var req NotificationSettingsRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+	if !models.IsValidDigest(req.Digest) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "不支持的摘要频率"})
+		return
+	}
+
+	email := ""
+	if strings.TrimSpace(req.Email) != "" {
+		var err error
+		if email, err = mail.ValidateAddress(req.Email); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "邮箱格式错误"})
+			return
+		}
+	}
+	if email == "" && (req.NotifyTaskEmail || req.Digest != models.DigestOff) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "开启邮件通知前请先填写邮箱"})
+		return
+	}
+
+	if err := models.UpdateUserByID(claims.UserID, map[string]interface{}{
+		"email":             email,
+		"notify_task_email": req.NotifyTaskEmail,
+		"digest":            req.Digest,
+	}); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "更新通知设置失败"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "通知设置已更新"})
+}
+
+// HandleSendTestEmail 发送测试邮件
+// @Summary 发送测试邮件
+// @Description 向当前用户的通知邮箱发送一封测试邮件，用于确认 SMTP 配置
+// @Tags User
+// @Produce json
+// @Success 200 {object} map[string]string "发送成功"
+// @Failure 400 {object} map[string]string "未填写邮箱或未配置 SMTP"
+// @Failure 502 {object} map[string]string "发送失败"
+// @Security ApiKeyAuth
+// @Router /api/v1/user/notifications/test [post]
+func HandleSendTestEmail(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
+
+	user, err := models.GetUserByID(claims.UserID)
+	if err != nil || user.Email == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "请先填写通知邮箱"})
+		return
+	}
+	if !mail.Enabled() {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "系统未配置 SMTP 服务器"})
+		return
+	}
+
+	if err := mail.Send(&mail.Message{
+		To:      []string{user.Email},
+		Subject: "[VulnFusion] 测试邮件",
+		HTML:    "<p>邮件通知配置成功，可以正常接收 VulnFusion 通知。</p>",
+	}); err != nil {
+		log.Warn("向用户 %d 发送测试邮件失败: %v", user.ID, err)
+		ctx.JSON(http.StatusBadGateway, gin.H{"error": "发送失败: " + err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "测试邮件已发送"})
+}
+
+// normalizeEmails 校验逗号分隔的邮箱列表，返回去除显示名后的地址
+func normalizeEmails(raw string) (string, error) {
+	var emails []string
+	for _, e := range strings.Split(raw, ",") {
+		if strings.TrimSpace(e) == "" {
+			continue
+		}
+		addr, err := mail.ValidateAddress(e)
+		if err != nil {
+			return "", err
+		}
+		emails = append(emails, addr)
+	}
+	return strings.Join(emails, ","), nil
+}
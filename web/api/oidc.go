@@ -0,0 +1,302 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"VulnFusion/internal/auth"
+	"VulnFusion/internal/config"
+	"VulnFusion/internal/log"
+	"VulnFusion/internal/models"
+	"VulnFusion/internal/oidc"
+	"VulnFusion/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	oidcStateCookie   = "vf_oidc_state"         // 保存 state、nonce 与 PKCE verifier 的 Cookie
+	oidcStateAudience = "vulnfusion-oidc-state" // 状态令牌受众
+	oidcStateTTL      = 10 * time.Minute        // 在身份提供方完成登录的时限
+	oidcCookiePath    = "/api/v1/auth/oidc"     // Cookie 仅随单点登录接口发送
+	ssoTicketTTL      = time.Minute             // 前端换取令牌的时限
+	oidcCallbackPath  = "/api/v1/auth/oidc/%s/callback"
+)
+
+// OIDCProviderView 登录页展示的身份提供方
+type OIDCProviderView struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+}
+
+// HandleListOIDCProviders 获取单点登录方式
+// @Summary 获取单点登录方式
+// @Description 返回已配置的 OIDC 身份提供方，供登录页展示单点登录按钮
+// @Tags Auth
+// @Produce json
+// @Success 200 {array} api.OIDCProviderView "身份提供方列表"
+// @Router /api/v1/auth/oidc/providers [get]
+func HandleListOIDCProviders(ctx *gin.Context) {
+	views := []OIDCProviderView{}
+	for _, p := range config.OIDCProviders() {
+		name := p.DisplayName
+		if name == "" {
+			name = p.Name
+		}
+		views = append(views, OIDCProviderView{Name: p.Name, DisplayName: name})
+	}
+	ctx.JSON(http.StatusOK, views)
+}
+
+// HandleOIDCLogin 发起单点登录
+// @Summary 发起单点登录
+// @Description 生成 state、nonce 与 PKCE 参数并跳转到身份提供方授权页（授权码模式）；参数保存在仅限单点登录接口的 HttpOnly Cookie 中
+// @Tags Auth
+// @Param provider path string true "身份提供方名称"
+// @Success 302 "跳转到身份提供方"
+// @Failure 404 {object} map[string]string "未配置的身份提供方"
+// @Failure 502 {object} map[string]string "身份提供方不可用"
+// @Router /api/v1/auth/oidc/{provider}/login [get]
+func HandleOIDCLogin(ctx *gin.Context) {
+	provider := ctx.Param("provider")
+	client, err := oidc.ClientFor(provider)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "未配置的身份提供方"})
+		return
+	}
+
+	state, err1 := utils.GenerateSecureToken(24)
+	nonce, err2 := utils.GenerateSecureToken(24)
+	verifier, challenge, err3 := oidc.NewPKCE()
+	if err := errors.Join(err1, err2, err3); err != nil {
+		log.Error("生成单点登录参数失败: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+		return
+	}
+	authURL, err := client.AuthCodeURL(ctx.Request.Context(), oidcRedirectURI(ctx, provider), state, nonce, challenge)
+	if err != nil {
+		log.Error("身份提供方 %s 不可用: %v", provider, err)
+		ctx.JSON(http.StatusBadGateway, gin.H{"error": "身份提供方不可用"})
+		return
+	}
+	cookie, err := auth.SignState(oidcStateAudience, map[string]string{
+		"provider": provider,
+		"state":    state,
+		"nonce":    nonce,
+		"verifier": verifier,
+	}, oidcStateTTL)
+	if err != nil {
+		log.Error("签发单点登录状态失败: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+		return
+	}
+
+	setOIDCStateCookie(ctx, cookie, int(oidcStateTTL.Seconds()))
+	ctx.Redirect(http.StatusFound, authURL)
+}
+
+// 可直接展示给用户的单点登录失败原因，其余错误只记录日志
+var (
+	errSSOSignupDisabled = errors.New("该账号未开通，请联系管理员")
+	errSSOUsernameTaken  = errors.New("用户名已被本地账号占用，请联系管理员关联")
+)
+
+// HandleOIDCCallback 单点登录回调
+// @Summary 单点登录回调
+// @Description 身份提供方登录完成后回调：校验 state，使用授权码与 PKCE verifier 换取 id_token 并通过 JWKS 验证，按 sub 查找、关联或创建用户，然后携带一次性票据跳转到前端登录页（#sso_ticket=...），失败时携带 #sso_error=...
+// @Tags Auth
+// @Param provider path string true "身份提供方名称"
+// @Param code query string true "授权码"
+// @Param state query string true "state"
+// @Success 302 "跳转到前端登录页"
+// @Router /api/v1/auth/oidc/{provider}/callback [get]
+func HandleOIDCCallback(ctx *gin.Context) {
+	provider := ctx.Param("provider")
+	raw, _ := ctx.Cookie(oidcStateCookie)
+	setOIDCStateCookie(ctx, "", -1)
+
+	if e := ctx.Query("error"); e != "" {
+		log.Warn("身份提供方 %s 返回错误: %s %s", provider, e, ctx.Query("error_description"))
+		redirectSSOResult(ctx, "sso_error", "身份提供方拒绝了登录请求")
+		return
+	}
+	data, err := auth.ParseState(raw, oidcStateAudience)
+	if err != nil || data["provider"] != provider || data["state"] == "" || data["state"] != ctx.Query("state") {
+		redirectSSOResult(ctx, "sso_error", "登录状态无效或已过期，请重新登录")
+		return
+	}
+	client, err := oidc.ClientFor(provider)
+	if err != nil {
+		redirectSSOResult(ctx, "sso_error", "未配置的身份提供方")
+		return
+	}
+
+	identity, err := client.Exchange(ctx.Request.Context(), ctx.Query("code"), oidcRedirectURI(ctx, provider), data["verifier"], data["nonce"])
+	if err != nil {
+		log.Warn("身份提供方 %s 登录验证失败: %v", provider, err)
+		redirectSSOResult(ctx, "sso_error", "单点登录验证失败")
+		return
+	}
+	user, err := provisionOIDCUser(ctx, client.Config(), identity)
+	if err != nil {
+		log.Warn("身份提供方 %s 用户 %s 登录失败: %v", provider, identity.Subject, err)
+		if errors.Is(err, errSSOSignupDisabled) || errors.Is(err, errSSOUsernameTaken) {
+			redirectSSOResult(ctx, "sso_error", err.Error())
+		} else {
+			redirectSSOResult(ctx, "sso_error", "单点登录失败，请联系管理员")
+		}
+		return
+	}
+
+	ticket, _, err := auth.GenerateSSOTicket(user.ID, ssoTicketTTL)
+	if err != nil {
+		log.Error("签发单点登录票据失败: %v", err)
+		redirectSSOResult(ctx, "sso_error", "服务器错误")
+		return
+	}
+	log.Info("用户 %s 通过 %s 单点登录", user.Username, provider)
+	redirectSSOResult(ctx, "sso_ticket", ticket)
+}
+
+// HandleOIDCExchange 单点登录票据换取令牌
+// @Summary 单点登录票据换取令牌
+// @Description 前端使用回调跳转中的一次性票据换取访问令牌与刷新令牌，票据 1 分钟内有效且只能使用一次。账号已启用或被强制启用二次验证、且身份提供方未配置 trust_mfa 时，与密码登录一样返回 mfa_required 与 mfa_token，须继续调用 /auth/login/mfa
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param data body api.SSOExchangeRequest true "票据"
+// @Success 200 {object} map[string]interface{} "包含 token 和 refresh_token，或 mfa_required 与 mfa_token"
+// @Failure 401 {object} map[string]string "票据无效"
+// @Router /api/v1/auth/oidc/exchange [post]
+func HandleOIDCExchange(ctx *gin.Context) {
+	var req SSOExchangeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+	claims, err := auth.ParseSSOTicket(req.Ticket)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "登录票据无效或已过期"})
+		return
+	}
+	if err := auth.AddTokenToBlacklist(claims.ID, time.Until(claims.ExpiresAt.Time)); err != nil {
+		log.Error("作废单点登录票据失败: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+		return
+	}
+	user, err := models.GetUserByID(claims.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "用户不存在"})
+		return
+	}
+	if (user.TOTPEnabled || config.MFARequired(user.Role)) && !ssoTrustsMFA(user) {
+		if userActive(ctx, user) {
+			respondMFAChallenge(ctx, user)
+		}
+		return
+	}
+	if resp, ok := completeLogin(ctx, user); ok {
+		ctx.JSON(http.StatusOK, resp)
+	}
+}
+
+// ssoTrustsMFA 判断用户关联的身份提供方是否被配置为已执行多因素认证
+func ssoTrustsMFA(user *models.User) bool {
+	p, ok := config.FindOIDCProvider(user.AuthProvider)
+	return ok && p.TrustMFA
+}
+
+// provisionOIDCUser 按身份提供方用户标识查找用户；首次登录时按配置关联已有本地用户或创建新用户，并同步角色
+func provisionOIDCUser(ctx *gin.Context, cfg config.OIDCProvider, id *oidc.Identity) (*models.User, error) {
+	user, err := models.GetUserByExternalID(cfg.Name, id.Subject)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if user == nil && cfg.LinkByEmail && id.EmailVerified && id.Email != "" {
+		if local, err := models.GetLocalUserByEmail(id.Email); err == nil {
+			if err := models.LinkExternalIdentity(local.ID, cfg.Name, id.Subject); err != nil {
+				return nil, err
+			}
+			local.AuthProvider, local.ExternalID = cfg.Name, id.Subject
+			user = local
+			recordAudit(ctx, models.AuditSSOLink, fmt.Sprintf("user:%d", user.ID),
+				fmt.Sprintf("%s 关联 %s 账号 %s", user.Username, cfg.Name, id.Email))
+		}
+	}
+
+	if user == nil {
+		if cfg.AllowSignup != nil && !*cfg.AllowSignup {
+			return nil, errSSOSignupDisabled
+		}
+		if _, err := models.GetUserByUsername(id.Username); err == nil {
+			return nil, errSSOUsernameTaken
+		}
+		// 单点登录用户没有可用的本地密码
+		random, err := utils.GenerateSecureToken(32)
+		if err != nil {
+			return nil, err
+		}
+		hashed, err := utils.HashPassword(random)
+		if err != nil {
+			return nil, err
+		}
+		user = &models.User{
+			Username:     id.Username,
+			Password:     hashed,
+			Role:         oidc.ResolveRole(cfg, id.Groups),
+			Email:        id.Email,
+			AuthProvider: cfg.Name,
+			ExternalID:   id.Subject,
+		}
+		if err := models.CreateUser(user); err != nil {
+			return nil, err
+		}
+		recordAudit(ctx, models.AuditSSOProvision, fmt.Sprintf("user:%d", user.ID),
+			fmt.Sprintf("%s 通过 %s 首次登录，角色 %s", user.Username, cfg.Name, user.Role))
+		return user, nil
+	}
+
+	// 配置了角色声明时以身份提供方为准
+	if cfg.RoleClaim != "" {
+		if role := oidc.ResolveRole(cfg, id.Groups); role != user.Role {
+			if err := models.UpdateUserByID(user.ID, map[string]interface{}{"role": role}); err != nil {
+				return nil, err
+			}
+			recordAudit(ctx, models.AuditSSORole, fmt.Sprintf("user:%d", user.ID),
+				fmt.Sprintf("%s 角色由 %s 同步为 %s", user.Username, user.Role, role))
+			user.Role = role
+		}
+	}
+	return user, nil
+}
+
+// oidcRedirectURI 回调地址，优先使用 oidc.redirect_base_url
+func oidcRedirectURI(ctx *gin.Context, provider string) string {
+	base := strings.TrimRight(config.Global.OIDC.RedirectBaseURL, "/")
+	if base == "" {
+		scheme := "http"
+		if ctx.Request.TLS != nil || ctx.GetHeader("X-Forwarded-Proto") == "https" {
+			scheme = "https"
+		}
+		base = scheme + "://" + ctx.Request.Host
+	}
+	return base + fmt.Sprintf(oidcCallbackPath, url.PathEscape(provider))
+}
+
+// setOIDCStateCookie 写入或清除单点登录状态 Cookie；SameSite=Lax 以便身份提供方跳转回来时携带
+func setOIDCStateCookie(ctx *gin.Context, value string, maxAge int) {
+	secure := ctx.Request.TLS != nil || ctx.GetHeader("X-Forwarded-Proto") == "https"
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(oidcStateCookie, value, maxAge, oidcCookiePath, "", secure, true)
+}
+
+// redirectSSOResult 跳转到前端登录页，结果放在 URL 片段中，不会发送到服务器或出现在 Referer 中
+func redirectSSOResult(ctx *gin.Context, key, value string) {
+	ctx.Redirect(http.StatusFound, config.OIDCFrontendURL()+"#"+key+"="+url.QueryEscape(value))
+}
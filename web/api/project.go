@@ -0,0 +1,412 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"VulnFusion/internal/auth"
+	"VulnFusion/internal/log"
+	"VulnFusion/internal/models"
+	"VulnFusion/internal/rbac"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// HandleCreateProject 创建项目
+// @Summary 创建项目
+// @Description 创建新项目，创建人自动成为项目 owner
+// @Tags Project
+// @Accept json
+// @Produce json
+// @Param data body api.ProjectRequest true "项目信息"
+// @Success 200 {object} models.Project "创建的项目"
+// @Failure 400 {object} map[string]string "参数错误"
+// @Failure 500 {object} map[string]string "创建失败"
+// @Security ApiKeyAuth
+// @Router /api/v1/projects [post]
+func HandleCreateProject(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
+
+	var req ProjectRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Name) == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "项目名称不能为空"})
+		return
+	}
+
+	digestEmails, ok := validateProjectDigest(ctx, &req)
+	if !ok {
+		return
+	}
+
+	project := &models.Project{
+		Name:         strings.TrimSpace(req.Name),
+		Description:  req.Description,
+		CreatedBy:    claims.UserID,
+		Digest:       req.Digest,
+		DigestEmails: digestEmails,
+	}
+	if err := models.CreateProject(project); err != nil {
+		log.Error("创建项目失败: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "创建项目失败"})
+		return
+	}
+	ctx.JSON(http.StatusOK, project)
+}
+
+// HandleListMyProjects 获取当前用户参与的项目
+// @Summary 获取我的项目
+// @Description 返回当前用户作为成员的所有项目，管理员返回全部项目
+// @Tags Project
+// @Produce json
+// @Success 200 {array} models.Project "项目列表"
+// @Failure 500 {object} map[string]string "获取失败"
+// @Security ApiKeyAuth
+// @Router /api/v1/projects [get]
+func HandleListMyProjects(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
+
+	var projects []models.Project
+	var err error
+	if rbac.CanAny(claims.Role, rbac.ProjectReadAny, rbac.ProjectManageAny) {
+		projects, err = models.ListAllProjects()
+	} else {
+		projects, err = models.ListProjectsByUserID(claims.UserID)
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "获取项目失败"})
+		return
+	}
+	ctx.JSON(http.StatusOK, projects)
+}
+
+// HandleGetProject 获取项目详情
+// @Summary 获取项目详情
+// @Description 返回项目信息与成员列表，需为项目成员
+// @Tags Project
+// @Produce json
+// @Param id path int true "项目 ID"
+// @Success 200 {object} map[string]interface{} "项目与成员"
+// @Failure 403 {object} map[string]string "无权限访问"
+// @Failure 404 {object} map[string]string "项目不存在"
+// @Security ApiKeyAuth
+// @Router /api/v1/projects/{id} [get]
+func HandleGetProject(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
+	project, ok := loadProject(ctx, claims, models.ProjectRoleViewer)
+	if !ok {
+		return
+	}
+
+	members, err := models.ListProjectMembers(project.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "获取项目成员失败"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"project": project, "members": members})
+}
+
+// HandleUpdateProject 更新项目信息
+// @Summary 更新项目
+// @Description 修改项目名称、描述与高危漏洞摘要设置，需项目 owner
+// @Tags Project
+// @Accept json
+// @Produce json
+// @Param id path int true "项目 ID"
+// @Param data body api.ProjectRequest true "项目信息"
+// @Success 200 {object} map[string]string "更新成功"
+// @Failure 400 {object} map[string]string "参数错误"
+// @Failure 403 {object} map[string]string "权限不足"
+// @Failure 404 {object} map[string]string "项目不存在"
+// @Security ApiKeyAuth
+// @Router /api/v1/projects/{id} [put]
+func HandleUpdateProject(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
+	project, ok := loadProject(ctx, claims, models.ProjectRoleOwner)
+	if !ok {
+		return
+	}
+
+	var req ProjectRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Name) == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "项目名称不能为空"})
+		return
+	}
+
+	digestEmails, ok := validateProjectDigest(ctx, &req)
+	if !ok {
+		return
+	}
+
+	if err := models.UpdateProjectByID(project.ID, map[string]interface{}{
+		"name":          strings.TrimSpace(req.Name),
+		"description":   req.Description,
+		"digest":        req.Digest,
+		"digest_emails": digestEmails,
+	}); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "更新项目失败"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "项目已更新"})
+}
+
+// HandleDeleteProject 删除项目
+// @Summary 删除项目
+// @Description 删除项目及其成员与范围，项目下的任务转为创建人的个人任务，需项目 owner
+// @Tags Project
+// @Produce json
+// @Param id path int true "项目 ID"
+// @Success 200 {object} map[string]string "删除成功"
+// @Failure 403 {object} map[string]string "权限不足"
+// @Failure 404 {object} map[string]string "项目不存在"
+// @Security ApiKeyAuth
+// @Router /api/v1/projects/{id} [delete]
+func HandleDeleteProject(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
+	project, ok := loadProject(ctx, claims, models.ProjectRoleOwner)
+	if !ok {
+		return
+	}
+
+	if err := models.DeleteProjectByID(project.ID); err != nil {
+		log.Error("删除项目 %d 失败: %v", project.ID, err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "删除项目失败"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "项目已删除"})
+}
+
+// HandleSaveProjectMember 添加或更新项目成员
+// @Summary 设置项目成员
+// @Description 添加成员或修改已有成员的角色（owner / editor / viewer），需项目 owner
+// @Tags Project
+// @Accept json
+// @Produce json
+// @Param id path int true "项目 ID"
+// @Param data body api.ProjectMemberRequest true "成员信息"
+// @Success 200 {object} map[string]string "保存成功"
+// @Failure 400 {object} map[string]string "参数错误"
+// @Failure 403 {object} map[string]string "权限不足"
+// @Failure 404 {object} map[string]string "项目或用户不存在"
+// @Security ApiKeyAuth
+// @Router /api/v1/projects/{id}/members [post]
+func HandleSaveProjectMember(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
+	project, ok := loadProject(ctx, claims, models.ProjectRoleOwner)
+	if !ok {
+		return
+	}
+
+	var req ProjectMemberRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil || req.UserID == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+	if models.ProjectRoleRank(req.Role) == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "不支持的项目角色"})
+		return
+	}
+	if _, err := models.GetUserByID(req.UserID); err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+
+	// 降级最后一个 owner 会导致项目无人管理
+	if req.Role != models.ProjectRoleOwner && projectRole(project.ID, req.UserID) == models.ProjectRoleOwner {
+		if !ensureOtherOwner(ctx, project.ID) {
+			return
+		}
+	}
+
+	if err := models.SaveProjectMember(project.ID, req.UserID, req.Role); err != nil {
+		log.Error("保存项目 %d 成员失败: %v", project.ID, err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "保存成员失败"})
+		return
+	}
+	recordAudit(ctx, models.AuditProjectMember, fmt.Sprintf("project:%d", project.ID), fmt.Sprintf("用户 %d 角色设为 %s", req.UserID, req.Role))
+	ctx.JSON(http.StatusOK, gin.H{"message": "成员已保存"})
+}
+
+// HandleRemoveProjectMember 移除项目成员
+// @Summary 移除项目成员
+// @Description 将用户移出项目，需项目 owner；成员也可以移除自己；不能移除最后一个 owner
+// @Tags Project
+// @Produce json
+// @Param id path int true "项目 ID"
+// @Param user_id path int true "成员用户 ID"
+// @Success 200 {object} map[string]string "移除成功"
+// @Failure 400 {object} map[string]string "参数错误"
+// @Failure 403 {object} map[string]string "权限不足"
+// @Failure 404 {object} map[string]string "项目不存在"
+// @Security ApiKeyAuth
+// @Router /api/v1/projects/{id}/members/{user_id} [delete]
+func HandleRemoveProjectMember(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
+
+	userID, err := strconv.ParseUint(ctx.Param("user_id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "用户 ID 格式错误"})
+		return
+	}
+
+	need := models.ProjectRoleOwner
+	if uint(userID) == claims.UserID {
+		need = models.ProjectRoleViewer
+	}
+	project, ok := loadProject(ctx, claims, need)
+	if !ok {
+		return
+	}
+
+	if projectRole(project.ID, uint(userID)) == models.ProjectRoleOwner && !ensureOtherOwner(ctx, project.ID) {
+		return
+	}
+
+	if err := models.RemoveProjectMember(project.ID, uint(userID)); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "移除成员失败"})
+		return
+	}
+	recordAudit(ctx, models.AuditProjectMember, fmt.Sprintf("project:%d", project.ID), fmt.Sprintf("移除用户 %d", userID))
+	ctx.JSON(http.StatusOK, gin.H{"message": "成员已移除"})
+}
+
+// HandleGetProjectScope 获取项目扫描范围
+// @Summary 获取项目扫描范围
+// @Description 返回项目的扫描范围规则，项目任务按此范围检查，需为项目成员
+// @Tags Project
+// @Produce json
+// @Param id path int true "项目 ID"
+// @Success 200 {object} models.Scope "扫描范围"
+// @Failure 403 {object} map[string]string "无权限访问"
+// @Failure 404 {object} map[string]string "项目不存在"
+// @Security ApiKeyAuth
+// @Router /api/v1/projects/{id}/scope [get]
+func HandleGetProjectScope(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
+	project, ok := loadProject(ctx, claims, models.ProjectRoleViewer)
+	if !ok {
+		return
+	}
+
+	s, err := models.GetScopeByProjectID(project.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		ctx.JSON(http.StatusOK, models.Scope{ProjectID: project.ID, Rules: []models.ScopeRule{}})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "获取扫描范围失败"})
+		return
+	}
+	ctx.JSON(http.StatusOK, s)
+}
+
+// HandleUpdateProjectScope 设置项目扫描范围
+// @Summary 设置项目扫描范围
+// @Description 整体替换项目的扫描范围规则，需要 scope.manage 权限（项目 owner 不能自行放宽范围）
+// @Tags Project
+// @Accept json
+// @Produce json
+// @Param id path int true "项目 ID"
+// @Param data body api.ScopeRequest true "范围规则"
+// @Success 200 {object} models.Scope "保存后的扫描范围"
+// @Failure 400 {object} map[string]string "规则格式错误"
+// @Failure 403 {object} map[string]string "权限不足"
+// @Failure 404 {object} map[string]string "项目不存在"
+// @Security ApiKeyAuth
+// @Router /api/v1/projects/{id}/scope [put]
+func HandleUpdateProjectScope(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
+	// 范围由具备 scope.manage 的管理员统一设置，不要求其为项目成员
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "项目 ID 格式错误"})
+		return
+	}
+	project, err := models.GetProjectByID(uint(id))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "项目不存在"})
+		return
+	}
+
+	var req ScopeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+	rules, err := buildScopeRules(req.Rules)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	s, err := models.GetScopeByProjectID(project.ID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "获取扫描范围失败"})
+		return
+	}
+	if s == nil {
+		s = &models.Scope{UserID: claims.UserID, ProjectID: project.ID}
+	}
+	s.Name = req.Name
+
+	if err := models.SaveScopeWithRules(s, rules); err != nil {
+		log.Error("保存项目 %d 扫描范围失败: %v", project.ID, err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "保存扫描范围失败"})
+		return
+	}
+
+	recordAudit(ctx, models.AuditScopeUpdate, fmt.Sprintf("scope:%d", s.ID), fmt.Sprintf("项目 %d 规则数 %d", project.ID, len(rules)))
+	ctx.JSON(http.StatusOK, s)
+}
+
+// loadProject 读取路径中的项目并校验调用者角色；ok 为 false 时已写入错误响应
+func loadProject(ctx *gin.Context, claims *auth.CustomClaims, need string) (*models.Project, bool) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "项目 ID 格式错误"})
+		return nil, false
+	}
+
+	project, err := models.GetProjectByID(uint(id))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "项目不存在"})
+		return nil, false
+	}
+
+	if !canAccessProject(claims, project.ID, need) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "无权限操作此项目"})
+		return nil, false
+	}
+	return project, true
+}
+
+// validateProjectDigest 校验项目摘要设置，返回规范化的收件人列表；ok 为 false 时已写入错误响应
+func validateProjectDigest(ctx *gin.Context, req *ProjectRequest) (string, bool) {
+	if !models.IsValidDigest(req.Digest) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "不支持的摘要频率"})
+		return "", false
+	}
+	emails, err := normalizeEmails(req.DigestEmails)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "摘要收件人邮箱格式错误"})
+		return "", false
+	}
+	return emails, true
+}
+
+// ensureOtherOwner 确认项目除待变更成员外仍有其他 owner；ok 为 false 时已写入错误响应
+func ensureOtherOwner(ctx *gin.Context, projectID uint) bool {
+	count, err := models.CountProjectOwners(projectID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "获取项目成员失败"})
+		return false
+	}
+	if count <= 1 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "项目至少需要保留一个 owner"})
+		return false
+	}
+	return true
+}
@@ -5,13 +5,16 @@ import (
 	"strconv"
 
 	"VulnFusion/internal/auth"
+	"VulnFusion/internal/enrich"
+	"VulnFusion/internal/log"
 	"VulnFusion/internal/models"
+	"VulnFusion/internal/provenance"
 	"github.com/gin-gonic/gin"
 )
 
 // HandleListResultsByTask 获取指定任务的扫描结果
 // @Summary 获取任务扫描结果
-// @Description 获取某个任务 ID 下所有扫描结果（用户或管理员）
+// @Description 获取某个任务 ID 下所有扫描结果（任务创建人、项目成员或管理员）
 // @Tags Result
 // @Produce json
 // @Param task_id path int true "任务 ID"
@@ -30,7 +33,7 @@ func HandleListResultsByTask(ctx *gin.Context) {
 	}
 
 	task, err := models.GetTaskByID(uint(taskID))
-	if err != nil || (task.UserID != claims.UserID && claims.Role != "admin") {
+	if err != nil || !canReadResults(claims, task) {
 		ctx.JSON(http.StatusForbidden, gin.H{"error": "无权限访问此任务结果"})
 		return
 	}
@@ -55,12 +58,6 @@ func HandleListResultsByTask(ctx *gin.Context) {
 // @Security ApiKeyAuth
 // @Router /api/v1/admin/results [get]
 func HandleListAllResults(ctx *gin.Context) {
-	claims := ctx.MustGet("claims").(*auth.CustomClaims)
-	if claims.Role != "admin" {
-		ctx.JSON(http.StatusForbidden, gin.H{"error": "权限不足"})
-		return
-	}
-
 	results, err := models.ListAllResults()
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "获取结果失败"})
@@ -72,15 +69,18 @@ func HandleListAllResults(ctx *gin.Context) {
 
 // HandleGetResultDetail 获取扫描结果详情
 // @Summary 查看单个扫描结果
-// @Description 根据结果 ID 查询具体的漏洞信息
+// @Description 根据结果 ID 查询具体的漏洞信息，并附带本地漏洞库中关联 CVE / CWE 的描述、CVSS、受影响 CPE 与参考链接
 // @Tags Result
 // @Produce json
 // @Param id path int true "扫描结果 ID"
-// @Success 200 {object} models.Result "扫描结果详情"
+// @Success 200 {object} api.ResultDetailResponse "扫描结果详情"
 // @Failure 400 {object} map[string]string "参数格式错误"
+// @Failure 403 {object} map[string]string "无权限访问"
 // @Failure 404 {object} map[string]string "结果不存在"
+// @Security ApiKeyAuth
 // @Router /api/v1/results/{id} [get]
 func HandleGetResultDetail(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
 	resultID, err := strconv.Atoi(ctx.Param("id"))
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "ID 格式错误"})
@@ -93,12 +93,23 @@ func HandleGetResultDetail(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, result)
+	task, err := models.GetTaskByID(result.TaskID)
+	if err != nil || !canReadResults(claims, task) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "无权限访问此结果"})
+		return
+	}
+
+	enrichment, err := enrich.Lookup(result)
+	if err != nil {
+		log.Warn("查询结果 %d 的漏洞库信息失败: %v", result.ID, err)
+		enrichment = &enrich.Enrichment{CVEs: []enrich.CVEDetail{}, CWEs: []enrich.CWEDetail{}}
+	}
+	ctx.JSON(http.StatusOK, ResultDetailResponse{Result: *result, Enrichment: enrichment})
 }
 
 // HandleDeleteResultsByTask 删除任务的所有扫描结果
 // @Summary 删除任务结果
-// @Description 删除指定任务下所有扫描结果（用户本人或管理员）
+// @Description 删除指定任务下所有扫描结果（任务创建人、项目 editor 及以上或管理员）
 // @Tags Result
 // @Produce json
 // @Param task_id path int true "任务 ID"
@@ -117,7 +128,7 @@ func HandleDeleteResultsByTask(ctx *gin.Context) {
 	}
 
 	task, err := models.GetTaskByID(uint(taskID))
-	if err != nil || (task.UserID != claims.UserID && claims.Role != "admin") {
+	if err != nil || !canAccessTask(claims, task, models.ProjectRoleEditor) {
 		ctx.JSON(http.StatusForbidden, gin.H{"error": "无权限删除此任务结果"})
 		return
 	}
@@ -138,16 +149,24 @@ func HandleDeleteResultsByTask(ctx *gin.Context) {
 // @Param task_id path int true "任务 ID"
 // @Success 200 {object} map[string]interface{} "任务及其结果数据"
 // @Failure 400 {object} map[string]string "参数错误"
+// @Failure 403 {object} map[string]string "无权限"
 // @Failure 500 {object} map[string]string "导出失败"
 // @Security ApiKeyAuth
 // @Router /api/v1/results/export/{task_id} [get]
 func HandleExportResults(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
 	taskID, err := strconv.Atoi(ctx.Param("task_id"))
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "任务 ID 错误"})
 		return
 	}
 
+	task, err := models.GetTaskByID(uint(taskID))
+	if err != nil || !canReadResults(claims, task) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "无权限访问此任务结果"})
+		return
+	}
+
 	results, err := models.ListResultsByTaskID(uint(taskID))
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "导出失败"})
@@ -159,3 +178,134 @@ func HandleExportResults(ctx *gin.Context) {
 		"results": results,
 	})
 }
+
+// HandleGetResultProvenance 查看扫描结果来源并复核签名
+// @Summary 查看结果来源
+// @Description 返回结果的节点 ID、nuclei 版本与模板库摘要；对节点回传的结果重新校验批次签名并确认结果包含在签名批次中
+// @Tags Result
+// @Produce json
+// @Param id path int true "扫描结果 ID"
+// @Success 200 {object} map[string]interface{} "来源信息与校验结论"
+// @Failure 400 {object} map[string]string "参数格式错误"
+// @Failure 403 {object} map[string]string "无权限访问"
+// @Failure 404 {object} map[string]string "结果不存在"
+// @Security ApiKeyAuth
+// @Router /api/v1/results/{id}/provenance [get]
+func HandleGetResultProvenance(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
+	resultID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "ID 格式错误"})
+		return
+	}
+
+	result, err := models.GetResultByID(uint(resultID))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "结果不存在"})
+		return
+	}
+	task, err := models.GetTaskByID(result.TaskID)
+	if err != nil || !canReadResults(claims, task) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "无权限访问此结果"})
+		return
+	}
+
+	resp := gin.H{
+		"result_id":      result.ID,
+		"task_id":        result.TaskID,
+		"agent_id":       result.AgentID,
+		"nuclei_version": result.NucleiVersion,
+		"template_hash":  result.TemplateHash,
+		"signed":         result.BatchID != 0,
+	}
+	if result.BatchID == 0 {
+		resp["source"] = "server"
+		ctx.JSON(http.StatusOK, resp)
+		return
+	}
+
+	batch, err := models.GetResultBatchByID(result.BatchID)
+	if err != nil {
+		resp["verified"] = false
+		resp["reason"] = "签名批次记录缺失"
+		ctx.JSON(http.StatusOK, resp)
+		return
+	}
+
+	resp["source"] = batch.Source
+	resp["batch_id"] = batch.ID
+	resp["sequence"] = batch.Sequence
+	resp["payload_hash"] = batch.PayloadHash
+	resp["received_at"] = batch.ReceivedAt
+
+	decoded, _, err := provenance.Verify(provenance.SignedBatch{Payload: batch.Payload, Signature: batch.Signature}, batch.PublicKey)
+	switch {
+	case err != nil:
+		resp["verified"] = false
+		resp["reason"] = err.Error()
+	case decoded.TaskID != result.TaskID || decoded.AgentID != result.AgentID:
+		resp["verified"] = false
+		resp["reason"] = "批次归属与结果不符"
+	case !containsLine(decoded.Lines, result.Detail):
+		resp["verified"] = false
+		resp["reason"] = "结果内容不在签名批次中"
+	default:
+		resp["verified"] = true
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+func containsLine(lines []string, line string) bool {
+	for _, l := range lines {
+		if l == line {
+			return true
+		}
+	}
+	return false
+}
+
+// HandleUpdateResultStatus 更新扫描结果处置状态
+// @Summary 处置扫描结果
+// @Description 将结果标记为 open / fixed / accepted / false_positive，需任务 editor 及以上权限
+// @Tags Result
+// @Accept json
+// @Produce json
+// @Param id path int true "扫描结果 ID"
+// @Param data body api.ResultStatusRequest true "处置状态"
+// @Success 200 {object} map[string]string "更新成功"
+// @Failure 400 {object} map[string]string "参数错误"
+// @Failure 403 {object} map[string]string "无权限"
+// @Failure 404 {object} map[string]string "结果不存在"
+// @Security ApiKeyAuth
+// @Router /api/v1/results/{id}/status [put]
+func HandleUpdateResultStatus(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
+	resultID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "ID 格式错误"})
+		return
+	}
+
+	var req ResultStatusRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil || !models.IsValidResultStatus(req.Status) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "不支持的处置状态"})
+		return
+	}
+
+	result, err := models.GetResultByID(uint(resultID))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "结果不存在"})
+		return
+	}
+	task, err := models.GetTaskByID(result.TaskID)
+	if err != nil || !canAccessTask(claims, task, models.ProjectRoleEditor) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "无权限处置此结果"})
+		return
+	}
+
+	if err := models.UpdateResultStatus(result.ID, req.Status); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "更新处置状态失败"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "处置状态已更新"})
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"net/http"
+
+	"VulnFusion/internal/log"
+	"VulnFusion/internal/risk"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleImportLikelihood 导入利用可能性数据（管理员）
+// @Summary 导入利用可能性数据
+// @Description 上传 EPSS 风格的 CSV（表头包含 cve、epss，可选 percentile），导入后重新计算全部风险分
+// @Tags Admin
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "CSV 文件"
+// @Success 200 {object} map[string]interface{} "导入条数"
+// @Failure 400 {object} map[string]string "文件格式错误"
+// @Security ApiKeyAuth
+// @Router /api/v1/admin/risk/likelihood [post]
+func HandleImportLikelihood(ctx *gin.Context) {
+	header, err := ctx.FormFile("file")
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "请上传 CSV 文件"})
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "读取文件失败"})
+		return
+	}
+	defer file.Close()
+
+	count, err := risk.ImportLikelihood(file)
+	if err != nil {
+		log.Warn("导入利用可能性数据失败: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "导入成功", "imported": count})
+}
+
+// HandleRecalculateRisk 重新计算全部风险分（管理员）
+// @Summary 重新计算风险分
+// @Description 调整评分配置后按当前规则重新计算全部扫描结果的风险分
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} map[string]interface{} "处理条数"
+// @Failure 500 {object} map[string]string "计算失败"
+// @Security ApiKeyAuth
+// @Router /api/v1/admin/risk/recalculate [post]
+func HandleRecalculateRisk(ctx *gin.Context) {
+	count, err := risk.RecalculateAll()
+	if err != nil {
+		log.Error("重新计算风险分失败: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "重新计算风险分失败"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "风险分已更新", "updated": count})
+}
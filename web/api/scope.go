@@ -0,0 +1,287 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"VulnFusion/internal/auth"
+	"VulnFusion/internal/config"
+	"VulnFusion/internal/log"
+	"VulnFusion/internal/models"
+	"VulnFusion/internal/scope"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// HandleGetMyScope 获取当前用户的扫描范围
+// @Summary 获取扫描范围
+// @Description 返回当前用户的扫描范围及包含/排除规则，未配置时返回空规则
+// @Tags Scope
+// @Produce json
+// @Success 200 {object} models.Scope "扫描范围"
+// @Failure 500 {object} map[string]string "获取失败"
+// @Security ApiKeyAuth
+// @Router /api/v1/scope [get]
+func HandleGetMyScope(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
+	respondUserScope(ctx, claims.UserID)
+}
+
+// HandleUpdateMyScope 设置当前用户的扫描范围
+// @Summary 设置扫描范围
+// @Description 整体替换当前用户的扫描范围规则：domain（支持 *. 通配）、cidr、url 前缀、port 范围；需要 scope.manage 权限
+// @Tags Scope
+// @Accept json
+// @Produce json
+// @Param data body api.ScopeRequest true "范围规则"
+// @Success 200 {object} models.Scope "保存后的扫描范围"
+// @Failure 400 {object} map[string]string "规则格式错误"
+// @Failure 500 {object} map[string]string "保存失败"
+// @Security ApiKeyAuth
+// @Router /api/v1/scope [put]
+func HandleUpdateMyScope(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
+	saveUserScope(ctx, claims.UserID)
+}
+
+// HandleGetUserScope 获取指定用户的扫描范围
+// @Summary 获取用户扫描范围（管理员）
+// @Description 返回指定用户的个人扫描范围，未配置时返回空规则
+// @Tags Admin
+// @Produce json
+// @Param id path int true "用户 ID"
+// @Success 200 {object} models.Scope "扫描范围"
+// @Failure 400 {object} map[string]string "无效的用户 ID"
+// @Failure 404 {object} map[string]string "用户不存在"
+// @Failure 500 {object} map[string]string "获取失败"
+// @Security ApiKeyAuth
+// @Router /api/v1/admin/users/{id}/scope [get]
+func HandleGetUserScope(ctx *gin.Context) {
+	user, ok := loadScopeUser(ctx)
+	if !ok {
+		return
+	}
+	respondUserScope(ctx, user.ID)
+}
+
+// HandleUpdateUserScope 设置指定用户的扫描范围
+// @Summary 设置用户扫描范围（管理员）
+// @Description 整体替换指定用户的个人扫描范围规则，该用户创建的个人任务按此范围检查
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param id path int true "用户 ID"
+// @Param data body api.ScopeRequest true "范围规则"
+// @Success 200 {object} models.Scope "保存后的扫描范围"
+// @Failure 400 {object} map[string]string "规则格式错误"
+// @Failure 404 {object} map[string]string "用户不存在"
+// @Failure 500 {object} map[string]string "保存失败"
+// @Security ApiKeyAuth
+// @Router /api/v1/admin/users/{id}/scope [put]
+func HandleUpdateUserScope(ctx *gin.Context) {
+	user, ok := loadScopeUser(ctx)
+	if !ok {
+		return
+	}
+	saveUserScope(ctx, user.ID)
+}
+
+// loadScopeUser 读取路径中的用户；ok 为 false 时已写入错误响应
+func loadScopeUser(ctx *gin.Context) (*models.User, bool) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户 ID"})
+		return nil, false
+	}
+	user, err := models.GetUserByID(uint(id))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return nil, false
+	}
+	return user, true
+}
+
+// respondUserScope 返回用户的个人扫描范围，未配置时返回空规则
+func respondUserScope(ctx *gin.Context, userID uint) {
+	s, err := models.GetScopeByUserID(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		ctx.JSON(http.StatusOK, models.Scope{UserID: userID, Rules: []models.ScopeRule{}})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "获取扫描范围失败"})
+		return
+	}
+	ctx.JSON(http.StatusOK, s)
+}
+
+// saveUserScope 按请求整体替换用户的个人扫描范围并写入审计日志
+func saveUserScope(ctx *gin.Context, userID uint) {
+	var req ScopeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+	rules, err := buildScopeRules(req.Rules)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	s, err := models.GetScopeByUserID(userID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "获取扫描范围失败"})
+		return
+	}
+	if s == nil {
+		s = &models.Scope{UserID: userID}
+	}
+	s.Name = req.Name
+
+	if err := models.SaveScopeWithRules(s, rules); err != nil {
+		log.Error("保存扫描范围失败: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "保存扫描范围失败"})
+		return
+	}
+
+	recordAudit(ctx, models.AuditScopeUpdate, fmt.Sprintf("scope:%d", s.ID), fmt.Sprintf("用户 %d 规则数 %d", userID, len(rules)))
+	ctx.JSON(http.StatusOK, s)
+}
+
+// HandleCheckScope 检查目标是否在扫描范围内
+// @Summary 检查目标范围
+// @Description 按当前用户（或指定项目）的扫描范围逐个检查目标，返回是否在范围内及原因
+// @Tags Scope
+// @Accept json
+// @Produce json
+// @Param data body api.ScopeCheckRequest true "待检查目标"
+// @Success 200 {array} map[string]interface{} "检查结果"
+// @Failure 400 {object} map[string]string "参数错误"
+// @Failure 403 {object} map[string]string "无权限访问项目"
+// @Security ApiKeyAuth
+// @Router /api/v1/scope/check [post]
+func HandleCheckScope(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
+
+	var req ScopeCheckRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+
+	if req.ProjectID != 0 && !canAccessProject(claims, req.ProjectID, models.ProjectRoleViewer) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "无权限访问此项目"})
+		return
+	}
+
+	rules, err := loadScopeRules(claims.UserID, req.ProjectID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "获取扫描范围失败"})
+		return
+	}
+
+	results := make([]gin.H, 0)
+	for _, target := range scope.SplitTargets(req.Targets) {
+		ok, reason := checkScope(target, rules)
+		results = append(results, gin.H{"target": target, "in_scope": ok, "reason": reason})
+	}
+	ctx.JSON(http.StatusOK, results)
+}
+
+// enforceScope 按扫描范围过滤任务目标；存在越界目标时按配置整体拒绝或剔除，并写入审计日志
+// projectID 非 0 时按项目范围检查；返回保留的目标，ok 为 false 时已写入错误响应
+func enforceScope(ctx *gin.Context, userID, projectID uint, rawTargets string) ([]string, bool) {
+	targets := scope.SplitTargets(rawTargets)
+	if len(targets) == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "目标不能为空"})
+		return nil, false
+	}
+
+	rules, err := loadScopeRules(userID, projectID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "获取扫描范围失败"})
+		return nil, false
+	}
+
+	var kept []string
+	var rejected []gin.H
+	for _, t := range targets {
+		if ok, reason := checkScope(t, rules); ok {
+			kept = append(kept, t)
+		} else {
+			rejected = append(rejected, gin.H{"target": t, "reason": reason})
+		}
+	}
+	if len(rejected) == 0 {
+		return kept, true
+	}
+
+	detail := describeRejected(rejected)
+	if config.GetScopeEnforcement() == "strip" && len(kept) > 0 {
+		log.Warn("用户 %d 创建任务时剔除越界目标: %s", userID, detail)
+		recordAudit(ctx, models.AuditScopeStrip, "task", detail)
+		return kept, true
+	}
+
+	log.Warn("用户 %d 创建任务时目标越界被拒绝: %s", userID, detail)
+	recordAudit(ctx, models.AuditScopeReject, "task", detail)
+	ctx.JSON(http.StatusBadRequest, gin.H{"error": "存在超出扫描范围的目标", "out_of_scope": rejected})
+	return nil, false
+}
+
+// checkScope 检查目标是否在范围内；未配置任何规则时按 scope.require_scope 决定放行或拒绝
+func checkScope(target string, rules []scope.Rule) (bool, string) {
+	if len(rules) == 0 && config.ScopeRequired() {
+		return false, "未配置扫描范围，请联系管理员设置"
+	}
+	return scope.Check(target, rules)
+}
+
+// loadScopeRules 读取扫描范围规则：projectID 非 0 时使用项目范围，否则使用用户个人范围；
+// 未配置范围时返回空规则
+func loadScopeRules(userID, projectID uint) ([]scope.Rule, error) {
+	var s *models.Scope
+	var err error
+	if projectID != 0 {
+		s, err = models.GetScopeByProjectID(projectID)
+	} else {
+		s, err = models.GetScopeByUserID(userID)
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make([]scope.Rule, 0, len(s.Rules))
+	for _, r := range s.Rules {
+		rules = append(rules, scope.Rule{Action: r.Action, Type: r.Type, Value: r.Value})
+	}
+	return rules, nil
+}
+
+// buildScopeRules 校验请求中的规则并转换为模型
+func buildScopeRules(input []scope.Rule) ([]models.ScopeRule, error) {
+	rules := make([]models.ScopeRule, 0, len(input))
+	for _, r := range input {
+		r.Value = strings.TrimSpace(r.Value)
+		if err := scope.ValidateRule(r); err != nil {
+			return nil, err
+		}
+		rules = append(rules, models.ScopeRule{Action: r.Action, Type: r.Type, Value: r.Value})
+	}
+	return rules, nil
+}
+
+func describeRejected(rejected []gin.H) string {
+	parts := make([]string, 0, len(rejected))
+	for _, r := range rejected {
+		parts = append(parts, fmt.Sprintf("%v（%v）", r["target"], r["reason"]))
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,208 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"VulnFusion/internal/auth"
+	"VulnFusion/internal/config"
+	"VulnFusion/internal/log"
+	"VulnFusion/internal/models"
+	"VulnFusion/internal/rbac"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SessionView 会话列表项，current 标记发起请求的会话
+type SessionView struct {
+	models.Session
+	Current bool `json:"current"`
+}
+
+// startSession 登录成功后记录会话，失败时仅记录日志
+func startSession(ctx *gin.Context, userID uint, family string) {
+	now := time.Now()
+	ua := ctx.Request.UserAgent()
+	session := &models.Session{
+		UserID:     userID,
+		Family:     family,
+		Device:     describeUserAgent(ua),
+		UserAgent:  ua,
+		IP:         ctx.ClientIP(),
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(config.DefaultRefreshTokenTTL()),
+	}
+	if err := models.CreateSession(session); err != nil {
+		log.Warn("记录用户 %d 的登录会话失败: %v", userID, err)
+	}
+}
+
+// revokeSessionFamily 吊销一次登录会话：令牌族、服务端刷新令牌与会话记录
+func revokeSessionFamily(family string) error {
+	if err := auth.RevokeTokenFamily(family); err != nil {
+		return err
+	}
+	if err := models.RevokeRefreshTokensByFamily(family); err != nil {
+		log.Warn("吊销令牌族的刷新令牌失败: %v", err)
+	}
+	if err := models.RevokeSessionByFamily(family); err != nil {
+		log.Warn("更新会话吊销状态失败: %v", err)
+	}
+	return nil
+}
+
+// revokeAllSessions 吊销用户的全部登录会话
+func revokeAllSessions(userID uint) error {
+	if err := auth.RevokeUserTokens(userID); err != nil {
+		return err
+	}
+	if err := models.RevokeRefreshTokensByUserID(userID); err != nil {
+		log.Warn("吊销用户 %d 的刷新令牌失败: %v", userID, err)
+	}
+	if err := models.RevokeSessionsByUserID(userID); err != nil {
+		log.Warn("更新用户 %d 的会话吊销状态失败: %v", userID, err)
+	}
+	return nil
+}
+
+// describeUserAgent 从 User-Agent 中粗略识别浏览器与操作系统，如 "Chrome / Windows"
+func describeUserAgent(ua string) string {
+	if ua == "" {
+		return "未知设备"
+	}
+	browser := "其他客户端"
+	switch {
+	case strings.Contains(ua, "Edg/"):
+		browser = "Edge"
+	case strings.Contains(ua, "OPR/"):
+		browser = "Opera"
+	case strings.Contains(ua, "Firefox/"):
+		browser = "Firefox"
+	case strings.Contains(ua, "Chrome/"):
+		browser = "Chrome"
+	case strings.Contains(ua, "Safari/"):
+		browser = "Safari"
+	case strings.HasPrefix(ua, "curl/"):
+		browser = "curl"
+	case strings.Contains(ua, "python-requests"):
+		browser = "Python"
+	case strings.HasPrefix(ua, "Go-http-client"):
+		browser = "Go"
+	}
+
+	system := ""
+	switch {
+	case strings.Contains(ua, "Windows"):
+		system = "Windows"
+	case strings.Contains(ua, "iPhone"), strings.Contains(ua, "iPad"):
+		system = "iOS"
+	case strings.Contains(ua, "Mac OS X"), strings.Contains(ua, "Macintosh"):
+		system = "macOS"
+	case strings.Contains(ua, "Android"):
+		system = "Android"
+	case strings.Contains(ua, "Linux"):
+		system = "Linux"
+	}
+	if system == "" {
+		return browser
+	}
+	return browser + " / " + system
+}
+
+// sessionViews 标记当前会话
+func sessionViews(sessions []models.Session, claims *auth.CustomClaims) []SessionView {
+	views := make([]SessionView, 0, len(sessions))
+	for _, s := range sessions {
+		views = append(views, SessionView{Session: s, Current: claims.Family != "" && s.Family == claims.Family})
+	}
+	return views
+}
+
+// HandleListSessions 获取当前用户的登录会话
+// @Summary 获取我的登录会话
+// @Description 返回当前用户未过期且未吊销的登录会话，包括设备、IP、User-Agent、登录时间与最近使用时间；current 为 true 的是当前会话
+// @Tags Session
+// @Produce json
+// @Success 200 {array} api.SessionView "会话列表"
+// @Failure 500 {object} map[string]string "获取失败"
+// @Security ApiKeyAuth
+// @Router /api/v1/sessions [get]
+func HandleListSessions(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
+
+	sessions, err := models.ListActiveSessionsByUserID(claims.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "获取会话失败"})
+		return
+	}
+	ctx.JSON(http.StatusOK, sessionViews(sessions, claims))
+}
+
+// HandleRevokeSession 吊销登录会话
+// @Summary 吊销登录会话
+// @Description 吊销指定会话的访问令牌与刷新令牌，该设备需重新登录；仅会话所属用户或管理员可操作
+// @Tags Session
+// @Produce json
+// @Param id path int true "会话 ID"
+// @Success 200 {object} map[string]string "吊销成功"
+// @Failure 403 {object} map[string]string "无权限"
+// @Failure 404 {object} map[string]string "会话不存在"
+// @Security ApiKeyAuth
+// @Router /api/v1/sessions/{id} [delete]
+func HandleRevokeSession(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
+
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "会话 ID 格式错误"})
+		return
+	}
+	session, err := models.GetSessionByID(uint(id))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "会话不存在"})
+		return
+	}
+	if session.UserID != claims.UserID && !rbac.Can(claims.Role, rbac.SessionManageAny) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "无权限吊销此会话"})
+		return
+	}
+
+	if err := revokeSessionFamily(session.Family); err != nil {
+		log.Error("吊销会话 %d 失败: %v", session.ID, err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "吊销会话失败"})
+		return
+	}
+	recordAudit(ctx, models.AuditSessionRevoke, fmt.Sprintf("session:%d", session.ID),
+		fmt.Sprintf("user:%d %s %s", session.UserID, session.Device, session.IP))
+	ctx.JSON(http.StatusOK, gin.H{"message": "会话已吊销"})
+}
+
+// HandleListUserSessions 管理员查看用户的登录会话
+// @Summary 获取用户登录会话（管理员）
+// @Description 返回指定用户未过期且未吊销的登录会话，可配合 DELETE /api/v1/sessions/{id} 吊销单个会话或 /admin/users/{id}/revoke-tokens 全部下线
+// @Tags Admin
+// @Produce json
+// @Param id path int true "用户 ID"
+// @Success 200 {array} api.SessionView "会话列表"
+// @Failure 400 {object} map[string]string "ID 错误"
+// @Failure 500 {object} map[string]string "获取失败"
+// @Security ApiKeyAuth
+// @Router /api/v1/admin/users/{id}/sessions [get]
+func HandleListUserSessions(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
+
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户 ID"})
+		return
+	}
+	sessions, err := models.ListActiveSessionsByUserID(uint(id))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "获取会话失败"})
+		return
+	}
+	ctx.JSON(http.StatusOK, sessionViews(sessions, claims))
+}
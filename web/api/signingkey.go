@@ -0,0 +1,94 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"VulnFusion/internal/auth"
+	"VulnFusion/internal/log"
+	"VulnFusion/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RotateSigningKeyRequest 轮换签名密钥参数
+type RotateSigningKeyRequest struct {
+	Algorithm string `json:"algorithm"` // HS256 / EdDSA / RS256，为空时使用 jwt.algorithm 配置
+}
+
+// HandleJWKS 获取 JWT 公钥集合
+// @Summary 获取 JWT 公钥集合（JWKS）
+// @Description 返回当前及宽限期内的非对称签名密钥公钥（RFC 7517），其他服务可据此按 kid 验证本系统签发的令牌；HS256 密钥不发布
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} auth.JWKS "公钥集合"
+// @Failure 500 {object} map[string]string "获取失败"
+// @Router /.well-known/jwks.json [get]
+func HandleJWKS(ctx *gin.Context) {
+	set, err := auth.PublicJWKS()
+	if err != nil {
+		log.Error("获取 JWKS 失败: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "获取公钥失败"})
+		return
+	}
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, set)
+}
+
+// HandleListSigningKeys 获取签名密钥列表
+// @Summary 获取 JWT 签名密钥（管理员）
+// @Description 返回当前签名密钥及宽限期内仍可验证的下线密钥，不含密钥材料
+// @Tags Admin
+// @Produce json
+// @Success 200 {array} auth.SigningKeyInfo "密钥列表"
+// @Failure 500 {object} map[string]string "获取失败"
+// @Security ApiKeyAuth
+// @Router /api/v1/admin/signing-keys [get]
+func HandleListSigningKeys(ctx *gin.Context) {
+	keys, err := auth.ListSigningKeys()
+	if err != nil {
+		log.Error("获取签名密钥失败: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "获取签名密钥失败"})
+		return
+	}
+	ctx.JSON(http.StatusOK, keys)
+}
+
+// HandleRotateSigningKey 轮换签名密钥
+// @Summary 轮换 JWT 签名密钥（管理员）
+// @Description 生成新的签名密钥用于后续签发，当前密钥下线但在 jwt.key_grace_period 内仍可验证，已签发的令牌不受影响；配置了 jwt.secret 时不可轮换
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param data body api.RotateSigningKeyRequest false "轮换参数"
+// @Success 200 {object} auth.SigningKeyInfo "新密钥"
+// @Failure 400 {object} map[string]string "参数错误"
+// @Failure 409 {object} map[string]string "固定密钥不可轮换"
+// @Security ApiKeyAuth
+// @Router /api/v1/admin/signing-keys/rotate [post]
+func HandleRotateSigningKey(ctx *gin.Context) {
+	var req RotateSigningKeyRequest
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+			return
+		}
+	}
+	if req.Algorithm != "" && auth.NormalizeAlgorithm(req.Algorithm) == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "不支持的签名算法，可选 HS256 / EdDSA / RS256"})
+		return
+	}
+	if auth.HasStaticSigningKey() {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "已配置 jwt.secret，固定密钥不支持轮换"})
+		return
+	}
+
+	key, err := auth.RotateSigningKey(req.Algorithm)
+	if err != nil {
+		log.Error("轮换签名密钥失败: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "轮换签名密钥失败"})
+		return
+	}
+	recordAudit(ctx, models.AuditSigningKeyRotate, fmt.Sprintf("signing-key:%s", key.ID), key.Algorithm)
+	ctx.JSON(http.StatusOK, key)
+}
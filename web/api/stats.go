@@ -0,0 +1,61 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"VulnFusion/internal/auth"
+	"VulnFusion/internal/log"
+	"VulnFusion/internal/models"
+	"VulnFusion/internal/rbac"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxStatsDays 时间序列最多回溯的天数
+const maxStatsDays = 365
+
+// HandleGetStats 获取仪表盘统计数据
+// @Summary 仪表盘统计
+// @Description 按调用者可见范围（个人任务与所在项目，管理员为全部）聚合漏洞严重等级、任务状态、Top 模板与主机、每日新增/关闭数及平均修复时长
+// @Tags Stats
+// @Produce json
+// @Param project_id query int false "项目 ID"
+// @Param days query int false "时间序列天数，默认 30，最大 365"
+// @Param top query int false "Top 条数，默认 10"
+// @Success 200 {object} models.Stats "统计数据"
+// @Failure 400 {object} map[string]string "参数错误"
+// @Failure 403 {object} map[string]string "无权限访问项目"
+// @Failure 500 {object} map[string]string "统计失败"
+// @Security ApiKeyAuth
+// @Router /api/v1/stats [get]
+func HandleGetStats(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
+
+	projectID, err1 := strconv.ParseUint(ctx.DefaultQuery("project_id", "0"), 10, 64)
+	days, err2 := strconv.Atoi(ctx.DefaultQuery("days", "30"))
+	top, err3 := strconv.Atoi(ctx.DefaultQuery("top", "10"))
+	if err1 != nil || err2 != nil || err3 != nil || days < 1 || days > maxStatsDays || top < 1 || top > 100 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+	if projectID != 0 && !canAccessProject(claims, uint(projectID), models.ProjectRoleViewer) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "无权限访问此项目"})
+		return
+	}
+
+	stats, err := models.GetStats(models.StatsFilter{
+		UserID:    claims.UserID,
+		ProjectID: uint(projectID),
+		All:       rbac.Can(claims.Role, rbac.StatsReadAny),
+		Since:     time.Now().AddDate(0, 0, -(days - 1)),
+		Limit:     top,
+	})
+	if err != nil {
+		log.Error("统计数据查询失败: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "统计失败"})
+		return
+	}
+	ctx.JSON(http.StatusOK, stats)
+}
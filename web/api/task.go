@@ -2,8 +2,10 @@ package api
 
 import (
 	"VulnFusion/internal/scanner"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"VulnFusion/internal/auth"
 	"VulnFusion/internal/log"
@@ -13,79 +15,107 @@ import (
 
 // HandleCreateTask 创建扫描任务
 // @Summary 创建扫描任务
-// @Description 创建新的扫描任务并异步执行，需提供目标和模板
+// @Description 创建新的扫描任务并异步执行，需提供目标和模板；mode 为 auto 时先识别技术栈再自动挑选模板；目标须在扫描范围内；指定 project_id 时任务归属项目并按项目范围检查
 // @Tags Task
 // @Accept json
 // @Produce json
 // @Param data body api.CreateTaskRequest true "任务创建参数"
 // @Success 200 {object} map[string]interface{} "任务创建成功，返回任务 ID"
 // @Failure 400 {object} map[string]string "参数错误"
+// @Failure 403 {object} map[string]string "无项目权限"
 // @Failure 500 {object} map[string]string "任务创建失败"
 // @Security ApiKeyAuth
 // @Router /api/v1/tasks [post]
 func HandleCreateTask(ctx *gin.Context) {
 	claims := ctx.MustGet("claims").(*auth.CustomClaims)
-	var req struct {
-		Target   string `json:"target"`
-		Template string `json:"template"`
-	}
+	var req CreateTaskRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		log.Warn("创建任务参数解析失败: %v", err)
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
 		return
 	}
 
-	task := &models.Task{
-		UserID:   claims.UserID,
-		Target:   req.Target,
-		Template: req.Template,
-		Status:   "pending", // 初始状态
+	task, ok := createTask(ctx, claims, req)
+	if !ok {
+		return
 	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "任务创建成功", "task_id": task.ID})
+}
 
-	if err := models.CreateTask(task); err != nil {
-		log.Error("任务创建失败: %v", err)
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "任务创建失败"})
-		return
+// createTask 校验参数与扫描范围后创建任务并调度执行；ok 为 false 时已写入错误响应
+func createTask(ctx *gin.Context, claims *auth.CustomClaims, req CreateTaskRequest) (*models.Task, bool) {
+	task, ok := buildTask(ctx, claims, req)
+	if !ok || !startTask(ctx, task) {
+		return nil, false
 	}
+	return task, true
+}
 
-	// 异步执行扫描
-	go func(taskID uint, target, template string) {
-		_ = models.UpdateTaskStatus(taskID, "running")
+// buildTask 校验参数、项目权限与扫描范围并构造任务（不落库）；ok 为 false 时已写入错误响应
+func buildTask(ctx *gin.Context, claims *auth.CustomClaims, req CreateTaskRequest) (*models.Task, bool) {
+	if req.Mode == "" {
+		req.Mode = models.ModeStandard
+	}
+	if req.Mode != models.ModeStandard && req.Mode != models.ModeAuto {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "不支持的扫描模式"})
+		return nil, false
+	}
+	if req.Mode == models.ModeStandard && req.Template == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "模板不能为空"})
+		return nil, false
+	}
 
-		output, err := scanner.RunScanTask(scanner.ScanOptions{
-			Target:     target,
-			Template:   template,
-			JsonOutput: true,
-			Silent:     true,
-		})
-		if err != nil {
-			log.Error("任务 %d 扫描失败: %v", taskID, err)
-			_ = models.UpdateTaskStatus(taskID, "failed")
-			return
-		}
+	if req.ProjectID != 0 && !canAccessProject(claims, req.ProjectID, models.ProjectRoleEditor) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "无权限在此项目中创建任务"})
+		return nil, false
+	}
+
+	targets, ok := enforceScope(ctx, claims.UserID, req.ProjectID, req.Target)
+	if !ok {
+		return nil, false
+	}
 
-		parsed, err := scanner.ParseNucleiResult(output)
+	// 没有节点具备该标签时任务将永远无法被领取
+	if req.AgentLabel != "" {
+		exists, err := models.AgentLabelExists(req.AgentLabel)
 		if err != nil {
-			log.Warn("任务 %d 扫描结果解析失败: %v", taskID, err)
-			_ = models.UpdateTaskStatus(taskID, "done")
-			return
+			log.Error("查询节点标签失败: %v", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "任务创建失败"})
+			return nil, false
 		}
-
-		for _, p := range parsed {
-			res := &models.Result{
-				TaskID:        taskID,
-				Target:        p.Matched,
-				Vulnerability: p.Info.Name,
-				Severity:      p.Info.Severity,
-				Detail:        string(output), // 可选：改为 JSON 单行或详情部分
-			}
-			_ = models.SaveScanResult(res)
+		if !exists {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("没有节点具备标签 %s", req.AgentLabel)})
+			return nil, false
 		}
+	}
 
-		_ = models.UpdateTaskStatus(taskID, "done")
-	}(task.ID, req.Target, req.Template)
+	task := &models.Task{
+		UserID:     claims.UserID,
+		ProjectID:  req.ProjectID,
+		Target:     strings.Join(targets, ","),
+		Template:   req.Template,
+		Mode:       req.Mode,
+		AgentLabel: req.AgentLabel,
+		Status:     "pending", // 初始状态
+	}
+	return task, true
+}
 
-	ctx.JSON(http.StatusOK, gin.H{"message": "任务创建成功", "task_id": task.ID})
+// startTask 保存任务并调度执行；返回 false 时已写入错误响应
+func startTask(ctx *gin.Context, task *models.Task) bool {
+	if err := models.CreateTask(task); err != nil {
+		log.Error("任务创建失败: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "任务创建失败"})
+		return false
+	}
+	scanner.RecordTargetAssets(task)
+
+	// 指定了节点标签的任务留在队列中，由远程节点领取执行
+	if task.AgentLabel == "" {
+		// 异步执行扫描
+		go scanner.ExecuteTask(task)
+	}
+	return true
 }
 
 // HandleGetTaskByID 获取任务详情
@@ -115,7 +145,7 @@ func HandleGetTaskByID(ctx *gin.Context) {
 		return
 	}
 
-	if claims.Role != "admin" && task.UserID != claims.UserID {
+	if !canAccessTask(claims, task, models.ProjectRoleViewer) {
 		ctx.JSON(http.StatusForbidden, gin.H{"error": "无权限访问此任务"})
 		return
 	}
@@ -125,16 +155,23 @@ func HandleGetTaskByID(ctx *gin.Context) {
 
 // HandleListMyTasks 获取当前用户的任务列表
 // @Summary 获取我的任务
-// @Description 返回当前用户创建的所有扫描任务
+// @Description 返回当前用户的个人任务及其所在项目的任务，可按 project_id 过滤
 // @Tags Task
 // @Produce json
+// @Param project_id query int false "项目 ID"
 // @Success 200 {array} models.Task "任务列表"
+// @Failure 400 {object} map[string]string "参数错误"
 // @Failure 500 {object} map[string]string "获取失败"
 // @Security ApiKeyAuth
 // @Router /api/v1/tasks [get]
 func HandleListMyTasks(ctx *gin.Context) {
 	claims := ctx.MustGet("claims").(*auth.CustomClaims)
-	tasks, err := models.ListTasksByUserID(claims.UserID)
+	projectID, err := strconv.ParseUint(ctx.DefaultQuery("project_id", "0"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "项目 ID 格式错误"})
+		return
+	}
+	tasks, err := models.ListVisibleTasks(claims.UserID, uint(projectID))
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "获取任务失败"})
 		return
@@ -153,11 +190,6 @@ func HandleListMyTasks(ctx *gin.Context) {
 // @Security ApiKeyAuth
 // @Router /api/v1/admin/tasks [get]
 func HandleListAllTasks(ctx *gin.Context) {
-	claims := ctx.MustGet("claims").(*auth.CustomClaims)
-	if claims.Role != "admin" {
-		ctx.JSON(http.StatusForbidden, gin.H{"error": "权限不足"})
-		return
-	}
 	tasks, err := models.ListAllTasks()
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "获取任务失败"})
@@ -168,7 +200,7 @@ func HandleListAllTasks(ctx *gin.Context) {
 
 // HandleDeleteTaskByID 删除任务
 // @Summary 删除任务
-// @Description 根据任务 ID 删除，需本人或管理员权限
+// @Description 根据任务 ID 删除，需本人、项目 editor 及以上或管理员权限
 // @Tags Task
 // @Produce json
 // @Param id path int true "任务 ID"
@@ -194,7 +226,7 @@ func HandleDeleteTaskByID(ctx *gin.Context) {
 		return
 	}
 
-	if claims.Role != "admin" && task.UserID != claims.UserID {
+	if !canAccessTask(claims, task, models.ProjectRoleEditor) {
 		ctx.JSON(http.StatusForbidden, gin.H{"error": "无权限删除此任务"})
 		return
 	}
@@ -208,7 +240,7 @@ func HandleDeleteTaskByID(ctx *gin.Context) {
 
 // HandleBatchDeleteTasks 批量删除任务
 // @Summary 批量删除任务
-// @Description 批量删除指定任务 ID 列表，需管理员或本人权限
+// @Description 批量删除指定任务 ID 列表，仅删除调用者有权限的任务（本人、项目 editor 及以上或管理员）
 // @Tags Task
 // @Accept json
 // @Produce json
@@ -228,31 +260,48 @@ func HandleBatchDeleteTasks(ctx *gin.Context) {
 		return
 	}
 
-	var userID *uint
-	if claims.Role != "admin" {
-		userID = &claims.UserID
+	tasks, err := models.ListTasksByIDs(req.IDs)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "批量删除失败"})
+		return
+	}
+
+	// 仅删除调用者有 editor 及以上权限的任务
+	ids := make([]uint, 0, len(tasks))
+	for i := range tasks {
+		if canAccessTask(claims, &tasks[i], models.ProjectRoleEditor) {
+			ids = append(ids, tasks[i].ID)
+		}
+	}
+	if len(ids) == 0 {
+		ctx.JSON(http.StatusOK, gin.H{"message": "批量删除成功", "deleted": 0})
+		return
 	}
 
-	if err := models.BatchDeleteTasks(req.IDs, userID); err != nil {
+	if err := models.BatchDeleteTasks(ids, nil); err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "批量删除失败"})
 		return
 	}
-	ctx.JSON(http.StatusOK, gin.H{"message": "批量删除成功"})
+	ctx.JSON(http.StatusOK, gin.H{"message": "批量删除成功", "deleted": len(ids)})
 }
 
 // HandleUpdateTaskStatus 更新任务状态
 // @Summary 更新任务状态
-// @Description 手动更新任务状态（如 pending, running, done 等）
+// @Description 手动更新任务状态（pending / running / done / failed），已结束的任务不能再变更
 // @Tags Task
 // @Accept json
 // @Produce json
 // @Param data body api.UpdateStatusRequest true "状态更新参数"
 // @Success 200 {object} map[string]string "更新成功"
 // @Failure 400 {object} map[string]string "参数错误"
+// @Failure 403 {object} map[string]string "权限不足"
+// @Failure 404 {object} map[string]string "任务不存在"
+// @Failure 409 {object} map[string]string "任务已结束"
 // @Failure 500 {object} map[string]string "更新失败"
 // @Security ApiKeyAuth
 // @Router /api/v1/tasks/status [post]
 func HandleUpdateTaskStatus(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
 	var req struct {
 		ID     uint   `json:"id"`
 		Status string `json:"status"`
@@ -261,10 +310,32 @@ func HandleUpdateTaskStatus(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
 		return
 	}
+	switch req.Status {
+	case models.StatusPending, models.StatusRunning, models.StatusDone, models.StatusFailed:
+	default:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "不支持的任务状态"})
+		return
+	}
 
-	if err := models.UpdateTaskStatus(req.ID, req.Status); err != nil {
+	task, err := models.GetTaskByID(req.ID)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+		return
+	}
+	if !canAccessTask(claims, task, models.ProjectRoleEditor) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "无权限修改此任务"})
+		return
+	}
+
+	// 已结束的任务不允许再变更状态
+	updated, err := models.UpdateUnfinishedTask(req.ID, map[string]interface{}{"status": req.Status})
+	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "更新失败"})
 		return
 	}
+	if !updated {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "任务已结束，不能再变更状态"})
+		return
+	}
 	ctx.JSON(http.StatusOK, gin.H{"message": "任务状态已更新"})
 }
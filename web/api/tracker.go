@@ -0,0 +1,405 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"VulnFusion/internal/auth"
+	"VulnFusion/internal/log"
+	"VulnFusion/internal/models"
+	"VulnFusion/internal/netguard"
+	"VulnFusion/internal/rbac"
+	"VulnFusion/internal/tracker"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleCreateTrackerConnector 创建工单连接器
+// @Summary 创建工单连接器
+// @Description 配置 Jira / GitLab / GitHub 连接信息与字段映射；指定项目时需项目 owner，项目内 editor 可使用
+// @Tags Tracker
+// @Accept json
+// @Produce json
+// @Param data body api.TrackerConnectorRequest true "连接器信息"
+// @Success 200 {object} models.TrackerConnector "创建的连接器"
+// @Failure 400 {object} map[string]string "参数错误"
+// @Failure 403 {object} map[string]string "无权限"
+// @Failure 500 {object} map[string]string "创建失败"
+// @Security ApiKeyAuth
+// @Router /api/v1/trackers [post]
+func HandleCreateTrackerConnector(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
+
+	var req TrackerConnectorRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+	mapping, ok := validateTrackerRequest(ctx, &req)
+	if !ok {
+		return
+	}
+	if req.Token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "访问令牌不能为空"})
+		return
+	}
+	if req.ProjectID != 0 && !canAccessProject(claims, req.ProjectID, models.ProjectRoleOwner) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "仅项目 owner 可配置项目连接器"})
+		return
+	}
+
+	conn := &models.TrackerConnector{
+		UserID:       claims.UserID,
+		ProjectID:    req.ProjectID,
+		Name:         strings.TrimSpace(req.Name),
+		Kind:         req.Kind,
+		BaseURL:      strings.TrimRight(req.BaseURL, "/"),
+		Project:      req.Project,
+		Username:     req.Username,
+		Token:        req.Token,
+		IssueType:    req.IssueType,
+		FieldMapping: mapping,
+		Enabled:      req.Enabled == nil || *req.Enabled,
+	}
+	if err := models.CreateTrackerConnector(conn); err != nil {
+		log.Error("创建工单连接器失败: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "创建连接器失败"})
+		return
+	}
+	if !conn.Enabled {
+		_ = models.UpdateTrackerConnectorByID(conn.ID, map[string]interface{}{"enabled": false})
+	}
+	ctx.JSON(http.StatusOK, conn)
+}
+
+// HandleListTrackerConnectors 获取工单连接器列表
+// @Summary 获取工单连接器列表
+// @Description 返回个人连接器与所在项目的连接器，管理员返回全部
+// @Tags Tracker
+// @Produce json
+// @Success 200 {array} models.TrackerConnector "连接器列表"
+// @Failure 500 {object} map[string]string "获取失败"
+// @Security ApiKeyAuth
+// @Router /api/v1/trackers [get]
+func HandleListTrackerConnectors(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
+
+	var conns []models.TrackerConnector
+	var err error
+	if rbac.Can(claims.Role, rbac.TrackerManageAny) {
+		conns, err = models.ListAllTrackerConnectors()
+	} else {
+		conns, err = models.ListVisibleTrackerConnectors(claims.UserID)
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "获取连接器失败"})
+		return
+	}
+	ctx.JSON(http.StatusOK, conns)
+}
+
+// HandleGetTrackerConnector 获取工单连接器详情
+// @Summary 获取工单连接器详情
+// @Tags Tracker
+// @Produce json
+// @Param id path int true "连接器 ID"
+// @Success 200 {object} models.TrackerConnector "连接器"
+// @Failure 403 {object} map[string]string "无权限"
+// @Failure 404 {object} map[string]string "连接器不存在"
+// @Security ApiKeyAuth
+// @Router /api/v1/trackers/{id} [get]
+func HandleGetTrackerConnector(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
+	conn, ok := loadTrackerConnector(ctx, claims, models.ProjectRoleViewer)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, conn)
+}
+
+// HandleUpdateTrackerConnector 更新工单连接器
+// @Summary 更新工单连接器
+// @Description 修改连接信息、字段映射与启用状态；token 为空时保留原令牌，所属项目不可修改
+// @Tags Tracker
+// @Accept json
+// @Produce json
+// @Param id path int true "连接器 ID"
+// @Param data body api.TrackerConnectorRequest true "连接器信息"
+// @Success 200 {object} map[string]string "更新成功"
+// @Failure 400 {object} map[string]string "参数错误"
+// @Failure 403 {object} map[string]string "无权限"
+// @Failure 404 {object} map[string]string "连接器不存在"
+// @Security ApiKeyAuth
+// @Router /api/v1/trackers/{id} [put]
+func HandleUpdateTrackerConnector(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
+	conn, ok := loadTrackerConnector(ctx, claims, models.ProjectRoleOwner)
+	if !ok {
+		return
+	}
+
+	var req TrackerConnectorRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+	mapping, ok := validateTrackerRequest(ctx, &req)
+	if !ok {
+		return
+	}
+
+	updates := map[string]interface{}{
+		"name":          strings.TrimSpace(req.Name),
+		"kind":          req.Kind,
+		"base_url":      strings.TrimRight(req.BaseURL, "/"),
+		"project":       req.Project,
+		"username":      req.Username,
+		"issue_type":    req.IssueType,
+		"field_mapping": mapping,
+	}
+	if req.Token != "" {
+		updates["token"] = req.Token
+	}
+	if req.Enabled != nil {
+		updates["enabled"] = *req.Enabled
+	}
+	if err := models.UpdateTrackerConnectorByID(conn.ID, updates); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "更新连接器失败"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "连接器已更新"})
+}
+
+// HandleDeleteTrackerConnector 删除工单连接器
+// @Summary 删除工单连接器
+// @Description 删除连接器，已创建的工单链接保留在结果中但不再同步状态
+// @Tags Tracker
+// @Produce json
+// @Param id path int true "连接器 ID"
+// @Success 200 {object} map[string]string "删除成功"
+// @Failure 403 {object} map[string]string "无权限"
+// @Failure 404 {object} map[string]string "连接器不存在"
+// @Security ApiKeyAuth
+// @Router /api/v1/trackers/{id} [delete]
+func HandleDeleteTrackerConnector(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
+	conn, ok := loadTrackerConnector(ctx, claims, models.ProjectRoleOwner)
+	if !ok {
+		return
+	}
+
+	if err := models.DeleteTrackerConnectorByID(conn.ID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "删除连接器失败"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "连接器已删除"})
+}
+
+// HandleSyncTrackerConnector 立即同步连接器下的工单状态
+// @Summary 同步工单状态
+// @Description 拉取连接器下所有工单的状态，工单状态变化时按映射回写结果处置状态
+// @Tags Tracker
+// @Produce json
+// @Param id path int true "连接器 ID"
+// @Success 200 {object} map[string]int "同步的工单数与更新的结果数"
+// @Failure 403 {object} map[string]string "无权限"
+// @Failure 404 {object} map[string]string "连接器不存在"
+// @Failure 500 {object} map[string]string "同步失败"
+// @Security ApiKeyAuth
+// @Router /api/v1/trackers/{id}/sync [post]
+func HandleSyncTrackerConnector(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
+	conn, ok := loadTrackerConnector(ctx, claims, models.ProjectRoleEditor)
+	if !ok {
+		return
+	}
+
+	synced, changed, err := tracker.SyncConnector(conn, nil)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "同步失败"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"synced": synced, "changed": changed})
+}
+
+// HandleCreateResultTicket 从扫描结果创建工单
+// @Summary 从结果创建工单
+// @Description 使用指定连接器为结果创建工单并关联；结果已有工单时直接返回已有工单，不会重复创建
+// @Tags Tracker
+// @Accept json
+// @Produce json
+// @Param id path int true "扫描结果 ID"
+// @Param data body api.CreateTicketRequest true "连接器"
+// @Success 200 {object} map[string]interface{} "结果与是否新建"
+// @Failure 400 {object} map[string]string "参数错误"
+// @Failure 403 {object} map[string]string "无权限"
+// @Failure 404 {object} map[string]string "结果或连接器不存在"
+// @Failure 502 {object} map[string]string "缺陷跟踪系统返回错误"
+// @Security ApiKeyAuth
+// @Router /api/v1/results/{id}/ticket [post]
+func HandleCreateResultTicket(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
+	result, task, ok := loadEditableResult(ctx, claims)
+	if !ok {
+		return
+	}
+
+	var req CreateTicketRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil || req.ConnectorID == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "请选择连接器"})
+		return
+	}
+	conn, err := models.GetTrackerConnectorByID(req.ConnectorID)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "连接器不存在"})
+		return
+	}
+	if !conn.Enabled || !canUseConnector(claims, conn, task) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "无权限使用此连接器"})
+		return
+	}
+
+	updated, created, err := tracker.CreateTicket(conn, result.ID, nil)
+	if err != nil {
+		log.Warn("为结果 %d 创建工单失败: %v", result.ID, err)
+		ctx.JSON(http.StatusBadGateway, gin.H{"error": "创建工单失败，请检查连接器配置"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"result": updated, "created": created})
+}
+
+// HandleSyncResultTicket 同步单个结果的工单状态
+// @Summary 同步结果工单状态
+// @Description 拉取结果关联工单的最新状态，工单状态变化时按映射回写处置状态
+// @Tags Tracker
+// @Produce json
+// @Param id path int true "扫描结果 ID"
+// @Success 200 {object} map[string]interface{} "结果与处置状态是否变化"
+// @Failure 400 {object} map[string]string "结果未关联工单"
+// @Failure 403 {object} map[string]string "无权限"
+// @Failure 502 {object} map[string]string "缺陷跟踪系统返回错误"
+// @Security ApiKeyAuth
+// @Router /api/v1/results/{id}/ticket/sync [post]
+func HandleSyncResultTicket(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
+	result, _, ok := loadEditableResult(ctx, claims)
+	if !ok {
+		return
+	}
+	if result.TicketKey == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "结果未关联工单"})
+		return
+	}
+	conn, err := models.GetTrackerConnectorByID(result.TicketConnectorID)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "工单连接器已删除"})
+		return
+	}
+
+	changed, err := tracker.SyncResult(conn, result, nil)
+	if err != nil {
+		log.Warn("同步结果 %d 的工单状态失败: %v", result.ID, err)
+		ctx.JSON(http.StatusBadGateway, gin.H{"error": "同步失败，请检查连接器配置"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"result": result, "changed": changed})
+}
+
+// loadTrackerConnector 读取路径中的连接器并校验权限：个人连接器仅创建人，项目连接器按项目角色；ok 为 false 时已写入错误响应
+func loadTrackerConnector(ctx *gin.Context, claims *auth.CustomClaims, need string) (*models.TrackerConnector, bool) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "连接器 ID 格式错误"})
+		return nil, false
+	}
+	conn, err := models.GetTrackerConnectorByID(uint(id))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "连接器不存在"})
+		return nil, false
+	}
+
+	allowed := rbac.Can(claims.Role, rbac.TrackerManageAny)
+	if conn.ProjectID != 0 {
+		allowed = allowed || isProjectMember(claims, conn.ProjectID, need)
+	} else {
+		allowed = allowed || conn.UserID == claims.UserID
+	}
+	if !allowed {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "无权限操作此连接器"})
+		return nil, false
+	}
+	return conn, true
+}
+
+// canUseConnector 判断能否用连接器为任务的结果创建工单：项目连接器仅用于本项目任务
+func canUseConnector(claims *auth.CustomClaims, conn *models.TrackerConnector, task *models.Task) bool {
+	if rbac.Can(claims.Role, rbac.TrackerManageAny) {
+		return true
+	}
+	if conn.ProjectID != 0 {
+		return task.ProjectID == conn.ProjectID && isProjectMember(claims, conn.ProjectID, models.ProjectRoleEditor)
+	}
+	return conn.UserID == claims.UserID
+}
+
+// loadEditableResult 读取路径中的结果并要求对其任务有 editor 权限；ok 为 false 时已写入错误响应
+func loadEditableResult(ctx *gin.Context, claims *auth.CustomClaims) (*models.Result, *models.Task, bool) {
+	resultID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "ID 格式错误"})
+		return nil, nil, false
+	}
+	result, err := models.GetResultByID(uint(resultID))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "结果不存在"})
+		return nil, nil, false
+	}
+	task, err := models.GetTaskByID(result.TaskID)
+	if err != nil || !canAccessTask(claims, task, models.ProjectRoleEditor) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "无权限处置此结果"})
+		return nil, nil, false
+	}
+	return result, task, true
+}
+
+// validateTrackerRequest 校验连接器参数，返回序列化后的字段映射；ok 为 false 时已写入错误响应
+func validateTrackerRequest(ctx *gin.Context, req *TrackerConnectorRequest) (string, bool) {
+	if strings.TrimSpace(req.Name) == "" || strings.TrimSpace(req.Project) == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "名称与目标项目不能为空"})
+		return "", false
+	}
+	if !models.IsValidTrackerKind(req.Kind) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "不支持的缺陷跟踪系统类型"})
+		return "", false
+	}
+	if req.BaseURL == "" && req.Kind != models.TrackerGitHub {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "服务地址不能为空"})
+		return "", false
+	}
+	if req.BaseURL != "" {
+		if err := netguard.ValidateURL(req.BaseURL); err != nil {
+			if errors.Is(err, netguard.ErrBlockedAddress) {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "服务地址不能指向内网或保留地址，如需访问内网实例请配置 outbound.allowed_cidrs"})
+				return "", false
+			}
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "服务地址必须为 http 或 https URL"})
+			return "", false
+		}
+	}
+	for _, status := range req.FieldMapping.Status {
+		if !models.IsValidResultStatus(status) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "状态映射中存在不支持的处置状态: " + status})
+			return "", false
+		}
+	}
+
+	data, err := json.Marshal(req.FieldMapping)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "字段映射格式错误"})
+		return "", false
+	}
+	return string(data), true
+}
@@ -1,10 +1,34 @@
 package api
 
-// RegisterRequest 用户注册请求参数
+import (
+	"VulnFusion/internal/enrich"
+	"VulnFusion/internal/models"
+	"VulnFusion/internal/scope"
+)
+
+// RegisterRequest 用户注册请求参数，自助注册的用户始终为 viewer 角色
 type RegisterRequest struct {
-	Username string `json:"username" example:"admin"`  // 用户名
-	Password string `json:"password" example:"123456"` // 密码
-	Role     string `json:"role" example:"user"`       // 用户角色：user / admin
+	Username   string `json:"username" example:"alice"`       // 用户名
+	Password   string `json:"password" example:"Passw0rd123"` // 密码，至少 8 位且同时包含字母和数字
+	InviteCode string `json:"invite_code"`                    // 邀请码，auth.registration.mode 为 invite 时必填
+}
+
+// CreateUserRequest 管理员创建用户请求参数
+type CreateUserRequest struct {
+	Username string `json:"username" example:"alice"`       // 用户名
+	Password string `json:"password" example:"Passw0rd123"` // 初始密码，至少 8 位且同时包含字母和数字
+	Role     string `json:"role" example:"user"`            // 角色，默认 user
+}
+
+// ApproveUserRequest 审批自助注册用户请求参数
+type ApproveUserRequest struct {
+	Role string `json:"role" example:"user"` // 审批后授予的角色，默认保持 viewer
+}
+
+// CreateInviteRequest 生成邀请码请求参数
+type CreateInviteRequest struct {
+	Note           string `json:"note" example:"bob@example.com"` // 备注，如受邀人
+	ExpiresInHours int    `json:"expires_in_hours" example:"72"`  // 有效小时数，0 表示使用 auth.registration.invite_ttl
 }
 
 // LoginRequest 用户登录请求参数
@@ -18,10 +42,35 @@ type RefreshRequest struct {
 	RefreshToken string `json:"refresh_token" example:"<refresh-token>"` // 刷新 token
 }
 
+// MFALoginRequest 二次验证登录请求
+type MFALoginRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"` // 登录第一步返回的挑战令牌
+	Code     string `json:"code"`                         // 6 位验证码或恢复码
+}
+
+// MFACodeRequest 验证码请求
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required" example:"123456"` // 6 位验证码
+}
+
+// MFADisableRequest 关闭二次验证请求
+type MFADisableRequest struct {
+	Password string `json:"password" binding:"required"` // 当前密码
+	Code     string `json:"code" binding:"required"`     // 6 位验证码或恢复码
+}
+
+// SSOExchangeRequest 单点登录票据换取令牌请求
+type SSOExchangeRequest struct {
+	Ticket string `json:"ticket" binding:"required"` // 回调跳转地址中的 sso_ticket
+}
+
 // CreateTaskRequest 创建任务请求
 type CreateTaskRequest struct {
-	Target   string `json:"target" example:"https://example.com"` // 目标地址
-	Template string `json:"template" example:"cves/2021/*.yaml"`  // Nuclei 模板路径
+	Target     string `json:"target" example:"https://example.com"` // 目标地址
+	Template   string `json:"template" example:"cves/2021/*.yaml"`  // Nuclei 模板路径
+	Mode       string `json:"mode" example:"standard"`              // 扫描模式：standard / auto
+	AgentLabel string `json:"agent_label" example:"dmz"`            // 交由带该标签的远程节点执行（可选）
+	ProjectID  uint   `json:"project_id" example:"0"`               // 所属项目（可选，需 editor 及以上角色）
 }
 
 // BatchDeleteRequest 批量删除任务请求
@@ -34,3 +83,143 @@ type UpdateStatusRequest struct {
 	ID     uint   `json:"id" example:"1"`        // 任务 ID
 	Status string `json:"status" example:"done"` // 新状态：pending / running / done / failed
 }
+
+// AgentRegisterRequest 远程节点注册请求
+type AgentRegisterRequest struct {
+	RegistrationToken string   `json:"registration_token"`        // 服务端配置的注册令牌
+	Name              string   `json:"name" example:"dmz-runner"` // 节点名称
+	Labels            []string `json:"labels" example:"dmz"`      // 节点标签
+	Capacity          int      `json:"capacity" example:"2"`      // 最大并发任务数
+	PublicKey         string   `json:"public_key"`                // 结果签名公钥（ed25519，base64）
+}
+
+// AgentHeartbeatRequest 远程节点心跳请求
+type AgentHeartbeatRequest struct {
+	Running  int      `json:"running"`  // 当前运行中的任务数
+	Capacity int      `json:"capacity"` // 最大并发任务数
+	Labels   []string `json:"labels"`   // 节点标签（可选，用于更新）
+}
+
+// AgentTaskUpdateRequest 远程节点上报任务状态请求
+type AgentTaskUpdateRequest struct {
+	Status       string `json:"status" example:"done"` // running / done / failed
+	DetectedTech string `json:"detected_tech"`         // 自动模式识别出的技术栈
+	SelectedTags string `json:"selected_tags"`         // 自动模式选中的模板标签
+	Error        string `json:"error"`                 // 失败原因
+}
+
+// ScopeRequest 设置扫描范围请求
+type ScopeRequest struct {
+	Name  string       `json:"name" example:"客户 A 渗透测试"` // 范围名称
+	Rules []scope.Rule `json:"rules"`                    // 包含 / 排除规则
+}
+
+// ScopeCheckRequest 检查目标范围请求
+type ScopeCheckRequest struct {
+	Targets   string `json:"targets" example:"https://app.example.com,10.0.0.5:8080"` // 以逗号或换行分隔的目标
+	ProjectID uint   `json:"project_id" example:"0"`                                  // 按项目范围检查（可选）
+}
+
+// ProjectRequest 创建或更新项目请求
+type ProjectRequest struct {
+	Name         string `json:"name" example:"客户 A 渗透测试"`                               // 项目名称
+	Description  string `json:"description"`                                            // 项目描述
+	Digest       string `json:"digest" example:"weekly"`                                // 项目高危漏洞摘要频率：空（关闭）/ daily / weekly
+	DigestEmails string `json:"digest_emails" example:"sec@example.com,pm@example.com"` // 项目摘要收件人（逗号分隔）
+}
+
+// ProjectMemberRequest 添加或更新项目成员请求
+type ProjectMemberRequest struct {
+	UserID uint   `json:"user_id" example:"2"`   // 成员用户 ID
+	Role   string `json:"role" example:"editor"` // owner / editor / viewer
+}
+
+// ResultStatusRequest 更新结果处置状态请求
+type ResultStatusRequest struct {
+	Status string `json:"status" example:"fixed"` // open / fixed / accepted / false_positive
+}
+
+// UpdateAssetRequest 更新资产请求
+type UpdateAssetRequest struct {
+	Owner       string `json:"owner" example:"运维组"`        // 资产负责人
+	Tags        string `json:"tags" example:"prod,dmz"`    // 资产标签（逗号分隔）
+	Criticality string `json:"criticality" example:"high"` // low / medium / high / critical
+}
+
+// ScanAssetsRequest 对选中资产发起扫描请求
+type ScanAssetsRequest struct {
+	AssetIDs   []uint `json:"asset_ids"`                           // 资产 ID 列表
+	Template   string `json:"template" example:"cves/2021/*.yaml"` // Nuclei 模板路径
+	Mode       string `json:"mode" example:"standard"`             // 扫描模式：standard / auto
+	AgentLabel string `json:"agent_label" example:"dmz"`           // 交由带该标签的远程节点执行（可选）
+}
+
+// ResultDetailResponse 扫描结果详情，附带本地漏洞库补充信息
+type ResultDetailResponse struct {
+	models.Result
+	Enrichment *enrich.Enrichment `json:"enrichment"`
+}
+
+// WebhookRequest 创建或更新 Webhook 请求
+type WebhookRequest struct {
+	Name        string   `json:"name" example:"告警群"`                                 // 名称
+	URL         string   `json:"url" example:"https://hooks.example.com/vulnfusion"` // 投递地址（http / https）
+	Secret      string   `json:"secret"`                                             // 签名密钥，generic 格式创建时为空则自动生成；钉钉 / 飞书填写机器人加签密钥；更新时为空表示不修改
+	Events      []string `json:"events" example:"task.done,finding.created"`         // 订阅的事件，为空表示全部
+	MinSeverity string   `json:"min_severity" example:"high"`                        // finding.created 的最低严重等级
+	Global      bool     `json:"global"`                                             // 接收全部任务的事件（仅管理员）
+	Format      string   `json:"format" example:"dingtalk"`                          // 消息格式：generic / slack / teams / dingtalk / feishu，默认 generic
+	QuietStart  string   `json:"quiet_start" example:"22:00"`                        // 免打扰开始时间（HH:MM）
+	QuietEnd    string   `json:"quiet_end" example:"08:00"`                          // 免打扰结束时间（HH:MM）
+	Enabled     *bool    `json:"enabled"`                                            // 是否启用，默认启用
+}
+
+// NotificationSettingsRequest 更新个人通知设置请求
+type NotificationSettingsRequest struct {
+	Email           string `json:"email" example:"alice@example.com"` // 通知邮箱
+	NotifyTaskEmail bool   `json:"notify_task_email"`                 // 任务结束时发送邮件
+	Digest          string `json:"digest" example:"daily"`            // 高危漏洞摘要频率：空（关闭）/ daily / weekly
+}
+
+// TrackerConnectorRequest 创建或更新工单连接器请求
+type TrackerConnectorRequest struct {
+	Name         string                     `json:"name" example:"研发 Jira"`                      // 名称
+	Kind         string                     `json:"kind" example:"jira"`                         // jira / gitlab / github
+	ProjectID    uint                       `json:"project_id" example:"0"`                      // 所属项目（可选，需项目 owner）
+	BaseURL      string                     `json:"base_url" example:"https://jira.example.com"` // 服务地址，GitHub 可留空
+	Project      string                     `json:"project" example:"SEC"`                       // Jira 项目 Key / GitLab 项目 ID 或路径 / GitHub owner/repo
+	Username     string                     `json:"username"`                                    // Jira 账号（可选）
+	Token        string                     `json:"token"`                                       // 访问令牌，更新时为空表示不修改
+	IssueType    string                     `json:"issue_type" example:"Bug"`                    // Jira 问题类型
+	FieldMapping models.TrackerFieldMapping `json:"field_mapping"`                               // 字段映射
+	Enabled      *bool                      `json:"enabled"`                                     // 是否启用，默认启用
+}
+
+// CreateTicketRequest 从结果创建工单请求
+type CreateTicketRequest struct {
+	ConnectorID uint `json:"connector_id" example:"1"` // 连接器 ID
+}
+
+// CreateAPITokenRequest 创建 API 令牌请求
+type CreateAPITokenRequest struct {
+	Name          string   `json:"name" example:"GitLab CI"`                  // 名称
+	Scopes        []string `json:"scopes" example:"tasks:write,results:read"` // 权限范围
+	ExpiresInDays int      `json:"expires_in_days" example:"90"`              // 有效天数，0 表示永不过期
+}
+
+// CIPolicyRequest CI 门禁策略，未填写的字段使用配置中的默认值
+type CIPolicyRequest struct {
+	FailOn         string   `json:"fail_on" example:"high"`                // 达到该等级（含）即失败
+	AllowTemplates []string `json:"allow_templates" example:"tech-detect"` // 豁免的模板 ID
+	IgnoreAccepted *bool    `json:"ignore_accepted"`                       // 忽略已接受风险的漏洞
+}
+
+// CIScanRequest CI 扫描请求
+type CIScanRequest struct {
+	Target     string           `json:"target" example:"https://staging.example.com"` // 扫描目标
+	Profile    string           `json:"profile" example:"auto"`                       // 扫描配置：auto 为自动识别技术栈，其他值作为 nuclei 模板
+	ProjectID  uint             `json:"project_id" example:"0"`                       // 所属项目（可选）
+	AgentLabel string           `json:"agent_label" example:"dmz"`                    // 交由带该标签的远程节点执行（可选）
+	Wait       *int             `json:"wait" example:"600"`                           // 同步等待秒数，默认等待至配置的上限；0 表示立即返回，之后轮询
+	Policy     *CIPolicyRequest `json:"policy"`                                       // 门禁策略（可选）
+}
@@ -1,24 +1,37 @@
 package api
 
 import (
+	"VulnFusion/internal/auth"
+	"VulnFusion/internal/log"
 	"VulnFusion/internal/models"
+	"VulnFusion/internal/rbac"
+	"VulnFusion/internal/utils"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
 	"net/http"
 	"strconv"
+	"strings"
 )
 
 // HandleListAllUsers godoc
 // @Summary 获取所有用户列表
-// @Description 管理员查看所有用户信息
+// @Description 管理员查看所有用户信息，status=pending 时只返回等待审批的自助注册用户
 // @Tags 用户管理
 // @Security BearerToken
 // @Produce json
+// @Param status query string false "账号状态，如 pending"
 // @Success 200 {array} models.User
 // @Failure 500 {object} gin.H{"error": "获取用户列表失败"}
 // @Router /admin/users [get]
 func HandleListAllUsers(c *gin.Context) {
-	users, err := models.ListAllUsers()
+	var users []models.User
+	var err error
+	if status := c.Query("status"); status != "" {
+		users, err = models.ListUsersByStatus(status)
+	} else {
+		users, err = models.ListAllUsers()
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取用户列表失败"})
 		return
@@ -26,6 +39,167 @@ func HandleListAllUsers(c *gin.Context) {
 	c.JSON(http.StatusOK, users)
 }
 
+// HandleCreateUser godoc
+// @Summary 创建用户
+// @Description 管理员创建本地用户并指定角色（不能超出操作者自身的权限），创建后即可登录
+// @Tags 用户管理
+// @Security BearerToken
+// @Accept json
+// @Produce json
+// @Param body body api.CreateUserRequest true "用户信息"
+// @Success 200 {object} models.User
+// @Failure 400 {object} gin.H{"error": "请求参数错误"}
+// @Failure 403 {object} gin.H{"error": "不能授予超出自身权限的角色"}
+// @Failure 500 {object} gin.H{"error": "用户名已存在或创建失败"}
+// @Router /admin/users [post]
+func HandleCreateUser(c *gin.Context) {
+	var req CreateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+	req.Username = strings.TrimSpace(req.Username)
+	if req.Username == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "用户名不能为空"})
+		return
+	}
+	if req.Role == "" {
+		req.Role = rbac.RoleUser
+	}
+	if !rbac.RoleExists(req.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "未定义的角色"})
+		return
+	}
+	if !canGrantRole(c, req.Role) {
+		return
+	}
+	if !utils.IsValidPassword(req.Password) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": weakPasswordMessage})
+		return
+	}
+
+	hashed, err := utils.HashPassword(req.Password)
+	if err != nil {
+		log.Error("创建用户密码加密失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+		return
+	}
+	user := &models.User{Username: req.Username, Password: hashed, Role: req.Role}
+	if err := models.CreateUser(user); err != nil {
+		log.Error("创建用户失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "用户名已存在或创建失败"})
+		return
+	}
+
+	recordAudit(c, models.AuditUserCreate, fmt.Sprintf("user:%d", user.ID), user.Username+" ["+user.Role+"]")
+	c.JSON(http.StatusOK, user)
+}
+
+// HandleApproveUser godoc
+// @Summary 审批自助注册用户
+// @Description 管理员审批通过等待审批的自助注册用户，可同时授予角色（默认保持 viewer）；拒绝注册请直接删除用户
+// @Tags 用户管理
+// @Security BearerToken
+// @Accept json
+// @Produce json
+// @Param id path int true "用户ID"
+// @Param body body api.ApproveUserRequest false "审批参数"
+// @Success 200 {object} gin.H{"message": "已审批通过"}
+// @Failure 400 {object} gin.H{"error": "请求参数错误"}
+// @Failure 403 {object} gin.H{"error": "不能授予超出自身权限的角色"}
+// @Failure 404 {object} gin.H{"error": "用户不存在或无需审批"}
+// @Failure 500 {object} gin.H{"error": "审批失败"}
+// @Router /admin/users/{id}/approve [post]
+func HandleApproveUser(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户 ID"})
+		return
+	}
+
+	var req ApproveUserRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+			return
+		}
+	}
+	if req.Role == "" {
+		req.Role = rbac.RoleViewer
+	}
+	if !rbac.RoleExists(req.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "未定义的角色"})
+		return
+	}
+	if !canGrantRole(c, req.Role) {
+		return
+	}
+
+	approved, err := models.ApproveUser(uint(id), req.Role)
+	if err != nil {
+		log.Error("审批用户 %d 失败: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "审批失败"})
+		return
+	}
+	if !approved {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在或无需审批"})
+		return
+	}
+
+	recordAudit(c, models.AuditUserApprove, fmt.Sprintf("user:%d", id), "授予角色 "+req.Role)
+	c.JSON(http.StatusOK, gin.H{"message": "已审批通过"})
+}
+
+// HandleUnlockUser godoc
+// @Summary 解除账号锁定
+// @Description 管理员清除用户的连续登录失败次数并解除临时锁定
+// @Tags 用户管理
+// @Security BearerToken
+// @Param id path int true "用户ID"
+// @Produce json
+// @Success 200 {object} gin.H{"message": "已解除锁定"}
+// @Failure 400 {object} gin.H{"error": "无效的用户 ID"}
+// @Failure 404 {object} gin.H{"error": "用户不存在"}
+// @Failure 500 {object} gin.H{"error": "解锁失败"}
+// @Router /admin/users/{id}/unlock [post]
+func HandleUnlockUser(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户 ID"})
+		return
+	}
+
+	user, err := models.GetUserByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+	if err := models.ResetLoginFailures(user.ID); err != nil {
+		log.Error("解除用户 %d 锁定失败: %v", user.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "解锁失败"})
+		return
+	}
+	loginGuard.Unlock(user.Username)
+
+	recordAudit(c, models.AuditLoginUnlock, fmt.Sprintf("user:%d", user.ID), user.Username)
+	c.JSON(http.StatusOK, gin.H{"message": "已解除锁定"})
+}
+
+// HandleListRoles godoc
+// @Summary 获取角色列表
+// @Description 返回内置角色与 rbac.roles 中配置的角色，以及各角色展开后的权限
+// @Tags 用户管理
+// @Security BearerToken
+// @Produce json
+// @Success 200 {object} map[string]interface{} "roles 为角色列表，permissions 为权限点说明"
+// @Router /admin/roles [get]
+func HandleListRoles(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"roles":       rbac.ListRoles(),
+		"permissions": rbac.Permissions,
+	})
+}
+
 // HandleDeleteUserByID godoc
 // @Summary 删除用户
 // @Description 管理员根据 ID 删除用户
@@ -35,6 +209,8 @@ func HandleListAllUsers(c *gin.Context) {
 // @Produce json
 // @Success 200 {object} gin.H{"message": "删除成功"}
 // @Failure 400 {object} gin.H{"error": "无效的用户 ID"}
+// @Failure 403 {object} gin.H{"error": "不能管理权限超出自身的用户"}
+// @Failure 404 {object} gin.H{"error": "用户不存在"}
 // @Failure 500 {object} gin.H{"error": "删除失败"}
 // @Router /admin/users/{id} [delete]
 func HandleDeleteUserByID(c *gin.Context) {
@@ -45,18 +221,23 @@ func HandleDeleteUserByID(c *gin.Context) {
 		return
 	}
 
+	if _, ok := loadManagedUser(c, uint(id)); !ok {
+		return
+	}
+
 	err = models.DeleteUserByID(uint(id))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除失败"})
 		return
 	}
+	revokeUserSessions(uint(id))
 
 	c.JSON(http.StatusOK, gin.H{"message": "删除成功"})
 }
 
 // HandleUpdateUserByID godoc
 // @Summary 更新用户信息
-// @Description 管理员根据 ID 更新用户角色或密码
+// @Description 管理员根据 ID 更新用户角色或密码，角色须为已定义的角色且不超出操作者自身的权限，密码须符合复杂度要求
 // @Tags 用户管理
 // @Security BearerToken
 // @Param id path int true "用户ID"
@@ -64,6 +245,8 @@ func HandleDeleteUserByID(c *gin.Context) {
 // @Produce json
 // @Success 200 {object} gin.H{"message": "更新成功"}
 // @Failure 400 {object} gin.H{"error": "请求参数错误"}
+// @Failure 403 {object} gin.H{"error": "不能授予超出自身权限的角色，或不能管理权限超出自身的用户"}
+// @Failure 404 {object} gin.H{"error": "用户不存在"}
 // @Failure 500 {object} gin.H{"error": "更新失败"}
 // @Router /admin/users/{id} [put]
 func HandleUpdateUserByID(c *gin.Context) {
@@ -87,9 +270,30 @@ func HandleUpdateUserByID(c *gin.Context) {
 			return
 		}
 	}
+	target, ok := loadManagedUser(c, uint(id))
+	if !ok {
+		return
+	}
+	newRole, roleChanged := "", false
+	if role, ok := updates["role"]; ok {
+		newRole, _ = role.(string)
+		if !rbac.RoleExists(newRole) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "未定义的角色"})
+			return
+		}
+		if !canGrantRole(c, newRole) {
+			return
+		}
+		roleChanged = newRole != target.Role
+	}
 
-	if pwd, ok := updates["password"].(string); ok && pwd != "" {
-		hash, _ := bcrypt.GenerateFromPassword([]byte(pwd), bcrypt.DefaultCost)
+	if pwd, ok := updates["password"]; ok {
+		plain, _ := pwd.(string)
+		if !utils.IsValidPassword(plain) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": weakPasswordMessage})
+			return
+		}
+		hash, _ := bcrypt.GenerateFromPassword([]byte(plain), bcrypt.DefaultCost)
 		updates["password"] = string(hash)
 	}
 
@@ -98,13 +302,19 @@ func HandleUpdateUserByID(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新失败"})
 		return
 	}
+	// 修改密码或角色后，旧令牌中的身份信息已失效
+	revokeUserSessions(uint(id))
+	if roleChanged {
+		recordAudit(c, models.AuditUserRole, fmt.Sprintf("user:%d", id),
+			fmt.Sprintf("%s 角色由 %s 变更为 %s", target.Username, target.Role, newRole))
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "更新成功"})
 }
 
 // HandleResetPasswordByID godoc
 // @Summary 重置用户密码
-// @Description 管理员根据用户 ID 重置密码
+// @Description 管理员根据用户 ID 重置密码，新密码须符合复杂度要求
 // @Tags 用户管理
 // @Security BearerToken
 // @Param id path int true "用户ID"
@@ -112,6 +322,8 @@ func HandleUpdateUserByID(c *gin.Context) {
 // @Produce json
 // @Success 200 {object} gin.H{"message": "密码重置成功"}
 // @Failure 400 {object} gin.H{"error": "请求错误"}
+// @Failure 403 {object} gin.H{"error": "不能管理权限超出自身的用户"}
+// @Failure 404 {object} gin.H{"error": "用户不存在"}
 // @Failure 500 {object} gin.H{"error": "重置失败"}
 // @Router /admin/users/{id}/password [put]
 func HandleResetPasswordByID(c *gin.Context) {
@@ -129,6 +341,13 @@ func HandleResetPasswordByID(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "密码不能为空"})
 		return
 	}
+	if !utils.IsValidPassword(body.Password) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": weakPasswordMessage})
+		return
+	}
+	if _, ok := loadManagedUser(c, uint(id)); !ok {
+		return
+	}
 
 	hash, _ := bcrypt.GenerateFromPassword([]byte(body.Password), bcrypt.DefaultCost)
 	err = models.UpdateUserByID(uint(id), map[string]interface{}{"password": string(hash)})
@@ -136,6 +355,80 @@ func HandleResetPasswordByID(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "密码重置失败"})
 		return
 	}
+	revokeUserSessions(uint(id))
+	revokeUserAPITokens(uint(id))
 
 	c.JSON(http.StatusOK, gin.H{"message": "密码重置成功"})
 }
+
+// HandleRevokeUserTokens godoc
+// @Summary 强制用户下线
+// @Description 管理员吊销指定用户已签发的全部访问令牌、刷新令牌与 API 令牌
+// @Tags 用户管理
+// @Security BearerToken
+// @Param id path int true "用户ID"
+// @Produce json
+// @Success 200 {object} gin.H{"message": "已强制下线"}
+// @Failure 400 {object} gin.H{"error": "无效的用户 ID"}
+// @Failure 500 {object} gin.H{"error": "操作失败"}
+// @Router /admin/users/{id}/revoke-tokens [post]
+func HandleRevokeUserTokens(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户 ID"})
+		return
+	}
+
+	if err := revokeAllSessions(uint(id)); err != nil {
+		log.Error("吊销用户 %d 的令牌失败: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "操作失败"})
+		return
+	}
+	if err := models.RevokeAPITokensByUserID(uint(id)); err != nil {
+		log.Error("吊销用户 %d 的 API 令牌失败: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "操作失败"})
+		return
+	}
+	recordAudit(c, models.AuditTokensRevokeAll, fmt.Sprintf("user:%d", id), "管理员强制下线")
+	c.JSON(http.StatusOK, gin.H{"message": "已强制下线"})
+}
+
+// canGrantRole 校验当前用户能否授予角色，超出自身权限时写入 403 响应并返回 false
+func canGrantRole(c *gin.Context, role string) bool {
+	claims := c.MustGet("claims").(*auth.CustomClaims)
+	if rbac.CanGrant(claims.Role, role) {
+		return true
+	}
+	c.JSON(http.StatusForbidden, gin.H{"error": "不能授予超出自身权限的角色"})
+	return false
+}
+
+// loadManagedUser 加载被管理的用户，并要求其当前角色不超出操作者自身的权限，
+// 避免借用户管理权限重置、降级或删除更高权限的账号；ok 为 false 时已写入错误响应
+func loadManagedUser(c *gin.Context, id uint) (*models.User, bool) {
+	target, err := models.GetUserByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return nil, false
+	}
+	claims := c.MustGet("claims").(*auth.CustomClaims)
+	if !rbac.CanGrant(claims.Role, target.Role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "不能管理权限超出自身的用户"})
+		return nil, false
+	}
+	return target, true
+}
+
+// revokeUserSessions 吊销用户的全部登录令牌，失败时仅记录日志
+func revokeUserSessions(userID uint) {
+	if err := revokeAllSessions(userID); err != nil {
+		log.Error("吊销用户 %d 的令牌失败: %v", userID, err)
+	}
+}
+
+// revokeUserAPITokens 吊销用户的全部 API 令牌，失败时仅记录日志
+func revokeUserAPITokens(userID uint) {
+	if err := models.RevokeAPITokensByUserID(userID); err != nil {
+		log.Error("吊销用户 %d 的 API 令牌失败: %v", userID, err)
+	}
+}
@@ -0,0 +1,342 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"VulnFusion/internal/auth"
+	"VulnFusion/internal/log"
+	"VulnFusion/internal/models"
+	"VulnFusion/internal/netguard"
+	"VulnFusion/internal/notify"
+	"VulnFusion/internal/rbac"
+	"VulnFusion/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxWebhookDeliveries 投递日志单次最多返回的条数
+const maxWebhookDeliveries = 200
+
+// HandleCreateWebhook 创建 Webhook
+// @Summary 创建 Webhook
+// @Description 注册事件回调地址，可按事件类型与漏洞严重等级过滤，免打扰时段内的事件推迟到时段结束后投递，支持 generic / slack / teams / dingtalk / feishu 消息格式；签名密钥仅在创建时返回一次
+// @Tags Webhook
+// @Accept json
+// @Produce json
+// @Param data body api.WebhookRequest true "Webhook 信息"
+// @Success 200 {object} map[string]interface{} "Webhook 与签名密钥"
+// @Failure 400 {object} map[string]string "参数错误"
+// @Failure 403 {object} map[string]string "无权限"
+// @Failure 500 {object} map[string]string "创建失败"
+// @Security ApiKeyAuth
+// @Router /api/v1/webhooks [post]
+func HandleCreateWebhook(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
+
+	var req WebhookRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+	events, ok := validateWebhookRequest(ctx, claims, &req)
+	if !ok {
+		return
+	}
+
+	secret := req.Secret
+	if secret == "" && (req.Format == "" || req.Format == notify.FormatGeneric) {
+		var err error
+		if secret, err = utils.GenerateSecureToken(24); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "生成签名密钥失败"})
+			return
+		}
+	}
+
+	webhook := &models.Webhook{
+		UserID:      claims.UserID,
+		Name:        strings.TrimSpace(req.Name),
+		URL:         req.URL,
+		Secret:      secret,
+		Events:      events,
+		MinSeverity: strings.ToLower(req.MinSeverity),
+		Global:      req.Global,
+		Format:      req.Format,
+		QuietStart:  req.QuietStart,
+		QuietEnd:    req.QuietEnd,
+		Enabled:     req.Enabled == nil || *req.Enabled,
+	}
+	if err := models.CreateWebhook(webhook); err != nil {
+		log.Error("创建 Webhook 失败: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "创建 Webhook 失败"})
+		return
+	}
+	// gorm 的 default:true 会把零值 false 当作未设置，这里显式写回
+	if !webhook.Enabled {
+		_ = models.UpdateWebhookByID(webhook.ID, map[string]interface{}{"enabled": false})
+	}
+	ctx.JSON(http.StatusOK, gin.H{"webhook": webhook, "secret": secret})
+}
+
+// HandleListWebhooks 获取 Webhook 列表
+// @Summary 获取 Webhook 列表
+// @Description 返回当前用户创建的 Webhook，管理员返回全部
+// @Tags Webhook
+// @Produce json
+// @Success 200 {array} models.Webhook "Webhook 列表"
+// @Failure 500 {object} map[string]string "获取失败"
+// @Security ApiKeyAuth
+// @Router /api/v1/webhooks [get]
+func HandleListWebhooks(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
+
+	var webhooks []models.Webhook
+	var err error
+	if rbac.Can(claims.Role, rbac.WebhookManageAny) {
+		webhooks, err = models.ListAllWebhooks()
+	} else {
+		webhooks, err = models.ListWebhooksByUserID(claims.UserID)
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "获取 Webhook 失败"})
+		return
+	}
+	ctx.JSON(http.StatusOK, webhooks)
+}
+
+// HandleGetWebhook 获取 Webhook 详情
+// @Summary 获取 Webhook 详情
+// @Tags Webhook
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} models.Webhook "Webhook"
+// @Failure 403 {object} map[string]string "无权限"
+// @Failure 404 {object} map[string]string "Webhook 不存在"
+// @Security ApiKeyAuth
+// @Router /api/v1/webhooks/{id} [get]
+func HandleGetWebhook(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
+	webhook, ok := loadWebhook(ctx, claims)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, webhook)
+}
+
+// HandleUpdateWebhook 更新 Webhook
+// @Summary 更新 Webhook
+// @Description 修改回调地址、消息格式、事件过滤、免打扰时段与启用状态；secret 为空时保留原密钥
+// @Tags Webhook
+// @Accept json
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Param data body api.WebhookRequest true "Webhook 信息"
+// @Success 200 {object} map[string]string "更新成功"
+// @Failure 400 {object} map[string]string "参数错误"
+// @Failure 403 {object} map[string]string "无权限"
+// @Failure 404 {object} map[string]string "Webhook 不存在"
+// @Security ApiKeyAuth
+// @Router /api/v1/webhooks/{id} [put]
+func HandleUpdateWebhook(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
+	webhook, ok := loadWebhook(ctx, claims)
+	if !ok {
+		return
+	}
+
+	var req WebhookRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+	events, ok := validateWebhookRequest(ctx, claims, &req)
+	if !ok {
+		return
+	}
+
+	updates := map[string]interface{}{
+		"name":         strings.TrimSpace(req.Name),
+		"url":          req.URL,
+		"events":       events,
+		"min_severity": strings.ToLower(req.MinSeverity),
+		"global":       req.Global,
+		"format":       req.Format,
+		"quiet_start":  req.QuietStart,
+		"quiet_end":    req.QuietEnd,
+	}
+	if req.Secret != "" {
+		updates["secret"] = req.Secret
+	}
+	if req.Enabled != nil {
+		updates["enabled"] = *req.Enabled
+	}
+	if err := models.UpdateWebhookByID(webhook.ID, updates); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "更新 Webhook 失败"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "Webhook 已更新"})
+}
+
+// HandleDeleteWebhook 删除 Webhook
+// @Summary 删除 Webhook
+// @Description 删除 Webhook 及其投递记录
+// @Tags Webhook
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} map[string]string "删除成功"
+// @Failure 403 {object} map[string]string "无权限"
+// @Failure 404 {object} map[string]string "Webhook 不存在"
+// @Security ApiKeyAuth
+// @Router /api/v1/webhooks/{id} [delete]
+func HandleDeleteWebhook(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
+	webhook, ok := loadWebhook(ctx, claims)
+	if !ok {
+		return
+	}
+
+	if err := models.DeleteWebhookByID(webhook.ID); err != nil {
+		log.Error("删除 Webhook %d 失败: %v", webhook.ID, err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "删除 Webhook 失败"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "Webhook 已删除"})
+}
+
+// HandleListWebhookDeliveries 获取 Webhook 投递日志
+// @Summary 获取投递日志
+// @Description 按时间倒序返回 Webhook 的投递记录，包括响应状态码、尝试次数与失败原因
+// @Tags Webhook
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Param limit query int false "返回条数，默认 50，最大 200"
+// @Success 200 {array} models.WebhookDelivery "投递记录"
+// @Failure 403 {object} map[string]string "无权限"
+// @Failure 404 {object} map[string]string "Webhook 不存在"
+// @Security ApiKeyAuth
+// @Router /api/v1/webhooks/{id}/deliveries [get]
+func HandleListWebhookDeliveries(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
+	webhook, ok := loadWebhook(ctx, claims)
+	if !ok {
+		return
+	}
+
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > maxWebhookDeliveries {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "参数错误"})
+		return
+	}
+
+	deliveries, err := models.ListWebhookDeliveries(webhook.ID, limit)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "获取投递日志失败"})
+		return
+	}
+	ctx.JSON(http.StatusOK, deliveries)
+}
+
+// HandleTestWebhook 发送测试事件
+// @Summary 发送测试事件
+// @Description 立即向 Webhook 投递一次 test 事件（不重试），仅返回是否送达，详细记录见投递日志
+// @Tags Webhook
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} map[string]interface{} "success 表示是否送达"
+// @Failure 403 {object} map[string]string "无权限"
+// @Failure 404 {object} map[string]string "Webhook 不存在"
+// @Failure 500 {object} map[string]string "投递失败"
+// @Security ApiKeyAuth
+// @Router /api/v1/webhooks/{id}/test [post]
+func HandleTestWebhook(ctx *gin.Context) {
+	claims := ctx.MustGet("claims").(*auth.CustomClaims)
+	webhook, ok := loadWebhook(ctx, claims)
+	if !ok {
+		return
+	}
+
+	// 只返回是否成功，不返回状态码与连接错误，避免被用作内网探测
+	delivery := notify.NewDispatcher().Deliver(webhook, notify.NewTestEvent(), 1)
+	if delivery == nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "投递失败"})
+		return
+	}
+	if !delivery.Success {
+		ctx.JSON(http.StatusOK, gin.H{"success": false, "message": "测试消息投递失败，请检查地址与接收方配置"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"success": true, "message": "测试消息已送达"})
+}
+
+// loadWebhook 读取路径中的 Webhook 并校验创建人或管理员权限；ok 为 false 时已写入错误响应
+func loadWebhook(ctx *gin.Context, claims *auth.CustomClaims) (*models.Webhook, bool) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Webhook ID 格式错误"})
+		return nil, false
+	}
+
+	webhook, err := models.GetWebhookByID(uint(id))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Webhook 不存在"})
+		return nil, false
+	}
+
+	if !rbac.Can(claims.Role, rbac.WebhookManageAny) && webhook.UserID != claims.UserID {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "无权限操作此 Webhook"})
+		return nil, false
+	}
+	return webhook, true
+}
+
+// validateWebhookRequest 校验 Webhook 参数，返回逗号拼接的事件列表；ok 为 false 时已写入错误响应
+func validateWebhookRequest(ctx *gin.Context, claims *auth.CustomClaims, req *WebhookRequest) (string, bool) {
+	if strings.TrimSpace(req.Name) == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "名称不能为空"})
+		return "", false
+	}
+	if err := netguard.ValidateURL(req.URL); err != nil {
+		if errors.Is(err, netguard.ErrBlockedAddress) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "投递地址不能指向内网或保留地址"})
+			return "", false
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "投递地址必须为 http 或 https URL"})
+		return "", false
+	}
+	if req.MinSeverity != "" && notify.SeverityRank(req.MinSeverity) < 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "不支持的严重等级"})
+		return "", false
+	}
+	if !notify.IsValidFormat(req.Format) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "不支持的消息格式"})
+		return "", false
+	}
+	if req.QuietStart != "" || req.QuietEnd != "" {
+		_, ok1 := notify.ParseClock(req.QuietStart)
+		_, ok2 := notify.ParseClock(req.QuietEnd)
+		if !ok1 || !ok2 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "免打扰时段格式应为 HH:MM"})
+			return "", false
+		}
+	}
+	if req.Global && !rbac.Can(claims.Role, rbac.WebhookManageAny) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "无权限接收全部任务的事件"})
+		return "", false
+	}
+
+	var events []string
+	for _, e := range req.Events {
+		e = strings.TrimSpace(e)
+		if e == "" {
+			continue
+		}
+		if !notify.IsValidEvent(e) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "不支持的事件类型: " + e})
+			return "", false
+		}
+		events = append(events, e)
+	}
+	return strings.Join(events, ","), true
+}
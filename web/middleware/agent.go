@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"VulnFusion/internal/models"
+	"VulnFusion/internal/utils"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// AgentAuthMiddleware 校验远程扫描节点的访问令牌（X-Agent-Token）并注入节点信息
+func AgentAuthMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		token := ctx.GetHeader("X-Agent-Token")
+		if token == "" {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "未提供节点令牌"})
+			return
+		}
+
+		agent, err := models.GetAgentByTokenHash(utils.SHA256Hex(token))
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "无效的节点令牌"})
+			return
+		}
+
+		ctx.Set("agent", agent)
+		ctx.Next()
+	}
+}
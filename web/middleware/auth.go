@@ -2,11 +2,21 @@ package middleware
 
 import (
 	"VulnFusion/internal/auth"
+	"VulnFusion/internal/log"
+	"VulnFusion/internal/models"
+	"VulnFusion/internal/rbac"
+	"VulnFusion/internal/utils"
 	"github.com/gin-gonic/gin"
 	"net/http"
+	"strings"
+	"time"
 )
 
-// JWTAuthMiddleware 验证 Access Token 并注入 claims
+// apiTokenTouchInterval 最近使用时间的最小更新间隔，避免每次请求都写库
+const apiTokenTouchInterval = time.Minute
+
+// JWTAuthMiddleware 验证 Access Token 或 API 令牌（vf_ 前缀）并注入 claims；
+// API 令牌还需具备当前路由所需的权限范围
 func JWTAuthMiddleware() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		token := ctx.GetHeader("Authorization")
@@ -20,6 +30,21 @@ func JWTAuthMiddleware() gin.HandlerFunc {
 			token = token[7:]
 		}
 
+		if strings.HasPrefix(token, auth.APITokenPrefix) {
+			claims, ok := authenticateAPIToken(ctx, token)
+			if !ok {
+				return
+			}
+			scope := auth.ScopeForRoute(ctx.Request.Method, ctx.FullPath())
+			if !claims.HasScope(scope) {
+				ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "API 令牌缺少权限范围: " + scope})
+				return
+			}
+			InjectClaimsToContext(ctx, claims)
+			ctx.Next()
+			return
+		}
+
 		claims, err := auth.ParseToken(token)
 		if err != nil {
 			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "无效或过期的 token"})
@@ -30,38 +55,47 @@ func JWTAuthMiddleware() gin.HandlerFunc {
 	}
 }
 
-// RequireAdmin 仅允许管理员角色访问
-func RequireAdmin() gin.HandlerFunc {
-	return func(ctx *gin.Context) {
-		claims, exists := ctx.Get("claims")
-		if !exists {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "未认证"})
-			return
-		}
-		if claims.(*auth.CustomClaims).Role != "admin" {
-			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "权限不足，需管理员身份"})
-			return
+// authenticateAPIToken 校验 API 令牌并构造 claims，角色取用户当前角色；ok 为 false 时已写入错误响应
+func authenticateAPIToken(ctx *gin.Context, raw string) (*auth.CustomClaims, bool) {
+	token, err := models.GetAPITokenByHash(utils.SHA256Hex(raw))
+	if err != nil || !token.Active(time.Now()) {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "无效、已吊销或已过期的 API 令牌"})
+		return nil, false
+	}
+	user, err := models.GetUserByID(token.UserID)
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "令牌所属用户不存在"})
+		return nil, false
+	}
+
+	if token.LastUsedAt == nil || time.Since(*token.LastUsedAt) > apiTokenTouchInterval || token.LastUsedIP != ctx.ClientIP() {
+		if err := models.TouchAPIToken(token.ID, ctx.ClientIP()); err != nil {
+			log.Warn("更新 API 令牌 %d 使用记录失败: %v", token.ID, err)
 		}
-		ctx.Next()
 	}
+
+	return &auth.CustomClaims{
+		UserID:   user.ID,
+		Username: user.Username,
+		Role:     user.Role,
+		TokenID:  token.ID,
+		Scopes:   token.ScopeList(),
+	}, true
 }
 
-// RequireRole 允许多个角色访问，只要命中任一即可通过
-func RequireRole(roles ...string) gin.HandlerFunc {
+// RequirePermission 要求当前用户的角色具备指定权限，路由级权限统一通过该中间件声明
+func RequirePermission(permission string) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		claims, exists := ctx.Get("claims")
 		if !exists {
 			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "未认证"})
 			return
 		}
-		userRole := claims.(*auth.CustomClaims).Role
-		for _, role := range roles {
-			if userRole == role {
-				ctx.Next()
-				return
-			}
+		if !rbac.Can(claims.(*auth.CustomClaims).Role, permission) {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "权限不足，需要 " + permission + " 权限"})
+			return
 		}
-		ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "权限不足"})
+		ctx.Next()
 	}
 }
 
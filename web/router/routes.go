@@ -1,22 +1,49 @@
 package router
 
 import (
+	"VulnFusion/internal/config"
+	"VulnFusion/internal/rbac"
 	"VulnFusion/web/api"
 	"VulnFusion/web/middleware"
 
 	"github.com/gin-gonic/gin"
 )
 
+// SetTrustedProxies 只采信 trusted_proxies 中的代理转发的客户端地址，避免伪造 X-Forwarded-For 绕过按 IP 的登录限制
+func SetTrustedProxies(r *gin.Engine) error {
+	return r.SetTrustedProxies(config.TrustedProxies())
+}
+
 // RegisterRoutes 初始化所有路由
 func RegisterRoutes(r *gin.Engine) {
 	r.Use(middleware.CORS())
 
+	// JWT 公钥集合，供其他服务验证本系统签发的令牌
+	r.GET("/.well-known/jwks.json", api.HandleJWKS)
+
 	apiV1 := r.Group("/api/v1")
 
 	// 公共接口（无需登录）
 	apiV1.POST("/auth/login", api.HandleLogin)
+	apiV1.POST("/auth/login/mfa", api.HandleLoginMFA)
+	apiV1.POST("/auth/login/mfa/setup", api.HandleLoginMFASetup)
+	apiV1.GET("/auth/oidc/providers", api.HandleListOIDCProviders)
+	apiV1.GET("/auth/oidc/:provider/login", api.HandleOIDCLogin)
+	apiV1.GET("/auth/oidc/:provider/callback", api.HandleOIDCCallback)
+	apiV1.POST("/auth/oidc/exchange", api.HandleOIDCExchange)
 	apiV1.POST("/auth/register", api.HandleRegister)
 	apiV1.POST("/auth/refresh", api.HandleRefreshToken)
+	apiV1.POST("/agent/register", api.HandleAgentRegister)
+
+	// 远程扫描节点接口（节点令牌鉴权）
+	agentGroup := apiV1.Group("/agent")
+	agentGroup.Use(middleware.AgentAuthMiddleware())
+	{
+		agentGroup.POST("/heartbeat", api.HandleAgentHeartbeat)
+		agentGroup.POST("/tasks/pull", api.HandleAgentPullTask)
+		agentGroup.POST("/tasks/:id/results", api.HandleAgentUploadResults)
+		agentGroup.POST("/tasks/:id/status", api.HandleAgentUpdateTask)
+	}
 
 	// 登录后访问接口
 	authGroup := apiV1.Group("")
@@ -24,33 +51,125 @@ func RegisterRoutes(r *gin.Engine) {
 	{
 		// 用户相关
 		authGroup.GET("/user/info", api.HandleGetCurrentUser)
+		authGroup.GET("/user/notifications", api.HandleGetNotificationSettings)
+		authGroup.PUT("/user/notifications", api.HandleUpdateNotificationSettings)
+		authGroup.POST("/user/notifications/test", api.HandleSendTestEmail)
 		authGroup.POST("/auth/logout", api.HandleLogout)
+		authGroup.POST("/auth/logout-all", api.HandleLogoutAll)
+		authGroup.GET("/auth/mfa", api.HandleGetMFAStatus)
+		authGroup.POST("/auth/mfa/setup", api.HandleSetupMFA)
+		authGroup.POST("/auth/mfa/enable", api.HandleEnableMFA)
+		authGroup.POST("/auth/mfa/disable", api.HandleDisableMFA)
+		authGroup.POST("/auth/mfa/recovery-codes", api.HandleRegenerateRecoveryCodes)
+		authGroup.GET("/sessions", api.HandleListSessions)
+		authGroup.DELETE("/sessions/:id", api.HandleRevokeSession)
 
 		// 扫描任务
-		authGroup.POST("/tasks", api.HandleCreateTask)
-		authGroup.GET("/tasks", api.HandleListMyTasks)
-		authGroup.GET("/tasks/:id", api.HandleGetTaskByID)
-		authGroup.DELETE("/tasks/:id", api.HandleDeleteTaskByID)
-		authGroup.POST("/tasks/batch_delete", api.HandleBatchDeleteTasks)
-		authGroup.POST("/tasks/status", api.HandleUpdateTaskStatus)
+		authGroup.POST("/tasks", middleware.RequirePermission(rbac.TaskCreate), api.HandleCreateTask)
+		authGroup.GET("/tasks", middleware.RequirePermission(rbac.TaskRead), api.HandleListMyTasks)
+		authGroup.GET("/tasks/:id", middleware.RequirePermission(rbac.TaskRead), api.HandleGetTaskByID)
+		authGroup.DELETE("/tasks/:id", middleware.RequirePermission(rbac.TaskWrite), api.HandleDeleteTaskByID)
+		authGroup.POST("/tasks/batch_delete", middleware.RequirePermission(rbac.TaskWrite), api.HandleBatchDeleteTasks)
+		authGroup.POST("/tasks/status", middleware.RequirePermission(rbac.TaskWrite), api.HandleUpdateTaskStatus)
+		authGroup.POST("/tasks/:id/import", middleware.RequirePermission(rbac.ResultTriage), api.HandleImportSignedResults)
+
+		// 扫描范围
+		authGroup.GET("/scope", middleware.RequirePermission(rbac.ScopeRead), api.HandleGetMyScope)
+		authGroup.PUT("/scope", middleware.RequirePermission(rbac.ScopeManage), api.HandleUpdateMyScope)
+		authGroup.POST("/scope/check", middleware.RequirePermission(rbac.ScopeRead), api.HandleCheckScope)
+
+		// 项目
+		authGroup.POST("/projects", middleware.RequirePermission(rbac.ProjectCreate), api.HandleCreateProject)
+		authGroup.GET("/projects", api.HandleListMyProjects)
+		authGroup.GET("/projects/:id", api.HandleGetProject)
+		authGroup.PUT("/projects/:id", middleware.RequirePermission(rbac.ProjectManage), api.HandleUpdateProject)
+		authGroup.DELETE("/projects/:id", middleware.RequirePermission(rbac.ProjectManage), api.HandleDeleteProject)
+		authGroup.POST("/projects/:id/members", middleware.RequirePermission(rbac.ProjectManage), api.HandleSaveProjectMember)
+		authGroup.DELETE("/projects/:id/members/:user_id", middleware.RequirePermission(rbac.ProjectManage), api.HandleRemoveProjectMember)
+		authGroup.GET("/projects/:id/scope", api.HandleGetProjectScope)
+		authGroup.PUT("/projects/:id/scope", middleware.RequirePermission(rbac.ScopeManage), api.HandleUpdateProjectScope)
 
 		// 扫描结果
-		authGroup.GET("/results/task/:task_id", api.HandleListResultsByTask)
-		authGroup.DELETE("/results/task/:task_id", api.HandleDeleteResultsByTask)
-		authGroup.GET("/results/:id", api.HandleGetResultDetail)
-		authGroup.GET("/results/export/:task_id", api.HandleExportResults)
+		authGroup.GET("/results/task/:task_id", middleware.RequirePermission(rbac.ResultRead), api.HandleListResultsByTask)
+		authGroup.DELETE("/results/task/:task_id", middleware.RequirePermission(rbac.TaskWrite), api.HandleDeleteResultsByTask)
+		authGroup.GET("/results/:id", middleware.RequirePermission(rbac.ResultRead), api.HandleGetResultDetail)
+		authGroup.GET("/results/:id/provenance", middleware.RequirePermission(rbac.ResultRead), api.HandleGetResultProvenance)
+		authGroup.GET("/results/export/:task_id", middleware.RequirePermission(rbac.ResultRead), api.HandleExportResults)
+		authGroup.PUT("/results/:id/status", middleware.RequirePermission(rbac.ResultTriage), api.HandleUpdateResultStatus)
+		authGroup.POST("/results/:id/ticket", middleware.RequirePermission(rbac.ResultTriage), api.HandleCreateResultTicket)
+		authGroup.POST("/results/:id/ticket/sync", middleware.RequirePermission(rbac.ResultTriage), api.HandleSyncResultTicket)
+
+		// 统计
+		authGroup.GET("/stats", middleware.RequirePermission(rbac.StatsRead), api.HandleGetStats)
+
+		// 资产
+		authGroup.GET("/assets", middleware.RequirePermission(rbac.AssetRead), api.HandleListAssets)
+		authGroup.POST("/assets/scan", middleware.RequirePermission(rbac.TaskCreate), api.HandleScanAssets)
+		authGroup.GET("/assets/:id", middleware.RequirePermission(rbac.AssetRead), api.HandleGetAsset)
+		authGroup.PUT("/assets/:id", middleware.RequirePermission(rbac.AssetWrite), api.HandleUpdateAsset)
+		authGroup.GET("/assets/:id/findings", middleware.RequirePermission(rbac.AssetRead), api.HandleListAssetFindings)
+
+		// Webhook
+		authGroup.POST("/webhooks", middleware.RequirePermission(rbac.WebhookManage), api.HandleCreateWebhook)
+		authGroup.GET("/webhooks", middleware.RequirePermission(rbac.WebhookManage), api.HandleListWebhooks)
+		authGroup.GET("/webhooks/:id", middleware.RequirePermission(rbac.WebhookManage), api.HandleGetWebhook)
+		authGroup.PUT("/webhooks/:id", middleware.RequirePermission(rbac.WebhookManage), api.HandleUpdateWebhook)
+		authGroup.DELETE("/webhooks/:id", middleware.RequirePermission(rbac.WebhookManage), api.HandleDeleteWebhook)
+		authGroup.GET("/webhooks/:id/deliveries", middleware.RequirePermission(rbac.WebhookManage), api.HandleListWebhookDeliveries)
+		authGroup.POST("/webhooks/:id/test", middleware.RequirePermission(rbac.WebhookManage), api.HandleTestWebhook)
+
+		// 缺陷跟踪系统
+		authGroup.POST("/trackers", middleware.RequirePermission(rbac.TrackerManage), api.HandleCreateTrackerConnector)
+		authGroup.GET("/trackers", middleware.RequirePermission(rbac.TrackerManage), api.HandleListTrackerConnectors)
+		authGroup.GET("/trackers/:id", middleware.RequirePermission(rbac.TrackerManage), api.HandleGetTrackerConnector)
+		authGroup.PUT("/trackers/:id", middleware.RequirePermission(rbac.TrackerManage), api.HandleUpdateTrackerConnector)
+		authGroup.DELETE("/trackers/:id", middleware.RequirePermission(rbac.TrackerManage), api.HandleDeleteTrackerConnector)
+		authGroup.POST("/trackers/:id/sync", middleware.RequirePermission(rbac.TrackerManage), api.HandleSyncTrackerConnector)
+
+		// API 令牌（令牌管理接口不接受 API 令牌本身）
+		authGroup.POST("/tokens", middleware.RequirePermission(rbac.TokenManage), api.HandleCreateAPIToken)
+		authGroup.GET("/tokens", middleware.RequirePermission(rbac.TokenManage), api.HandleListAPITokens)
+		authGroup.DELETE("/tokens/:id", middleware.RequirePermission(rbac.TokenManage), api.HandleRevokeAPIToken)
+
+		// CI 门禁
+		authGroup.POST("/ci/scan", middleware.RequirePermission(rbac.TaskCreate), api.HandleCIScan)
+		authGroup.GET("/ci/scan/:id", middleware.RequirePermission(rbac.TaskRead), api.HandleGetCIScan)
 	}
 
-	// 管理员接口（需具备 admin 权限）
+	// 管理接口（按路由声明所需权限）
 	adminGroup := apiV1.Group("/admin")
-	adminGroup.Use(middleware.JWTAuthMiddleware(), middleware.RequireAdmin())
+	adminGroup.Use(middleware.JWTAuthMiddleware())
 	{
-		adminGroup.GET("/tasks", api.HandleListAllTasks)
-		adminGroup.GET("/results", api.HandleListAllResults)
-		adminGroup.GET("/users", api.HandleListAllUsers)
-		adminGroup.DELETE("/users/:id", api.HandleDeleteUserByID)
-		adminGroup.PUT("/users/:id", api.HandleUpdateUserByID)
-		adminGroup.PUT("/users/:id/password", api.HandleResetPasswordByID) // ✅ 新增
+		adminGroup.GET("/tasks", middleware.RequirePermission(rbac.TaskReadAny), api.HandleListAllTasks)
+		adminGroup.GET("/results", middleware.RequirePermission(rbac.ResultReadAny), api.HandleListAllResults)
+		adminGroup.GET("/users", middleware.RequirePermission(rbac.UserRead), api.HandleListAllUsers)
+		adminGroup.POST("/users", middleware.RequirePermission(rbac.UserManage), api.HandleCreateUser)
+		adminGroup.DELETE("/users/:id", middleware.RequirePermission(rbac.UserManage), api.HandleDeleteUserByID)
+		adminGroup.PUT("/users/:id", middleware.RequirePermission(rbac.UserManage), api.HandleUpdateUserByID)
+		adminGroup.PUT("/users/:id/password", middleware.RequirePermission(rbac.UserManage), api.HandleResetPasswordByID) // ✅ 新增
+		adminGroup.POST("/users/:id/revoke-tokens", middleware.RequirePermission(rbac.UserManage), api.HandleRevokeUserTokens)
+		adminGroup.GET("/users/:id/sessions", middleware.RequirePermission(rbac.UserRead), api.HandleListUserSessions)
+		adminGroup.POST("/users/:id/mfa/reset", middleware.RequirePermission(rbac.UserManage), api.HandleResetUserMFA)
+		adminGroup.POST("/users/:id/approve", middleware.RequirePermission(rbac.UserManage), api.HandleApproveUser)
+		adminGroup.POST("/users/:id/unlock", middleware.RequirePermission(rbac.UserManage), api.HandleUnlockUser)
+		adminGroup.GET("/users/:id/scope", middleware.RequirePermission(rbac.ScopeManage), api.HandleGetUserScope)
+		adminGroup.PUT("/users/:id/scope", middleware.RequirePermission(rbac.ScopeManage), api.HandleUpdateUserScope)
+		adminGroup.POST("/invites", middleware.RequirePermission(rbac.UserManage), api.HandleCreateInvite)
+		adminGroup.GET("/invites", middleware.RequirePermission(rbac.UserRead), api.HandleListInvites)
+		adminGroup.DELETE("/invites/:id", middleware.RequirePermission(rbac.UserManage), api.HandleRevokeInvite)
+		adminGroup.GET("/signing-keys", middleware.RequirePermission(rbac.KeyManage), api.HandleListSigningKeys)
+		adminGroup.POST("/signing-keys/rotate", middleware.RequirePermission(rbac.KeyManage), api.HandleRotateSigningKey)
+		adminGroup.GET("/agents", middleware.RequirePermission(rbac.AgentRead), api.HandleListAgents)
+		adminGroup.DELETE("/agents/:id", middleware.RequirePermission(rbac.AgentManage), api.HandleDeleteAgent)
+		adminGroup.PUT("/agents/:id/key", middleware.RequirePermission(rbac.AgentManage), api.HandleUpdateAgentKey)
+		adminGroup.GET("/audit", middleware.RequirePermission(rbac.AuditRead), api.HandleListAuditLogs)
+		adminGroup.GET("/tokens", middleware.RequirePermission(rbac.TokenReadAny), api.HandleListAllAPITokens)
+		adminGroup.POST("/risk/likelihood", middleware.RequirePermission(rbac.RiskManage), api.HandleImportLikelihood)
+		adminGroup.POST("/risk/recalculate", middleware.RequirePermission(rbac.RiskManage), api.HandleRecalculateRisk)
+		adminGroup.POST("/enrich/nvd", middleware.RequirePermission(rbac.EnrichManage), api.HandleImportNVD)
+		adminGroup.POST("/enrich/cwe", middleware.RequirePermission(rbac.EnrichManage), api.HandleImportCWE)
+		adminGroup.GET("/roles", middleware.RequirePermission(rbac.UserRead), api.HandleListRoles)
+		adminGroup.GET("/enrich/status", middleware.RequirePermission(rbac.EnrichRead), api.HandleEnrichStatus)
 	}
 
 }